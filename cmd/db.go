@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/drift"
+	"github.com/spf13/cobra"
+)
+
+var dbVerifySample int
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance commands",
+}
+
+var dbVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Reclaim space from deleted rows and refresh planner statistics",
+	Long: `Reclaim space from deleted rows and refresh planner statistics. On
+SQLite this rewrites the whole database file, blocking writers until it
+finishes, so run it while traffic is low.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		withDB(func(ctx context.Context, dbService *db.Service) error {
+			if err := dbService.Vacuum(ctx); err != nil {
+				return err
+			}
+			fmt.Println("Vacuumed database")
+			return nil
+		})
+	},
+}
+
+var dbReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the indexes of every table",
+	Run: func(_ *cobra.Command, _ []string) {
+		withDB(func(ctx context.Context, dbService *db.Service) error {
+			if err := dbService.Reindex(ctx); err != nil {
+				return err
+			}
+			fmt.Println("Rebuilt indexes")
+			return nil
+		})
+	},
+}
+
+var dbStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show row counts and the database size",
+	Run: func(_ *cobra.Command, _ []string) {
+		withDB(func(ctx context.Context, dbService *db.Service) error {
+			stats, err := dbService.Stats(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Driver: %s\nSize:   %.1f MiB\n\n", stats.Driver, float64(stats.SizeBytes)/(1<<20))
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TABLE\tROWS")
+			for _, table := range stats.Tables {
+				fmt.Fprintf(tw, "%s\t%d\n", table.Name, table.Rows)
+			}
+			return tw.Flush()
+		})
+	},
+}
+
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare a sample of indexed topics and messages with their PDS records",
+	Long: `Compare a random sample of indexed topics and messages with the records
+on their authors' PDSes and report drift: rows whose record is missing, no
+longer matches its lexicon, or differs in a field the index copies.
+
+Exits with status 1 when drift is found.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		withDB(func(ctx context.Context, dbService *db.Service) error {
+			if dbVerifySample < 1 {
+				return fmt.Errorf("--sample must be at least 1")
+			}
+			topics, err := dbService.Queries().SampleTopics(ctx, int32(dbVerifySample))
+			if err != nil {
+				return fmt.Errorf("failed to sample topics: %w", err)
+			}
+			messages, err := dbService.Queries().SampleMessages(ctx, int32(dbVerifySample))
+			if err != nil {
+				return fmt.Errorf("failed to sample messages: %w", err)
+			}
+
+			rows := make([]drift.Row, 0, len(topics)+len(messages))
+			for _, topic := range topics {
+				rows = append(rows, drift.TopicRow(topic))
+			}
+			for _, message := range messages {
+				rows = append(rows, drift.MessageRow(message))
+			}
+
+			report := drift.Check(ctx, drift.PDS{Resolve: auth.DiscoverPDS}, rows, func(uri string, err error) {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", uri, err)
+			})
+			for _, d := range report.Drift {
+				if d.Detail != "" {
+					fmt.Printf("%s %s: %s\n", d.Kind, d.URI, d.Detail)
+				} else {
+					fmt.Printf("%s %s\n", d.Kind, d.URI)
+				}
+			}
+			fmt.Printf("Checked %d rows: %d differences, %d unreachable\n", report.Checked, len(report.Drift), report.Unreachable)
+			if len(report.Drift) > 0 {
+				return fmt.Errorf("index has drifted from the PDS")
+			}
+			return nil
+		})
+	},
+}
+
+// withDB opens the configured database for a maintenance command, runs fn
+// and exits with status 1 if it fails
+func withDB(fn func(ctx context.Context, dbService *db.Service) error) {
+	dbService, err := db.NewService(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	err = fn(context.Background(), dbService)
+	_ = dbService.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	dbVerifyCmd.Flags().IntVar(&dbVerifySample, "sample", 50, "topics and messages to sample, each")
+	dbCmd.AddCommand(dbVacuumCmd, dbReindexCmd, dbStatsCmd, dbVerifyCmd)
+	rootCmd.AddCommand(dbCmd)
+}
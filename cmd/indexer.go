@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/jrschumacher/dis.quest/server"
+	"github.com/spf13/cobra"
+)
+
+var indexerCmd = &cobra.Command{
+	Use:   "indexer",
+	Short: "Run the background jobs that maintain the shared database",
+	Long: `Run the background jobs that maintain the shared database: purges,
+participation retries and mirrors. Use it when running several server
+instances, each with indexer set to external, and run exactly one indexer.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		server.StartIndexer(cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexerCmd)
+}
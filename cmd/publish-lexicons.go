@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/spf13/cobra"
+)
+
+// appPasswordEnv names the environment variable holding the operator's app
+// password, kept out of flags so it does not land in shell history
+const appPasswordEnv = "DISQUEST_APP_PASSWORD"
+
+var publishLexiconsHandle string
+var publishLexiconsHost string
+
+var publishLexiconsCmd = &cobra.Command{
+	Use:   "publish-lexicons",
+	Short: "Publish the quest.dis.* lexicons to the operator's repo",
+	Long: `Publish each lexicon in lexicons/ to the operator's repo as a
+com.atproto.lexicon.schema record keyed by its NSID, replacing any earlier
+version. Resolvers find the repo through the _lexicon TXT record of the
+NSID authority's domain, which must point at the operator's DID.
+
+The app password is read from $` + appPasswordEnv + `.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		password := os.Getenv(appPasswordEnv)
+		if publishLexiconsHandle == "" || password == "" {
+			fmt.Fprintf(os.Stderr, "--handle and $%s are required\n", appPasswordEnv)
+			os.Exit(1)
+		}
+		host := publishLexiconsHost
+		if host == "" {
+			host = cfg.PDSEndpoint
+		}
+
+		session, err := auth.CreateSession(host, publishLexiconsHandle, password)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to sign in to %s: %v\n", host, err)
+			os.Exit(1)
+		}
+		client := pds.NewClient(host, pds.BearerToken(session.AccessJwt), nil)
+
+		ctx := context.Background()
+		failed := false
+		for _, nsid := range lexicon.SchemaNSIDs() {
+			record, err := lexicon.SchemaRecord(nsid)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", nsid, err)
+				failed = true
+				continue
+			}
+			ref, err := client.PutRecord(ctx, session.Did, lexicon.SchemaNSID, nsid, record)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to publish %s: %v\n", nsid, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Published %s at %s\n", nsid, ref.URI)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	publishLexiconsCmd.Flags().StringVar(&publishLexiconsHandle, "handle", "", "operator handle or DID to publish as")
+	publishLexiconsCmd.Flags().StringVar(&publishLexiconsHost, "pds", "", "PDS hosting the operator's repo (default pds_endpoint)")
+	rootCmd.AddCommand(publishLexiconsCmd)
+}
@@ -0,0 +1,87 @@
+package components
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+)
+
+// formatDate renders a timestamp the way dates are shown across fragments
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// formatDateTime renders a timestamp to the minute, in UTC
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// topicElementID returns a stable DOM id for a topic so fragments can be
+// swapped in place
+func topicElementID(topic db.Topic) string {
+	return "topic-" + topic.Rkey
+}
+
+// messageElementID returns a stable DOM id for a message, which posting a
+// reply without JavaScript scrolls back to
+func messageElementID(message db.Message) string {
+	return "message-" + message.Rkey
+}
+
+// topicURL returns the path of a topic's thread page
+func topicURL(topic db.Topic) string {
+	return "/discussion/" + url.PathEscape(topic.Did+":"+topic.Rkey)
+}
+
+// replyURL returns the thread page with its form set up to reply to message
+func replyURL(topic db.Topic, message db.Message) string {
+	return topicURL(topic) + "?reply_to=" + url.QueryEscape(message.Rkey) + "#reply"
+}
+
+// formatCount renders a counter for dashboard tables
+func formatCount(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatAverageSeconds renders total/count seconds as a duration, or a dash
+// when there is nothing to average
+func formatAverageSeconds(total, count int64) string {
+	if count == 0 {
+		return "—"
+	}
+	return (time.Duration(total/count) * time.Second).String()
+}
+
+// pluralize renders a count with its noun, adding an s unless it is one
+func pluralize(n int64, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return formatCount(n) + " " + noun + "s"
+}
+
+// formatScopes renders an access token's scopes
+func formatScopes(scopes []string) string {
+	return strings.Join(scopes, ", ")
+}
+
+// formatLastUsed renders when an access token was last used
+func formatLastUsed(t time.Time) string {
+	if t.IsZero() {
+		return "Never"
+	}
+	return formatDateTime(t)
+}
+
+// expiryWarning tells the user how long their session has left, in whole
+// minutes rounded up
+func expiryWarning(expiresIn time.Duration) string {
+	if expiresIn <= 0 {
+		return "Your session has expired."
+	}
+	minutes := int64((expiresIn + time.Minute - 1) / time.Minute)
+	return "Your session expires in " + pluralize(minutes, "minute") + "."
+}
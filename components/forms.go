@@ -0,0 +1,17 @@
+package components
+
+// TopicDraft is the new topic form as last submitted, shown again with the
+// reason it was refused
+type TopicDraft struct {
+	Subject        string
+	InitialMessage string
+	Error          string
+}
+
+// MessageDraft is a thread page's reply form
+type MessageDraft struct {
+	Content string
+	// ParentMessageRkey is the message replied to, empty for the topic itself
+	ParentMessageRkey string
+	Error             string
+}
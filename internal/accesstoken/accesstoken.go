@@ -0,0 +1,231 @@
+// Package accesstoken issues personal access tokens, which let scripts call
+// the REST API as the user who issued them without going through the OAuth
+// sign-in. Tokens are random, so only their SHA-256 hash is stored; the token
+// itself is shown once, when it is issued.
+package accesstoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/clock"
+)
+
+const (
+	// Prefix starts every token, so they are recognisable in Authorization
+	// headers and to secret scanners
+	Prefix = "dqp_"
+	// DefaultLifetime is how long a token lasts when none is asked for
+	DefaultLifetime = 90 * 24 * time.Hour
+	// MaxLifetime bounds how long a token may last; every token expires
+	MaxLifetime = 365 * 24 * time.Hour
+	// maxNameLength bounds the label a user gives a token
+	maxNameLength = 100
+	// touchInterval limits how often last-used times are written
+	touchInterval = 5 * time.Minute
+)
+
+// Scopes a token may be granted
+const (
+	// ScopeRead allows safe requests: GET, HEAD and OPTIONS
+	ScopeRead = "read"
+	// ScopeWrite allows every other request too
+	ScopeWrite = "write"
+)
+
+var (
+	// ErrNotFound is returned for tokens that do not exist, have been
+	// revoked, have expired, or belong to someone else
+	ErrNotFound = errors.New("access token not found")
+	// ErrInvalid is returned when a token cannot be issued as asked
+	ErrInvalid = errors.New("invalid access token request")
+)
+
+// Token is an issued access token, without its secret
+type Token struct {
+	ID        string    `json:"id"`
+	DID       string    `json:"did"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// LastUsedAt is zero until the token is first used
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Hash is the SHA-256 of the token, hex encoded
+	Hash string `json:"-"`
+}
+
+// Allows reports whether the token grants scope. Write access implies read.
+func (t Token) Allows(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || (scope == ScopeRead && slices.Contains(t.Scopes, ScopeWrite))
+}
+
+// Expired reports whether the token has expired as of now
+func (t Token) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Store persists tokens
+type Store interface {
+	Create(ctx context.Context, t Token) error
+	// GetByHash returns ErrNotFound when no token has the hash
+	GetByHash(ctx context.Context, hash string) (Token, error)
+	Touch(ctx context.Context, id string, lastUsedAt time.Time) error
+	// List returns did's tokens, newest first
+	List(ctx context.Context, did string) ([]Token, error)
+	// Delete removes one of did's tokens, reporting whether it existed
+	Delete(ctx context.Context, did, id string) (bool, error)
+}
+
+// Manager issues, checks and revokes tokens
+type Manager struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewManager creates a manager backed by store that reads the time from clk
+func NewManager(store Store, clk clock.Clock) *Manager {
+	return &Manager{store: store, now: clk.Now}
+}
+
+// Issue creates a token for did named name, granting scopes for lifetime,
+// or DefaultLifetime when lifetime is zero. It returns the token's secret,
+// which cannot be recovered later.
+func (m *Manager) Issue(ctx context.Context, did, name string, scopes []string, lifetime time.Duration) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxNameLength {
+		return "", Token{}, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalid, maxNameLength)
+	}
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	if lifetime < 0 || lifetime > MaxLifetime {
+		return "", Token{}, fmt.Errorf("%w: lifetime must be at most %d days", ErrInvalid, MaxLifetime/(24*time.Hour))
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	id, err := randomString(12)
+	if err != nil {
+		return "", Token{}, err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", Token{}, err
+	}
+	secret = Prefix + secret
+	now := m.now().UTC()
+	t := Token{
+		ID:        id,
+		DID:       did,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+		Hash:      Hash(secret),
+	}
+	if err := m.store.Create(ctx, t); err != nil {
+		return "", Token{}, err
+	}
+	return secret, t, nil
+}
+
+// Authenticate returns the live token whose secret is given, refreshing its
+// last-used time
+func (m *Manager) Authenticate(ctx context.Context, secret string) (Token, error) {
+	if !strings.HasPrefix(secret, Prefix) {
+		return Token{}, ErrNotFound
+	}
+	t, err := m.store.GetByHash(ctx, Hash(secret))
+	if err != nil {
+		return Token{}, err
+	}
+	now := m.now().UTC()
+	if t.Expired(now) {
+		return Token{}, ErrNotFound
+	}
+	if now.Sub(t.LastUsedAt) > touchInterval {
+		// A failed touch only makes the last-used time stale
+		if err := m.store.Touch(ctx, t.ID, now); err == nil {
+			t.LastUsedAt = now
+		}
+	}
+	return t, nil
+}
+
+// Lookup returns the live token whose secret req presents. It matches
+// middleware.TokenAuthenticator.
+func (m *Manager) Lookup(req *http.Request, secret string) (Token, bool) {
+	t, err := m.Authenticate(req.Context(), secret)
+	return t, err == nil
+}
+
+// List returns did's tokens, newest first, including expired ones
+func (m *Manager) List(ctx context.Context, did string) ([]Token, error) {
+	return m.store.List(ctx, did)
+}
+
+// Revoke deletes one of did's tokens
+func (m *Manager) Revoke(ctx context.Context, did, id string) error {
+	found, err := m.store.Delete(ctx, did, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Hash returns the stored form of a token's secret
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// FormatScopes joins scopes the way they are stored
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ParseScopes splits stored scopes
+func ParseScopes(s string) []string {
+	return strings.Fields(s)
+}
+
+// normalizeScopes checks scopes, drops duplicates and defaults to read
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{ScopeRead}, nil
+	}
+	var normalized []string
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeWrite {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalid, scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,133 @@
+package accesstoken
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/clock"
+)
+
+func newTestManager() (*Manager, *clock.Manual) {
+	clk := clock.NewManual(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	return NewManager(NewMemoryStore(), clk), clk
+}
+
+func TestManagerIssue(t *testing.T) {
+	ctx := context.Background()
+	m, clk := newTestManager()
+
+	secret, tok, err := m.Issue(ctx, "did:plc:alice", "  backup script ", []string{ScopeWrite, ScopeRead, ScopeWrite}, 0)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !strings.HasPrefix(secret, Prefix) {
+		t.Errorf("secret %q lacks prefix %q", secret, Prefix)
+	}
+	if tok.Name != "backup script" {
+		t.Errorf("Name = %q, want trimmed", tok.Name)
+	}
+	if !slices.Equal(tok.Scopes, []string{ScopeRead, ScopeWrite}) {
+		t.Errorf("Scopes = %v, want [read write]", tok.Scopes)
+	}
+	if want := clk.Now().Add(DefaultLifetime); !tok.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", tok.ExpiresAt, want)
+	}
+	if tok.Hash == secret || tok.Hash != Hash(secret) {
+		t.Error("expected only the secret's hash to be kept")
+	}
+
+	_, readOnly, err := m.Issue(ctx, "did:plc:alice", "reader", nil, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if !readOnly.Allows(ScopeRead) || readOnly.Allows(ScopeWrite) {
+		t.Errorf("default scopes = %v, want read only", readOnly.Scopes)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		scopes   []string
+		lifetime time.Duration
+	}{
+		{"", nil, 0},
+		{strings.Repeat("x", maxNameLength+1), nil, 0},
+		{"admin", []string{"admin"}, 0},
+		{"forever", nil, MaxLifetime + time.Hour},
+		{"negative", nil, -time.Hour},
+	} {
+		if _, _, err := m.Issue(ctx, "did:plc:alice", tc.name, tc.scopes, tc.lifetime); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Issue(%q, %v, %v) error = %v, want ErrInvalid", tc.name, tc.scopes, tc.lifetime, err)
+		}
+	}
+}
+
+func TestManagerAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	m, clk := newTestManager()
+
+	secret, issued, err := m.Issue(ctx, "did:plc:alice", "script", nil, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	tok, err := m.Authenticate(ctx, secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if tok.ID != issued.ID || tok.DID != "did:plc:alice" {
+		t.Errorf("Authenticate returned %+v", tok)
+	}
+	if !tok.LastUsedAt.Equal(clk.Now()) {
+		t.Errorf("LastUsedAt = %v, want %v", tok.LastUsedAt, clk.Now())
+	}
+
+	for _, bad := range []string{"", secret + "x", strings.TrimPrefix(secret, Prefix)} {
+		if _, err := m.Authenticate(ctx, bad); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Authenticate(%q) error = %v, want ErrNotFound", bad, err)
+		}
+	}
+
+	clk.Advance(24 * time.Hour)
+	if _, err := m.Authenticate(ctx, secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired token error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManagerRevoke(t *testing.T) {
+	ctx := context.Background()
+	m, clk := newTestManager()
+
+	secret, first, err := m.Issue(ctx, "did:plc:alice", "first", nil, 0)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	clk.Advance(time.Minute)
+	if _, _, err := m.Issue(ctx, "did:plc:alice", "second", nil, 0); err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	tokens, err := m.List(ctx, "did:plc:alice")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].Name != "second" {
+		t.Fatalf("List = %+v, want newest first", tokens)
+	}
+
+	if err := m.Revoke(ctx, "did:plc:mallory", first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoking another user's token error = %v, want ErrNotFound", err)
+	}
+	if err := m.Revoke(ctx, "did:plc:alice", first.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := m.Authenticate(ctx, secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoked token error = %v, want ErrNotFound", err)
+	}
+	if err := m.Revoke(ctx, "did:plc:alice", first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Revoke error = %v, want ErrNotFound", err)
+	}
+}
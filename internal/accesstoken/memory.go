@@ -0,0 +1,79 @@
+package accesstoken
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps tokens in process memory. Tokens are lost on restart,
+// so it suits tests and single-instance development servers.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]Token)}
+}
+
+// Create implements Store
+func (s *MemoryStore) Create(_ context.Context, t Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[t.ID] = t
+	return nil
+}
+
+// GetByHash implements Store
+func (s *MemoryStore) GetByHash(_ context.Context, hash string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			return t, nil
+		}
+	}
+	return Token{}, ErrNotFound
+}
+
+// Touch implements Store
+func (s *MemoryStore) Touch(_ context.Context, id string, lastUsedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tokens[id]; ok {
+		t.LastUsedAt = lastUsedAt
+		s.tokens[id] = t
+	}
+	return nil
+}
+
+// List implements Store
+func (s *MemoryStore) List(_ context.Context, did string) ([]Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := []Token{}
+	for _, t := range s.tokens {
+		if t.DID == did {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(_ context.Context, did, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[id]
+	if !ok || t.DID != did {
+		return false, nil
+	}
+	delete(s.tokens, id)
+	return true, nil
+}
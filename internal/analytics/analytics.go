@@ -0,0 +1,141 @@
+// Package analytics aggregates topic activity into daily totals. Counters
+// are keyed by topic and UTC day only, so nothing it stores can be traced
+// back to the people who viewed or posted.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Key identifies one topic's counters for one day
+type Key struct {
+	TopicDid  string
+	TopicRkey string
+	// Day is midnight UTC of the day the activity happened
+	Day time.Time
+}
+
+// Counts are activity totals. ResponseSeconds is the summed time to first
+// response across FirstResponses topics, so the average is their ratio.
+type Counts struct {
+	Views           int32
+	Messages        int32
+	Participants    int32
+	FirstResponses  int32
+	ResponseSeconds int64
+}
+
+func (c *Counts) add(o Counts) {
+	c.Views += o.Views
+	c.Messages += o.Messages
+	c.Participants += o.Participants
+	c.FirstResponses += o.FirstResponses
+	c.ResponseSeconds += o.ResponseSeconds
+}
+
+// Store persists counters by adding deltas to the stored totals
+type Store interface {
+	Add(ctx context.Context, key Key, delta Counts) error
+}
+
+// Day truncates t to midnight UTC
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Tracker buffers counter increments in memory and writes them to the
+// store in batches, so a page view costs a map update rather than a write
+type Tracker struct {
+	store Store
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[Key]Counts
+}
+
+// NewTracker creates a tracker that flushes to store
+func NewTracker(store Store) *Tracker {
+	return &Tracker{
+		store:   store,
+		now:     time.Now,
+		pending: make(map[Key]Counts),
+	}
+}
+
+// View counts a topic being viewed
+func (t *Tracker) View(topicDid, topicRkey string) {
+	t.add(topicDid, topicRkey, Counts{Views: 1})
+}
+
+// Message counts a message posted to a topic. newParticipant is true when
+// it is the author's first message in the topic today.
+func (t *Tracker) Message(topicDid, topicRkey string, newParticipant bool) {
+	delta := Counts{Messages: 1}
+	if newParticipant {
+		delta.Participants = 1
+	}
+	t.add(topicDid, topicRkey, delta)
+}
+
+// FirstResponse records how long a topic waited for its first reply from
+// someone other than its author
+func (t *Tracker) FirstResponse(topicDid, topicRkey string, after time.Duration) {
+	t.add(topicDid, topicRkey, Counts{FirstResponses: 1, ResponseSeconds: int64(max(after, 0) / time.Second)})
+}
+
+func (t *Tracker) add(topicDid, topicRkey string, delta Counts) {
+	key := Key{TopicDid: topicDid, TopicRkey: topicRkey, Day: Day(t.now())}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := t.pending[key]
+	counts.add(delta)
+	t.pending[key] = counts
+}
+
+// Flush writes buffered counters to the store. Counters that fail to write
+// are kept and retried on the next flush.
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[Key]Counts)
+	t.mu.Unlock()
+
+	var errs []error
+	for key, delta := range batch {
+		if err := t.store.Add(ctx, key, delta); err != nil {
+			errs = append(errs, err)
+			t.mu.Lock()
+			counts := t.pending[key]
+			counts.add(delta)
+			t.pending[key] = counts
+			t.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run flushes every interval until ctx is done, then flushes once more.
+// Flush errors are passed to onError.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := t.Flush(context.WithoutCancel(ctx)); err != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
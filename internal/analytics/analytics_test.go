@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryStore sums deltas in a map and can be told to fail
+type memoryStore struct {
+	totals map[Key]Counts
+	err    error
+}
+
+func (s *memoryStore) Add(_ context.Context, key Key, delta Counts) error {
+	if s.err != nil {
+		return s.err
+	}
+	counts := s.totals[key]
+	counts.add(delta)
+	s.totals[key] = counts
+	return nil
+}
+
+func TestDay(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	got := Day(time.Date(2025, 3, 1, 22, 30, 0, 0, est))
+	want := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("Day() = %v, want %v", got, want)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{totals: make(map[Key]Counts)}
+	tracker := NewTracker(store)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.View("did:plc:alice", "topic")
+	tracker.View("did:plc:alice", "topic")
+	tracker.Message("did:plc:alice", "topic", true)
+	tracker.Message("did:plc:alice", "topic", false)
+	tracker.FirstResponse("did:plc:alice", "topic", 90*time.Second)
+
+	now = now.Add(24 * time.Hour)
+	tracker.View("did:plc:alice", "topic")
+
+	if err := tracker.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	first := store.totals[Key{TopicDid: "did:plc:alice", TopicRkey: "topic", Day: Day(now.Add(-24 * time.Hour))}]
+	want := Counts{Views: 2, Messages: 2, Participants: 1, FirstResponses: 1, ResponseSeconds: 90}
+	if first != want {
+		t.Errorf("Expected %+v for the first day, got %+v", want, first)
+	}
+	second := store.totals[Key{TopicDid: "did:plc:alice", TopicRkey: "topic", Day: Day(now)}]
+	if second != (Counts{Views: 1}) {
+		t.Errorf("Expected one view on the second day, got %+v", second)
+	}
+
+	t.Run("Failed writes are retried", func(t *testing.T) {
+		store.err = errors.New("database unavailable")
+		tracker.View("did:plc:alice", "topic")
+		if err := tracker.Flush(ctx); err == nil {
+			t.Fatal("Expected flush error")
+		}
+
+		store.err = nil
+		if err := tracker.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		got := store.totals[Key{TopicDid: "did:plc:alice", TopicRkey: "topic", Day: Day(now)}]
+		if got.Views != 2 {
+			t.Errorf("Expected retried view to be stored, got %+v", got)
+		}
+	})
+}
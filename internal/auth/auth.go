@@ -7,13 +7,16 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/pds"
 	"golang.org/x/oauth2"
 )
 
@@ -58,7 +61,12 @@ type DPoPPKCETransport struct {
 	Base         http.RoundTripper
 	CodeVerifier string
 	DPoPKey      *ecdsa.PrivateKey
-	TargetURL    string
+	// DPoPSigner, when set, signs proofs instead of DPoPKey
+	DPoPSigner Signer
+	TargetURL  string
+	// Nonces keeps the authorization server's DPoP nonces between requests;
+	// nil uses pds.Nonces
+	Nonces *pds.NonceCache
 }
 
 // RoundTrip implements http.RoundTripper for DPoP + PKCE with nonce retry
@@ -83,7 +91,7 @@ func (t *DPoPPKCETransport) RoundTrip(req *http.Request) (*http.Response, error)
 		newReq := req.Clone(req.Context())
 		
 		// Create DPoP JWT with optional nonce
-		dpopJWT, err := CreateDPoPJWTWithNonce(t.DPoPKey, req.Method, t.TargetURL, nonce)
+		dpopJWT, err := CreateDPoPProof(req.Context(), signerOrKey(t.DPoPSigner, t.DPoPKey), req.Method, t.TargetURL, nonce, "")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create DPoP JWT: %w", err)
 		}
@@ -115,8 +123,14 @@ func (t *DPoPPKCETransport) RoundTrip(req *http.Request) (*http.Response, error)
 		base = http.DefaultTransport
 	}
 	
-	// First attempt without nonce
-	firstReq, err := makeRequest("")
+	nonces := t.Nonces
+	if nonces == nil {
+		nonces = pds.Nonces()
+	}
+
+	// First attempt with the server's latest nonce, if any is known yet
+	nonce := nonces.Get(t.TargetURL)
+	firstReq, err := makeRequest(nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -125,6 +139,8 @@ func (t *DPoPPKCETransport) RoundTrip(req *http.Request) (*http.Response, error)
 	if err != nil {
 		return resp, err
 	}
+	fresh := resp.Header.Get("DPoP-Nonce")
+	nonces.Set(t.TargetURL, fresh)
 	
 	// Check if we got a use_dpop_nonce error
 	if resp.StatusCode == 400 {
@@ -135,14 +151,18 @@ func (t *DPoPPKCETransport) RoundTrip(req *http.Request) (*http.Response, error)
 			_ = err
 		}
 		if err == nil && strings.Contains(string(respBody), "use_dpop_nonce") {
-			// Extract nonce from DPoP-Nonce header
-			if nonce := resp.Header.Get("DPoP-Nonce"); nonce != "" {
-				// Retry with nonce
-				retryReq, err := makeRequest(nonce)
+			// Retry with the nonce from the DPoP-Nonce header
+			if fresh != "" && fresh != nonce {
+				pds.Metrics().CountNonceRetry()
+				retryReq, err := makeRequest(fresh)
 				if err != nil {
 					return nil, err
 				}
-				return base.RoundTrip(retryReq)
+				retryResp, err := base.RoundTrip(retryReq)
+				if err == nil {
+					nonces.Set(t.TargetURL, retryResp.Header.Get("DPoP-Nonce"))
+				}
+				return retryResp, err
 			}
 		}
 		// Restore the response body for the original error
@@ -172,7 +192,7 @@ func OAuth2Config(metadata *AuthorizationServerMetadata, cfg *config.Config) *oa
 		ClientID:     cfg.OAuthClientID,
 		ClientSecret: "", // Not required for public clients
 		RedirectURL:  cfg.OAuthRedirectURL,
-		Scopes:       []string{"atproto", "transition:generic"},
+		Scopes:       slices.Clone(RequestedScopes),
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  metadata.AuthorizationEndpoint,
 			TokenURL: metadata.TokenEndpoint,
@@ -272,8 +292,10 @@ func ExchangeCodeForToken(ctx context.Context, metadata *AuthorizationServerMeta
 	return conf.Exchange(ctx, code)
 }
 
-// ExchangeCodeForTokenWithDPoP exchanges an authorization code for an access token using DPoP
-func ExchangeCodeForTokenWithDPoP(ctx context.Context, metadata *AuthorizationServerMetadata, code, codeVerifier string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) (*oauth2.Token, error) {
+// ExchangeCodeForTokenWithDPoP exchanges an authorization code for an access
+// token using DPoP. The grant must be for expectedDID, the account the user
+// started signing in as, unless expectedDID is empty.
+func ExchangeCodeForTokenWithDPoP(ctx context.Context, metadata *AuthorizationServerMetadata, code, codeVerifier string, dpopKey *ecdsa.PrivateKey, expectedDID string, cfg *config.Config) (*Grant, error) {
 	conf := OAuth2Config(metadata, cfg)
 	
 	// Use a custom transport that adds both PKCE and DPoP with nonce retry
@@ -285,5 +307,53 @@ func ExchangeCodeForTokenWithDPoP(ctx context.Context, metadata *AuthorizationSe
 			TargetURL:    metadata.TokenEndpoint,
 		},
 	})
-	return conf.Exchange(ctx, code)
+	token, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return nil, tokenEndpointError(metadata, err)
+	}
+	grant, err := NewGrant(token)
+	if err != nil {
+		return nil, err
+	}
+	if expectedDID != "" && grant.Subject != expectedDID {
+		return nil, fmt.Errorf("%w: got %s, want %s", ErrSubjectMismatch, grant.Subject, expectedDID)
+	}
+	return grant, nil
+}
+
+// RefreshTokenWithDPoP exchanges a refresh token for a new token pair using
+// DPoP. The authorization server rotates refresh tokens, so the returned
+// token's RefreshToken replaces the one passed in.
+func RefreshTokenWithDPoP(ctx context.Context, metadata *AuthorizationServerMetadata, refreshToken string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) (*oauth2.Token, error) {
+	conf := OAuth2Config(metadata, cfg)
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: &DPoPPKCETransport{
+			Base:      http.DefaultTransport,
+			DPoPKey:   dpopKey,
+			TargetURL: metadata.TokenEndpoint,
+		},
+	})
+	pds.Metrics().CountRefresh()
+	token, err := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		err = tokenEndpointError(metadata, err)
+		// An outage is not a rejection: the refresh token is still good
+		var retrieveErr *oauth2.RetrieveError
+		if !pds.IsUnavailable(err) && errors.As(err, &retrieveErr) {
+			return nil, fmt.Errorf("%w: %w", ErrRefreshRejected, err)
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// tokenEndpointError marks token endpoint failures caused by the
+// authorization server being down or unreachable with pds.ErrUnavailable
+func tokenEndpointError(metadata *AuthorizationServerMetadata, err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return pds.Unavailable(metadata.Issuer, retrieveErr.Response, err)
+	}
+	return pds.Unavailable(metadata.Issuer, nil, err)
 }
@@ -1,10 +1,19 @@
 package auth
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/pds"
 )
 
 func TestGeneratePKCE(t *testing.T) {
@@ -51,9 +60,12 @@ func TestDPoPKeyCookieRoundTrip(t *testing.T) {
 		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
 	}
 	rr := httptest.NewRecorder()
-	if err := SetDPoPKeyCookie(rr, keypair.PrivateKey, true); err != nil {
+	if err := SetDPoPKeyCookie(rr, keypair.PrivateKey, time.Hour, true); err != nil {
 		t.Fatalf("SetDPoPKeyCookie error: %v", err)
 	}
+	if maxAge := rr.Result().Cookies()[0].MaxAge; maxAge != 3600 {
+		t.Errorf("MaxAge = %d, want 3600", maxAge)
+	}
 	req := httptest.NewRequest("GET", "/", nil)
 	for _, c := range rr.Result().Cookies() {
 		req.AddCookie(c)
@@ -124,3 +136,185 @@ func TestDecodeDPoPPrivateKeyFromPEM_InvalidPEM(t *testing.T) {
 		t.Errorf("expected ErrInvalidPEMBlock, got %v", err)
 	}
 }
+
+func TestRefreshSession(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.server.refreshSession" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") == "Bearer maintenance" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer old-refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"accessJwt":"new-access","refreshJwt":"new-refresh","did":"did:plc:test"}`))
+	}))
+	defer srv.Close()
+
+	session, err := RefreshSession(context.Background(), srv.URL, "old-refresh")
+	if err != nil {
+		t.Fatalf("RefreshSession error: %v", err)
+	}
+	if session.AccessJwt != "new-access" || session.RefreshJwt != "new-refresh" {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+
+	if _, err := RefreshSession(context.Background(), srv.URL, "revoked"); !errors.Is(err, ErrRefreshRejected) {
+		t.Fatalf("expected ErrRefreshRejected, got %v", err)
+	}
+	if _, err := RefreshSession(context.Background(), srv.URL, "maintenance"); !pds.IsUnavailable(err) {
+		t.Fatalf("expected an unavailable error, got %v", err)
+	}
+}
+
+func TestRefreshTokenWithDPoP(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %v", err)
+		}
+		if r.Header.Get("DPoP") == "" {
+			t.Error("expected DPoP header on refresh request")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("refresh_token") == "maintenance" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "old-refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"new-access","refresh_token":"new-refresh","token_type":"DPoP","expires_in":3600}`))
+	}))
+	defer srv.Close()
+
+	metadata := &AuthorizationServerMetadata{TokenEndpoint: srv.URL + "/oauth/token"}
+	cfg := &config.Config{OAuthClientID: "https://example.com/client-metadata.json"}
+
+	token, err := RefreshTokenWithDPoP(context.Background(), metadata, "old-refresh", keypair.PrivateKey, cfg)
+	if err != nil {
+		t.Fatalf("RefreshTokenWithDPoP error: %v", err)
+	}
+	if token.AccessToken != "new-access" || token.RefreshToken != "new-refresh" {
+		t.Fatalf("unexpected token: %+v", token)
+	}
+
+	if _, err := RefreshTokenWithDPoP(context.Background(), metadata, "revoked", keypair.PrivateKey, cfg); !errors.Is(err, ErrRefreshRejected) {
+		t.Fatalf("expected ErrRefreshRejected, got %v", err)
+	}
+
+	// An outage must not be mistaken for a revoked refresh token
+	_, err = RefreshTokenWithDPoP(context.Background(), metadata, "maintenance", keypair.PrivateKey, cfg)
+	if !pds.IsUnavailable(err) || errors.Is(err, ErrRefreshRejected) {
+		t.Fatalf("expected an unavailable error, got %v", err)
+	}
+}
+
+func TestDPoPPKCETransportNonces(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("DPoP-Nonce", "n1")
+		parts := strings.Split(r.Header.Get("DPoP"), ".")
+		var proof struct {
+			Nonce string `json:"nonce"`
+		}
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(payload, &proof) != nil {
+			t.Errorf("malformed DPoP proof %q", r.Header.Get("DPoP"))
+		}
+		if proof.Nonce != "n1" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"use_dpop_nonce"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	nonces := pds.NewNonceCache()
+	post := func() {
+		t.Helper()
+		transport := &DPoPPKCETransport{DPoPKey: keypair.PrivateKey, TargetURL: srv.URL + "/oauth/token", Nonces: nonces}
+		req := httptest.NewRequest("POST", srv.URL+"/oauth/token", strings.NewReader("grant_type=refresh_token"))
+		req.RequestURI = ""
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+	}
+
+	post()
+	if requests != 2 {
+		t.Fatalf("first request took %d attempts, want a nonce retry", requests)
+	}
+	// Another transport, as each refresh creates, starts with the nonce
+	post()
+	if requests != 3 {
+		t.Errorf("second request took %d attempts, want 1", requests-2)
+	}
+}
+
+func TestDPoPAuthorizer(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	req := httptest.NewRequest("POST", "https://pds.example.com/xrpc/com.atproto.repo.applyWrites", nil)
+	if err := (DPoPAuthorizer{AccessToken: "access", Key: keypair.PrivateKey}).Authorize(req, "nonce"); err != nil {
+		t.Fatalf("Authorize error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "DPoP access" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	parts := strings.Split(req.Header.Get("DPoP"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT proof, got %q", req.Header.Get("DPoP"))
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	var payload DPoPJWTPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	hash := sha256.Sum256([]byte("access"))
+	if payload.ATH != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		t.Errorf("ath = %q, want hash of access token", payload.ATH)
+	}
+	if payload.Nonce != "nonce" || payload.HTM != "POST" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func BenchmarkCreateDPoPJWT(b *testing.B) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		b.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := CreateDPoPJWTWithNonce(keypair.PrivateKey, "POST", "https://bsky.social/xrpc/com.atproto.repo.createRecord?x=1", "nonce"); err != nil {
+			b.Fatalf("CreateDPoPJWTWithNonce error: %v", err)
+		}
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/pds"
 )
 
 // AuthorizationServerMetadata represents OAuth Authorization Server metadata
@@ -13,8 +17,15 @@ type AuthorizationServerMetadata struct {
 	AuthorizationEndpoint                string   `json:"authorization_endpoint"`
 	TokenEndpoint                        string   `json:"token_endpoint"`
 	PushedAuthorizationRequestEndpoint   string   `json:"pushed_authorization_request_endpoint"`
+	RevocationEndpoint                   string   `json:"revocation_endpoint"`
 	ScopesSupported                      []string `json:"scopes_supported"`
+	ResponseTypesSupported               []string `json:"response_types_supported"`
+	CodeChallengeMethodsSupported        []string `json:"code_challenge_methods_supported"`
 	DPoPSigningAlgValuesSupported        []string `json:"dpop_signing_alg_values_supported"`
+
+	// AuthorizationResponseISSParameterSupported means authorization
+	// responses carry an iss parameter (RFC 9207)
+	AuthorizationResponseISSParameterSupported bool `json:"authorization_response_iss_parameter_supported"`
 }
 
 // DiscoverPDS returns the PDS base URL for a given handle (Bluesky username).
@@ -28,30 +39,165 @@ func DiscoverPDS(_ string) (string, error) {
 func DiscoverAuthorizationServer(handle string) (*AuthorizationServerMetadata, error) {
 	// For Bluesky handles, we need to resolve to the authorization server
 	// First discover the PDS
-	pds, err := DiscoverPDS(handle)
+	host, err := DiscoverPDS(handle)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover PDS for handle %s: %w", handle, err)
 	}
-	
-	// For Bluesky, the authorization server is typically the same as the PDS
-	// but we should fetch the metadata to be sure
-	metadataURL := strings.TrimSuffix(pds, "/") + "/.well-known/oauth-authorization-server"
-	
+
+	// The PDS names its authorization server, which for accounts hosted
+	// behind an entryway is not the PDS itself
+	resource, err := FetchProtectedResourceMetadata(host)
+	if err != nil {
+		return nil, err
+	}
+	return FetchAuthorizationServerMetadata(resource.AuthorizationServers[0])
+}
+
+// ProtectedResourceMetadata represents OAuth Protected Resource metadata
+// (RFC 9728), which a PDS serves to name its authorization server
+type ProtectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+}
+
+// FetchProtectedResourceMetadata fetches the protected resource metadata of
+// the PDS at host and checks it with Validate
+func FetchProtectedResourceMetadata(host string) (*ProtectedResourceMetadata, error) {
+	metadataURL := strings.TrimSuffix(host, "/") + "/.well-known/oauth-protected-resource"
+
 	// #nosec G107 -- URL is constructed from trusted PDS discovery, not user input
 	resp, err := http.Get(metadataURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch authorization server metadata from %s: %w", metadataURL, err)
+		return nil, fmt.Errorf("failed to fetch protected resource metadata from %s: %w", metadataURL, pds.Unavailable(host, nil, err))
 	}
 	defer func() { _ = resp.Body.Close() }()
-	
+
+	if err := pds.Unavailable(host, resp, nil); err != nil {
+		return nil, fmt.Errorf("failed to fetch protected resource metadata from %s: %w", metadataURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("protected resource metadata endpoint returned status %d", resp.StatusCode)
+	}
+
+	var metadata ProtectedResourceMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode protected resource metadata: %w", err)
+	}
+	if err := metadata.Validate(host); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// Validate checks metadata served by the PDS at origin: the resource must
+// be origin and it must name an authorization server by an absolute URL
+func (m *ProtectedResourceMetadata) Validate(origin string) error {
+	invalid := func(field, reason string) error {
+		return &MetadataError{Document: "protected resource", Field: field, Reason: reason}
+	}
+	if strings.TrimSuffix(m.Resource, "/") != strings.TrimSuffix(origin, "/") {
+		return invalid("resource", fmt.Sprintf("is %q, not the server's origin %q", m.Resource, origin))
+	}
+	if len(m.AuthorizationServers) == 0 {
+		return invalid("authorization_servers", "is empty")
+	}
+	if u, err := url.Parse(m.AuthorizationServers[0]); err != nil || !u.IsAbs() {
+		return invalid("authorization_servers", fmt.Sprintf("%q is not an absolute URL", m.AuthorizationServers[0]))
+	}
+	return nil
+}
+
+// FetchAuthorizationServerMetadata fetches the metadata of the
+// authorization server at host and checks it with Validate
+func FetchAuthorizationServerMetadata(host string) (*AuthorizationServerMetadata, error) {
+	metadataURL := strings.TrimSuffix(host, "/") + "/.well-known/oauth-authorization-server"
+
+	// #nosec G107 -- URL is constructed from trusted PDS discovery, not user input
+	resp, err := http.Get(metadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch authorization server metadata from %s: %w", metadataURL, pds.Unavailable(host, nil, err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := pds.Unavailable(host, resp, nil); err != nil {
+		return nil, fmt.Errorf("failed to fetch authorization server metadata from %s: %w", metadataURL, err)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("authorization server metadata endpoint returned status %d", resp.StatusCode)
 	}
-	
+
 	var metadata AuthorizationServerMetadata
 	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
 		return nil, fmt.Errorf("failed to decode authorization server metadata: %w", err)
 	}
-	
+	if err := metadata.Validate(host); err != nil {
+		return nil, err
+	}
 	return &metadata, nil
 }
+
+// MetadataError names the field of the authorization server or protected
+// resource metadata that does not meet the atproto OAuth profile
+type MetadataError struct {
+	// Document is the metadata the field belongs to, "authorization
+	// server" unless set
+	Document string
+	Field    string
+	Reason   string
+}
+
+func (e *MetadataError) Error() string {
+	document := e.Document
+	if document == "" {
+		document = "authorization server"
+	}
+	return fmt.Sprintf("invalid %s metadata: %s %s", document, e.Field, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidMetadata
+func (e *MetadataError) Is(target error) bool {
+	return target == ErrInvalidMetadata
+}
+
+// Validate checks metadata served by the authorization server at origin
+// against what the atproto OAuth profile requires of it and this client
+// relies on: an issuer equal to origin, the authorization, token and
+// pushed authorization request endpoints, the code response type, S256
+// PKCE and ES256 DPoP proofs
+func (m *AuthorizationServerMetadata) Validate(origin string) error {
+	invalid := func(field, reason string) error {
+		return &MetadataError{Field: field, Reason: reason}
+	}
+	if m.Issuer == "" {
+		return invalid("issuer", "is missing")
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != strings.TrimSuffix(origin, "/") {
+		return invalid("issuer", fmt.Sprintf("is %q, not the server's origin %q", m.Issuer, origin))
+	}
+	for _, endpoint := range []struct{ field, value string }{
+		{"authorization_endpoint", m.AuthorizationEndpoint},
+		{"token_endpoint", m.TokenEndpoint},
+		{"pushed_authorization_request_endpoint", m.PushedAuthorizationRequestEndpoint},
+	} {
+		if endpoint.value == "" {
+			return invalid(endpoint.field, "is missing")
+		}
+		if u, err := url.Parse(endpoint.value); err != nil || !u.IsAbs() {
+			return invalid(endpoint.field, fmt.Sprintf("%q is not an absolute URL", endpoint.value))
+		}
+	}
+	for _, supported := range []struct {
+		field  string
+		values []string
+		want   string
+	}{
+		{"response_types_supported", m.ResponseTypesSupported, "code"},
+		{"code_challenge_methods_supported", m.CodeChallengeMethodsSupported, "S256"},
+		{"dpop_signing_alg_values_supported", m.DPoPSigningAlgValuesSupported, "ES256"},
+	} {
+		if !slices.Contains(supported.values, supported.want) {
+			return invalid(supported.field, fmt.Sprintf("does not include %s", supported.want))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizationServerMetadataValidate(t *testing.T) {
+	valid := func() *AuthorizationServerMetadata {
+		return &AuthorizationServerMetadata{
+			Issuer:                             "https://auth.example.com",
+			AuthorizationEndpoint:              "https://auth.example.com/oauth/authorize",
+			TokenEndpoint:                      "https://auth.example.com/oauth/token",
+			PushedAuthorizationRequestEndpoint: "https://auth.example.com/oauth/par",
+			ResponseTypesSupported:             []string{"code"},
+			CodeChallengeMethodsSupported:      []string{"S256"},
+			DPoPSigningAlgValuesSupported:      []string{"RS256", "ES256"},
+		}
+	}
+	if err := valid().Validate("https://auth.example.com/"); err != nil {
+		t.Fatalf("valid metadata: %v", err)
+	}
+
+	for _, tc := range []struct {
+		field  string
+		modify func(m *AuthorizationServerMetadata)
+	}{
+		{"issuer", func(m *AuthorizationServerMetadata) { m.Issuer = "" }},
+		{"issuer", func(m *AuthorizationServerMetadata) { m.Issuer = "https://evil.example.com" }},
+		{"authorization_endpoint", func(m *AuthorizationServerMetadata) { m.AuthorizationEndpoint = "/oauth/authorize" }},
+		{"token_endpoint", func(m *AuthorizationServerMetadata) { m.TokenEndpoint = "" }},
+		{"pushed_authorization_request_endpoint", func(m *AuthorizationServerMetadata) { m.PushedAuthorizationRequestEndpoint = "" }},
+		{"response_types_supported", func(m *AuthorizationServerMetadata) { m.ResponseTypesSupported = []string{"token"} }},
+		{"code_challenge_methods_supported", func(m *AuthorizationServerMetadata) { m.CodeChallengeMethodsSupported = []string{"plain"} }},
+		{"dpop_signing_alg_values_supported", func(m *AuthorizationServerMetadata) { m.DPoPSigningAlgValuesSupported = nil }},
+	} {
+		m := valid()
+		tc.modify(m)
+		err := m.Validate("https://auth.example.com")
+		var metadataErr *MetadataError
+		if !errors.Is(err, ErrInvalidMetadata) || !errors.As(err, &metadataErr) || metadataErr.Field != tc.field {
+			t.Errorf("%s: err = %v, want a MetadataError for that field", tc.field, err)
+		}
+	}
+}
+
+func TestFetchProtectedResourceMetadata(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/oauth-protected-resource" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	body = fmt.Sprintf(`{"resource":%q,"authorization_servers":["https://entryway.example.com"]}`, srv.URL)
+	metadata, err := FetchProtectedResourceMetadata(srv.URL)
+	if err != nil {
+		t.Fatalf("FetchProtectedResourceMetadata: %v", err)
+	}
+	if len(metadata.AuthorizationServers) != 1 || metadata.AuthorizationServers[0] != "https://entryway.example.com" {
+		t.Errorf("unexpected metadata: %+v", metadata)
+	}
+
+	for name, invalid := range map[string]string{
+		"other resource":          `{"resource":"https://other.example.com","authorization_servers":["https://entryway.example.com"]}`,
+		"no authorization server": fmt.Sprintf(`{"resource":%q}`, srv.URL),
+		"relative server":         fmt.Sprintf(`{"resource":%q,"authorization_servers":["/oauth"]}`, srv.URL),
+	} {
+		body = invalid
+		if _, err := FetchProtectedResourceMetadata(srv.URL); !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("%s: err = %v, want ErrInvalidMetadata", name, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/jwtutil"
+)
+
+// maxProofAge is how far a proof's iat may stray from the clock before the
+// report flags it; servers typically allow a minute or so either way
+const maxProofAge = time.Minute
+
+// DPoPReport explains why a server may have rejected a DPoP-authorized
+// request, by checking the proof the client sent against the request, the
+// access token and the server's response
+type DPoPReport struct {
+	Method string
+	URL    string
+	Status int
+	// Challenge is the response's WWW-Authenticate header, which names the
+	// server's objection, e.g. error="use_dpop_nonce" or "invalid_dpop_proof"
+	Challenge string
+	// Problems are the mismatches found, empty when the proof looks right
+	Problems []string
+}
+
+// String formats the report for logs and terminals
+func (r *DPoPReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DPoP request %s %s rejected with %d", r.Method, r.URL, r.Status)
+	if r.Challenge != "" {
+		fmt.Fprintf(&b, " (%s)", r.Challenge)
+	}
+	if len(r.Problems) == 0 {
+		b.WriteString("; the proof matches the request and token, so the server may reject the token itself")
+		return b.String()
+	}
+	for _, problem := range r.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(problem)
+	}
+	return b.String()
+}
+
+func (r *DPoPReport) problemf(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// DiagnoseDPoP checks the DPoP proof on req against the request, its access
+// token and resp, as of now. It returns nil unless resp is a 401 to a
+// request carrying a proof.
+func DiagnoseDPoP(req *http.Request, resp *http.Response, now time.Time) *DPoPReport {
+	proof := req.Header.Get("DPoP")
+	if proof == "" || resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	report := &DPoPReport{
+		Method:    req.Method,
+		URL:       req.URL.Redacted(),
+		Status:    resp.StatusCode,
+		Challenge: resp.Header.Get("WWW-Authenticate"),
+	}
+	header, payload, err := ParseDPoPProof(proof)
+	if err != nil {
+		report.problemf("proof is malformed: %v", err)
+		return report
+	}
+
+	if payload.HTM != req.Method {
+		report.problemf("htm is %q but the request method is %q", payload.HTM, req.Method)
+	}
+	if want := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path; payload.HTU != want {
+		report.problemf("htu is %q but the request URL without query is %q", payload.HTU, want)
+	}
+	if iat := time.Unix(payload.IAT, 0); iat.Before(now.Add(-maxProofAge)) || iat.After(now.Add(maxProofAge)) {
+		report.problemf("iat is %s, %s from now; check the clock", iat.UTC().Format(time.RFC3339), iat.Sub(now).Round(time.Second))
+	}
+	if fresh := resp.Header.Get("DPoP-Nonce"); fresh != "" && fresh != payload.Nonce {
+		report.problemf("nonce is %q but the server now wants %q", payload.Nonce, fresh)
+	}
+
+	pub, err := jwkPublicKey(header.JWK)
+	if err != nil {
+		report.problemf("proof jwk is unusable: %v", err)
+	} else if !verifyProofSignature(proof, pub) {
+		report.problemf("signature does not verify against the proof's jwk")
+	}
+
+	scheme, token, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	if token == "" {
+		return report
+	}
+	if scheme != "DPoP" {
+		report.problemf("Authorization scheme is %q; DPoP-bound tokens must be sent as DPoP", scheme)
+	}
+	sum := sha256.Sum256([]byte(token))
+	if ath := base64.RawURLEncoding.EncodeToString(sum[:]); payload.ATH != ath {
+		report.problemf("ath is %q but the access token hashes to %q", payload.ATH, ath)
+	}
+	if pub != nil {
+		jkt := jwkThumbprint(pub)
+		// Opaque tokens carry no binding to compare
+		if claims, err := jwtutil.ParseClaims(token); err == nil && claims.Jkt != "" && claims.Jkt != jkt {
+			report.problemf("proof key thumbprint is %q but the token is bound to cnf.jkt %q; the session's DPoP key was replaced", jkt, claims.Jkt)
+		}
+	}
+	return report
+}
+
+// jwkPublicKey returns the P-256 public key a proof header's JWK describes
+func jwkPublicKey(jwk map[string]interface{}) (*ecdsa.PublicKey, error) {
+	if jwk["kty"] != "EC" || jwk["crv"] != "P-256" {
+		return nil, fmt.Errorf("kty %v crv %v, want EC P-256", jwk["kty"], jwk["crv"])
+	}
+	var coords [2]*big.Int
+	for i, name := range []string{"x", "y"} {
+		s, _ := jwk[name].(string)
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) != p256CoordinateSize {
+			return nil, fmt.Errorf("%s is not a %d-byte coordinate", name, p256CoordinateSize)
+		}
+		coords[i] = new(big.Int).SetBytes(b)
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: coords[0], Y: coords[1]}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("point is not on P-256")
+	}
+	return pub, nil
+}
+
+func verifyProofSignature(proof string, pub *ecdsa.PublicKey) bool {
+	i := strings.LastIndexByte(proof, '.')
+	sig, err := base64.RawURLEncoding.DecodeString(proof[i+1:])
+	if err != nil || len(sig) != 2*p256CoordinateSize {
+		return false
+	}
+	hash := sha256.Sum256([]byte(proof[:i]))
+	r := new(big.Int).SetBytes(sig[:p256CoordinateSize])
+	s := new(big.Int).SetBytes(sig[p256CoordinateSize:])
+	return ecdsa.Verify(pub, hash[:], r, s)
+}
+
+// DPoPDebugTransport passes requests to Base and, when one carrying a DPoP
+// proof is refused with a 401, hands Report an explanation. It only reads
+// headers, so wrapping a client's transport does not change its requests.
+// Nonce challenges, which clients answer by retrying, are not reported.
+type DPoPDebugTransport struct {
+	// Base sends the requests; nil uses http.DefaultTransport
+	Base   http.RoundTripper
+	Report func(*DPoPReport)
+	// Now reads the clock for the iat check; nil uses time.Now
+	Now func() time.Time
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DPoPDebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || t.Report == nil {
+		return resp, err
+	}
+	now := time.Now
+	if t.Now != nil {
+		now = t.Now
+	}
+	if report := DiagnoseDPoP(req, resp, now()); report != nil && !strings.Contains(report.Challenge, "use_dpop_nonce") {
+		t.Report(report)
+	}
+	return resp, nil
+}
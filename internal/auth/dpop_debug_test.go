@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// boundToken returns an unsigned JWT access token bound to key
+func boundToken(key *DPoPKeyPair) string {
+	claims := fmt.Sprintf(`{"sub":"did:plc:alice","cnf":{"jkt":%q}}`, key.JWKThumbprint())
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestDiagnoseDPoP(t *testing.T) {
+	key, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const target = "https://pds.example.com/xrpc/com.atproto.repo.createRecord"
+	unauthorized := &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{
+		"Www-Authenticate": {`DPoP error="invalid_token"`},
+		"Dpop-Nonce":       {"n1"},
+	}}
+
+	request := func(t *testing.T, proofKey *DPoPKeyPair, method, token, proofToken string) *http.Request {
+		t.Helper()
+		proof, err := CreateDPoPProof(context.Background(), KeySigner(proofKey.PrivateKey), method, target, "n1", proofToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", target+"?x=1", nil)
+		req.Header.Set("Authorization", "DPoP "+token)
+		req.Header.Set("DPoP", proof)
+		return req
+	}
+	token := boundToken(key)
+
+	report := DiagnoseDPoP(request(t, key, "POST", token, token), unauthorized, time.Now())
+	if report == nil || len(report.Problems) != 0 || report.Challenge != `DPoP error="invalid_token"` {
+		t.Fatalf("matching proof: %+v", report)
+	}
+	if !strings.Contains(report.String(), "server may reject the token itself") {
+		t.Errorf("report = %q", report.String())
+	}
+
+	for name, tc := range map[string]struct {
+		req  *http.Request
+		now  time.Time
+		want string
+	}{
+		"method":     {request(t, key, "GET", token, token), time.Now(), "htm is"},
+		"token hash": {request(t, key, "POST", token, "another token"), time.Now(), "ath is"},
+		"key":        {request(t, other, "POST", token, token), time.Now(), "cnf.jkt"},
+		"clock":      {request(t, key, "POST", token, token), time.Now().Add(time.Hour), "check the clock"},
+	} {
+		report := DiagnoseDPoP(tc.req, unauthorized, tc.now)
+		if report == nil || len(report.Problems) != 1 || !strings.Contains(report.Problems[0], tc.want) {
+			t.Errorf("%s: report = %+v, want one problem mentioning %q", name, report, tc.want)
+		}
+	}
+
+	if report := DiagnoseDPoP(request(t, key, "POST", token, token), &http.Response{StatusCode: http.StatusOK}, time.Now()); report != nil {
+		t.Errorf("successful response: report = %+v", report)
+	}
+}
+
+func TestDPoPDebugTransport(t *testing.T) {
+	challenge := `DPoP error="invalid_dpop_proof"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("WWW-Authenticate", challenge)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	key, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []*DPoPReport
+	client := &http.Client{Transport: &DPoPDebugTransport{Report: func(r *DPoPReport) { reports = append(reports, r) }}}
+	send := func() {
+		req, _ := http.NewRequest("GET", srv.URL+"/xrpc/app.bsky.actor.getProfile", nil)
+		if err := (DPoPAuthorizer{AccessToken: "opaque", Key: key.PrivateKey}).Authorize(req, ""); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	send()
+	if len(reports) != 1 || reports[0].Challenge != challenge || len(reports[0].Problems) != 0 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	// Nonce challenges are part of normal operation
+	challenge = `DPoP error="use_dpop_nonce"`
+	send()
+	if len(reports) != 1 {
+		t.Errorf("nonce challenge was reported: %+v", reports[1])
+	}
+}
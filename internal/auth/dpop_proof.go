@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DPoPProofError explains why a DPoP proof was rejected. It matches
+// ErrInvalidDPoPProof with errors.Is.
+type DPoPProofError struct {
+	Reason string
+}
+
+func (e *DPoPProofError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidDPoPProof, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidDPoPProof
+func (e *DPoPProofError) Is(target error) bool {
+	return target == ErrInvalidDPoPProof
+}
+
+// ParseDPoPProof decodes the header and payload of a DPoP proof JWT and
+// checks that the fields RFC 9449 requires are present. It does not verify
+// the signature.
+func ParseDPoPProof(proof string) (*DPoPJWTHeader, *DPoPJWTPayload, error) {
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		return nil, nil, &DPoPProofError{Reason: fmt.Sprintf("expected 3 segments, got %d", len(parts))}
+	}
+
+	var header DPoPJWTHeader
+	if err := decodeProofSegment(parts[0], &header); err != nil {
+		return nil, nil, &DPoPProofError{Reason: "header: " + err.Error()}
+	}
+	switch {
+	case header.Typ != "dpop+jwt":
+		return nil, nil, &DPoPProofError{Reason: fmt.Sprintf("typ is %q, want dpop+jwt", header.Typ)}
+	case header.Alg == "" || strings.EqualFold(header.Alg, "none"):
+		return nil, nil, &DPoPProofError{Reason: "missing or unsigned alg"}
+	case len(header.JWK) == 0:
+		return nil, nil, &DPoPProofError{Reason: "missing jwk"}
+	}
+
+	var payload DPoPJWTPayload
+	if err := decodeProofSegment(parts[1], &payload); err != nil {
+		return nil, nil, &DPoPProofError{Reason: "payload: " + err.Error()}
+	}
+	switch {
+	case payload.JTI == "":
+		return nil, nil, &DPoPProofError{Reason: "missing jti"}
+	case payload.HTM == "":
+		return nil, nil, &DPoPProofError{Reason: "missing htm"}
+	case payload.HTU == "":
+		return nil, nil, &DPoPProofError{Reason: "missing htu"}
+	case payload.IAT <= 0:
+		return nil, nil, &DPoPProofError{Reason: "missing iat"}
+	}
+
+	if parts[2] == "" {
+		return nil, nil, &DPoPProofError{Reason: "missing signature"}
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, nil, &DPoPProofError{Reason: "signature: bad base64"}
+	}
+	return &header, &payload, nil
+}
+
+func decodeProofSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("bad base64")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("bad JSON")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseDPoPProof(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	proof, err := CreateDPoPJWTWithNonce(keypair.PrivateKey, "POST", "https://bsky.social/oauth/token?x=1", "n-1")
+	if err != nil {
+		t.Fatalf("CreateDPoPJWTWithNonce error: %v", err)
+	}
+
+	header, payload, err := ParseDPoPProof(proof)
+	if err != nil {
+		t.Fatalf("ParseDPoPProof error: %v", err)
+	}
+	if header.Alg != "ES256" || header.JWK["kty"] != "EC" {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if payload.HTM != "POST" || payload.HTU != "https://bsky.social/oauth/token" || payload.Nonce != "n-1" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestParseDPoPProof_Malformed(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	header := enc(`{"typ":"dpop+jwt","alg":"ES256","jwk":{"kty":"EC"}}`)
+	payload := enc(`{"jti":"j","htm":"GET","htu":"https://pds.example","iat":1700000000}`)
+
+	for name, proof := range map[string]string{
+		"empty":           "",
+		"missing segment": header + "." + payload,
+		"bad base64":      "*." + payload + ".c2ln",
+		"wrong typ":       enc(`{"typ":"JWT","alg":"ES256","jwk":{"kty":"EC"}}`) + "." + payload + ".c2ln",
+		"alg none":        enc(`{"typ":"dpop+jwt","alg":"none","jwk":{"kty":"EC"}}`) + "." + payload + ".c2ln",
+		"no jwk":          enc(`{"typ":"dpop+jwt","alg":"ES256"}`) + "." + payload + ".c2ln",
+		"payload array":   header + "." + enc(`[]`) + ".c2ln",
+		"no htu":          header + "." + enc(`{"jti":"j","htm":"GET","iat":1}`) + ".c2ln",
+		"no signature":    header + "." + payload + ".",
+		"extra segment":   strings.Repeat(header+".", 3) + "c2ln",
+	} {
+		if _, _, err := ParseDPoPProof(proof); !errors.Is(err, ErrInvalidDPoPProof) {
+			t.Errorf("%s: error = %v, want ErrInvalidDPoPProof", name, err)
+		}
+	}
+}
+
+func FuzzParseDPoPProof(f *testing.F) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		f.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	proof, err := CreateDPoPJWTWithAccessToken(keypair.PrivateKey, "GET", "https://pds.example/xrpc/x", "n", "token")
+	if err != nil {
+		f.Fatalf("CreateDPoPJWTWithAccessToken error: %v", err)
+	}
+	f.Add(proof)
+	f.Add("eyJ0eXAiOiJkcG9wK2p3dCJ9..")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, proof string) {
+		header, payload, err := ParseDPoPProof(proof)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidDPoPProof) {
+				t.Errorf("error %v does not match ErrInvalidDPoPProof", err)
+			}
+			return
+		}
+		if header == nil || payload == nil {
+			t.Error("nil header or payload without an error")
+		}
+	})
+}
+
+func FuzzDecodeDPoPPrivateKeyFromPEM(f *testing.F) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		f.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	encoded, err := EncodeDPoPPrivateKeyToPEM(keypair.PrivateKey)
+	if err != nil {
+		f.Fatalf("EncodeDPoPPrivateKeyToPEM error: %v", err)
+	}
+	f.Add(encoded)
+	f.Add("not-base64!")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, value string) {
+		key, err := DecodeDPoPPrivateKeyFromPEM(value)
+		if err == nil && key == nil {
+			t.Error("nil key without an error")
+		}
+	})
+}
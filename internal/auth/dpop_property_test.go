@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// keyFromScalar builds a P-256 key from a private scalar so quick can drive
+// key generation deterministically. ok is false for scalars outside [1, n).
+func keyFromScalar(t *testing.T, d []byte) (*DPoPKeyPair, bool) {
+	t.Helper()
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, false
+	}
+	point := priv.PublicKey().Bytes() // 0x04 || X || Y
+	return &DPoPKeyPair{PrivateKey: &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}}, true
+}
+
+// publicKeyFromJWK decodes an EC JWK without going through the package's
+// own encoding helpers
+func publicKeyFromJWK(t *testing.T, jwk map[string]interface{}) *ecdsa.PublicKey {
+	t.Helper()
+	if jwk["kty"] != "EC" || jwk["crv"] != "P-256" {
+		t.Fatalf("unexpected JWK key type: %v", jwk)
+	}
+	coord := func(name string) *big.Int {
+		s, _ := jwk[name].(string)
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) != p256CoordinateSize {
+			t.Fatalf("JWK %s = %q: want %d base64url bytes", name, s, p256CoordinateSize)
+		}
+		return new(big.Int).SetBytes(b)
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: coord("x"), Y: coord("y")}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		t.Fatal("JWK point is not on P-256")
+	}
+	return pub
+}
+
+// verifyProof checks a DPoP proof the way a resource server would: the key
+// comes from the proof's own header and the signature is raw r || s
+func verifyProof(t *testing.T, proof string) (map[string]interface{}, DPoPJWTPayload) {
+	t.Helper()
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		t.Fatalf("proof has %d segments", len(parts))
+	}
+	var header struct {
+		Typ string                 `json:"typ"`
+		Alg string                 `json:"alg"`
+		JWK map[string]interface{} `json:"jwk"`
+	}
+	var payload DPoPJWTPayload
+	for i, v := range []any{&header, &payload} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("segment %d: %v", i, err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("segment %d: %v", i, err)
+		}
+	}
+	if header.Typ != "dpop+jwt" || header.Alg != "ES256" {
+		t.Fatalf("header typ=%q alg=%q", header.Typ, header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("signature: %v", err)
+	}
+	if len(sig) != 2*p256CoordinateSize {
+		t.Fatalf("signature is %d bytes, want %d", len(sig), 2*p256CoordinateSize)
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(sig[:p256CoordinateSize])
+	s := new(big.Int).SetBytes(sig[p256CoordinateSize:])
+	if !ecdsa.Verify(publicKeyFromJWK(t, header.JWK), hash[:], r, s) {
+		t.Fatal("signature does not verify against the header JWK")
+	}
+	return header.JWK, payload
+}
+
+// thumbprint computes RFC 7638 independently of JWKThumbprint: encoding/json
+// writes map keys in sorted order, which is the canonical form
+func thumbprint(t *testing.T, jwk map[string]interface{}) string {
+	t.Helper()
+	canonical, err := json.Marshal(map[string]interface{}{
+		"crv": jwk["crv"], "kty": jwk["kty"], "x": jwk["x"], "y": jwk["y"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestDPoPKeyProperties(t *testing.T) {
+	property := func(d [32]byte, method, path, token string) bool {
+		keypair, ok := keyFromScalar(t, d[:])
+		if !ok {
+			return true
+		}
+
+		// PEM round trip keeps the private key
+		encoded, err := EncodeDPoPPrivateKeyToPEM(keypair.PrivateKey)
+		if err != nil {
+			t.Fatalf("EncodeDPoPPrivateKeyToPEM: %v", err)
+		}
+		decoded, err := DecodeDPoPPrivateKeyFromPEM(encoded)
+		if err != nil {
+			t.Fatalf("DecodeDPoPPrivateKeyFromPEM: %v", err)
+		}
+		if !decoded.Equal(keypair.PrivateKey) {
+			t.Fatal("PEM round trip changed the key")
+		}
+
+		// JWK round trip keeps the public key
+		if !publicKeyFromJWK(t, keypair.DPoPPublicJWK()).Equal(&keypair.PrivateKey.PublicKey) {
+			t.Fatal("JWK round trip changed the public key")
+		}
+
+		// Proofs verify independently and carry the key the AS binds to
+		if method == "" {
+			method = "GET"
+		}
+		target := "https://pds.example/" + url.PathEscape(path) + "?q=1"
+		proof, err := CreateDPoPJWTWithAccessToken(decoded, method, target, "", token)
+		if err != nil {
+			t.Fatalf("CreateDPoPJWTWithAccessToken: %v", err)
+		}
+		jwk, payload := verifyProof(t, proof)
+		if payload.HTM != method || strings.Contains(payload.HTU, "?") {
+			t.Fatalf("htm=%q htu=%q", payload.HTM, payload.HTU)
+		}
+		if token != "" {
+			ath := sha256.Sum256([]byte(token))
+			if payload.ATH != base64.RawURLEncoding.EncodeToString(ath[:]) {
+				t.Fatal("ath does not hash the access token")
+			}
+		}
+		cnfJKT := thumbprint(t, jwk)
+		if got := keypair.JWKThumbprint(); got != cnfJKT {
+			t.Fatalf("JWKThumbprint = %q, cnf.jkt = %q", got, cnfJKT)
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
+
+// Coordinates and signature halves below 2^248 lose their leading zero byte
+// with big.Int.Bytes; they must still encode at full width
+func TestDPoPShortCoordinates(t *testing.T) {
+	var short *DPoPKeyPair
+	for i := int64(1); short == nil && i < 10000; i++ {
+		keypair, _ := keyFromScalar(t, new(big.Int).SetInt64(i).FillBytes(make([]byte, 32)))
+		if keypair.PrivateKey.X.BitLen() <= 248 || keypair.PrivateKey.Y.BitLen() <= 248 {
+			short = keypair
+		}
+	}
+	if short == nil {
+		t.Fatal("no key with a short coordinate found")
+	}
+	publicKeyFromJWK(t, short.DPoPPublicJWK())
+
+	// Enough proofs that some signatures have a short r or s
+	for i := 0; i < 1000; i++ {
+		proof, err := CreateDPoPJWT(short.PrivateKey, "POST", "https://bsky.social/oauth/token")
+		if err != nil {
+			t.Fatalf("CreateDPoPJWT: %v", err)
+		}
+		verifyProof(t, proof)
+	}
+}
+
+func TestJWKThumbprint_Vector(t *testing.T) {
+	// d = 1 makes the public key the P-256 base point
+	keypair, ok := keyFromScalar(t, new(big.Int).SetInt64(1).FillBytes(make([]byte, 32)))
+	if !ok {
+		t.Fatal("keyFromScalar(1) failed")
+	}
+	jwk := keypair.DPoPPublicJWK()
+	if jwk["x"] != "axfR8uEsQkf4vOblY6RA8ncDfYEt6zOg9KE5RdiYwpY" || jwk["y"] != "T-NC4v4af5uO5-tKfA-eFivOM1drMV7Oy7ZAaDe_UfU" {
+		t.Fatalf("unexpected base point JWK: %v", jwk)
+	}
+	if got, want := keypair.JWKThumbprint(), "xx0BcA-wMohw8atYDJOe6peGModklG2wRHBlXHMvl0M"; got != want {
+		t.Errorf("JWKThumbprint = %q, want %q", got, want)
+	}
+}
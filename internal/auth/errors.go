@@ -9,4 +9,11 @@ var (
 	ErrSessionNotFound    = errors.New("session not found")
 	ErrTokenExpired       = errors.New("token has expired")
 	ErrInvalidToken       = errors.New("invalid token")
-)
\ No newline at end of file
+	ErrRefreshRejected    = errors.New("refresh token rejected")
+	ErrInvalidDPoPProof   = errors.New("invalid DPoP proof")
+	ErrInvalidGrant       = errors.New("invalid token grant")
+	ErrSubjectMismatch    = errors.New("token issued for a different account")
+	ErrIssuerMismatch     = errors.New("authorization response from a different issuer")
+	ErrInvalidMetadata    = errors.New("invalid OAuth server metadata")
+	ErrIdentityMismatch   = errors.New("handle, DID and PDS do not match")
+)
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"golang.org/x/oauth2"
+)
+
+// ScopeATProto is the scope every atproto OAuth grant must include
+const ScopeATProto = "atproto"
+
+// ScopeTransitionGeneric grants the broad repository access app passwords
+// have, which writing quest.dis.* records needs
+const ScopeTransitionGeneric = "transition:generic"
+
+// RequestedScopes are the scopes this client asks for when users sign in
+var RequestedScopes = []string{ScopeATProto, ScopeTransitionGeneric}
+
+// CanGrantScope reports whether signing in again could grant scope: this
+// client must ask for it and the authorization server must support it. An
+// empty scope means the missing scope is unknown, so every requested scope
+// must be supported. Servers that do not list scopes_supported are assumed
+// to support what is asked.
+func CanGrantScope(metadata *AuthorizationServerMetadata, scope string) bool {
+	wanted := RequestedScopes
+	if scope != "" {
+		if !slices.Contains(RequestedScopes, scope) {
+			return false
+		}
+		wanted = []string{scope}
+	}
+	if metadata == nil || len(metadata.ScopesSupported) == 0 {
+		return true
+	}
+	for _, s := range wanted {
+		if !slices.Contains(metadata.ScopesSupported, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Grant is a token response from an atproto authorization server, with the
+// account and scopes it was issued for
+type Grant struct {
+	*oauth2.Token
+	// Subject is the DID of the account the tokens act for
+	Subject string
+	// Scopes are the scopes granted, which may be fewer than were requested
+	Scopes []string
+}
+
+// NewGrant reads the atproto fields of a token response. It fails unless the
+// response names a DID as its subject and grants the atproto scope.
+func NewGrant(token *oauth2.Token) (*Grant, error) {
+	sub, _ := token.Extra("sub").(string)
+	if !strings.HasPrefix(sub, "did:") {
+		return nil, fmt.Errorf("%w: sub %q is not a DID", ErrInvalidGrant, sub)
+	}
+	scope, _ := token.Extra("scope").(string)
+	grant := &Grant{Token: token, Subject: sub, Scopes: strings.Fields(scope)}
+	if !grant.HasScope(ScopeATProto) {
+		return nil, fmt.Errorf("%w: scope %q lacks %s", ErrInvalidGrant, scope, ScopeATProto)
+	}
+	return grant, nil
+}
+
+// HasScope reports whether scope was granted
+func (g *Grant) HasScope(scope string) bool {
+	return slices.Contains(g.Scopes, scope)
+}
+
+// VerifyIssuer checks the iss parameter of an authorization response
+// (RFC 9207) against the authorization server the flow started at, so a
+// response from another server cannot be mixed into this sign-in
+func VerifyIssuer(metadata *AuthorizationServerMetadata, iss string) error {
+	if iss == "" {
+		if metadata.AuthorizationResponseISSParameterSupported {
+			return fmt.Errorf("%w: authorization response has no iss", ErrIssuerMismatch)
+		}
+		return nil
+	}
+	if iss != metadata.Issuer {
+		return fmt.Errorf("%w: got %q, want %q", ErrIssuerMismatch, iss, metadata.Issuer)
+	}
+	return nil
+}
+
+// ResolveHandle returns the DID a handle belongs to, asking the PDS at host.
+// A DID is returned unchanged.
+func ResolveHandle(ctx context.Context, host, handle string) (string, error) {
+	if strings.HasPrefix(handle, "did:") {
+		return handle, nil
+	}
+	endpoint := strings.TrimSuffix(host, "/") + "/xrpc/com.atproto.identity.resolveHandle?" + url.Values{"handle": {handle}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", pds.Unavailable(host, nil, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := pds.Unavailable(host, resp, nil); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resolveHandle returned status %d for %s", resp.StatusCode, handle)
+	}
+	var out struct {
+		DID string `json:"did"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode resolveHandle response: %w", err)
+	}
+	if !strings.HasPrefix(out.DID, "did:") {
+		return "", fmt.Errorf("resolveHandle returned %q for %s, not a DID", out.DID, handle)
+	}
+	return out.DID, nil
+}
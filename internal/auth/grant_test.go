@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"golang.org/x/oauth2"
+)
+
+func TestNewGrant(t *testing.T) {
+	token := (&oauth2.Token{AccessToken: "a"}).WithExtra(map[string]any{
+		"sub": "did:plc:alice", "scope": "atproto transition:generic",
+	})
+	grant, err := NewGrant(token)
+	if err != nil {
+		t.Fatalf("NewGrant: %v", err)
+	}
+	if grant.Subject != "did:plc:alice" || !grant.HasScope("transition:generic") || grant.HasScope("transition:chat.bsky") {
+		t.Errorf("unexpected grant: %+v", grant)
+	}
+
+	for name, extra := range map[string]map[string]any{
+		"no sub":        {"scope": "atproto"},
+		"handle as sub": {"sub": "alice.example.com", "scope": "atproto"},
+		"no atproto":    {"sub": "did:plc:alice", "scope": "transition:generic"},
+	} {
+		if _, err := NewGrant((&oauth2.Token{AccessToken: "a"}).WithExtra(extra)); !errors.Is(err, ErrInvalidGrant) {
+			t.Errorf("%s: err = %v, want ErrInvalidGrant", name, err)
+		}
+	}
+}
+
+func TestVerifyIssuer(t *testing.T) {
+	metadata := &AuthorizationServerMetadata{Issuer: "https://auth.example.com"}
+	if err := VerifyIssuer(metadata, "https://auth.example.com"); err != nil {
+		t.Errorf("matching iss: %v", err)
+	}
+	if err := VerifyIssuer(metadata, ""); err != nil {
+		t.Errorf("missing iss from a server that does not send it: %v", err)
+	}
+	if err := VerifyIssuer(metadata, "https://evil.example.com"); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("other iss: err = %v, want ErrIssuerMismatch", err)
+	}
+	metadata.AuthorizationResponseISSParameterSupported = true
+	if err := VerifyIssuer(metadata, ""); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("missing iss from a server that sends it: err = %v, want ErrIssuerMismatch", err)
+	}
+}
+
+func TestResolveHandle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.identity.resolveHandle" || r.URL.Query().Get("handle") != "alice.example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"did":"did:plc:alice"}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if did, err := ResolveHandle(ctx, srv.URL, "alice.example.com"); err != nil || did != "did:plc:alice" {
+		t.Errorf("ResolveHandle = %q, %v", did, err)
+	}
+	if did, err := ResolveHandle(ctx, srv.URL, "did:plc:bob"); err != nil || did != "did:plc:bob" {
+		t.Errorf("ResolveHandle of a DID = %q, %v", did, err)
+	}
+	if _, err := ResolveHandle(ctx, srv.URL, "nobody.example.com"); err == nil {
+		t.Error("expected an unknown handle to fail")
+	}
+}
+
+func TestExchangeCodeForTokenWithDPoP(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %v", err)
+		}
+		if r.PostForm.Get("code_verifier") != "verifier" {
+			t.Errorf("code_verifier = %q", r.PostForm.Get("code_verifier"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","token_type":"DPoP","expires_in":3600,"sub":"did:plc:alice","scope":"atproto transition:generic"}`))
+	}))
+	defer srv.Close()
+
+	metadata := &AuthorizationServerMetadata{TokenEndpoint: srv.URL + "/oauth/token"}
+	cfg := &config.Config{OAuthClientID: "https://example.com/client-metadata.json"}
+	ctx := context.Background()
+
+	grant, err := ExchangeCodeForTokenWithDPoP(ctx, metadata, "code", "verifier", keypair.PrivateKey, "did:plc:alice", cfg)
+	if err != nil {
+		t.Fatalf("ExchangeCodeForTokenWithDPoP error: %v", err)
+	}
+	if grant.AccessToken != "access" || grant.Subject != "did:plc:alice" || !grant.HasScope("transition:generic") {
+		t.Errorf("unexpected grant: %+v", grant)
+	}
+
+	// Signing in as one account must not yield another's tokens
+	_, err = ExchangeCodeForTokenWithDPoP(ctx, metadata, "code", "verifier", keypair.PrivateKey, "did:plc:bob", cfg)
+	if !errors.Is(err, ErrSubjectMismatch) {
+		t.Errorf("err = %v, want ErrSubjectMismatch", err)
+	}
+}
+
+func TestCanGrantScope(t *testing.T) {
+	both := &AuthorizationServerMetadata{ScopesSupported: []string{ScopeATProto, ScopeTransitionGeneric}}
+	atprotoOnly := &AuthorizationServerMetadata{ScopesSupported: []string{ScopeATProto}}
+	for _, tc := range []struct {
+		name     string
+		metadata *AuthorizationServerMetadata
+		scope    string
+		want     bool
+	}{
+		{"supported and requested", both, ScopeTransitionGeneric, true},
+		{"not supported", atprotoOnly, ScopeTransitionGeneric, false},
+		{"not requested by this client", both, "transition:chat.bsky", false},
+		{"unknown scope, all requested supported", both, "", true},
+		{"unknown scope, some requested unsupported", atprotoOnly, "", false},
+		{"server lists no scopes", &AuthorizationServerMetadata{}, ScopeTransitionGeneric, true},
+	} {
+		if got := CanGrantScope(tc.metadata, tc.scope); got != tc.want {
+			t.Errorf("%s: CanGrantScope = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
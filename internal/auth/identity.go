@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPLCDirectory resolves did:plc identifiers
+	DefaultPLCDirectory = "https://plc.directory"
+
+	identityTimeout = 10 * time.Second
+	// maxIdentityResponse bounds DID documents and well-known DID files
+	maxIdentityResponse = 64 << 10
+)
+
+// DIDDocument is the part of a DID document atproto relies on
+type DIDDocument struct {
+	ID          string       `json:"id"`
+	AlsoKnownAs []string     `json:"alsoKnownAs"`
+	Service     []DIDService `json:"service"`
+	// VerificationMethod lists the public keys the account signs with
+	VerificationMethod []DIDVerificationMethod `json:"verificationMethod"`
+}
+
+// DIDService is a service entry of a DID document
+type DIDService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// DIDVerificationMethod is a public key of a DID document
+type DIDVerificationMethod struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	Controller         string `json:"controller"`
+	PublicKeyMultibase string `json:"publicKeyMultibase"`
+}
+
+// PDSEndpoint returns the URL of the account's PDS, or "" if the document
+// lists none
+func (d *DIDDocument) PDSEndpoint() string {
+	for _, s := range d.Service {
+		if (s.ID == "#atproto_pds" || s.ID == d.ID+"#atproto_pds") && s.Type == "AtprotoPersonalDataServer" {
+			return s.ServiceEndpoint
+		}
+	}
+	return ""
+}
+
+// SigningKey returns the multibase public key the account's repository
+// commits are signed with, or "" if the document lists none
+func (d *DIDDocument) SigningKey() string {
+	for _, m := range d.VerificationMethod {
+		if m.ID == "#atproto" || m.ID == d.ID+"#atproto" {
+			return m.PublicKeyMultibase
+		}
+	}
+	return ""
+}
+
+// HasHandle reports whether the document claims handle
+func (d *DIDDocument) HasHandle(handle string) bool {
+	return slices.ContainsFunc(d.AlsoKnownAs, func(aka string) bool {
+		return strings.EqualFold(aka, "at://"+handle)
+	})
+}
+
+// IdentityResolver resolves handles and DIDs from their own records rather
+// than taking a PDS's word for them
+type IdentityResolver struct {
+	// LookupTXT resolves TXT records; defaults to net.DefaultResolver
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+	// HTTPClient fetches well-known DID files and DID documents
+	HTTPClient *http.Client
+	// PLCDirectory resolves did:plc identifiers
+	PLCDirectory string
+	// Scheme is used for handle and did:web lookups; only tests should
+	// change it
+	Scheme string
+}
+
+// NewIdentityResolver creates a resolver using the system resolver, the
+// public PLC directory and HTTPS
+func NewIdentityResolver() *IdentityResolver {
+	return &IdentityResolver{
+		LookupTXT:    net.DefaultResolver.LookupTXT,
+		HTTPClient:   &http.Client{Timeout: identityTimeout},
+		PLCDirectory: DefaultPLCDirectory,
+		Scheme:       "https",
+	}
+}
+
+// ResolveHandle returns the DID handle claims, from its _atproto TXT record
+// or else its /.well-known/atproto-did file
+func (r *IdentityResolver) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	handle = strings.ToLower(handle)
+	dnsDID, dnsErr := r.resolveHandleDNS(ctx, handle)
+	if dnsErr == nil {
+		return dnsDID, nil
+	}
+	body, err := r.get(ctx, r.Scheme+"://"+handle+"/.well-known/atproto-did")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve handle %s: dns: %v; well-known: %w", handle, dnsErr, err)
+	}
+	did := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(did, "did:") {
+		return "", fmt.Errorf("well-known DID of %s is %q, not a DID", handle, did)
+	}
+	return did, nil
+}
+
+func (r *IdentityResolver) resolveHandleDNS(ctx context.Context, handle string) (string, error) {
+	records, err := r.LookupTXT(ctx, "_atproto."+handle)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range records {
+		if did, ok := strings.CutPrefix(strings.TrimSpace(record), "did="); ok && strings.HasPrefix(did, "did:") {
+			return did, nil
+		}
+	}
+	return "", fmt.Errorf("no did= TXT record")
+}
+
+// ResolveDID fetches the DID document of a did:plc or did:web identifier
+func (r *IdentityResolver) ResolveDID(ctx context.Context, did string) (*DIDDocument, error) {
+	var docURL string
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		docURL = strings.TrimSuffix(r.PLCDirectory, "/") + "/" + did
+	case strings.HasPrefix(did, "did:web:"):
+		// atproto only uses did:web for whole hosts, not paths
+		id := strings.TrimPrefix(did, "did:web:")
+		host, err := url.PathUnescape(id)
+		if err != nil || host == "" || strings.Contains(id, ":") || strings.Contains(host, "/") {
+			return nil, fmt.Errorf("unsupported did:web %q", did)
+		}
+		docURL = r.Scheme + "://" + host + "/.well-known/did.json"
+	default:
+		return nil, fmt.Errorf("unsupported DID method in %q", did)
+	}
+	body, err := r.get(ctx, docURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", did, err)
+	}
+	var doc DIDDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode DID document of %s: %w", did, err)
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("DID document of %s is for %q", did, doc.ID)
+	}
+	return &doc, nil
+}
+
+// SigningKey returns the multibase atproto signing key in did's document,
+// so the resolver can back a jwtutil.DIDKeyCache
+func (r *IdentityResolver) SigningKey(ctx context.Context, did string) (string, error) {
+	doc, err := r.ResolveDID(ctx, did)
+	if err != nil {
+		return "", err
+	}
+	key := doc.SigningKey()
+	if key == "" {
+		return "", fmt.Errorf("DID document of %s lists no signing key", did)
+	}
+	return key, nil
+}
+
+// ResolvePDS returns the URL of the PDS in did's document, which is where
+// the account's repository and blobs are served
+func (r *IdentityResolver) ResolvePDS(ctx context.Context, did string) (string, error) {
+	doc, err := r.ResolveDID(ctx, did)
+	if err != nil {
+		return "", err
+	}
+	endpoint := doc.PDSEndpoint()
+	if endpoint == "" {
+		return "", fmt.Errorf("DID document of %s lists no PDS", did)
+	}
+	return endpoint, nil
+}
+
+// VerifyIdentity checks that handle and did name the same account in both
+// directions, and that the account's PDS trusts the authorization server
+// described by metadata, so a PDS or authorization server cannot sign
+// someone in under a handle that is not theirs. A DID typed in place of a
+// handle skips the handle checks.
+func (r *IdentityResolver) VerifyIdentity(ctx context.Context, handle, did string, metadata *AuthorizationServerMetadata) error {
+	if !strings.HasPrefix(handle, "did:") {
+		resolved, err := r.ResolveHandle(ctx, handle)
+		if err != nil {
+			return err
+		}
+		if resolved != did {
+			return fmt.Errorf("%w: %s resolves to %s, not %s", ErrIdentityMismatch, handle, resolved, did)
+		}
+	} else if handle != did {
+		return fmt.Errorf("%w: signed in as %s, not %s", ErrIdentityMismatch, did, handle)
+	}
+
+	doc, err := r.ResolveDID(ctx, did)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(handle, "did:") && !doc.HasHandle(handle) {
+		return fmt.Errorf("%w: DID document of %s does not claim %s", ErrIdentityMismatch, did, handle)
+	}
+	endpoint := doc.PDSEndpoint()
+	if endpoint == "" {
+		return fmt.Errorf("%w: DID document of %s lists no PDS", ErrIdentityMismatch, did)
+	}
+	resource, err := FetchProtectedResourceMetadata(endpoint)
+	if err != nil {
+		return err
+	}
+	issuer := strings.TrimSuffix(metadata.Issuer, "/")
+	if !slices.ContainsFunc(resource.AuthorizationServers, func(s string) bool { return strings.TrimSuffix(s, "/") == issuer }) {
+		return fmt.Errorf("%w: PDS %s of %s does not use authorization server %s", ErrIdentityMismatch, endpoint, did, metadata.Issuer)
+	}
+	return nil
+}
+
+// get fetches rawURL, failing on any status but 200
+func (r *IdentityResolver) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxIdentityResponse))
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdentityResolverVerifyIdentity(t *testing.T) {
+	const (
+		did    = "did:plc:alice"
+		issuer = "https://entryway.example.com"
+	)
+	var pdsURL, claimedHandle string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + did:
+			_, _ = fmt.Fprintf(w, `{"id":%q,"alsoKnownAs":["at://%s"],"service":[{"id":"#atproto_pds","type":"AtprotoPersonalDataServer","serviceEndpoint":%q}],"verificationMethod":[{"id":"#atproto","type":"Multikey","publicKeyMultibase":"zSigningKey"}]}`, did, claimedHandle, pdsURL)
+		case "/.well-known/atproto-did":
+			_, _ = fmt.Fprintln(w, did)
+		case "/.well-known/oauth-protected-resource":
+			_, _ = fmt.Fprintf(w, `{"resource":%q,"authorization_servers":[%q]}`, pdsURL, issuer)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	pdsURL = srv.URL
+
+	resolver := &IdentityResolver{
+		LookupTXT: func(_ context.Context, name string) ([]string, error) {
+			switch name {
+			case "_atproto.alice.example.com":
+				return []string{"did=" + did}, nil
+			case "_atproto.mallory.example.com":
+				return []string{"did=did:plc:mallory"}, nil
+			}
+			return nil, errors.New("no such host")
+		},
+		HTTPClient:   srv.Client(),
+		PLCDirectory: srv.URL,
+		Scheme:       "http",
+	}
+	ctx := context.Background()
+	metadata := &AuthorizationServerMetadata{Issuer: issuer}
+
+	claimedHandle = "alice.example.com"
+	if err := resolver.VerifyIdentity(ctx, "Alice.example.com", did, metadata); err != nil {
+		t.Errorf("matching identity: %v", err)
+	}
+	if err := resolver.VerifyIdentity(ctx, did, did, metadata); err != nil {
+		t.Errorf("DID typed as the handle: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		handle   string
+		claimed  string
+		metadata *AuthorizationServerMetadata
+	}{
+		"handle resolves elsewhere": {"mallory.example.com", "mallory.example.com", metadata},
+		"DID does not claim handle": {"alice.example.com", "someone.example.com", metadata},
+		"PDS uses another server":   {"alice.example.com", "alice.example.com", &AuthorizationServerMetadata{Issuer: "https://evil.example.com"}},
+		"other DID typed as handle": {"did:plc:bob", "alice.example.com", metadata},
+	} {
+		claimedHandle = tc.claimed
+		if err := resolver.VerifyIdentity(ctx, tc.handle, did, tc.metadata); !errors.Is(err, ErrIdentityMismatch) {
+			t.Errorf("%s: err = %v, want ErrIdentityMismatch", name, err)
+		}
+	}
+
+	// Without a TXT record the handle's well-known file is used
+	if got, err := resolver.ResolveHandle(ctx, strings.TrimPrefix(srv.URL, "http://")); err != nil || got != did {
+		t.Errorf("ResolveHandle from the well-known file = %q, %v", got, err)
+	}
+	if got, err := resolver.SigningKey(ctx, did); err != nil || got != "zSigningKey" {
+		t.Errorf("SigningKey = %q, %v", got, err)
+	}
+	if got, err := resolver.ResolvePDS(ctx, did); err != nil || got != pdsURL {
+		t.Errorf("ResolvePDS = %q, %v", got, err)
+	}
+}
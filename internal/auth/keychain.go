@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+const (
+	// macOSItemNotFound is the exit status security(1) uses for a missing item
+	macOSItemNotFound = 44
+	// keychainUnsafe are the characters that cannot be quoted in a
+	// security(1) interactive command, so are refused in names and IDs
+	keychainUnsafe = "\"\\\n"
+)
+
+// KeychainDPoPKeyStore keeps DPoP keys in the operating system's keychain:
+// the login keychain on macOS through security(1), and the Secret Service
+// (GNOME Keyring, KWallet) on Linux through secret-tool(1). It suits CLI and
+// desktop use, where keys should outlive the process but not sit in files.
+//
+// Items are stored as the key's expiry, in Unix seconds, a space and the
+// encoded key. The keychain cannot be searched by expiry, so items are
+// removed when an expired one is loaded rather than swept.
+type KeychainDPoPKeyStore struct {
+	service string
+	macOS   bool
+	now     func() time.Time
+	// run runs a command with stdin and returns its standard output
+	run func(ctx context.Context, stdin, name string, args ...string) ([]byte, error)
+}
+
+// NewKeychainDPoPKeyStore creates a store that files keys under service in
+// the keychain
+func NewKeychainDPoPKeyStore(service string) (*KeychainDPoPKeyStore, error) {
+	if service == "" || strings.ContainsAny(service, keychainUnsafe) {
+		return nil, fmt.Errorf("invalid keychain service name %q", service)
+	}
+	var tool string
+	switch runtime.GOOS {
+	case "darwin":
+		tool = "security"
+	case "linux", "freebsd", "openbsd":
+		tool = "secret-tool"
+	default:
+		return nil, fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, fmt.Errorf("keychain: %w", err)
+	}
+	return &KeychainDPoPKeyStore{service: service, macOS: tool == "security", now: time.Now, run: runCommand}, nil
+}
+
+func runCommand(ctx context.Context, stdin, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return out, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+// exitCode returns the exit status of a command that ran and failed, or -1
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// Save implements DPoPKeyStore. On macOS the key is passed on standard
+// input so it never appears in the process list.
+func (s *KeychainDPoPKeyStore) Save(ctx context.Context, id string, key *ecdsa.PrivateKey, expires time.Time) error {
+	encoded, err := EncodeDPoPPrivateKeyToPEM(key)
+	if err != nil {
+		return err
+	}
+	value := strconv.FormatInt(expires.Unix(), 10) + " " + encoded
+	if strings.ContainsAny(id, keychainUnsafe) {
+		return fmt.Errorf("invalid DPoP key ID %q", id)
+	}
+	if s.macOS {
+		command := fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -X %s\n", s.service, id, hex.EncodeToString([]byte(value)))
+		_, err = s.run(ctx, command, "security", "-i")
+		return err
+	}
+	_, err = s.run(ctx, value, "secret-tool", "store", "--label="+s.service+" DPoP key", "service", s.service, "account", id)
+	return err
+}
+
+// Load implements DPoPKeyStore
+func (s *KeychainDPoPKeyStore) Load(ctx context.Context, id string) (*ecdsa.PrivateKey, error) {
+	var out []byte
+	var err error
+	if s.macOS {
+		out, err = s.run(ctx, "", "security", "find-generic-password", "-s", s.service, "-a", id, "-w")
+		if exitCode(err) == macOSItemNotFound {
+			return nil, ErrDPoPKeyNotFound
+		}
+	} else {
+		// secret-tool exits 1 without output when nothing matches
+		out, err = s.run(ctx, "", "secret-tool", "lookup", "service", s.service, "account", id)
+		if exitCode(err) == 1 && len(out) == 0 {
+			return nil, ErrDPoPKeyNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	expiry, encoded, ok := strings.Cut(strings.TrimSpace(string(out)), " ")
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("malformed keychain item for DPoP key %q", id)
+	}
+	if !s.now().Before(time.Unix(unix, 0)) {
+		if err := s.Delete(ctx, id); err != nil {
+			logger.Warn("Failed to delete expired DPoP key", "error", err)
+		}
+		return nil, ErrDPoPKeyNotFound
+	}
+	return DecodeDPoPPrivateKeyFromPEM(encoded)
+}
+
+// Delete implements DPoPKeyStore
+func (s *KeychainDPoPKeyStore) Delete(ctx context.Context, id string) error {
+	if s.macOS {
+		_, err := s.run(ctx, "", "security", "delete-generic-password", "-s", s.service, "-a", id)
+		if exitCode(err) == macOSItemNotFound {
+			return nil
+		}
+		return err
+	}
+	_, err := s.run(ctx, "", "secret-tool", "clear", "service", s.service, "account", id)
+	if exitCode(err) == 1 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/keyseal"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// Backend names accepted by the dpop_key_store setting
+const (
+	DPoPKeyStoreCookie   = "cookie"
+	DPoPKeyStoreMemory   = "memory"
+	DPoPKeyStoreFile     = "file"
+	DPoPKeyStoreKeychain = "keychain"
+)
+
+const (
+	// fileKeySecretLabel separates the key that encrypts DPoP key files from
+	// other uses of the same secret
+	fileKeySecretLabel = "dis.quest dpop key file"
+	// dpopKeySweepInterval is how often the memory and file stores remove
+	// the keys of sign-ins that were abandoned and have expired
+	dpopKeySweepInterval = time.Minute
+)
+
+// ErrDPoPKeyNotFound is returned when no DPoP key is stored under an ID
+var ErrDPoPKeyNotFound = errors.New("DPoP key not found")
+
+// DPoPKeyStore keeps DPoP private keys by ID, such as the state of the
+// sign-in they were generated for, until they expire. Sign-ins that are
+// never finished leave their keys behind, so expired keys are not kept.
+type DPoPKeyStore interface {
+	// Save stores key under id until expires, replacing any key already there
+	Save(ctx context.Context, id string, key *ecdsa.PrivateKey, expires time.Time) error
+	// Load returns the key stored under id, or ErrDPoPKeyNotFound when there
+	// is none or it has expired
+	Load(ctx context.Context, id string) (*ecdsa.PrivateKey, error)
+	// Delete removes the key under id. Deleting a missing key is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewDPoPKeyStore creates the store selected by cfg.DPoPKeyStore. It returns
+// nil for "cookie", the default, which keeps a sign-in's key in a cookie.
+func NewDPoPKeyStore(cfg *config.Config) (DPoPKeyStore, error) {
+	switch cfg.DPoPKeyStore {
+	case "", DPoPKeyStoreCookie:
+		return nil, nil
+	case DPoPKeyStoreMemory:
+		return NewMemoryDPoPKeyStore(), nil
+	case DPoPKeyStoreFile:
+		return NewFileDPoPKeyStore(cfg.DPoPKeyDir, []byte(cfg.JWKSPrivate))
+	case DPoPKeyStoreKeychain:
+		return NewKeychainDPoPKeyStore(cfg.AppName)
+	default:
+		return nil, fmt.Errorf("unknown DPoP key store %q", cfg.DPoPKeyStore)
+	}
+}
+
+// MemoryDPoPKeyStore keeps DPoP keys in process memory, so they are lost on
+// restart
+type MemoryDPoPKeyStore struct {
+	mu    sync.Mutex
+	keys  map[string]memoryDPoPKey
+	swept time.Time
+	now   func() time.Time
+}
+
+// memoryDPoPKey is a key held by a MemoryDPoPKeyStore
+type memoryDPoPKey struct {
+	key     *ecdsa.PrivateKey
+	expires time.Time
+}
+
+// NewMemoryDPoPKeyStore creates an empty in-memory store
+func NewMemoryDPoPKeyStore() *MemoryDPoPKeyStore {
+	return &MemoryDPoPKeyStore{keys: make(map[string]memoryDPoPKey), now: time.Now}
+}
+
+// Save implements DPoPKeyStore, first removing expired keys when they have
+// not been swept for a while
+func (s *MemoryDPoPKeyStore) Save(_ context.Context, id string, key *ecdsa.PrivateKey, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); now.Sub(s.swept) >= dpopKeySweepInterval {
+		for id, stored := range s.keys {
+			if !now.Before(stored.expires) {
+				delete(s.keys, id)
+			}
+		}
+		s.swept = now
+	}
+	s.keys[id] = memoryDPoPKey{key: key, expires: expires}
+	return nil
+}
+
+// Load implements DPoPKeyStore
+func (s *MemoryDPoPKeyStore) Load(_ context.Context, id string) (*ecdsa.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.keys[id]
+	if !ok {
+		return nil, ErrDPoPKeyNotFound
+	}
+	if !s.now().Before(stored.expires) {
+		delete(s.keys, id)
+		return nil, ErrDPoPKeyNotFound
+	}
+	return stored.key, nil
+}
+
+// Delete implements DPoPKeyStore
+func (s *MemoryDPoPKeyStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, id)
+	return nil
+}
+
+// FileDPoPKeyStore keeps DPoP keys encrypted in files under a directory
+// only the current user can read, so they survive restarts. Each file's
+// modification time is set to when its key expires.
+type FileDPoPKeyStore struct {
+	dir    string
+	sealer *keyseal.Sealer
+	now    func() time.Time
+
+	mu    sync.Mutex
+	swept time.Time
+}
+
+// NewFileDPoPKeyStore creates a store under dir, creating it if needed.
+// Keys are encrypted with a key derived from secret; changing the secret
+// makes stored keys unreadable.
+func NewFileDPoPKeyStore(dir string, secret []byte) (*FileDPoPKeyStore, error) {
+	if dir == "" {
+		return nil, errors.New("DPoP key directory not set")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	sealer, err := keyseal.New(secret, fileKeySecretLabel)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDPoPKeyStore{dir: dir, sealer: sealer, now: time.Now}, nil
+}
+
+// path returns the file for id. IDs are hashed so any string is a safe name.
+func (s *FileDPoPKeyStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".key")
+}
+
+// Save implements DPoPKeyStore. The ID is authenticated with the key, so a
+// file copied to another ID will not load. Expired key files are removed
+// first when they have not been swept for a while.
+func (s *FileDPoPKeyStore) Save(_ context.Context, id string, key *ecdsa.PrivateKey, expires time.Time) error {
+	s.sweep()
+	sealed, err := s.sealer.Seal(id, key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".key-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(sealed); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), expires, expires); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// sweep removes the files of expired keys, at most once per
+// dpopKeySweepInterval
+func (s *FileDPoPKeyStore) sweep() {
+	now := s.now()
+	s.mu.Lock()
+	if now.Sub(s.swept) < dpopKeySweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.swept = now
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		logger.Warn("Failed to list DPoP key files", "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".key") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Before(info.ModTime()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to remove expired DPoP key file", "error", err)
+		}
+	}
+}
+
+// Load implements DPoPKeyStore
+func (s *FileDPoPKeyStore) Load(ctx context.Context, id string) (*ecdsa.PrivateKey, error) {
+	info, err := os.Stat(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrDPoPKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(info.ModTime()) {
+		_ = s.Delete(ctx, id)
+		return nil, ErrDPoPKeyNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrDPoPKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.sealer.Open(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DPoP key: %w", err)
+	}
+	return key, nil
+}
+
+// Delete implements DPoPKeyStore
+func (s *FileDPoPKeyStore) Delete(_ context.Context, id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDPoPKeyStore exercises store, whose clock reads *now
+func testDPoPKeyStore(t *testing.T, store DPoPKeyStore, now *time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	pair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := now.Add(10 * time.Minute)
+	if _, err := store.Load(ctx, "state-1"); !errors.Is(err, ErrDPoPKeyNotFound) {
+		t.Errorf("Load before Save: err = %v, want ErrDPoPKeyNotFound", err)
+	}
+	if err := store.Save(ctx, "state-1", pair.PrivateKey, expires); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load(ctx, "state-1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !got.Equal(pair.PrivateKey) {
+		t.Error("Load returned another key")
+	}
+	if err := store.Delete(ctx, "state-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Load(ctx, "state-1"); !errors.Is(err, ErrDPoPKeyNotFound) {
+		t.Errorf("Load after Delete: err = %v, want ErrDPoPKeyNotFound", err)
+	}
+	if err := store.Delete(ctx, "state-1"); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+
+	// A key from a sign-in that timed out is gone
+	if err := store.Save(ctx, "state-2", pair.PrivateKey, expires); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	*now = expires
+	if _, err := store.Load(ctx, "state-2"); !errors.Is(err, ErrDPoPKeyNotFound) {
+		t.Errorf("Load after expiry: err = %v, want ErrDPoPKeyNotFound", err)
+	}
+}
+
+func TestMemoryDPoPKeyStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryDPoPKeyStore()
+	store.now = func() time.Time { return now }
+	testDPoPKeyStore(t, store, &now)
+
+	// Abandoned sign-ins are swept on a later Save
+	ctx := context.Background()
+	pair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"abandoned-1", "abandoned-2"} {
+		if err := store.Save(ctx, id, pair.PrivateKey, now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(2 * dpopKeySweepInterval)
+	if err := store.Save(ctx, "fresh", pair.PrivateKey, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 1 {
+		t.Errorf("store holds %d keys after a sweep, want 1", len(store.keys))
+	}
+}
+
+func TestFileDPoPKeyStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	store, err := NewFileDPoPKeyStore(dir, []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewFileDPoPKeyStore: %v", err)
+	}
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	testDPoPKeyStore(t, store, &now)
+
+	ctx := context.Background()
+	pair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Abandoned sign-ins are swept on a later Save
+	for _, id := range []string{"abandoned-1", "abandoned-2"} {
+		if err := store.Save(ctx, id, pair.PrivateKey, now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(2 * dpopKeySweepInterval)
+	if err := store.Save(ctx, "../escape", pair.PrivateKey, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("key directory holds %v, %v; want one file", entries, err)
+	}
+	info, err := entries[0].Info()
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	// Another secret cannot read the key, and a file moved to another ID
+	// does not open
+	other, err := NewFileDPoPKeyStore(dir, []byte("other secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Load(ctx, "../escape"); err == nil {
+		t.Error("key opened with another secret")
+	}
+	if err := os.Rename(store.path("../escape"), store.path("state-2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "state-2"); err == nil {
+		t.Error("key opened under another ID")
+	}
+}
+
+// fakeExit is a command failing with an exit status
+type fakeExit int
+
+func (e fakeExit) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e fakeExit) ExitCode() int { return int(e) }
+
+// fakeKeychain answers secret-tool and security commands from a map
+func fakeKeychain(items map[string]string) func(context.Context, string, string, ...string) ([]byte, error) {
+	return func(_ context.Context, stdin, name string, args ...string) ([]byte, error) {
+		if name == "security" {
+			if len(args) == 1 && args[0] == "-i" {
+				fields := strings.Fields(stdin)
+				account, value := strings.Trim(fields[5], `"`), fields[7]
+				decoded, err := hex.DecodeString(value)
+				if err != nil {
+					return nil, err
+				}
+				items[account] = string(decoded)
+				return nil, nil
+			}
+			account := args[4]
+			if _, ok := items[account]; !ok {
+				return nil, fakeExit(macOSItemNotFound)
+			}
+			if args[0] == "delete-generic-password" {
+				delete(items, account)
+				return nil, nil
+			}
+			return []byte(items[account] + "\n"), nil
+		}
+		account := args[len(args)-1]
+		switch args[0] {
+		case "store":
+			items[account] = stdin
+			return nil, nil
+		case "lookup":
+			if value, ok := items[account]; ok {
+				return []byte(value), nil
+			}
+		case "clear":
+			if _, ok := items[account]; ok {
+				delete(items, account)
+				return nil, nil
+			}
+		}
+		return nil, fakeExit(1)
+	}
+}
+
+func TestKeychainDPoPKeyStore(t *testing.T) {
+	for _, macOS := range []bool{false, true} {
+		t.Run(fmt.Sprintf("macOS=%v", macOS), func(t *testing.T) {
+			now := time.Now()
+			items := map[string]string{}
+			testDPoPKeyStore(t, &KeychainDPoPKeyStore{service: "dis.quest", macOS: macOS, now: func() time.Time { return now }, run: fakeKeychain(items)}, &now)
+			if len(items) != 0 {
+				t.Errorf("keychain still holds %v after loading an expired key", items)
+			}
+		})
+	}
+	store := &KeychainDPoPKeyStore{service: "dis.quest", now: time.Now, run: fakeKeychain(map[string]string{})}
+	pair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(context.Background(), "bad\"id", pair.PrivateKey, time.Now().Add(time.Minute)); err == nil {
+		t.Error("Save accepted an ID with a quote")
+	}
+}
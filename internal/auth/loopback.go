@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+)
+
+// LoopbackHost is where the browser must be for a loopback client's
+// callback to carry its cookies; atproto does not allow "localhost" in
+// loopback redirect URIs
+const LoopbackHost = "127.0.0.1"
+
+// LoopbackClientID returns the client ID of an atproto loopback client
+// that redirects to redirectURL and asks for scopes. Authorization servers
+// accept it without fetching client metadata, so it only suits development.
+func LoopbackClientID(redirectURL string, scopes []string) string {
+	return "http://localhost?" + url.Values{
+		"redirect_uri": {redirectURL},
+		"scope":        {strings.Join(scopes, " ")},
+	}.Encode()
+}
+
+// LoopbackConfig returns a copy of cfg that signs in as a loopback client
+// redirecting to this server on LoopbackHost, instead of the client whose
+// metadata is hosted at OAuthClientID
+func LoopbackConfig(cfg *config.Config) *config.Config {
+	loopback := *cfg
+	loopback.OAuthRedirectURL = "http://" + LoopbackHost + ":" + cfg.Port + "/auth/callback"
+	loopback.OAuthRedirectURLs = ""
+	loopback.OAuthClientID = LoopbackClientID(loopback.OAuthRedirectURL, RequestedScopes)
+	return &loopback
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+)
+
+func TestLoopbackConfig(t *testing.T) {
+	cfg := LoopbackConfig(&config.Config{
+		Port:              "3000",
+		OAuthClientID:     "https://dis.quest/auth/client-metadata.json",
+		OAuthRedirectURL:  "https://dis.quest/auth/callback",
+		OAuthRedirectURLs: "https://staging.dis.quest/auth/callback",
+		OAuthLoopback:     true,
+	})
+	if cfg.OAuthRedirectURL != "http://127.0.0.1:3000/auth/callback" || len(cfg.RedirectURLs()) != 1 {
+		t.Errorf("redirect URLs = %v", cfg.RedirectURLs())
+	}
+
+	clientID, err := url.Parse(cfg.OAuthClientID)
+	if err != nil {
+		t.Fatalf("client ID %q: %v", cfg.OAuthClientID, err)
+	}
+	if clientID.Scheme != "http" || clientID.Host != "localhost" || clientID.Path != "" {
+		t.Errorf("client ID %q is not a loopback client ID", cfg.OAuthClientID)
+	}
+	query := clientID.Query()
+	if query.Get("redirect_uri") != cfg.OAuthRedirectURL || query.Get("scope") != "atproto transition:generic" {
+		t.Errorf("client ID query = %v", query)
+	}
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"golang.org/x/oauth2"
+)
+
+// ErrRevocationUnsupported is returned by RevokeToken when the
+// authorization server has no revocation endpoint
+var ErrRevocationUnsupported = errors.New("authorization server does not support token revocation")
+
+// Provider runs the OAuth flows with an authorization server: sending the
+// user to sign in, exchanging the code for tokens, refreshing and revoking
+// them. PublicClient is the default; a test double or a confidential client
+// can stand in for it.
+type Provider interface {
+	// GetAuthURL returns the authorization endpoint URL for a sign-in with
+	// state and the S256 PKCE codeChallenge
+	GetAuthURL(metadata *AuthorizationServerMetadata, cfg *config.Config, state, codeChallenge string, opts ...oauth2.AuthCodeOption) string
+	// PushedAuthRequest pushes the same authorization request to the
+	// server's pushed authorization request endpoint (RFC 9126), and returns
+	// the authorization endpoint URL that refers to it
+	PushedAuthRequest(ctx context.Context, metadata *AuthorizationServerMetadata, cfg *config.Config, state, codeChallenge string, dpopKey *ecdsa.PrivateKey, opts ...oauth2.AuthCodeOption) (string, error)
+	// ExchangeToken exchanges an authorization code for a grant, which must
+	// be for expectedDID unless it is empty
+	ExchangeToken(ctx context.Context, metadata *AuthorizationServerMetadata, code, codeVerifier string, dpopKey *ecdsa.PrivateKey, expectedDID string, cfg *config.Config) (*Grant, error)
+	// RefreshToken exchanges a refresh token for a new token pair
+	RefreshToken(ctx context.Context, metadata *AuthorizationServerMetadata, refreshToken string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) (*oauth2.Token, error)
+	// RevokeToken revokes an access or refresh token (RFC 7009)
+	RevokeToken(ctx context.Context, metadata *AuthorizationServerMetadata, token string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) error
+}
+
+// PublicClient is the Provider for a public client, as atproto clients
+// without a backend key are: it authenticates with no secret, and binds
+// every token request to a DPoP key
+type PublicClient struct{}
+
+var _ Provider = PublicClient{}
+
+// GetAuthURL implements Provider
+func (PublicClient) GetAuthURL(metadata *AuthorizationServerMetadata, cfg *config.Config, state, codeChallenge string, opts ...oauth2.AuthCodeOption) string {
+	opts = append([]oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}, opts...)
+	return OAuth2Config(metadata, cfg).AuthCodeURL(state, opts...)
+}
+
+// PushedAuthRequest implements Provider
+func (p PublicClient) PushedAuthRequest(ctx context.Context, metadata *AuthorizationServerMetadata, cfg *config.Config, state, codeChallenge string, dpopKey *ecdsa.PrivateKey, opts ...oauth2.AuthCodeOption) (string, error) {
+	if metadata.PushedAuthorizationRequestEndpoint == "" {
+		return "", &MetadataError{Field: "pushed_authorization_request_endpoint", Reason: "is missing"}
+	}
+	// The pushed request carries the parameters the URL would have
+	authURL, err := url.Parse(p.GetAuthURL(metadata, cfg, state, codeChallenge, opts...))
+	if err != nil {
+		return "", err
+	}
+	resp, err := postForm(ctx, metadata, metadata.PushedAuthorizationRequestEndpoint, authURL.Query(), dpopKey)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", endpointError(metadata, resp, "pushed authorization request")
+	}
+	var pushed struct {
+		RequestURI string `json:"request_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pushed); err != nil || pushed.RequestURI == "" {
+		return "", fmt.Errorf("pushed authorization request: response has no request_uri")
+	}
+
+	authURL.RawQuery = url.Values{"client_id": {cfg.OAuthClientID}, "request_uri": {pushed.RequestURI}}.Encode()
+	return authURL.String(), nil
+}
+
+// ExchangeToken implements Provider with ExchangeCodeForTokenWithDPoP
+func (PublicClient) ExchangeToken(ctx context.Context, metadata *AuthorizationServerMetadata, code, codeVerifier string, dpopKey *ecdsa.PrivateKey, expectedDID string, cfg *config.Config) (*Grant, error) {
+	return ExchangeCodeForTokenWithDPoP(ctx, metadata, code, codeVerifier, dpopKey, expectedDID, cfg)
+}
+
+// RefreshToken implements Provider with RefreshTokenWithDPoP
+func (PublicClient) RefreshToken(ctx context.Context, metadata *AuthorizationServerMetadata, refreshToken string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) (*oauth2.Token, error) {
+	return RefreshTokenWithDPoP(ctx, metadata, refreshToken, dpopKey, cfg)
+}
+
+// RevokeToken implements Provider. Servers without a revocation endpoint
+// fail with ErrRevocationUnsupported; their tokens lapse when they expire.
+func (PublicClient) RevokeToken(ctx context.Context, metadata *AuthorizationServerMetadata, token string, dpopKey *ecdsa.PrivateKey, cfg *config.Config) error {
+	if metadata.RevocationEndpoint == "" {
+		return ErrRevocationUnsupported
+	}
+	resp, err := postForm(ctx, metadata, metadata.RevocationEndpoint, url.Values{"token": {token}, "client_id": {cfg.OAuthClientID}}, dpopKey)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return endpointError(metadata, resp, "token revocation")
+	}
+	return nil
+}
+
+// postForm posts form to one of the authorization server's endpoints with a
+// DPoP proof, retrying once with a fresh nonce if the server asks for one
+func postForm(ctx context.Context, metadata *AuthorizationServerMetadata, endpoint string, form url.Values, dpopKey *ecdsa.PrivateKey) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := &http.Client{Transport: &DPoPPKCETransport{
+		Base:      http.DefaultTransport,
+		DPoPKey:   dpopKey,
+		TargetURL: endpoint,
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, pds.Unavailable(metadata.Issuer, nil, err)
+	}
+	return resp, nil
+}
+
+// endpointError describes a refused request to one of the authorization
+// server's endpoints, marking outages with pds.ErrUnavailable
+func endpointError(metadata *AuthorizationServerMetadata, resp *http.Response, what string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	err := fmt.Errorf("%s: status %d: %s", what, resp.StatusCode, strings.TrimSpace(string(body)))
+	return pds.Unavailable(metadata.Issuer, resp, err)
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"golang.org/x/oauth2"
+)
+
+func TestPublicClientGetAuthURL(t *testing.T) {
+	metadata := &AuthorizationServerMetadata{AuthorizationEndpoint: "https://auth.example/oauth/authorize"}
+	cfg := &config.Config{OAuthClientID: "https://dis.quest/client-metadata.json", OAuthRedirectURL: "https://dis.quest/auth/callback"}
+
+	raw := PublicClient{}.GetAuthURL(metadata, cfg, "state-1", "challenge-1", oauth2.SetAuthURLParam("login_hint", "alice.example.com"))
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("GetAuthURL = %q: %v", raw, err)
+	}
+	q := u.Query()
+	for param, want := range map[string]string{
+		"client_id":             cfg.OAuthClientID,
+		"redirect_uri":          cfg.OAuthRedirectURL,
+		"state":                 "state-1",
+		"code_challenge":        "challenge-1",
+		"code_challenge_method": "S256",
+		"login_hint":            "alice.example.com",
+	} {
+		if got := q.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+}
+
+func TestPublicClientPushedAuthRequest(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %v", err)
+		}
+		if r.Header.Get("DPoP") == "" {
+			t.Error("expected DPoP header on pushed authorization request")
+		}
+		if r.PostForm.Get("state") == "refused" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+		if r.PostForm.Get("code_challenge") != "challenge-1" || r.PostForm.Get("response_type") != "code" {
+			t.Errorf("unexpected pushed request: %v", r.PostForm)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"request_uri":"urn:ietf:params:oauth:request_uri:abc","expires_in":60}`))
+	}))
+	defer srv.Close()
+
+	metadata := &AuthorizationServerMetadata{
+		Issuer:                             srv.URL,
+		AuthorizationEndpoint:              srv.URL + "/oauth/authorize",
+		PushedAuthorizationRequestEndpoint: srv.URL + "/oauth/par",
+	}
+	cfg := &config.Config{OAuthClientID: "https://dis.quest/client-metadata.json"}
+	ctx := context.Background()
+
+	raw, err := PublicClient{}.PushedAuthRequest(ctx, metadata, cfg, "state-1", "challenge-1", keypair.PrivateKey)
+	if err != nil {
+		t.Fatalf("PushedAuthRequest error: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("PushedAuthRequest = %q: %v", raw, err)
+	}
+	want := url.Values{"client_id": {cfg.OAuthClientID}, "request_uri": {"urn:ietf:params:oauth:request_uri:abc"}}
+	if u.Path != "/oauth/authorize" || u.Query().Encode() != want.Encode() {
+		t.Errorf("PushedAuthRequest = %q, want the authorization endpoint with only the request URI", raw)
+	}
+
+	if _, err := (PublicClient{}).PushedAuthRequest(ctx, metadata, cfg, "refused", "challenge-1", keypair.PrivateKey); err == nil {
+		t.Error("expected a refused pushed request to fail")
+	}
+	noPAR := &AuthorizationServerMetadata{AuthorizationEndpoint: metadata.AuthorizationEndpoint}
+	if _, err := (PublicClient{}).PushedAuthRequest(ctx, noPAR, cfg, "state-1", "challenge-1", keypair.PrivateKey); !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("err = %v, want ErrInvalidMetadata without a pushed authorization request endpoint", err)
+	}
+}
+
+func TestPublicClientRevokeToken(t *testing.T) {
+	keypair, err := GenerateDPoPKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateDPoPKeyPair error: %v", err)
+	}
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm error: %v", err)
+		}
+		if r.PostForm.Get("token") == "maintenance" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.PostForm.Get("client_id") == "" {
+			t.Error("expected client_id on revocation request")
+		}
+		revoked = append(revoked, r.PostForm.Get("token"))
+	}))
+	defer srv.Close()
+
+	metadata := &AuthorizationServerMetadata{Issuer: srv.URL, RevocationEndpoint: srv.URL + "/oauth/revoke"}
+	cfg := &config.Config{OAuthClientID: "https://dis.quest/client-metadata.json"}
+	ctx := context.Background()
+
+	if err := (PublicClient{}).RevokeToken(ctx, metadata, "refresh-1", keypair.PrivateKey, cfg); err != nil {
+		t.Fatalf("RevokeToken error: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != "refresh-1" {
+		t.Errorf("revoked %v, want refresh-1", revoked)
+	}
+	if err := (PublicClient{}).RevokeToken(ctx, metadata, "maintenance", keypair.PrivateKey, cfg); !pds.IsUnavailable(err) {
+		t.Errorf("err = %v, want an unavailable error", err)
+	}
+	if err := (PublicClient{}).RevokeToken(ctx, &AuthorizationServerMetadata{}, "refresh-1", keypair.PrivateKey, cfg); !errors.Is(err, ErrRevocationUnsupported) {
+		t.Errorf("err = %v, want ErrRevocationUnsupported", err)
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// refreshReuse is how long a finished refresh's tokens are handed to
+// requests still presenting the refresh token it consumed. It covers
+// requests that were already in flight with the old cookie.
+const refreshReuse = 30 * time.Second
+
+// RefreshedTokens is the token pair a refresh returns
+type RefreshedTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// RefreshGroup makes refreshes atomic per refresh token. Authorization
+// servers rotate refresh tokens, so when two requests sharing a session
+// refresh at once the second would present a consumed token and have the
+// session ended. Instead it waits for the first and shares its tokens. It is
+// safe for concurrent use; the zero value is ready to use.
+type RefreshGroup struct {
+	mu    sync.Mutex
+	calls map[[sha256.Size]byte]*refreshCall
+	// now reads the clock; nil uses time.Now
+	now func() time.Time
+}
+
+type refreshCall struct {
+	done     chan struct{}
+	tokens   RefreshedTokens
+	err      error
+	finished time.Time
+}
+
+// Do runs refresh for refreshToken unless a refresh of it is in flight or
+// recently succeeded, in which case it returns that refresh's result.
+// refresh runs without ctx's cancellation, so a caller going away cannot
+// strand a session whose refresh token the server has already rotated.
+func (g *RefreshGroup) Do(ctx context.Context, refreshToken string, refresh func(context.Context) (RefreshedTokens, error)) (RefreshedTokens, error) {
+	key := sha256.Sum256([]byte(refreshToken))
+
+	g.mu.Lock()
+	now := g.clock()
+	if g.calls == nil {
+		g.calls = make(map[[sha256.Size]byte]*refreshCall)
+	}
+	for k, call := range g.calls {
+		if !call.finished.IsZero() && now.Sub(call.finished) > refreshReuse {
+			delete(g.calls, k)
+		}
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &refreshCall{done: make(chan struct{})}
+		g.calls[key] = call
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		tokens, err := refresh(context.WithoutCancel(ctx))
+		g.mu.Lock()
+		call.tokens, call.err = tokens, err
+		if err != nil {
+			// A failed refresh may be retried with the same token
+			delete(g.calls, key)
+		} else {
+			call.finished = g.clock()
+		}
+		g.mu.Unlock()
+		close(call.done)
+		return tokens, err
+	}
+
+	select {
+	case <-call.done:
+		return call.tokens, call.err
+	case <-ctx.Done():
+		return RefreshedTokens{}, ctx.Err()
+	}
+}
+
+func (g *RefreshGroup) clock() time.Time {
+	if g.now != nil {
+		return g.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRefreshGroup refreshes one session from many goroutines, as
+// concurrent requests sharing a session do; run it with -race
+func TestRefreshGroup(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	group := &RefreshGroup{now: func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	refresh := func(context.Context) (RefreshedTokens, error) {
+		n := calls.Add(1)
+		<-release
+		return RefreshedTokens{AccessToken: "access", RefreshToken: string(rune('0' + n))}, nil
+	}
+
+	const workers = 16
+	ctx := context.Background()
+	results := make(chan RefreshedTokens, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens, err := group.Do(ctx, "refresh-1", refresh)
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+			results <- tokens
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+	for tokens := range results {
+		if tokens.RefreshToken != "1" {
+			t.Errorf("got refresh token %q, want the first refresh's", tokens.RefreshToken)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("refreshed %d times, want 1", calls.Load())
+	}
+
+	// A request still holding the consumed token shortly after gets the same
+	// tokens; once the reuse window passes it refreshes again
+	if tokens, _ := group.Do(ctx, "refresh-1", refresh); tokens.RefreshToken != "1" || calls.Load() != 1 {
+		t.Errorf("late request got %+v after %d refreshes", tokens, calls.Load())
+	}
+	mu.Lock()
+	now = now.Add(refreshReuse + time.Second)
+	mu.Unlock()
+	if tokens, _ := group.Do(ctx, "refresh-1", refresh); tokens.RefreshToken != "2" {
+		t.Errorf("after the reuse window got %+v", tokens)
+	}
+
+	// Failures are shared with waiters but not remembered
+	failure := errors.New("PDS unavailable")
+	if _, err := group.Do(ctx, "refresh-2", func(context.Context) (RefreshedTokens, error) {
+		return RefreshedTokens{}, failure
+	}); !errors.Is(err, failure) {
+		t.Errorf("err = %v, want the refresh's", err)
+	}
+	if tokens, err := group.Do(ctx, "refresh-2", refresh); err != nil || tokens.RefreshToken != "3" {
+		t.Errorf("retry after failure = %+v, %v", tokens, err)
+	}
+}
+
+func TestRefreshGroupCancelledWaiter(t *testing.T) {
+	var group RefreshGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	var refreshCtx context.Context
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(done)
+		_, _ = group.Do(ctx, "refresh", func(ctx context.Context) (RefreshedTokens, error) {
+			refreshCtx = ctx
+			close(started)
+			<-release
+			return RefreshedTokens{AccessToken: "a"}, nil
+		})
+	}()
+	<-started
+
+	waiterCtx, cancelWaiter := context.WithCancel(context.Background())
+	cancelWaiter()
+	if _, err := group.Do(waiterCtx, "refresh", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled waiter: err = %v", err)
+	}
+	// The refresh itself outlives its caller's cancellation
+	cancel()
+	if refreshCtx.Err() != nil {
+		t.Error("refresh context was cancelled with its caller")
+	}
+	close(release)
+	<-done
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -14,6 +15,8 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/pds"
 )
 
 // CreateSessionRequest represents a session creation request
@@ -31,8 +34,8 @@ type CreateSessionResponse struct {
 }
 
 // CreateSession calls the ATProto createSession endpoint with handle and app password
-func CreateSession(pds, handle, password string) (*CreateSessionResponse, error) {
-	url := fmt.Sprintf("%s/xrpc/com.atproto.server.createSession", pds)
+func CreateSession(host, handle, password string) (*CreateSessionResponse, error) {
+	url := fmt.Sprintf("%s/xrpc/com.atproto.server.createSession", host)
 	body, _ := json.Marshal(CreateSessionRequest{
 		Identifier: handle,
 		Password:   password,
@@ -45,10 +48,13 @@ func CreateSession(pds, handle, password string) (*CreateSessionResponse, error)
 	// TODO: refactor to allow injecting an HTTP client so this can be tested without network access
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, pds.Unavailable(host, nil, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 	if resp.StatusCode != 200 {
+		if err := pds.Unavailable(host, resp, nil); err != nil {
+			return nil, err
+		}
 		return nil, ErrInvalidCredentials
 	}
 	var out CreateSessionResponse
@@ -58,6 +64,64 @@ func CreateSession(pds, handle, password string) (*CreateSessionResponse, error)
 	return &out, nil
 }
 
+// RefreshSession calls the ATProto refreshSession endpoint to rotate an
+// app-password session. The refresh JWT is consumed; use the returned pair.
+func RefreshSession(ctx context.Context, host, refreshJwt string) (*CreateSessionResponse, error) {
+	url := fmt.Sprintf("%s/xrpc/com.atproto.server.refreshSession", host)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshJwt)
+	metrics := pds.Metrics()
+	metrics.CountRefresh()
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		metrics.ObserveRequest("com.atproto.server.refreshSession", 0, time.Since(start))
+		return nil, pds.Unavailable(host, nil, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	metrics.ObserveRequest("com.atproto.server.refreshSession", resp.StatusCode, time.Since(start))
+	if err := pds.Unavailable(host, resp, nil); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrRefreshRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("refreshSession returned status %d", resp.StatusCode)
+	}
+	var out CreateSessionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSession calls the ATProto deleteSession endpoint to end an
+// app-password session, revoking its refresh JWT
+func DeleteSession(ctx context.Context, host, refreshJwt string) error {
+	url := fmt.Sprintf("%s/xrpc/com.atproto.server.deleteSession", host)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshJwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return pds.Unavailable(host, nil, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := pds.Unavailable(host, resp, nil); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("deleteSession returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // DPoPKeyPair holds an ECDSA P-256 keypair for DPoP
 // Only the private key is needed to sign DPoP JWTs; public key is used for JWK
 type DPoPKeyPair struct {
@@ -98,21 +162,45 @@ func DecodeDPoPPrivateKeyFromPEM(pemStr string) (*ecdsa.PrivateKey, error) {
 
 // DPoPPublicJWK returns the public key as a JWK map (for DPoP JWT header)
 func (k *DPoPKeyPair) DPoPPublicJWK() map[string]interface{} {
-	pub := k.PrivateKey.PublicKey
+	return publicJWK(&k.PrivateKey.PublicKey)
+}
+
+// publicJWK returns pub as a JWK map
+func publicJWK(pub *ecdsa.PublicKey) map[string]interface{} {
 	return map[string]interface{}{
 		"kty": "EC",
 		"crv": "P-256",
-		"x":   base64.RawURLEncoding.EncodeToString(pub.X.Bytes()),
-		"y":   base64.RawURLEncoding.EncodeToString(pub.Y.Bytes()),
+		"x":   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, p256CoordinateSize))),
+		"y":   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, p256CoordinateSize))),
 		"alg": "ES256",
 		"use": "sig",
 	}
 }
 
+// p256CoordinateSize is the fixed width of P-256 coordinates and signature
+// halves. JWKs and JWS signatures require it, so leading zeros must be kept.
+const p256CoordinateSize = 32
+
+// JWKThumbprint returns the RFC 7638 SHA-256 thumbprint of the public key,
+// the value an authorization server binds tokens to as cnf.jkt
+func (k *DPoPKeyPair) JWKThumbprint() string {
+	return jwkThumbprint(&k.PrivateKey.PublicKey)
+}
+
+// jwkThumbprint returns the RFC 7638 SHA-256 thumbprint of pub
+func jwkThumbprint(pub *ecdsa.PublicKey) string {
+	jwk := publicJWK(pub)
+	// Required members only, in lexicographic order, without whitespace
+	canonical := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk["crv"], jwk["kty"], jwk["x"], jwk["y"])
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
 const dpopKeyCookieName = "dpop_key"
 
 // SetDPoPKeyCookie stores the DPoP private key in a secure, HttpOnly cookie
-func SetDPoPKeyCookie(w http.ResponseWriter, key *ecdsa.PrivateKey, isDev bool) error {
+// that expires after lifetime, or with the browser session when lifetime is 0
+func SetDPoPKeyCookie(w http.ResponseWriter, key *ecdsa.PrivateKey, lifetime time.Duration, isDev bool) error {
 	pemStr, err := EncodeDPoPPrivateKeyToPEM(key)
 	if err != nil {
 		return err
@@ -125,11 +213,25 @@ func SetDPoPKeyCookie(w http.ResponseWriter, key *ecdsa.PrivateKey, isDev bool)
 		HttpOnly: true,
 		Secure:   secure,
 		SameSite: http.SameSiteLaxMode,
-		// Optionally: Short expiry, e.g. 10 min
+		MaxAge:   int(lifetime / time.Second),
 	})
 	return nil
 }
 
+// ClearDPoPKeyCookie removes the DPoP key cookie once sign-in has stored the
+// key elsewhere
+func ClearDPoPKeyCookie(w http.ResponseWriter, isDev bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     dpopKeyCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   !isDev,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 // GetDPoPKeyFromCookie retrieves and decodes the DPoP private key from the cookie
 func GetDPoPKeyFromCookie(r *http.Request) (*ecdsa.PrivateKey, error) {
 	cookie, err := r.Cookie(dpopKeyCookieName)
@@ -153,6 +255,8 @@ type DPoPJWTPayload struct {
 	HTU   string `json:"htu"`
 	IAT   int64  `json:"iat"`
 	Nonce string `json:"nonce,omitempty"`
+	// ATH is the hash of the access token, required when calling a resource server
+	ATH string `json:"ath,omitempty"`
 }
 
 // CreateDPoPJWT creates a DPoP JWT for the given HTTP method and URL
@@ -162,6 +266,19 @@ func CreateDPoPJWT(key *ecdsa.PrivateKey, method, targetURL string) (string, err
 
 // CreateDPoPJWTWithNonce creates a DPoP JWT for the given HTTP method and URL with optional nonce
 func CreateDPoPJWTWithNonce(key *ecdsa.PrivateKey, method, targetURL, nonce string) (string, error) {
+	return CreateDPoPJWTWithAccessToken(key, method, targetURL, nonce, "")
+}
+
+// CreateDPoPJWTWithAccessToken creates a DPoP JWT bound to an access token,
+// as resource servers such as a PDS require. An empty accessToken omits the
+// binding, for requests to the authorization server.
+func CreateDPoPJWTWithAccessToken(key *ecdsa.PrivateKey, method, targetURL, nonce, accessToken string) (string, error) {
+	return CreateDPoPProof(context.Background(), KeySigner(key), method, targetURL, nonce, accessToken)
+}
+
+// CreateDPoPProof creates a DPoP JWT like CreateDPoPJWTWithAccessToken,
+// signed by signer
+func CreateDPoPProof(ctx context.Context, signer Signer, method, targetURL, nonce, accessToken string) (string, error) {
 	// Parse the URL to get the scheme, host, and path (no query or fragment)
 	u, err := url.Parse(targetURL)
 	if err != nil {
@@ -171,14 +288,11 @@ func CreateDPoPJWTWithNonce(key *ecdsa.PrivateKey, method, targetURL, nonce stri
 	// HTU should be scheme + host + path (no query or fragment)
 	htu := fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, u.Path)
 	
-	// Create the key pair wrapper to get JWK
-	keyPair := &DPoPKeyPair{PrivateKey: key}
-	
 	// Create header
 	header := DPoPJWTHeader{
 		Typ: "dpop+jwt",
 		Alg: "ES256",
-		JWK: keyPair.DPoPPublicJWK(),
+		JWK: publicJWK(signer.Public()),
 	}
 	
 	// Generate random JTI (nonce)
@@ -196,6 +310,10 @@ func CreateDPoPJWTWithNonce(key *ecdsa.PrivateKey, method, targetURL, nonce stri
 		IAT:   time.Now().Unix(),
 		Nonce: nonce,
 	}
+	if accessToken != "" {
+		ath := sha256.Sum256([]byte(accessToken))
+		payload.ATH = base64.RawURLEncoding.EncodeToString(ath[:])
+	}
 	
 	// Encode header and payload
 	headerBytes, err := json.Marshal(header)
@@ -216,14 +334,42 @@ func CreateDPoPJWTWithNonce(key *ecdsa.PrivateKey, method, targetURL, nonce stri
 	
 	// Sign
 	hash := sha256.Sum256([]byte(signingInput))
-	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	signature, err := signer.Sign(ctx, hash[:])
 	if err != nil {
 		return "", fmt.Errorf("failed to sign DPoP JWT: %w", err)
 	}
 	
 	// Encode signature
-	signature := append(r.Bytes(), s.Bytes()...)
 	signatureEncoded := base64.RawURLEncoding.EncodeToString(signature)
 	
 	return signingInput + "." + signatureEncoded, nil
 }
+
+// DPoPAuthorizer authorizes PDS requests with a DPoP-bound access token.
+// It never changes once built, so one may be shared by concurrent requests;
+// to use refreshed tokens, build a new one.
+type DPoPAuthorizer struct {
+	AccessToken string
+	Key         *ecdsa.PrivateKey
+	// Signer, when set, signs proofs instead of Key
+	Signer Signer
+}
+
+// Authorize sets the DPoP Authorization and proof headers on req
+func (a DPoPAuthorizer) Authorize(req *http.Request, nonce string) error {
+	proof, err := CreateDPoPProof(req.Context(), signerOrKey(a.Signer, a.Key), req.Method, req.URL.String(), nonce, a.AccessToken)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "DPoP "+a.AccessToken)
+	req.Header.Set("DPoP", proof)
+	return nil
+}
+
+// signerOrKey returns signer, or a KeySigner for key when signer is nil
+func signerOrKey(signer Signer, key *ecdsa.PrivateKey) Signer {
+	if signer != nil {
+		return signer
+	}
+	return KeySigner(key)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrUnsupportedKey is returned for signing keys that are not ECDSA P-256,
+// the only curve ES256 allows
+var ErrUnsupportedKey = errors.New("signing key is not an ECDSA P-256 key")
+
+// Signer signs DPoP proofs with a P-256 key. The key need not be in memory:
+// a Signer may forward digests to AWS KMS, HashiCorp Vault or a hardware
+// token. KeySigner is the in-memory default.
+type Signer interface {
+	// Public returns the public half of the key
+	Public() *ecdsa.PublicKey
+	// Sign returns the ES256 signature, r||s, of a SHA-256 digest
+	Sign(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// KeySigner returns a Signer for a key held in memory
+func KeySigner(key *ecdsa.PrivateKey) Signer {
+	return keySigner{key: key}
+}
+
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s keySigner) Public() *ecdsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s keySigner) Sign(_ context.Context, digest []byte) ([]byte, error) {
+	r, sv, err := ecdsa.Sign(rand.Reader, s.key, digest)
+	if err != nil {
+		return nil, err
+	}
+	return fixedSignature(r, sv), nil
+}
+
+// CryptoSigner adapts a crypto.Signer holding a P-256 key, as the KMS and
+// PKCS#11 libraries provide, to a Signer. crypto.Signer takes no context, so
+// a slow backend cannot be cancelled through Sign's ctx.
+func CryptoSigner(signer crypto.Signer) (Signer, error) {
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() {
+		return nil, ErrUnsupportedKey
+	}
+	return cryptoSigner{signer: signer, pub: pub}, nil
+}
+
+type cryptoSigner struct {
+	signer crypto.Signer
+	pub    *ecdsa.PublicKey
+}
+
+func (s cryptoSigner) Public() *ecdsa.PublicKey {
+	return s.pub
+}
+
+// Sign converts the ASN.1 signature crypto.Signer returns for ECDSA keys to
+// the fixed-width form JWS uses
+func (s cryptoSigner) Sign(_ context.Context, digest []byte) ([]byte, error) {
+	der, err := s.signer.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("signer returned a malformed ECDSA signature: %w", err)
+	}
+	return fixedSignature(sig.R, sig.S), nil
+}
+
+// fixedSignature encodes r and s as the 64-byte r||s ES256 signature
+func fixedSignature(r, s *big.Int) []byte {
+	signature := make([]byte, 2*p256CoordinateSize)
+	r.FillBytes(signature[:p256CoordinateSize])
+	s.FillBytes(signature[p256CoordinateSize:])
+	return signature
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+// remoteKey stands in for a key held by a KMS: it signs through
+// crypto.Signer and never hands out the private key
+type remoteKey struct {
+	key   *ecdsa.PrivateKey
+	calls int
+}
+
+func (k *remoteKey) Public() crypto.PublicKey { return &k.key.PublicKey }
+
+func (k *remoteKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	k.calls++
+	return k.key.Sign(rand, digest, opts)
+}
+
+func TestCryptoSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &remoteKey{key: key}
+	signer, err := CryptoSigner(remote)
+	if err != nil {
+		t.Fatalf("CryptoSigner: %v", err)
+	}
+
+	proof, err := CreateDPoPProof(context.Background(), signer, "POST", "https://pds.example.com/xrpc/x", "n1", "token")
+	if err != nil {
+		t.Fatalf("CreateDPoPProof: %v", err)
+	}
+	jwk, payload := verifyProof(t, proof)
+	if thumbprint(t, jwk) != (&DPoPKeyPair{PrivateKey: key}).JWKThumbprint() {
+		t.Error("proof header carries another key")
+	}
+	if payload.Nonce != "n1" || payload.ATH == "" || remote.calls != 1 {
+		t.Errorf("payload = %+v after %d signatures", payload, remote.calls)
+	}
+
+	// DPoPAuthorizer prefers its Signer to its Key
+	req := httptest.NewRequest("GET", "https://pds.example.com/xrpc/y", nil)
+	if err := (DPoPAuthorizer{AccessToken: "token", Signer: signer}).Authorize(req, ""); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	verifyProof(t, req.Header.Get("DPoP"))
+	if remote.calls != 2 {
+		t.Errorf("remote key signed %d times, want 2", remote.calls)
+	}
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CryptoSigner(p384); !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("P-384 key: err = %v, want ErrUnsupportedKey", err)
+	}
+}
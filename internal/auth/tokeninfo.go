@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/jwtutil"
+)
+
+// SessionToken is what dis.quest reads from a session's access token. The
+// token is not verified here: the PDS verifies it on every use, and the
+// server-side session decides whether it still counts.
+type SessionToken struct {
+	// Token is the access token itself
+	Token string
+	// DID is the account the token was issued to
+	DID string
+	// PDS is the token's issuer
+	PDS   string
+	Scope string
+	// ExpiresAt is zero when the token has no expiry
+	ExpiresAt time.Time
+	// Jkt is the thumbprint of the DPoP key the token is bound to, empty
+	// for app-password sessions
+	Jkt string
+}
+
+// TokenInfo reads the access token in the request's session cookie. Without
+// the cookie it fails with http.ErrNoCookie.
+func TokenInfo(r *http.Request) (*SessionToken, error) {
+	accessToken, err := GetSessionCookie(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTokenInfo(accessToken)
+}
+
+// ParseTokenInfo reads an access token like TokenInfo. Tokens without a
+// subject fail with jwtutil.ErrMissingSubject.
+func ParseTokenInfo(accessToken string) (*SessionToken, error) {
+	claims, err := jwtutil.ParseClaims(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Sub == "" {
+		return nil, jwtutil.ErrMissingSubject
+	}
+	info := &SessionToken{
+		Token: accessToken,
+		DID:   claims.Sub,
+		PDS:   claims.Iss,
+		Scope: claims.Scope,
+		Jkt:   claims.Jkt,
+	}
+	if claims.Exp > 0 {
+		info.ExpiresAt = time.Unix(claims.Exp, 0).UTC()
+	}
+	return info, nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/jwtutil"
+)
+
+// unsignedToken builds a token with payload as its claims; TokenInfo does
+// not look at signatures
+func unsignedToken(payload string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"ES256K"}`)) + "." + enc([]byte(payload)) + ".sig"
+}
+
+func TestTokenInfo(t *testing.T) {
+	token := unsignedToken(`{"iss":"https://pds.example","sub":"did:plc:alice","scope":"atproto transition:generic","exp":1700000000,"cnf":{"jkt":"thumb"}}`)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+
+	info, err := TokenInfo(r)
+	if err != nil {
+		t.Fatalf("TokenInfo: %v", err)
+	}
+	want := SessionToken{
+		Token:     token,
+		DID:       "did:plc:alice",
+		PDS:       "https://pds.example",
+		Scope:     "atproto transition:generic",
+		ExpiresAt: time.Unix(1700000000, 0).UTC(),
+		Jkt:       "thumb",
+	}
+	if *info != want {
+		t.Errorf("TokenInfo = %+v, want %+v", *info, want)
+	}
+
+	if _, err := TokenInfo(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, http.ErrNoCookie) {
+		t.Errorf("TokenInfo without a cookie: error = %v, want http.ErrNoCookie", err)
+	}
+	if info, err := ParseTokenInfo(unsignedToken(`{"sub":"did:plc:alice"}`)); err != nil || !info.ExpiresAt.IsZero() {
+		t.Errorf("ParseTokenInfo = %+v, %v, want a zero expiry", info, err)
+	}
+	if _, err := ParseTokenInfo(unsignedToken(`{"iss":"https://pds.example"}`)); !errors.Is(err, jwtutil.ErrMissingSubject) {
+		t.Errorf("ParseTokenInfo without sub: error = %v, want ErrMissingSubject", err)
+	}
+	if _, err := ParseTokenInfo("not-a-token"); !errors.Is(err, jwtutil.ErrInvalidToken) {
+		t.Errorf("ParseTokenInfo of garbage: error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package blobstore stores uploaded and cached binary objects, such as
+// attachments and link preview images, on local disk or in an S3-compatible
+// bucket
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+)
+
+// Backend names accepted by the blob_store setting
+const (
+	BackendDisk = "disk"
+	BackendS3   = "s3"
+)
+
+var (
+	// ErrNotFound is returned when no object is stored under a key
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys that are empty, absolute or escape
+	// the store with ".." segments
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Object is a stored blob. Callers must close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Store saves and retrieves blobs by key. Keys are slash-separated relative
+// paths such as "attachments/did:plc:abc/3k2…".
+type Store interface {
+	// Put stores body under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get returns the object stored under key, or ErrNotFound
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the store selected by cfg.BlobStore
+func New(cfg *config.Config) (Store, error) {
+	switch cfg.BlobStore {
+	case "", BackendDisk:
+		return NewDisk(cfg.BlobDir)
+	case BackendS3:
+		return NewS3(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PathStyle:       cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+	}
+}
+
+// cleanKey validates key and returns it in canonical form
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsRune(key, '\\') {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return path.Clean(key), nil
+}
@@ -0,0 +1,108 @@
+package blobstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Disk stores blobs as files under a root directory. Content types are not
+// persisted; Get sniffs them from the file contents.
+type Disk struct {
+	root string
+}
+
+// NewDisk creates a disk store rooted at dir, creating it if needed
+func NewDisk(dir string) (*Disk, error) {
+	if dir == "" {
+		return nil, errors.New("blob directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Disk{root: dir}, nil
+}
+
+// Put writes body to a temporary file and renames it into place, so readers
+// never see a partial object
+func (d *Disk) Put(_ context.Context, key string, body io.Reader, _ string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get opens the file stored under key
+func (d *Disk) Get(_ context.Context, key string) (*Object, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(f, 512)
+	head, _ := r.Peek(512)
+	return &Object{
+		Body:        readCloser{Reader: r, Closer: f},
+		ContentType: http.DetectContentType(head),
+		Size:        info.Size(),
+	}, nil
+}
+
+// Delete removes the file stored under key
+func (d *Disk) Delete(_ context.Context, key string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d *Disk) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,124 @@
+// Package broker relays live events between instances of the app that
+// share a database, so a client connected to one instance sees events
+// published on another. Stored state is coordinated through the database;
+// the broker only carries notifications and may drop them while it is
+// unavailable.
+package broker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const (
+	// outboxSize is how many payloads may wait to be published before new
+	// ones are dropped
+	outboxSize = 1024
+	// resubscribeDelay is the wait before the first resubscribe; it doubles
+	// with each failed attempt up to maxResubscribeDelay
+	resubscribeDelay    = time.Second
+	maxResubscribeDelay = time.Minute
+)
+
+// ErrOutboxFull is passed to a Relay's onError when a payload is dropped
+// because the broker is not keeping up
+var ErrOutboxFull = errors.New("broker: outbox full, event dropped")
+
+// Broker is a publish/subscribe channel shared by every instance
+type Broker interface {
+	// Publish sends payload to every subscriber of channel, on any instance
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe calls fn for each payload published on channel until ctx
+	// is done or the connection fails
+	Subscribe(ctx context.Context, channel string, fn func(payload []byte)) error
+}
+
+// Relay connects one in-process fan-out to a broker channel. Payloads are
+// tagged with a random instance ID so an instance does not receive its own
+// events back; it has already delivered them locally.
+type Relay struct {
+	broker  Broker
+	channel string
+	id      []byte
+	outbox  chan []byte
+}
+
+// NewRelay returns a relay over channel
+func NewRelay(b Broker, channel string) *Relay {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return &Relay{
+		broker:  b,
+		channel: channel,
+		id:      []byte(hex.EncodeToString(id[:])),
+		outbox:  make(chan []byte, outboxSize),
+	}
+}
+
+// Send queues payload for other instances without blocking. It reports
+// false when the outbox is full and the payload was dropped.
+func (r *Relay) Send(payload []byte) bool {
+	frame := make([]byte, 0, len(r.id)+1+len(payload))
+	frame = append(append(append(frame, r.id...), '\n'), payload...)
+	select {
+	case r.outbox <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run publishes queued payloads and passes payloads from other instances
+// to receive until ctx is done. Errors, including a dropped subscription,
+// are passed to onError; the subscription is retried with backoff.
+func (r *Relay) Run(ctx context.Context, receive func(payload []byte), onError func(error)) {
+	go r.subscribe(ctx, receive, onError)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame := <-r.outbox:
+			if err := r.broker.Publish(ctx, r.channel, frame); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (r *Relay) subscribe(ctx context.Context, receive func(payload []byte), onError func(error)) {
+	delay := resubscribeDelay
+	for {
+		received := false
+		err := r.broker.Subscribe(ctx, r.channel, func(frame []byte) {
+			received = true
+			id, payload, ok := bytes.Cut(frame, []byte{'\n'})
+			if !ok || bytes.Equal(id, r.id) {
+				return
+			}
+			receive(payload)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			onError(err)
+		}
+		if received {
+			delay = resubscribeDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
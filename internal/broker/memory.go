@@ -0,0 +1,59 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-process Broker, for tests and for wiring several hubs
+// together inside one process
+type Memory struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+// NewMemory returns an empty in-process broker
+func NewMemory() *Memory {
+	return &Memory{subscribers: make(map[string]map[chan []byte]struct{})}
+}
+
+// Publish delivers payload to the channel's current subscribers
+func (m *Memory) Publish(_ context.Context, channel string, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.subscribers[channel] {
+		select {
+		case ch <- payload:
+		default:
+			// Like Redis, a subscriber that cannot keep up loses messages
+		}
+	}
+	return nil
+}
+
+// Subscribe calls fn for each payload published on channel until ctx is done
+func (m *Memory) Subscribe(ctx context.Context, channel string, fn func(payload []byte)) error {
+	ch := make(chan []byte, outboxSize)
+	m.mu.Lock()
+	if m.subscribers[channel] == nil {
+		m.subscribers[channel] = make(map[chan []byte]struct{})
+	}
+	m.subscribers[channel][ch] = struct{}{}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.subscribers[channel], ch)
+		m.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case payload := <-ch:
+			fn(payload)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds dialing and each publish when ctx has no deadline
+const redisTimeout = 5 * time.Second
+
+// Redis is a Broker backed by Redis pub/sub. It speaks just enough of the
+// RESP protocol to authenticate, publish and subscribe; publishes share one
+// connection and each subscription opens its own.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	tls      *tls.Config
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis returns a broker for a redis:// or rediss:// (TLS) URL, e.g.
+// redis://:password@localhost:6379. The URL's database number is ignored
+// since pub/sub channels are shared across databases.
+func NewRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	r := &Redis{}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("invalid redis URL: unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("invalid redis URL: missing host")
+	}
+	r.addr = u.Host
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	return r, nil
+}
+
+// Publish sends payload on channel over the shared publishing connection,
+// reconnecting if the previous publish broke it
+func (r *Redis) Publish(ctx context.Context, channel string, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		conn, reader, err := r.dial(ctx)
+		if err != nil {
+			return err
+		}
+		r.conn, r.reader = conn, reader
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	_ = r.conn.SetDeadline(deadline)
+	if _, err := r.command(r.conn, r.reader, "PUBLISH", []byte(channel), payload); err != nil {
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			_ = r.conn.Close()
+			r.conn, r.reader = nil, nil
+		}
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe listens on channel over a dedicated connection until ctx is
+// done or the connection fails
+func (r *Redis) Subscribe(ctx context.Context, channel string, fn func(payload []byte)) error {
+	conn, reader, err := r.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if err := writeCommand(conn, "SUBSCRIBE", []byte(channel)); err != nil {
+		return fmt.Errorf("redis subscribe: %w", err)
+	}
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("redis subscribe: %w", err)
+		}
+		// Pushes are [kind, channel, data]; kind is "subscribe" when the
+		// subscription is confirmed and "message" for each payload
+		push, ok := reply.([]any)
+		if !ok || len(push) != 3 {
+			continue
+		}
+		if kind, _ := push[0].([]byte); string(kind) == "message" {
+			if payload, ok := push[2].([]byte); ok {
+				fn(payload)
+			}
+		}
+	}
+}
+
+// Close closes the publishing connection
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn, r.reader = nil, nil
+	return err
+}
+
+// dial connects and authenticates
+func (r *Redis) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if r.tls != nil {
+		dialer := tls.Dialer{Config: r.tls}
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("redis dial: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+
+	if r.password != "" {
+		deadline, _ := ctx.Deadline()
+		_ = conn.SetDeadline(deadline)
+		args := [][]byte{[]byte(r.password)}
+		if r.username != "" {
+			args = [][]byte{[]byte(r.username), []byte(r.password)}
+		}
+		if _, err := r.command(conn, reader, "AUTH", args...); err != nil {
+			_ = conn.Close()
+			return nil, nil, fmt.Errorf("redis auth: %w", err)
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return conn, reader, nil
+}
+
+// command sends a command and reads its reply
+func (r *Redis) command(conn net.Conn, reader *bufio.Reader, name string, args ...[]byte) (any, error) {
+	if err := writeCommand(conn, name, args...); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(reader)
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// writeCommand writes a command as a RESP array of bulk strings
+func writeCommand(w io.Writer, name string, args ...[]byte) error {
+	buf := fmt.Appendf(nil, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(name), name)
+	for _, arg := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n", len(arg))
+		buf = append(append(buf, arg...), '\r', '\n')
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readReply reads one RESP value: a string, redisError, int64, []byte
+// (nil for a null bulk string) or []any
+func readReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("malformed reply")
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, errors.New("malformed bulk length")
+		}
+		if n == -1 {
+			return []byte(nil), nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < -1 {
+			return nil, errors.New("malformed array length")
+		}
+		if n == -1 {
+			return []any(nil), nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply type %q", kind)
+	}
+}
@@ -0,0 +1,104 @@
+// Package clock provides the time source and record key generator used by
+// handlers, sessions and repositories, so expiry, rkey and cleanup logic can
+// be tested deterministically
+package clock
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock
+type System struct{}
+
+// Now returns time.Now()
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a clock that only moves when told to. It is meant for tests.
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManual creates a manual clock reading t
+func NewManual(t time.Time) *Manual {
+	return &Manual{now: t}
+}
+
+// Now returns the clock's current reading
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set moves the clock to t
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+}
+
+// Advance moves the clock forward by d
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
+
+// IDGenerator creates record keys for new records
+type IDGenerator interface {
+	// NewID returns a key starting with prefix, e.g. "topic-…"
+	NewID(prefix string) string
+}
+
+// NanoIDs generates "<prefix>-<unix nanoseconds>" keys. When two calls read
+// the same instant (or the clock steps back) the value is bumped, so keys
+// from one generator are unique and increasing.
+type NanoIDs struct {
+	clock Clock
+
+	mu   sync.Mutex
+	last int64
+}
+
+// NewNanoIDs creates a generator reading c
+func NewNanoIDs(c Clock) *NanoIDs {
+	return &NanoIDs{clock: c}
+}
+
+// NewID returns the next key for prefix
+func (g *NanoIDs) NewID(prefix string) string {
+	g.mu.Lock()
+	n := g.clock.Now().UnixNano()
+	if n <= g.last {
+		n = g.last + 1
+	}
+	g.last = n
+	g.mu.Unlock()
+	return prefix + "-" + strconv.FormatInt(n, 10)
+}
+
+// Sequence generates "<prefix>-1", "<prefix>-2", … for tests. The counter is
+// shared across prefixes.
+type Sequence struct {
+	mu sync.Mutex
+	n  int64
+}
+
+// NewID returns the next key for prefix
+func (s *Sequence) NewID(prefix string) string {
+	s.mu.Lock()
+	s.n++
+	n := s.n
+	s.mu.Unlock()
+	return prefix + "-" + strconv.FormatInt(n, 10)
+}
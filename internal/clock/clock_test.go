@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewManual(start)
+	if !c.Now().Equal(start) {
+		t.Fatalf("Now = %v, want %v", c.Now(), start)
+	}
+	c.Advance(time.Hour)
+	if want := start.Add(time.Hour); !c.Now().Equal(want) {
+		t.Errorf("after Advance, Now = %v, want %v", c.Now(), want)
+	}
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Errorf("after Set, Now = %v, want %v", c.Now(), start)
+	}
+}
+
+func TestNanoIDsStayUniqueWhenTimeStandsStill(t *testing.T) {
+	c := NewManual(time.Unix(0, 1000))
+	ids := NewNanoIDs(c)
+
+	if got := ids.NewID("topic"); got != "topic-1000" {
+		t.Errorf("first ID = %q", got)
+	}
+	if got := ids.NewID("msg"); got != "msg-1001" {
+		t.Errorf("ID at the same instant = %q, want msg-1001", got)
+	}
+	c.Set(time.Unix(0, 500))
+	if got := ids.NewID("msg"); got != "msg-1002" {
+		t.Errorf("ID after the clock stepped back = %q, want msg-1002", got)
+	}
+	c.Set(time.Unix(0, 5000))
+	if got := ids.NewID("msg"); got != "msg-5000" {
+		t.Errorf("ID after the clock moved on = %q, want msg-5000", got)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var ids Sequence
+	for i, want := range []string{"topic-1", "msg-2", "msg-3"} {
+		prefix := "msg"
+		if i == 0 {
+			prefix = "topic"
+		}
+		if got := ids.NewID(prefix); got != want {
+			t.Errorf("NewID = %q, want %q", got, want)
+		}
+	}
+}
@@ -3,12 +3,17 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/creasty/defaults"
 	"github.com/go-playground/validator/v10"
+	"github.com/jrschumacher/dis.quest/internal/jwtutil"
 	"github.com/jrschumacher/dis.quest/internal/logger"
 	"github.com/spf13/viper"
 )
@@ -32,11 +37,162 @@ type Config struct {
 	JWKSPublic       string `mapstructure:"jwks_public" validate:"required"`
 	PublicDomain     string `mapstructure:"public_domain" validate:"required"`
 	AppName          string `mapstructure:"app_name" validate:"required"`
-	OAuthClientID    string `mapstructure:"oauth_client_id" validate:"required"`
-	OAuthRedirectURL string `mapstructure:"oauth_redirect_url" validate:"required"`
+	OAuthClientID    string `mapstructure:"oauth_client_id" validate:"required_unless=OAuthLoopback true"`
+	OAuthRedirectURL string `mapstructure:"oauth_redirect_url" validate:"required_unless=OAuthLoopback true"`
+
+	// OAuthLoopback signs users in as an atproto loopback client, whose
+	// client ID needs no hosted client metadata, redirecting back to
+	// http://127.0.0.1:<port>/auth/callback. OAuthClientID and the redirect
+	// URLs are ignored. Only allowed outside production.
+	OAuthLoopback bool `mapstructure:"oauth_loopback"`
+
+	// OAuthRedirectURLs lists, space-separated, further callback URLs
+	// registered in the client metadata alongside OAuthRedirectURL, such as
+	// localhost and staging callbacks. Each sign-in redirects to the one on
+	// the host the request arrived at.
+	OAuthRedirectURLs string `mapstructure:"oauth_redirect_urls"`
+
+	// OAuthFlowMinutes is how long a visitor has to sign in at their
+	// authorization server before the DPoP key, PKCE verifier and state
+	// cookies expire.
+	OAuthFlowMinutes int `mapstructure:"oauth_flow_minutes" default:"30" validate:"min=1,max=1440"`
+
+	// SessionRefreshMinutes is how close to its access token's expiry a
+	// session must be before an open page renews it; pages check every
+	// minute. SessionRefreshJitterMinutes spreads the renewals of sessions
+	// signed in together over that many more minutes.
+	SessionRefreshMinutes       int `mapstructure:"session_refresh_minutes" default:"15" validate:"min=1,max=1440"`
+	SessionRefreshJitterMinutes int `mapstructure:"session_refresh_jitter_minutes" default:"5" validate:"min=0,max=60"`
+
+	// DPoPKeyStore selects where a sign-in's DPoP key waits between the
+	// redirect to the authorization server and the callback: "cookie" in the
+	// visitor's browser, "memory" in this process, "file" encrypted under
+	// DPoPKeyDir, or "keychain" in the OS keychain for CLI and desktop use.
+	DPoPKeyStore string `mapstructure:"dpop_key_store" default:"cookie" validate:"oneof=cookie memory file keychain"`
+	DPoPKeyDir   string `mapstructure:"dpop_key_dir" default:"data/dpop-keys"`
+
+	// DPoPDebug logs an explanation of each PDS request refused with a 401
+	// despite a DPoP proof, comparing the proof with the request and the
+	// access token's key binding. Tokens are not logged.
+	DPoPDebug bool `mapstructure:"dpop_debug"`
+
+	// SlowQueryMS logs database queries that take at least this many
+	// milliseconds; 0 disables the log.
+	SlowQueryMS int `mapstructure:"slow_query_ms" default:"200"`
+
+	// MessageCompressionBytes stores message content longer than this many
+	// bytes gzipped in the database, keeping long-form discussions small.
+	// PDS records are unaffected. 0 stores content as is.
+	MessageCompressionBytes int `mapstructure:"message_compression_bytes" validate:"min=0"`
+
+	// Branding shown to visitors alongside AppName. DefaultCategories is a
+	// comma-separated list of categories clients suggest for new topics.
+	InstanceLogoURL     string `mapstructure:"instance_logo_url" reload:"true"`
+	InstanceWelcomeText string `mapstructure:"instance_welcome_text" reload:"true" default:"A secure, decentralized discussion platform built on ATProtocol with optional OpenTDF encryption."`
+	DefaultCategories   string `mapstructure:"default_categories" reload:"true"`
+
+	// EmbedFrameAncestors is the CSP frame-ancestors source list for the
+	// embed widget route, e.g. "https://blog.example.com". Empty disallows framing.
+	EmbedFrameAncestors string `mapstructure:"embed_frame_ancestors"`
+
+	// CORSAllowedOrigins lists, space-separated, the origins whose pages may
+	// call the JSON API (/api/*), e.g. "https://app.example.com
+	// https://*.example.org". "*" allows any origin, but only without
+	// credentials. Empty allows none. CORSAllowCredentials lets listed
+	// origins send the session cookie; access tokens need no credentials.
+	// CORSMaxAgeSeconds is how long browsers may cache a preflight.
+	CORSAllowedOrigins   string `mapstructure:"cors_allowed_origins"`
+	CORSAllowCredentials bool   `mapstructure:"cors_allow_credentials"`
+	CORSMaxAgeSeconds    int    `mapstructure:"cors_max_age_seconds" default:"600" validate:"min=0,max=86400"`
+
+	// AdminDIDs is a space-separated list of DIDs allowed to hide any content.
+	AdminDIDs string `mapstructure:"admin_dids"`
+
+	// MaxStreams caps concurrent live event streams across all clients and
+	// MaxStreamsPerUser caps them per DID (or per address when signed out).
+	MaxStreams        int `mapstructure:"max_streams" reload:"true" default:"10000"`
+	MaxStreamsPerUser int `mapstructure:"max_streams_per_user" reload:"true" default:"8"`
+
+	// RequestTimeoutSeconds bounds each request, including the PDS calls
+	// made for it, so a slow PDS cannot hold a worker indefinitely.
+	// APITimeoutSeconds is the shorter bound for JSON API requests. Event
+	// streams and WebSockets are exempt. 0 disables a bound.
+	RequestTimeoutSeconds int `mapstructure:"request_timeout_seconds" default:"30" validate:"min=0,max=3600"`
+	APITimeoutSeconds     int `mapstructure:"api_timeout_seconds" default:"15" validate:"min=0,max=3600"`
+
+	// BlobStore selects where attachments and cached preview images are
+	// stored: "disk" under BlobDir, or "s3" in an S3-compatible bucket.
+	BlobStore         string `mapstructure:"blob_store" default:"disk" validate:"oneof=disk s3"`
+	BlobDir           string `mapstructure:"blob_dir" default:"data/blobs"`
+	S3Endpoint        string `mapstructure:"s3_endpoint"`
+	S3Region          string `mapstructure:"s3_region"`
+	S3Bucket          string `mapstructure:"s3_bucket"`
+	S3AccessKeyID     string `mapstructure:"s3_access_key_id"`
+	S3SecretAccessKey string `secret:"true" mapstructure:"s3_secret_access_key"`
+	// S3PathStyle addresses objects as endpoint/bucket/key, as MinIO and
+	// most self-hosted services expect
+	S3PathStyle bool `mapstructure:"s3_path_style"`
+	// ThumbnailSizes lists the widths, in pixels and space-separated, the
+	// blob proxy scales images to when asked for ?size=
+	ThumbnailSizes string `mapstructure:"thumbnail_sizes" default:"160 320 640"`
+
+	// MirrorSources lists other dis.quest instances whose topics are
+	// mirrored read-only, as space-separated origin=category[,category...]
+	// entries, e.g. "https://go.example=generics,tooling".
+	MirrorSources string `mapstructure:"mirror_sources"`
+	// MirrorQueueSize bounds the events each source reads ahead of the
+	// database, and MirrorWorkers how many of a collection's events are
+	// applied at once. MirrorOverload is what a full queue does: block
+	// reading, park the source for a while, or drop events.
+	MirrorQueueSize int    `mapstructure:"mirror_queue_size" default:"1000" validate:"min=1"`
+	MirrorWorkers   int    `mapstructure:"mirror_workers" default:"4" validate:"min=1,max=64"`
+	MirrorOverload  string `mapstructure:"mirror_overload" default:"block" validate:"oneof=block park drop"`
+	// MirrorRequireVerified skips mirrored records that cannot be proven
+	// against their author's signed repository, instead of mirroring them
+	// marked unverified
+	MirrorRequireVerified bool `mapstructure:"mirror_require_verified"`
+
+	// LabelerDID makes the instance an atproto labeler: moderators' hides
+	// are published as signed "!hide" labels that other services can
+	// subscribe to. LabelerSigningKey is the PEM-encoded P-256 key they are
+	// signed with; the DID document must publish its public half as the
+	// #atproto_label verification method.
+	LabelerDID        string `mapstructure:"labeler_did"`
+	LabelerSigningKey string `secret:"true" mapstructure:"labeler_signing_key" validate:"required_with=LabelerDID"`
+
+	// Indexer selects where the jobs that maintain the shared database run:
+	// "embedded" inside the server, which suits a single instance, or
+	// "external" when one separate `disquest indexer` process runs them for
+	// every server instance.
+	Indexer string `mapstructure:"indexer" default:"embedded" validate:"oneof=embedded external"`
+	// RedisURL joins server instances so live events published on one reach
+	// clients connected to the others, e.g. redis://:password@localhost:6379.
+	// Leave empty when running a single instance.
+	RedisURL string `secret:"true" mapstructure:"redis_url"`
+
+	// MetricsAddr is where Prometheus can scrape PDS request and mirror
+	// ingestion metrics, e.g. "127.0.0.1:9090". It gets its own listener so
+	// /metrics is never exposed on the public port. Empty disables the
+	// metrics.
+	MetricsAddr string `mapstructure:"metrics_addr"`
+	// SentryDSN sends handler panics, 5xx responses and failed background
+	// jobs to a Sentry project, tagged with app_env. Empty only logs them.
+	SentryDSN string `secret:"true" mapstructure:"sentry_dsn"`
+
+	// AppPasswordFallback lists, space-separated, the PDS write operations
+	// ("records.migrate", "account.delete") a user signed in with OAuth may
+	// retry with an app password when their PDS refuses to let the OAuth
+	// token write quest.dis.* records. The password is used for that one
+	// operation and never stored, but it grants full access to the account,
+	// so leave this empty unless your users' PDSes cannot grant the scope.
+	AppPasswordFallback string `mapstructure:"app_password_fallback" reload:"true"`
 
 	// Logging
-	LogLevel string `default:"INFO" validate:"oneof=DEBUG INFO WARN ERROR"`
+	LogLevel string `mapstructure:"log_level" reload:"true" default:"INFO" validate:"oneof=DEBUG INFO WARN ERROR"`
+
+	// live holds the settings tagged reload as last reloaded; copies of the
+	// config share it
+	live *live
 }
 
 // Load loads configuration from config file and environment variables using viper.
@@ -60,12 +216,9 @@ func Load() *Config {
 	// Bind env vars for each field
 	typeOfCfg := reflect.TypeOf(cfg)
 	for i := 0; i < typeOfCfg.NumField(); i++ {
-		field := typeOfCfg.Field(i)
-		key := field.Tag.Get("mapstructure")
-		if key == "" {
-			key = toSnakeCase(field.Name)
+		if field := typeOfCfg.Field(i); field.IsExported() {
+			_ = v.BindEnv(configKey(field))
 		}
-		_ = v.BindEnv(key)
 	}
 
 	// Read config file if it exists
@@ -82,13 +235,182 @@ func Load() *Config {
 
 	logger.Info("Loaded config", "config", cfg.String())
 
+	cfg.live = &live{}
 	return &cfg
 }
 
 // Validate validates the configuration using struct tags
 func Validate(cfg *Config) error {
 	validate := validator.New()
-	return validate.Struct(cfg)
+	if err := validate.Struct(cfg); err != nil {
+		return err
+	}
+	if cfg.OAuthLoopback && cfg.AppEnv == EnvProd {
+		return fmt.Errorf("oauth_loopback is for development and cannot be set in %s", EnvProd)
+	}
+	if _, err := parseWidths(cfg.ThumbnailSizes); err != nil {
+		return fmt.Errorf("thumbnail_sizes: %w", err)
+	}
+	return nil
+}
+
+// ThumbnailWidths returns the widths listed in ThumbnailSizes
+func (c *Config) ThumbnailWidths() []int {
+	widths, _ := parseWidths(c.ThumbnailSizes)
+	return widths
+}
+
+// maxThumbnailWidth bounds the widths thumbnail_sizes may list
+const maxThumbnailWidth = 2048
+
+func parseWidths(s string) ([]int, error) {
+	var widths []int
+	for _, field := range strings.Fields(s) {
+		width, err := strconv.Atoi(field)
+		if err != nil || width <= 0 || width > maxThumbnailWidth {
+			return nil, fmt.Errorf("%q is not a width between 1 and %d", field, maxThumbnailWidth)
+		}
+		widths = append(widths, width)
+	}
+	return widths, nil
+}
+
+// OAuthFlowTimeout returns how long a sign-in may take
+func (c *Config) OAuthFlowTimeout() time.Duration {
+	return time.Duration(c.OAuthFlowMinutes) * time.Minute
+}
+
+// SessionRefreshPolicy returns when a session's access token is renewed
+func (c *Config) SessionRefreshPolicy() jwtutil.RefreshPolicy {
+	return jwtutil.RefreshPolicy{
+		Threshold: time.Duration(c.SessionRefreshMinutes) * time.Minute,
+		Jitter:    time.Duration(c.SessionRefreshJitterMinutes) * time.Minute,
+	}
+}
+
+// RequestTimeout returns how long a request may take, 0 for no limit
+func (c *Config) RequestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeoutSeconds) * time.Second
+}
+
+// APITimeout returns how long a JSON API request may take, 0 for no limit
+func (c *Config) APITimeout() time.Duration {
+	return time.Duration(c.APITimeoutSeconds) * time.Second
+}
+
+// IsAdmin reports whether did is listed in AdminDIDs.
+func (c *Config) IsAdmin(did string) bool {
+	if c == nil || did == "" {
+		return false
+	}
+	for _, admin := range strings.Fields(c.AdminDIDs) {
+		if admin == did {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAppPasswordFallback reports whether op is listed in
+// AppPasswordFallback.
+func (c *Config) AllowsAppPasswordFallback(op string) bool {
+	if c == nil || op == "" {
+		return false
+	}
+	for _, allowed := range strings.Fields(c.current().AppPasswordFallback) {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultInstanceName is shown when AppName is not configured
+const defaultInstanceName = "dis.quest"
+
+// Instance is the public branding of a deployment
+type Instance struct {
+	Name              string   `json:"name"`
+	LogoURL           string   `json:"logo_url,omitempty"`
+	WelcomeText       string   `json:"welcome_text"`
+	DefaultCategories []string `json:"default_categories"`
+}
+
+// Instance returns the deployment's branding, so forks can rebrand through
+// configuration rather than by editing templates
+func (c *Config) Instance() Instance {
+	current := c.current()
+	instance := Instance{
+		Name:              c.AppName,
+		LogoURL:           current.InstanceLogoURL,
+		WelcomeText:       current.InstanceWelcomeText,
+		DefaultCategories: []string{},
+	}
+	if instance.Name == "" {
+		instance.Name = defaultInstanceName
+	}
+	for _, category := range strings.Split(current.DefaultCategories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			instance.DefaultCategories = append(instance.DefaultCategories, category)
+		}
+	}
+	return instance
+}
+
+// ForOrigin returns a copy of the config with PublicDomain, OAuthClientID
+// and OAuthRedirectURL moved onto origin, keeping their paths, and no
+// further redirect URLs. Requests that arrive on a community's custom
+// domain use it so the OAuth client is identified by, and redirects back
+// to, that domain.
+func (c *Config) ForOrigin(origin string) *Config {
+	rebased := *c
+	rebased.PublicDomain = origin
+	rebased.OAuthClientID = withOrigin(c.OAuthClientID, origin)
+	rebased.OAuthRedirectURL = withOrigin(c.OAuthRedirectURL, origin)
+	rebased.OAuthRedirectURLs = ""
+	return &rebased
+}
+
+// RedirectURLs returns every registered OAuth callback URL,
+// OAuthRedirectURL first
+func (c *Config) RedirectURLs() []string {
+	urls := []string{c.OAuthRedirectURL}
+	for _, u := range strings.Fields(c.OAuthRedirectURLs) {
+		if !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// ForHost returns a copy of the config whose OAuthRedirectURL is the
+// registered callback URL on host, so a sign-in started on localhost or
+// staging returns there. The config is returned unchanged when
+// OAuthRedirectURL is already on host or no registered URL is.
+func (c *Config) ForHost(host string) *Config {
+	urls := c.RedirectURLs()
+	for i, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil || !strings.EqualFold(u.Host, host) {
+			continue
+		}
+		if i == 0 {
+			return c
+		}
+		rebased := *c
+		rebased.OAuthRedirectURL = rawURL
+		return &rebased
+	}
+	return c
+}
+
+// withOrigin replaces the scheme and host of rawURL with origin
+func withOrigin(rawURL, origin string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return origin + u.RequestURI()
 }
 
 // String returns a string representation of the config with secret fields redacted.
@@ -99,15 +421,18 @@ func (c *Config) String() string {
 	sb.WriteString("Config{")
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
 		name := field.Name
 		value := v.Field(i).Interface()
 		if field.Tag.Get("secret") == "true" {
 			value = "***REDACTED***"
 		}
 		sb.WriteString(name + ": " + toString(value))
-		if i < t.NumField()-1 {
-			sb.WriteString(", ")
-		}
 	}
 	sb.WriteString("}")
 	return sb.String()
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInstance(t *testing.T) {
+	cfg := &Config{
+		AppName:             "Rust Forum",
+		InstanceLogoURL:     "https://forum.example.com/logo.svg",
+		InstanceWelcomeText: "Ask anything about Rust.",
+		DefaultCategories:   "help, announcements,, show and tell ",
+	}
+
+	got := cfg.Instance()
+	if got.Name != "Rust Forum" || got.LogoURL != cfg.InstanceLogoURL || got.WelcomeText != cfg.InstanceWelcomeText {
+		t.Errorf("Instance() = %+v", got)
+	}
+	if want := []string{"help", "announcements", "show and tell"}; !slices.Equal(got.DefaultCategories, want) {
+		t.Errorf("DefaultCategories = %q, want %q", got.DefaultCategories, want)
+	}
+
+	empty := (&Config{}).Instance()
+	if empty.Name != defaultInstanceName || empty.DefaultCategories == nil || len(empty.DefaultCategories) != 0 {
+		t.Errorf("expected the default name and no categories, got %+v", empty)
+	}
+}
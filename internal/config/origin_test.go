@@ -0,0 +1,61 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestForOrigin(t *testing.T) {
+	cfg := &Config{
+		AppName:          "dis.quest",
+		PublicDomain:     "https://dis.quest",
+		OAuthClientID:    "https://dis.quest/auth/client-metadata.json",
+		OAuthRedirectURL: "https://dis.quest/auth/callback",
+	}
+
+	got := cfg.ForOrigin("https://forum.example.com")
+	if got.PublicDomain != "https://forum.example.com" {
+		t.Errorf("PublicDomain = %q", got.PublicDomain)
+	}
+	if got.OAuthClientID != "https://forum.example.com/auth/client-metadata.json" {
+		t.Errorf("OAuthClientID = %q", got.OAuthClientID)
+	}
+	if got.OAuthRedirectURL != "https://forum.example.com/auth/callback" {
+		t.Errorf("OAuthRedirectURL = %q", got.OAuthRedirectURL)
+	}
+	if got.AppName != cfg.AppName {
+		t.Errorf("expected other fields to be kept, got AppName %q", got.AppName)
+	}
+	if cfg.PublicDomain != "https://dis.quest" {
+		t.Error("ForOrigin modified the original config")
+	}
+}
+
+func TestForHost(t *testing.T) {
+	cfg := &Config{
+		OAuthRedirectURL:  "https://dis.quest/auth/callback",
+		OAuthRedirectURLs: "http://localhost:3000/auth/callback https://staging.dis.quest/auth/callback https://dis.quest/auth/callback",
+	}
+
+	want := []string{"https://dis.quest/auth/callback", "http://localhost:3000/auth/callback", "https://staging.dis.quest/auth/callback"}
+	if got := cfg.RedirectURLs(); !slices.Equal(got, want) {
+		t.Errorf("RedirectURLs = %v, want %v", got, want)
+	}
+
+	for host, want := range map[string]string{
+		"localhost:3000":    "http://localhost:3000/auth/callback",
+		"STAGING.dis.quest": "https://staging.dis.quest/auth/callback",
+		"dis.quest":         "https://dis.quest/auth/callback",
+		"other.example.com": "https://dis.quest/auth/callback",
+	} {
+		if got := cfg.ForHost(host).OAuthRedirectURL; got != want {
+			t.Errorf("ForHost(%q).OAuthRedirectURL = %q, want %q", host, got, want)
+		}
+	}
+	if cfg.OAuthRedirectURL != "https://dis.quest/auth/callback" {
+		t.Error("ForHost modified the original config")
+	}
+	if got := cfg.ForOrigin("https://forum.example.com").RedirectURLs(); !slices.Equal(got, []string{"https://forum.example.com/auth/callback"}) {
+		t.Errorf("ForOrigin RedirectURLs = %v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotReloadable is returned when reloading a config that was not loaded
+// by Load
+var ErrNotReloadable = errors.New("config was not loaded from its sources, so cannot be reloaded")
+
+// live is the reloadable state of a loaded config
+type live struct {
+	// current is the config as last reloaded, nil until the first reload
+	current atomic.Pointer[Config]
+
+	// mu serializes reloads and guards hooks
+	mu    sync.Mutex
+	hooks []func(*Config)
+}
+
+// ReloadResult lists, by config key, what a reload changed
+type ReloadResult struct {
+	// Changed settings took effect
+	Changed []string `json:"changed"`
+	// Ignored settings changed in the sources but only take effect on
+	// restart
+	Ignored []string `json:"ignored"`
+}
+
+// Reload applies the settings tagged reload in next, which must be valid,
+// to the running config: log level, stream limits, branding and categories,
+// and app password fallback. OAuth, keys, the database and every other
+// setting stay as they were loaded, so instances of one deployment keep
+// agreeing on them until each is restarted. Reloaded settings are read
+// through the config's methods and the hooks registered with OnReload,
+// which run before Reload returns.
+func (c *Config) Reload(next *Config) (*ReloadResult, error) {
+	if c.live == nil {
+		return nil, ErrNotReloadable
+	}
+	if err := Validate(next); err != nil {
+		return nil, err
+	}
+
+	c.live.mu.Lock()
+	defer c.live.mu.Unlock()
+	current := c.current()
+	reloaded := *current
+	result := &ReloadResult{Changed: []string{}, Ignored: []string{}}
+	was, now, set := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem(), reflect.ValueOf(&reloaded).Elem()
+	for i := 0; i < was.NumField(); i++ {
+		field := was.Type().Field(i)
+		if !field.IsExported() || reflect.DeepEqual(was.Field(i).Interface(), now.Field(i).Interface()) {
+			continue
+		}
+		if field.Tag.Get("reload") != "true" {
+			result.Ignored = append(result.Ignored, configKey(field))
+			continue
+		}
+		set.Field(i).Set(now.Field(i))
+		result.Changed = append(result.Changed, configKey(field))
+	}
+	if len(result.Changed) == 0 {
+		return result, nil
+	}
+
+	c.live.current.Store(&reloaded)
+	for _, hook := range c.live.hooks {
+		hook(&reloaded)
+	}
+	return result, nil
+}
+
+// OnReload registers hook to be called with the reloaded config whenever a
+// reload changes a setting, for settings applied outside the config, such
+// as the log level. Configs not loaded by Load never reload.
+func (c *Config) OnReload(hook func(*Config)) {
+	if c == nil || c.live == nil {
+		return
+	}
+	c.live.mu.Lock()
+	defer c.live.mu.Unlock()
+	c.live.hooks = append(c.live.hooks, hook)
+}
+
+// current returns the config as last reloaded. Only its settings tagged
+// reload may differ from c's.
+func (c *Config) current() *Config {
+	if c.live != nil {
+		if reloaded := c.live.current.Load(); reloaded != nil {
+			return reloaded
+		}
+	}
+	return c
+}
+
+// configKey returns the key a field is configured by
+func configKey(field reflect.StructField) string {
+	if key := field.Tag.Get("mapstructure"); key != "" {
+		return key
+	}
+	return toSnakeCase(field.Name)
+}
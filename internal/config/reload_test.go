@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/creasty/defaults"
+)
+
+// loaded returns a valid config as Load would return it
+func loaded(t *testing.T) *Config {
+	t.Helper()
+	cfg := &Config{}
+	if err := defaults.Set(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.JWKSPrivate = `{"keys":[]}`
+	cfg.JWKSPublic = `{"keys":[]}`
+	cfg.PublicDomain = "https://forum.example.com"
+	cfg.AppName = "Forum"
+	cfg.OAuthClientID = "https://forum.example.com/auth/client-metadata.json"
+	cfg.OAuthRedirectURL = "https://forum.example.com/auth/callback"
+	cfg.live = &live{}
+	return cfg
+}
+
+func TestReload(t *testing.T) {
+	cfg := loaded(t)
+	onDomain := cfg.ForOrigin("https://rust.example.org")
+	var hooked []*Config
+	cfg.OnReload(func(reloaded *Config) { hooked = append(hooked, reloaded) })
+
+	next := *cfg
+	next.LogLevel = "DEBUG"
+	next.MaxStreamsPerUser = 2
+	next.DefaultCategories = "help, meta"
+	next.AppPasswordFallback = "records.migrate"
+	next.OAuthClientID = "https://elsewhere.example/client-metadata.json"
+	next.DatabaseURL = "postgres://elsewhere.example/forum"
+	result, err := cfg.Reload(&next)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	wantChanged := []string{"default_categories", "max_streams_per_user", "app_password_fallback", "log_level"}
+	if !slices.Equal(result.Changed, wantChanged) {
+		t.Errorf("Changed = %q, want %q", result.Changed, wantChanged)
+	}
+	if wantIgnored := []string{"database_url", "oauth_client_id"}; !slices.Equal(result.Ignored, wantIgnored) {
+		t.Errorf("Ignored = %q, want %q", result.Ignored, wantIgnored)
+	}
+	if len(hooked) != 1 || hooked[0].LogLevel != "DEBUG" || hooked[0].MaxStreamsPerUser != 2 {
+		t.Fatalf("hooks saw %+v", hooked)
+	}
+	if hooked[0].OAuthClientID != cfg.OAuthClientID || hooked[0].DatabaseURL != cfg.DatabaseURL {
+		t.Errorf("reload changed settings that need a restart: %+v", hooked[0])
+	}
+
+	// Copies of the config, such as a custom domain's, see reloaded settings
+	for _, c := range []*Config{cfg, onDomain} {
+		if got := c.Instance().DefaultCategories; !slices.Equal(got, []string{"help", "meta"}) {
+			t.Errorf("DefaultCategories = %q after reload", got)
+		}
+		if !c.AllowsAppPasswordFallback("records.migrate") {
+			t.Error("expected the reloaded app password fallback")
+		}
+	}
+
+	// Reloading unchanged sources changes nothing and runs no hooks
+	if result, err := cfg.Reload(&next); err != nil || len(result.Changed) != 0 || len(hooked) != 1 {
+		t.Errorf("Reload again = %+v, %v with %d hook calls", result, err, len(hooked))
+	}
+}
+
+func TestReloadRejects(t *testing.T) {
+	cfg := loaded(t)
+	invalid := *cfg
+	invalid.LogLevel = "LOUD"
+	if _, err := cfg.Reload(&invalid); err == nil {
+		t.Error("expected an invalid config to be rejected")
+	}
+	if cfg.current().LogLevel != "INFO" {
+		t.Errorf("LogLevel = %q after a rejected reload", cfg.current().LogLevel)
+	}
+
+	literal := *cfg
+	literal.live = nil
+	if _, err := literal.Reload(cfg); !errors.Is(err, ErrNotReloadable) {
+		t.Errorf("Reload error = %v, want ErrNotReloadable", err)
+	}
+}
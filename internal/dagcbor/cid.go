@@ -0,0 +1,119 @@
+package dagcbor
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Codecs of the blocks atproto repositories hold
+const (
+	CodecRaw     = 0x55
+	CodecDAGCBOR = 0x71
+)
+
+// sha256Code is the multihash code of SHA-256, the only hash atproto uses
+const sha256Code = 0x12
+
+// cidBase32 is the multibase "b" alphabet CIDs are written in
+var cidBase32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// CID is a version 1 content identifier in its binary form
+type CID string
+
+// Sum returns the CID of a block of codec holding data
+func Sum(codec uint64, data []byte) CID {
+	digest := sha256.Sum256(data)
+	b := binary.AppendUvarint(nil, 1)
+	b = binary.AppendUvarint(b, codec)
+	b = binary.AppendUvarint(b, sha256Code)
+	b = binary.AppendUvarint(b, uint64(len(digest)))
+	return CID(append(b, digest[:]...))
+}
+
+// ReadCID reads the binary CID data starts with, returning its length
+func ReadCID(data []byte) (CID, int, error) {
+	pos := 0
+	var fields [4]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return "", 0, fmt.Errorf("%w: truncated CID", ErrMalformed)
+		}
+		fields[i] = v
+		pos += n
+	}
+	version, hashCode, size := fields[0], fields[2], fields[3]
+	if version != 1 || hashCode != sha256Code || size != sha256.Size {
+		return "", 0, fmt.Errorf("%w: unsupported CID", ErrMalformed)
+	}
+	if len(data)-pos < sha256.Size {
+		return "", 0, fmt.Errorf("%w: truncated CID", ErrMalformed)
+	}
+	pos += sha256.Size
+	return CID(data[:pos]), pos, nil
+}
+
+// ParseCID parses a CID written in base32, as atproto writes them
+func ParseCID(s string) (CID, error) {
+	encoded, ok := strings.CutPrefix(s, "b")
+	if !ok {
+		return "", fmt.Errorf("%w: unsupported CID encoding", ErrMalformed)
+	}
+	data, err := cidBase32.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: CID is not base32", ErrMalformed)
+	}
+	cid, n, err := ReadCID(data)
+	if err != nil {
+		return "", err
+	}
+	if n != len(data) {
+		return "", fmt.Errorf("%w: trailing bytes after CID", ErrMalformed)
+	}
+	return cid, nil
+}
+
+// Codec returns the codec of the block c identifies
+func (c CID) Codec() uint64 {
+	_, n := binary.Uvarint([]byte(c))
+	codec, _ := binary.Uvarint([]byte(c)[n:])
+	return codec
+}
+
+// Matches reports whether c identifies data
+func (c CID) Matches(data []byte) bool {
+	return Sum(c.Codec(), data) == c
+}
+
+// String returns c in the base32 form atproto writes CIDs in
+func (c CID) String() string {
+	return "b" + cidBase32.EncodeToString([]byte(c))
+}
+
+// JSON converts a decoded value to the form atproto records take in JSON:
+// bytes become {"$bytes": base64} and links {"$link": cid}
+func JSON(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return map[string]any{"$bytes": base64.RawStdEncoding.EncodeToString(v)}
+	case CID:
+		return map[string]any{"$link": v.String()}
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = JSON(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = JSON(item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,86 @@
+package dagcbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	link := Sum(CodecRaw, []byte("hello world"))
+	value := map[string]any{
+		"text":    "Generics",
+		"count":   int64(-300),
+		"big":     int64(1) << 40,
+		"flag":    true,
+		"none":    nil,
+		"bytes":   []byte{1, 2, 3},
+		"link":    link,
+		"entries": []any{map[string]any{"k": "a"}, int64(0)},
+	}
+	data := Append(nil, value)
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("Decode = %#v, want %#v", decoded, value)
+	}
+	if again := Append(nil, decoded); string(again) != string(data) {
+		t.Error("re-encoding changed the bytes")
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"float":             "fb3ff0000000000000",
+		"indefinite array":  "9f01ff",
+		"duplicate key":     "a2616101616102",
+		"integer key":       "a10101",
+		"other tag":         "c074323032352d30312d30315430303a30303a30305a",
+		"trailing bytes":    "0101",
+		"truncated":         "63616263"[:6],
+		"huge array length": "9bffffffffffffffff",
+	} {
+		b, _ := hex.DecodeString(data)
+		if _, err := Decode(b); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: Decode error = %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestCID(t *testing.T) {
+	cid := Sum(CodecRaw, []byte("hello world"))
+	if got, want := cid.String(), "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"; got != want {
+		t.Errorf("String = %s, want %s", got, want)
+	}
+	if cid.Codec() != CodecRaw || !cid.Matches([]byte("hello world")) || cid.Matches([]byte("hello")) {
+		t.Error("expected the CID to match only its block")
+	}
+	read, n, err := ReadCID(append([]byte(cid), 0xff))
+	if err != nil || read != cid || n != len(cid) {
+		t.Errorf("ReadCID = %v, %d, %v", read, n, err)
+	}
+	parsed, err := ParseCID(cid.String())
+	if err != nil || parsed != cid {
+		t.Errorf("ParseCID = %v, %v", parsed, err)
+	}
+	for _, bad := range []string{"", "zQm", "b!!", cid.String()[:20], cid.String() + "aa"} {
+		if _, err := ParseCID(bad); err == nil {
+			t.Errorf("ParseCID(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	link := Sum(CodecDAGCBOR, []byte{0xa0})
+	got := JSON(map[string]any{"blob": map[string]any{"ref": link, "sig": []byte{1, 2, 3}}})
+	want := map[string]any{"blob": map[string]any{
+		"ref": map[string]any{"$link": link.String()},
+		"sig": map[string]any{"$bytes": "AQID"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON = %#v, want %#v", got, want)
+	}
+}
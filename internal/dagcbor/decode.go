@@ -0,0 +1,186 @@
+package dagcbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxDepth bounds how deeply arrays and maps may nest
+const maxDepth = 64
+
+// ErrMalformed is returned for data that is not DAG-CBOR Decode supports
+var ErrMalformed = errors.New("dagcbor: malformed data")
+
+// Decode decodes the single DAG-CBOR item in data into int64, bool, nil,
+// string, []byte, CID, []any and map[string]any values. Floats, indefinite
+// lengths and tags other than CID links are rejected, as atproto forbids
+// them.
+func Decode(data []byte) (any, error) {
+	d := decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(data)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrMalformed)
+	}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer out of range", ErrMalformed)
+		}
+		return int64(n), nil
+	case cborNegint:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer out of range", ErrMalformed)
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		return d.bytes(n)
+	case cborText:
+		b, err := d.bytes(n)
+		return string(b), err
+	case cborArray:
+		// Every item takes at least a byte
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: truncated array", ErrMalformed)
+		}
+		items := make([]any, 0, n)
+		for range n {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("%w: truncated map", ErrMalformed)
+		}
+		m := make(map[string]any, n)
+		for range n {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: map key is %T, not a string", ErrMalformed, key)
+			}
+			if _, dup := m[k]; dup {
+				return nil, fmt.Errorf("%w: duplicate map key %q", ErrMalformed, k)
+			}
+			if m[k], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cborTag:
+		if n != tagCID {
+			return nil, fmt.Errorf("%w: unsupported tag %d", ErrMalformed, n)
+		}
+		major, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborBytes {
+			return nil, fmt.Errorf("%w: CID link is not bytes", ErrMalformed)
+		}
+		b, err := d.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) == 0 || b[0] != 0 {
+			return nil, fmt.Errorf("%w: CID link without its zero prefix", ErrMalformed)
+		}
+		cid, size, err := ReadCID(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size != len(b)-1 {
+			return nil, fmt.Errorf("%w: trailing bytes after CID link", ErrMalformed)
+		}
+		return cid, nil
+	}
+	// Major type 7 arguments are simple values once head has rejected
+	// floats and breaks
+	switch n {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported simple value %d", ErrMalformed, n)
+}
+
+// head reads an item's major type and argument
+func (d *decoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1f
+	if major == cborSimple && info >= 25 {
+		return 0, 0, fmt.Errorf("%w: floats and indefinite lengths are not allowed", ErrMalformed)
+	}
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("%w: indefinite lengths are not allowed", ErrMalformed)
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+	arg := d.data[d.pos : d.pos+size]
+	d.pos += size
+	switch size {
+	case 1:
+		return major, uint64(arg[0]), nil
+	case 2:
+		return major, uint64(binary.BigEndian.Uint16(arg)), nil
+	case 4:
+		return major, uint64(binary.BigEndian.Uint32(arg)), nil
+	}
+	return major, binary.BigEndian.Uint64(arg), nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+	b := d.data[d.pos : d.pos+int(n)] // #nosec G115 -- n is bounded by len(d.data)
+	d.pos += int(n)                   // #nosec G115 -- as above
+	return b, nil
+}
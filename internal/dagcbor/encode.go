@@ -0,0 +1,97 @@
+// Package dagcbor encodes and decodes the DAG-CBOR subset atproto uses for
+// repository blocks, labels and event stream frames, and identifies blocks
+// by CID.
+package dagcbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// tagCID marks a CID link
+const tagCID = 42
+
+// Append appends v encoded as DAG-CBOR: the shortest integer heads and map
+// keys sorted by length, then bytewise. v may be built of the types Decode
+// returns, plus int.
+func Append(buf []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 0xf6)
+	case int64:
+		if v < 0 {
+			return appendHead(buf, cborNegint, uint64(-1-v)) // #nosec G115 -- -1-v is non-negative
+		}
+		return appendHead(buf, cborUint, uint64(v))
+	case int:
+		return Append(buf, int64(v))
+	case bool:
+		if v {
+			return append(buf, 0xf5)
+		}
+		return append(buf, 0xf4)
+	case string:
+		return append(appendHead(buf, cborText, uint64(len(v))), v...)
+	case []byte:
+		return append(appendHead(buf, cborBytes, uint64(len(v))), v...)
+	case CID:
+		// Links carry the binary CID behind a zero multibase prefix
+		buf = appendHead(buf, cborTag, tagCID)
+		buf = appendHead(buf, cborBytes, uint64(len(v)+1))
+		return append(append(buf, 0), v...)
+	case []any:
+		buf = appendHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			buf = Append(buf, item)
+		}
+		return buf
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		buf = appendHead(buf, cborMap, uint64(len(v)))
+		for _, key := range keys {
+			buf = Append(buf, key)
+			buf = Append(buf, v[key])
+		}
+		return buf
+	}
+	panic(fmt.Sprintf("dagcbor: cannot encode %T", v))
+}
+
+// appendHead appends the initial bytes of a CBOR item of major type major
+// and argument n
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= 0xff:
+		return append(buf, major|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+}
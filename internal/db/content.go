@@ -0,0 +1,106 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// compressedPrefix marks stored content as base64-encoded gzip. It starts
+// with a control character no message begins with in practice; one that
+// does is stored compressed whatever its length, so reads stay unambiguous.
+const compressedPrefix = "\x1fgzip:"
+
+// compressAbove is the length in bytes past which message content is
+// stored compressed, 0 to store it as is
+var compressAbove atomic.Int64
+
+// SetCompressionThreshold makes messages longer than bytes be stored
+// gzipped in the index from now on. 0 stores new messages as is; messages
+// already compressed stay readable either way.
+func SetCompressionThreshold(bytes int) {
+	compressAbove.Store(int64(bytes))
+}
+
+// MessageContent is a message's text in the index. Callers read and write
+// it as a string; long content is compressed when stored and decompressed
+// when read. Only the index is affected, never the PDS record.
+type MessageContent string
+
+// Value implements driver.Valuer, compressing content past the threshold
+// when that makes it smaller
+func (c MessageContent) Value() (driver.Value, error) {
+	content := string(c)
+	marked := strings.HasPrefix(content, compressedPrefix)
+	threshold := compressAbove.Load()
+	if !marked && (threshold <= 0 || int64(len(content)) <= threshold) {
+		return content, nil
+	}
+	compressed, err := compress(content)
+	if err != nil {
+		return nil, fmt.Errorf("compressing message content: %w", err)
+	}
+	if !marked && len(compressed) >= len(content) {
+		return content, nil
+	}
+	return compressed, nil
+}
+
+// Scan implements sql.Scanner, decompressing content stored compressed
+func (c *MessageContent) Scan(src any) error {
+	var stored string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into MessageContent", src)
+	}
+	content, err := decompress(stored)
+	if err != nil {
+		return fmt.Errorf("decompressing message content: %w", err)
+	}
+	*c = MessageContent(content)
+	return nil
+}
+
+func compress(content string) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, content); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return compressedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompress returns stored content as written, leaving uncompressed
+// content as is
+func decompress(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, compressedPrefix)
+	if !ok {
+		return stored, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,77 @@
+package db_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestMessageContentCompression(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	db.SetCompressionThreshold(100)
+	t.Cleanup(func() { db.SetCompressionThreshold(0) })
+
+	now := time.Now()
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did: "did:plc:alice", Rkey: "topic", Subject: "Long reads", InitialMessage: "Post essays here",
+		CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	essay := strings.Repeat("A long-form reply that goes on and on. ", 100)
+	tests := []struct {
+		rkey       string
+		content    string
+		compressed bool
+	}{
+		{"short", "Agreed!", false},
+		{"essay", essay, true},
+		// Random-looking content gzip cannot shrink is kept as is
+		{"incompressible", "k8Qz1xT0pVb7YwN3rLs9HdJm2FgC5eKu4aXo6iRtZq1WnE8yBv0MhSjPl7cUdG3fOk9QzXw2Ae5Rb8Ty4Np1Ls6Hm0Jg7Fd3Kc9Vu2Ix5Zo8Wq4Er1", false},
+		// Content that looks compressed is stored compressed, so it reads back as written
+		{"lookalike", "\x1fgzip:not really", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rkey, func(t *testing.T) {
+			created, err := dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
+				Did: "did:plc:bob", Rkey: tt.rkey, TopicDid: topic.Did, TopicRkey: topic.Rkey,
+				Content: db.MessageContent(tt.content), CreatedAt: now, UpdatedAt: now,
+			})
+			if err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+			read, err := dbService.Queries().GetMessage(ctx, db.GetMessageParams{Did: "did:plc:bob", Rkey: tt.rkey})
+			if err != nil {
+				t.Fatalf("GetMessage: %v", err)
+			}
+			if string(created.Content) != tt.content || string(read.Content) != tt.content {
+				t.Errorf("content did not round-trip: created %q, read %q", created.Content, read.Content)
+			}
+
+			var stored string
+			if err := dbService.DB().QueryRowContext(ctx, "SELECT content FROM quest_dis_message WHERE rkey = ?", tt.rkey).Scan(&stored); err != nil {
+				t.Fatal(err)
+			}
+			if compressed := stored != tt.content; compressed != tt.compressed {
+				t.Errorf("stored compressed = %t, want %t", compressed, tt.compressed)
+			}
+			if tt.compressed && tt.rkey == "essay" && len(stored) >= len(essay)/4 {
+				t.Errorf("stored %d bytes for a %d-byte essay", len(stored), len(essay))
+			}
+		})
+	}
+
+	// Compressed messages stay readable once compression is turned off
+	db.SetCompressionThreshold(0)
+	read, err := dbService.Queries().GetMessage(ctx, db.GetMessageParams{Did: "did:plc:bob", Rkey: "essay"})
+	if err != nil || string(read.Content) != essay {
+		t.Errorf("GetMessage after turning compression off = %q, %v", read.Content, err)
+	}
+}
@@ -24,30 +24,156 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addCommunityModeratorStmt, err = db.PrepareContext(ctx, AddCommunityModerator); err != nil {
+		return nil, fmt.Errorf("error preparing query AddCommunityModerator: %w", err)
+	}
+	if q.addTopicStatsStmt, err = db.PrepareContext(ctx, AddTopicStats); err != nil {
+		return nil, fmt.Errorf("error preparing query AddTopicStats: %w", err)
+	}
+	if q.adjustTopicMessageCountStmt, err = db.PrepareContext(ctx, AdjustTopicMessageCount); err != nil {
+		return nil, fmt.Errorf("error preparing query AdjustTopicMessageCount: %w", err)
+	}
+	if q.appendFirehoseEventStmt, err = db.PrepareContext(ctx, AppendFirehoseEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query AppendFirehoseEvent: %w", err)
+	}
+	if q.appendLabelStmt, err = db.PrepareContext(ctx, AppendLabel); err != nil {
+		return nil, fmt.Errorf("error preparing query AppendLabel: %w", err)
+	}
+	if q.countAuthorMessagesSinceStmt, err = db.PrepareContext(ctx, CountAuthorMessagesSince); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAuthorMessagesSince: %w", err)
+	}
+	if q.countMirrorTopicStmt, err = db.PrepareContext(ctx, CountMirrorTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query CountMirrorTopic: %w", err)
+	}
+	if q.countTopicModeratorStmt, err = db.PrepareContext(ctx, CountTopicModerator); err != nil {
+		return nil, fmt.Errorf("error preparing query CountTopicModerator: %w", err)
+	}
+	if q.countTopicResponsesStmt, err = db.PrepareContext(ctx, CountTopicResponses); err != nil {
+		return nil, fmt.Errorf("error preparing query CountTopicResponses: %w", err)
+	}
+	if q.countUnreadMessagesStmt, err = db.PrepareContext(ctx, CountUnreadMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUnreadMessages: %w", err)
+	}
+	if q.createAccessTokenStmt, err = db.PrepareContext(ctx, CreateAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAccessToken: %w", err)
+	}
+	if q.createCommunityStmt, err = db.PrepareContext(ctx, CreateCommunity); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCommunity: %w", err)
+	}
+	if q.createCommunityDomainStmt, err = db.PrepareContext(ctx, CreateCommunityDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCommunityDomain: %w", err)
+	}
 	if q.createMessageStmt, err = db.PrepareContext(ctx, CreateMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
 	}
 	if q.createParticipationStmt, err = db.PrepareContext(ctx, CreateParticipation); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateParticipation: %w", err)
 	}
+	if q.createSessionStmt, err = db.PrepareContext(ctx, CreateSession); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
+	}
 	if q.createTopicStmt, err = db.PrepareContext(ctx, CreateTopic); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateTopic: %w", err)
 	}
+	if q.deleteAccessTokenStmt, err = db.PrepareContext(ctx, DeleteAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAccessToken: %w", err)
+	}
+	if q.deleteAccessTokensByDidStmt, err = db.PrepareContext(ctx, DeleteAccessTokensByDid); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteAccessTokensByDid: %w", err)
+	}
+	if q.deleteCommunityCategoryStmt, err = db.PrepareContext(ctx, DeleteCommunityCategory); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCommunityCategory: %w", err)
+	}
+	if q.deleteCommunityDomainStmt, err = db.PrepareContext(ctx, DeleteCommunityDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCommunityDomain: %w", err)
+	}
+	if q.deleteFirehoseEventsByAccountStmt, err = db.PrepareContext(ctx, DeleteFirehoseEventsByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFirehoseEventsByAccount: %w", err)
+	}
 	if q.deleteMessageStmt, err = db.PrepareContext(ctx, DeleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
 	}
+	if q.deleteMessagesByAccountStmt, err = db.PrepareContext(ctx, DeleteMessagesByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessagesByAccount: %w", err)
+	}
+	if q.deleteMirrorMessageStmt, err = db.PrepareContext(ctx, DeleteMirrorMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMirrorMessage: %w", err)
+	}
+	if q.deleteMirrorTopicStmt, err = db.PrepareContext(ctx, DeleteMirrorTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMirrorTopic: %w", err)
+	}
+	if q.deleteMirrorTopicMessagesStmt, err = db.PrepareContext(ctx, DeleteMirrorTopicMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMirrorTopicMessages: %w", err)
+	}
 	if q.deleteParticipationStmt, err = db.PrepareContext(ctx, DeleteParticipation); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteParticipation: %w", err)
 	}
+	if q.deleteParticipationRetriesByAccountStmt, err = db.PrepareContext(ctx, DeleteParticipationRetriesByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteParticipationRetriesByAccount: %w", err)
+	}
+	if q.deleteParticipationRetryStmt, err = db.PrepareContext(ctx, DeleteParticipationRetry); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteParticipationRetry: %w", err)
+	}
+	if q.deleteParticipationsByAccountStmt, err = db.PrepareContext(ctx, DeleteParticipationsByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteParticipationsByAccount: %w", err)
+	}
+	if q.deleteReadMarkerStmt, err = db.PrepareContext(ctx, DeleteReadMarker); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteReadMarker: %w", err)
+	}
+	if q.deleteReadMarkersByAccountStmt, err = db.PrepareContext(ctx, DeleteReadMarkersByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteReadMarkersByAccount: %w", err)
+	}
+	if q.deleteSessionStmt, err = db.PrepareContext(ctx, DeleteSession); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
+	}
+	if q.deleteSessionsByDidStmt, err = db.PrepareContext(ctx, DeleteSessionsByDid); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSessionsByDid: %w", err)
+	}
 	if q.deleteTopicStmt, err = db.PrepareContext(ctx, DeleteTopic); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteTopic: %w", err)
 	}
+	if q.deleteTopicStatsByAccountStmt, err = db.PrepareContext(ctx, DeleteTopicStatsByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTopicStatsByAccount: %w", err)
+	}
+	if q.deleteTopicsByAccountStmt, err = db.PrepareContext(ctx, DeleteTopicsByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTopicsByAccount: %w", err)
+	}
+	if q.getAccessTokenByHashStmt, err = db.PrepareContext(ctx, GetAccessTokenByHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccessTokenByHash: %w", err)
+	}
+	if q.getCommunityStmt, err = db.PrepareContext(ctx, GetCommunity); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCommunity: %w", err)
+	}
+	if q.getCommunityDomainStmt, err = db.PrepareContext(ctx, GetCommunityDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCommunityDomain: %w", err)
+	}
+	if q.getDeletedMessageStmt, err = db.PrepareContext(ctx, GetDeletedMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeletedMessage: %w", err)
+	}
+	if q.getDeletedTopicStmt, err = db.PrepareContext(ctx, GetDeletedTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeletedTopic: %w", err)
+	}
+	if q.getLatestFirehoseEventTimeStmt, err = db.PrepareContext(ctx, GetLatestFirehoseEventTime); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestFirehoseEventTime: %w", err)
+	}
+	if q.getLatestLabelSeqStmt, err = db.PrepareContext(ctx, GetLatestLabelSeq); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestLabelSeq: %w", err)
+	}
 	if q.getMessageStmt, err = db.PrepareContext(ctx, GetMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
 	}
 	if q.getMessagesByTopicStmt, err = db.PrepareContext(ctx, GetMessagesByTopic); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessagesByTopic: %w", err)
 	}
+	if q.getMirrorCursorStmt, err = db.PrepareContext(ctx, GetMirrorCursor); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMirrorCursor: %w", err)
+	}
+	if q.getMirrorRecordStmt, err = db.PrepareContext(ctx, GetMirrorRecord); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMirrorRecord: %w", err)
+	}
+	if q.getMirrorTopicStmt, err = db.PrepareContext(ctx, GetMirrorTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMirrorTopic: %w", err)
+	}
 	if q.getParticipationStmt, err = db.PrepareContext(ctx, GetParticipation); err != nil {
 		return nil, fmt.Errorf("error preparing query GetParticipation: %w", err)
 	}
@@ -60,26 +186,253 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getRepliesByMessageStmt, err = db.PrepareContext(ctx, GetRepliesByMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetRepliesByMessage: %w", err)
 	}
+	if q.getSessionStmt, err = db.PrepareContext(ctx, GetSession); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSession: %w", err)
+	}
 	if q.getTopicStmt, err = db.PrepareContext(ctx, GetTopic); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTopic: %w", err)
 	}
 	if q.getTopicsByCategoryStmt, err = db.PrepareContext(ctx, GetTopicsByCategory); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTopicsByCategory: %w", err)
 	}
+	if q.getUnreadCountsStmt, err = db.PrepareContext(ctx, GetUnreadCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUnreadCounts: %w", err)
+	}
+	if q.getVerifiedDomainCommunityStmt, err = db.PrepareContext(ctx, GetVerifiedDomainCommunity); err != nil {
+		return nil, fmt.Errorf("error preparing query GetVerifiedDomainCommunity: %w", err)
+	}
+	if q.incrementTopicActivityStmt, err = db.PrepareContext(ctx, IncrementTopicActivity); err != nil {
+		return nil, fmt.Errorf("error preparing query IncrementTopicActivity: %w", err)
+	}
+	if q.listAccessTokensByDidStmt, err = db.PrepareContext(ctx, ListAccessTokensByDid); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAccessTokensByDid: %w", err)
+	}
+	if q.listCommunitiesStmt, err = db.PrepareContext(ctx, ListCommunities); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCommunities: %w", err)
+	}
+	if q.listCommunityCategoriesStmt, err = db.PrepareContext(ctx, ListCommunityCategories); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCommunityCategories: %w", err)
+	}
+	if q.listCommunityDomainsStmt, err = db.PrepareContext(ctx, ListCommunityDomains); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCommunityDomains: %w", err)
+	}
+	if q.listCommunityModeratorsStmt, err = db.PrepareContext(ctx, ListCommunityModerators); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCommunityModerators: %w", err)
+	}
+	if q.listDailyStatsStmt, err = db.PrepareContext(ctx, ListDailyStats); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDailyStats: %w", err)
+	}
+	if q.listDueParticipationRetriesStmt, err = db.PrepareContext(ctx, ListDueParticipationRetries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDueParticipationRetries: %w", err)
+	}
+	if q.listFirehoseEventsSinceStmt, err = db.PrepareContext(ctx, ListFirehoseEventsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFirehoseEventsSince: %w", err)
+	}
+	if q.listLabelsByURIStmt, err = db.PrepareContext(ctx, ListLabelsByURI); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLabelsByURI: %w", err)
+	}
+	if q.listLabelsByURIPrefixStmt, err = db.PrepareContext(ctx, ListLabelsByURIPrefix); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLabelsByURIPrefix: %w", err)
+	}
+	if q.listLabelsSinceStmt, err = db.PrepareContext(ctx, ListLabelsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLabelsSince: %w", err)
+	}
+	if q.listMessagesByAuthorStmt, err = db.PrepareContext(ctx, ListMessagesByAuthor); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessagesByAuthor: %w", err)
+	}
+	if q.listMirrorMessagesStmt, err = db.PrepareContext(ctx, ListMirrorMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMirrorMessages: %w", err)
+	}
+	if q.listMirrorTopicsStmt, err = db.PrepareContext(ctx, ListMirrorTopics); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMirrorTopics: %w", err)
+	}
+	if q.listMirrorTopicsByCategoryStmt, err = db.PrepareContext(ctx, ListMirrorTopicsByCategory); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMirrorTopicsByCategory: %w", err)
+	}
+	if q.listParticipationRetriesStmt, err = db.PrepareContext(ctx, ListParticipationRetries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListParticipationRetries: %w", err)
+	}
+	if q.listRelatedTopicCandidatesStmt, err = db.PrepareContext(ctx, ListRelatedTopicCandidates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRelatedTopicCandidates: %w", err)
+	}
+	if q.listSessionsByDidStmt, err = db.PrepareContext(ctx, ListSessionsByDid); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionsByDid: %w", err)
+	}
+	if q.listTopTopicsByViewsStmt, err = db.PrepareContext(ctx, ListTopTopicsByViews); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopTopicsByViews: %w", err)
+	}
+	if q.listTopicStatsStmt, err = db.PrepareContext(ctx, ListTopicStats); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicStats: %w", err)
+	}
 	if q.listTopicsStmt, err = db.PrepareContext(ctx, ListTopics); err != nil {
 		return nil, fmt.Errorf("error preparing query ListTopics: %w", err)
 	}
+	if q.listTopicsByActivityStmt, err = db.PrepareContext(ctx, ListTopicsByActivity); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicsByActivity: %w", err)
+	}
+	if q.listTopicsByAuthorStmt, err = db.PrepareContext(ctx, ListTopicsByAuthor); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicsByAuthor: %w", err)
+	}
+	if q.listTopicsByHotRankStmt, err = db.PrepareContext(ctx, ListTopicsByHotRank); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicsByHotRank: %w", err)
+	}
+	if q.listTopicsByMessageCountStmt, err = db.PrepareContext(ctx, ListTopicsByMessageCount); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicsByMessageCount: %w", err)
+	}
+	if q.listTopicsMissingCreatorParticipationStmt, err = db.PrepareContext(ctx, ListTopicsMissingCreatorParticipation); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTopicsMissingCreatorParticipation: %w", err)
+	}
+	if q.listTrendingCategoriesStmt, err = db.PrepareContext(ctx, ListTrendingCategories); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTrendingCategories: %w", err)
+	}
+	if q.listTrendingTopicsStmt, err = db.PrepareContext(ctx, ListTrendingTopics); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTrendingTopics: %w", err)
+	}
+	if q.markCommunityDomainVerifiedStmt, err = db.PrepareContext(ctx, MarkCommunityDomainVerified); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkCommunityDomainVerified: %w", err)
+	}
+	if q.purgeDeletedMessagesStmt, err = db.PrepareContext(ctx, PurgeDeletedMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedMessages: %w", err)
+	}
+	if q.purgeDeletedTopicsStmt, err = db.PrepareContext(ctx, PurgeDeletedTopics); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedTopics: %w", err)
+	}
+	if q.purgeFirehoseEventsStmt, err = db.PrepareContext(ctx, PurgeFirehoseEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeFirehoseEvents: %w", err)
+	}
+	if q.purgeIdleSessionsStmt, err = db.PrepareContext(ctx, PurgeIdleSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeIdleSessions: %w", err)
+	}
+	if q.queueParticipationRetryStmt, err = db.PrepareContext(ctx, QueueParticipationRetry); err != nil {
+		return nil, fmt.Errorf("error preparing query QueueParticipationRetry: %w", err)
+	}
+	if q.removeCommunityModeratorStmt, err = db.PrepareContext(ctx, RemoveCommunityModerator); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveCommunityModerator: %w", err)
+	}
+	if q.rescheduleParticipationRetryStmt, err = db.PrepareContext(ctx, RescheduleParticipationRetry); err != nil {
+		return nil, fmt.Errorf("error preparing query RescheduleParticipationRetry: %w", err)
+	}
+	if q.restoreMessageStmt, err = db.PrepareContext(ctx, RestoreMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreMessage: %w", err)
+	}
+	if q.restoreTopicStmt, err = db.PrepareContext(ctx, RestoreTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreTopic: %w", err)
+	}
+	if q.sampleMessagesStmt, err = db.PrepareContext(ctx, SampleMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SampleMessages: %w", err)
+	}
+	if q.sampleTopicsStmt, err = db.PrepareContext(ctx, SampleTopics); err != nil {
+		return nil, fmt.Errorf("error preparing query SampleTopics: %w", err)
+	}
+	if q.saveMirrorCursorStmt, err = db.PrepareContext(ctx, SaveMirrorCursor); err != nil {
+		return nil, fmt.Errorf("error preparing query SaveMirrorCursor: %w", err)
+	}
+	if q.saveMirrorRecordStmt, err = db.PrepareContext(ctx, SaveMirrorRecord); err != nil {
+		return nil, fmt.Errorf("error preparing query SaveMirrorRecord: %w", err)
+	}
+	if q.softDeleteMessageStmt, err = db.PrepareContext(ctx, SoftDeleteMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query SoftDeleteMessage: %w", err)
+	}
+	if q.softDeleteTopicStmt, err = db.PrepareContext(ctx, SoftDeleteTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query SoftDeleteTopic: %w", err)
+	}
+	if q.touchAccessTokenStmt, err = db.PrepareContext(ctx, TouchAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchAccessToken: %w", err)
+	}
+	if q.touchSessionStmt, err = db.PrepareContext(ctx, TouchSession); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchSession: %w", err)
+	}
 	if q.updateParticipationStatusStmt, err = db.PrepareContext(ctx, UpdateParticipationStatus); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateParticipationStatus: %w", err)
 	}
+	if q.updateTopicHotRankStmt, err = db.PrepareContext(ctx, UpdateTopicHotRank); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateTopicHotRank: %w", err)
+	}
 	if q.updateTopicSelectedAnswerStmt, err = db.PrepareContext(ctx, UpdateTopicSelectedAnswer); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateTopicSelectedAnswer: %w", err)
 	}
+	if q.upsertCommunityCategoryStmt, err = db.PrepareContext(ctx, UpsertCommunityCategory); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertCommunityCategory: %w", err)
+	}
+	if q.upsertMirrorMessageStmt, err = db.PrepareContext(ctx, UpsertMirrorMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertMirrorMessage: %w", err)
+	}
+	if q.upsertMirrorTopicStmt, err = db.PrepareContext(ctx, UpsertMirrorTopic); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertMirrorTopic: %w", err)
+	}
+	if q.upsertReadMarkerStmt, err = db.PrepareContext(ctx, UpsertReadMarker); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertReadMarker: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addCommunityModeratorStmt != nil {
+		if cerr := q.addCommunityModeratorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addCommunityModeratorStmt: %w", cerr)
+		}
+	}
+	if q.addTopicStatsStmt != nil {
+		if cerr := q.addTopicStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addTopicStatsStmt: %w", cerr)
+		}
+	}
+	if q.adjustTopicMessageCountStmt != nil {
+		if cerr := q.adjustTopicMessageCountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing adjustTopicMessageCountStmt: %w", cerr)
+		}
+	}
+	if q.appendFirehoseEventStmt != nil {
+		if cerr := q.appendFirehoseEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing appendFirehoseEventStmt: %w", cerr)
+		}
+	}
+	if q.appendLabelStmt != nil {
+		if cerr := q.appendLabelStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing appendLabelStmt: %w", cerr)
+		}
+	}
+	if q.countAuthorMessagesSinceStmt != nil {
+		if cerr := q.countAuthorMessagesSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAuthorMessagesSinceStmt: %w", cerr)
+		}
+	}
+	if q.countMirrorTopicStmt != nil {
+		if cerr := q.countMirrorTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countMirrorTopicStmt: %w", cerr)
+		}
+	}
+	if q.countTopicModeratorStmt != nil {
+		if cerr := q.countTopicModeratorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countTopicModeratorStmt: %w", cerr)
+		}
+	}
+	if q.countTopicResponsesStmt != nil {
+		if cerr := q.countTopicResponsesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countTopicResponsesStmt: %w", cerr)
+		}
+	}
+	if q.countUnreadMessagesStmt != nil {
+		if cerr := q.countUnreadMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUnreadMessagesStmt: %w", cerr)
+		}
+	}
+	if q.createAccessTokenStmt != nil {
+		if cerr := q.createAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.createCommunityStmt != nil {
+		if cerr := q.createCommunityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCommunityStmt: %w", cerr)
+		}
+	}
+	if q.createCommunityDomainStmt != nil {
+		if cerr := q.createCommunityDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCommunityDomainStmt: %w", cerr)
+		}
+	}
 	if q.createMessageStmt != nil {
 		if cerr := q.createMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
@@ -90,26 +443,156 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createParticipationStmt: %w", cerr)
 		}
 	}
+	if q.createSessionStmt != nil {
+		if cerr := q.createSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
+		}
+	}
 	if q.createTopicStmt != nil {
 		if cerr := q.createTopicStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createTopicStmt: %w", cerr)
 		}
 	}
+	if q.deleteAccessTokenStmt != nil {
+		if cerr := q.deleteAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.deleteAccessTokensByDidStmt != nil {
+		if cerr := q.deleteAccessTokensByDidStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteAccessTokensByDidStmt: %w", cerr)
+		}
+	}
+	if q.deleteCommunityCategoryStmt != nil {
+		if cerr := q.deleteCommunityCategoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCommunityCategoryStmt: %w", cerr)
+		}
+	}
+	if q.deleteCommunityDomainStmt != nil {
+		if cerr := q.deleteCommunityDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCommunityDomainStmt: %w", cerr)
+		}
+	}
+	if q.deleteFirehoseEventsByAccountStmt != nil {
+		if cerr := q.deleteFirehoseEventsByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFirehoseEventsByAccountStmt: %w", cerr)
+		}
+	}
 	if q.deleteMessageStmt != nil {
 		if cerr := q.deleteMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.deleteMessagesByAccountStmt != nil {
+		if cerr := q.deleteMessagesByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessagesByAccountStmt: %w", cerr)
+		}
+	}
+	if q.deleteMirrorMessageStmt != nil {
+		if cerr := q.deleteMirrorMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMirrorMessageStmt: %w", cerr)
+		}
+	}
+	if q.deleteMirrorTopicStmt != nil {
+		if cerr := q.deleteMirrorTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMirrorTopicStmt: %w", cerr)
+		}
+	}
+	if q.deleteMirrorTopicMessagesStmt != nil {
+		if cerr := q.deleteMirrorTopicMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMirrorTopicMessagesStmt: %w", cerr)
+		}
+	}
 	if q.deleteParticipationStmt != nil {
 		if cerr := q.deleteParticipationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteParticipationStmt: %w", cerr)
 		}
 	}
+	if q.deleteParticipationRetriesByAccountStmt != nil {
+		if cerr := q.deleteParticipationRetriesByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteParticipationRetriesByAccountStmt: %w", cerr)
+		}
+	}
+	if q.deleteParticipationRetryStmt != nil {
+		if cerr := q.deleteParticipationRetryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteParticipationRetryStmt: %w", cerr)
+		}
+	}
+	if q.deleteParticipationsByAccountStmt != nil {
+		if cerr := q.deleteParticipationsByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteParticipationsByAccountStmt: %w", cerr)
+		}
+	}
+	if q.deleteReadMarkerStmt != nil {
+		if cerr := q.deleteReadMarkerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteReadMarkerStmt: %w", cerr)
+		}
+	}
+	if q.deleteReadMarkersByAccountStmt != nil {
+		if cerr := q.deleteReadMarkersByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteReadMarkersByAccountStmt: %w", cerr)
+		}
+	}
+	if q.deleteSessionStmt != nil {
+		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
+		}
+	}
+	if q.deleteSessionsByDidStmt != nil {
+		if cerr := q.deleteSessionsByDidStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSessionsByDidStmt: %w", cerr)
+		}
+	}
 	if q.deleteTopicStmt != nil {
 		if cerr := q.deleteTopicStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteTopicStmt: %w", cerr)
 		}
 	}
+	if q.deleteTopicStatsByAccountStmt != nil {
+		if cerr := q.deleteTopicStatsByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTopicStatsByAccountStmt: %w", cerr)
+		}
+	}
+	if q.deleteTopicsByAccountStmt != nil {
+		if cerr := q.deleteTopicsByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTopicsByAccountStmt: %w", cerr)
+		}
+	}
+	if q.getAccessTokenByHashStmt != nil {
+		if cerr := q.getAccessTokenByHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccessTokenByHashStmt: %w", cerr)
+		}
+	}
+	if q.getCommunityStmt != nil {
+		if cerr := q.getCommunityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCommunityStmt: %w", cerr)
+		}
+	}
+	if q.getCommunityDomainStmt != nil {
+		if cerr := q.getCommunityDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCommunityDomainStmt: %w", cerr)
+		}
+	}
+	if q.getDeletedMessageStmt != nil {
+		if cerr := q.getDeletedMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeletedMessageStmt: %w", cerr)
+		}
+	}
+	if q.getDeletedTopicStmt != nil {
+		if cerr := q.getDeletedTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeletedTopicStmt: %w", cerr)
+		}
+	}
+	if q.getLatestFirehoseEventTimeStmt != nil {
+		if cerr := q.getLatestFirehoseEventTimeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestFirehoseEventTimeStmt: %w", cerr)
+		}
+	}
+	if q.getLatestLabelSeqStmt != nil {
+		if cerr := q.getLatestLabelSeqStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestLabelSeqStmt: %w", cerr)
+		}
+	}
 	if q.getMessageStmt != nil {
 		if cerr := q.getMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
@@ -120,6 +603,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getMessagesByTopicStmt: %w", cerr)
 		}
 	}
+	if q.getMirrorCursorStmt != nil {
+		if cerr := q.getMirrorCursorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMirrorCursorStmt: %w", cerr)
+		}
+	}
+	if q.getMirrorRecordStmt != nil {
+		if cerr := q.getMirrorRecordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMirrorRecordStmt: %w", cerr)
+		}
+	}
+	if q.getMirrorTopicStmt != nil {
+		if cerr := q.getMirrorTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMirrorTopicStmt: %w", cerr)
+		}
+	}
 	if q.getParticipationStmt != nil {
 		if cerr := q.getParticipationStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getParticipationStmt: %w", cerr)
@@ -140,6 +638,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getRepliesByMessageStmt: %w", cerr)
 		}
 	}
+	if q.getSessionStmt != nil {
+		if cerr := q.getSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionStmt: %w", cerr)
+		}
+	}
 	if q.getTopicStmt != nil {
 		if cerr := q.getTopicStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTopicStmt: %w", cerr)
@@ -150,21 +653,286 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getTopicsByCategoryStmt: %w", cerr)
 		}
 	}
+	if q.getUnreadCountsStmt != nil {
+		if cerr := q.getUnreadCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUnreadCountsStmt: %w", cerr)
+		}
+	}
+	if q.getVerifiedDomainCommunityStmt != nil {
+		if cerr := q.getVerifiedDomainCommunityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getVerifiedDomainCommunityStmt: %w", cerr)
+		}
+	}
+	if q.incrementTopicActivityStmt != nil {
+		if cerr := q.incrementTopicActivityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing incrementTopicActivityStmt: %w", cerr)
+		}
+	}
+	if q.listAccessTokensByDidStmt != nil {
+		if cerr := q.listAccessTokensByDidStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAccessTokensByDidStmt: %w", cerr)
+		}
+	}
+	if q.listCommunitiesStmt != nil {
+		if cerr := q.listCommunitiesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCommunitiesStmt: %w", cerr)
+		}
+	}
+	if q.listCommunityCategoriesStmt != nil {
+		if cerr := q.listCommunityCategoriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCommunityCategoriesStmt: %w", cerr)
+		}
+	}
+	if q.listCommunityDomainsStmt != nil {
+		if cerr := q.listCommunityDomainsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCommunityDomainsStmt: %w", cerr)
+		}
+	}
+	if q.listCommunityModeratorsStmt != nil {
+		if cerr := q.listCommunityModeratorsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCommunityModeratorsStmt: %w", cerr)
+		}
+	}
+	if q.listDailyStatsStmt != nil {
+		if cerr := q.listDailyStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDailyStatsStmt: %w", cerr)
+		}
+	}
+	if q.listDueParticipationRetriesStmt != nil {
+		if cerr := q.listDueParticipationRetriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDueParticipationRetriesStmt: %w", cerr)
+		}
+	}
+	if q.listFirehoseEventsSinceStmt != nil {
+		if cerr := q.listFirehoseEventsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFirehoseEventsSinceStmt: %w", cerr)
+		}
+	}
+	if q.listLabelsByURIStmt != nil {
+		if cerr := q.listLabelsByURIStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLabelsByURIStmt: %w", cerr)
+		}
+	}
+	if q.listLabelsByURIPrefixStmt != nil {
+		if cerr := q.listLabelsByURIPrefixStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLabelsByURIPrefixStmt: %w", cerr)
+		}
+	}
+	if q.listLabelsSinceStmt != nil {
+		if cerr := q.listLabelsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLabelsSinceStmt: %w", cerr)
+		}
+	}
+	if q.listMessagesByAuthorStmt != nil {
+		if cerr := q.listMessagesByAuthorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessagesByAuthorStmt: %w", cerr)
+		}
+	}
+	if q.listMirrorMessagesStmt != nil {
+		if cerr := q.listMirrorMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMirrorMessagesStmt: %w", cerr)
+		}
+	}
+	if q.listMirrorTopicsStmt != nil {
+		if cerr := q.listMirrorTopicsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMirrorTopicsStmt: %w", cerr)
+		}
+	}
+	if q.listMirrorTopicsByCategoryStmt != nil {
+		if cerr := q.listMirrorTopicsByCategoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMirrorTopicsByCategoryStmt: %w", cerr)
+		}
+	}
+	if q.listParticipationRetriesStmt != nil {
+		if cerr := q.listParticipationRetriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listParticipationRetriesStmt: %w", cerr)
+		}
+	}
+	if q.listRelatedTopicCandidatesStmt != nil {
+		if cerr := q.listRelatedTopicCandidatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRelatedTopicCandidatesStmt: %w", cerr)
+		}
+	}
+	if q.listSessionsByDidStmt != nil {
+		if cerr := q.listSessionsByDidStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionsByDidStmt: %w", cerr)
+		}
+	}
+	if q.listTopTopicsByViewsStmt != nil {
+		if cerr := q.listTopTopicsByViewsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopTopicsByViewsStmt: %w", cerr)
+		}
+	}
+	if q.listTopicStatsStmt != nil {
+		if cerr := q.listTopicStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicStatsStmt: %w", cerr)
+		}
+	}
 	if q.listTopicsStmt != nil {
 		if cerr := q.listTopicsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listTopicsStmt: %w", cerr)
 		}
 	}
+	if q.listTopicsByActivityStmt != nil {
+		if cerr := q.listTopicsByActivityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicsByActivityStmt: %w", cerr)
+		}
+	}
+	if q.listTopicsByAuthorStmt != nil {
+		if cerr := q.listTopicsByAuthorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicsByAuthorStmt: %w", cerr)
+		}
+	}
+	if q.listTopicsByHotRankStmt != nil {
+		if cerr := q.listTopicsByHotRankStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicsByHotRankStmt: %w", cerr)
+		}
+	}
+	if q.listTopicsByMessageCountStmt != nil {
+		if cerr := q.listTopicsByMessageCountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicsByMessageCountStmt: %w", cerr)
+		}
+	}
+	if q.listTopicsMissingCreatorParticipationStmt != nil {
+		if cerr := q.listTopicsMissingCreatorParticipationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTopicsMissingCreatorParticipationStmt: %w", cerr)
+		}
+	}
+	if q.listTrendingCategoriesStmt != nil {
+		if cerr := q.listTrendingCategoriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTrendingCategoriesStmt: %w", cerr)
+		}
+	}
+	if q.listTrendingTopicsStmt != nil {
+		if cerr := q.listTrendingTopicsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTrendingTopicsStmt: %w", cerr)
+		}
+	}
+	if q.markCommunityDomainVerifiedStmt != nil {
+		if cerr := q.markCommunityDomainVerifiedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markCommunityDomainVerifiedStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedMessagesStmt != nil {
+		if cerr := q.purgeDeletedMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedMessagesStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedTopicsStmt != nil {
+		if cerr := q.purgeDeletedTopicsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedTopicsStmt: %w", cerr)
+		}
+	}
+	if q.purgeFirehoseEventsStmt != nil {
+		if cerr := q.purgeFirehoseEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeFirehoseEventsStmt: %w", cerr)
+		}
+	}
+	if q.purgeIdleSessionsStmt != nil {
+		if cerr := q.purgeIdleSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeIdleSessionsStmt: %w", cerr)
+		}
+	}
+	if q.queueParticipationRetryStmt != nil {
+		if cerr := q.queueParticipationRetryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing queueParticipationRetryStmt: %w", cerr)
+		}
+	}
+	if q.removeCommunityModeratorStmt != nil {
+		if cerr := q.removeCommunityModeratorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeCommunityModeratorStmt: %w", cerr)
+		}
+	}
+	if q.rescheduleParticipationRetryStmt != nil {
+		if cerr := q.rescheduleParticipationRetryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rescheduleParticipationRetryStmt: %w", cerr)
+		}
+	}
+	if q.restoreMessageStmt != nil {
+		if cerr := q.restoreMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreMessageStmt: %w", cerr)
+		}
+	}
+	if q.restoreTopicStmt != nil {
+		if cerr := q.restoreTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreTopicStmt: %w", cerr)
+		}
+	}
+	if q.sampleMessagesStmt != nil {
+		if cerr := q.sampleMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing sampleMessagesStmt: %w", cerr)
+		}
+	}
+	if q.sampleTopicsStmt != nil {
+		if cerr := q.sampleTopicsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing sampleTopicsStmt: %w", cerr)
+		}
+	}
+	if q.saveMirrorCursorStmt != nil {
+		if cerr := q.saveMirrorCursorStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing saveMirrorCursorStmt: %w", cerr)
+		}
+	}
+	if q.saveMirrorRecordStmt != nil {
+		if cerr := q.saveMirrorRecordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing saveMirrorRecordStmt: %w", cerr)
+		}
+	}
+	if q.softDeleteMessageStmt != nil {
+		if cerr := q.softDeleteMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing softDeleteMessageStmt: %w", cerr)
+		}
+	}
+	if q.softDeleteTopicStmt != nil {
+		if cerr := q.softDeleteTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing softDeleteTopicStmt: %w", cerr)
+		}
+	}
+	if q.touchAccessTokenStmt != nil {
+		if cerr := q.touchAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.touchSessionStmt != nil {
+		if cerr := q.touchSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchSessionStmt: %w", cerr)
+		}
+	}
 	if q.updateParticipationStatusStmt != nil {
 		if cerr := q.updateParticipationStatusStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateParticipationStatusStmt: %w", cerr)
 		}
 	}
+	if q.updateTopicHotRankStmt != nil {
+		if cerr := q.updateTopicHotRankStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateTopicHotRankStmt: %w", cerr)
+		}
+	}
 	if q.updateTopicSelectedAnswerStmt != nil {
 		if cerr := q.updateTopicSelectedAnswerStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateTopicSelectedAnswerStmt: %w", cerr)
 		}
 	}
+	if q.upsertCommunityCategoryStmt != nil {
+		if cerr := q.upsertCommunityCategoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertCommunityCategoryStmt: %w", cerr)
+		}
+	}
+	if q.upsertMirrorMessageStmt != nil {
+		if cerr := q.upsertMirrorMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertMirrorMessageStmt: %w", cerr)
+		}
+	}
+	if q.upsertMirrorTopicStmt != nil {
+		if cerr := q.upsertMirrorTopicStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertMirrorTopicStmt: %w", cerr)
+		}
+	}
+	if q.upsertReadMarkerStmt != nil {
+		if cerr := q.upsertReadMarkerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertReadMarkerStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -202,47 +970,239 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                            DBTX
-	tx                            *sql.Tx
-	createMessageStmt             *sql.Stmt
-	createParticipationStmt       *sql.Stmt
-	createTopicStmt               *sql.Stmt
-	deleteMessageStmt             *sql.Stmt
-	deleteParticipationStmt       *sql.Stmt
-	deleteTopicStmt               *sql.Stmt
-	getMessageStmt                *sql.Stmt
-	getMessagesByTopicStmt        *sql.Stmt
-	getParticipationStmt          *sql.Stmt
-	getParticipationsByTopicStmt  *sql.Stmt
-	getParticipationsByUserStmt   *sql.Stmt
-	getRepliesByMessageStmt       *sql.Stmt
-	getTopicStmt                  *sql.Stmt
-	getTopicsByCategoryStmt       *sql.Stmt
-	listTopicsStmt                *sql.Stmt
-	updateParticipationStatusStmt *sql.Stmt
-	updateTopicSelectedAnswerStmt *sql.Stmt
+	db                                        DBTX
+	tx                                        *sql.Tx
+	addCommunityModeratorStmt                 *sql.Stmt
+	addTopicStatsStmt                         *sql.Stmt
+	adjustTopicMessageCountStmt               *sql.Stmt
+	appendFirehoseEventStmt                   *sql.Stmt
+	appendLabelStmt                           *sql.Stmt
+	countAuthorMessagesSinceStmt              *sql.Stmt
+	countMirrorTopicStmt                      *sql.Stmt
+	countTopicModeratorStmt                   *sql.Stmt
+	countTopicResponsesStmt                   *sql.Stmt
+	countUnreadMessagesStmt                   *sql.Stmt
+	createAccessTokenStmt                     *sql.Stmt
+	createCommunityStmt                       *sql.Stmt
+	createCommunityDomainStmt                 *sql.Stmt
+	createMessageStmt                         *sql.Stmt
+	createParticipationStmt                   *sql.Stmt
+	createSessionStmt                         *sql.Stmt
+	createTopicStmt                           *sql.Stmt
+	deleteAccessTokenStmt                     *sql.Stmt
+	deleteAccessTokensByDidStmt               *sql.Stmt
+	deleteCommunityCategoryStmt               *sql.Stmt
+	deleteCommunityDomainStmt                 *sql.Stmt
+	deleteFirehoseEventsByAccountStmt         *sql.Stmt
+	deleteMessageStmt                         *sql.Stmt
+	deleteMessagesByAccountStmt               *sql.Stmt
+	deleteMirrorMessageStmt                   *sql.Stmt
+	deleteMirrorTopicStmt                     *sql.Stmt
+	deleteMirrorTopicMessagesStmt             *sql.Stmt
+	deleteParticipationStmt                   *sql.Stmt
+	deleteParticipationRetriesByAccountStmt   *sql.Stmt
+	deleteParticipationRetryStmt              *sql.Stmt
+	deleteParticipationsByAccountStmt         *sql.Stmt
+	deleteReadMarkerStmt                      *sql.Stmt
+	deleteReadMarkersByAccountStmt            *sql.Stmt
+	deleteSessionStmt                         *sql.Stmt
+	deleteSessionsByDidStmt                   *sql.Stmt
+	deleteTopicStmt                           *sql.Stmt
+	deleteTopicStatsByAccountStmt             *sql.Stmt
+	deleteTopicsByAccountStmt                 *sql.Stmt
+	getAccessTokenByHashStmt                  *sql.Stmt
+	getCommunityStmt                          *sql.Stmt
+	getCommunityDomainStmt                    *sql.Stmt
+	getDeletedMessageStmt                     *sql.Stmt
+	getDeletedTopicStmt                       *sql.Stmt
+	getLatestFirehoseEventTimeStmt            *sql.Stmt
+	getLatestLabelSeqStmt                     *sql.Stmt
+	getMessageStmt                            *sql.Stmt
+	getMessagesByTopicStmt                    *sql.Stmt
+	getMirrorCursorStmt                       *sql.Stmt
+	getMirrorRecordStmt                       *sql.Stmt
+	getMirrorTopicStmt                        *sql.Stmt
+	getParticipationStmt                      *sql.Stmt
+	getParticipationsByTopicStmt              *sql.Stmt
+	getParticipationsByUserStmt               *sql.Stmt
+	getRepliesByMessageStmt                   *sql.Stmt
+	getSessionStmt                            *sql.Stmt
+	getTopicStmt                              *sql.Stmt
+	getTopicsByCategoryStmt                   *sql.Stmt
+	getUnreadCountsStmt                       *sql.Stmt
+	getVerifiedDomainCommunityStmt            *sql.Stmt
+	incrementTopicActivityStmt                *sql.Stmt
+	listAccessTokensByDidStmt                 *sql.Stmt
+	listCommunitiesStmt                       *sql.Stmt
+	listCommunityCategoriesStmt               *sql.Stmt
+	listCommunityDomainsStmt                  *sql.Stmt
+	listCommunityModeratorsStmt               *sql.Stmt
+	listDailyStatsStmt                        *sql.Stmt
+	listDueParticipationRetriesStmt           *sql.Stmt
+	listFirehoseEventsSinceStmt               *sql.Stmt
+	listLabelsByURIStmt                       *sql.Stmt
+	listLabelsByURIPrefixStmt                 *sql.Stmt
+	listLabelsSinceStmt                       *sql.Stmt
+	listMessagesByAuthorStmt                  *sql.Stmt
+	listMirrorMessagesStmt                    *sql.Stmt
+	listMirrorTopicsStmt                      *sql.Stmt
+	listMirrorTopicsByCategoryStmt            *sql.Stmt
+	listParticipationRetriesStmt              *sql.Stmt
+	listRelatedTopicCandidatesStmt            *sql.Stmt
+	listSessionsByDidStmt                     *sql.Stmt
+	listTopTopicsByViewsStmt                  *sql.Stmt
+	listTopicStatsStmt                        *sql.Stmt
+	listTopicsStmt                            *sql.Stmt
+	listTopicsByActivityStmt                  *sql.Stmt
+	listTopicsByAuthorStmt                    *sql.Stmt
+	listTopicsByHotRankStmt                   *sql.Stmt
+	listTopicsByMessageCountStmt              *sql.Stmt
+	listTopicsMissingCreatorParticipationStmt *sql.Stmt
+	listTrendingCategoriesStmt                *sql.Stmt
+	listTrendingTopicsStmt                    *sql.Stmt
+	markCommunityDomainVerifiedStmt           *sql.Stmt
+	purgeDeletedMessagesStmt                  *sql.Stmt
+	purgeDeletedTopicsStmt                    *sql.Stmt
+	purgeFirehoseEventsStmt                   *sql.Stmt
+	purgeIdleSessionsStmt                     *sql.Stmt
+	queueParticipationRetryStmt               *sql.Stmt
+	removeCommunityModeratorStmt              *sql.Stmt
+	rescheduleParticipationRetryStmt          *sql.Stmt
+	restoreMessageStmt                        *sql.Stmt
+	restoreTopicStmt                          *sql.Stmt
+	sampleMessagesStmt                        *sql.Stmt
+	sampleTopicsStmt                          *sql.Stmt
+	saveMirrorCursorStmt                      *sql.Stmt
+	saveMirrorRecordStmt                      *sql.Stmt
+	softDeleteMessageStmt                     *sql.Stmt
+	softDeleteTopicStmt                       *sql.Stmt
+	touchAccessTokenStmt                      *sql.Stmt
+	touchSessionStmt                          *sql.Stmt
+	updateParticipationStatusStmt             *sql.Stmt
+	updateTopicHotRankStmt                    *sql.Stmt
+	updateTopicSelectedAnswerStmt             *sql.Stmt
+	upsertCommunityCategoryStmt               *sql.Stmt
+	upsertMirrorMessageStmt                   *sql.Stmt
+	upsertMirrorTopicStmt                     *sql.Stmt
+	upsertReadMarkerStmt                      *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                            tx,
-		tx:                            tx,
-		createMessageStmt:             q.createMessageStmt,
-		createParticipationStmt:       q.createParticipationStmt,
-		createTopicStmt:               q.createTopicStmt,
-		deleteMessageStmt:             q.deleteMessageStmt,
-		deleteParticipationStmt:       q.deleteParticipationStmt,
-		deleteTopicStmt:               q.deleteTopicStmt,
-		getMessageStmt:                q.getMessageStmt,
-		getMessagesByTopicStmt:        q.getMessagesByTopicStmt,
-		getParticipationStmt:          q.getParticipationStmt,
-		getParticipationsByTopicStmt:  q.getParticipationsByTopicStmt,
-		getParticipationsByUserStmt:   q.getParticipationsByUserStmt,
-		getRepliesByMessageStmt:       q.getRepliesByMessageStmt,
-		getTopicStmt:                  q.getTopicStmt,
-		getTopicsByCategoryStmt:       q.getTopicsByCategoryStmt,
-		listTopicsStmt:                q.listTopicsStmt,
-		updateParticipationStatusStmt: q.updateParticipationStatusStmt,
-		updateTopicSelectedAnswerStmt: q.updateTopicSelectedAnswerStmt,
+		db:                                        tx,
+		tx:                                        tx,
+		addCommunityModeratorStmt:                 q.addCommunityModeratorStmt,
+		addTopicStatsStmt:                         q.addTopicStatsStmt,
+		adjustTopicMessageCountStmt:               q.adjustTopicMessageCountStmt,
+		appendFirehoseEventStmt:                   q.appendFirehoseEventStmt,
+		appendLabelStmt:                           q.appendLabelStmt,
+		countAuthorMessagesSinceStmt:              q.countAuthorMessagesSinceStmt,
+		countMirrorTopicStmt:                      q.countMirrorTopicStmt,
+		countTopicModeratorStmt:                   q.countTopicModeratorStmt,
+		countTopicResponsesStmt:                   q.countTopicResponsesStmt,
+		countUnreadMessagesStmt:                   q.countUnreadMessagesStmt,
+		createAccessTokenStmt:                     q.createAccessTokenStmt,
+		createCommunityStmt:                       q.createCommunityStmt,
+		createCommunityDomainStmt:                 q.createCommunityDomainStmt,
+		createMessageStmt:                         q.createMessageStmt,
+		createParticipationStmt:                   q.createParticipationStmt,
+		createSessionStmt:                         q.createSessionStmt,
+		createTopicStmt:                           q.createTopicStmt,
+		deleteAccessTokenStmt:                     q.deleteAccessTokenStmt,
+		deleteAccessTokensByDidStmt:               q.deleteAccessTokensByDidStmt,
+		deleteCommunityCategoryStmt:               q.deleteCommunityCategoryStmt,
+		deleteCommunityDomainStmt:                 q.deleteCommunityDomainStmt,
+		deleteFirehoseEventsByAccountStmt:         q.deleteFirehoseEventsByAccountStmt,
+		deleteMessageStmt:                         q.deleteMessageStmt,
+		deleteMessagesByAccountStmt:               q.deleteMessagesByAccountStmt,
+		deleteMirrorMessageStmt:                   q.deleteMirrorMessageStmt,
+		deleteMirrorTopicStmt:                     q.deleteMirrorTopicStmt,
+		deleteMirrorTopicMessagesStmt:             q.deleteMirrorTopicMessagesStmt,
+		deleteParticipationStmt:                   q.deleteParticipationStmt,
+		deleteParticipationRetriesByAccountStmt:   q.deleteParticipationRetriesByAccountStmt,
+		deleteParticipationRetryStmt:              q.deleteParticipationRetryStmt,
+		deleteParticipationsByAccountStmt:         q.deleteParticipationsByAccountStmt,
+		deleteReadMarkerStmt:                      q.deleteReadMarkerStmt,
+		deleteReadMarkersByAccountStmt:            q.deleteReadMarkersByAccountStmt,
+		deleteSessionStmt:                         q.deleteSessionStmt,
+		deleteSessionsByDidStmt:                   q.deleteSessionsByDidStmt,
+		deleteTopicStmt:                           q.deleteTopicStmt,
+		deleteTopicStatsByAccountStmt:             q.deleteTopicStatsByAccountStmt,
+		deleteTopicsByAccountStmt:                 q.deleteTopicsByAccountStmt,
+		getAccessTokenByHashStmt:                  q.getAccessTokenByHashStmt,
+		getCommunityStmt:                          q.getCommunityStmt,
+		getCommunityDomainStmt:                    q.getCommunityDomainStmt,
+		getDeletedMessageStmt:                     q.getDeletedMessageStmt,
+		getDeletedTopicStmt:                       q.getDeletedTopicStmt,
+		getLatestFirehoseEventTimeStmt:            q.getLatestFirehoseEventTimeStmt,
+		getLatestLabelSeqStmt:                     q.getLatestLabelSeqStmt,
+		getMessageStmt:                            q.getMessageStmt,
+		getMessagesByTopicStmt:                    q.getMessagesByTopicStmt,
+		getMirrorCursorStmt:                       q.getMirrorCursorStmt,
+		getMirrorRecordStmt:                       q.getMirrorRecordStmt,
+		getMirrorTopicStmt:                        q.getMirrorTopicStmt,
+		getParticipationStmt:                      q.getParticipationStmt,
+		getParticipationsByTopicStmt:              q.getParticipationsByTopicStmt,
+		getParticipationsByUserStmt:               q.getParticipationsByUserStmt,
+		getRepliesByMessageStmt:                   q.getRepliesByMessageStmt,
+		getSessionStmt:                            q.getSessionStmt,
+		getTopicStmt:                              q.getTopicStmt,
+		getTopicsByCategoryStmt:                   q.getTopicsByCategoryStmt,
+		getUnreadCountsStmt:                       q.getUnreadCountsStmt,
+		getVerifiedDomainCommunityStmt:            q.getVerifiedDomainCommunityStmt,
+		incrementTopicActivityStmt:                q.incrementTopicActivityStmt,
+		listAccessTokensByDidStmt:                 q.listAccessTokensByDidStmt,
+		listCommunitiesStmt:                       q.listCommunitiesStmt,
+		listCommunityCategoriesStmt:               q.listCommunityCategoriesStmt,
+		listCommunityDomainsStmt:                  q.listCommunityDomainsStmt,
+		listCommunityModeratorsStmt:               q.listCommunityModeratorsStmt,
+		listDailyStatsStmt:                        q.listDailyStatsStmt,
+		listDueParticipationRetriesStmt:           q.listDueParticipationRetriesStmt,
+		listFirehoseEventsSinceStmt:               q.listFirehoseEventsSinceStmt,
+		listLabelsByURIStmt:                       q.listLabelsByURIStmt,
+		listLabelsByURIPrefixStmt:                 q.listLabelsByURIPrefixStmt,
+		listLabelsSinceStmt:                       q.listLabelsSinceStmt,
+		listMessagesByAuthorStmt:                  q.listMessagesByAuthorStmt,
+		listMirrorMessagesStmt:                    q.listMirrorMessagesStmt,
+		listMirrorTopicsStmt:                      q.listMirrorTopicsStmt,
+		listMirrorTopicsByCategoryStmt:            q.listMirrorTopicsByCategoryStmt,
+		listParticipationRetriesStmt:              q.listParticipationRetriesStmt,
+		listRelatedTopicCandidatesStmt:            q.listRelatedTopicCandidatesStmt,
+		listSessionsByDidStmt:                     q.listSessionsByDidStmt,
+		listTopTopicsByViewsStmt:                  q.listTopTopicsByViewsStmt,
+		listTopicStatsStmt:                        q.listTopicStatsStmt,
+		listTopicsStmt:                            q.listTopicsStmt,
+		listTopicsByActivityStmt:                  q.listTopicsByActivityStmt,
+		listTopicsByAuthorStmt:                    q.listTopicsByAuthorStmt,
+		listTopicsByHotRankStmt:                   q.listTopicsByHotRankStmt,
+		listTopicsByMessageCountStmt:              q.listTopicsByMessageCountStmt,
+		listTopicsMissingCreatorParticipationStmt: q.listTopicsMissingCreatorParticipationStmt,
+		listTrendingCategoriesStmt:                q.listTrendingCategoriesStmt,
+		listTrendingTopicsStmt:                    q.listTrendingTopicsStmt,
+		markCommunityDomainVerifiedStmt:           q.markCommunityDomainVerifiedStmt,
+		purgeDeletedMessagesStmt:                  q.purgeDeletedMessagesStmt,
+		purgeDeletedTopicsStmt:                    q.purgeDeletedTopicsStmt,
+		purgeFirehoseEventsStmt:                   q.purgeFirehoseEventsStmt,
+		purgeIdleSessionsStmt:                     q.purgeIdleSessionsStmt,
+		queueParticipationRetryStmt:               q.queueParticipationRetryStmt,
+		removeCommunityModeratorStmt:              q.removeCommunityModeratorStmt,
+		rescheduleParticipationRetryStmt:          q.rescheduleParticipationRetryStmt,
+		restoreMessageStmt:                        q.restoreMessageStmt,
+		restoreTopicStmt:                          q.restoreTopicStmt,
+		sampleMessagesStmt:                        q.sampleMessagesStmt,
+		sampleTopicsStmt:                          q.sampleTopicsStmt,
+		saveMirrorCursorStmt:                      q.saveMirrorCursorStmt,
+		saveMirrorRecordStmt:                      q.saveMirrorRecordStmt,
+		softDeleteMessageStmt:                     q.softDeleteMessageStmt,
+		softDeleteTopicStmt:                       q.softDeleteTopicStmt,
+		touchAccessTokenStmt:                      q.touchAccessTokenStmt,
+		touchSessionStmt:                          q.touchSessionStmt,
+		updateParticipationStatusStmt:             q.updateParticipationStatusStmt,
+		updateTopicHotRankStmt:                    q.updateTopicHotRankStmt,
+		updateTopicSelectedAnswerStmt:             q.updateTopicSelectedAnswerStmt,
+		upsertCommunityCategoryStmt:               q.upsertCommunityCategoryStmt,
+		upsertMirrorMessageStmt:                   q.upsertMirrorMessageStmt,
+		upsertMirrorTopicStmt:                     q.upsertMirrorTopicStmt,
+		upsertReadMarkerStmt:                      q.upsertReadMarkerStmt,
 	}
 }
@@ -3,7 +3,9 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -11,8 +13,16 @@ import (
 	"github.com/jrschumacher/dis.quest/internal/logger"
 	
 	// Database drivers
-	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLite tuning. WAL lets readers proceed while a write is in progress, so a
+// file database gets a small pool; writers still go one at a time, and wait
+// up to the busy timeout for the lock before failing with SQLITE_BUSY.
+const (
+	sqliteMaxOpenConns = 4
+	sqliteBusyTimeout  = 10 * time.Second
 )
 
 // DatabaseDriver represents the type of database driver
@@ -66,15 +76,15 @@ func OpenDatabase(cfg *config.Config) (*sql.DB, DatabaseDriver, error) {
 	// Adjust settings based on driver and environment
 	switch dbConfig.Driver {
 	case SQLite:
-		// SQLite doesn't benefit from connection pooling
-		dbConfig.MaxOpenConns = 1
-		dbConfig.MaxIdleConns = 1
+		dbConfig.MaxOpenConns = sqliteMaxOpenConns
+		dbConfig.MaxIdleConns = sqliteMaxOpenConns
 		dbConfig.ConnMaxLifetime = 0 // No limit for SQLite
-		
-		// Add SQLite-specific parameters if not present
-		if !strings.Contains(dbConfig.ConnectionString, "?") {
-			dbConfig.ConnectionString += "?_busy_timeout=10000&_journal_mode=WAL&_foreign_keys=on"
+		if isSQLiteMemory(dbConfig.ConnectionString) {
+			// Each connection to :memory: would open its own empty database
+			dbConfig.MaxOpenConns = 1
+			dbConfig.MaxIdleConns = 1
 		}
+		dbConfig.ConnectionString = sqliteDSN(dbConfig.ConnectionString)
 		
 	case PostgreSQL:
 		// Use connection pooling for PostgreSQL
@@ -115,6 +125,49 @@ func OpenDatabase(cfg *config.Config) (*sql.DB, DatabaseDriver, error) {
 	return db, dbConfig.Driver, nil
 }
 
+// sqliteDSN adds the connection parameters every pooled SQLite connection
+// needs, keeping any the connection string already sets. Immediate
+// transactions take the write lock at BEGIN, where the busy timeout applies,
+// rather than failing with SQLITE_BUSY when a read upgrades to a write.
+func sqliteDSN(connectionString string) string {
+	path, rawQuery, _ := strings.Cut(connectionString, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Leave a connection string we cannot parse for the driver to reject
+		return connectionString
+	}
+	defaults := map[string]string{
+		"_busy_timeout": fmt.Sprint(sqliteBusyTimeout.Milliseconds()),
+		"_journal_mode": "WAL",
+		"_synchronous":  "NORMAL",
+		"_foreign_keys": "on",
+		"_txlock":       "immediate",
+	}
+	for key, value := range defaults {
+		if !params.Has(key) {
+			params.Set(key, value)
+		}
+	}
+	return path + "?" + params.Encode()
+}
+
+// isSQLiteMemory reports whether the connection string names an in-memory
+// database
+func isSQLiteMemory(connectionString string) bool {
+	return strings.HasPrefix(connectionString, ":memory:") ||
+		strings.Contains(connectionString, "mode=memory")
+}
+
+// isBusy reports whether err is SQLite giving up on a lock another
+// connection or process holds
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
 // initializeDatabase applies driver-specific initialization
 func initializeDatabase(db *sql.DB, driver DatabaseDriver) error {
 	switch driver {
@@ -124,7 +177,8 @@ func initializeDatabase(db *sql.DB, driver DatabaseDriver) error {
 			return fmt.Errorf("failed to enable foreign keys: %w", err)
 		}
 		
-		// Set other useful SQLite pragmas
+		// Set other useful SQLite pragmas. Journal mode and synchronous are
+		// also in the DSN, which applies them to every pooled connection.
 		pragmas := []string{
 			"PRAGMA journal_mode = WAL",
 			"PRAGMA synchronous = NORMAL", 
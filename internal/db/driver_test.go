@@ -1,7 +1,11 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 func TestDetectDriver(t *testing.T) {
@@ -129,4 +133,41 @@ func TestSupportsReturning(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		name             string
+		connectionString string
+		want             string
+	}{
+		{
+			name:             "plain path gets every default",
+			connectionString: "./test.db",
+			want:             "./test.db?_busy_timeout=10000&_foreign_keys=on&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate",
+		},
+		{
+			name:             "existing parameters are kept",
+			connectionString: "file:test.db?cache=shared&_busy_timeout=500",
+			want:             "file:test.db?_busy_timeout=500&_foreign_keys=on&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate&cache=shared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqliteDSN(tt.connectionString); got != tt.want {
+				t.Errorf("sqliteDSN(%q) = %q, want %q", tt.connectionString, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBusy(t *testing.T) {
+	busy := fmt.Errorf("failed to begin transaction: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+	if !isBusy(busy) {
+		t.Error("expected a wrapped SQLITE_BUSY to be busy")
+	}
+	if isBusy(sqlite3.Error{Code: sqlite3.ErrNo(19)}) || isBusy(errors.New("other")) || isBusy(nil) {
+		t.Error("expected other errors not to be busy")
+	}
+}
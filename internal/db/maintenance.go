@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tablePrefix marks the tables the application owns
+const tablePrefix = "quest_dis_"
+
+// ErrUnversioned is returned by SchemaVersion when tern has never migrated
+// the database
+var ErrUnversioned = errors.New("database schema is not versioned")
+
+// TableStats is the row count of one application table
+type TableStats struct {
+	Name string
+	Rows int64
+}
+
+// DatabaseStats summarizes the size of the database
+type DatabaseStats struct {
+	Driver    DatabaseDriver
+	SizeBytes int64
+	Tables    []TableStats
+}
+
+// Vacuum reclaims space left by deleted rows and refreshes the planner's
+// statistics. SQLite rewrites the whole file, so run it while traffic is low.
+func (s *Service) Vacuum(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "ANALYZE"); err != nil {
+		return fmt.Errorf("failed to analyze: %w", err)
+	}
+	return nil
+}
+
+// Reindex rebuilds the indexes of every application table
+func (s *Service) Reindex(ctx context.Context) error {
+	tables, err := s.tables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		statement := "REINDEX " + quoteIdentifier(table)
+		if s.IsPostgreSQL() {
+			statement = "REINDEX TABLE " + quoteIdentifier(table)
+		}
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to reindex %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Stats counts the rows of every application table and measures the
+// database's size on disk
+func (s *Service) Stats(ctx context.Context) (*DatabaseStats, error) {
+	tables, err := s.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &DatabaseStats{Driver: s.driver}
+	for _, table := range tables {
+		var rows int64
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteIdentifier(table)).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		stats.Tables = append(stats.Tables, TableStats{Name: table, Rows: rows})
+	}
+
+	sizeQuery := "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"
+	if s.IsPostgreSQL() {
+		sizeQuery = "SELECT pg_database_size(current_database())"
+	}
+	if err := s.db.QueryRowContext(ctx, sizeQuery).Scan(&stats.SizeBytes); err != nil {
+		return nil, fmt.Errorf("failed to measure database size: %w", err)
+	}
+	return stats, nil
+}
+
+// SchemaVersion returns the number of the last migration tern applied to
+// the database
+func (s *Service) SchemaVersion(ctx context.Context) (int, error) {
+	exists := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'"
+	if s.IsPostgreSQL() {
+		exists = "SELECT COUNT(*) FROM pg_tables WHERE schemaname = current_schema() AND tablename = 'schema_version'"
+	}
+	var tables int
+	if err := s.db.QueryRowContext(ctx, exists).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to look up schema version: %w", err)
+	}
+	if tables == 0 {
+		return 0, ErrUnversioned
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, "SELECT version FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// tables lists the application's tables by name
+func (s *Service) tables(ctx context.Context) ([]string, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE '" + tablePrefix + "%' ORDER BY name"
+	if s.IsPostgreSQL() {
+		query = "SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename LIKE '" + tablePrefix + "%' ORDER BY tablename"
+	}
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// quoteIdentifier quotes a table name for both SQLite and PostgreSQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
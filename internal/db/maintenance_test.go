@@ -0,0 +1,29 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	if _, err := dbService.SchemaVersion(ctx); !errors.Is(err, db.ErrUnversioned) {
+		t.Fatalf("SchemaVersion error = %v, want ErrUnversioned", err)
+	}
+
+	// tern records the last migration it applied in schema_version
+	if _, err := dbService.DB().ExecContext(ctx, "CREATE TABLE schema_version (version INTEGER NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbService.DB().ExecContext(ctx, "INSERT INTO schema_version (version) VALUES (17)"); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := dbService.SchemaVersion(ctx); err != nil || version != 17 {
+		t.Errorf("SchemaVersion = %d, %v; want 17", version, err)
+	}
+}
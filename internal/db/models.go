@@ -9,15 +9,118 @@ import (
 	"time"
 )
 
+type AccessToken struct {
+	ID         string       `json:"id"`
+	Did        string       `json:"did"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     string       `json:"scopes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+}
+
+type Community struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CommunityCategory struct {
+	Community string `json:"community"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+}
+
+type CommunityDomain struct {
+	Domain             string         `json:"domain"`
+	Community          string         `json:"community"`
+	Token              string         `json:"token"`
+	VerificationMethod sql.NullString `json:"verification_method"`
+	VerifiedAt         sql.NullTime   `json:"verified_at"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
+
+type CommunityModerator struct {
+	Community string    `json:"community"`
+	Did       string    `json:"did"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FirehoseEvent struct {
+	TimeUs     int64          `json:"time_us"`
+	Did        string         `json:"did"`
+	Collection string         `json:"collection"`
+	Rkey       string         `json:"rkey"`
+	Operation  string         `json:"operation"`
+	Record     sql.NullString `json:"record"`
+}
+
+type Label struct {
+	Seq int64  `json:"seq"`
+	Src string `json:"src"`
+	Uri string `json:"uri"`
+	Cid string `json:"cid"`
+	Val string `json:"val"`
+	Neg bool   `json:"neg"`
+	Cts string `json:"cts"`
+	Sig []byte `json:"sig"`
+}
+
 type Message struct {
 	Did               string         `json:"did"`
 	Rkey              string         `json:"rkey"`
 	TopicDid          string         `json:"topic_did"`
 	TopicRkey         string         `json:"topic_rkey"`
 	ParentMessageRkey sql.NullString `json:"parent_message_rkey"`
-	Content           string         `json:"content"`
+	Content           MessageContent `json:"content"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         sql.NullTime   `json:"deleted_at"`
+	DeletedBy         sql.NullString `json:"deleted_by"`
+}
+
+type MirrorMessage struct {
+	Did               string         `json:"did"`
+	Rkey              string         `json:"rkey"`
+	Origin            string         `json:"origin"`
+	TopicDid          string         `json:"topic_did"`
+	TopicRkey         string         `json:"topic_rkey"`
+	ParentMessageRkey string         `json:"parent_message_rkey"`
+	Content           MessageContent `json:"content"`
+	CreatedAt         time.Time      `json:"created_at"`
+	MirroredAt        time.Time      `json:"mirrored_at"`
+	Verified          bool           `json:"verified"`
+}
+
+type MirrorRecord struct {
+	Origin     string `json:"origin"`
+	Did        string `json:"did"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+	Cid        string `json:"cid"`
+	TimeUs     int64  `json:"time_us"`
+	Deleted    bool   `json:"deleted"`
+}
+
+type MirrorSource struct {
+	Origin    string    `json:"origin"`
+	TimeUs    int64     `json:"time_us"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MirrorTopic struct {
+	Did            string    `json:"did"`
+	Rkey           string    `json:"rkey"`
+	Origin         string    `json:"origin"`
+	Subject        string    `json:"subject"`
+	InitialMessage string    `json:"initial_message"`
+	Category       string    `json:"category"`
+	CreatedAt      time.Time `json:"created_at"`
+	MirroredAt     time.Time `json:"mirrored_at"`
+	Verified       bool      `json:"verified"`
 }
 
 type Participation struct {
@@ -29,13 +132,64 @@ type Participation struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type ParticipationRetry struct {
+	Did           string    `json:"did"`
+	TopicDid      string    `json:"topic_did"`
+	TopicRkey     string    `json:"topic_rkey"`
+	Rkey          string    `json:"rkey"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReadMarker struct {
+	Did        string    `json:"did"`
+	TopicDid   string    `json:"topic_did"`
+	TopicRkey  string    `json:"topic_rkey"`
+	LastReadAt time.Time `json:"last_read_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type Session struct {
+	ID          string    `json:"id"`
+	Did         string    `json:"did"`
+	UserAgent   string    `json:"user_agent"`
+	Ip          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	DpopKey     string    `json:"dpop_key"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Avatar      string    `json:"avatar"`
+}
+
 type Topic struct {
-	Did            string         `json:"did"`
-	Rkey           string         `json:"rkey"`
-	Subject        string         `json:"subject"`
-	InitialMessage string         `json:"initial_message"`
-	Category       sql.NullString `json:"category"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	SelectedAnswer sql.NullString `json:"selected_answer"`
+	Did              string         `json:"did"`
+	Rkey             string         `json:"rkey"`
+	Subject          string         `json:"subject"`
+	InitialMessage   string         `json:"initial_message"`
+	Category         sql.NullString `json:"category"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	SelectedAnswer   sql.NullString `json:"selected_answer"`
+	DeletedAt        sql.NullTime   `json:"deleted_at"`
+	DeletedBy        sql.NullString `json:"deleted_by"`
+	MessageCount     int32          `json:"message_count"`
+	LastActivityAt   sql.NullTime   `json:"last_activity_at"`
+	HotRank          float64        `json:"hot_rank"`
+	Community        string         `json:"community"`
+	ParticipantCount int32          `json:"participant_count"`
+}
+
+type TopicStats struct {
+	TopicDid        string    `json:"topic_did"`
+	TopicRkey       string    `json:"topic_rkey"`
+	Day             time.Time `json:"day"`
+	Views           int32     `json:"views"`
+	Messages        int32     `json:"messages"`
+	Participants    int32     `json:"participants"`
+	FirstResponses  int32     `json:"first_responses"`
+	ResponseSeconds int64     `json:"response_seconds"`
 }
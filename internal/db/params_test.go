@@ -0,0 +1,153 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+// TestQueryParametersInOrder guards the SQLite driver: it numbers $N
+// parameters by the order they first appear, not by N, so a query whose
+// parameters are out of order binds its arguments to the wrong columns.
+// Queries that use a parameter before a lower-numbered one should name
+// them with sqlc.arg, which sqlc numbers in order of appearance.
+func TestQueryParametersInOrder(t *testing.T) {
+	src, err := os.ReadFile("queries.sql.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := regexp.MustCompile("(?s)const (\\w+) = `(.*?)`").FindAllSubmatch(src, -1)
+	if len(queries) == 0 {
+		t.Fatal("found no queries")
+	}
+	param := regexp.MustCompile(`\$(\d+)`)
+	for _, q := range queries {
+		next := 1
+		for _, m := range param.FindAllSubmatch(q[2], -1) {
+			n, _ := strconv.Atoi(string(m[1]))
+			if n > next {
+				t.Errorf("%s uses $%d before $%d", q[1], n, next)
+				break
+			}
+			if n == next {
+				next++
+			}
+		}
+	}
+}
+
+func TestTouchSessionSQLite(t *testing.T) {
+	queries := testutil.TestDatabase(t).Queries()
+	ctx := context.Background()
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := queries.CreateSession(ctx, db.CreateSessionParams{
+		ID: "s1", Did: "did:plc:alice", CreatedAt: created, LastSeenAt: created,
+	}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	seen := created.Add(time.Hour)
+	if err := queries.TouchSession(ctx, db.TouchSessionParams{ID: "s1", LastSeenAt: seen}); err != nil {
+		t.Fatalf("TouchSession: %v", err)
+	}
+	s, err := queries.GetSession(ctx, "s1")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if !s.LastSeenAt.Equal(seen) {
+		t.Errorf("last_seen_at = %v, want %v", s.LastSeenAt, seen)
+	}
+}
+
+func TestMarkCommunityDomainVerifiedSQLite(t *testing.T) {
+	queries := testutil.TestDatabase(t).Queries()
+	ctx := context.Background()
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := queries.CreateCommunity(ctx, db.CreateCommunityParams{
+		Slug: "gophers", Name: "Gophers", CreatedBy: "did:plc:alice", CreatedAt: created,
+	}); err != nil {
+		t.Fatalf("CreateCommunity: %v", err)
+	}
+	if _, err := queries.CreateCommunityDomain(ctx, db.CreateCommunityDomainParams{
+		Domain: "chat.example.com", Community: "gophers", Token: "tok", CreatedAt: created,
+	}); err != nil {
+		t.Fatalf("CreateCommunityDomain: %v", err)
+	}
+
+	verified := created.Add(time.Minute)
+	d, err := queries.MarkCommunityDomainVerified(ctx, db.MarkCommunityDomainVerifiedParams{
+		Domain:             "chat.example.com",
+		VerificationMethod: sql.NullString{String: "dns", Valid: true},
+		VerifiedAt:         sql.NullTime{Time: verified, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("MarkCommunityDomainVerified: %v", err)
+	}
+	if d.VerificationMethod.String != "dns" || !d.VerifiedAt.Time.Equal(verified) {
+		t.Errorf("got method %q verified_at %v, want dns at %v", d.VerificationMethod.String, d.VerifiedAt.Time, verified)
+	}
+}
+
+func TestRescheduleParticipationRetrySQLite(t *testing.T) {
+	queries := testutil.TestDatabase(t).Queries()
+	ctx := context.Background()
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := queries.QueueParticipationRetry(ctx, db.QueueParticipationRetryParams{
+		Did: "did:plc:alice", TopicDid: "did:plc:alice", TopicRkey: "t1", Rkey: "p1",
+		LastError: "timeout", NextAttemptAt: created, CreatedAt: created,
+	}); err != nil {
+		t.Fatalf("QueueParticipationRetry: %v", err)
+	}
+
+	next := created.Add(time.Minute)
+	if err := queries.RescheduleParticipationRetry(ctx, db.RescheduleParticipationRetryParams{
+		Did: "did:plc:alice", TopicDid: "did:plc:alice", TopicRkey: "t1",
+		LastError: "pds unavailable", NextAttemptAt: next,
+	}); err != nil {
+		t.Fatalf("RescheduleParticipationRetry: %v", err)
+	}
+	retries, err := queries.ListParticipationRetries(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListParticipationRetries: %v", err)
+	}
+	if len(retries) != 1 {
+		t.Fatalf("got %d retries, want 1", len(retries))
+	}
+	r := retries[0]
+	if r.Attempts != 1 || r.LastError != "pds unavailable" || !r.NextAttemptAt.Equal(next) {
+		t.Errorf("got attempts %d last_error %q next %v, want 1 %q %v", r.Attempts, r.LastError, r.NextAttemptAt, "pds unavailable", next)
+	}
+}
+
+func TestTouchAccessTokenSQLite(t *testing.T) {
+	queries := testutil.TestDatabase(t).Queries()
+	ctx := context.Background()
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := queries.CreateAccessToken(ctx, db.CreateAccessTokenParams{
+		ID: "tok1", Did: "did:plc:alice", Name: "ci", TokenHash: "hash", Scopes: "read",
+		CreatedAt: created, ExpiresAt: created.Add(24 * time.Hour),
+	}); err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+
+	used := created.Add(time.Hour)
+	if err := queries.TouchAccessToken(ctx, db.TouchAccessTokenParams{
+		ID: "tok1", LastUsedAt: sql.NullTime{Time: used, Valid: true},
+	}); err != nil {
+		t.Fatalf("TouchAccessToken: %v", err)
+	}
+	tok, err := queries.GetAccessTokenByHash(ctx, "hash")
+	if err != nil {
+		t.Fatalf("GetAccessTokenByHash: %v", err)
+	}
+	if !tok.LastUsedAt.Valid || !tok.LastUsedAt.Time.Equal(used) {
+		t.Errorf("last_used_at = %v, want %v", tok.LastUsedAt, used)
+	}
+}
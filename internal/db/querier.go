@@ -6,31 +6,156 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	AddCommunityModerator(ctx context.Context, arg AddCommunityModeratorParams) error
+	// Topic stats queries
+	AddTopicStats(ctx context.Context, arg AddTopicStatsParams) error
+	// Uncounts a removed message (delta -1) or counts a restored one again (delta 1),
+	// never below zero for messages that were stored without being counted
+	AdjustTopicMessageCount(ctx context.Context, arg AdjustTopicMessageCountParams) (Topic, error)
+	// Firehose event queries
+	AppendFirehoseEvent(ctx context.Context, arg AppendFirehoseEventParams) error
+	// Label queries. Labels are signed as stored, so cts is kept as the
+	// exact string that was signed.
+	AppendLabel(ctx context.Context, arg AppendLabelParams) error
+	// Counts an author's messages in a topic since a point in time, so the
+	// first message of the day can be counted as a new participant
+	CountAuthorMessagesSince(ctx context.Context, arg CountAuthorMessagesSinceParams) (int64, error)
+	CountMirrorTopic(ctx context.Context, arg CountMirrorTopicParams) (int64, error)
+	CountTopicModerator(ctx context.Context, arg CountTopicModeratorParams) (int64, error)
+	// Counts messages in a topic written by someone other than its author
+	CountTopicResponses(ctx context.Context, arg CountTopicResponsesParams) (int64, error)
+	// Counts messages by others posted after the user's read marker
+	CountUnreadMessages(ctx context.Context, arg CountUnreadMessagesParams) (int64, error)
+	// Access token queries
+	CreateAccessToken(ctx context.Context, arg CreateAccessTokenParams) (AccessToken, error)
+	CreateCommunity(ctx context.Context, arg CreateCommunityParams) (Community, error)
+	CreateCommunityDomain(ctx context.Context, arg CreateCommunityDomainParams) (CommunityDomain, error)
 	// Messages queries
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	// Participation queries
 	CreateParticipation(ctx context.Context, arg CreateParticipationParams) (Participation, error)
+	// Session queries
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	// queries.sql - Central SQL query file for dis.quest
 	// All SQL queries should be added to this file as documented in CLAUDE.md
 	// Topics queries
 	CreateTopic(ctx context.Context, arg CreateTopicParams) (Topic, error)
+	DeleteAccessToken(ctx context.Context, arg DeleteAccessTokenParams) (int64, error)
+	DeleteAccessTokensByDid(ctx context.Context, did string) (int64, error)
+	DeleteCommunityCategory(ctx context.Context, arg DeleteCommunityCategoryParams) (int64, error)
+	DeleteCommunityDomain(ctx context.Context, arg DeleteCommunityDomainParams) (int64, error)
+	DeleteFirehoseEventsByAccount(ctx context.Context, did string) (int64, error)
 	DeleteMessage(ctx context.Context, arg DeleteMessageParams) error
+	// Account purge queries. Rows in the account's own topics go with the topic.
+	DeleteMessagesByAccount(ctx context.Context, did string) (int64, error)
+	DeleteMirrorMessage(ctx context.Context, arg DeleteMirrorMessageParams) error
+	DeleteMirrorTopic(ctx context.Context, arg DeleteMirrorTopicParams) error
+	DeleteMirrorTopicMessages(ctx context.Context, arg DeleteMirrorTopicMessagesParams) error
 	DeleteParticipation(ctx context.Context, arg DeleteParticipationParams) error
+	DeleteParticipationRetriesByAccount(ctx context.Context, did string) (int64, error)
+	DeleteParticipationRetry(ctx context.Context, arg DeleteParticipationRetryParams) error
+	DeleteParticipationsByAccount(ctx context.Context, did string) (int64, error)
+	DeleteReadMarker(ctx context.Context, arg DeleteReadMarkerParams) error
+	DeleteReadMarkersByAccount(ctx context.Context, did string) (int64, error)
+	DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error)
+	DeleteSessionsByDid(ctx context.Context, did string) (int64, error)
 	DeleteTopic(ctx context.Context, arg DeleteTopicParams) error
+	DeleteTopicStatsByAccount(ctx context.Context, topicDid string) (int64, error)
+	DeleteTopicsByAccount(ctx context.Context, did string) (int64, error)
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (AccessToken, error)
+	GetCommunity(ctx context.Context, slug string) (Community, error)
+	GetCommunityDomain(ctx context.Context, domain string) (CommunityDomain, error)
+	GetDeletedMessage(ctx context.Context, arg GetDeletedMessageParams) (Message, error)
+	GetDeletedTopic(ctx context.Context, arg GetDeletedTopicParams) (Topic, error)
+	GetLatestFirehoseEventTime(ctx context.Context) (int64, error)
+	GetLatestLabelSeq(ctx context.Context) (int64, error)
 	GetMessage(ctx context.Context, arg GetMessageParams) (Message, error)
 	GetMessagesByTopic(ctx context.Context, arg GetMessagesByTopicParams) ([]Message, error)
+	// Mirror queries
+	GetMirrorCursor(ctx context.Context, origin string) (int64, error)
+	GetMirrorRecord(ctx context.Context, arg GetMirrorRecordParams) (MirrorRecord, error)
+	GetMirrorTopic(ctx context.Context, arg GetMirrorTopicParams) (MirrorTopic, error)
 	GetParticipation(ctx context.Context, arg GetParticipationParams) (Participation, error)
 	GetParticipationsByTopic(ctx context.Context, arg GetParticipationsByTopicParams) ([]Participation, error)
 	GetParticipationsByUser(ctx context.Context, did string) ([]Participation, error)
 	GetRepliesByMessage(ctx context.Context, arg GetRepliesByMessageParams) ([]Message, error)
+	GetSession(ctx context.Context, id string) (Session, error)
 	GetTopic(ctx context.Context, arg GetTopicParams) (Topic, error)
 	GetTopicsByCategory(ctx context.Context, arg GetTopicsByCategoryParams) ([]Topic, error)
+	GetUnreadCounts(ctx context.Context, did string) ([]GetUnreadCountsRow, error)
+	GetVerifiedDomainCommunity(ctx context.Context, domain string) (string, error)
+	// Counts a message that has just been inserted, and its author as a new
+	// participant when it is their first message in a topic they did not create
+	IncrementTopicActivity(ctx context.Context, arg IncrementTopicActivityParams) (Topic, error)
+	ListAccessTokensByDid(ctx context.Context, did string) ([]AccessToken, error)
+	ListCommunities(ctx context.Context, arg ListCommunitiesParams) ([]Community, error)
+	ListCommunityCategories(ctx context.Context, community string) ([]CommunityCategory, error)
+	ListCommunityDomains(ctx context.Context, community string) ([]CommunityDomain, error)
+	ListCommunityModerators(ctx context.Context, community string) ([]CommunityModerator, error)
+	ListDailyStats(ctx context.Context, day time.Time) ([]ListDailyStatsRow, error)
+	ListDueParticipationRetries(ctx context.Context, arg ListDueParticipationRetriesParams) ([]ParticipationRetry, error)
+	ListFirehoseEventsSince(ctx context.Context, arg ListFirehoseEventsSinceParams) ([]FirehoseEvent, error)
+	ListLabelsByURI(ctx context.Context, arg ListLabelsByURIParams) ([]Label, error)
+	ListLabelsByURIPrefix(ctx context.Context, arg ListLabelsByURIPrefixParams) ([]Label, error)
+	ListLabelsSince(ctx context.Context, arg ListLabelsSinceParams) ([]Label, error)
+	// Messages in removed topics are excluded along with removed messages
+	ListMessagesByAuthor(ctx context.Context, arg ListMessagesByAuthorParams) ([]Message, error)
+	ListMirrorMessages(ctx context.Context, arg ListMirrorMessagesParams) ([]MirrorMessage, error)
+	ListMirrorTopics(ctx context.Context, arg ListMirrorTopicsParams) ([]MirrorTopic, error)
+	ListMirrorTopicsByCategory(ctx context.Context, arg ListMirrorTopicsByCategoryParams) ([]MirrorTopic, error)
+	ListParticipationRetries(ctx context.Context, limit int32) ([]ParticipationRetry, error)
+	// Lists the most recent live topics for the related-topics model
+	ListRelatedTopicCandidates(ctx context.Context, limit int32) ([]ListRelatedTopicCandidatesRow, error)
+	ListSessionsByDid(ctx context.Context, did string) ([]Session, error)
+	ListTopTopicsByViews(ctx context.Context, arg ListTopTopicsByViewsParams) ([]ListTopTopicsByViewsRow, error)
+	ListTopicStats(ctx context.Context, arg ListTopicStatsParams) ([]TopicStats, error)
 	ListTopics(ctx context.Context, arg ListTopicsParams) ([]Topic, error)
+	ListTopicsByActivity(ctx context.Context, arg ListTopicsByActivityParams) ([]Topic, error)
+	ListTopicsByAuthor(ctx context.Context, arg ListTopicsByAuthorParams) ([]Topic, error)
+	ListTopicsByHotRank(ctx context.Context, arg ListTopicsByHotRankParams) ([]Topic, error)
+	ListTopicsByMessageCount(ctx context.Context, arg ListTopicsByMessageCountParams) ([]Topic, error)
+	// Topics whose author has no participation row, for the reconciliation report
+	ListTopicsMissingCreatorParticipation(ctx context.Context, limit int32) ([]Topic, error)
+	// Ranks categories by messages posted since a point in time across their topics
+	ListTrendingCategories(ctx context.Context, arg ListTrendingCategoriesParams) ([]ListTrendingCategoriesRow, error)
+	// Ranks topics by messages posted since a point in time, breaking ties by
+	// how many people posted them
+	ListTrendingTopics(ctx context.Context, arg ListTrendingTopicsParams) ([]ListTrendingTopicsRow, error)
+	MarkCommunityDomainVerified(ctx context.Context, arg MarkCommunityDomainVerifiedParams) (CommunityDomain, error)
+	PurgeDeletedMessages(ctx context.Context, deletedAt sql.NullTime) (int64, error)
+	PurgeDeletedTopics(ctx context.Context, deletedAt sql.NullTime) (int64, error)
+	PurgeFirehoseEvents(ctx context.Context, timeUs int64) (int64, error)
+	PurgeIdleSessions(ctx context.Context, lastSeenAt time.Time) (int64, error)
+	// Participation retry queries
+	QueueParticipationRetry(ctx context.Context, arg QueueParticipationRetryParams) (ParticipationRetry, error)
+	RemoveCommunityModerator(ctx context.Context, arg RemoveCommunityModeratorParams) (int64, error)
+	RescheduleParticipationRetry(ctx context.Context, arg RescheduleParticipationRetryParams) error
+	RestoreMessage(ctx context.Context, arg RestoreMessageParams) (int64, error)
+	RestoreTopic(ctx context.Context, arg RestoreTopicParams) (int64, error)
+	// A random sample of live messages, for checking the index against the PDS
+	SampleMessages(ctx context.Context, limit int32) ([]Message, error)
+	// A random sample of live topics, for checking the index against the PDS
+	SampleTopics(ctx context.Context, limit int32) ([]Topic, error)
+	SaveMirrorCursor(ctx context.Context, arg SaveMirrorCursorParams) error
+	SaveMirrorRecord(ctx context.Context, arg SaveMirrorRecordParams) error
+	SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) (int64, error)
+	SoftDeleteTopic(ctx context.Context, arg SoftDeleteTopicParams) (int64, error)
+	TouchAccessToken(ctx context.Context, arg TouchAccessTokenParams) error
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
 	UpdateParticipationStatus(ctx context.Context, arg UpdateParticipationStatusParams) error
+	UpdateTopicHotRank(ctx context.Context, arg UpdateTopicHotRankParams) error
 	UpdateTopicSelectedAnswer(ctx context.Context, arg UpdateTopicSelectedAnswerParams) error
+	UpsertCommunityCategory(ctx context.Context, arg UpsertCommunityCategoryParams) (CommunityCategory, error)
+	UpsertMirrorMessage(ctx context.Context, arg UpsertMirrorMessageParams) error
+	// A record already mirrored from another origin is left alone
+	UpsertMirrorTopic(ctx context.Context, arg UpsertMirrorTopicParams) error
+	// Read marker queries
+	UpsertReadMarker(ctx context.Context, arg UpsertReadMarkerParams) (ReadMarker, error)
 }
 
 var _ Querier = (*Queries)(nil)
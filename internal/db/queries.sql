@@ -4,25 +4,88 @@
 -- Topics queries
 -- name: CreateTopic :one
 INSERT INTO quest_dis_topic (
-    did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer
+    did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, community
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetTopic :one
 SELECT * FROM quest_dis_topic
-WHERE did = $1 AND rkey = $2;
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NULL;
 
 -- name: GetTopicsByCategory :many
 SELECT * FROM quest_dis_topic
-WHERE category = $1
+WHERE category = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT $2;
 
 -- name: ListTopics :many
 SELECT * FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+LIMIT $2 OFFSET $3;
+
+-- name: ListTopicsByAuthor :many
+SELECT * FROM quest_dis_topic
+WHERE did = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListTopicsByActivity :many
+SELECT * FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY COALESCE(last_activity_at, created_at) DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListTopicsByMessageCount :many
+SELECT * FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY message_count DESC, created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListTopicsByHotRank :many
+SELECT * FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY hot_rank DESC, created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: IncrementTopicActivity :one
+-- Counts a message that has just been inserted, and its author as a new
+-- participant when it is their first message in a topic they did not create
+UPDATE quest_dis_topic
+SET message_count = message_count + 1,
+    last_activity_at = sqlc.arg(last_activity_at),
+    participant_count = participant_count + CASE
+        WHEN did <> sqlc.arg(author_did) AND (
+            SELECT COUNT(*) FROM quest_dis_message m
+            WHERE m.topic_did = quest_dis_topic.did AND m.topic_rkey = quest_dis_topic.rkey AND m.did = sqlc.arg(author_did)
+        ) = 1 THEN 1 ELSE 0
+    END
+WHERE did = sqlc.arg(did) AND rkey = sqlc.arg(rkey)
+RETURNING *;
+
+-- name: SampleTopics :many
+-- A random sample of live topics, for checking the index against the PDS
+SELECT * FROM quest_dis_topic
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT $1;
+
+-- name: AdjustTopicMessageCount :one
+-- Uncounts a removed message (delta -1) or counts a restored one again (delta 1),
+-- never below zero for messages that were stored without being counted
+UPDATE quest_dis_topic
+SET message_count = CASE
+    WHEN message_count + CAST(sqlc.arg(delta) AS INTEGER) < 0 THEN 0
+    ELSE message_count + CAST(sqlc.arg(delta) AS INTEGER)
+END
+WHERE did = sqlc.arg(did) AND rkey = sqlc.arg(rkey)
+RETURNING *;
+
+-- name: UpdateTopicHotRank :exec
+UPDATE quest_dis_topic
+SET hot_rank = $1
+WHERE did = $2 AND rkey = $3;
 
 -- name: UpdateTopicSelectedAnswer :exec
 UPDATE quest_dis_topic
@@ -33,6 +96,24 @@ WHERE did = $3 AND rkey = $4;
 DELETE FROM quest_dis_topic
 WHERE did = $1 AND rkey = $2;
 
+-- name: SoftDeleteTopic :execrows
+UPDATE quest_dis_topic
+SET deleted_at = $1, deleted_by = $2
+WHERE did = $3 AND rkey = $4 AND deleted_at IS NULL;
+
+-- name: GetDeletedTopic :one
+SELECT * FROM quest_dis_topic
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL;
+
+-- name: RestoreTopic :execrows
+UPDATE quest_dis_topic
+SET deleted_at = NULL, deleted_by = NULL
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedTopics :execrows
+DELETE FROM quest_dis_topic
+WHERE deleted_at < $1;
+
 -- Messages queries
 -- name: CreateMessage :one
 INSERT INTO quest_dis_message (
@@ -41,24 +122,57 @@ INSERT INTO quest_dis_message (
     $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
+-- name: SampleMessages :many
+-- A random sample of live messages, for checking the index against the PDS
+SELECT * FROM quest_dis_message
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT $1;
+
 -- name: GetMessage :one
 SELECT * FROM quest_dis_message
-WHERE did = $1 AND rkey = $2;
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NULL;
 
 -- name: GetMessagesByTopic :many
 SELECT * FROM quest_dis_message
-WHERE topic_did = $1 AND topic_rkey = $2
+WHERE topic_did = $1 AND topic_rkey = $2 AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetRepliesByMessage :many
 SELECT * FROM quest_dis_message
-WHERE topic_did = $1 AND topic_rkey = $2 AND parent_message_rkey = $3
+WHERE topic_did = $1 AND topic_rkey = $2 AND parent_message_rkey = $3 AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
+-- name: ListMessagesByAuthor :many
+-- Messages in removed topics are excluded along with removed messages
+SELECT m.* FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.did = $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL
+ORDER BY m.created_at DESC
+LIMIT $2 OFFSET $3;
+
 -- name: DeleteMessage :exec
 DELETE FROM quest_dis_message
 WHERE did = $1 AND rkey = $2;
 
+-- name: SoftDeleteMessage :execrows
+UPDATE quest_dis_message
+SET deleted_at = $1, deleted_by = $2
+WHERE did = $3 AND rkey = $4 AND deleted_at IS NULL;
+
+-- name: GetDeletedMessage :one
+SELECT * FROM quest_dis_message
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL;
+
+-- name: RestoreMessage :execrows
+UPDATE quest_dis_message
+SET deleted_at = NULL, deleted_by = NULL
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedMessages :execrows
+DELETE FROM quest_dis_message
+WHERE deleted_at < $1;
+
 -- Participation queries
 -- name: CreateParticipation :one
 INSERT INTO quest_dis_participation (
@@ -87,4 +201,473 @@ WHERE did = $3 AND topic_did = $4 AND topic_rkey = $5;
 
 -- name: DeleteParticipation :exec
 DELETE FROM quest_dis_participation
-WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3;
\ No newline at end of file
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3;
+
+-- name: ListTopicsMissingCreatorParticipation :many
+-- Topics whose author has no participation row, for the reconciliation report
+SELECT t.* FROM quest_dis_topic t
+LEFT JOIN quest_dis_participation p ON p.did = t.did AND p.topic_did = t.did AND p.topic_rkey = t.rkey
+WHERE p.did IS NULL AND t.deleted_at IS NULL
+ORDER BY t.created_at DESC
+LIMIT $1;
+
+-- Participation retry queries
+-- name: QueueParticipationRetry :one
+INSERT INTO quest_dis_participation_retry (
+    did, topic_did, topic_rkey, rkey, attempts, last_error, next_attempt_at, created_at
+) VALUES (
+    $1, $2, $3, $4, 0, $5, $6, $7
+)
+ON CONFLICT (did, topic_did, topic_rkey)
+DO UPDATE SET last_error = EXCLUDED.last_error
+RETURNING *;
+
+-- name: ListDueParticipationRetries :many
+SELECT * FROM quest_dis_participation_retry
+WHERE next_attempt_at <= $1
+ORDER BY next_attempt_at ASC
+LIMIT $2;
+
+-- name: ListParticipationRetries :many
+SELECT * FROM quest_dis_participation_retry
+ORDER BY created_at ASC
+LIMIT $1;
+
+-- name: RescheduleParticipationRetry :exec
+UPDATE quest_dis_participation_retry
+SET attempts = attempts + 1, last_error = sqlc.arg(last_error), next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE did = sqlc.arg(did) AND topic_did = sqlc.arg(topic_did) AND topic_rkey = sqlc.arg(topic_rkey);
+
+-- name: DeleteParticipationRetry :exec
+DELETE FROM quest_dis_participation_retry
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3;
+
+-- Read marker queries
+-- name: UpsertReadMarker :one
+INSERT INTO quest_dis_read_marker (
+    did, topic_did, topic_rkey, last_read_at, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (did, topic_did, topic_rkey)
+DO UPDATE SET last_read_at = EXCLUDED.last_read_at, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: DeleteReadMarker :exec
+DELETE FROM quest_dis_read_marker
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3;
+
+-- name: CountUnreadMessages :one
+-- Counts messages by others posted after the user's read marker
+SELECT COUNT(*) FROM quest_dis_message m
+LEFT JOIN quest_dis_read_marker r ON r.did = $1 AND r.topic_did = m.topic_did AND r.topic_rkey = m.topic_rkey
+WHERE m.topic_did = $2 AND m.topic_rkey = $3 AND m.did <> $1 AND m.deleted_at IS NULL
+AND (r.last_read_at IS NULL OR m.created_at > r.last_read_at);
+
+-- name: GetUnreadCounts :many
+SELECT m.topic_did, m.topic_rkey, COUNT(*) AS unread_count FROM quest_dis_message m
+LEFT JOIN quest_dis_read_marker r ON r.did = $1 AND r.topic_did = m.topic_did AND r.topic_rkey = m.topic_rkey
+WHERE m.did <> $1 AND m.deleted_at IS NULL
+AND (r.last_read_at IS NULL OR m.created_at > r.last_read_at)
+GROUP BY m.topic_did, m.topic_rkey;
+
+-- Firehose event queries
+-- name: AppendFirehoseEvent :exec
+INSERT INTO quest_dis_firehose_event (
+    time_us, did, collection, rkey, operation, record
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListFirehoseEventsSince :many
+SELECT * FROM quest_dis_firehose_event
+WHERE time_us >= $1
+ORDER BY time_us
+LIMIT $2;
+
+-- name: GetLatestFirehoseEventTime :one
+SELECT time_us FROM quest_dis_firehose_event
+ORDER BY time_us DESC
+LIMIT 1;
+
+-- name: PurgeFirehoseEvents :execrows
+DELETE FROM quest_dis_firehose_event
+WHERE time_us < $1;
+
+-- Topic stats queries
+-- name: AddTopicStats :exec
+INSERT INTO quest_dis_topic_stats (
+    topic_did, topic_rkey, day, views, messages, participants, first_responses, response_seconds
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (topic_did, topic_rkey, day)
+DO UPDATE SET
+    views = quest_dis_topic_stats.views + EXCLUDED.views,
+    messages = quest_dis_topic_stats.messages + EXCLUDED.messages,
+    participants = quest_dis_topic_stats.participants + EXCLUDED.participants,
+    first_responses = quest_dis_topic_stats.first_responses + EXCLUDED.first_responses,
+    response_seconds = quest_dis_topic_stats.response_seconds + EXCLUDED.response_seconds;
+
+-- name: ListTopicStats :many
+SELECT * FROM quest_dis_topic_stats
+WHERE topic_did = $1 AND topic_rkey = $2 AND day >= $3
+ORDER BY day DESC;
+
+-- name: ListDailyStats :many
+SELECT day,
+    CAST(SUM(views) AS BIGINT) AS views,
+    CAST(SUM(messages) AS BIGINT) AS messages,
+    CAST(SUM(participants) AS BIGINT) AS participants,
+    CAST(SUM(first_responses) AS BIGINT) AS first_responses,
+    CAST(SUM(response_seconds) AS BIGINT) AS response_seconds
+FROM quest_dis_topic_stats
+WHERE day >= $1
+GROUP BY day
+ORDER BY day DESC;
+
+-- name: ListTopTopicsByViews :many
+SELECT s.topic_did, s.topic_rkey, t.subject,
+    CAST(SUM(s.views) AS BIGINT) AS views,
+    CAST(SUM(s.messages) AS BIGINT) AS messages
+FROM quest_dis_topic_stats s
+JOIN quest_dis_topic t ON t.did = s.topic_did AND t.rkey = s.topic_rkey
+WHERE s.day >= $1 AND t.deleted_at IS NULL
+GROUP BY s.topic_did, s.topic_rkey, t.subject
+ORDER BY views DESC
+LIMIT $2;
+
+-- name: ListRelatedTopicCandidates :many
+-- Lists the most recent live topics for the related-topics model
+SELECT did, rkey, subject, initial_message, category FROM quest_dis_topic
+WHERE deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: ListTrendingTopics :many
+-- Ranks topics by messages posted since a point in time, breaking ties by
+-- how many people posted them
+SELECT m.topic_did, m.topic_rkey, t.subject,
+    COUNT(*) AS messages,
+    COUNT(DISTINCT m.did) AS participants
+FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.created_at >= $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL
+GROUP BY m.topic_did, m.topic_rkey, t.subject
+ORDER BY messages DESC, participants DESC, m.topic_did, m.topic_rkey
+LIMIT $2;
+
+-- name: ListTrendingCategories :many
+-- Ranks categories by messages posted since a point in time across their topics
+SELECT t.category,
+    COUNT(*) AS messages,
+    COUNT(DISTINCT m.topic_did || ':' || m.topic_rkey) AS topics
+FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.created_at >= $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL AND t.category <> ''
+GROUP BY t.category
+ORDER BY messages DESC, topics DESC, t.category
+LIMIT $2;
+
+-- name: CountAuthorMessagesSince :one
+-- Counts an author's messages in a topic since a point in time, so the
+-- first message of the day can be counted as a new participant
+SELECT COUNT(*) FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND did = $3 AND created_at >= $4;
+
+-- name: CountTopicResponses :one
+-- Counts messages in a topic written by someone other than its author
+SELECT COUNT(*) FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND did <> $3;
+
+-- Session queries
+-- name: CreateSession :one
+INSERT INTO quest_dis_session (
+    id, did, user_agent, ip, created_at, last_seen_at, dpop_key,
+    handle, display_name, avatar
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM quest_dis_session
+WHERE id = $1;
+
+-- name: TouchSession :exec
+UPDATE quest_dis_session
+SET last_seen_at = sqlc.arg(last_seen_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListSessionsByDid :many
+SELECT * FROM quest_dis_session
+WHERE did = $1
+ORDER BY last_seen_at DESC;
+
+-- name: DeleteSession :execrows
+DELETE FROM quest_dis_session
+WHERE id = $1 AND did = $2;
+
+-- name: DeleteSessionsByDid :execrows
+DELETE FROM quest_dis_session
+WHERE did = $1;
+
+-- name: PurgeIdleSessions :execrows
+DELETE FROM quest_dis_session
+WHERE last_seen_at < $1;
+
+-- Access token queries
+-- name: CreateAccessToken :one
+INSERT INTO quest_dis_access_token (
+    id, did, name, token_hash, scopes, created_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetAccessTokenByHash :one
+SELECT * FROM quest_dis_access_token
+WHERE token_hash = $1;
+
+-- name: TouchAccessToken :exec
+UPDATE quest_dis_access_token
+SET last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListAccessTokensByDid :many
+SELECT * FROM quest_dis_access_token
+WHERE did = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteAccessToken :execrows
+DELETE FROM quest_dis_access_token
+WHERE id = $1 AND did = $2;
+
+-- name: DeleteAccessTokensByDid :execrows
+DELETE FROM quest_dis_access_token
+WHERE did = $1;
+
+-- Account purge queries. Rows in the account's own topics go with the topic.
+-- name: DeleteMessagesByAccount :execrows
+DELETE FROM quest_dis_message
+WHERE did = $1 OR topic_did = $1;
+
+-- name: DeleteParticipationsByAccount :execrows
+DELETE FROM quest_dis_participation
+WHERE did = $1 OR topic_did = $1;
+
+-- name: DeleteParticipationRetriesByAccount :execrows
+DELETE FROM quest_dis_participation_retry
+WHERE did = $1 OR topic_did = $1;
+
+-- name: DeleteReadMarkersByAccount :execrows
+DELETE FROM quest_dis_read_marker
+WHERE did = $1 OR topic_did = $1;
+
+-- name: DeleteTopicStatsByAccount :execrows
+DELETE FROM quest_dis_topic_stats
+WHERE topic_did = $1;
+
+-- name: DeleteTopicsByAccount :execrows
+DELETE FROM quest_dis_topic
+WHERE did = $1;
+
+-- name: DeleteFirehoseEventsByAccount :execrows
+DELETE FROM quest_dis_firehose_event
+WHERE did = $1;
+
+-- name: CreateCommunity :one
+INSERT INTO quest_dis_community (slug, name, description, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetCommunity :one
+SELECT * FROM quest_dis_community
+WHERE slug = $1;
+
+-- name: ListCommunities :many
+SELECT * FROM quest_dis_community
+ORDER BY name
+LIMIT $1 OFFSET $2;
+
+-- name: UpsertCommunityCategory :one
+INSERT INTO quest_dis_community_category (community, slug, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (community, slug) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: DeleteCommunityCategory :execrows
+DELETE FROM quest_dis_community_category
+WHERE community = $1 AND slug = $2;
+
+-- name: ListCommunityCategories :many
+SELECT * FROM quest_dis_community_category
+WHERE community = $1
+ORDER BY name;
+
+-- name: AddCommunityModerator :exec
+INSERT INTO quest_dis_community_moderator (community, did, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (community, did) DO NOTHING;
+
+-- name: RemoveCommunityModerator :execrows
+DELETE FROM quest_dis_community_moderator
+WHERE community = $1 AND did = $2;
+
+-- name: ListCommunityModerators :many
+SELECT * FROM quest_dis_community_moderator
+WHERE community = $1
+ORDER BY created_at;
+
+-- name: CountTopicModerator :one
+SELECT COUNT(*) FROM quest_dis_community_moderator m
+JOIN quest_dis_topic t ON t.community = m.community
+WHERE t.did = sqlc.arg(topic_did) AND t.rkey = sqlc.arg(topic_rkey) AND m.did = sqlc.arg(moderator_did);
+
+-- name: CreateCommunityDomain :one
+INSERT INTO quest_dis_community_domain (domain, community, token, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetCommunityDomain :one
+SELECT * FROM quest_dis_community_domain
+WHERE domain = $1;
+
+-- name: ListCommunityDomains :many
+SELECT * FROM quest_dis_community_domain
+WHERE community = $1
+ORDER BY domain;
+
+-- name: MarkCommunityDomainVerified :one
+UPDATE quest_dis_community_domain
+SET verification_method = sqlc.arg(verification_method), verified_at = sqlc.arg(verified_at)
+WHERE domain = sqlc.arg(domain)
+RETURNING *;
+
+-- name: DeleteCommunityDomain :execrows
+DELETE FROM quest_dis_community_domain
+WHERE community = $1 AND domain = $2;
+
+-- name: GetVerifiedDomainCommunity :one
+SELECT community FROM quest_dis_community_domain
+WHERE domain = $1 AND verified_at IS NOT NULL;
+
+-- Mirror queries
+-- name: GetMirrorCursor :one
+SELECT time_us FROM quest_dis_mirror_source
+WHERE origin = $1;
+
+-- name: SaveMirrorCursor :exec
+INSERT INTO quest_dis_mirror_source (origin, time_us, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (origin) DO UPDATE SET time_us = EXCLUDED.time_us, updated_at = EXCLUDED.updated_at;
+
+-- name: GetMirrorRecord :one
+SELECT * FROM quest_dis_mirror_record
+WHERE origin = $1 AND did = $2 AND collection = $3 AND rkey = $4;
+
+-- name: SaveMirrorRecord :exec
+INSERT INTO quest_dis_mirror_record (origin, did, collection, rkey, cid, time_us, deleted)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (origin, did, collection, rkey) DO UPDATE SET
+    cid = EXCLUDED.cid,
+    time_us = EXCLUDED.time_us,
+    deleted = EXCLUDED.deleted;
+
+-- name: UpsertMirrorTopic :exec
+-- A record already mirrored from another origin is left alone
+INSERT INTO quest_dis_mirror_topic (
+    did, rkey, origin, subject, initial_message, category, created_at, mirrored_at, verified
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (did, rkey) DO UPDATE SET
+    subject = EXCLUDED.subject,
+    initial_message = EXCLUDED.initial_message,
+    category = EXCLUDED.category,
+    mirrored_at = EXCLUDED.mirrored_at,
+    verified = EXCLUDED.verified
+WHERE quest_dis_mirror_topic.origin = EXCLUDED.origin;
+
+-- name: CountMirrorTopic :one
+SELECT COUNT(*) FROM quest_dis_mirror_topic
+WHERE origin = $1 AND did = $2 AND rkey = $3;
+
+-- name: GetMirrorTopic :one
+SELECT * FROM quest_dis_mirror_topic
+WHERE did = $1 AND rkey = $2;
+
+-- name: ListMirrorTopics :many
+SELECT * FROM quest_dis_mirror_topic
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListMirrorTopicsByCategory :many
+SELECT * FROM quest_dis_mirror_topic
+WHERE category = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: DeleteMirrorTopic :exec
+DELETE FROM quest_dis_mirror_topic
+WHERE origin = $1 AND did = $2 AND rkey = $3;
+
+-- name: DeleteMirrorTopicMessages :exec
+DELETE FROM quest_dis_mirror_message
+WHERE origin = $1 AND topic_did = $2 AND topic_rkey = $3;
+
+-- name: UpsertMirrorMessage :exec
+INSERT INTO quest_dis_mirror_message (
+    did, rkey, origin, topic_did, topic_rkey, parent_message_rkey, content, created_at, mirrored_at, verified
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (did, rkey) DO UPDATE SET
+    parent_message_rkey = EXCLUDED.parent_message_rkey,
+    content = EXCLUDED.content,
+    mirrored_at = EXCLUDED.mirrored_at,
+    verified = EXCLUDED.verified
+WHERE quest_dis_mirror_message.origin = EXCLUDED.origin;
+
+-- name: DeleteMirrorMessage :exec
+DELETE FROM quest_dis_mirror_message
+WHERE origin = $1 AND did = $2 AND rkey = $3;
+
+-- name: ListMirrorMessages :many
+SELECT * FROM quest_dis_mirror_message
+WHERE topic_did = $1 AND topic_rkey = $2
+ORDER BY created_at ASC
+LIMIT $3 OFFSET $4;
+
+-- Label queries. Labels are signed as stored, so cts is kept as the
+-- exact string that was signed.
+-- name: AppendLabel :exec
+INSERT INTO quest_dis_label (
+    seq, src, uri, cid, val, neg, cts, sig
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: ListLabelsSince :many
+SELECT * FROM quest_dis_label
+WHERE seq > $1
+ORDER BY seq
+LIMIT $2;
+
+-- name: GetLatestLabelSeq :one
+SELECT seq FROM quest_dis_label
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: ListLabelsByURI :many
+SELECT * FROM quest_dis_label
+WHERE uri = $1 AND seq > $2
+ORDER BY seq
+LIMIT $3;
+
+-- name: ListLabelsByURIPrefix :many
+SELECT * FROM quest_dis_label
+WHERE substr(uri, 1, length(CAST(sqlc.arg(prefix) AS TEXT))) = CAST(sqlc.arg(prefix) AS TEXT)
+    AND seq > sqlc.arg(seq)
+ORDER BY seq
+LIMIT sqlc.arg(row_limit);
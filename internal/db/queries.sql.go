@@ -11,12 +11,388 @@ import (
 	"time"
 )
 
+const AddCommunityModerator = `-- name: AddCommunityModerator :exec
+INSERT INTO quest_dis_community_moderator (community, did, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (community, did) DO NOTHING
+`
+
+type AddCommunityModeratorParams struct {
+	Community string    `json:"community"`
+	Did       string    `json:"did"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) AddCommunityModerator(ctx context.Context, arg AddCommunityModeratorParams) error {
+	_, err := q.exec(ctx, q.addCommunityModeratorStmt, AddCommunityModerator, arg.Community, arg.Did, arg.CreatedAt)
+	return err
+}
+
+const AddTopicStats = `-- name: AddTopicStats :exec
+INSERT INTO quest_dis_topic_stats (
+    topic_did, topic_rkey, day, views, messages, participants, first_responses, response_seconds
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT (topic_did, topic_rkey, day)
+DO UPDATE SET
+    views = quest_dis_topic_stats.views + EXCLUDED.views,
+    messages = quest_dis_topic_stats.messages + EXCLUDED.messages,
+    participants = quest_dis_topic_stats.participants + EXCLUDED.participants,
+    first_responses = quest_dis_topic_stats.first_responses + EXCLUDED.first_responses,
+    response_seconds = quest_dis_topic_stats.response_seconds + EXCLUDED.response_seconds
+`
+
+type AddTopicStatsParams struct {
+	TopicDid        string    `json:"topic_did"`
+	TopicRkey       string    `json:"topic_rkey"`
+	Day             time.Time `json:"day"`
+	Views           int32     `json:"views"`
+	Messages        int32     `json:"messages"`
+	Participants    int32     `json:"participants"`
+	FirstResponses  int32     `json:"first_responses"`
+	ResponseSeconds int64     `json:"response_seconds"`
+}
+
+// Topic stats queries
+func (q *Queries) AddTopicStats(ctx context.Context, arg AddTopicStatsParams) error {
+	_, err := q.exec(ctx, q.addTopicStatsStmt, AddTopicStats,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.Day,
+		arg.Views,
+		arg.Messages,
+		arg.Participants,
+		arg.FirstResponses,
+		arg.ResponseSeconds,
+	)
+	return err
+}
+
+const AdjustTopicMessageCount = `-- name: AdjustTopicMessageCount :one
+UPDATE quest_dis_topic
+SET message_count = CASE
+    WHEN message_count + CAST($1 AS INTEGER) < 0 THEN 0
+    ELSE message_count + CAST($1 AS INTEGER)
+END
+WHERE did = $2 AND rkey = $3
+RETURNING did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count
+`
+
+type AdjustTopicMessageCountParams struct {
+	Delta int32  `json:"delta"`
+	Did   string `json:"did"`
+	Rkey  string `json:"rkey"`
+}
+
+// Uncounts a removed message (delta -1) or counts a restored one again (delta 1),
+// never below zero for messages that were stored without being counted
+func (q *Queries) AdjustTopicMessageCount(ctx context.Context, arg AdjustTopicMessageCountParams) (Topic, error) {
+	row := q.queryRow(ctx, q.adjustTopicMessageCountStmt, AdjustTopicMessageCount, arg.Delta, arg.Did, arg.Rkey)
+	var i Topic
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.Subject,
+		&i.InitialMessage,
+		&i.Category,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SelectedAnswer,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.MessageCount,
+		&i.LastActivityAt,
+		&i.HotRank,
+		&i.Community,
+		&i.ParticipantCount,
+	)
+	return i, err
+}
+
+const AppendFirehoseEvent = `-- name: AppendFirehoseEvent :exec
+INSERT INTO quest_dis_firehose_event (
+    time_us, did, collection, rkey, operation, record
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+`
+
+type AppendFirehoseEventParams struct {
+	TimeUs     int64          `json:"time_us"`
+	Did        string         `json:"did"`
+	Collection string         `json:"collection"`
+	Rkey       string         `json:"rkey"`
+	Operation  string         `json:"operation"`
+	Record     sql.NullString `json:"record"`
+}
+
+// Firehose event queries
+func (q *Queries) AppendFirehoseEvent(ctx context.Context, arg AppendFirehoseEventParams) error {
+	_, err := q.exec(ctx, q.appendFirehoseEventStmt, AppendFirehoseEvent,
+		arg.TimeUs,
+		arg.Did,
+		arg.Collection,
+		arg.Rkey,
+		arg.Operation,
+		arg.Record,
+	)
+	return err
+}
+
+const AppendLabel = `-- name: AppendLabel :exec
+INSERT INTO quest_dis_label (
+    seq, src, uri, cid, val, neg, cts, sig
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+)
+`
+
+type AppendLabelParams struct {
+	Seq int64  `json:"seq"`
+	Src string `json:"src"`
+	Uri string `json:"uri"`
+	Cid string `json:"cid"`
+	Val string `json:"val"`
+	Neg bool   `json:"neg"`
+	Cts string `json:"cts"`
+	Sig []byte `json:"sig"`
+}
+
+// Label queries. Labels are signed as stored, so cts is kept as the
+// exact string that was signed.
+func (q *Queries) AppendLabel(ctx context.Context, arg AppendLabelParams) error {
+	_, err := q.exec(ctx, q.appendLabelStmt, AppendLabel,
+		arg.Seq,
+		arg.Src,
+		arg.Uri,
+		arg.Cid,
+		arg.Val,
+		arg.Neg,
+		arg.Cts,
+		arg.Sig,
+	)
+	return err
+}
+
+const CountAuthorMessagesSince = `-- name: CountAuthorMessagesSince :one
+SELECT COUNT(*) FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND did = $3 AND created_at >= $4
+`
+
+type CountAuthorMessagesSinceParams struct {
+	TopicDid  string    `json:"topic_did"`
+	TopicRkey string    `json:"topic_rkey"`
+	Did       string    `json:"did"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Counts an author's messages in a topic since a point in time, so the
+// first message of the day can be counted as a new participant
+func (q *Queries) CountAuthorMessagesSince(ctx context.Context, arg CountAuthorMessagesSinceParams) (int64, error) {
+	row := q.queryRow(ctx, q.countAuthorMessagesSinceStmt, CountAuthorMessagesSince,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.Did,
+		arg.CreatedAt,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountMirrorTopic = `-- name: CountMirrorTopic :one
+SELECT COUNT(*) FROM quest_dis_mirror_topic
+WHERE origin = $1 AND did = $2 AND rkey = $3
+`
+
+type CountMirrorTopicParams struct {
+	Origin string `json:"origin"`
+	Did    string `json:"did"`
+	Rkey   string `json:"rkey"`
+}
+
+func (q *Queries) CountMirrorTopic(ctx context.Context, arg CountMirrorTopicParams) (int64, error) {
+	row := q.queryRow(ctx, q.countMirrorTopicStmt, CountMirrorTopic, arg.Origin, arg.Did, arg.Rkey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountTopicModerator = `-- name: CountTopicModerator :one
+SELECT COUNT(*) FROM quest_dis_community_moderator m
+JOIN quest_dis_topic t ON t.community = m.community
+WHERE t.did = $1 AND t.rkey = $2 AND m.did = $3
+`
+
+type CountTopicModeratorParams struct {
+	TopicDid     string `json:"topic_did"`
+	TopicRkey    string `json:"topic_rkey"`
+	ModeratorDid string `json:"moderator_did"`
+}
+
+func (q *Queries) CountTopicModerator(ctx context.Context, arg CountTopicModeratorParams) (int64, error) {
+	row := q.queryRow(ctx, q.countTopicModeratorStmt, CountTopicModerator, arg.TopicDid, arg.TopicRkey, arg.ModeratorDid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountTopicResponses = `-- name: CountTopicResponses :one
+SELECT COUNT(*) FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND did <> $3
+`
+
+type CountTopicResponsesParams struct {
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+	Did       string `json:"did"`
+}
+
+// Counts messages in a topic written by someone other than its author
+func (q *Queries) CountTopicResponses(ctx context.Context, arg CountTopicResponsesParams) (int64, error) {
+	row := q.queryRow(ctx, q.countTopicResponsesStmt, CountTopicResponses, arg.TopicDid, arg.TopicRkey, arg.Did)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CountUnreadMessages = `-- name: CountUnreadMessages :one
+SELECT COUNT(*) FROM quest_dis_message m
+LEFT JOIN quest_dis_read_marker r ON r.did = $1 AND r.topic_did = m.topic_did AND r.topic_rkey = m.topic_rkey
+WHERE m.topic_did = $2 AND m.topic_rkey = $3 AND m.did <> $1 AND m.deleted_at IS NULL
+AND (r.last_read_at IS NULL OR m.created_at > r.last_read_at)
+`
+
+type CountUnreadMessagesParams struct {
+	Did       string `json:"did"`
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+}
+
+// Counts messages by others posted after the user's read marker
+func (q *Queries) CountUnreadMessages(ctx context.Context, arg CountUnreadMessagesParams) (int64, error) {
+	row := q.queryRow(ctx, q.countUnreadMessagesStmt, CountUnreadMessages, arg.Did, arg.TopicDid, arg.TopicRkey)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateAccessToken = `-- name: CreateAccessToken :one
+INSERT INTO quest_dis_access_token (
+    id, did, name, token_hash, scopes, created_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, did, name, token_hash, scopes, created_at, expires_at, last_used_at
+`
+
+type CreateAccessTokenParams struct {
+	ID        string    `json:"id"`
+	Did       string    `json:"did"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"token_hash"`
+	Scopes    string    `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Access token queries
+func (q *Queries) CreateAccessToken(ctx context.Context, arg CreateAccessTokenParams) (AccessToken, error) {
+	row := q.queryRow(ctx, q.createAccessTokenStmt, CreateAccessToken,
+		arg.ID,
+		arg.Did,
+		arg.Name,
+		arg.TokenHash,
+		arg.Scopes,
+		arg.CreatedAt,
+		arg.ExpiresAt,
+	)
+	var i AccessToken
+	err := row.Scan(
+		&i.ID,
+		&i.Did,
+		&i.Name,
+		&i.TokenHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const CreateCommunity = `-- name: CreateCommunity :one
+INSERT INTO quest_dis_community (slug, name, description, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING slug, name, description, created_by, created_at
+`
+
+type CreateCommunityParams struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateCommunity(ctx context.Context, arg CreateCommunityParams) (Community, error) {
+	row := q.queryRow(ctx, q.createCommunityStmt, CreateCommunity,
+		arg.Slug,
+		arg.Name,
+		arg.Description,
+		arg.CreatedBy,
+		arg.CreatedAt,
+	)
+	var i Community
+	err := row.Scan(
+		&i.Slug,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const CreateCommunityDomain = `-- name: CreateCommunityDomain :one
+INSERT INTO quest_dis_community_domain (domain, community, token, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING domain, community, token, verification_method, verified_at, created_at
+`
+
+type CreateCommunityDomainParams struct {
+	Domain    string    `json:"domain"`
+	Community string    `json:"community"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CreateCommunityDomain(ctx context.Context, arg CreateCommunityDomainParams) (CommunityDomain, error) {
+	row := q.queryRow(ctx, q.createCommunityDomainStmt, CreateCommunityDomain,
+		arg.Domain,
+		arg.Community,
+		arg.Token,
+		arg.CreatedAt,
+	)
+	var i CommunityDomain
+	err := row.Scan(
+		&i.Domain,
+		&i.Community,
+		&i.Token,
+		&i.VerificationMethod,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const CreateMessage = `-- name: CreateMessage :one
 INSERT INTO quest_dis_message (
     did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at
+) RETURNING did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by
 `
 
 type CreateMessageParams struct {
@@ -25,7 +401,7 @@ type CreateMessageParams struct {
 	TopicDid          string         `json:"topic_did"`
 	TopicRkey         string         `json:"topic_rkey"`
 	ParentMessageRkey sql.NullString `json:"parent_message_rkey"`
-	Content           string         `json:"content"`
+	Content           MessageContent `json:"content"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 }
@@ -52,6 +428,8 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
@@ -95,13 +473,66 @@ func (q *Queries) CreateParticipation(ctx context.Context, arg CreateParticipati
 	return i, err
 }
 
+const CreateSession = `-- name: CreateSession :one
+INSERT INTO quest_dis_session (
+    id, did, user_agent, ip, created_at, last_seen_at, dpop_key,
+    handle, display_name, avatar
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id, did, user_agent, ip, created_at, last_seen_at, dpop_key, handle, display_name, avatar
+`
+
+type CreateSessionParams struct {
+	ID          string    `json:"id"`
+	Did         string    `json:"did"`
+	UserAgent   string    `json:"user_agent"`
+	Ip          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	DpopKey     string    `json:"dpop_key"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Avatar      string    `json:"avatar"`
+}
+
+// Session queries
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.queryRow(ctx, q.createSessionStmt, CreateSession,
+		arg.ID,
+		arg.Did,
+		arg.UserAgent,
+		arg.Ip,
+		arg.CreatedAt,
+		arg.LastSeenAt,
+		arg.DpopKey,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Avatar,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.Did,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.DpopKey,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Avatar,
+	)
+	return i, err
+}
+
 const CreateTopic = `-- name: CreateTopic :one
 
 INSERT INTO quest_dis_topic (
-    did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer
+    did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, community
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count
 `
 
 type CreateTopicParams struct {
@@ -113,6 +544,7 @@ type CreateTopicParams struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	SelectedAnswer sql.NullString `json:"selected_answer"`
+	Community      string         `json:"community"`
 }
 
 // queries.sql - Central SQL query file for dis.quest
@@ -128,6 +560,7 @@ func (q *Queries) CreateTopic(ctx context.Context, arg CreateTopicParams) (Topic
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.SelectedAnswer,
+		arg.Community,
 	)
 	var i Topic
 	err := row.Scan(
@@ -139,10 +572,97 @@ func (q *Queries) CreateTopic(ctx context.Context, arg CreateTopicParams) (Topic
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.SelectedAnswer,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.MessageCount,
+		&i.LastActivityAt,
+		&i.HotRank,
+		&i.Community,
+		&i.ParticipantCount,
 	)
 	return i, err
 }
 
+const DeleteAccessToken = `-- name: DeleteAccessToken :execrows
+DELETE FROM quest_dis_access_token
+WHERE id = $1 AND did = $2
+`
+
+type DeleteAccessTokenParams struct {
+	ID  string `json:"id"`
+	Did string `json:"did"`
+}
+
+func (q *Queries) DeleteAccessToken(ctx context.Context, arg DeleteAccessTokenParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteAccessTokenStmt, DeleteAccessToken, arg.ID, arg.Did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteAccessTokensByDid = `-- name: DeleteAccessTokensByDid :execrows
+DELETE FROM quest_dis_access_token
+WHERE did = $1
+`
+
+func (q *Queries) DeleteAccessTokensByDid(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteAccessTokensByDidStmt, DeleteAccessTokensByDid, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteCommunityCategory = `-- name: DeleteCommunityCategory :execrows
+DELETE FROM quest_dis_community_category
+WHERE community = $1 AND slug = $2
+`
+
+type DeleteCommunityCategoryParams struct {
+	Community string `json:"community"`
+	Slug      string `json:"slug"`
+}
+
+func (q *Queries) DeleteCommunityCategory(ctx context.Context, arg DeleteCommunityCategoryParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteCommunityCategoryStmt, DeleteCommunityCategory, arg.Community, arg.Slug)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteCommunityDomain = `-- name: DeleteCommunityDomain :execrows
+DELETE FROM quest_dis_community_domain
+WHERE community = $1 AND domain = $2
+`
+
+type DeleteCommunityDomainParams struct {
+	Community string `json:"community"`
+	Domain    string `json:"domain"`
+}
+
+func (q *Queries) DeleteCommunityDomain(ctx context.Context, arg DeleteCommunityDomainParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteCommunityDomainStmt, DeleteCommunityDomain, arg.Community, arg.Domain)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteFirehoseEventsByAccount = `-- name: DeleteFirehoseEventsByAccount :execrows
+DELETE FROM quest_dis_firehose_event
+WHERE did = $1
+`
+
+func (q *Queries) DeleteFirehoseEventsByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteFirehoseEventsByAccountStmt, DeleteFirehoseEventsByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const DeleteMessage = `-- name: DeleteMessage :exec
 DELETE FROM quest_dis_message
 WHERE did = $1 AND rkey = $2
@@ -158,75 +678,412 @@ func (q *Queries) DeleteMessage(ctx context.Context, arg DeleteMessageParams) er
 	return err
 }
 
-const DeleteParticipation = `-- name: DeleteParticipation :exec
-DELETE FROM quest_dis_participation
-WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3
+const DeleteMessagesByAccount = `-- name: DeleteMessagesByAccount :execrows
+DELETE FROM quest_dis_message
+WHERE did = $1 OR topic_did = $1
 `
 
-type DeleteParticipationParams struct {
-	Did       string `json:"did"`
-	TopicDid  string `json:"topic_did"`
-	TopicRkey string `json:"topic_rkey"`
-}
-
-func (q *Queries) DeleteParticipation(ctx context.Context, arg DeleteParticipationParams) error {
-	_, err := q.exec(ctx, q.deleteParticipationStmt, DeleteParticipation, arg.Did, arg.TopicDid, arg.TopicRkey)
-	return err
+// Account purge queries. Rows in the account's own topics go with the topic.
+func (q *Queries) DeleteMessagesByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteMessagesByAccountStmt, DeleteMessagesByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const DeleteTopic = `-- name: DeleteTopic :exec
-DELETE FROM quest_dis_topic
-WHERE did = $1 AND rkey = $2
+const DeleteMirrorMessage = `-- name: DeleteMirrorMessage :exec
+DELETE FROM quest_dis_mirror_message
+WHERE origin = $1 AND did = $2 AND rkey = $3
 `
 
-type DeleteTopicParams struct {
-	Did  string `json:"did"`
-	Rkey string `json:"rkey"`
+type DeleteMirrorMessageParams struct {
+	Origin string `json:"origin"`
+	Did    string `json:"did"`
+	Rkey   string `json:"rkey"`
 }
 
-func (q *Queries) DeleteTopic(ctx context.Context, arg DeleteTopicParams) error {
-	_, err := q.exec(ctx, q.deleteTopicStmt, DeleteTopic, arg.Did, arg.Rkey)
+func (q *Queries) DeleteMirrorMessage(ctx context.Context, arg DeleteMirrorMessageParams) error {
+	_, err := q.exec(ctx, q.deleteMirrorMessageStmt, DeleteMirrorMessage, arg.Origin, arg.Did, arg.Rkey)
 	return err
 }
 
-const GetMessage = `-- name: GetMessage :one
-SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at FROM quest_dis_message
-WHERE did = $1 AND rkey = $2
+const DeleteMirrorTopic = `-- name: DeleteMirrorTopic :exec
+DELETE FROM quest_dis_mirror_topic
+WHERE origin = $1 AND did = $2 AND rkey = $3
 `
 
-type GetMessageParams struct {
-	Did  string `json:"did"`
-	Rkey string `json:"rkey"`
+type DeleteMirrorTopicParams struct {
+	Origin string `json:"origin"`
+	Did    string `json:"did"`
+	Rkey   string `json:"rkey"`
 }
 
-func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
-	row := q.queryRow(ctx, q.getMessageStmt, GetMessage, arg.Did, arg.Rkey)
-	var i Message
-	err := row.Scan(
-		&i.Did,
-		&i.Rkey,
-		&i.TopicDid,
-		&i.TopicRkey,
-		&i.ParentMessageRkey,
-		&i.Content,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) DeleteMirrorTopic(ctx context.Context, arg DeleteMirrorTopicParams) error {
+	_, err := q.exec(ctx, q.deleteMirrorTopicStmt, DeleteMirrorTopic, arg.Origin, arg.Did, arg.Rkey)
+	return err
 }
 
-const GetMessagesByTopic = `-- name: GetMessagesByTopic :many
-SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at FROM quest_dis_message
-WHERE topic_did = $1 AND topic_rkey = $2
-ORDER BY created_at ASC
+const DeleteMirrorTopicMessages = `-- name: DeleteMirrorTopicMessages :exec
+DELETE FROM quest_dis_mirror_message
+WHERE origin = $1 AND topic_did = $2 AND topic_rkey = $3
 `
 
-type GetMessagesByTopicParams struct {
+type DeleteMirrorTopicMessagesParams struct {
+	Origin    string `json:"origin"`
 	TopicDid  string `json:"topic_did"`
 	TopicRkey string `json:"topic_rkey"`
 }
 
-func (q *Queries) GetMessagesByTopic(ctx context.Context, arg GetMessagesByTopicParams) ([]Message, error) {
+func (q *Queries) DeleteMirrorTopicMessages(ctx context.Context, arg DeleteMirrorTopicMessagesParams) error {
+	_, err := q.exec(ctx, q.deleteMirrorTopicMessagesStmt, DeleteMirrorTopicMessages, arg.Origin, arg.TopicDid, arg.TopicRkey)
+	return err
+}
+
+const DeleteParticipation = `-- name: DeleteParticipation :exec
+DELETE FROM quest_dis_participation
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3
+`
+
+type DeleteParticipationParams struct {
+	Did       string `json:"did"`
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+}
+
+func (q *Queries) DeleteParticipation(ctx context.Context, arg DeleteParticipationParams) error {
+	_, err := q.exec(ctx, q.deleteParticipationStmt, DeleteParticipation, arg.Did, arg.TopicDid, arg.TopicRkey)
+	return err
+}
+
+const DeleteParticipationRetriesByAccount = `-- name: DeleteParticipationRetriesByAccount :execrows
+DELETE FROM quest_dis_participation_retry
+WHERE did = $1 OR topic_did = $1
+`
+
+func (q *Queries) DeleteParticipationRetriesByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteParticipationRetriesByAccountStmt, DeleteParticipationRetriesByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteParticipationRetry = `-- name: DeleteParticipationRetry :exec
+DELETE FROM quest_dis_participation_retry
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3
+`
+
+type DeleteParticipationRetryParams struct {
+	Did       string `json:"did"`
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+}
+
+func (q *Queries) DeleteParticipationRetry(ctx context.Context, arg DeleteParticipationRetryParams) error {
+	_, err := q.exec(ctx, q.deleteParticipationRetryStmt, DeleteParticipationRetry, arg.Did, arg.TopicDid, arg.TopicRkey)
+	return err
+}
+
+const DeleteParticipationsByAccount = `-- name: DeleteParticipationsByAccount :execrows
+DELETE FROM quest_dis_participation
+WHERE did = $1 OR topic_did = $1
+`
+
+func (q *Queries) DeleteParticipationsByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteParticipationsByAccountStmt, DeleteParticipationsByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteReadMarker = `-- name: DeleteReadMarker :exec
+DELETE FROM quest_dis_read_marker
+WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3
+`
+
+type DeleteReadMarkerParams struct {
+	Did       string `json:"did"`
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+}
+
+func (q *Queries) DeleteReadMarker(ctx context.Context, arg DeleteReadMarkerParams) error {
+	_, err := q.exec(ctx, q.deleteReadMarkerStmt, DeleteReadMarker, arg.Did, arg.TopicDid, arg.TopicRkey)
+	return err
+}
+
+const DeleteReadMarkersByAccount = `-- name: DeleteReadMarkersByAccount :execrows
+DELETE FROM quest_dis_read_marker
+WHERE did = $1 OR topic_did = $1
+`
+
+func (q *Queries) DeleteReadMarkersByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteReadMarkersByAccountStmt, DeleteReadMarkersByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteSession = `-- name: DeleteSession :execrows
+DELETE FROM quest_dis_session
+WHERE id = $1 AND did = $2
+`
+
+type DeleteSessionParams struct {
+	ID  string `json:"id"`
+	Did string `json:"did"`
+}
+
+func (q *Queries) DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteSessionStmt, DeleteSession, arg.ID, arg.Did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteSessionsByDid = `-- name: DeleteSessionsByDid :execrows
+DELETE FROM quest_dis_session
+WHERE did = $1
+`
+
+func (q *Queries) DeleteSessionsByDid(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteSessionsByDidStmt, DeleteSessionsByDid, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteTopic = `-- name: DeleteTopic :exec
+DELETE FROM quest_dis_topic
+WHERE did = $1 AND rkey = $2
+`
+
+type DeleteTopicParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) DeleteTopic(ctx context.Context, arg DeleteTopicParams) error {
+	_, err := q.exec(ctx, q.deleteTopicStmt, DeleteTopic, arg.Did, arg.Rkey)
+	return err
+}
+
+const DeleteTopicStatsByAccount = `-- name: DeleteTopicStatsByAccount :execrows
+DELETE FROM quest_dis_topic_stats
+WHERE topic_did = $1
+`
+
+func (q *Queries) DeleteTopicStatsByAccount(ctx context.Context, topicDid string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteTopicStatsByAccountStmt, DeleteTopicStatsByAccount, topicDid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteTopicsByAccount = `-- name: DeleteTopicsByAccount :execrows
+DELETE FROM quest_dis_topic
+WHERE did = $1
+`
+
+func (q *Queries) DeleteTopicsByAccount(ctx context.Context, did string) (int64, error) {
+	result, err := q.exec(ctx, q.deleteTopicsByAccountStmt, DeleteTopicsByAccount, did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const GetAccessTokenByHash = `-- name: GetAccessTokenByHash :one
+SELECT id, did, name, token_hash, scopes, created_at, expires_at, last_used_at FROM quest_dis_access_token
+WHERE token_hash = $1
+`
+
+func (q *Queries) GetAccessTokenByHash(ctx context.Context, tokenHash string) (AccessToken, error) {
+	row := q.queryRow(ctx, q.getAccessTokenByHashStmt, GetAccessTokenByHash, tokenHash)
+	var i AccessToken
+	err := row.Scan(
+		&i.ID,
+		&i.Did,
+		&i.Name,
+		&i.TokenHash,
+		&i.Scopes,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const GetCommunity = `-- name: GetCommunity :one
+SELECT slug, name, description, created_by, created_at FROM quest_dis_community
+WHERE slug = $1
+`
+
+func (q *Queries) GetCommunity(ctx context.Context, slug string) (Community, error) {
+	row := q.queryRow(ctx, q.getCommunityStmt, GetCommunity, slug)
+	var i Community
+	err := row.Scan(
+		&i.Slug,
+		&i.Name,
+		&i.Description,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetCommunityDomain = `-- name: GetCommunityDomain :one
+SELECT domain, community, token, verification_method, verified_at, created_at FROM quest_dis_community_domain
+WHERE domain = $1
+`
+
+func (q *Queries) GetCommunityDomain(ctx context.Context, domain string) (CommunityDomain, error) {
+	row := q.queryRow(ctx, q.getCommunityDomainStmt, GetCommunityDomain, domain)
+	var i CommunityDomain
+	err := row.Scan(
+		&i.Domain,
+		&i.Community,
+		&i.Token,
+		&i.VerificationMethod,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetDeletedMessage = `-- name: GetDeletedMessage :one
+SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by FROM quest_dis_message
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL
+`
+
+type GetDeletedMessageParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) GetDeletedMessage(ctx context.Context, arg GetDeletedMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.getDeletedMessageStmt, GetDeletedMessage, arg.Did, arg.Rkey)
+	var i Message
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.TopicDid,
+		&i.TopicRkey,
+		&i.ParentMessageRkey,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const GetDeletedTopic = `-- name: GetDeletedTopic :one
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL
+`
+
+type GetDeletedTopicParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) GetDeletedTopic(ctx context.Context, arg GetDeletedTopicParams) (Topic, error) {
+	row := q.queryRow(ctx, q.getDeletedTopicStmt, GetDeletedTopic, arg.Did, arg.Rkey)
+	var i Topic
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.Subject,
+		&i.InitialMessage,
+		&i.Category,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SelectedAnswer,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.MessageCount,
+		&i.LastActivityAt,
+		&i.HotRank,
+		&i.Community,
+		&i.ParticipantCount,
+	)
+	return i, err
+}
+
+const GetLatestFirehoseEventTime = `-- name: GetLatestFirehoseEventTime :one
+SELECT time_us FROM quest_dis_firehose_event
+ORDER BY time_us DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestFirehoseEventTime(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.getLatestFirehoseEventTimeStmt, GetLatestFirehoseEventTime)
+	var timeUs int64
+	err := row.Scan(&timeUs)
+	return timeUs, err
+}
+
+const GetLatestLabelSeq = `-- name: GetLatestLabelSeq :one
+SELECT seq FROM quest_dis_label
+ORDER BY seq DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestLabelSeq(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.getLatestLabelSeqStmt, GetLatestLabelSeq)
+	var seq int64
+	err := row.Scan(&seq)
+	return seq, err
+}
+
+const GetMessage = `-- name: GetMessage :one
+SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by FROM quest_dis_message
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NULL
+`
+
+type GetMessageParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageStmt, GetMessage, arg.Did, arg.Rkey)
+	var i Message
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.TopicDid,
+		&i.TopicRkey,
+		&i.ParentMessageRkey,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const GetMessagesByTopic = `-- name: GetMessagesByTopic :many
+SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND deleted_at IS NULL
+ORDER BY created_at ASC
+`
+
+type GetMessagesByTopicParams struct {
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+}
+
+func (q *Queries) GetMessagesByTopic(ctx context.Context, arg GetMessagesByTopicParams) ([]Message, error) {
 	rows, err := q.query(ctx, q.getMessagesByTopicStmt, GetMessagesByTopic, arg.TopicDid, arg.TopicRkey)
 	if err != nil {
 		return nil, err
@@ -244,6 +1101,8 @@ func (q *Queries) GetMessagesByTopic(ctx context.Context, arg GetMessagesByTopic
 			&i.Content,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -258,6 +1117,78 @@ func (q *Queries) GetMessagesByTopic(ctx context.Context, arg GetMessagesByTopic
 	return items, nil
 }
 
+const GetMirrorCursor = `-- name: GetMirrorCursor :one
+SELECT time_us FROM quest_dis_mirror_source
+WHERE origin = $1
+`
+
+// Mirror queries
+func (q *Queries) GetMirrorCursor(ctx context.Context, origin string) (int64, error) {
+	row := q.queryRow(ctx, q.getMirrorCursorStmt, GetMirrorCursor, origin)
+	var timeUs int64
+	err := row.Scan(&timeUs)
+	return timeUs, err
+}
+
+const GetMirrorRecord = `-- name: GetMirrorRecord :one
+SELECT origin, did, collection, rkey, cid, time_us, deleted FROM quest_dis_mirror_record
+WHERE origin = $1 AND did = $2 AND collection = $3 AND rkey = $4
+`
+
+type GetMirrorRecordParams struct {
+	Origin     string `json:"origin"`
+	Did        string `json:"did"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+}
+
+func (q *Queries) GetMirrorRecord(ctx context.Context, arg GetMirrorRecordParams) (MirrorRecord, error) {
+	row := q.queryRow(ctx, q.getMirrorRecordStmt, GetMirrorRecord,
+		arg.Origin,
+		arg.Did,
+		arg.Collection,
+		arg.Rkey,
+	)
+	var i MirrorRecord
+	err := row.Scan(
+		&i.Origin,
+		&i.Did,
+		&i.Collection,
+		&i.Rkey,
+		&i.Cid,
+		&i.TimeUs,
+		&i.Deleted,
+	)
+	return i, err
+}
+
+const GetMirrorTopic = `-- name: GetMirrorTopic :one
+SELECT did, rkey, origin, subject, initial_message, category, created_at, mirrored_at, verified FROM quest_dis_mirror_topic
+WHERE did = $1 AND rkey = $2
+`
+
+type GetMirrorTopicParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) GetMirrorTopic(ctx context.Context, arg GetMirrorTopicParams) (MirrorTopic, error) {
+	row := q.queryRow(ctx, q.getMirrorTopicStmt, GetMirrorTopic, arg.Did, arg.Rkey)
+	var i MirrorTopic
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.Origin,
+		&i.Subject,
+		&i.InitialMessage,
+		&i.Category,
+		&i.CreatedAt,
+		&i.MirroredAt,
+		&i.Verified,
+	)
+	return i, err
+}
+
 const GetParticipation = `-- name: GetParticipation :one
 SELECT did, topic_did, topic_rkey, status, created_at, updated_at FROM quest_dis_participation
 WHERE did = $1 AND topic_did = $2 AND topic_rkey = $3
@@ -360,8 +1291,8 @@ func (q *Queries) GetParticipationsByUser(ctx context.Context, did string) ([]Pa
 }
 
 const GetRepliesByMessage = `-- name: GetRepliesByMessage :many
-SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at FROM quest_dis_message
-WHERE topic_did = $1 AND topic_rkey = $2 AND parent_message_rkey = $3
+SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by FROM quest_dis_message
+WHERE topic_did = $1 AND topic_rkey = $2 AND parent_message_rkey = $3 AND deleted_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -389,6 +1320,8 @@ func (q *Queries) GetRepliesByMessage(ctx context.Context, arg GetRepliesByMessa
 			&i.Content,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -403,62 +1336,1732 @@ func (q *Queries) GetRepliesByMessage(ctx context.Context, arg GetRepliesByMessa
 	return items, nil
 }
 
-const GetTopic = `-- name: GetTopic :one
-SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer FROM quest_dis_topic
-WHERE did = $1 AND rkey = $2
+const GetSession = `-- name: GetSession :one
+SELECT id, did, user_agent, ip, created_at, last_seen_at, dpop_key, handle, display_name, avatar FROM quest_dis_session
+WHERE id = $1
 `
 
-type GetTopicParams struct {
-	Did  string `json:"did"`
-	Rkey string `json:"rkey"`
-}
-
-func (q *Queries) GetTopic(ctx context.Context, arg GetTopicParams) (Topic, error) {
-	row := q.queryRow(ctx, q.getTopicStmt, GetTopic, arg.Did, arg.Rkey)
+func (q *Queries) GetSession(ctx context.Context, id string) (Session, error) {
+	row := q.queryRow(ctx, q.getSessionStmt, GetSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.Did,
+		&i.UserAgent,
+		&i.Ip,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.DpopKey,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Avatar,
+	)
+	return i, err
+}
+
+const GetTopic = `-- name: GetTopic :one
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NULL
+`
+
+type GetTopicParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) GetTopic(ctx context.Context, arg GetTopicParams) (Topic, error) {
+	row := q.queryRow(ctx, q.getTopicStmt, GetTopic, arg.Did, arg.Rkey)
+	var i Topic
+	err := row.Scan(
+		&i.Did,
+		&i.Rkey,
+		&i.Subject,
+		&i.InitialMessage,
+		&i.Category,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SelectedAnswer,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.MessageCount,
+		&i.LastActivityAt,
+		&i.HotRank,
+		&i.Community,
+		&i.ParticipantCount,
+	)
+	return i, err
+}
+
+const GetTopicsByCategory = `-- name: GetTopicsByCategory :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE category = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetTopicsByCategoryParams struct {
+	Category sql.NullString `json:"category"`
+	Limit    int32          `json:"limit"`
+}
+
+func (q *Queries) GetTopicsByCategory(ctx context.Context, arg GetTopicsByCategoryParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.getTopicsByCategoryStmt, GetTopicsByCategory, arg.Category, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetUnreadCounts = `-- name: GetUnreadCounts :many
+SELECT m.topic_did, m.topic_rkey, COUNT(*) AS unread_count FROM quest_dis_message m
+LEFT JOIN quest_dis_read_marker r ON r.did = $1 AND r.topic_did = m.topic_did AND r.topic_rkey = m.topic_rkey
+WHERE m.did <> $1 AND m.deleted_at IS NULL
+AND (r.last_read_at IS NULL OR m.created_at > r.last_read_at)
+GROUP BY m.topic_did, m.topic_rkey
+`
+
+type GetUnreadCountsRow struct {
+	TopicDid    string `json:"topic_did"`
+	TopicRkey   string `json:"topic_rkey"`
+	UnreadCount int64  `json:"unread_count"`
+}
+
+func (q *Queries) GetUnreadCounts(ctx context.Context, did string) ([]GetUnreadCountsRow, error) {
+	rows, err := q.query(ctx, q.getUnreadCountsStmt, GetUnreadCounts, did)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetUnreadCountsRow{}
+	for rows.Next() {
+		var i GetUnreadCountsRow
+		if err := rows.Scan(
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetVerifiedDomainCommunity = `-- name: GetVerifiedDomainCommunity :one
+SELECT community FROM quest_dis_community_domain
+WHERE domain = $1 AND verified_at IS NOT NULL
+`
+
+func (q *Queries) GetVerifiedDomainCommunity(ctx context.Context, domain string) (string, error) {
+	row := q.queryRow(ctx, q.getVerifiedDomainCommunityStmt, GetVerifiedDomainCommunity, domain)
+	var community string
+	err := row.Scan(&community)
+	return community, err
+}
+
+const IncrementTopicActivity = `-- name: IncrementTopicActivity :one
+UPDATE quest_dis_topic
+SET message_count = message_count + 1,
+    last_activity_at = $1,
+    participant_count = participant_count + CASE
+        WHEN did <> $2 AND (
+            SELECT COUNT(*) FROM quest_dis_message m
+            WHERE m.topic_did = quest_dis_topic.did AND m.topic_rkey = quest_dis_topic.rkey AND m.did = $2
+        ) = 1 THEN 1 ELSE 0
+    END
+WHERE did = $3 AND rkey = $4
+RETURNING did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count
+`
+
+type IncrementTopicActivityParams struct {
+	LastActivityAt sql.NullTime `json:"last_activity_at"`
+	AuthorDid      string       `json:"author_did"`
+	Did            string       `json:"did"`
+	Rkey           string       `json:"rkey"`
+}
+
+// Counts a message that has just been inserted, and its author as a new
+// participant when it is their first message in a topic they did not create
+func (q *Queries) IncrementTopicActivity(ctx context.Context, arg IncrementTopicActivityParams) (Topic, error) {
+	row := q.queryRow(ctx, q.incrementTopicActivityStmt, IncrementTopicActivity,
+		arg.LastActivityAt,
+		arg.AuthorDid,
+		arg.Did,
+		arg.Rkey,
+	)
 	var i Topic
 	err := row.Scan(
 		&i.Did,
 		&i.Rkey,
-		&i.Subject,
-		&i.InitialMessage,
-		&i.Category,
+		&i.Subject,
+		&i.InitialMessage,
+		&i.Category,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SelectedAnswer,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.MessageCount,
+		&i.LastActivityAt,
+		&i.HotRank,
+		&i.Community,
+		&i.ParticipantCount,
+	)
+	return i, err
+}
+
+const ListAccessTokensByDid = `-- name: ListAccessTokensByDid :many
+SELECT id, did, name, token_hash, scopes, created_at, expires_at, last_used_at FROM quest_dis_access_token
+WHERE did = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListAccessTokensByDid(ctx context.Context, did string) ([]AccessToken, error) {
+	rows, err := q.query(ctx, q.listAccessTokensByDidStmt, ListAccessTokensByDid, did)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccessToken{}
+	for rows.Next() {
+		var i AccessToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Did,
+			&i.Name,
+			&i.TokenHash,
+			&i.Scopes,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCommunities = `-- name: ListCommunities :many
+SELECT slug, name, description, created_by, created_at FROM quest_dis_community
+ORDER BY name
+LIMIT $1 OFFSET $2
+`
+
+type ListCommunitiesParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListCommunities(ctx context.Context, arg ListCommunitiesParams) ([]Community, error) {
+	rows, err := q.query(ctx, q.listCommunitiesStmt, ListCommunities, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Community{}
+	for rows.Next() {
+		var i Community
+		if err := rows.Scan(
+			&i.Slug,
+			&i.Name,
+			&i.Description,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCommunityCategories = `-- name: ListCommunityCategories :many
+SELECT community, slug, name FROM quest_dis_community_category
+WHERE community = $1
+ORDER BY name
+`
+
+func (q *Queries) ListCommunityCategories(ctx context.Context, community string) ([]CommunityCategory, error) {
+	rows, err := q.query(ctx, q.listCommunityCategoriesStmt, ListCommunityCategories, community)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CommunityCategory{}
+	for rows.Next() {
+		var i CommunityCategory
+		if err := rows.Scan(
+			&i.Community,
+			&i.Slug,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCommunityDomains = `-- name: ListCommunityDomains :many
+SELECT domain, community, token, verification_method, verified_at, created_at FROM quest_dis_community_domain
+WHERE community = $1
+ORDER BY domain
+`
+
+func (q *Queries) ListCommunityDomains(ctx context.Context, community string) ([]CommunityDomain, error) {
+	rows, err := q.query(ctx, q.listCommunityDomainsStmt, ListCommunityDomains, community)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CommunityDomain{}
+	for rows.Next() {
+		var i CommunityDomain
+		if err := rows.Scan(
+			&i.Domain,
+			&i.Community,
+			&i.Token,
+			&i.VerificationMethod,
+			&i.VerifiedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCommunityModerators = `-- name: ListCommunityModerators :many
+SELECT community, did, created_at FROM quest_dis_community_moderator
+WHERE community = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListCommunityModerators(ctx context.Context, community string) ([]CommunityModerator, error) {
+	rows, err := q.query(ctx, q.listCommunityModeratorsStmt, ListCommunityModerators, community)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CommunityModerator{}
+	for rows.Next() {
+		var i CommunityModerator
+		if err := rows.Scan(
+			&i.Community,
+			&i.Did,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDailyStats = `-- name: ListDailyStats :many
+SELECT day,
+    CAST(SUM(views) AS BIGINT) AS views,
+    CAST(SUM(messages) AS BIGINT) AS messages,
+    CAST(SUM(participants) AS BIGINT) AS participants,
+    CAST(SUM(first_responses) AS BIGINT) AS first_responses,
+    CAST(SUM(response_seconds) AS BIGINT) AS response_seconds
+FROM quest_dis_topic_stats
+WHERE day >= $1
+GROUP BY day
+ORDER BY day DESC
+`
+
+type ListDailyStatsRow struct {
+	Day             time.Time `json:"day"`
+	Views           int64     `json:"views"`
+	Messages        int64     `json:"messages"`
+	Participants    int64     `json:"participants"`
+	FirstResponses  int64     `json:"first_responses"`
+	ResponseSeconds int64     `json:"response_seconds"`
+}
+
+func (q *Queries) ListDailyStats(ctx context.Context, day time.Time) ([]ListDailyStatsRow, error) {
+	rows, err := q.query(ctx, q.listDailyStatsStmt, ListDailyStats, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDailyStatsRow{}
+	for rows.Next() {
+		var i ListDailyStatsRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.Views,
+			&i.Messages,
+			&i.Participants,
+			&i.FirstResponses,
+			&i.ResponseSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDueParticipationRetries = `-- name: ListDueParticipationRetries :many
+SELECT did, topic_did, topic_rkey, rkey, attempts, last_error, next_attempt_at, created_at FROM quest_dis_participation_retry
+WHERE next_attempt_at <= $1
+ORDER BY next_attempt_at ASC
+LIMIT $2
+`
+
+type ListDueParticipationRetriesParams struct {
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Limit         int32     `json:"limit"`
+}
+
+func (q *Queries) ListDueParticipationRetries(ctx context.Context, arg ListDueParticipationRetriesParams) ([]ParticipationRetry, error) {
+	rows, err := q.query(ctx, q.listDueParticipationRetriesStmt, ListDueParticipationRetries, arg.NextAttemptAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ParticipationRetry{}
+	for rows.Next() {
+		var i ParticipationRetry
+		if err := rows.Scan(
+			&i.Did,
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.Rkey,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListFirehoseEventsSince = `-- name: ListFirehoseEventsSince :many
+SELECT time_us, did, collection, rkey, operation, record FROM quest_dis_firehose_event
+WHERE time_us >= $1
+ORDER BY time_us
+LIMIT $2
+`
+
+type ListFirehoseEventsSinceParams struct {
+	TimeUs int64 `json:"time_us"`
+	Limit  int32 `json:"limit"`
+}
+
+func (q *Queries) ListFirehoseEventsSince(ctx context.Context, arg ListFirehoseEventsSinceParams) ([]FirehoseEvent, error) {
+	rows, err := q.query(ctx, q.listFirehoseEventsSinceStmt, ListFirehoseEventsSince, arg.TimeUs, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FirehoseEvent{}
+	for rows.Next() {
+		var i FirehoseEvent
+		if err := rows.Scan(
+			&i.TimeUs,
+			&i.Did,
+			&i.Collection,
+			&i.Rkey,
+			&i.Operation,
+			&i.Record,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListLabelsByURI = `-- name: ListLabelsByURI :many
+SELECT seq, src, uri, cid, val, neg, cts, sig FROM quest_dis_label
+WHERE uri = $1 AND seq > $2
+ORDER BY seq
+LIMIT $3
+`
+
+type ListLabelsByURIParams struct {
+	Uri   string `json:"uri"`
+	Seq   int64  `json:"seq"`
+	Limit int32  `json:"limit"`
+}
+
+func (q *Queries) ListLabelsByURI(ctx context.Context, arg ListLabelsByURIParams) ([]Label, error) {
+	rows, err := q.query(ctx, q.listLabelsByURIStmt, ListLabelsByURI, arg.Uri, arg.Seq, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Label{}
+	for rows.Next() {
+		var i Label
+		if err := rows.Scan(
+			&i.Seq,
+			&i.Src,
+			&i.Uri,
+			&i.Cid,
+			&i.Val,
+			&i.Neg,
+			&i.Cts,
+			&i.Sig,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListLabelsByURIPrefix = `-- name: ListLabelsByURIPrefix :many
+SELECT seq, src, uri, cid, val, neg, cts, sig FROM quest_dis_label
+WHERE substr(uri, 1, length(CAST($1 AS TEXT))) = CAST($1 AS TEXT)
+    AND seq > $2
+ORDER BY seq
+LIMIT $3
+`
+
+type ListLabelsByURIPrefixParams struct {
+	Prefix   string `json:"prefix"`
+	Seq      int64  `json:"seq"`
+	RowLimit int32  `json:"row_limit"`
+}
+
+func (q *Queries) ListLabelsByURIPrefix(ctx context.Context, arg ListLabelsByURIPrefixParams) ([]Label, error) {
+	rows, err := q.query(ctx, q.listLabelsByURIPrefixStmt, ListLabelsByURIPrefix, arg.Prefix, arg.Seq, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Label{}
+	for rows.Next() {
+		var i Label
+		if err := rows.Scan(
+			&i.Seq,
+			&i.Src,
+			&i.Uri,
+			&i.Cid,
+			&i.Val,
+			&i.Neg,
+			&i.Cts,
+			&i.Sig,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListLabelsSince = `-- name: ListLabelsSince :many
+SELECT seq, src, uri, cid, val, neg, cts, sig FROM quest_dis_label
+WHERE seq > $1
+ORDER BY seq
+LIMIT $2
+`
+
+type ListLabelsSinceParams struct {
+	Seq   int64 `json:"seq"`
+	Limit int32 `json:"limit"`
+}
+
+func (q *Queries) ListLabelsSince(ctx context.Context, arg ListLabelsSinceParams) ([]Label, error) {
+	rows, err := q.query(ctx, q.listLabelsSinceStmt, ListLabelsSince, arg.Seq, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Label{}
+	for rows.Next() {
+		var i Label
+		if err := rows.Scan(
+			&i.Seq,
+			&i.Src,
+			&i.Uri,
+			&i.Cid,
+			&i.Val,
+			&i.Neg,
+			&i.Cts,
+			&i.Sig,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMessagesByAuthor = `-- name: ListMessagesByAuthor :many
+SELECT m.did, m.rkey, m.topic_did, m.topic_rkey, m.parent_message_rkey, m.content, m.created_at, m.updated_at, m.deleted_at, m.deleted_by FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.did = $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL
+ORDER BY m.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListMessagesByAuthorParams struct {
+	Did    string `json:"did"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+// Messages in removed topics are excluded along with removed messages
+func (q *Queries) ListMessagesByAuthor(ctx context.Context, arg ListMessagesByAuthorParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.listMessagesByAuthorStmt, ListMessagesByAuthor, arg.Did, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.ParentMessageRkey,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMirrorMessages = `-- name: ListMirrorMessages :many
+SELECT did, rkey, origin, topic_did, topic_rkey, parent_message_rkey, content, created_at, mirrored_at, verified FROM quest_dis_mirror_message
+WHERE topic_did = $1 AND topic_rkey = $2
+ORDER BY created_at ASC
+LIMIT $3 OFFSET $4
+`
+
+type ListMirrorMessagesParams struct {
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListMirrorMessages(ctx context.Context, arg ListMirrorMessagesParams) ([]MirrorMessage, error) {
+	rows, err := q.query(ctx, q.listMirrorMessagesStmt, ListMirrorMessages,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MirrorMessage{}
+	for rows.Next() {
+		var i MirrorMessage
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Origin,
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.ParentMessageRkey,
+			&i.Content,
+			&i.CreatedAt,
+			&i.MirroredAt,
+			&i.Verified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMirrorTopics = `-- name: ListMirrorTopics :many
+SELECT did, rkey, origin, subject, initial_message, category, created_at, mirrored_at, verified FROM quest_dis_mirror_topic
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListMirrorTopicsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListMirrorTopics(ctx context.Context, arg ListMirrorTopicsParams) ([]MirrorTopic, error) {
+	rows, err := q.query(ctx, q.listMirrorTopicsStmt, ListMirrorTopics, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MirrorTopic{}
+	for rows.Next() {
+		var i MirrorTopic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Origin,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.MirroredAt,
+			&i.Verified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMirrorTopicsByCategory = `-- name: ListMirrorTopicsByCategory :many
+SELECT did, rkey, origin, subject, initial_message, category, created_at, mirrored_at, verified FROM quest_dis_mirror_topic
+WHERE category = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListMirrorTopicsByCategoryParams struct {
+	Category string `json:"category"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+func (q *Queries) ListMirrorTopicsByCategory(ctx context.Context, arg ListMirrorTopicsByCategoryParams) ([]MirrorTopic, error) {
+	rows, err := q.query(ctx, q.listMirrorTopicsByCategoryStmt, ListMirrorTopicsByCategory, arg.Category, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MirrorTopic{}
+	for rows.Next() {
+		var i MirrorTopic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Origin,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.MirroredAt,
+			&i.Verified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListParticipationRetries = `-- name: ListParticipationRetries :many
+SELECT did, topic_did, topic_rkey, rkey, attempts, last_error, next_attempt_at, created_at FROM quest_dis_participation_retry
+ORDER BY created_at ASC
+LIMIT $1
+`
+
+func (q *Queries) ListParticipationRetries(ctx context.Context, limit int32) ([]ParticipationRetry, error) {
+	rows, err := q.query(ctx, q.listParticipationRetriesStmt, ListParticipationRetries, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ParticipationRetry{}
+	for rows.Next() {
+		var i ParticipationRetry
+		if err := rows.Scan(
+			&i.Did,
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.Rkey,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRelatedTopicCandidates = `-- name: ListRelatedTopicCandidates :many
+SELECT did, rkey, subject, initial_message, category FROM quest_dis_topic
+WHERE deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $1
+`
+
+type ListRelatedTopicCandidatesRow struct {
+	Did            string         `json:"did"`
+	Rkey           string         `json:"rkey"`
+	Subject        string         `json:"subject"`
+	InitialMessage string         `json:"initial_message"`
+	Category       sql.NullString `json:"category"`
+}
+
+// Lists the most recent live topics for the related-topics model
+func (q *Queries) ListRelatedTopicCandidates(ctx context.Context, limit int32) ([]ListRelatedTopicCandidatesRow, error) {
+	rows, err := q.query(ctx, q.listRelatedTopicCandidatesStmt, ListRelatedTopicCandidates, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListRelatedTopicCandidatesRow{}
+	for rows.Next() {
+		var i ListRelatedTopicCandidatesRow
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListSessionsByDid = `-- name: ListSessionsByDid :many
+SELECT id, did, user_agent, ip, created_at, last_seen_at, dpop_key, handle, display_name, avatar FROM quest_dis_session
+WHERE did = $1
+ORDER BY last_seen_at DESC
+`
+
+func (q *Queries) ListSessionsByDid(ctx context.Context, did string) ([]Session, error) {
+	rows, err := q.query(ctx, q.listSessionsByDidStmt, ListSessionsByDid, did)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.Did,
+			&i.UserAgent,
+			&i.Ip,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+			&i.DpopKey,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Avatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopTopicsByViews = `-- name: ListTopTopicsByViews :many
+SELECT s.topic_did, s.topic_rkey, t.subject,
+    CAST(SUM(s.views) AS BIGINT) AS views,
+    CAST(SUM(s.messages) AS BIGINT) AS messages
+FROM quest_dis_topic_stats s
+JOIN quest_dis_topic t ON t.did = s.topic_did AND t.rkey = s.topic_rkey
+WHERE s.day >= $1 AND t.deleted_at IS NULL
+GROUP BY s.topic_did, s.topic_rkey, t.subject
+ORDER BY views DESC
+LIMIT $2
+`
+
+type ListTopTopicsByViewsParams struct {
+	Day   time.Time `json:"day"`
+	Limit int32     `json:"limit"`
+}
+
+type ListTopTopicsByViewsRow struct {
+	TopicDid  string `json:"topic_did"`
+	TopicRkey string `json:"topic_rkey"`
+	Subject   string `json:"subject"`
+	Views     int64  `json:"views"`
+	Messages  int64  `json:"messages"`
+}
+
+func (q *Queries) ListTopTopicsByViews(ctx context.Context, arg ListTopTopicsByViewsParams) ([]ListTopTopicsByViewsRow, error) {
+	rows, err := q.query(ctx, q.listTopTopicsByViewsStmt, ListTopTopicsByViews, arg.Day, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTopTopicsByViewsRow{}
+	for rows.Next() {
+		var i ListTopTopicsByViewsRow
+		if err := rows.Scan(
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.Subject,
+			&i.Views,
+			&i.Messages,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicStats = `-- name: ListTopicStats :many
+SELECT topic_did, topic_rkey, day, views, messages, participants, first_responses, response_seconds FROM quest_dis_topic_stats
+WHERE topic_did = $1 AND topic_rkey = $2 AND day >= $3
+ORDER BY day DESC
+`
+
+type ListTopicStatsParams struct {
+	TopicDid  string    `json:"topic_did"`
+	TopicRkey string    `json:"topic_rkey"`
+	Day       time.Time `json:"day"`
+}
+
+func (q *Queries) ListTopicStats(ctx context.Context, arg ListTopicStatsParams) ([]TopicStats, error) {
+	rows, err := q.query(ctx, q.listTopicStatsStmt, ListTopicStats, arg.TopicDid, arg.TopicRkey, arg.Day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TopicStats{}
+	for rows.Next() {
+		var i TopicStats
+		if err := rows.Scan(
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.Day,
+			&i.Views,
+			&i.Messages,
+			&i.Participants,
+			&i.FirstResponses,
+			&i.ResponseSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopics = `-- name: ListTopics :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTopicsParams struct {
+	Community string `json:"community"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListTopics(ctx context.Context, arg ListTopicsParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsStmt, ListTopics, arg.Community, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicsByActivity = `-- name: ListTopicsByActivity :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY COALESCE(last_activity_at, created_at) DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTopicsByActivityParams struct {
+	Community string `json:"community"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListTopicsByActivity(ctx context.Context, arg ListTopicsByActivityParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsByActivityStmt, ListTopicsByActivity, arg.Community, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicsByAuthor = `-- name: ListTopicsByAuthor :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE did = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTopicsByAuthorParams struct {
+	Did    string `json:"did"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListTopicsByAuthor(ctx context.Context, arg ListTopicsByAuthorParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsByAuthorStmt, ListTopicsByAuthor, arg.Did, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicsByHotRank = `-- name: ListTopicsByHotRank :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY hot_rank DESC, created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTopicsByHotRankParams struct {
+	Community string `json:"community"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListTopicsByHotRank(ctx context.Context, arg ListTopicsByHotRankParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsByHotRankStmt, ListTopicsByHotRank, arg.Community, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicsByMessageCount = `-- name: ListTopicsByMessageCount :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE community = $1 AND deleted_at IS NULL
+ORDER BY message_count DESC, created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTopicsByMessageCountParams struct {
+	Community string `json:"community"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListTopicsByMessageCount(ctx context.Context, arg ListTopicsByMessageCountParams) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsByMessageCountStmt, ListTopicsByMessageCount, arg.Community, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTopicsMissingCreatorParticipation = `-- name: ListTopicsMissingCreatorParticipation :many
+SELECT t.did, t.rkey, t.subject, t.initial_message, t.category, t.created_at, t.updated_at, t.selected_answer, t.deleted_at, t.deleted_by, t.message_count, t.last_activity_at, t.hot_rank, t.community, t.participant_count FROM quest_dis_topic t
+LEFT JOIN quest_dis_participation p ON p.did = t.did AND p.topic_did = t.did AND p.topic_rkey = t.rkey
+WHERE p.did IS NULL AND t.deleted_at IS NULL
+ORDER BY t.created_at DESC
+LIMIT $1
+`
+
+// Topics whose author has no participation row, for the reconciliation report
+func (q *Queries) ListTopicsMissingCreatorParticipation(ctx context.Context, limit int32) ([]Topic, error) {
+	rows, err := q.query(ctx, q.listTopicsMissingCreatorParticipationStmt, ListTopicsMissingCreatorParticipation, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Topic{}
+	for rows.Next() {
+		var i Topic
+		if err := rows.Scan(
+			&i.Did,
+			&i.Rkey,
+			&i.Subject,
+			&i.InitialMessage,
+			&i.Category,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTrendingCategories = `-- name: ListTrendingCategories :many
+SELECT t.category,
+    COUNT(*) AS messages,
+    COUNT(DISTINCT m.topic_did || ':' || m.topic_rkey) AS topics
+FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.created_at >= $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL AND t.category <> ''
+GROUP BY t.category
+ORDER BY messages DESC, topics DESC, t.category
+LIMIT $2
+`
+
+type ListTrendingCategoriesParams struct {
+	CreatedAt time.Time `json:"created_at"`
+	Limit     int32     `json:"limit"`
+}
+
+type ListTrendingCategoriesRow struct {
+	Category sql.NullString `json:"category"`
+	Messages int64          `json:"messages"`
+	Topics   int64          `json:"topics"`
+}
+
+// Ranks categories by messages posted since a point in time across their topics
+func (q *Queries) ListTrendingCategories(ctx context.Context, arg ListTrendingCategoriesParams) ([]ListTrendingCategoriesRow, error) {
+	rows, err := q.query(ctx, q.listTrendingCategoriesStmt, ListTrendingCategories, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrendingCategoriesRow{}
+	for rows.Next() {
+		var i ListTrendingCategoriesRow
+		if err := rows.Scan(
+			&i.Category,
+			&i.Messages,
+			&i.Topics,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTrendingTopics = `-- name: ListTrendingTopics :many
+SELECT m.topic_did, m.topic_rkey, t.subject,
+    COUNT(*) AS messages,
+    COUNT(DISTINCT m.did) AS participants
+FROM quest_dis_message m
+JOIN quest_dis_topic t ON t.did = m.topic_did AND t.rkey = m.topic_rkey
+WHERE m.created_at >= $1 AND m.deleted_at IS NULL AND t.deleted_at IS NULL
+GROUP BY m.topic_did, m.topic_rkey, t.subject
+ORDER BY messages DESC, participants DESC, m.topic_did, m.topic_rkey
+LIMIT $2
+`
+
+type ListTrendingTopicsParams struct {
+	CreatedAt time.Time `json:"created_at"`
+	Limit     int32     `json:"limit"`
+}
+
+type ListTrendingTopicsRow struct {
+	TopicDid     string `json:"topic_did"`
+	TopicRkey    string `json:"topic_rkey"`
+	Subject      string `json:"subject"`
+	Messages     int64  `json:"messages"`
+	Participants int64  `json:"participants"`
+}
+
+// Ranks topics by messages posted since a point in time, breaking ties by
+// how many people posted them
+func (q *Queries) ListTrendingTopics(ctx context.Context, arg ListTrendingTopicsParams) ([]ListTrendingTopicsRow, error) {
+	rows, err := q.query(ctx, q.listTrendingTopicsStmt, ListTrendingTopics, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrendingTopicsRow{}
+	for rows.Next() {
+		var i ListTrendingTopicsRow
+		if err := rows.Scan(
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.Subject,
+			&i.Messages,
+			&i.Participants,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const MarkCommunityDomainVerified = `-- name: MarkCommunityDomainVerified :one
+UPDATE quest_dis_community_domain
+SET verification_method = $1, verified_at = $2
+WHERE domain = $3
+RETURNING domain, community, token, verification_method, verified_at, created_at
+`
+
+type MarkCommunityDomainVerifiedParams struct {
+	VerificationMethod sql.NullString `json:"verification_method"`
+	VerifiedAt         sql.NullTime   `json:"verified_at"`
+	Domain             string         `json:"domain"`
+}
+
+func (q *Queries) MarkCommunityDomainVerified(ctx context.Context, arg MarkCommunityDomainVerifiedParams) (CommunityDomain, error) {
+	row := q.queryRow(ctx, q.markCommunityDomainVerifiedStmt, MarkCommunityDomainVerified, arg.VerificationMethod, arg.VerifiedAt, arg.Domain)
+	var i CommunityDomain
+	err := row.Scan(
+		&i.Domain,
+		&i.Community,
+		&i.Token,
+		&i.VerificationMethod,
+		&i.VerifiedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const PurgeDeletedMessages = `-- name: PurgeDeletedMessages :execrows
+DELETE FROM quest_dis_message
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedMessages(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.exec(ctx, q.purgeDeletedMessagesStmt, PurgeDeletedMessages, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const PurgeDeletedTopics = `-- name: PurgeDeletedTopics :execrows
+DELETE FROM quest_dis_topic
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedTopics(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.exec(ctx, q.purgeDeletedTopicsStmt, PurgeDeletedTopics, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const PurgeFirehoseEvents = `-- name: PurgeFirehoseEvents :execrows
+DELETE FROM quest_dis_firehose_event
+WHERE time_us < $1
+`
+
+func (q *Queries) PurgeFirehoseEvents(ctx context.Context, timeUs int64) (int64, error) {
+	result, err := q.exec(ctx, q.purgeFirehoseEventsStmt, PurgeFirehoseEvents, timeUs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const PurgeIdleSessions = `-- name: PurgeIdleSessions :execrows
+DELETE FROM quest_dis_session
+WHERE last_seen_at < $1
+`
+
+func (q *Queries) PurgeIdleSessions(ctx context.Context, lastSeenAt time.Time) (int64, error) {
+	result, err := q.exec(ctx, q.purgeIdleSessionsStmt, PurgeIdleSessions, lastSeenAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const QueueParticipationRetry = `-- name: QueueParticipationRetry :one
+INSERT INTO quest_dis_participation_retry (
+    did, topic_did, topic_rkey, rkey, attempts, last_error, next_attempt_at, created_at
+) VALUES (
+    $1, $2, $3, $4, 0, $5, $6, $7
+)
+ON CONFLICT (did, topic_did, topic_rkey)
+DO UPDATE SET last_error = EXCLUDED.last_error
+RETURNING did, topic_did, topic_rkey, rkey, attempts, last_error, next_attempt_at, created_at
+`
+
+type QueueParticipationRetryParams struct {
+	Did           string    `json:"did"`
+	TopicDid      string    `json:"topic_did"`
+	TopicRkey     string    `json:"topic_rkey"`
+	Rkey          string    `json:"rkey"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Participation retry queries
+func (q *Queries) QueueParticipationRetry(ctx context.Context, arg QueueParticipationRetryParams) (ParticipationRetry, error) {
+	row := q.queryRow(ctx, q.queueParticipationRetryStmt, QueueParticipationRetry,
+		arg.Did,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.Rkey,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.CreatedAt,
+	)
+	var i ParticipationRetry
+	err := row.Scan(
+		&i.Did,
+		&i.TopicDid,
+		&i.TopicRkey,
+		&i.Rkey,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
 		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.SelectedAnswer,
 	)
 	return i, err
 }
 
-const GetTopicsByCategory = `-- name: GetTopicsByCategory :many
-SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer FROM quest_dis_topic
-WHERE category = $1
-ORDER BY created_at DESC
-LIMIT $2
+const RemoveCommunityModerator = `-- name: RemoveCommunityModerator :execrows
+DELETE FROM quest_dis_community_moderator
+WHERE community = $1 AND did = $2
 `
 
-type GetTopicsByCategoryParams struct {
-	Category sql.NullString `json:"category"`
-	Limit    int32          `json:"limit"`
+type RemoveCommunityModeratorParams struct {
+	Community string `json:"community"`
+	Did       string `json:"did"`
 }
 
-func (q *Queries) GetTopicsByCategory(ctx context.Context, arg GetTopicsByCategoryParams) ([]Topic, error) {
-	rows, err := q.query(ctx, q.getTopicsByCategoryStmt, GetTopicsByCategory, arg.Category, arg.Limit)
+func (q *Queries) RemoveCommunityModerator(ctx context.Context, arg RemoveCommunityModeratorParams) (int64, error) {
+	result, err := q.exec(ctx, q.removeCommunityModeratorStmt, RemoveCommunityModerator, arg.Community, arg.Did)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const RescheduleParticipationRetry = `-- name: RescheduleParticipationRetry :exec
+UPDATE quest_dis_participation_retry
+SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+WHERE did = $3 AND topic_did = $4 AND topic_rkey = $5
+`
+
+type RescheduleParticipationRetryParams struct {
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Did           string    `json:"did"`
+	TopicDid      string    `json:"topic_did"`
+	TopicRkey     string    `json:"topic_rkey"`
+}
+
+func (q *Queries) RescheduleParticipationRetry(ctx context.Context, arg RescheduleParticipationRetryParams) error {
+	_, err := q.exec(ctx, q.rescheduleParticipationRetryStmt, RescheduleParticipationRetry,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.Did,
+		arg.TopicDid,
+		arg.TopicRkey,
+	)
+	return err
+}
+
+const RestoreMessage = `-- name: RestoreMessage :execrows
+UPDATE quest_dis_message
+SET deleted_at = NULL, deleted_by = NULL
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL
+`
+
+type RestoreMessageParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) RestoreMessage(ctx context.Context, arg RestoreMessageParams) (int64, error) {
+	result, err := q.exec(ctx, q.restoreMessageStmt, RestoreMessage, arg.Did, arg.Rkey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const RestoreTopic = `-- name: RestoreTopic :execrows
+UPDATE quest_dis_topic
+SET deleted_at = NULL, deleted_by = NULL
+WHERE did = $1 AND rkey = $2 AND deleted_at IS NOT NULL
+`
+
+type RestoreTopicParams struct {
+	Did  string `json:"did"`
+	Rkey string `json:"rkey"`
+}
+
+func (q *Queries) RestoreTopic(ctx context.Context, arg RestoreTopicParams) (int64, error) {
+	result, err := q.exec(ctx, q.restoreTopicStmt, RestoreTopic, arg.Did, arg.Rkey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const SampleMessages = `-- name: SampleMessages :many
+SELECT did, rkey, topic_did, topic_rkey, parent_message_rkey, content, created_at, updated_at, deleted_at, deleted_by FROM quest_dis_message
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT $1
+`
+
+// A random sample of live messages, for checking the index against the PDS
+func (q *Queries) SampleMessages(ctx context.Context, limit int32) ([]Message, error) {
+	rows, err := q.query(ctx, q.sampleMessagesStmt, SampleMessages, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Topic{}
+	items := []Message{}
 	for rows.Next() {
-		var i Topic
+		var i Message
 		if err := rows.Scan(
 			&i.Did,
 			&i.Rkey,
-			&i.Subject,
-			&i.InitialMessage,
-			&i.Category,
+			&i.TopicDid,
+			&i.TopicRkey,
+			&i.ParentMessageRkey,
+			&i.Content,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -473,19 +3076,16 @@ func (q *Queries) GetTopicsByCategory(ctx context.Context, arg GetTopicsByCatego
 	return items, nil
 }
 
-const ListTopics = `-- name: ListTopics :many
-SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer FROM quest_dis_topic
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+const SampleTopics = `-- name: SampleTopics :many
+SELECT did, rkey, subject, initial_message, category, created_at, updated_at, selected_answer, deleted_at, deleted_by, message_count, last_activity_at, hot_rank, community, participant_count FROM quest_dis_topic
+WHERE deleted_at IS NULL
+ORDER BY RANDOM()
+LIMIT $1
 `
 
-type ListTopicsParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
-}
-
-func (q *Queries) ListTopics(ctx context.Context, arg ListTopicsParams) ([]Topic, error) {
-	rows, err := q.query(ctx, q.listTopicsStmt, ListTopics, arg.Limit, arg.Offset)
+// A random sample of live topics, for checking the index against the PDS
+func (q *Queries) SampleTopics(ctx context.Context, limit int32) ([]Topic, error) {
+	rows, err := q.query(ctx, q.sampleTopicsStmt, SampleTopics, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -502,6 +3102,13 @@ func (q *Queries) ListTopics(ctx context.Context, arg ListTopicsParams) ([]Topic
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.SelectedAnswer,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.MessageCount,
+			&i.LastActivityAt,
+			&i.HotRank,
+			&i.Community,
+			&i.ParticipantCount,
 		); err != nil {
 			return nil, err
 		}
@@ -516,6 +3123,139 @@ func (q *Queries) ListTopics(ctx context.Context, arg ListTopicsParams) ([]Topic
 	return items, nil
 }
 
+const SaveMirrorCursor = `-- name: SaveMirrorCursor :exec
+INSERT INTO quest_dis_mirror_source (origin, time_us, updated_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (origin) DO UPDATE SET time_us = EXCLUDED.time_us, updated_at = EXCLUDED.updated_at
+`
+
+type SaveMirrorCursorParams struct {
+	Origin    string    `json:"origin"`
+	TimeUs    int64     `json:"time_us"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) SaveMirrorCursor(ctx context.Context, arg SaveMirrorCursorParams) error {
+	_, err := q.exec(ctx, q.saveMirrorCursorStmt, SaveMirrorCursor, arg.Origin, arg.TimeUs, arg.UpdatedAt)
+	return err
+}
+
+const SaveMirrorRecord = `-- name: SaveMirrorRecord :exec
+INSERT INTO quest_dis_mirror_record (origin, did, collection, rkey, cid, time_us, deleted)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (origin, did, collection, rkey) DO UPDATE SET
+    cid = EXCLUDED.cid,
+    time_us = EXCLUDED.time_us,
+    deleted = EXCLUDED.deleted
+`
+
+type SaveMirrorRecordParams struct {
+	Origin     string `json:"origin"`
+	Did        string `json:"did"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+	Cid        string `json:"cid"`
+	TimeUs     int64  `json:"time_us"`
+	Deleted    bool   `json:"deleted"`
+}
+
+func (q *Queries) SaveMirrorRecord(ctx context.Context, arg SaveMirrorRecordParams) error {
+	_, err := q.exec(ctx, q.saveMirrorRecordStmt, SaveMirrorRecord,
+		arg.Origin,
+		arg.Did,
+		arg.Collection,
+		arg.Rkey,
+		arg.Cid,
+		arg.TimeUs,
+		arg.Deleted,
+	)
+	return err
+}
+
+const SoftDeleteMessage = `-- name: SoftDeleteMessage :execrows
+UPDATE quest_dis_message
+SET deleted_at = $1, deleted_by = $2
+WHERE did = $3 AND rkey = $4 AND deleted_at IS NULL
+`
+
+type SoftDeleteMessageParams struct {
+	DeletedAt sql.NullTime   `json:"deleted_at"`
+	DeletedBy sql.NullString `json:"deleted_by"`
+	Did       string         `json:"did"`
+	Rkey      string         `json:"rkey"`
+}
+
+func (q *Queries) SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) (int64, error) {
+	result, err := q.exec(ctx, q.softDeleteMessageStmt, SoftDeleteMessage,
+		arg.DeletedAt,
+		arg.DeletedBy,
+		arg.Did,
+		arg.Rkey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const SoftDeleteTopic = `-- name: SoftDeleteTopic :execrows
+UPDATE quest_dis_topic
+SET deleted_at = $1, deleted_by = $2
+WHERE did = $3 AND rkey = $4 AND deleted_at IS NULL
+`
+
+type SoftDeleteTopicParams struct {
+	DeletedAt sql.NullTime   `json:"deleted_at"`
+	DeletedBy sql.NullString `json:"deleted_by"`
+	Did       string         `json:"did"`
+	Rkey      string         `json:"rkey"`
+}
+
+func (q *Queries) SoftDeleteTopic(ctx context.Context, arg SoftDeleteTopicParams) (int64, error) {
+	result, err := q.exec(ctx, q.softDeleteTopicStmt, SoftDeleteTopic,
+		arg.DeletedAt,
+		arg.DeletedBy,
+		arg.Did,
+		arg.Rkey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const TouchAccessToken = `-- name: TouchAccessToken :exec
+UPDATE quest_dis_access_token
+SET last_used_at = $1
+WHERE id = $2
+`
+
+type TouchAccessTokenParams struct {
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	ID         string       `json:"id"`
+}
+
+func (q *Queries) TouchAccessToken(ctx context.Context, arg TouchAccessTokenParams) error {
+	_, err := q.exec(ctx, q.touchAccessTokenStmt, TouchAccessToken, arg.LastUsedAt, arg.ID)
+	return err
+}
+
+const TouchSession = `-- name: TouchSession :exec
+UPDATE quest_dis_session
+SET last_seen_at = $1
+WHERE id = $2
+`
+
+type TouchSessionParams struct {
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ID         string    `json:"id"`
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.exec(ctx, q.touchSessionStmt, TouchSession, arg.LastSeenAt, arg.ID)
+	return err
+}
+
 const UpdateParticipationStatus = `-- name: UpdateParticipationStatus :exec
 UPDATE quest_dis_participation
 SET status = $1, updated_at = $2
@@ -541,6 +3281,23 @@ func (q *Queries) UpdateParticipationStatus(ctx context.Context, arg UpdateParti
 	return err
 }
 
+const UpdateTopicHotRank = `-- name: UpdateTopicHotRank :exec
+UPDATE quest_dis_topic
+SET hot_rank = $1
+WHERE did = $2 AND rkey = $3
+`
+
+type UpdateTopicHotRankParams struct {
+	HotRank float64 `json:"hot_rank"`
+	Did     string  `json:"did"`
+	Rkey    string  `json:"rkey"`
+}
+
+func (q *Queries) UpdateTopicHotRank(ctx context.Context, arg UpdateTopicHotRankParams) error {
+	_, err := q.exec(ctx, q.updateTopicHotRankStmt, UpdateTopicHotRank, arg.HotRank, arg.Did, arg.Rkey)
+	return err
+}
+
 const UpdateTopicSelectedAnswer = `-- name: UpdateTopicSelectedAnswer :exec
 UPDATE quest_dis_topic
 SET selected_answer = $1, updated_at = $2
@@ -563,3 +3320,155 @@ func (q *Queries) UpdateTopicSelectedAnswer(ctx context.Context, arg UpdateTopic
 	)
 	return err
 }
+
+const UpsertCommunityCategory = `-- name: UpsertCommunityCategory :one
+INSERT INTO quest_dis_community_category (community, slug, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (community, slug) DO UPDATE SET name = EXCLUDED.name
+RETURNING community, slug, name
+`
+
+type UpsertCommunityCategoryParams struct {
+	Community string `json:"community"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+}
+
+func (q *Queries) UpsertCommunityCategory(ctx context.Context, arg UpsertCommunityCategoryParams) (CommunityCategory, error) {
+	row := q.queryRow(ctx, q.upsertCommunityCategoryStmt, UpsertCommunityCategory, arg.Community, arg.Slug, arg.Name)
+	var i CommunityCategory
+	err := row.Scan(
+		&i.Community,
+		&i.Slug,
+		&i.Name,
+	)
+	return i, err
+}
+
+const UpsertMirrorMessage = `-- name: UpsertMirrorMessage :exec
+INSERT INTO quest_dis_mirror_message (
+    did, rkey, origin, topic_did, topic_rkey, parent_message_rkey, content, created_at, mirrored_at, verified
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (did, rkey) DO UPDATE SET
+    parent_message_rkey = EXCLUDED.parent_message_rkey,
+    content = EXCLUDED.content,
+    mirrored_at = EXCLUDED.mirrored_at,
+    verified = EXCLUDED.verified
+WHERE quest_dis_mirror_message.origin = EXCLUDED.origin
+`
+
+type UpsertMirrorMessageParams struct {
+	Did               string         `json:"did"`
+	Rkey              string         `json:"rkey"`
+	Origin            string         `json:"origin"`
+	TopicDid          string         `json:"topic_did"`
+	TopicRkey         string         `json:"topic_rkey"`
+	ParentMessageRkey string         `json:"parent_message_rkey"`
+	Content           MessageContent `json:"content"`
+	CreatedAt         time.Time      `json:"created_at"`
+	MirroredAt        time.Time      `json:"mirrored_at"`
+	Verified          bool           `json:"verified"`
+}
+
+func (q *Queries) UpsertMirrorMessage(ctx context.Context, arg UpsertMirrorMessageParams) error {
+	_, err := q.exec(ctx, q.upsertMirrorMessageStmt, UpsertMirrorMessage,
+		arg.Did,
+		arg.Rkey,
+		arg.Origin,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.ParentMessageRkey,
+		arg.Content,
+		arg.CreatedAt,
+		arg.MirroredAt,
+		arg.Verified,
+	)
+	return err
+}
+
+const UpsertMirrorTopic = `-- name: UpsertMirrorTopic :exec
+INSERT INTO quest_dis_mirror_topic (
+    did, rkey, origin, subject, initial_message, category, created_at, mirrored_at, verified
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (did, rkey) DO UPDATE SET
+    subject = EXCLUDED.subject,
+    initial_message = EXCLUDED.initial_message,
+    category = EXCLUDED.category,
+    mirrored_at = EXCLUDED.mirrored_at,
+    verified = EXCLUDED.verified
+WHERE quest_dis_mirror_topic.origin = EXCLUDED.origin
+`
+
+type UpsertMirrorTopicParams struct {
+	Did            string    `json:"did"`
+	Rkey           string    `json:"rkey"`
+	Origin         string    `json:"origin"`
+	Subject        string    `json:"subject"`
+	InitialMessage string    `json:"initial_message"`
+	Category       string    `json:"category"`
+	CreatedAt      time.Time `json:"created_at"`
+	MirroredAt     time.Time `json:"mirrored_at"`
+	Verified       bool      `json:"verified"`
+}
+
+// A record already mirrored from another origin is left alone
+func (q *Queries) UpsertMirrorTopic(ctx context.Context, arg UpsertMirrorTopicParams) error {
+	_, err := q.exec(ctx, q.upsertMirrorTopicStmt, UpsertMirrorTopic,
+		arg.Did,
+		arg.Rkey,
+		arg.Origin,
+		arg.Subject,
+		arg.InitialMessage,
+		arg.Category,
+		arg.CreatedAt,
+		arg.MirroredAt,
+		arg.Verified,
+	)
+	return err
+}
+
+const UpsertReadMarker = `-- name: UpsertReadMarker :one
+INSERT INTO quest_dis_read_marker (
+    did, topic_did, topic_rkey, last_read_at, created_at, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (did, topic_did, topic_rkey)
+DO UPDATE SET last_read_at = EXCLUDED.last_read_at, updated_at = EXCLUDED.updated_at
+RETURNING did, topic_did, topic_rkey, last_read_at, created_at, updated_at
+`
+
+type UpsertReadMarkerParams struct {
+	Did        string    `json:"did"`
+	TopicDid   string    `json:"topic_did"`
+	TopicRkey  string    `json:"topic_rkey"`
+	LastReadAt time.Time `json:"last_read_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Read marker queries
+func (q *Queries) UpsertReadMarker(ctx context.Context, arg UpsertReadMarkerParams) (ReadMarker, error) {
+	row := q.queryRow(ctx, q.upsertReadMarkerStmt, UpsertReadMarker,
+		arg.Did,
+		arg.TopicDid,
+		arg.TopicRkey,
+		arg.LastReadAt,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i ReadMarker
+	err := row.Scan(
+		&i.Did,
+		&i.TopicDid,
+		&i.TopicRkey,
+		&i.LastReadAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,113 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+const (
+	benchTopics           = 500
+	benchMessagesPerTopic = 20
+)
+
+// seedBenchData fills a test database with topics and messages so the
+// index queries have realistic amounts of data to sort through
+func seedBenchData(b *testing.B) (*db.Service, db.Topic) {
+	b.Helper()
+	dbService := testutil.TestDatabase(b)
+	ctx := context.Background()
+	start := time.Now().Add(-benchTopics * time.Minute)
+
+	var topic db.Topic
+	for i := 0; i < benchTopics; i++ {
+		createdAt := start.Add(time.Duration(i) * time.Minute)
+		var err error
+		topic, err = dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            fmt.Sprintf("did:plc:author%d", i%25),
+			Rkey:           fmt.Sprintf("topic-%d", i),
+			Subject:        fmt.Sprintf("Topic %d", i),
+			InitialMessage: "Benchmark topic body",
+			CreatedAt:      createdAt,
+			UpdatedAt:      createdAt,
+		})
+		if err != nil {
+			b.Fatalf("Failed to create topic: %v", err)
+		}
+		for j := 0; j < benchMessagesPerTopic; j++ {
+			if _, err := dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
+				Did:       fmt.Sprintf("did:plc:user%d", j%10),
+				Rkey:      fmt.Sprintf("msg-%d-%d", i, j),
+				TopicDid:  topic.Did,
+				TopicRkey: topic.Rkey,
+				Content:   "Benchmark reply",
+				CreatedAt: createdAt.Add(time.Duration(j) * time.Second),
+				UpdatedAt: createdAt.Add(time.Duration(j) * time.Second),
+			}); err != nil {
+				b.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+	return dbService, topic
+}
+
+func BenchmarkQueries(b *testing.B) {
+	dbService, topic := seedBenchData(b)
+	queries := dbService.Queries()
+	ctx := context.Background()
+
+	benchmarks := []struct {
+		name string
+		run  func() error
+	}{
+		{"ListTopics", func() error {
+			_, err := queries.ListTopics(ctx, db.ListTopicsParams{Limit: 20})
+			return err
+		}},
+		{"ListTopicsByHotRank", func() error {
+			_, err := queries.ListTopicsByHotRank(ctx, db.ListTopicsByHotRankParams{Limit: 20})
+			return err
+		}},
+		{"ListTopicsByActivity", func() error {
+			_, err := queries.ListTopicsByActivity(ctx, db.ListTopicsByActivityParams{Limit: 20})
+			return err
+		}},
+		{"GetMessagesByTopic", func() error {
+			_, err := queries.GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{TopicDid: topic.Did, TopicRkey: topic.Rkey})
+			return err
+		}},
+		{"GetUnreadCounts", func() error {
+			_, err := queries.GetUnreadCounts(ctx, "did:plc:user3")
+			return err
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := bm.run(); err != nil {
+					b.Fatalf("%s: %v", bm.name, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRecordTopicActivity(b *testing.B) {
+	dbService, topic := seedBenchData(b)
+	ctx := context.Background()
+	now := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dbService.RecordTopicActivity(ctx, topic.Did, topic.Rkey, topic.Did, now.Add(time.Duration(i)*time.Millisecond)); err != nil {
+			b.Fatalf("RecordTopicActivity: %v", err)
+		}
+	}
+}
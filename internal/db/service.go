@@ -3,11 +3,21 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/jrschumacher/dis.quest/internal/config"
 	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/ranking"
+)
+
+// Retrying SQLite write transactions that time out waiting for the lock,
+// which another process sharing the file (such as the indexer) may hold
+const (
+	maxBusyAttempts = 4
+	busyRetryDelay  = 50 * time.Millisecond
 )
 
 // Service wraps the database connection and provides methods for database operations
@@ -15,6 +25,10 @@ type Service struct {
 	db      *sql.DB
 	queries *Queries
 	driver  DatabaseDriver
+	timed   *timedDB
+	// writer queues write transactions on SQLite, which allows one writer
+	// at a time; nil for PostgreSQL
+	writer chan struct{}
 }
 
 // NewService creates a new database service instance
@@ -24,22 +38,30 @@ func NewService(cfg *config.Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	queries := New(db)
+	timed := newTimedDB(db, time.Duration(cfg.SlowQueryMS)*time.Millisecond)
+	queries := New(timed)
+	SetCompressionThreshold(cfg.MessageCompressionBytes)
 	
 	logger.Info("Database service initialized", 
 		"driver", string(driver),
 		"url", cfg.DatabaseURL)
 
-	return &Service{
+	service := &Service{
 		db:      db,
 		queries: queries,
 		driver:  driver,
-	}, nil
+		timed:   timed,
+	}
+	if driver == SQLite {
+		service.writer = make(chan struct{}, 1)
+	}
+	return service, nil
 }
 
 // Close closes the database connection
 func (s *Service) Close() error {
 	if s.db != nil {
+		s.timed.close()
 		return s.db.Close()
 	}
 	return nil
@@ -50,6 +72,14 @@ func (s *Service) Queries() *Queries {
 	return s.queries
 }
 
+// QueryStats returns latency stats for each query run since startup,
+// slowest in total first
+func (s *Service) QueryStats() []QueryStat {
+	stats := s.timed.snapshot()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].TotalMS > stats[j].TotalMS })
+	return stats
+}
+
 // DB returns the underlying database connection
 func (s *Service) DB() *sql.DB {
 	return s.db
@@ -70,8 +100,40 @@ func (s *Service) IsSQLite() bool {
 	return s.driver == SQLite
 }
 
-// WithTx executes a function within a database transaction
+// WithTx executes a function within a database transaction. On SQLite the
+// transaction waits its turn for the single writer and is retried if the
+// database stays busy, so fn may run more than once and should only touch
+// the database through the Queries it is given.
 func (s *Service) WithTx(ctx context.Context, fn func(*Queries) error) error {
+	if s.writer == nil {
+		return s.withTx(ctx, fn)
+	}
+
+	select {
+	case s.writer <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.writer }()
+
+	delay := busyRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := s.withTx(ctx, fn)
+		if !isBusy(err) || attempt == maxBusyAttempts {
+			return err
+		}
+		logger.Warn("Database busy, retrying transaction", "attempt", attempt, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// withTx runs fn in a single transaction, rolling back if it fails
+func (s *Service) withTx(ctx context.Context, fn func(*Queries) error) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -83,7 +145,7 @@ func (s *Service) WithTx(ctx context.Context, fn func(*Queries) error) error {
 		}
 	}()
 
-	queries := s.queries.WithTx(tx)
+	queries := New(timedTx{timed: s.timed, tx: tx})
 	if err := fn(queries); err != nil {
 		_ = tx.Rollback()
 		return err
@@ -108,6 +170,7 @@ func (s *Service) CreateTopicWithParticipation(ctx context.Context, params Creat
 			CreatedAt:      params.CreatedAt,
 			UpdatedAt:      params.UpdatedAt,
 			SelectedAnswer: sql.NullString{}, // No selected answer initially
+			Community:      params.Community,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create topic: %w", err)
@@ -126,6 +189,16 @@ func (s *Service) CreateTopicWithParticipation(ctx context.Context, params Creat
 			return fmt.Errorf("failed to create participation: %w", err)
 		}
 		
+		// Seed the hot rank so new topics surface before their first reply
+		topic.HotRank = ranking.Hot(0, topic.CreatedAt)
+		if err := q.UpdateTopicHotRank(ctx, UpdateTopicHotRankParams{
+			HotRank: topic.HotRank,
+			Did:     topic.Did,
+			Rkey:    topic.Rkey,
+		}); err != nil {
+			return fmt.Errorf("failed to rank topic: %w", err)
+		}
+		
 		result.Topic = topic
 		result.Participation = participation
 		return nil
@@ -147,10 +220,338 @@ type CreateTopicWithParticipationParams struct {
 	Category       sql.NullString
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	// Community is the slug of the community the topic belongs to, or empty
+	// for the default space
+	Community string
 }
 
 // TopicWithParticipation represents a topic along with the creator's participation
 type TopicWithParticipation struct {
 	Topic         Topic
 	Participation Participation
-}
\ No newline at end of file
+}
+// SetParticipationStatus creates or updates a user's participation in a topic
+func (s *Service) SetParticipationStatus(ctx context.Context, params SetParticipationStatusParams) (*Participation, error) {
+	var result Participation
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		existing, err := q.GetParticipation(ctx, GetParticipationParams{
+			Did:       params.Did,
+			TopicDid:  params.TopicDid,
+			TopicRkey: params.TopicRkey,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			result, err = q.CreateParticipation(ctx, CreateParticipationParams{
+				Did:       params.Did,
+				TopicDid:  params.TopicDid,
+				TopicRkey: params.TopicRkey,
+				Status:    params.Status,
+				CreatedAt: params.UpdatedAt,
+				UpdatedAt: params.UpdatedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create participation: %w", err)
+			}
+			return nil
+		case err != nil:
+			return fmt.Errorf("failed to get participation: %w", err)
+		}
+
+		if err := q.UpdateParticipationStatus(ctx, UpdateParticipationStatusParams{
+			Status:    params.Status,
+			UpdatedAt: params.UpdatedAt,
+			Did:       params.Did,
+			TopicDid:  params.TopicDid,
+			TopicRkey: params.TopicRkey,
+		}); err != nil {
+			return fmt.Errorf("failed to update participation: %w", err)
+		}
+		existing.Status = params.Status
+		existing.UpdatedAt = params.UpdatedAt
+		result = existing
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SetParticipationStatusParams represents the parameters for setting a participation status
+type SetParticipationStatusParams struct {
+	Did       string
+	TopicDid  string
+	TopicRkey string
+	Status    string
+	UpdatedAt time.Time
+}
+
+// RecordTopicActivity counts a new message by authorDID in a topic and
+// refreshes its participant count, activity time and hot rank. Call it after
+// the message has been inserted.
+func (s *Service) RecordTopicActivity(ctx context.Context, topicDid, topicRkey, authorDID string, at time.Time) (*Topic, error) {
+	var result Topic
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		topic, err := recordTopicActivity(ctx, q, topicDid, topicRkey, authorDID, at)
+		if err != nil {
+			return err
+		}
+		result = topic
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func recordTopicActivity(ctx context.Context, q *Queries, topicDid, topicRkey, authorDID string, at time.Time) (Topic, error) {
+	topic, err := q.IncrementTopicActivity(ctx, IncrementTopicActivityParams{
+		LastActivityAt: sql.NullTime{Time: at, Valid: true},
+		AuthorDid:      authorDID,
+		Did:            topicDid,
+		Rkey:           topicRkey,
+	})
+	if err != nil {
+		return Topic{}, fmt.Errorf("failed to record topic activity: %w", err)
+	}
+	if err := updateHotRank(ctx, q, &topic, at); err != nil {
+		return Topic{}, err
+	}
+	return topic, nil
+}
+
+// updateHotRank recomputes a topic's hot rank from its message count
+func updateHotRank(ctx context.Context, q *Queries, topic *Topic, lastActivity time.Time) error {
+	topic.HotRank = ranking.Hot(int(topic.MessageCount), lastActivity)
+	if err := q.UpdateTopicHotRank(ctx, UpdateTopicHotRankParams{
+		HotRank: topic.HotRank,
+		Did:     topic.Did,
+		Rkey:    topic.Rkey,
+	}); err != nil {
+		return fmt.Errorf("failed to update hot rank: %w", err)
+	}
+	return nil
+}
+
+// MessageWithTopic is a newly created message and its topic with the
+// message counted
+type MessageWithTopic struct {
+	Message Message
+	Topic   Topic
+}
+
+// CreateMessageWithActivity inserts a message and records it as activity in
+// its topic in one transaction, so counts never include a message that
+// failed to save or miss one that did
+func (s *Service) CreateMessageWithActivity(ctx context.Context, params CreateMessageParams) (*MessageWithTopic, error) {
+	var result MessageWithTopic
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		message, err := q.CreateMessage(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to create message: %w", err)
+		}
+		topic, err := recordTopicActivity(ctx, q, params.TopicDid, params.TopicRkey, params.Did, params.CreatedAt)
+		if err != nil {
+			return err
+		}
+		result = MessageWithTopic{Message: message, Topic: topic}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SoftDeleteMessage tombstones a message in the topic identified by topicDid
+// and topicRkey and uncounts it from the topic's message count, in one
+// transaction. It returns the number of messages deleted, 0 if the message
+// does not exist or is already deleted.
+func (s *Service) SoftDeleteMessage(ctx context.Context, params SoftDeleteMessageParams, topicDid, topicRkey string) (int64, error) {
+	var affected int64
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		var err error
+		affected, err = q.SoftDeleteMessage(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to delete message: %w", err)
+		}
+		if affected == 0 {
+			return nil
+		}
+		return adjustMessageCount(ctx, q, topicDid, topicRkey, -1)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// RestoreMessage undoes SoftDeleteMessage, counting the message in its topic
+// again. It returns the number of messages restored, 0 if the message is not
+// deleted.
+func (s *Service) RestoreMessage(ctx context.Context, params RestoreMessageParams, topicDid, topicRkey string) (int64, error) {
+	var affected int64
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		var err error
+		affected, err = q.RestoreMessage(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to restore message: %w", err)
+		}
+		if affected == 0 {
+			return nil
+		}
+		return adjustMessageCount(ctx, q, topicDid, topicRkey, 1)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// adjustMessageCount changes a topic's message count by delta and re-ranks
+// it without touching its activity time
+func adjustMessageCount(ctx context.Context, q *Queries, topicDid, topicRkey string, delta int32) error {
+	topic, err := q.AdjustTopicMessageCount(ctx, AdjustTopicMessageCountParams{
+		Delta: delta,
+		Did:   topicDid,
+		Rkey:  topicRkey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update message count: %w", err)
+	}
+	lastActivity := topic.CreatedAt
+	if topic.LastActivityAt.Valid {
+		lastActivity = topic.LastActivityAt.Time
+	}
+	return updateHotRank(ctx, q, &topic, lastActivity)
+}
+
+// PurgeTombstones permanently removes topics and messages that were soft-deleted
+// before cutoff and returns the number of rows removed
+func (s *Service) PurgeTombstones(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	before := sql.NullTime{Time: cutoff, Valid: true}
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		messages, err := q.PurgeDeletedMessages(ctx, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge messages: %w", err)
+		}
+		topics, err := q.PurgeDeletedTopics(ctx, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge topics: %w", err)
+		}
+		purged = messages + topics
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// AccountPurge counts the rows PurgeAccount removed
+type AccountPurge struct {
+	Topics         int64 `json:"topics"`
+	Messages       int64 `json:"messages"`
+	Participations int64 `json:"participations"`
+	ReadMarkers    int64 `json:"read_markers"`
+	Sessions       int64 `json:"sessions"`
+	FirehoseEvents int64 `json:"firehose_events"`
+	AccessTokens   int64 `json:"access_tokens"`
+}
+
+// PurgeAccount removes everything stored locally for an account in one
+// transaction: its topics (with every reply, marker and stat attached to
+// them), its messages elsewhere, its participation and read state, its
+// firehose history, its sessions and its access tokens
+func (s *Service) PurgeAccount(ctx context.Context, did string) (*AccountPurge, error) {
+	var result AccountPurge
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		steps := []struct {
+			name  string
+			count *int64
+			run   func(context.Context, string) (int64, error)
+		}{
+			{"messages", &result.Messages, q.DeleteMessagesByAccount},
+			{"participations", &result.Participations, q.DeleteParticipationsByAccount},
+			{"participation retries", nil, q.DeleteParticipationRetriesByAccount},
+			{"read markers", &result.ReadMarkers, q.DeleteReadMarkersByAccount},
+			{"topic stats", nil, q.DeleteTopicStatsByAccount},
+			{"topics", &result.Topics, q.DeleteTopicsByAccount},
+			{"firehose events", &result.FirehoseEvents, q.DeleteFirehoseEventsByAccount},
+			{"sessions", &result.Sessions, q.DeleteSessionsByDid},
+			{"access tokens", &result.AccessTokens, q.DeleteAccessTokensByDid},
+		}
+		for _, step := range steps {
+			n, err := step.run(ctx, did)
+			if err != nil {
+				return fmt.Errorf("failed to purge %s: %w", step.name, err)
+			}
+			if step.count != nil {
+				*step.count = n
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteMirrorTopic removes a topic mirrored from origin together with the
+// messages mirrored into it
+func (s *Service) DeleteMirrorTopic(ctx context.Context, origin, did, rkey string) error {
+	return s.WithTx(ctx, func(q *Queries) error {
+		if err := q.DeleteMirrorTopicMessages(ctx, DeleteMirrorTopicMessagesParams{Origin: origin, TopicDid: did, TopicRkey: rkey}); err != nil {
+			return fmt.Errorf("failed to delete mirrored messages: %w", err)
+		}
+		if err := q.DeleteMirrorTopic(ctx, DeleteMirrorTopicParams{Origin: origin, Did: did, Rkey: rkey}); err != nil {
+			return fmt.Errorf("failed to delete mirrored topic: %w", err)
+		}
+		return nil
+	})
+}
+
+// CreateCommunityWithModerator creates a community and makes its creator the
+// first moderator
+func (s *Service) CreateCommunityWithModerator(ctx context.Context, params CreateCommunityParams) (*Community, error) {
+	var result Community
+
+	err := s.WithTx(ctx, func(q *Queries) error {
+		community, err := q.CreateCommunity(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to create community: %w", err)
+		}
+		if err := q.AddCommunityModerator(ctx, AddCommunityModeratorParams{
+			Community: community.Slug,
+			Did:       params.CreatedBy,
+			CreatedAt: params.CreatedAt,
+		}); err != nil {
+			return fmt.Errorf("failed to add moderator: %w", err)
+		}
+		result = community
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// latencyBuckets are the upper bounds of the query latency histogram; a
+// final bucket counts everything slower
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStat summarizes the latency of one named query since startup. Times
+// cover executing the statement, not reading the rows it returns.
+type QueryStat struct {
+	Name    string  `json:"name"`
+	Count   int64   `json:"count"`
+	Errors  int64   `json:"errors"`
+	TotalMS float64 `json:"total_ms"`
+	MaxMS   float64 `json:"max_ms"`
+	// Buckets counts calls by latency, keyed by upper bound ("+Inf" for the
+	// rest)
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket counts calls that finished within LE
+type LatencyBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+type queryTiming struct {
+	count   int64
+	errors  int64
+	total   time.Duration
+	max     time.Duration
+	buckets []int64
+}
+
+// timedDB runs sqlc queries through statements prepared once per query and
+// reused, timing each call and logging those slower than slow
+type timedDB struct {
+	db   *sql.DB
+	slow time.Duration
+
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt
+
+	statsMu sync.Mutex
+	stats   map[string]*queryTiming
+}
+
+func newTimedDB(db *sql.DB, slow time.Duration) *timedDB {
+	return &timedDB{
+		db:    db,
+		slow:  slow,
+		stmts: make(map[string]*sql.Stmt),
+		stats: make(map[string]*queryTiming),
+	}
+}
+
+// stmt returns the prepared statement for query, preparing it on first use.
+// Preparing lazily lets the service open before the schema exists.
+func (t *timedDB) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	t.stmtMu.Lock()
+	defer t.stmtMu.Unlock()
+
+	if stmt, ok := t.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := t.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	t.stmts[query] = stmt
+	return stmt, nil
+}
+
+// close closes every prepared statement
+func (t *timedDB) close() {
+	t.stmtMu.Lock()
+	defer t.stmtMu.Unlock()
+
+	for query, stmt := range t.stmts {
+		_ = stmt.Close()
+		delete(t.stmts, query)
+	}
+}
+
+// observe records one call of query that took d
+func (t *timedDB) observe(query string, d time.Duration, err error) {
+	name := queryName(query)
+	if t.slow > 0 && d >= t.slow {
+		logger.Warn("Slow query", "query", name, "duration", d)
+	}
+
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	timing, ok := t.stats[name]
+	if !ok {
+		timing = &queryTiming{buckets: make([]int64, len(latencyBuckets)+1)}
+		t.stats[name] = timing
+	}
+	timing.count++
+	if err != nil && err != sql.ErrNoRows {
+		timing.errors++
+	}
+	timing.total += d
+	timing.max = max(timing.max, d)
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if d <= bound {
+			bucket = i
+			break
+		}
+	}
+	timing.buckets[bucket]++
+}
+
+// snapshot returns the stats for every query run so far, by name
+func (t *timedDB) snapshot() []QueryStat {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	stats := make([]QueryStat, 0, len(t.stats))
+	for name, timing := range t.stats {
+		stat := QueryStat{
+			Name:    name,
+			Count:   timing.count,
+			Errors:  timing.errors,
+			TotalMS: milliseconds(timing.total),
+			MaxMS:   milliseconds(timing.max),
+			Buckets: make([]LatencyBucket, len(timing.buckets)),
+		}
+		for i, count := range timing.buckets {
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = latencyBuckets[i].String()
+			}
+			stat.Buckets[i] = LatencyBucket{LE: le, Count: count}
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// queryName returns the sqlc name of query, taken from its "-- name: X"
+// header, or its first line for queries written by hand
+func queryName(query string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(query), "\n")
+	if rest, ok := strings.CutPrefix(line, "-- name: "); ok {
+		name, _, _ := strings.Cut(rest, " ")
+		return name
+	}
+	return line
+}
+
+// ExecContext implements DBTX
+func (t *timedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.exec(ctx, nil, query, args...)
+}
+
+// QueryContext implements DBTX
+func (t *timedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.query(ctx, nil, query, args...)
+}
+
+// QueryRowContext implements DBTX
+func (t *timedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.queryRow(ctx, nil, query, args...)
+}
+
+// PrepareContext implements DBTX
+func (t *timedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.db.PrepareContext(ctx, query)
+}
+
+// prepared returns the statement for query, bound to tx if set. Within a
+// transaction a query not prepared yet is prepared on the transaction's own
+// connection, as the pool may have no other connection to spare.
+func (t *timedDB) prepared(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	if tx == nil {
+		return t.stmt(ctx, query)
+	}
+	t.stmtMu.Lock()
+	stmt, ok := t.stmts[query]
+	t.stmtMu.Unlock()
+	if ok {
+		return tx.StmtContext(ctx, stmt), nil
+	}
+	return tx.PrepareContext(ctx, query)
+}
+
+// exec runs query through its prepared statement
+func (t *timedDB) exec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	stmt, err := t.prepared(ctx, tx, query)
+	if err != nil {
+		t.observe(query, time.Since(start), err)
+		return nil, err
+	}
+	result, err := stmt.ExecContext(ctx, args...)
+	t.observe(query, time.Since(start), err)
+	return result, err
+}
+
+// query runs query through its prepared statement
+func (t *timedDB) query(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	stmt, err := t.prepared(ctx, tx, query)
+	if err != nil {
+		t.observe(query, time.Since(start), err)
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	t.observe(query, time.Since(start), err)
+	return rows, err
+}
+
+// queryRow runs query through its prepared statement. A failure to prepare
+// is left for the row's Scan to return, as for an unprepared query.
+func (t *timedDB) queryRow(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	stmt, err := t.prepared(ctx, tx, query)
+	if err != nil {
+		t.observe(query, time.Since(start), err)
+		if tx != nil {
+			return tx.QueryRowContext(ctx, query, args...)
+		}
+		return t.db.QueryRowContext(ctx, query, args...)
+	}
+	row := stmt.QueryRowContext(ctx, args...)
+	t.observe(query, time.Since(start), row.Err())
+	return row
+}
+
+// timedTx is timedDB bound to a transaction
+type timedTx struct {
+	timed *timedDB
+	tx    *sql.Tx
+}
+
+// ExecContext implements DBTX
+func (t timedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.timed.exec(ctx, t.tx, query, args...)
+}
+
+// QueryContext implements DBTX
+func (t timedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.timed.query(ctx, t.tx, query, args...)
+}
+
+// QueryRowContext implements DBTX
+func (t timedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.timed.queryRow(ctx, t.tx, query, args...)
+}
+
+// PrepareContext implements DBTX
+func (t timedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
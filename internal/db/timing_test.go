@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{GetTopic, "GetTopic"},
+		{"-- name: ListTopics :many\nSELECT 1", "ListTopics"},
+		{"SELECT 1", "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		if got := queryName(tt.query); got != tt.want {
+			t.Errorf("queryName(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTimedDBObserve(t *testing.T) {
+	timed := newTimedDB(nil, 0)
+	timed.observe(GetTopic, 2*time.Millisecond, nil)
+	timed.observe(GetTopic, 3*time.Millisecond, sql.ErrNoRows)
+	timed.observe(GetTopic, 10*time.Second, errors.New("timeout"))
+
+	stats := timed.snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("Expected stats for one query, got %+v", stats)
+	}
+	stat := stats[0]
+	if stat.Name != "GetTopic" || stat.Count != 3 || stat.Errors != 1 || stat.MaxMS != 10000 {
+		t.Errorf("unexpected stat %+v", stat)
+	}
+
+	counts := map[string]int64{}
+	for _, bucket := range stat.Buckets {
+		counts[bucket.LE] = bucket.Count
+	}
+	if counts["5ms"] != 2 || counts["+Inf"] != 1 || counts["1ms"] != 0 {
+		t.Errorf("unexpected buckets %+v", stat.Buckets)
+	}
+}
@@ -0,0 +1,143 @@
+// Package domains verifies custom domains for communities and resolves
+// requests that arrive on them
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// TXTRecordPrefix is prepended to a domain to name its verification TXT record
+	TXTRecordPrefix = "_disquest."
+	// TXTValuePrefix precedes the token in the verification TXT record
+	TXTValuePrefix = "disquest-verification="
+	// WellKnownPath serves the bare token when verifying over HTTPS
+	WellKnownPath = "/.well-known/disquest-verification"
+
+	// MethodDNS and MethodWellKnown name how a domain was verified
+	MethodDNS       = "dns"
+	MethodWellKnown = "well-known"
+
+	verifyTimeout  = 10 * time.Second
+	maxTokenLength = 256
+)
+
+var (
+	// ErrInvalidDomain is returned for values that are not a public host name
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrNotVerified is returned when neither the TXT record nor the
+	// well-known file holds the expected token
+	ErrNotVerified = errors.New("verification token not found")
+)
+
+// NewToken returns a random verification token
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Normalize lowercases a domain and checks that it is a multi-label host
+// name without scheme, port or path
+func Normalize(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) == 0 || len(domain) > 253 || !strings.Contains(domain, ".") || net.ParseIP(domain) != nil {
+		return "", ErrInvalidDomain
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", ErrInvalidDomain
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return "", ErrInvalidDomain
+			}
+		}
+	}
+	return domain, nil
+}
+
+// Verifier checks that whoever controls a domain has published its token
+type Verifier struct {
+	// LookupTXT resolves TXT records; defaults to net.DefaultResolver
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+	// HTTPClient fetches the well-known file; redirects are not followed
+	HTTPClient *http.Client
+	// Scheme is used for the well-known check; only tests should change it
+	Scheme string
+}
+
+// NewVerifier creates a verifier using the system resolver and HTTPS
+func NewVerifier() *Verifier {
+	return &Verifier{
+		LookupTXT: net.DefaultResolver.LookupTXT,
+		HTTPClient: &http.Client{
+			Timeout: verifyTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Scheme: "https",
+	}
+}
+
+// Verify looks for token in the domain's TXT record, then in its
+// well-known file, and reports which method succeeded
+func (v *Verifier) Verify(ctx context.Context, domain, token string) (string, error) {
+	dnsErr := v.verifyDNS(ctx, domain, token)
+	if dnsErr == nil {
+		return MethodDNS, nil
+	}
+	wellKnownErr := v.verifyWellKnown(ctx, domain, token)
+	if wellKnownErr == nil {
+		return MethodWellKnown, nil
+	}
+	return "", fmt.Errorf("%w: dns: %v; well-known: %v", ErrNotVerified, dnsErr, wellKnownErr)
+}
+
+func (v *Verifier) verifyDNS(ctx context.Context, domain, token string) error {
+	records, err := v.LookupTXT(ctx, TXTRecordPrefix+domain)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == TXTValuePrefix+token {
+			return nil
+		}
+	}
+	return errors.New("no matching TXT record")
+}
+
+func (v *Verifier) verifyWellKnown(ctx context.Context, domain, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Scheme+"://"+domain+WellKnownPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenLength))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return errors.New("token does not match")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "Forum.Example.com.", want: "forum.example.com", ok: true},
+		{in: " example.org ", want: "example.org", ok: true},
+		{in: "localhost"},
+		{in: "127.0.0.1"},
+		{in: "example.com:8080"},
+		{in: "https://example.com"},
+		{in: "-bad.example.com"},
+		{in: "a..b"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidDomain) {
+			t.Errorf("Normalize(%q) = %q, %v; want ErrInvalidDomain", tt.in, got, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const token = "abc123"
+	wellKnown := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != WellKnownPath || wellKnown == "" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(wellKnown + "\n"))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	txt := map[string][]string{}
+	v := &Verifier{
+		LookupTXT: func(_ context.Context, name string) ([]string, error) {
+			if records, ok := txt[name]; ok {
+				return records, nil
+			}
+			return nil, errors.New("no such host")
+		},
+		HTTPClient: srv.Client(),
+		Scheme:     "http",
+	}
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, host, token); !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("expected ErrNotVerified, got %v", err)
+	}
+
+	wellKnown = "wrong"
+	if _, err := v.Verify(ctx, host, token); !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("expected a mismatched token to fail, got %v", err)
+	}
+
+	wellKnown = token
+	if method, err := v.Verify(ctx, host, token); err != nil || method != MethodWellKnown {
+		t.Fatalf("Verify = %q, %v; want well-known", method, err)
+	}
+
+	txt[TXTRecordPrefix+host] = []string{"v=spf1 -all", TXTValuePrefix + token}
+	if method, err := v.Verify(ctx, host, token); err != nil || method != MethodDNS {
+		t.Fatalf("Verify = %q, %v; want dns", method, err)
+	}
+}
+
+func TestResolverCachesAndInvalidates(t *testing.T) {
+	lookups := 0
+	communities := map[string]string{"forum.example.com": "golang"}
+	r := NewResolver(func(_ context.Context, host string) (string, error) {
+		lookups++
+		return communities[host], nil
+	}, time.Minute)
+	ctx := context.Background()
+
+	if d, ok := r.Resolve(ctx, "forum.example.com"); !ok || d.Community != "golang" || d.Origin() != "https://forum.example.com" {
+		t.Fatalf("Resolve = %+v, %v", d, ok)
+	}
+	if _, ok := r.Resolve(ctx, "other.example.com"); ok {
+		t.Fatal("expected unknown host to miss")
+	}
+	r.Resolve(ctx, "forum.example.com")
+	r.Resolve(ctx, "other.example.com")
+	if lookups != 2 {
+		t.Errorf("expected cached results, got %d lookups", lookups)
+	}
+
+	communities["other.example.com"] = "rust"
+	r.Invalidate("other.example.com")
+	if d, ok := r.Resolve(ctx, "other.example.com"); !ok || d.Community != "rust" {
+		t.Errorf("expected invalidated host to be looked up again, got %+v, %v", d, ok)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := NewResolver(func(_ context.Context, host string) (string, error) {
+		if host == "forum.example.com" {
+			return "golang", nil
+		}
+		return "", nil
+	}, time.Minute)
+
+	var got Domain
+	var found bool
+	handler := Middleware(r, "https://dis.quest")(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		got, found = FromContext(req.Context())
+	}))
+
+	for _, tt := range []struct {
+		host string
+		want bool
+	}{
+		{host: "forum.example.com:443", want: true},
+		{host: "dis.quest", want: false},
+		{host: "localhost:3000", want: false},
+		{host: "unknown.example.com", want: false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = tt.host
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if found != tt.want {
+			t.Errorf("host %s: found = %v, want %v", tt.host, found, tt.want)
+		}
+		if found && got.Community != "golang" {
+			t.Errorf("host %s: community = %q", tt.host, got.Community)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package domains
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Domain is a verified custom domain and the community it serves
+type Domain struct {
+	Host      string
+	Community string
+}
+
+// Origin is the HTTPS origin of the domain
+func (d Domain) Origin() string {
+	return "https://" + d.Host
+}
+
+// LookupFunc returns the community that has verified host, or an empty
+// string when none has
+type LookupFunc func(ctx context.Context, host string) (string, error)
+
+type cacheEntry struct {
+	community string
+	expires   time.Time
+}
+
+// Resolver maps request hosts to verified community domains, caching
+// lookups (including misses) for a fixed TTL
+type Resolver struct {
+	lookup LookupFunc
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewResolver creates a resolver that caches lookup results for ttl
+func NewResolver(lookup LookupFunc, ttl time.Duration) *Resolver {
+	return &Resolver{lookup: lookup, ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// Resolve returns the verified domain for host. Lookup failures are treated
+// as misses and not cached, so a database hiccup doesn't stick.
+func (r *Resolver) Resolve(ctx context.Context, host string) (Domain, bool) {
+	now := time.Now()
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	r.mu.Unlock()
+	if !ok || now.After(entry.expires) {
+		community, err := r.lookup(ctx, host)
+		if err != nil {
+			return Domain{}, false
+		}
+		entry = cacheEntry{community: community, expires: now.Add(r.ttl)}
+		r.mu.Lock()
+		r.entries[host] = entry
+		r.mu.Unlock()
+	}
+	if entry.community == "" {
+		return Domain{}, false
+	}
+	return Domain{Host: host, Community: entry.community}, true
+}
+
+// Invalidate drops any cached result for host, e.g. after it is verified
+// or removed
+func (r *Resolver) Invalidate(host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, host)
+}
+
+type contextKey struct{}
+
+// WithDomain returns a context carrying the verified domain of the request
+func WithDomain(ctx context.Context, d Domain) context.Context {
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// FromContext returns the verified domain the request arrived on, if any
+func FromContext(ctx context.Context) (Domain, bool) {
+	d, ok := ctx.Value(contextKey{}).(Domain)
+	return d, ok
+}
+
+// Middleware attaches the verified Domain to requests whose Host is a
+// community's custom domain. Requests for primaryOrigin's host, and
+// localhost, skip the lookup.
+func Middleware(resolver *Resolver, primaryOrigin string) func(http.Handler) http.Handler {
+	primary := hostOf(primaryOrigin)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := hostOf(r.Host)
+			if host != "" && host != primary && host != "localhost" && net.ParseIP(host) == nil {
+				if d, ok := resolver.Resolve(r.Context(), host); ok {
+					r = r.WithContext(WithDomain(r.Context(), d))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hostOf strips any scheme, port and path, leaving a lowercase host name
+func hostOf(s string) string {
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	if h, _, err := net.SplitHostPort(s); err == nil {
+		s = h
+	}
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
@@ -0,0 +1,185 @@
+// Package drift checks the local index against the records it was built
+// from: each sampled row is fetched from its author's PDS and compared field
+// by field, so rows whose record was deleted or edited elsewhere show up.
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// ErrRecordNotFound is returned by Records when the PDS has no such record
+var ErrRecordNotFound = errors.New("record not found")
+
+// Kinds of drift
+const (
+	// Missing rows have no record on the PDS
+	Missing = "missing"
+	// Invalid records no longer match their lexicon
+	Invalid = "invalid"
+	// Mismatched records differ from the row in a field the index copies
+	Mismatched = "mismatched"
+)
+
+// Records fetches the value of a record from its author's PDS
+type Records interface {
+	GetRecord(ctx context.Context, did, collection, rkey string) (map[string]any, error)
+}
+
+// Row is a local index row and the record fields it was built from
+type Row struct {
+	URI    pds.ATURI
+	Fields map[string]string
+}
+
+// TopicRow returns the row for an indexed topic
+func TopicRow(topic db.Topic) Row {
+	return Row{
+		URI: pds.ATURI{Repo: topic.Did, Collection: lexicon.TopicNSID, Rkey: topic.Rkey},
+		Fields: map[string]string{
+			"title":     topic.Subject,
+			"summary":   topic.InitialMessage,
+			"createdBy": topic.Did,
+		},
+	}
+}
+
+// MessageRow returns the row for an indexed message
+func MessageRow(message db.Message) Row {
+	return Row{
+		URI: pds.ATURI{Repo: message.Did, Collection: lexicon.MessageNSID, Rkey: message.Rkey},
+		Fields: map[string]string{
+			"topic":   pds.ATURI{Repo: message.TopicDid, Collection: lexicon.TopicNSID, Rkey: message.TopicRkey}.String(),
+			"content": string(message.Content),
+			"replyTo": message.ParentMessageRkey.String,
+		},
+	}
+}
+
+// Drift is a row that no longer matches its record
+type Drift struct {
+	URI    string
+	Kind   string
+	Detail string
+}
+
+// Report is the outcome of checking a sample of rows
+type Report struct {
+	// Checked counts rows whose record could be fetched or was found missing
+	Checked int
+	// Unreachable counts rows skipped because their PDS could not be asked
+	Unreachable int
+	Drift       []Drift
+}
+
+// Check compares each row with its record. Rows whose PDS fails to answer
+// are counted as unreachable rather than drift; onUnreachable, if set, is
+// told why.
+func Check(ctx context.Context, records Records, rows []Row, onUnreachable func(uri string, err error)) Report {
+	var report Report
+	for _, row := range rows {
+		uri := row.URI.String()
+		value, err := records.GetRecord(ctx, row.URI.Repo, row.URI.Collection, row.URI.Rkey)
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			report.Checked++
+			report.Drift = append(report.Drift, Drift{URI: uri, Kind: Missing})
+			continue
+		case err != nil:
+			report.Unreachable++
+			if onUnreachable != nil {
+				onUnreachable(uri, err)
+			}
+			continue
+		}
+
+		report.Checked++
+		remote, err := recordFields(row.URI.Collection, value)
+		if err != nil {
+			report.Drift = append(report.Drift, Drift{URI: uri, Kind: Invalid, Detail: err.Error()})
+			continue
+		}
+		keys := make([]string, 0, len(row.Fields))
+		for key := range row.Fields {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if local := row.Fields[key]; remote[key] != local {
+				report.Drift = append(report.Drift, Drift{
+					URI:    uri,
+					Kind:   Mismatched,
+					Detail: fmt.Sprintf("%s: index %q, pds %q", key, local, remote[key]),
+				})
+			}
+		}
+	}
+	return report
+}
+
+// recordFields decodes a record at its current version and returns the
+// fields the index copies from it
+func recordFields(collection string, value map[string]any) (map[string]string, error) {
+	if _, err := lexicon.Upgrade(collection, value); err != nil {
+		return nil, err
+	}
+	switch collection {
+	case lexicon.TopicNSID:
+		topic, err := lexicon.TopicFromMap(value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"title":     topic.Title,
+			"summary":   topic.Summary,
+			"createdBy": topic.CreatedBy,
+		}, nil
+	case lexicon.MessageNSID:
+		message, err := lexicon.MessageFromMap(value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"topic":   message.Topic,
+			"content": message.Content,
+			"replyTo": message.ReplyTo,
+		}, nil
+	}
+	return nil, fmt.Errorf("no index fields for collection %s", collection)
+}
+
+// PDS reads records from the PDS hosting each account, without credentials
+type PDS struct {
+	// Resolve finds the PDS hosting an account's repo
+	Resolve func(did string) (string, error)
+}
+
+// GetRecord implements Records
+func (p PDS) GetRecord(ctx context.Context, did, collection, rkey string) (map[string]any, error) {
+	host, err := p.Resolve(did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PDS for %s: %w", did, err)
+	}
+	record, err := pds.NewClient(host, pds.Anonymous{}, nil).GetRecord(ctx, did, collection, rkey)
+	if err != nil {
+		var xrpcErr *pds.XRPCError
+		if errors.As(err, &xrpcErr) && xrpcErr.Status == http.StatusBadRequest && xrpcErr.Name == "RecordNotFound" {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var value map[string]any
+	if err := json.Unmarshal(record.Value, &value); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+	return value, nil
+}
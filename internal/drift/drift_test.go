@@ -0,0 +1,88 @@
+package drift
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+// fakeRecords serves records by AT URI; unknown URIs are missing
+type fakeRecords struct {
+	records map[string]map[string]any
+	down    map[string]bool
+}
+
+func (f fakeRecords) GetRecord(_ context.Context, did, collection, rkey string) (map[string]any, error) {
+	if f.down[did] {
+		return nil, errors.New("connection refused")
+	}
+	record, ok := f.records["at://"+did+"/"+collection+"/"+rkey]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return record, nil
+}
+
+func TestCheck(t *testing.T) {
+	topic := db.Topic{Did: "did:plc:alice", Rkey: "t1", Subject: "Generics", InitialMessage: "How?"}
+	edited := db.Topic{Did: "did:plc:alice", Rkey: "t2", Subject: "Old title"}
+	deleted := db.Topic{Did: "did:plc:alice", Rkey: "t3", Subject: "Gone"}
+	unreachable := db.Topic{Did: "did:plc:bob", Rkey: "t4", Subject: "Offline"}
+	message := db.Message{
+		Did: "did:plc:carol", Rkey: "m1", TopicDid: topic.Did, TopicRkey: topic.Rkey,
+		Content: "Like this", ParentMessageRkey: sql.NullString{},
+	}
+	invalid := db.Message{Did: "did:plc:carol", Rkey: "m2", TopicDid: topic.Did, TopicRkey: topic.Rkey, Content: "?"}
+
+	records := fakeRecords{
+		records: map[string]map[string]any{
+			"at://did:plc:alice/quest.dis.topic/t1": {
+				"$type": lexicon.TopicNSID, "title": "Generics", "summary": "How?",
+				"createdBy": "did:plc:alice", "createdAt": "2025-01-01T00:00:00Z",
+			},
+			"at://did:plc:alice/quest.dis.topic/t2": {
+				"$type": lexicon.TopicNSID, "title": "New title",
+				"createdBy": "did:plc:alice", "createdAt": "2025-01-01T00:00:00Z",
+			},
+			"at://did:plc:carol/quest.dis.message/m1": {
+				"$type": lexicon.MessageNSID, "topic": "at://did:plc:alice/quest.dis.topic/t1",
+				"content": "Like this", "createdAt": "2025-01-01T00:00:00Z",
+			},
+			"at://did:plc:carol/quest.dis.message/m2": {
+				"$type": lexicon.MessageNSID, "content": "?",
+			},
+		},
+		down: map[string]bool{"did:plc:bob": true},
+	}
+	rows := []Row{TopicRow(topic), TopicRow(edited), TopicRow(deleted), TopicRow(unreachable), MessageRow(message), MessageRow(invalid)}
+
+	var skipped []string
+	report := Check(context.Background(), records, rows, func(uri string, _ error) { skipped = append(skipped, uri) })
+
+	if report.Checked != 5 || report.Unreachable != 1 {
+		t.Errorf("checked %d, unreachable %d; want 5 and 1", report.Checked, report.Unreachable)
+	}
+	if len(skipped) != 1 || skipped[0] != "at://did:plc:bob/quest.dis.topic/t4" {
+		t.Errorf("skipped %v", skipped)
+	}
+	want := []Drift{
+		{URI: "at://did:plc:alice/quest.dis.topic/t2", Kind: Mismatched, Detail: `title: index "Old title", pds "New title"`},
+		{URI: "at://did:plc:alice/quest.dis.topic/t3", Kind: Missing},
+		{URI: "at://did:plc:carol/quest.dis.message/m2", Kind: Invalid},
+	}
+	if len(report.Drift) != len(want) {
+		t.Fatalf("drift = %+v, want %d entries", report.Drift, len(want))
+	}
+	for i, got := range report.Drift {
+		if got.URI != want[i].URI || got.Kind != want[i].Kind {
+			t.Errorf("drift[%d] = %+v, want %+v", i, got, want[i])
+		}
+		if want[i].Detail != "" && got.Detail != want[i].Detail {
+			t.Errorf("drift[%d] detail = %q, want %q", i, got.Detail, want[i].Detail)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package errreport sends panics and server errors, from HTTP handlers and
+// background jobs, to an error tracker
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Event is one error worth a look: a recovered panic, a 5xx response or a
+// failed background job
+type Event struct {
+	Err error
+	// Stack is the panicking goroutine's stack, nil when Err is not a panic
+	Stack []byte
+	// Job names the background job that failed, empty for requests
+	Job string
+
+	// RequestID, Method, Path and Status describe the failed request
+	RequestID string
+	Method    string
+	Path      string
+	Status    int
+}
+
+// Panicked reports whether the event is a recovered panic
+func (e Event) Panicked() bool {
+	return e.Stack != nil
+}
+
+// Reporter receives events. Report must not block for long, since handlers
+// call it before answering, and must be safe for concurrent use.
+type Reporter interface {
+	Report(ctx context.Context, event Event)
+}
+
+// Flusher is implemented by reporters that send events in the background
+type Flusher interface {
+	// Flush waits up to timeout for events already reported to be sent,
+	// and reports whether they were
+	Flush(timeout time.Duration) bool
+}
+
+// reporterHolder lets a nil reporter be stored in an atomic.Value
+type reporterHolder struct{ reporter Reporter }
+
+var defaultReporter atomic.Value
+
+// SetReporter sends every event to reporter. A nil reporter turns reporting
+// off.
+func SetReporter(reporter Reporter) {
+	defaultReporter.Store(reporterHolder{reporter})
+}
+
+// Current returns the reporter set by SetReporter, or one that discards
+// everything
+func Current() Reporter {
+	if h, ok := defaultReporter.Load().(reporterHolder); ok && h.reporter != nil {
+		return h.reporter
+	}
+	return discard{}
+}
+
+type discard struct{}
+
+func (discard) Report(context.Context, Event) {}
+
+// Report sends event to the current reporter
+func Report(ctx context.Context, event Event) {
+	Current().Report(ctx, event)
+}
+
+// Job reports a background job's failure. Callers still log it; reporting
+// only tells the error tracker.
+func Job(job string, err error) {
+	if err == nil {
+		return
+	}
+	Report(context.Background(), Event{Err: err, Job: job})
+}
+
+// Flush waits up to timeout for the current reporter to send what it was
+// given, for use before the process exits
+func Flush(timeout time.Duration) bool {
+	if f, ok := Current().(Flusher); ok {
+		return f.Flush(timeout)
+	}
+	return true
+}
+
+// panicFlushTimeout bounds how long a crashing job waits for its panic to
+// be sent
+const panicFlushTimeout = 2 * time.Second
+
+// PanicError wraps the value a goroutine panicked with
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Go runs fn in a new goroutine as the background job named job. A panic
+// in fn is reported and then re-raised, so it still crashes the process as
+// it would have unwatched, but not before the error tracker has it.
+func Go(job string, fn func()) {
+	go run(job, fn)
+}
+
+func run(job string, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			Report(context.Background(), Event{Err: &PanicError{Value: p}, Stack: debug.Stack(), Job: job})
+			Flush(panicFlushTimeout)
+			panic(p)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,71 @@
+package errreport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recording keeps the events it is given
+type recording struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recording) Report(_ context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestJob(t *testing.T) {
+	reporter := &recording{}
+	SetReporter(reporter)
+	t.Cleanup(func() { SetReporter(nil) })
+
+	Job("trending", nil)
+	failure := errors.New("database is locked")
+	Job("trending", failure)
+	if len(reporter.events) != 1 {
+		t.Fatalf("reported %d events, want only the failure", len(reporter.events))
+	}
+	if event := reporter.events[0]; event.Job != "trending" || event.Err != failure || event.Panicked() {
+		t.Errorf("event = %+v", event)
+	}
+
+	SetReporter(nil)
+	Job("trending", failure)
+	if len(reporter.events) != 1 {
+		t.Error("expected nothing reported with reporting off")
+	}
+}
+
+func TestRunRepanics(t *testing.T) {
+	reporter := &recording{}
+	SetReporter(reporter)
+	t.Cleanup(func() { SetReporter(nil) })
+
+	cause := errors.New("nil map")
+	func() {
+		defer func() {
+			if p := recover(); p != cause {
+				t.Errorf("recovered %v, want the job's panic re-raised", p)
+			}
+		}()
+		run("mirror", func() { panic(cause) })
+	}()
+
+	if len(reporter.events) != 1 {
+		t.Fatalf("reported %d events, want the panic", len(reporter.events))
+	}
+	event := reporter.events[0]
+	if event.Job != "mirror" || !event.Panicked() || !errors.Is(event.Err, cause) {
+		t.Errorf("event = %+v", event)
+	}
+
+	run("mirror", func() {})
+	if len(reporter.events) != 1 {
+		t.Error("expected a job that returns not to be reported")
+	}
+}
@@ -0,0 +1,211 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// sentryQueueSize bounds the events waiting to be sent. Events reported
+// while it is full are dropped, so an outage of the tracker never slows
+// requests down.
+const sentryQueueSize = 64
+
+// Sentry reports events to a Sentry project through its envelope endpoint
+type Sentry struct {
+	endpoint    string
+	auth        string
+	dsn         string
+	environment string
+	client      *http.Client
+
+	queue   chan []byte
+	pending sync.WaitGroup
+}
+
+// NewSentry creates a reporter for the project of dsn, as shown in the
+// project's client keys settings, e.g. https://key@o1.ingest.sentry.io/42.
+// Events are tagged with environment and sent in the background by client.
+func NewSentry(dsn, environment string, client *http.Client) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("invalid Sentry DSN: want scheme://key@host/project")
+	}
+	prefix, project := "", strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	if _, err := strconv.Atoi(project); err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: project %q is not numeric", project)
+	}
+
+	s := &Sentry{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth:        "Sentry sentry_version=7, sentry_client=disquest/1.0, sentry_key=" + u.User.Username(),
+		dsn:         dsn,
+		environment: environment,
+		client:      client,
+		queue:       make(chan []byte, sentryQueueSize),
+	}
+	go s.send()
+	return s, nil
+}
+
+// Report queues event to be sent, dropping it if the queue is full
+func (s *Sentry) Report(_ context.Context, event Event) {
+	envelope, err := s.envelope(event, time.Now())
+	if err != nil {
+		logger.Warn("Failed to encode error report", "error", err)
+		return
+	}
+	s.pending.Add(1)
+	select {
+	case s.queue <- envelope:
+	default:
+		s.pending.Done()
+		logger.Warn("Error report queue full, dropping report", "error", event.Err)
+	}
+}
+
+// Flush waits up to timeout for the queued events to be sent
+func (s *Sentry) Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// send posts queued envelopes for the life of the process
+func (s *Sentry) send() {
+	for envelope := range s.queue {
+		if err := s.post(envelope); err != nil {
+			logger.Warn("Failed to send error report", "error", err)
+		}
+		s.pending.Done()
+	}
+}
+
+func (s *Sentry) post(envelope []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sentry answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sentryEvent is the subset of Sentry's event payload the reporter fills in
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Environment string            `json:"environment,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// envelope encodes event as a one-item Sentry envelope
+func (s *Sentry) envelope(event Event, now time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	payload := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   now.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Environment: s.environment,
+		Tags:        map[string]string{},
+		Extra:       map[string]string{},
+	}
+	exception := sentryException{Type: "error", Value: "unknown error"}
+	if event.Err != nil {
+		exception = sentryException{Type: fmt.Sprintf("%T", event.Err), Value: event.Err.Error()}
+	}
+	if event.Panicked() {
+		payload.Level = "fatal"
+		exception.Type = "panic"
+		payload.Extra["stack"] = string(event.Stack)
+	}
+	payload.Exception.Values = []sentryException{exception}
+	if event.Job != "" {
+		payload.Tags["job"] = event.Job
+	}
+	if event.RequestID != "" {
+		payload.Tags["request_id"] = event.RequestID
+	}
+	if event.Status != 0 {
+		payload.Tags["status"] = strconv.Itoa(event.Status)
+	}
+	if event.Method != "" {
+		payload.Request = &sentryRequest{Method: event.Method, URL: event.Path}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{
+		"event_id": payload.EventID,
+		"sent_at":  payload.Timestamp,
+		"dsn":      s.dsn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(header)
+	fmt.Fprintf(&buf, "\n{\"type\":\"event\",\"length\":%d}\n", len(body))
+	buf.Write(body)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+package errreport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewSentry(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		endpoint string
+		wantErr  bool
+	}{
+		{dsn: "https://abc@o1.ingest.sentry.io/42", endpoint: "https://o1.ingest.sentry.io/api/42/envelope/"},
+		{dsn: "https://abc@sentry.example.com/tracker/7", endpoint: "https://sentry.example.com/tracker/api/7/envelope/"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantErr: true},
+		{dsn: "https://abc@o1.ingest.sentry.io/", wantErr: true},
+		{dsn: "ftp://abc@o1.ingest.sentry.io/42", wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := NewSentry(tt.dsn, "production", http.DefaultClient)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewSentry(%q) accepted an invalid DSN", tt.dsn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewSentry(%q): %v", tt.dsn, err)
+			continue
+		}
+		if s.endpoint != tt.endpoint {
+			t.Errorf("NewSentry(%q) endpoint = %q, want %q", tt.dsn, s.endpoint, tt.endpoint)
+		}
+	}
+}
+
+func TestSentryReport(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		auth   string
+		events []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/envelope/" {
+			http.NotFound(w, r)
+			return
+		}
+		// An envelope is a header line, then an item header and its payload
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, 1<<20)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		var event map[string]any
+		if len(lines) != 3 || json.Unmarshal([]byte(lines[2]), &event) != nil {
+			http.Error(w, "bad envelope", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		auth = r.Header.Get("X-Sentry-Auth")
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	s, err := NewSentry(strings.Replace(srv.URL, "://", "://abc@", 1)+"/42", "production", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Report(context.Background(), Event{
+		Err:       &PanicError{Value: "index out of range"},
+		Stack:     []byte("goroutine 1 [running]"),
+		RequestID: "req-1",
+		Method:    http.MethodPost,
+		Path:      "/api/topics",
+		Status:    http.StatusInternalServerError,
+	})
+	s.Report(context.Background(), Event{Err: errors.New("database is locked"), Job: "trending"})
+	if !s.Flush(5 * time.Second) {
+		t.Fatal("Flush timed out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("received %d events, want 2", len(events))
+	}
+	if !strings.Contains(auth, "sentry_key=abc") {
+		t.Errorf("X-Sentry-Auth = %q, want the DSN's key", auth)
+	}
+	panicked, failed := events[0], events[1]
+	if panicked["level"] != "fatal" || panicked["environment"] != "production" {
+		t.Errorf("panic event = %v", panicked)
+	}
+	if tags, _ := panicked["tags"].(map[string]any); tags["request_id"] != "req-1" || tags["status"] != "500" {
+		t.Errorf("panic event tags = %v", panicked["tags"])
+	}
+	if extra, _ := panicked["extra"].(map[string]any); extra["stack"] != "goroutine 1 [running]" {
+		t.Errorf("panic event lacks its stack: %v", panicked["extra"])
+	}
+	if tags, _ := failed["tags"].(map[string]any); failed["level"] != "error" || tags["job"] != "trending" {
+		t.Errorf("job event = %v", failed)
+	}
+}
@@ -0,0 +1,72 @@
+// Package firehose publishes record changes as an ordered, resumable event
+// stream framed like Bluesky's Jetstream, so existing Jetstream consumers
+// can follow a dis.quest instance.
+package firehose
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// KindCommit marks an event describing a record change
+const KindCommit = "commit"
+
+// Commit operations
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// Event is a single firehose message. TimeUS is the event's cursor: unix
+// microseconds, unique and strictly increasing within an instance.
+type Event struct {
+	Did    string  `json:"did"`
+	TimeUS int64   `json:"time_us"`
+	Kind   string  `json:"kind"`
+	Commit *Commit `json:"commit,omitempty"`
+}
+
+// Commit describes the record a commit event touched. Record is omitted for
+// deletes. CID is the record's repository CID when the publisher knows it,
+// as on Jetstream; this instance publishes records before they reach a
+// repository, so it leaves CID empty.
+type Commit struct {
+	Operation  string          `json:"operation"`
+	Collection string          `json:"collection"`
+	Rkey       string          `json:"rkey"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	CID        string          `json:"cid,omitempty"`
+}
+
+// Filter selects events by collection and author. Empty lists match
+// everything. Collections may end in ".*" to match an NSID prefix, as
+// Jetstream's wantedCollections does.
+type Filter struct {
+	Collections []string
+	DIDs        []string
+}
+
+// Matches reports whether the filter accepts the event
+func (f Filter) Matches(event Event) bool {
+	if len(f.DIDs) > 0 && !slices.Contains(f.DIDs, event.Did) {
+		return false
+	}
+	if len(f.Collections) == 0 {
+		return true
+	}
+	if event.Commit == nil {
+		return false
+	}
+	for _, wanted := range f.Collections {
+		if prefix, ok := strings.CutSuffix(wanted, "*"); ok {
+			if strings.HasPrefix(event.Commit.Collection, prefix) {
+				return true
+			}
+		} else if wanted == event.Commit.Collection {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package firehose
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultBufferSize is the number of events queued per subscriber before
+	// it is considered too slow and dropped
+	defaultBufferSize = 256
+	// replayBatchSize is how many stored events are read per replay query
+	replayBatchSize = 500
+	// maxPublishAttempts bounds retries when another instance has stored
+	// an event at the cursor this one picked
+	maxPublishAttempts = 3
+)
+
+// Store persists events so subscribers can resume from a cursor
+type Store interface {
+	// Append stores an event
+	Append(ctx context.Context, event Event) error
+	// Since returns up to limit events with TimeUS >= cursor, oldest first
+	Since(ctx context.Context, cursor int64, limit int) ([]Event, error)
+	// Latest returns the newest stored TimeUS, or zero when the store is empty
+	Latest(ctx context.Context) (int64, error)
+}
+
+// Subscriber receives live events that match its filter
+type Subscriber struct {
+	filter  Filter
+	events  chan Event
+	dropped bool
+}
+
+// Events returns the channel of live events. It is closed when the
+// subscriber is removed.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped reports whether the subscriber was removed for falling behind.
+// It is only meaningful once Events has been closed.
+func (s *Subscriber) Dropped() bool {
+	return s.dropped
+}
+
+// Firehose orders, stores and fans out events
+type Firehose struct {
+	store Store
+	now   func() time.Time
+
+	// mu serializes publishing so cursors are delivered in order
+	mu          sync.Mutex
+	last        int64
+	loaded      bool
+	subscribers map[*Subscriber]struct{}
+
+	// relay is set while Relay runs
+	relay atomic.Pointer[firehoseRelay]
+}
+
+// New creates a firehose backed by store
+func New(store Store) *Firehose {
+	return &Firehose{
+		store:       store,
+		now:         time.Now,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Publish stamps the event with the next cursor, stores it and delivers it
+// to matching subscribers. A subscriber whose buffer is full is dropped
+// rather than allowed to block publishing; it can reconnect from its last
+// cursor. When instances share a store and one of them has already used
+// the cursor, Publish reloads the latest cursor and tries again.
+func (f *Firehose) Publish(ctx context.Context, event Event) (Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.loaded {
+		latest, err := f.store.Latest(ctx)
+		if err != nil {
+			return Event{}, err
+		}
+		f.last, f.loaded = latest, true
+	}
+
+	if event.Kind == "" {
+		event.Kind = KindCommit
+	}
+	for attempt := 1; ; attempt++ {
+		event.TimeUS = max(f.now().UnixMicro(), f.last+1)
+		err := f.store.Append(ctx, event)
+		if err == nil {
+			break
+		}
+		latest, latestErr := f.store.Latest(ctx)
+		if latestErr != nil || latest < event.TimeUS || attempt == maxPublishAttempts {
+			return Event{}, err
+		}
+		f.last = latest
+	}
+	f.last = event.TimeUS
+
+	f.deliver(event)
+	f.forward(event)
+	return event, nil
+}
+
+// deliver queues event for matching subscribers. f.mu must be held.
+func (f *Firehose) deliver(event Event) {
+	for sub := range f.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped = true
+			delete(f.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for live events matching filter
+func (f *Firehose) Subscribe(filter Filter) *Subscriber {
+	sub := &Subscriber{filter: filter, events: make(chan Event, defaultBufferSize)}
+
+	f.mu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel. It is safe to
+// call more than once.
+func (f *Firehose) Unsubscribe(sub *Subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Replay calls fn for each stored event from cursor onwards that matches
+// filter. It returns the cursor of the last stored event it read, which
+// callers use to skip live events they have already replayed.
+func (f *Firehose) Replay(ctx context.Context, cursor int64, filter Filter, fn func(Event) error) (int64, error) {
+	var last int64
+	for {
+		events, err := f.store.Since(ctx, cursor, replayBatchSize)
+		if err != nil {
+			return last, err
+		}
+		for _, event := range events {
+			last = event.TimeUS
+			if !filter.Matches(event) {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return last, err
+			}
+		}
+		if len(events) < replayBatchSize {
+			return last, nil
+		}
+		cursor = last + 1
+	}
+}
@@ -0,0 +1,169 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps events in a slice
+type memoryStore struct {
+	events []Event
+}
+
+func (s *memoryStore) Append(_ context.Context, event Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryStore) Since(_ context.Context, cursor int64, limit int) ([]Event, error) {
+	var out []Event
+	for _, event := range s.events {
+		if event.TimeUS >= cursor && len(out) < limit {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryStore) Latest(context.Context) (int64, error) {
+	if len(s.events) == 0 {
+		return 0, nil
+	}
+	return s.events[len(s.events)-1].TimeUS, nil
+}
+
+func commit(did, collection string) Event {
+	return Event{Did: did, Commit: &Commit{Operation: OperationCreate, Collection: collection, Rkey: "r"}}
+}
+
+func TestFilterMatches(t *testing.T) {
+	topic := commit("did:plc:alice", "quest.dis.topic")
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"Empty filter", Filter{}, true},
+		{"Exact collection", Filter{Collections: []string{"quest.dis.topic"}}, true},
+		{"Other collection", Filter{Collections: []string{"quest.dis.message"}}, false},
+		{"Collection prefix", Filter{Collections: []string{"quest.dis.*"}}, true},
+		{"Wanted DID", Filter{DIDs: []string{"did:plc:alice"}}, true},
+		{"Other DID", Filter{DIDs: []string{"did:plc:bob"}}, false},
+		{"Both must match", Filter{Collections: []string{"quest.dis.*"}, DIDs: []string{"did:plc:bob"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(topic); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirehose(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{events: []Event{{TimeUS: 5000, Kind: KindCommit}}}
+	fh := New(store)
+	// A clock behind the stored log must not produce duplicate cursors
+	fh.now = func() time.Time { return time.UnixMicro(1000) }
+
+	messages := fh.Subscribe(Filter{Collections: []string{"quest.dis.message"}})
+	defer fh.Unsubscribe(messages)
+
+	first, err := fh.Publish(ctx, commit("did:plc:alice", "quest.dis.topic"))
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	second, err := fh.Publish(ctx, commit("did:plc:alice", "quest.dis.message"))
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if first.TimeUS != 5001 || second.TimeUS != 5002 || first.Kind != KindCommit {
+		t.Errorf("Expected cursors 5001 and 5002, got %+v and %+v", first, second)
+	}
+
+	t.Run("Live delivery is filtered", func(t *testing.T) {
+		select {
+		case event := <-messages.Events():
+			if event.TimeUS != second.TimeUS {
+				t.Errorf("Expected message event, got %+v", event)
+			}
+		default:
+			t.Fatal("Expected a live event")
+		}
+		if len(messages.Events()) != 0 {
+			t.Error("Expected topic event to be filtered out")
+		}
+	})
+
+	t.Run("Replay resumes from cursor", func(t *testing.T) {
+		var replayed []int64
+		last, err := fh.Replay(ctx, first.TimeUS, Filter{}, func(event Event) error {
+			replayed = append(replayed, event.TimeUS)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		if len(replayed) != 2 || replayed[0] != first.TimeUS || last != second.TimeUS {
+			t.Errorf("Expected replay of %d and %d, got %v (last %d)", first.TimeUS, second.TimeUS, replayed, last)
+		}
+	})
+
+	t.Run("Slow subscribers are dropped", func(t *testing.T) {
+		slow := fh.Subscribe(Filter{})
+		for i := 0; i <= defaultBufferSize; i++ {
+			if _, err := fh.Publish(ctx, commit("did:plc:alice", "quest.dis.message")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+		}
+		for range slow.Events() {
+		}
+		if !slow.Dropped() {
+			t.Error("Expected subscriber to be marked dropped")
+		}
+		fh.Unsubscribe(slow)
+	})
+}
+
+func BenchmarkFirehosePublish(b *testing.B) {
+	for _, subscribers := range []int{1, 100} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			ctx := context.Background()
+			fh := New(&memoryStore{})
+			var wg sync.WaitGroup
+			subs := make([]*Subscriber, 0, subscribers)
+			for i := 0; i < subscribers; i++ {
+				sub := fh.Subscribe(Filter{Collections: []string{"quest.dis.*"}})
+				subs = append(subs, sub)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range sub.Events() {
+					}
+				}()
+			}
+			event := commit("did:plc:alice", "quest.dis.message")
+			event.Commit.Record = []byte(`{"$type":"quest.dis.message","content":"hello"}`)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := fh.Publish(ctx, event); err != nil {
+					b.Fatalf("Publish: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			for _, sub := range subs {
+				fh.Unsubscribe(sub)
+			}
+			wg.Wait()
+		})
+	}
+}
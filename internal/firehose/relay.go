@@ -0,0 +1,59 @@
+package firehose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jrschumacher/dis.quest/internal/broker"
+)
+
+// relayChannel is the broker channel live events travel on
+const relayChannel = "disquest.firehose"
+
+type firehoseRelay struct {
+	relay   *broker.Relay
+	onError func(error)
+}
+
+// Relay shares live events with the firehoses of other instances through b
+// until ctx is done. Events published on any instance are stored once, in
+// the shared store, and delivered to subscribers on every instance; events
+// from other instances arrive in the order the broker delivers them.
+// Errors, including events dropped because the broker is not keeping up,
+// are passed to onError. Subscribers that miss a relayed event can recover
+// it by replaying from their cursor.
+func (f *Firehose) Relay(ctx context.Context, b broker.Broker, onError func(error)) {
+	relay := broker.NewRelay(b, relayChannel)
+	f.relay.Store(&firehoseRelay{relay: relay, onError: onError})
+	defer f.relay.Store(nil)
+
+	relay.Run(ctx, func(payload []byte) {
+		var event Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			onError(fmt.Errorf("malformed relayed event: %w", err))
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// Keep cursors picked here ahead of those used elsewhere
+		f.last = max(f.last, event.TimeUS)
+		f.deliver(event)
+	}, onError)
+}
+
+// forward sends a stored event to the other instances, if relaying
+func (f *Firehose) forward(event Event) {
+	r := f.relay.Load()
+	if r == nil {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		r.onError(fmt.Errorf("failed to relay event: %w", err))
+		return
+	}
+	if !r.relay.Send(payload) {
+		r.onError(broker.ErrOutboxFull)
+	}
+}
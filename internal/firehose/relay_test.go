@@ -0,0 +1,94 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/broker"
+)
+
+// uniqueStore rejects a second event at the same cursor, as the database's
+// primary key does
+type uniqueStore struct {
+	memoryStore
+}
+
+func (s *uniqueStore) Append(ctx context.Context, event Event) error {
+	for _, stored := range s.events {
+		if stored.TimeUS == event.TimeUS {
+			return errors.New("duplicate cursor")
+		}
+	}
+	return s.memoryStore.Append(ctx, event)
+}
+
+func (s *uniqueStore) Latest(context.Context) (int64, error) {
+	var latest int64
+	for _, event := range s.events {
+		latest = max(latest, event.TimeUS)
+	}
+	return latest, nil
+}
+
+func TestPublishSharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := &uniqueStore{}
+	a, b := New(store), New(store)
+	now := func() time.Time { return time.UnixMicro(1000) }
+	a.now, b.now = now, now
+
+	first, err := a.Publish(ctx, commit("did:plc:alice", "quest.dis.topic"))
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if _, err := b.Publish(ctx, commit("did:plc:bob", "quest.dis.topic")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// a still believes 1000 is the latest cursor, which b has since used
+	second, err := a.Publish(ctx, commit("did:plc:alice", "quest.dis.message"))
+	if err != nil {
+		t.Fatalf("Expected Publish to retry past the other instance's cursor: %v", err)
+	}
+	if first.TimeUS != 1000 || second.TimeUS != 1002 {
+		t.Errorf("Expected cursors 1000 and 1002, got %d and %d", first.TimeUS, second.TimeUS)
+	}
+}
+
+func TestFirehoseRelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	memory := broker.NewMemory()
+	store := &uniqueStore{}
+	local, remote := New(store), New(store)
+	for _, fh := range []*Firehose{local, remote} {
+		go fh.Relay(ctx, memory, func(err error) { t.Errorf("relay: %v", err) })
+	}
+
+	sub := remote.Subscribe(Filter{Collections: []string{"quest.dis.topic"}})
+	defer remote.Unsubscribe(sub)
+
+	// The relays subscribe in the background, so publish until one arrives
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		published, err := local.Publish(ctx, commit("did:plc:alice", "quest.dis.topic"))
+		if err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		select {
+		case event := <-sub.Events():
+			if event.Did != "did:plc:alice" || event.TimeUS > published.TimeUS {
+				t.Errorf("relayed event = %+v", event)
+			}
+			if n := len(store.events); n == 0 || store.events[n-1].TimeUS != published.TimeUS {
+				t.Error("Expected the relayed event to be stored once, by its publisher")
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for a relayed event")
+			}
+		}
+	}
+}
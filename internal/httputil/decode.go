@@ -0,0 +1,145 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the request body limit applied by DecodeJSON
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// Errors returned (wrapped in a *DecodeError) by DecodeJSON
+var (
+	ErrRequestBodyTooLarge  = errors.New("request body too large")
+	ErrEmptyRequestBody     = errors.New("request body is empty")
+	ErrMalformedJSON        = errors.New("request body contains malformed JSON")
+	ErrUnknownField         = errors.New("request body contains an unknown field")
+	ErrInvalidFieldValue    = errors.New("request body contains an invalid value")
+	ErrTrailingData         = errors.New("request body must contain a single JSON value")
+	ErrUnsupportedMediaType = errors.New("request content type must be application/json")
+)
+
+// DecodeError describes why a request body could not be decoded, along with
+// the HTTP status that should be returned to the client
+type DecodeError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// Error implements the error interface
+func (e *DecodeError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying sentinel error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeOptions controls how DecodeJSONWithOptions reads a request body
+type DecodeOptions struct {
+	// MaxBytes limits the body size; zero means DefaultMaxBodyBytes
+	MaxBytes int64
+	// AllowUnknownFields accepts fields that dst does not declare
+	AllowUnknownFields bool
+}
+
+// DecodeJSON decodes a JSON request body into dst with the default limits:
+// at most DefaultMaxBodyBytes, no unknown fields and exactly one JSON value.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return DecodeJSONWithOptions(w, r, dst, DecodeOptions{})
+}
+
+// DecodeJSONWithOptions decodes a JSON request body into dst. Failures are
+// returned as *DecodeError so WriteDecodeError can respond consistently.
+func DecodeJSONWithOptions(w http.ResponseWriter, r *http.Request, dst any, opts DecodeOptions) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return &DecodeError{Status: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json", Err: ErrUnsupportedMediaType}
+		}
+	}
+
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	dec := json.NewDecoder(r.Body)
+	if !opts.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err, maxBytes)
+	}
+
+	// A second value (or garbage) after the first one is a client error
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err, maxBytes)
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body must contain a single JSON value", Err: ErrTrailingData}
+	}
+
+	return nil
+}
+
+func decodeError(err error, maxBytes int64) *DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Request body must not exceed %d bytes", maxBytes),
+			Err:     ErrRequestBodyTooLarge,
+		}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body must not be empty", Err: ErrEmptyRequestBody}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("Request body contains malformed JSON (at position %d)", syntaxErr.Offset),
+			Err:     ErrMalformedJSON,
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Request body contains malformed JSON", Err: ErrMalformedJSON}
+	case errors.As(err, &typeErr):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("Request body contains an invalid value for field %q", typeErr.Field),
+			Err:     ErrInvalidFieldValue,
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("Request body contains unknown field %s", field),
+			Err:     ErrUnknownField,
+		}
+	default:
+		return &DecodeError{Status: http.StatusBadRequest, Message: "Invalid JSON in request body", Err: ErrMalformedJSON}
+	}
+}
+
+// WriteDecodeError writes the response for an error returned by DecodeJSON
+func WriteDecodeError(w http.ResponseWriter, err error) {
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		WriteError(w, decodeErr.Status, decodeErr.Message, "error", decodeErr.Err)
+		return
+	}
+	WriteError(w, http.StatusBadRequest, "Invalid JSON in request body", "error", err)
+}
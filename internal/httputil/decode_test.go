@@ -0,0 +1,73 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type payload struct {
+		Subject string `json:"subject"`
+		Count   int    `json:"count"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		opts        DecodeOptions
+		wantErr     error
+		wantStatus  int
+	}{
+		{name: "valid", body: `{"subject":"hi","count":1}`, contentType: "application/json"},
+		{name: "valid with charset", body: `{"subject":"hi"}`, contentType: "application/json; charset=utf-8"},
+		{name: "no content type", body: `{"subject":"hi"}`},
+		{name: "empty body", body: ``, wantErr: ErrEmptyRequestBody, wantStatus: http.StatusBadRequest},
+		{name: "malformed", body: `{"subject":`, wantErr: ErrMalformedJSON, wantStatus: http.StatusBadRequest},
+		{name: "syntax error", body: `{"subject" "hi"}`, wantErr: ErrMalformedJSON, wantStatus: http.StatusBadRequest},
+		{name: "wrong type", body: `{"count":"one"}`, wantErr: ErrInvalidFieldValue, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"subject":"hi","admin":true}`, wantErr: ErrUnknownField, wantStatus: http.StatusBadRequest},
+		{name: "unknown field allowed", body: `{"subject":"hi","admin":true}`, opts: DecodeOptions{AllowUnknownFields: true}},
+		{name: "trailing data", body: `{"subject":"hi"}{"subject":"again"}`, wantErr: ErrTrailingData, wantStatus: http.StatusBadRequest},
+		{name: "too large", body: `{"subject":"` + strings.Repeat("a", 64) + `"}`, opts: DecodeOptions{MaxBytes: 32}, wantErr: ErrRequestBodyTooLarge, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "wrong content type", body: `subject=hi`, contentType: "application/x-www-form-urlencoded", wantErr: ErrUnsupportedMediaType, wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			var dst payload
+			err := DecodeJSONWithOptions(w, req, &dst, tt.opts)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			WriteDecodeError(w, err)
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if resp.Message == "" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}
@@ -3,7 +3,10 @@ package httputil
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/jrschumacher/dis.quest/internal/logger"
 	"github.com/jrschumacher/dis.quest/internal/validation"
@@ -12,15 +15,53 @@ import (
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
 	Error   string                      `json:"error"`
+	Code    string                      `json:"code,omitempty"`
 	Message string                      `json:"message,omitempty"`
 	Details []validation.Error `json:"details,omitempty"`
+	Remedy  *ScopeRemedy       `json:"remedy,omitempty"`
+	// RequestID identifies the request in logs and error reports, for
+	// users quoting it to an operator
+	RequestID string `json:"request_id,omitempty"`
 }
 
+// CodeValidationFailed is the error code for responses carrying field errors
+const CodeValidationFailed = "validation_failed"
+
+// CodePDSUnavailable is the error code for requests that failed because the
+// user's PDS could not be reached. Clients should offer to retry later.
+const CodePDSUnavailable = "pds_unavailable"
+
+// PDSUnavailableMessage is the user-facing text for CodePDSUnavailable
+const PDSUnavailableMessage = "Your PDS is unreachable right now. Please try again later."
+
+// CodeInsufficientScope is the error code for writes the user's PDS refused
+// because their sign-in's scope does not cover them. The response's remedy
+// says what may fix it.
+const CodeInsufficientScope = "insufficient_scope"
+
+// ScopeRemedy describes a scope refusal and what the user can do about it
+type ScopeRemedy struct {
+	// MissingScope is the scope the PDS asked for, when it named one
+	MissingScope string `json:"missing_scope,omitempty"`
+	// Relogin reports whether signing in again can grant the scope
+	Relogin bool `json:"relogin"`
+	// AppPassword reports whether the request may be repeated with an
+	// app_password
+	AppPassword bool `json:"app_password"`
+}
+
+// defaultPDSRetryAfter is suggested when the PDS gave no Retry-After
+const defaultPDSRetryAfter = time.Minute
+
 // WriteError writes a standardized error response
 func WriteError(w http.ResponseWriter, status int, message string, logFields ...any) {
 	response := ErrorResponse{
-		Error:   http.StatusText(status),
-		Message: message,
+		Error:     http.StatusText(status),
+		Message:   message,
+		RequestID: requestID(w),
+	}
+	if status >= http.StatusInternalServerError {
+		recordError(w, errors.New(message))
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -39,8 +80,10 @@ func WriteError(w http.ResponseWriter, status int, message string, logFields ...
 func WriteValidationError(w http.ResponseWriter, validationErr validation.Errors) {
 	response := ErrorResponse{
 		Error:   "Validation Failed",
+		Code:    CodeValidationFailed,
 		Message: validationErr.Error(),
-		Details: validationErr,
+		Details:   validationErr,
+		RequestID: requestID(w),
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -53,12 +96,59 @@ func WriteValidationError(w http.ResponseWriter, validationErr validation.Errors
 	logger.Warn("Validation error", "errors", validationErr.Error())
 }
 
+// WriteValidationFailure writes the response for an error returned by a
+// Validate method: field errors when available, a plain 400 otherwise
+func WriteValidationFailure(w http.ResponseWriter, err error) {
+	var validationErrors validation.Errors
+	if errors.As(err, &validationErrors) {
+		WriteValidationError(w, validationErrors)
+		return
+	}
+	WriteError(w, http.StatusBadRequest, err.Error())
+}
+
+// WritePDSUnavailable writes a 503 telling the client the user's PDS is
+// unreachable, with a Retry-After of retryAfter (or a minute when unknown)
+func WritePDSUnavailable(w http.ResponseWriter, retryAfter time.Duration, logFields ...any) {
+	if retryAfter <= 0 {
+		retryAfter = defaultPDSRetryAfter
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second)/time.Second)))
+	WriteJSON(w, http.StatusServiceUnavailable, ErrorResponse{
+		Error:   http.StatusText(http.StatusServiceUnavailable),
+		Code:      CodePDSUnavailable,
+		Message:   PDSUnavailableMessage,
+		RequestID: requestID(w),
+	})
+	logger.Warn("PDS unavailable", logFields...)
+}
+
+// WriteInsufficientScope writes a 403 telling the client the user's PDS
+// refused their sign-in's scope, with message and remedy explaining what to
+// do next
+func WriteInsufficientScope(w http.ResponseWriter, message string, remedy ScopeRemedy, logFields ...any) {
+	WriteJSON(w, http.StatusForbidden, ErrorResponse{
+		Error:   http.StatusText(http.StatusForbidden),
+		Code:    CodeInsufficientScope,
+		Message: message,
+		Remedy:    &remedy,
+		RequestID: requestID(w),
+	})
+	logFields = append([]any{"missing_scope", remedy.MissingScope, "relogin", remedy.Relogin}, logFields...)
+	logger.Warn("PDS refused token scope", logFields...)
+}
+
 // WriteInternalError writes a generic internal server error
 func WriteInternalError(w http.ResponseWriter, err error, message string, logFields ...any) {
 	response := ErrorResponse{
-		Error:   "Internal Server Error",
-		Message: message,
+		Error:     "Internal Server Error",
+		Message:   message,
+		RequestID: requestID(w),
+	}
+	if err == nil {
+		err = errors.New(message)
 	}
+	recordError(w, err)
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusInternalServerError)
@@ -72,6 +162,37 @@ func WriteInternalError(w http.ResponseWriter, err error, message string, logFie
 	logger.Error("Internal server error", logFields...)
 }
 
+// requestID returns the ID the recovery middleware gave the request w
+// answers, or "" when it did not run
+func requestID(w http.ResponseWriter) string {
+	for {
+		switch rw := w.(type) {
+		case interface{ RequestID() string }:
+			return rw.RequestID()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return ""
+		}
+	}
+}
+
+// recordError hands err to the recovery middleware, which reports it with
+// the response
+func recordError(w http.ResponseWriter, err error) {
+	for {
+		switch rw := w.(type) {
+		case interface{ RecordError(error) }:
+			rw.RecordError(err)
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
 // WriteJSON writes a JSON response with proper error handling
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
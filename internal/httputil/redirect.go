@@ -0,0 +1,102 @@
+package httputil
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxRedirectLength bounds the redirect targets accepted
+const maxRedirectLength = 2048
+
+// maxUnescapes bounds how many layers of percent-encoding are looked
+// through for slashes hidden in a path
+const maxUnescapes = 3
+
+// IsLocalPath reports whether target is a path on this site, safe to send in
+// a Location header. Anything a browser, or a server decoding it again,
+// could read as another host is refused:
+//   - "//host" and "/\host", as browsers treat backslashes as slashes
+//   - backslashes anywhere, and control characters or whitespace, which
+//     browsers strip before resolving a URL
+//   - absolute URLs
+//   - encoded slashes, backslashes and control characters in the path, as
+//     in "/%2F%2Fhost", even encoded more than once
+//   - "." and ".." path segments, which resolve to a different path
+func IsLocalPath(target string) bool {
+	if target == "" || len(target) > maxRedirectLength || target[0] != '/' || hasUnsafeRedirectChars(target) {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return false
+	}
+	// url.Parse has decoded the path once
+	path := u.Path
+	for range maxUnescapes {
+		if strings.HasPrefix(path, "//") || hasUnsafeRedirectChars(path) {
+			return false
+		}
+		for _, segment := range strings.Split(path, "/") {
+			if segment == "." || segment == ".." {
+				return false
+			}
+		}
+		unescaped, err := url.PathUnescape(path)
+		if err != nil || unescaped == path {
+			break
+		}
+		path = unescaped
+	}
+	return true
+}
+
+// SafeRedirect returns target as a local path when it is safe to redirect
+// to: a path IsLocalPath accepts, or an absolute URL on publicDomain, which
+// is reduced to its path so redirects never name a host. ok is false for
+// anything else, including scheme-relative URLs and URLs on other hosts.
+func SafeRedirect(target, publicDomain string) (local string, ok bool) {
+	if IsLocalPath(target) {
+		return target, true
+	}
+	if target == "" || len(target) > maxRedirectLength || hasUnsafeRedirectChars(target) {
+		return "", false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil || u.Opaque != "" || !sameOrigin(u, publicDomain) {
+		return "", false
+	}
+	local = u.EscapedPath()
+	if local == "" {
+		local = "/"
+	}
+	if u.RawQuery != "" {
+		local += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		local += "#" + u.EscapedFragment()
+	}
+	if !IsLocalPath(local) {
+		return "", false
+	}
+	return local, true
+}
+
+// sameOrigin reports whether u has the scheme and host of publicDomain
+func sameOrigin(u *url.URL, publicDomain string) bool {
+	if publicDomain == "" || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	public, err := url.Parse(publicDomain)
+	if err != nil || public.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, public.Scheme) && strings.EqualFold(u.Host, public.Host)
+}
+
+// hasUnsafeRedirectChars reports whether s has a backslash, whitespace or a
+// control character
+func hasUnsafeRedirectChars(s string) bool {
+	return strings.ContainsFunc(s, func(c rune) bool {
+		return c <= ' ' || c == 0x7f || c == '\\'
+	})
+}
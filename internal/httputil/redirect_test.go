@@ -0,0 +1,114 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPublicDomain = "https://dis.quest"
+
+func TestSafeRedirect(t *testing.T) {
+	for _, tc := range []struct {
+		target string
+		want   string
+	}{
+		{"/", "/"},
+		{"/discussion", "/discussion"},
+		{"/discussion/topic/42?tab=replies#reply-3", "/discussion/topic/42?tab=replies#reply-3"},
+		{"/search?q=a%2F%2Fb", "/search?q=a%2F%2Fb"},
+		{"/search?q=a%5Cb", "/search?q=a%5Cb"},
+		{"/topics/caf%C3%A9", "/topics/caf%C3%A9"},
+		{"https://dis.quest/discussion", "/discussion"},
+		{"https://DIS.quest/discussion?tab=new#top", "/discussion?tab=new#top"},
+		{"HTTPS://dis.quest", "/"},
+	} {
+		got, ok := SafeRedirect(tc.target, testPublicDomain)
+		if !ok || got != tc.want {
+			t.Errorf("SafeRedirect(%q) = %q, %v; want %q", tc.target, got, ok, tc.want)
+			continue
+		}
+		// Whatever an accepted target resolves to stays on this site
+		w := httptest.NewRecorder()
+		http.Redirect(w, httptest.NewRequest("GET", "/login", nil), got, http.StatusSeeOther)
+		if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/") || strings.HasPrefix(loc, "//") || strings.Contains(loc, "://") {
+			t.Errorf("redirect to %q sent Location %q", tc.target, loc)
+		}
+	}
+
+	for _, target := range []string{
+		"",
+		"discussion",
+		"evil.example",
+		"/" + strings.Repeat("a", maxRedirectLength),
+		// Scheme-relative
+		"//evil.example",
+		"///evil.example",
+		"//dis.quest/discussion",
+		// Backslashes, which browsers read as slashes
+		"/\\evil.example",
+		"\\\\evil.example",
+		"/path\\..\\x",
+		"https:/\\evil.example",
+		"https://evil.example\\@dis.quest/",
+		// Whitespace and control characters, which browsers strip
+		"/\t/evil.example",
+		"/\n/evil.example",
+		"/\r\n/evil.example",
+		"/ /evil.example",
+		"\x00//evil.example",
+		" //evil.example",
+		// Encoded slashes and backslashes
+		"/%2F%2Fevil.example",
+		"/%2fevil.example",
+		"/%5Cevil.example",
+		"/%5cevil.example",
+		"/%252F%252Fevil.example",
+		"/%25252F%25252Fevil.example",
+		"/%09/evil.example",
+		"/%0A/evil.example",
+		// Dot segments
+		"/./evil",
+		"/.%2F/evil.example",
+		"/../../etc/passwd",
+		"/discussion/%2E%2E/admin",
+		// Absolute URLs elsewhere
+		"https://evil.example/",
+		"https://evil.example/https://dis.quest/",
+		"https://dis.quest.evil.example/",
+		"https://dis.quest@evil.example/",
+		"https://user@dis.quest/",
+		"https://dis.quest:8443/",
+		"http://dis.quest/discussion",
+		"https://dis.quest//evil.example",
+		"https://dis.quest/%2F%2Fevil.example",
+		// Other schemes
+		"javascript:alert(1)",
+		"JavaScript:alert(1)",
+		"data:text/html,<script>alert(1)</script>",
+		"mailto:someone@evil.example",
+	} {
+		if got, ok := SafeRedirect(target, testPublicDomain); ok {
+			t.Errorf("SafeRedirect(%q) = %q, want refused", target, got)
+		}
+	}
+}
+
+func TestSafeRedirectWithoutPublicDomain(t *testing.T) {
+	if got, ok := SafeRedirect("https://dis.quest/discussion", ""); ok {
+		t.Errorf("SafeRedirect of an absolute URL without a public domain = %q, want refused", got)
+	}
+	if got, ok := SafeRedirect("/discussion", ""); !ok || got != "/discussion" {
+		t.Errorf("SafeRedirect of a local path = %q, %v; want it kept", got, ok)
+	}
+}
+
+func TestIsLocalPath(t *testing.T) {
+	if !IsLocalPath("/discussion?tab=new") {
+		t.Error("IsLocalPath of a local path = false, want true")
+	}
+	if IsLocalPath("https://dis.quest/discussion") {
+		t.Error("IsLocalPath of an absolute URL = true, want false")
+	}
+}
@@ -0,0 +1,198 @@
+package jwtutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// ParseError describes why a token could not be parsed. It matches
+// ErrInvalidToken with errors.Is.
+type ParseError struct {
+	// Part is the token part that failed: "token", "header" or "payload"
+	Part string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrInvalidToken, e.Part, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidToken
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidToken
+}
+
+// rawClaims accepts the shapes the JWT spec allows: aud may be a string or
+// an array and the numeric dates may be floats
+type rawClaims struct {
+	Iss   string          `json:"iss"`
+	Sub   string          `json:"sub"`
+	Aud   json.RawMessage `json:"aud"`
+	Exp   float64         `json:"exp"`
+	Iat   float64         `json:"iat"`
+	Nbf   float64         `json:"nbf"`
+	Scope string          `json:"scope"`
+	Lxm   string          `json:"lxm"`
+	Cnf   struct {
+		Jkt string `json:"jkt"`
+	} `json:"cnf"`
+}
+
+// ParseClaims decodes the claims of a compact JWS without verifying its
+// signature. Malformed input returns a *ParseError rather than panicking.
+func ParseClaims(tokenString string) (*JWTClaims, error) {
+	d := getDecoder()
+	defer putDecoder(d)
+	claims := new(JWTClaims)
+	if err := d.parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ClaimsResult is one token's outcome in ParseClaimsBatch
+type ClaimsResult struct {
+	Claims JWTClaims
+	// Err is a *ParseError when the token is malformed, and Claims is then
+	// the zero value
+	Err error
+}
+
+// ParseClaimsBatch decodes the claims of many tokens like ParseClaims, for
+// consumers replaying tokens in bulk. Result i is for tokens[i]; a malformed
+// token fails alone. The batch reuses one decoder's buffers and allocates
+// its results at once rather than per token.
+func ParseClaimsBatch(tokens []string) []ClaimsResult {
+	results := make([]ClaimsResult, len(tokens))
+	d := getDecoder()
+	defer putDecoder(d)
+	for i, token := range tokens {
+		if err := d.parse(token, &results[i].Claims); err != nil {
+			results[i] = ClaimsResult{Err: err}
+		}
+	}
+	return results
+}
+
+// maxPooledBuffer bounds the buffers a pooled decoder keeps, so one huge
+// token does not pin its memory in the pool
+const maxPooledBuffer = 16 << 10
+
+// claimsDecoder holds the buffers decoding a token reuses
+type claimsDecoder struct {
+	src, buf []byte
+	header   struct {
+		Alg string `json:"alg"`
+	}
+	raw rawClaims
+}
+
+var decoderPool = sync.Pool{New: func() any { return new(claimsDecoder) }}
+
+func getDecoder() *claimsDecoder {
+	return decoderPool.Get().(*claimsDecoder)
+}
+
+func putDecoder(d *claimsDecoder) {
+	if cap(d.src) > maxPooledBuffer || cap(d.buf) > maxPooledBuffer {
+		return
+	}
+	decoderPool.Put(d)
+}
+
+// parse decodes tokenString's claims into claims
+func (d *claimsDecoder) parse(tokenString string, claims *JWTClaims) error {
+	headerSegment, rest, _ := strings.Cut(tokenString, ".")
+	payloadSegment, signature, found := strings.Cut(rest, ".")
+	if !found || strings.Contains(signature, ".") {
+		return &ParseError{Part: "token", Err: fmt.Errorf("expected 3 segments, got %d", strings.Count(tokenString, ".")+1)}
+	}
+
+	d.header.Alg = ""
+	if err := d.decodeSegment(headerSegment, &d.header); err != nil {
+		return &ParseError{Part: "header", Err: err}
+	}
+	if d.header.Alg == "" {
+		return &ParseError{Part: "header", Err: fmt.Errorf("missing alg")}
+	}
+
+	// Reset the claims, keeping the audience's buffer
+	d.raw = rawClaims{Aud: d.raw.Aud[:0]}
+	if err := d.decodeSegment(payloadSegment, &d.raw); err != nil {
+		return &ParseError{Part: "payload", Err: err}
+	}
+	aud, err := audiences(d.raw.Aud)
+	if err != nil {
+		return &ParseError{Part: "payload", Err: err}
+	}
+
+	*claims = JWTClaims{
+		Iss:   d.raw.Iss,
+		Sub:   d.raw.Sub,
+		Aud:   aud,
+		Exp:   numericDate(d.raw.Exp),
+		Iat:   numericDate(d.raw.Iat),
+		Nbf:   numericDate(d.raw.Nbf),
+		Scope: d.raw.Scope,
+		Lxm:   d.raw.Lxm,
+		Jkt:   d.raw.Cnf.Jkt,
+	}
+	return nil
+}
+
+// decodeSegment base64url-decodes a token segment, tolerating padding, and
+// unmarshals it as a JSON object
+func (d *claimsDecoder) decodeSegment(segment string, v any) error {
+	d.src = append(d.src[:0], strings.TrimRight(segment, "=")...)
+	if n := base64.RawURLEncoding.DecodedLen(len(d.src)); cap(d.buf) < n {
+		d.buf = make([]byte, n)
+	}
+	n, err := base64.RawURLEncoding.Decode(d.buf[:cap(d.buf)], d.src)
+	if err != nil {
+		return fmt.Errorf("bad base64: %w", err)
+	}
+	if err := json.Unmarshal(d.buf[:n], v); err != nil {
+		return fmt.Errorf("bad JSON: %w", err)
+	}
+	return nil
+}
+
+// audiences returns aud as a list, whether the token wrote it as a string
+// or an array
+func audiences(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var single string
+	if raw[0] == '"' && json.Unmarshal(raw, &single) == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("aud must be a string or an array of strings")
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// numericDate truncates a NumericDate to whole seconds, clamping values
+// outside the int64 range
+func numericDate(v float64) int64 {
+	switch {
+	case v >= math.MaxInt64:
+		return math.MaxInt64
+	case v <= math.MinInt64:
+		return math.MinInt64
+	}
+	return int64(v)
+}
@@ -0,0 +1,170 @@
+package jwtutil
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func segment(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestParseClaims(t *testing.T) {
+	header := segment(`{"alg":"ES256","typ":"JWT"}`)
+
+	tests := []struct {
+		name  string
+		token string
+		want  JWTClaims
+	}{
+		{
+			name:  "string audience",
+			token: header + "." + segment(`{"iss":"https://pds.example","sub":"did:plc:abc","aud":"did:web:dis.quest","exp":1700000000,"iat":1690000000,"scope":"atproto","lxm":"quest.dis.getTopics"}`) + ".sig",
+			want:  JWTClaims{Iss: "https://pds.example", Sub: "did:plc:abc", Aud: []string{"did:web:dis.quest"}, Exp: 1700000000, Iat: 1690000000, Scope: "atproto", Lxm: "quest.dis.getTopics"},
+		},
+		{
+			name:  "array audience and float dates",
+			token: header + "." + segment(`{"sub":"did:plc:abc","aud":["a","b"],"exp":1700000000.5}`) + ".",
+			want:  JWTClaims{Sub: "did:plc:abc", Aud: []string{"a", "b"}, Exp: 1700000000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClaims(tt.token)
+			if err != nil {
+				t.Fatalf("ParseClaims: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseClaims = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClaims_Malformed(t *testing.T) {
+	header := segment(`{"alg":"ES256"}`)
+
+	tests := []struct {
+		name  string
+		token string
+		part  string
+	}{
+		{"empty", "", "token"},
+		{"two segments", header + "." + segment(`{}`), "token"},
+		{"four segments", header + "." + segment(`{}`) + ".sig.extra", "token"},
+		{"bad header base64", "!!!." + segment(`{}`) + ".sig", "header"},
+		{"header missing alg", segment(`{"typ":"JWT"}`) + "." + segment(`{}`) + ".sig", "header"},
+		{"bad payload base64", header + ".%%%.sig", "payload"},
+		{"payload not an object", header + "." + segment(`[1,2]`) + ".sig", "payload"},
+		{"numeric audience", header + "." + segment(`{"aud":42}`) + ".sig", "payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseClaims(tt.token)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseClaims error = %v, want *ParseError", err)
+			}
+			if parseErr.Part != tt.part {
+				t.Errorf("Part = %q, want %q", parseErr.Part, tt.part)
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Error("error does not match ErrInvalidToken")
+			}
+		})
+	}
+}
+
+func FuzzParseClaims(f *testing.F) {
+	header := segment(`{"alg":"ES256"}`)
+	f.Add(header + "." + segment(`{"sub":"did:plc:abc","aud":["x"],"exp":1e300}`) + ".sig")
+	f.Add(header + "." + segment(`{"exp":-1e300}`) + ".")
+	f.Add("invalid.jwt.token")
+	f.Add("..")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := ParseClaims(token)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("error %v does not match ErrInvalidToken", err)
+			}
+			return
+		}
+		if claims == nil {
+			t.Error("nil claims without an error")
+		}
+	})
+}
+
+func TestParseClaimsBatch(t *testing.T) {
+	header := segment(`{"alg":"ES256"}`)
+	tokens := []string{
+		header + "." + segment(`{"iss":"https://pds.example","sub":"did:plc:abc","aud":["a","b"],"exp":1700000000,"cnf":{"jkt":"thumb"}}`) + ".sig",
+		"not.a.token.at.all",
+		// Nothing may carry over from the tokens decoded before
+		header + "." + segment(`{"sub":"did:plc:def"}`) + ".sig",
+		header + "." + segment(`{"aud":42}`) + ".sig",
+	}
+
+	results := ParseClaimsBatch(tokens)
+	if len(results) != len(tokens) {
+		t.Fatalf("got %d results for %d tokens", len(results), len(tokens))
+	}
+	for i, token := range tokens {
+		want, wantErr := ParseClaims(token)
+		got := results[i]
+		if (got.Err != nil) != (wantErr != nil) {
+			t.Errorf("token %d: error = %v, want %v", i, got.Err, wantErr)
+			continue
+		}
+		if got.Err != nil {
+			if !errors.Is(got.Err, ErrInvalidToken) || !reflect.DeepEqual(got.Claims, JWTClaims{}) {
+				t.Errorf("token %d: got %+v with %v, want zero claims and a parse error", i, got.Claims, got.Err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got.Claims, *want) {
+			t.Errorf("token %d: claims = %+v, want %+v", i, got.Claims, *want)
+		}
+	}
+	if !reflect.DeepEqual(results[2].Claims, JWTClaims{Sub: "did:plc:def"}) {
+		t.Errorf("claims leaked between tokens: %+v", results[2].Claims)
+	}
+}
+
+func benchmarkTokens(n int) []string {
+	header := segment(`{"alg":"ES256K","typ":"JWT"}`)
+	payload := segment(`{"iss":"did:plc:abc","aud":"did:web:dis.quest","exp":1700000000,"iat":1690000000,"lxm":"quest.dis.getTopics","jti":"0123456789abcdef"}`)
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = header + "." + payload + ".c2lnbmF0dXJl"
+	}
+	return tokens
+}
+
+func BenchmarkParseClaims(b *testing.B) {
+	tokens := benchmarkTokens(1000)
+	b.ReportAllocs()
+	for range b.N {
+		for _, token := range tokens {
+			if _, err := ParseClaims(token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseClaimsBatch(b *testing.B) {
+	tokens := benchmarkTokens(1000)
+	b.ReportAllocs()
+	for range b.N {
+		for _, result := range ParseClaimsBatch(tokens) {
+			if result.Err != nil {
+				b.Fatal(result.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+// DIDResolver finds the atproto signing key a DID's document publishes, as
+// the multibase multikey in its publicKeyMultibase
+type DIDResolver interface {
+	SigningKey(ctx context.Context, did string) (string, error)
+}
+
+// DIDResolverFunc adapts a function to a DIDResolver
+type DIDResolverFunc func(ctx context.Context, did string) (string, error)
+
+// SigningKey calls f
+func (f DIDResolverFunc) SigningKey(ctx context.Context, did string) (string, error) {
+	return f(ctx, did)
+}
+
+// VerifyWithKey verifies a token signed with key, an atproto signing key as
+// multikey.Parse returns, and checks its claims with Validate: against each
+// of opts, or only for expiry and issue time without them. Errors match
+// those of ParseAndValidateJWT.
+func VerifyWithKey(tokenString string, key crypto.PublicKey, opts ...ValidateOptions) (*JWTClaims, error) {
+	claims, err := ParseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// ParseClaims has checked there are three segments
+	dot := strings.LastIndexByte(tokenString, '.')
+	signingInput, signature := tokenString[:dot], tokenString[dot+1:]
+
+	headerSegment, _, _ := strings.Cut(signingInput, ".")
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(headerSegment)
+	if err != nil || json.Unmarshal(headerJSON, &header) != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	if alg := keyAlgorithm(key); alg == "" || header.Alg != alg {
+		return nil, fmt.Errorf("%w: %s token for a %s key", ErrBadSignature, header.Alg, keyAlgorithm(key))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrBadSignature)
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	if !multikey.Verify(key, digest[:], sig) {
+		return nil, ErrBadSignature
+	}
+
+	if len(opts) == 0 {
+		opts = []ValidateOptions{{}}
+	}
+	for _, o := range opts {
+		if err := Validate(claims, o); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// keyAlgorithm returns the JWS algorithm key signs with, "" for keys atproto
+// does not use
+func keyAlgorithm(key crypto.PublicKey) string {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P256() {
+			return "ES256"
+		}
+	case *secp256k1.PublicKey:
+		return "ES256K"
+	}
+	return ""
+}
+
+// DIDKeyCache verifies tokens issued by DIDs, such as atproto inter-service
+// auth tokens, against the atproto signing key in the issuer's DID document
+// rather than a JWKS. Each DID's key is used for up to the TTL after it was
+// resolved. A token the cached key cannot verify makes the cache resolve the
+// DID again, so rotated keys are picked up.
+type DIDKeyCache struct {
+	ttl      time.Duration
+	resolver DIDResolver
+	now      func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]cachedDIDKey
+}
+
+// cachedDIDKey is one DID's signing key
+type cachedDIDKey struct {
+	key      crypto.PublicKey
+	resolved time.Time
+}
+
+// NewDIDKeyCache creates a cache keeping each DID's signing key for ttl,
+// finding it with resolver
+func NewDIDKeyCache(ttl time.Duration, resolver DIDResolver) *DIDKeyCache {
+	return &DIDKeyCache{
+		ttl:      ttl,
+		resolver: resolver,
+		now:      time.Now,
+		keys:     map[string]cachedDIDKey{},
+	}
+}
+
+// Verify verifies a token like VerifyWithKey, with the signing key of the
+// DID in its iss claim. A service fragment on the issuer, as in
+// "did:web:labeler.example#atproto_labeler", is ignored.
+func (c *DIDKeyCache) Verify(ctx context.Context, tokenString string, opts ...ValidateOptions) (*JWTClaims, error) {
+	unverifiedClaims, err := ParseJWTWithoutVerification(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if unverifiedClaims.Iss == "" {
+		return nil, ErrMissingIssuer
+	}
+	did, _, _ := strings.Cut(unverifiedClaims.Iss, "#")
+	if !strings.HasPrefix(did, "did:") {
+		return nil, fmt.Errorf("%w: issuer %q is not a DID", ErrUnknownIssuer, unverifiedClaims.Iss)
+	}
+
+	key, cached, err := c.key(ctx, did, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := VerifyWithKey(tokenString, key, opts...)
+	// Only a signature the cached key cannot verify may be fixed by a
+	// fresh one
+	if err == nil || !cached || !errors.Is(err, ErrBadSignature) || !c.mayRefetch(did) {
+		return claims, err
+	}
+	// The DID may have rotated its key since it was cached
+	if key, _, err = c.key(ctx, did, true); err != nil {
+		return nil, err
+	}
+	return VerifyWithKey(tokenString, key, opts...)
+}
+
+// key returns did's signing key and whether it came from the cache. refresh
+// skips the cache.
+func (c *DIDKeyCache) key(ctx context.Context, did string, refresh bool) (crypto.PublicKey, bool, error) {
+	now := c.now()
+	c.mu.Lock()
+	entry, ok := c.keys[did]
+	c.mu.Unlock()
+	if ok && !refresh && now.Sub(entry.resolved) < c.ttl {
+		return entry.key, true, nil
+	}
+
+	signingKey, err := c.resolver.SigningKey(ctx, did)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %s: %w", ErrUnknownIssuer, did, err)
+	}
+	key, err := multikey.Parse(signingKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: signing key of %s: %w", ErrUnknownIssuer, did, err)
+	}
+	c.mu.Lock()
+	// Drop keys past their TTL so DIDs seen once do not pile up
+	for other, e := range c.keys {
+		if now.Sub(e.resolved) >= c.ttl {
+			delete(c.keys, other)
+		}
+	}
+	c.keys[did] = cachedDIDKey{key: key, resolved: now}
+	c.mu.Unlock()
+	return key, false, nil
+}
+
+// mayRefetch reports whether did's key was resolved long enough ago to
+// resolve it again early
+func (c *DIDKeyCache) mayRefetch(did string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.keys[did]
+	return !ok || c.now().Sub(entry.resolved) >= minRefetchInterval
+}
@@ -0,0 +1,138 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+func newK256Key(t *testing.T) (*secp256k1.PrivateKey, string) {
+	t.Helper()
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, multikey.Encode(multikey.K256, key.PubKey().SerializeCompressed())
+}
+
+func newP256Key(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, multikey.Encode(multikey.P256, elliptic.MarshalCompressed(elliptic.P256(), key.X, key.Y))
+}
+
+func didToken(t *testing.T, key any, iss string) string {
+	t.Helper()
+	token, err := SignServiceToken(key, iss, "did:web:dis.quest", "quest.dis.getTopics", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyWithKey(t *testing.T) {
+	k256, k256Multikey := newK256Key(t)
+	p256, p256Multikey := newP256Key(t)
+	k256Pub, err := multikey.Parse(k256Multikey)
+	if err != nil {
+		t.Fatalf("multikey.Parse: %v", err)
+	}
+	p256Pub, err := multikey.Parse(p256Multikey)
+	if err != nil {
+		t.Fatalf("multikey.Parse: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		key     any
+		pub     any
+		opts    []ValidateOptions
+		wantErr error
+	}{
+		"secp256k1":           {key: k256, pub: k256Pub},
+		"P-256":               {key: p256, pub: p256Pub},
+		"another key":         {key: p256, pub: k256Pub, wantErr: ErrBadSignature},
+		"matching options":    {key: k256, pub: k256Pub, opts: []ValidateOptions{{Audience: "did:web:dis.quest", Lxm: "quest.dis.getTopics"}}},
+		"another audience":    {key: k256, pub: k256Pub, opts: []ValidateOptions{{Audience: "did:web:elsewhere.example"}}, wantErr: ErrAudienceMismatch},
+		"another XRPC method": {key: k256, pub: k256Pub, opts: []ValidateOptions{{Lxm: "quest.dis.createTopic"}}, wantErr: ErrMethodMismatch},
+	} {
+		t.Run(name, func(t *testing.T) {
+			claims, err := VerifyWithKey(didToken(t, tc.key, "did:plc:alice"), tc.pub, tc.opts...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("VerifyWithKey error = %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && claims.Iss != "did:plc:alice" {
+				t.Errorf("claims = %+v, want the token's", claims)
+			}
+		})
+	}
+
+	token := didToken(t, k256, "did:plc:alice")
+	if _, err := VerifyWithKey(token[:strings.LastIndexByte(token, '.')+1], k256Pub); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unsigned token: error = %v, want ErrBadSignature", err)
+	}
+	if _, err := VerifyWithKey("not-a-token", k256Pub); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("garbage: error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestDIDKeyCache(t *testing.T) {
+	key, signingKey := newK256Key(t)
+	var resolved []string
+	resolver := DIDResolverFunc(func(_ context.Context, did string) (string, error) {
+		resolved = append(resolved, did)
+		if did != "did:plc:alice" {
+			return "", errors.New("no such DID")
+		}
+		return signingKey, nil
+	})
+	now := time.Now()
+	c := NewDIDKeyCache(time.Hour, resolver)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.Verify(ctx, didToken(t, key, "did:plc:alice#atproto_labeler")); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	}
+	if len(resolved) != 1 || resolved[0] != "did:plc:alice" {
+		t.Fatalf("resolved %v, want the issuer's DID once", resolved)
+	}
+
+	// A rotated key is picked up, but not within minRefetchInterval
+	rotated, rotatedMultikey := newK256Key(t)
+	signingKey = rotatedMultikey
+	if _, err := c.Verify(ctx, didToken(t, rotated, "did:plc:alice")); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Verify right after caching: error = %v, want ErrBadSignature", err)
+	}
+	now = now.Add(minRefetchInterval)
+	if _, err := c.Verify(ctx, didToken(t, rotated, "did:plc:alice")); err != nil {
+		t.Fatalf("Verify with a rotated key: %v", err)
+	}
+	if len(resolved) != 2 {
+		t.Errorf("resolved %d times, want a second resolution for the rotated key", len(resolved))
+	}
+
+	if _, err := c.Verify(ctx, didToken(t, key, "did:plc:mallory")); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("unresolvable DID: error = %v, want ErrUnknownIssuer", err)
+	}
+	if _, err := c.Verify(ctx, didToken(t, key, "https://pds.example")); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("issuer that is not a DID: error = %v, want ErrUnknownIssuer", err)
+	}
+	later := ValidateOptions{Now: func() time.Time { return time.Now().Add(2 * DefaultServiceTokenTTL) }}
+	if _, err := c.Verify(ctx, didToken(t, rotated, "did:plc:alice"), later); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Verify after expiry: error = %v, want ErrTokenExpired", err)
+	}
+}
@@ -0,0 +1,154 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// DPoPProofMaxAge is how far a proof's iat may stray from the clock either
+// way before the proof is refused
+const DPoPProofMaxAge = time.Minute
+
+var (
+	// ErrNotDPoPBound is returned when an access token has no cnf.jkt to
+	// bind it to a DPoP key
+	ErrNotDPoPBound = fmt.Errorf("token is not DPoP-bound")
+	// ErrInvalidDPoPProof is returned when a DPoP proof is malformed,
+	// badly signed, or does not match the request or token it came with
+	ErrInvalidDPoPProof = fmt.Errorf("invalid DPoP proof")
+	// ErrDPoPKeyMismatch is returned when a proof is signed with another
+	// key than the one its access token is bound to
+	ErrDPoPKeyMismatch = fmt.Errorf("DPoP proof key does not match token binding")
+)
+
+// dpopProofClaims are the claims RFC 9449 requires of a proof
+type dpopProofClaims struct {
+	JTI string `json:"jti"`
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	IAT int64  `json:"iat"`
+	ATH string `json:"ath"`
+}
+
+// ValidateWithDPoP checks that dpopProof, presented with accessToken on a
+// method request to url, proves possession of the key the token is bound
+// to: the proof must be a dpop+jwt signed by the public key in its header,
+// whose thumbprint is the token's cnf.jkt, and its htm, htu, iat and ath
+// must match the request, the clock and the token. It does not verify the
+// access token's own signature, nor remember proofs' jti to refuse replays;
+// verify the token with VerifyJWT or a KeyCache.
+func ValidateWithDPoP(_ context.Context, accessToken, dpopProof, method, url string) error {
+	claims, err := ParseClaims(accessToken)
+	if err != nil {
+		return err
+	}
+	if claims.Jkt == "" {
+		return ErrNotDPoPBound
+	}
+
+	key, proof, err := verifyDPoPProof(dpopProof)
+	if err != nil {
+		return err
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	if jkt := base64.RawURLEncoding.EncodeToString(thumbprint); jkt != claims.Jkt {
+		return fmt.Errorf("%w: proof key %s, token bound to %s", ErrDPoPKeyMismatch, jkt, claims.Jkt)
+	}
+
+	if proof.JTI == "" {
+		return fmt.Errorf("%w: missing jti", ErrInvalidDPoPProof)
+	}
+	if proof.HTM != method {
+		return fmt.Errorf("%w: htm is %q, request method is %q", ErrInvalidDPoPProof, proof.HTM, method)
+	}
+	if !sameHTU(proof.HTU, url) {
+		return fmt.Errorf("%w: htu is %q, request URL is %q", ErrInvalidDPoPProof, proof.HTU, url)
+	}
+	now := time.Now()
+	if iat := time.Unix(proof.IAT, 0); proof.IAT == 0 || iat.Before(now.Add(-DPoPProofMaxAge)) || iat.After(now.Add(DPoPProofMaxAge)) {
+		return fmt.Errorf("%w: iat %d is not within %s of now", ErrInvalidDPoPProof, proof.IAT, DPoPProofMaxAge)
+	}
+	sum := sha256.Sum256([]byte(accessToken))
+	if ath := base64.RawURLEncoding.EncodeToString(sum[:]); proof.ATH != ath {
+		return fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+	return nil
+}
+
+// verifyDPoPProof checks a proof's header and signature, returning the key
+// it was signed with and its claims
+func verifyDPoPProof(dpopProof string) (jwk.Key, *dpopProofClaims, error) {
+	msg, err := jws.Parse([]byte(dpopProof))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, nil, fmt.Errorf("%w: want one signature", ErrInvalidDPoPProof)
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	if headers.Type() != "dpop+jwt" {
+		return nil, nil, fmt.Errorf("%w: typ is %q, want dpop+jwt", ErrInvalidDPoPProof, headers.Type())
+	}
+	key := headers.JWK()
+	if key == nil {
+		return nil, nil, fmt.Errorf("%w: missing jwk header", ErrInvalidDPoPProof)
+	}
+	// A symmetric or private key would let anyone who saw the proof forge
+	// the next one
+	if key.KeyType() == jwa.OctetSeq {
+		return nil, nil, fmt.Errorf("%w: jwk must be an asymmetric public key", ErrInvalidDPoPProof)
+	}
+	if private, err := jwk.IsPrivateKey(key); err != nil || private {
+		return nil, nil, fmt.Errorf("%w: jwk must be an asymmetric public key", ErrInvalidDPoPProof)
+	}
+
+	payload, err := jws.Verify([]byte(dpopProof), jws.WithKey(headers.Algorithm(), key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	var claims dpopProofClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, nil, fmt.Errorf("%w: bad payload: %v", ErrInvalidDPoPProof, err)
+	}
+	return key, &claims, nil
+}
+
+// sameHTU reports whether a proof's htu names the request URL, comparing
+// them without query or fragment and normalizing scheme, host and default
+// port as RFC 9449 allows
+func sameHTU(htu, requestURL string) bool {
+	a, errA := normalizeHTU(htu)
+	b, errB := normalizeHTU(requestURL)
+	return errA == nil && errB == nil && a == b
+}
+
+func normalizeHTU(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "https" && port == "443") && !(scheme == "http" && port == "80") {
+		host = net.JoinHostPort(host, port)
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return scheme + "://" + host + path, nil
+}
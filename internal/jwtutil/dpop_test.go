@@ -0,0 +1,158 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const resource = "https://pds.example.com/xrpc/com.atproto.repo.createRecord"
+
+// boundToken signs an access token bound to dpopKey
+func boundToken(t *testing.T, dpopKey jwk.Key) string {
+	t.Helper()
+	thumbprint, err := dpopKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewBuilder().Issuer(issuer).Subject("did:plc:alice").Expiration(time.Now().Add(time.Hour)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = token.Set("cnf", map[string]any{"jkt": base64.RawURLEncoding.EncodeToString(thumbprint)})
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, signingKey(t, "server")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signed)
+}
+
+// dpopProof signs a proof with key, with claims changing the defaults for
+// accessToken and a POST to resource, and headers the default header
+func dpopProof(t *testing.T, key jwk.Key, accessToken string, claims func(map[string]any), headers func(jws.Headers)) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(accessToken))
+	payload := map[string]any{
+		"jti": "proof-1",
+		"htm": "POST",
+		"htu": resource,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	if claims != nil {
+		claims(payload)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := key.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := jws.NewHeaders()
+	_ = hdr.Set(jws.TypeKey, "dpop+jwt")
+	_ = hdr.Set(jws.JWKKey, pub)
+	if headers != nil {
+		headers(hdr)
+	}
+	signed, err := jws.Sign(body, jws.WithKey(jwa.ES256, key, jws.WithProtectedHeaders(hdr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signed)
+}
+
+func TestValidateWithDPoP(t *testing.T) {
+	dpopKey := signingKey(t, "")
+	token := boundToken(t, dpopKey)
+	other := signingKey(t, "")
+
+	tests := []struct {
+		name    string
+		proof   func() string
+		token   string
+		method  string
+		url     string
+		wantErr error
+	}{
+		{name: "valid", proof: func() string { return dpopProof(t, dpopKey, token, nil, nil) }},
+		{
+			name:  "htu with default port and query",
+			proof: func() string { return dpopProof(t, dpopKey, token, nil, nil) },
+			url:   "HTTPS://PDS.example.com:443/xrpc/com.atproto.repo.createRecord?repo=did:plc:alice",
+		},
+		{name: "another key", proof: func() string { return dpopProof(t, other, token, nil, nil) }, wantErr: ErrDPoPKeyMismatch},
+		{name: "another method", proof: func() string { return dpopProof(t, dpopKey, token, nil, nil) }, method: "GET", wantErr: ErrInvalidDPoPProof},
+		{
+			name:    "another URL",
+			proof:   func() string { return dpopProof(t, dpopKey, token, nil, nil) },
+			url:     "https://pds.example.com/xrpc/com.atproto.repo.deleteRecord",
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "stale",
+			proof:   func() string { return dpopProof(t, dpopKey, token, func(c map[string]any) { c["iat"] = time.Now().Add(-time.Hour).Unix() }, nil) },
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "for another token",
+			proof:   func() string { return dpopProof(t, dpopKey, "another-token", nil, nil) },
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "missing jti",
+			proof:   func() string { return dpopProof(t, dpopKey, token, func(c map[string]any) { delete(c, "jti") }, nil) },
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "not a DPoP proof",
+			proof:   func() string { return dpopProof(t, dpopKey, token, nil, func(h jws.Headers) { _ = h.Set(jws.TypeKey, "JWT") }) },
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name: "signed by another key than its header's",
+			proof: func() string {
+				return dpopProof(t, dpopKey, token, nil, func(h jws.Headers) {
+					pub, _ := other.PublicKey()
+					_ = h.Set(jws.JWKKey, pub)
+				})
+			},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "token not bound",
+			proof:   func() string { return dpopProof(t, dpopKey, token, nil, nil) },
+			token:   sign(t, signingKey(t, "server"), issuer),
+			wantErr: ErrNotDPoPBound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessToken, method, url := token, "POST", resource
+			if tt.token != "" {
+				accessToken = tt.token
+			}
+			if tt.method != "" {
+				method = tt.method
+			}
+			if tt.url != "" {
+				url = tt.url
+			}
+			err := ValidateWithDPoP(context.Background(), accessToken, tt.proof(), method, url)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("ValidateWithDPoP error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package jwtutil
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -16,38 +17,66 @@ var (
 	ErrMissingIssuer = fmt.Errorf("missing issuer in token")
 	// ErrInvalidToken is returned when the JWT is in an invalid format
 	ErrInvalidToken = fmt.Errorf("invalid token format")
+	// ErrBadSignature is returned when none of the issuer's keys verifies a
+	// token: it was forged, or signed with a key the issuer no longer
+	// publishes
+	ErrBadSignature = fmt.Errorf("token signature invalid")
+	// ErrUnknownIssuer is returned when an issuer's keys cannot be found,
+	// because it does not publish them or cannot be reached
+	ErrUnknownIssuer = fmt.Errorf("unknown token issuer")
 )
 
 // JWTClaims represents the claims we care about from a JWT token
 // (adapted from ATProto, but not limited to it)
 type JWTClaims struct {
-	Iss   string `json:"iss"`   // Issuer (PDS)
-	Sub   string `json:"sub"`   // Subject (DID)
-	Aud   string `json:"aud"`   // Audience
-	Exp   int64  `json:"exp"`   // Expiry time
-	Iat   int64  `json:"iat"`   // Issued at
-	Scope string `json:"scope"` // Token scope
+	Iss   string   `json:"iss"`   // Issuer (PDS)
+	Sub   string   `json:"sub"`   // Subject (DID)
+	Aud   []string `json:"aud"`   // Audiences
+	Exp   int64    `json:"exp"`   // Expiry time
+	Iat   int64    `json:"iat"`   // Issued at
+	Nbf   int64    `json:"nbf"`   // Not before
+	Scope string   `json:"scope"` // Token scope
+	Lxm   string   `json:"lxm"`   // XRPC method a service-auth token is bound to
+	Jkt   string   `json:"jkt"`   // Thumbprint of the DPoP key the token is bound to (cnf.jkt)
 }
 
-// ParseAndValidateJWT parses and validates a JWT token using the jwx library
-func ParseAndValidateJWT(_ context.Context, tokenString string, keySet jwk.Set) (*JWTClaims, error) {
+// ParseAndValidateJWT parses and validates a JWT token using the jwx library.
+// Given opts, the claims are checked with Validate instead of jwx's default
+// time checks, so its leeway applies. Errors match ErrInvalidToken,
+// ErrBadSignature, ErrTokenExpired, ErrTokenNotYetValid or one of Validate's
+// errors, so callers can tell a token to refresh from one to reject.
+func ParseAndValidateJWT(_ context.Context, tokenString string, keySet jwk.Set, opts ...ValidateOptions) (*JWTClaims, error) {
+	// Malformed tokens are told apart here, so jwx's errors below are
+	// about signatures and claims
+	if _, err := ParseClaims(tokenString); err != nil {
+		return nil, err
+	}
+
 	// Parse and verify the JWT with the provided key set
-	token, err := jwt.Parse([]byte(tokenString), jwt.WithKeySet(keySet))
+	parseOptions := []jwt.ParseOption{jwt.WithKeySet(keySet)}
+	if len(opts) > 0 {
+		parseOptions = append(parseOptions, jwt.WithValidate(false))
+	}
+	token, err := jwt.Parse([]byte(tokenString), parseOptions...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse and verify JWT: %w", err)
+		return nil, classifyParseError(err)
 	}
 
 	// Extract claims into our struct
 	claims := &JWTClaims{
 		Iss: token.Issuer(),
 		Sub: token.Subject(),
-		Exp: token.Expiration().Unix(),
-		Iat: token.IssuedAt().Unix(),
+		Aud: token.Audience(),
 	}
-
-	// Get audience (may be a string or []string)
-	if aud := token.Audience(); len(aud) > 0 {
-		claims.Aud = aud[0]
+	// Absent dates stay 0, so Validate can tell a token without exp
+	if exp := token.Expiration(); !exp.IsZero() {
+		claims.Exp = exp.Unix()
+	}
+	if iat := token.IssuedAt(); !iat.IsZero() {
+		claims.Iat = iat.Unix()
+	}
+	if nbf := token.NotBefore(); !nbf.IsZero() {
+		claims.Nbf = nbf.Unix()
 	}
 
 	// Extract scope from private claims
@@ -56,43 +85,49 @@ func ParseAndValidateJWT(_ context.Context, tokenString string, keySet jwk.Set)
 			claims.Scope = scope
 		}
 	}
+	if lxmClaim, ok := token.Get("lxm"); ok {
+		if lxm, ok := lxmClaim.(string); ok {
+			claims.Lxm = lxm
+		}
+	}
+	if cnfClaim, ok := token.Get("cnf"); ok {
+		if cnf, ok := cnfClaim.(map[string]any); ok {
+			claims.Jkt, _ = cnf["jkt"].(string)
+		}
+	}
 
+	for _, o := range opts {
+		if err := Validate(claims, o); err != nil {
+			return nil, err
+		}
+	}
 	return claims, nil
 }
 
+// classifyParseError wraps an error jwt.Parse returned for a well-formed
+// token in the matching sentinel. jwx checks the signature before the
+// claims, so a claims error means the token is genuine.
+func classifyParseError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired()):
+		return fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	case errors.Is(err, jwt.ErrTokenNotYetValid()), errors.Is(err, jwt.ErrInvalidIssuedAt()):
+		return fmt.Errorf("%w: %w", ErrTokenNotYetValid, err)
+	case jwt.IsValidationError(err):
+		return fmt.Errorf("invalid token claims: %w", err)
+	default:
+		// Includes tokens whose key ID the key set lacks
+		return fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+}
+
 // ParseJWTWithoutVerification extracts claims from a JWT without verification
 // Note: This should only be used in development or for extracting issuer info to fetch keys
 func ParseJWTWithoutVerification(tokenString string) (*JWTClaims, error) {
-	// Parse JWT without verification
-	token, err := jwt.Parse([]byte(tokenString), jwt.WithVerify(false), jwt.WithValidate(false))
+	claims, err := ParseClaims(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWT: %w", err)
 	}
-
-	claims := &JWTClaims{
-		Iss: token.Issuer(),
-		Sub: token.Subject(),
-	}
-
-	if !token.Expiration().IsZero() {
-		claims.Exp = token.Expiration().Unix()
-	}
-	if !token.IssuedAt().IsZero() {
-		claims.Iat = token.IssuedAt().Unix()
-	}
-
-	// Get audience
-	if aud := token.Audience(); len(aud) > 0 {
-		claims.Aud = aud[0]
-	}
-
-	// Extract scope
-	if scopeClaim, ok := token.Get("scope"); ok {
-		if scope, ok := scopeClaim.(string); ok {
-			claims.Scope = scope
-		}
-	}
-
 	return claims, nil
 }
 
@@ -113,35 +148,13 @@ func ExtractDIDFromJWT(tokenString string) (string, error) {
 
 // GetJWKSFromIssuer fetches the JWKS from an issuer's well-known endpoint
 func GetJWKSFromIssuer(ctx context.Context, issuer string) (jwk.Set, error) {
-	jwksURL := fmt.Sprintf("%s/.well-known/jwks.json", issuer)
-
-	// Fetch the JWKS
-	set, err := jwk.Fetch(ctx, jwksURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch JWKS from %s: %w", jwksURL, err)
-	}
-
-	return set, nil
+	return Config{}.Resolve(ctx, issuer)
 }
 
-// VerifyJWT verifies a JWT token by fetching JWKS from the issuer
-func VerifyJWT(ctx context.Context, tokenString string) (*JWTClaims, error) {
-	// First, parse without verification to get the issuer
-	unverifiedClaims, err := ParseJWTWithoutVerification(tokenString)
-	if err != nil {
-		return nil, err
-	}
-
-	if unverifiedClaims.Iss == "" {
-		return nil, fmt.Errorf("missing issuer")
-	}
-
-	// Fetch JWKS from the issuer
-	keySet, err := GetJWKSFromIssuer(ctx, unverifiedClaims.Iss)
-	if err != nil {
-		return nil, err
-	}
-
-	// Now parse and verify with the proper keys
-	return ParseAndValidateJWT(ctx, tokenString, keySet)
+// VerifyJWT verifies a JWT token by fetching JWKS from the issuer, checking
+// its claims against opts when given. The keys are fetched on every call;
+// verify with a KeyCache to keep them between calls, and with a Config to
+// fetch them differently.
+func VerifyJWT(ctx context.Context, tokenString string, opts ...ValidateOptions) (*JWTClaims, error) {
+	return Config{}.VerifyJWT(ctx, tokenString, opts...)
 }
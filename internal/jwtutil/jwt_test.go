@@ -2,7 +2,13 @@ package jwtutil
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
 func TestExtractDIDFromJWT_InvalidToken(t *testing.T) {
@@ -21,3 +27,47 @@ func TestVerifyJWT_InvalidToken(t *testing.T) {
 		t.Fatal("expected error with invalid token")
 	}
 }
+
+func TestParseAndValidateJWT_Errors(t *testing.T) {
+	key := signingKey(t, "k1")
+	keys := publicSet(t, key)
+	signed := func(signer jwk.Key, build func(*jwt.Builder) *jwt.Builder) string {
+		token, err := build(jwt.NewBuilder().Issuer(issuer).Subject("did:plc:alice")).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, signer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	valid := func(b *jwt.Builder) *jwt.Builder { return b.Expiration(time.Now().Add(time.Hour)) }
+	expired := func(b *jwt.Builder) *jwt.Builder { return b.Expiration(time.Now().Add(-time.Hour)) }
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"malformed", "invalid.jwt.token", ErrInvalidToken},
+		{"unpublished key", signed(signingKey(t, "k2"), valid), ErrBadSignature},
+		{"forged with a published key ID", signed(signingKey(t, "k1"), valid), ErrBadSignature},
+		{"forged and expired", signed(signingKey(t, "k1"), expired), ErrBadSignature},
+		{"expired", signed(key, expired), ErrTokenExpired},
+		{"not yet valid", signed(key, func(b *jwt.Builder) *jwt.Builder { return valid(b).NotBefore(time.Now().Add(time.Hour)) }), ErrTokenNotYetValid},
+		{"issued in the future", signed(key, func(b *jwt.Builder) *jwt.Builder { return valid(b).IssuedAt(time.Now().Add(time.Hour)) }), ErrTokenNotYetValid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAndValidateJWT(context.Background(), tt.token, keys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ParseAndValidateJWT(context.Background(), signed(key, valid), keys); err != nil {
+		t.Errorf("valid token refused: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package jwtutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// minRefetchInterval limits how often a token the cached keys cannot verify
+// makes the cache fetch its issuer's keys early, so a stream of bad tokens
+// cannot turn into a stream of fetches
+const minRefetchInterval = time.Minute
+
+// maxCachedIssuers bounds how many issuers' keys a KeyCache keeps. Issuers
+// come from unverified iss claims, so any token could otherwise add one.
+const maxCachedIssuers = 1024
+
+// KeyCache keeps issuers' JWKS between verifications. Each issuer's keys
+// are used for up to the TTL after they were fetched; Run refreshes them in
+// the background before then, so verification rarely waits on a fetch. A
+// token signed with a key the cached set lacks makes the cache fetch the
+// issuer's keys again, so rotated keys are picked up. Concurrent misses for
+// one issuer share a single fetch.
+type KeyCache struct {
+	ttl        time.Duration
+	fetch      func(ctx context.Context, issuer string) (jwk.Set, error)
+	allow      func(issuer string) bool
+	now        func() time.Time
+	maxIssuers int
+
+	mu       sync.Mutex
+	issuers  map[string]*cachedKeys
+	fetching map[string]*keysFetch
+}
+
+// cachedKeys is one issuer's key set
+type cachedKeys struct {
+	set     jwk.Set
+	fetched time.Time
+	used    time.Time
+}
+
+// keysFetch is a fetch of one issuer's keys in flight
+type keysFetch struct {
+	done chan struct{}
+	set  jwk.Set
+	err  error
+}
+
+// NewKeyCache creates a cache keeping each issuer's keys for ttl, fetching
+// them from the issuer's well-known endpoint
+func NewKeyCache(ttl time.Duration) *KeyCache {
+	return NewKeyCacheWithConfig(ttl, Config{})
+}
+
+// NewKeyCacheWithConfig creates a cache keeping each issuer's keys for ttl,
+// finding them as cfg says. Only the keys of cfg.Issuers are fetched when it
+// is set.
+func NewKeyCacheWithConfig(ttl time.Duration, cfg Config) *KeyCache {
+	return &KeyCache{
+		ttl:        ttl,
+		fetch:      cfg.Resolve,
+		allow:      cfg.allows,
+		now:        time.Now,
+		maxIssuers: maxCachedIssuers,
+		issuers:    map[string]*cachedKeys{},
+		fetching:   map[string]*keysFetch{},
+	}
+}
+
+// Keys returns issuer's key set, fetching it if it is not cached or older
+// than the TTL
+func (c *KeyCache) Keys(ctx context.Context, issuer string) (jwk.Set, error) {
+	set, _, err := c.keys(ctx, issuer, false)
+	return set, err
+}
+
+// Verify verifies a token like VerifyJWT, but with its issuer's cached keys
+func (c *KeyCache) Verify(ctx context.Context, tokenString string, opts ...ValidateOptions) (*JWTClaims, error) {
+	unverifiedClaims, err := ParseJWTWithoutVerification(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if unverifiedClaims.Iss == "" {
+		return nil, ErrMissingIssuer
+	}
+
+	keySet, cached, err := c.keys(ctx, unverifiedClaims.Iss, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := ParseAndValidateJWT(ctx, tokenString, keySet, opts...)
+	// Only a signature the cached keys cannot verify may be fixed by
+	// fresh keys
+	if err == nil || !cached || !errors.Is(err, ErrBadSignature) || !c.mayRefetch(unverifiedClaims.Iss) {
+		return claims, err
+	}
+	// The issuer may have rotated its keys since they were cached
+	if keySet, _, err = c.keys(ctx, unverifiedClaims.Iss, true); err != nil {
+		return nil, err
+	}
+	return ParseAndValidateJWT(ctx, tokenString, keySet, opts...)
+}
+
+// keys returns issuer's key set and whether it came from the cache. refresh
+// skips the cache.
+func (c *KeyCache) keys(ctx context.Context, issuer string, refresh bool) (jwk.Set, bool, error) {
+	now := c.now()
+	var set jwk.Set
+	fresh := false
+	c.mu.Lock()
+	if entry, ok := c.issuers[issuer]; ok {
+		entry.used = now
+		set, fresh = entry.set, now.Sub(entry.fetched) < c.ttl
+	}
+	c.mu.Unlock()
+	if fresh && !refresh {
+		return set, true, nil
+	}
+	if !c.allow(issuer) {
+		return nil, false, fmt.Errorf("%w: %s is not an allowed issuer", ErrUnknownIssuer, issuer)
+	}
+
+	set, err := c.fetchKeys(ctx, issuer)
+	if err != nil {
+		return nil, false, err
+	}
+	c.mu.Lock()
+	c.store(issuer, set, now)
+	c.mu.Unlock()
+	return set, false, nil
+}
+
+// fetchKeys fetches issuer's keys, or waits for a fetch of them already in
+// flight, so a burst of tokens from an uncached issuer fetches once
+func (c *KeyCache) fetchKeys(ctx context.Context, issuer string) (jwk.Set, error) {
+	c.mu.Lock()
+	call, ok := c.fetching[issuer]
+	if !ok {
+		call = &keysFetch{done: make(chan struct{})}
+		c.fetching[issuer] = call
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		call.set, call.err = c.fetch(ctx, issuer)
+		c.mu.Lock()
+		delete(c.fetching, issuer)
+		c.mu.Unlock()
+		close(call.done)
+		return call.set, call.err
+	}
+
+	select {
+	case <-call.done:
+		return call.set, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// store caches issuer's keys. A new issuer first makes room: issuers no
+// token has needed for a TTL are dropped and, if the cache is still full,
+// so is the least recently used. c.mu must be held.
+func (c *KeyCache) store(issuer string, set jwk.Set, now time.Time) {
+	if _, ok := c.issuers[issuer]; !ok {
+		var lru string
+		for other, e := range c.issuers {
+			if now.Sub(e.used) >= c.ttl {
+				delete(c.issuers, other)
+			} else if lru == "" || e.used.Before(c.issuers[lru].used) {
+				lru = other
+			}
+		}
+		if len(c.issuers) >= c.maxIssuers && lru != "" {
+			delete(c.issuers, lru)
+		}
+	}
+	c.issuers[issuer] = &cachedKeys{set: set, fetched: now, used: now}
+}
+
+// mayRefetch reports whether issuer's keys were fetched long enough ago to
+// fetch them again early
+func (c *KeyCache) mayRefetch(issuer string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.issuers[issuer]
+	return !ok || c.now().Sub(entry.fetched) >= minRefetchInterval
+}
+
+// Refresh fetches again the keys of issuers past half their TTL, and drops
+// those no token has needed for a TTL. An issuer whose keys cannot be
+// fetched keeps its cached keys until they expire.
+func (c *KeyCache) Refresh(ctx context.Context) error {
+	now := c.now()
+	var due []string
+	c.mu.Lock()
+	for issuer, entry := range c.issuers {
+		switch {
+		case now.Sub(entry.used) >= c.ttl:
+			delete(c.issuers, issuer)
+		case now.Sub(entry.fetched) >= c.ttl/2:
+			due = append(due, issuer)
+		}
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, issuer := range due {
+		set, err := c.fetchKeys(ctx, issuer)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("refreshing keys of %s: %w", issuer, err))
+			continue
+		}
+		c.mu.Lock()
+		if entry, ok := c.issuers[issuer]; ok {
+			entry.set, entry.fetched = set, now
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Run refreshes every interval until ctx is done. Refresh errors are passed
+// to onError.
+func (c *KeyCache) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				onError(err)
+			}
+		}
+	}
+}
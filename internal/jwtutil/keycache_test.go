@@ -0,0 +1,289 @@
+package jwtutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const issuer = "https://pds.example.com"
+
+// signingKey generates a P-256 key with kid
+func signingKey(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = key.Set(jwk.KeyIDKey, kid)
+	_ = key.Set(jwk.AlgorithmKey, jwa.ES256)
+	return key
+}
+
+// publicSet returns the public JWKS of keys
+func publicSet(t *testing.T, keys ...jwk.Key) jwk.Set {
+	t.Helper()
+	set := jwk.NewSet()
+	for _, key := range keys {
+		pub, err := key.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = set.AddKey(pub)
+	}
+	return set
+}
+
+func sign(t *testing.T, key jwk.Key, iss string) string {
+	t.Helper()
+	token, err := jwt.NewBuilder().Issuer(iss).Subject("did:plc:alice").Expiration(time.Now().Add(time.Hour)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signed)
+}
+
+// fakeIssuers serves key sets by issuer and counts fetches
+type fakeIssuers struct {
+	sets    map[string]jwk.Set
+	fetches int
+	err     error
+}
+
+func (f *fakeIssuers) fetch(_ context.Context, iss string) (jwk.Set, error) {
+	f.fetches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	set, ok := f.sets[iss]
+	if !ok {
+		return nil, errors.New("no such issuer")
+	}
+	return set, nil
+}
+
+func newTestCache(issuers *fakeIssuers, now *time.Time) *KeyCache {
+	c := NewKeyCache(time.Hour)
+	c.fetch = issuers.fetch
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestKeyCacheVerify(t *testing.T) {
+	key := signingKey(t, "k1")
+	issuers := &fakeIssuers{sets: map[string]jwk.Set{issuer: publicSet(t, key)}}
+	now := time.Now()
+	c := newTestCache(issuers, &now)
+	ctx := context.Background()
+
+	for range 3 {
+		claims, err := c.Verify(ctx, sign(t, key, issuer))
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if claims.Sub != "did:plc:alice" || claims.Iss != issuer {
+			t.Errorf("claims = %+v", claims)
+		}
+	}
+	if issuers.fetches != 1 {
+		t.Errorf("fetched %d times, want the keys cached after one fetch", issuers.fetches)
+	}
+
+	// Keys past the TTL are fetched again
+	now = now.Add(time.Hour)
+	if _, err := c.Verify(ctx, sign(t, key, issuer)); err != nil || issuers.fetches != 2 {
+		t.Errorf("expected expired keys fetched again, fetched %d times (%v)", issuers.fetches, err)
+	}
+}
+
+func TestKeyCacheRotation(t *testing.T) {
+	old, rotated := signingKey(t, "old"), signingKey(t, "new")
+	issuers := &fakeIssuers{sets: map[string]jwk.Set{issuer: publicSet(t, old)}}
+	now := time.Now()
+	c := newTestCache(issuers, &now)
+	ctx := context.Background()
+	if _, err := c.Verify(ctx, sign(t, old, issuer)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	issuers.sets[issuer] = publicSet(t, rotated)
+	// Right after a fetch, an unknown key is refused without fetching again
+	if _, err := c.Verify(ctx, sign(t, rotated, issuer)); err == nil || issuers.fetches != 1 {
+		t.Errorf("expected an early refetch to wait, fetched %d times (%v)", issuers.fetches, err)
+	}
+	now = now.Add(minRefetchInterval)
+	if _, err := c.Verify(ctx, sign(t, rotated, issuer)); err != nil || issuers.fetches != 2 {
+		t.Errorf("expected the rotated key fetched, fetched %d times (%v)", issuers.fetches, err)
+	}
+	// A token signed with a key the issuer never published stays refused
+	now = now.Add(minRefetchInterval)
+	if _, err := c.Verify(ctx, sign(t, signingKey(t, "forged"), issuer)); err == nil {
+		t.Error("expected a token signed with an unpublished key to be refused")
+	}
+}
+
+func TestKeyCacheRefresh(t *testing.T) {
+	key := signingKey(t, "k1")
+	other := "https://other.example.com"
+	issuers := &fakeIssuers{sets: map[string]jwk.Set{issuer: publicSet(t, key), other: publicSet(t, key)}}
+	start := time.Now()
+	now := start
+	c := newTestCache(issuers, &now)
+	ctx := context.Background()
+	for _, iss := range []string{issuer, other} {
+		if _, err := c.Keys(ctx, iss); err != nil {
+			t.Fatalf("Keys: %v", err)
+		}
+	}
+
+	// Nothing is due before half the TTL
+	now = start.Add(20 * time.Minute)
+	if err := c.Refresh(ctx); err != nil || issuers.fetches != 2 {
+		t.Fatalf("Refresh fetched %d times (%v)", issuers.fetches, err)
+	}
+	if _, err := c.Keys(ctx, issuer); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+
+	// Both issuers are due; a failed refresh keeps the cached keys
+	now = start.Add(40 * time.Minute)
+	issuers.err = errors.New("connection refused")
+	if err := c.Refresh(ctx); err == nil || issuers.fetches != 4 {
+		t.Fatalf("Refresh fetched %d times (%v), want two failed fetches", issuers.fetches, err)
+	}
+	issuers.err = nil
+	if _, err := c.Keys(ctx, issuer); err != nil || issuers.fetches != 4 {
+		t.Fatalf("expected the cached keys kept, fetched %d times (%v)", issuers.fetches, err)
+	}
+
+	// The other issuer went unused for a TTL and is dropped; the refreshed
+	// issuer is served without a fetch past its original expiry
+	now = start.Add(61 * time.Minute)
+	if err := c.Refresh(ctx); err != nil || issuers.fetches != 5 {
+		t.Fatalf("Refresh fetched %d times (%v), want one", issuers.fetches, err)
+	}
+	if _, err := c.Keys(ctx, issuer); err != nil || issuers.fetches != 5 {
+		t.Errorf("expected refreshed keys cached, fetched %d times (%v)", issuers.fetches, err)
+	}
+	if _, err := c.Keys(ctx, other); err != nil || issuers.fetches != 6 {
+		t.Errorf("expected the dropped issuer fetched again, fetched %d times (%v)", issuers.fetches, err)
+	}
+}
+
+func TestKeyCacheBounded(t *testing.T) {
+	key := signingKey(t, "k1")
+	issuers := &fakeIssuers{sets: map[string]jwk.Set{}}
+	for _, iss := range []string{"https://a.example", "https://b.example", "https://c.example"} {
+		issuers.sets[iss] = publicSet(t, key)
+	}
+	now := time.Now()
+	c := newTestCache(issuers, &now)
+	c.maxIssuers = 2
+	ctx := context.Background()
+
+	for _, iss := range []string{"https://a.example", "https://b.example"} {
+		if _, err := c.Keys(ctx, iss); err != nil {
+			t.Fatalf("Keys: %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	// a is used again, so b is the least recently used when c arrives
+	if _, err := c.Keys(ctx, "https://a.example"); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if _, err := c.Keys(ctx, "https://c.example"); err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if len(c.issuers) != 2 {
+		t.Fatalf("cached %d issuers, want 2", len(c.issuers))
+	}
+	if _, ok := c.issuers["https://b.example"]; ok {
+		t.Error("expected the least recently used issuer evicted")
+	}
+}
+
+func TestKeyCacheSharedFetch(t *testing.T) {
+	key := signingKey(t, "k1")
+	set := publicSet(t, key)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	c := NewKeyCache(time.Hour)
+	c.fetch = func(context.Context, string) (jwk.Set, error) {
+		fetches.Add(1)
+		<-release
+		return set, nil
+	}
+	token := sign(t, key, issuer)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	// Let every verification reach the fetch before it completes
+	for {
+		c.mu.Lock()
+		started := c.fetching[issuer] != nil
+		c.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want concurrent misses to share one fetch", n)
+	}
+}
+
+func TestKeyCacheAllowedIssuers(t *testing.T) {
+	key := signingKey(t, "k1")
+	var fetched []string
+	resolver := ResolverFunc(func(_ context.Context, iss string) (jwk.Set, error) {
+		fetched = append(fetched, iss)
+		return publicSet(t, key), nil
+	})
+	c := NewKeyCacheWithConfig(time.Hour, Config{Resolver: resolver, Issuers: []string{issuer + "/"}})
+	ctx := context.Background()
+
+	if _, err := c.Verify(ctx, sign(t, key, issuer)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := c.Verify(ctx, sign(t, key, "https://attacker.example")); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("Verify error = %v, want ErrUnknownIssuer", err)
+	}
+	if len(fetched) != 1 || fetched[0] != issuer {
+		t.Errorf("fetched %v, want only the allowed issuer", fetched)
+	}
+}
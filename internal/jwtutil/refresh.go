@@ -0,0 +1,73 @@
+package jwtutil
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// TimeUntilExpiry returns how long until token expires at now, negative once
+// it has. ok is false when the token is unreadable or has no expiry.
+func TimeUntilExpiry(token string, now time.Time) (d time.Duration, ok bool) {
+	claims, err := ParseClaims(token)
+	if err != nil || claims.Exp == 0 {
+		return 0, false
+	}
+	return time.Unix(claims.Exp, 0).Sub(now), true
+}
+
+// IsExpired reports whether token's expiry has passed. Unreadable tokens count
+// as expired; tokens without an expiry never expire.
+func IsExpired(token string) bool {
+	claims, err := ParseClaims(token)
+	if err != nil {
+		return true
+	}
+	return claims.Exp != 0 && !time.Now().Before(time.Unix(claims.Exp, 0))
+}
+
+// ShouldRefresh reports whether token expires within threshold, or has
+// already, so it should be renewed now
+func ShouldRefresh(token string, threshold time.Duration) bool {
+	return RefreshPolicy{Threshold: threshold}.ShouldRefresh(token)
+}
+
+// RefreshPolicy decides when a token is due for renewal. Tokens minted
+// together, e.g. after a deploy signs everyone in again, would otherwise all
+// come due at the same instant, so each token's threshold is pushed out by
+// its own share of Jitter.
+type RefreshPolicy struct {
+	// Threshold is how long before expiry a token is renewed
+	Threshold time.Duration
+	// Jitter widens Threshold by up to this much, by an amount fixed per
+	// token so repeated checks agree
+	Jitter time.Duration
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// ShouldRefresh reports whether token is due for renewal. Expired and
+// unreadable tokens always are; tokens without an expiry never are.
+func (p RefreshPolicy) ShouldRefresh(token string) bool {
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	claims, err := ParseClaims(token)
+	if err != nil {
+		return true
+	}
+	if claims.Exp == 0 {
+		return false
+	}
+	return time.Unix(claims.Exp, 0).Sub(now()) <= p.threshold(token)
+}
+
+// threshold is p.Threshold plus token's share of p.Jitter
+func (p RefreshPolicy) threshold(token string) time.Duration {
+	if p.Jitter <= 0 {
+		return p.Threshold
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(token))
+	return p.Threshold + time.Duration(h.Sum64()%uint64(p.Jitter))
+}
@@ -0,0 +1,74 @@
+package jwtutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// expiringToken builds an unsigned token that expires at exp, told apart
+// from others by jti
+func expiringToken(exp time.Time, jti int) string {
+	return segment(`{"alg":"ES256K"}`) + "." + segment(fmt.Sprintf(`{"sub":"did:plc:alice","exp":%d,"jti":"%d"}`, exp.Unix(), jti)) + ".sig"
+}
+
+func TestShouldRefresh(t *testing.T) {
+	now := time.Now()
+	noExpiry := segment(`{"alg":"ES256K"}`) + "." + segment(`{"sub":"did:plc:alice"}`) + ".sig"
+
+	if d, ok := TimeUntilExpiry(expiringToken(now.Add(time.Hour), 0), now); !ok || d <= 59*time.Minute {
+		t.Errorf("TimeUntilExpiry = %v, %v, want about an hour", d, ok)
+	}
+	if _, ok := TimeUntilExpiry(noExpiry, now); ok {
+		t.Error("TimeUntilExpiry of a token without exp reported an expiry")
+	}
+	if !IsExpired(expiringToken(now.Add(-time.Second), 0)) || IsExpired(expiringToken(now.Add(time.Hour), 0)) || IsExpired(noExpiry) || !IsExpired("garbage") {
+		t.Error("IsExpired disagrees with the tokens' expiries")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"far from expiry", expiringToken(now.Add(time.Hour), 0), false},
+		{"within the threshold", expiringToken(now.Add(4*time.Minute), 0), true},
+		{"expired", expiringToken(now.Add(-time.Minute), 0), true},
+		{"no expiry", noExpiry, false},
+		{"unreadable", "garbage", true},
+	}
+	for _, tt := range tests {
+		if got := ShouldRefresh(tt.token, 5*time.Minute); got != tt.want {
+			t.Errorf("%s: ShouldRefresh = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshPolicy_Jitter(t *testing.T) {
+	now := time.Now()
+	p := RefreshPolicy{Threshold: 5 * time.Minute, Jitter: 10 * time.Minute, Now: func() time.Time { return now }}
+
+	// Tokens minted together come due at different times, but each one
+	// consistently
+	due := 0
+	for i := range 100 {
+		token := expiringToken(now.Add(10*time.Minute), i)
+		got := p.ShouldRefresh(token)
+		if got != p.ShouldRefresh(token) {
+			t.Fatalf("token %d: ShouldRefresh changed between calls", i)
+		}
+		if got {
+			due++
+		}
+	}
+	if due == 0 || due == 100 {
+		t.Errorf("%d of 100 tokens due halfway through the jitter, want them spread out", due)
+	}
+
+	// Jitter only ever delays the threshold
+	for i := range 100 {
+		if !p.ShouldRefresh(expiringToken(now.Add(5*time.Minute), i)) || p.ShouldRefresh(expiringToken(now.Add(15*time.Minute+time.Second), i)) {
+			t.Fatalf("token %d due outside [Threshold, Threshold+Jitter)", i)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package jwtutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// DefaultWellKnownPath is where issuers publish their JWKS
+const DefaultWellKnownPath = "/.well-known/jwks.json"
+
+// Resolver finds the key set an issuer signs its tokens with
+type Resolver interface {
+	Resolve(ctx context.Context, issuer string) (jwk.Set, error)
+}
+
+// ResolverFunc adapts a function to a Resolver
+type ResolverFunc func(ctx context.Context, issuer string) (jwk.Set, error)
+
+// Resolve calls f
+func (f ResolverFunc) Resolve(ctx context.Context, issuer string) (jwk.Set, error) {
+	return f(ctx, issuer)
+}
+
+// Config sets how issuers' keys are found. The zero Config fetches them
+// from DefaultWellKnownPath with http.DefaultClient.
+type Config struct {
+	// Client fetches key sets, e.g. through a proxy. Nil uses
+	// http.DefaultClient.
+	Client *http.Client
+	// WellKnownPath is the path of an issuer's JWKS, DefaultWellKnownPath
+	// when empty
+	WellKnownPath string
+	// Resolver finds key sets instead of fetching them over HTTP, e.g. to
+	// keep tests off the network. Client and WellKnownPath are then unused.
+	Resolver Resolver
+	// Issuers, when set, are the only issuers whose keys are fetched.
+	// Issuers come from unverified tokens, so this keeps arbitrary tokens
+	// from making the server fetch from arbitrary URLs.
+	Issuers []string
+}
+
+// allows reports whether issuer's keys may be fetched
+func (c Config) allows(issuer string) bool {
+	if len(c.Issuers) == 0 {
+		return true
+	}
+	issuer = strings.TrimSuffix(issuer, "/")
+	for _, allowed := range c.Issuers {
+		if strings.TrimSuffix(allowed, "/") == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns issuer's key set from the Resolver, or fetched from the
+// issuer's well-known endpoint. Failures match ErrUnknownIssuer.
+func (c Config) Resolve(ctx context.Context, issuer string) (jwk.Set, error) {
+	if !c.allows(issuer) {
+		return nil, fmt.Errorf("%w: %s is not an allowed issuer", ErrUnknownIssuer, issuer)
+	}
+	if c.Resolver != nil {
+		set, err := c.Resolver.Resolve(ctx, issuer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrUnknownIssuer, issuer, err)
+		}
+		return set, nil
+	}
+
+	path := c.WellKnownPath
+	if path == "" {
+		path = DefaultWellKnownPath
+	}
+	jwksURL := strings.TrimSuffix(issuer, "/") + "/" + strings.TrimPrefix(path, "/")
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	set, err := jwk.Fetch(ctx, jwksURL, jwk.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch JWKS from %s: %w", ErrUnknownIssuer, jwksURL, err)
+	}
+	return set, nil
+}
+
+// VerifyJWT verifies a token like the package's VerifyJWT, with keys found
+// as c says
+func (c Config) VerifyJWT(ctx context.Context, tokenString string, opts ...ValidateOptions) (*JWTClaims, error) {
+	// First, parse without verification to get the issuer
+	unverifiedClaims, err := ParseJWTWithoutVerification(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if unverifiedClaims.Iss == "" {
+		return nil, ErrMissingIssuer
+	}
+
+	keySet, err := c.Resolve(ctx, unverifiedClaims.Iss)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAndValidateJWT(ctx, tokenString, keySet, opts...)
+}
@@ -0,0 +1,83 @@
+package jwtutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// countingTransport counts the requests it passes on
+type countingTransport struct {
+	requests int
+	next     http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return t.next.RoundTrip(req)
+}
+
+func TestConfigResolve(t *testing.T) {
+	key := signingKey(t, "k1")
+	keys := publicSet(t, key)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/keys/jwks" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(keys)
+	}))
+	defer srv.Close()
+	transport := &countingTransport{next: srv.Client().Transport}
+	ctx := context.Background()
+
+	cfg := Config{Client: &http.Client{Transport: transport}, WellKnownPath: "/keys/jwks"}
+	claims, err := cfg.VerifyJWT(ctx, sign(t, key, srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("VerifyJWT: %v", err)
+	}
+	if claims.Sub != "did:plc:alice" || transport.requests != 1 {
+		t.Errorf("claims = %+v after %d requests, want one through the configured client", claims, transport.requests)
+	}
+
+	// Without the custom path the default one is fetched, which this issuer lacks
+	if _, err := (Config{Client: cfg.Client}).Resolve(ctx, srv.URL); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("Resolve error = %v, want ErrUnknownIssuer from the default well-known path", err)
+	}
+}
+
+func TestConfigResolver(t *testing.T) {
+	key := signingKey(t, "k1")
+	var asked []string
+	cfg := Config{
+		// The client must go unused
+		Client: &http.Client{Transport: &countingTransport{}},
+		Resolver: ResolverFunc(func(_ context.Context, iss string) (jwk.Set, error) {
+			asked = append(asked, iss)
+			if iss != issuer {
+				return nil, errors.New("unknown issuer")
+			}
+			return publicSet(t, key), nil
+		}),
+	}
+
+	if _, err := cfg.VerifyJWT(context.Background(), sign(t, key, issuer)); err != nil {
+		t.Fatalf("VerifyJWT: %v", err)
+	}
+	c := NewKeyCacheWithConfig(time.Hour, cfg)
+	if _, err := c.Verify(context.Background(), sign(t, key, issuer)); err != nil {
+		t.Fatalf("KeyCache.Verify: %v", err)
+	}
+	if len(asked) != 2 || asked[0] != issuer {
+		t.Errorf("resolver asked for %v, want the token's issuer twice", asked)
+	}
+	if _, err := cfg.VerifyJWT(context.Background(), sign(t, key, "https://elsewhere.example.com")); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("VerifyJWT error = %v, want ErrUnknownIssuer", err)
+	}
+}
@@ -0,0 +1,88 @@
+package jwtutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+// DefaultServiceTokenTTL is how long a service token lives when no TTL is
+// given. Service tokens authorize one call, so they are kept short.
+const DefaultServiceTokenTTL = time.Minute
+
+// serviceTokenClaims are the claims of an atproto inter-service auth token
+type serviceTokenClaims struct {
+	Iss string `json:"iss"`
+	Aud string `json:"aud"`
+	Exp int64  `json:"exp"`
+	Iat int64  `json:"iat"`
+	Jti string `json:"jti"`
+	Lxm string `json:"lxm,omitempty"`
+}
+
+// SignServiceToken mints an atproto inter-service auth token: iss calling
+// the service aud, e.g. "did:web:api.bsky.app#bsky_appview", to run the
+// XRPC method lxm, within ttl (DefaultServiceTokenTTL when 0 or less). An
+// empty lxm leaves the token usable for any method, which services may
+// refuse. signingKey is iss's atproto signing key: an *ecdsa.PrivateKey on
+// P-256, signing ES256, or a *secp256k1.PrivateKey, signing ES256K. Either
+// way the signature is in the low-S form atproto requires.
+func SignServiceToken(signingKey any, iss, aud, lxm string, ttl time.Duration) (string, error) {
+	var alg string
+	switch key := signingKey.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported ECDSA curve %s, want P-256", key.Curve.Params().Name)
+		}
+		alg = "ES256"
+	case *secp256k1.PrivateKey:
+		alg = "ES256K"
+	default:
+		return "", fmt.Errorf("unsupported signing key %T", signingKey)
+	}
+	if iss == "" || aud == "" {
+		return "", fmt.Errorf("service token needs an issuer and an audience")
+	}
+	if ttl <= 0 {
+		ttl = DefaultServiceTokenTTL
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": alg})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(serviceTokenClaims{
+		Iss: iss,
+		Aud: aud,
+		Exp: now.Add(ttl).Unix(),
+		Iat: now.Unix(),
+		Jti: hex.EncodeToString(nonce),
+		Lxm: lxm,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := multikey.Sign(signingKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
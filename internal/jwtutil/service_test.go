@@ -0,0 +1,111 @@
+package jwtutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	k256ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const appView = "did:web:api.bsky.app#bsky_appview"
+
+func TestSignServiceTokenP256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough signatures that some would be high-S without normalizing
+	for range 20 {
+		token, err := SignServiceToken(key, "did:plc:alice", appView, "com.atproto.repo.getRecord", 0)
+		if err != nil {
+			t.Fatalf("SignServiceToken: %v", err)
+		}
+		parsed, err := jwt.Parse([]byte(token), jwt.WithKey(jwa.ES256, &key.PublicKey))
+		if err != nil {
+			t.Fatalf("token does not verify: %v", err)
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(token[strings.LastIndex(token, ".")+1:])
+		if s := new(big.Int).SetBytes(sig[32:]); s.Cmp(new(big.Int).Rsh(elliptic.P256().Params().N, 1)) > 0 {
+			t.Fatal("signature is not low-S")
+		}
+
+		claims, err := ParseClaims(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.Iss != "did:plc:alice" || !slices.Equal(claims.Aud, []string{appView}) || claims.Lxm != "com.atproto.repo.getRecord" {
+			t.Errorf("claims = %+v", claims)
+		}
+		if ttl := parsed.Expiration().Sub(parsed.IssuedAt()); ttl != DefaultServiceTokenTTL {
+			t.Errorf("ttl = %s, want %s", ttl, DefaultServiceTokenTTL)
+		}
+		if jti, ok := parsed.Get("jti"); !ok || jti == "" {
+			t.Error("token lacks a jti")
+		}
+	}
+}
+
+func TestSignServiceTokenK256(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := SignServiceToken(key, "did:plc:alice", appView, "", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SignServiceToken: %v", err)
+	}
+	dot := strings.LastIndex(token, ".")
+	sig, err := base64.RawURLEncoding.DecodeString(token[dot+1:])
+	if err != nil || len(sig) != 64 {
+		t.Fatalf("signature = %x (%v), want 64 bytes", sig, err)
+	}
+	var r, s secp256k1.ModNScalar
+	r.SetByteSlice(sig[:32])
+	s.SetByteSlice(sig[32:])
+	digest := sha256.Sum256([]byte(token[:dot]))
+	if s.IsOverHalfOrder() || !k256ecdsa.NewSignature(&r, &s).Verify(digest[:], key.PubKey()) {
+		t.Error("signature does not verify as low-S ES256K")
+	}
+	if !strings.HasPrefix(token, base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256K","typ":"JWT"}`))) {
+		t.Errorf("header = %s", token[:strings.Index(token, ".")])
+	}
+
+	claims, err := ParseClaims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Lxm != "" || claims.Exp-claims.Iat != 300 {
+		t.Errorf("claims = %+v, want no lxm and a five-minute TTL", claims)
+	}
+}
+
+func TestSignServiceTokenRefusals(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignServiceToken(p384, "did:plc:alice", appView, "", 0); err == nil {
+		t.Error("expected a P-384 key refused")
+	}
+	if _, err := SignServiceToken("secret", "did:plc:alice", appView, "", 0); err == nil {
+		t.Error("expected a non-key refused")
+	}
+	if _, err := SignServiceToken(p256, "did:plc:alice", "", "", 0); err == nil {
+		t.Error("expected a token without audience refused")
+	}
+}
@@ -0,0 +1,90 @@
+package jwtutil
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAudienceMismatch is returned when a token is addressed to another
+	// service
+	ErrAudienceMismatch = fmt.Errorf("token audience mismatch")
+	// ErrMethodMismatch is returned when a token is bound to another XRPC
+	// method, or to none where one is required
+	ErrMethodMismatch = fmt.Errorf("token lexicon method mismatch")
+	// ErrMissingExpiry is returned when a token has no expiry
+	ErrMissingExpiry = fmt.Errorf("missing expiry in token")
+	// ErrTokenExpired is returned when a token's expiry has passed
+	ErrTokenExpired = fmt.Errorf("token expired")
+	// ErrTokenNotYetValid is returned when a token was issued in the future
+	// or is not valid yet
+	ErrTokenNotYetValid = fmt.Errorf("token issued in the future")
+	// ErrMissingScope is returned when a token lacks a required scope
+	ErrMissingScope = fmt.Errorf("missing scope in token")
+)
+
+// ValidateOptions says what a token must claim to be accepted, beyond a
+// valid signature. ATProto service-auth tokens are minted by a user's PDS
+// for one service and, usually, one XRPC method, so a service checks both
+// before acting on one.
+type ValidateOptions struct {
+	// Audience is a DID the token must be addressed to, among any others,
+	// e.g. the service's did:web. Empty accepts any audience.
+	Audience string
+	// Lxm is the NSID of the XRPC method the token must be bound to by its
+	// lxm claim. Empty accepts tokens bound to any method or none.
+	Lxm string
+	// Leeway tolerates clock skew between the token's issuer and this
+	// server when checking exp, iat and nbf
+	Leeway time.Duration
+	// Scopes must all appear in the token's space-separated scope claim
+	Scopes []string
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// Validate checks verified claims against opts: the token must carry an
+// expiry that has not passed, must not be issued in the future or before its
+// nbf, and must match the audience, method and scopes opts ask for
+func Validate(claims *JWTClaims, opts ValidateOptions) error {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	at := now()
+
+	if claims.Exp == 0 {
+		return ErrMissingExpiry
+	}
+	if exp := time.Unix(claims.Exp, 0); !at.Before(exp.Add(opts.Leeway)) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, exp.UTC().Format(time.RFC3339))
+	}
+	if claims.Iat != 0 {
+		if iat := time.Unix(claims.Iat, 0); iat.After(at.Add(opts.Leeway)) {
+			return fmt.Errorf("%w: %s", ErrTokenNotYetValid, iat.UTC().Format(time.RFC3339))
+		}
+	}
+	if claims.Nbf != 0 {
+		if nbf := time.Unix(claims.Nbf, 0); nbf.After(at.Add(opts.Leeway)) {
+			return fmt.Errorf("%w: not before %s", ErrTokenNotYetValid, nbf.UTC().Format(time.RFC3339))
+		}
+	}
+	if opts.Audience != "" && !slices.Contains(claims.Aud, opts.Audience) {
+		return fmt.Errorf("%w: addressed to %q, want %q", ErrAudienceMismatch, claims.Aud, opts.Audience)
+	}
+	if opts.Lxm != "" && claims.Lxm != opts.Lxm {
+		if claims.Lxm == "" {
+			return fmt.Errorf("%w: token is not bound to a method, want %s", ErrMethodMismatch, opts.Lxm)
+		}
+		return fmt.Errorf("%w: bound to %s, want %s", ErrMethodMismatch, claims.Lxm, opts.Lxm)
+	}
+	granted := strings.Fields(claims.Scope)
+	for _, scope := range opts.Scopes {
+		if !slices.Contains(granted, scope) {
+			return fmt.Errorf("%w: %s", ErrMissingScope, scope)
+		}
+	}
+	return nil
+}
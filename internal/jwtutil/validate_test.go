@@ -0,0 +1,183 @@
+package jwtutil
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const service = "did:web:dis.quest"
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid := JWTClaims{Iss: "did:plc:alice", Aud: []string{service}, Exp: now.Unix() + 60, Iat: now.Unix(), Lxm: "quest.dis.createTopic", Scope: "atproto transition:generic"}
+	opts := ValidateOptions{Audience: service, Lxm: "quest.dis.createTopic", Now: func() time.Time { return now }}
+
+	tests := []struct {
+		name    string
+		claims  func(c *JWTClaims)
+		opts    func(o *ValidateOptions)
+		wantErr error
+	}{
+		{name: "valid"},
+		{name: "another audience", claims: func(c *JWTClaims) { c.Aud = []string{"did:web:elsewhere.example"} }, wantErr: ErrAudienceMismatch},
+		{name: "among several audiences", claims: func(c *JWTClaims) { c.Aud = []string{"did:web:elsewhere.example", service} }},
+		{name: "no audience", claims: func(c *JWTClaims) { c.Aud = nil }, wantErr: ErrAudienceMismatch},
+		{name: "another method", claims: func(c *JWTClaims) { c.Lxm = "quest.dis.deleteTopic" }, wantErr: ErrMethodMismatch},
+		{name: "no method", claims: func(c *JWTClaims) { c.Lxm = "" }, wantErr: ErrMethodMismatch},
+		{name: "any method", claims: func(c *JWTClaims) { c.Lxm = "" }, opts: func(o *ValidateOptions) { o.Lxm = "" }},
+		{name: "any audience", claims: func(c *JWTClaims) { c.Aud = []string{"did:web:elsewhere.example"} }, opts: func(o *ValidateOptions) { o.Audience = "" }},
+		{name: "no expiry", claims: func(c *JWTClaims) { c.Exp = 0 }, wantErr: ErrMissingExpiry},
+		{name: "expired", claims: func(c *JWTClaims) { c.Exp = now.Unix() - 10 }, wantErr: ErrTokenExpired},
+		{
+			name:   "expired within leeway",
+			claims: func(c *JWTClaims) { c.Exp = now.Unix() - 10 },
+			opts:   func(o *ValidateOptions) { o.Leeway = 30 * time.Second },
+		},
+		{name: "issued in the future", claims: func(c *JWTClaims) { c.Iat = now.Unix() + 10 }, wantErr: ErrTokenNotYetValid},
+		{
+			name:   "issued in the future within leeway",
+			claims: func(c *JWTClaims) { c.Iat = now.Unix() + 10 },
+			opts:   func(o *ValidateOptions) { o.Leeway = 30 * time.Second },
+		},
+		{name: "not yet valid", claims: func(c *JWTClaims) { c.Nbf = now.Unix() + 10 }, wantErr: ErrTokenNotYetValid},
+		{
+			name:   "not yet valid within leeway",
+			claims: func(c *JWTClaims) { c.Nbf = now.Unix() + 10 },
+			opts:   func(o *ValidateOptions) { o.Leeway = 30 * time.Second },
+		},
+		{name: "valid since nbf", claims: func(c *JWTClaims) { c.Nbf = now.Unix() - 10 }},
+		{name: "scopes granted", opts: func(o *ValidateOptions) { o.Scopes = []string{"atproto", "transition:generic"} }},
+		{name: "scope missing", opts: func(o *ValidateOptions) { o.Scopes = []string{"atproto", "transition:chat.bsky"} }, wantErr: ErrMissingScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, o := valid, opts
+			if tt.claims != nil {
+				tt.claims(&claims)
+			}
+			if tt.opts != nil {
+				tt.opts(&o)
+			}
+			if err := Validate(&claims, o); !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("Validate error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// serviceToken signs a service-auth token for lxm that expired expiredFor
+// ago
+func serviceToken(t *testing.T, key jwk.Key, lxm string, expiredFor time.Duration) string {
+	t.Helper()
+	token, err := jwt.NewBuilder().Issuer("did:plc:alice").Audience([]string{service}).
+		IssuedAt(time.Now().Add(-time.Minute)).Expiration(time.Now().Add(-expiredFor)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = token.Set("lxm", lxm)
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signed)
+}
+
+func TestParseAndValidateJWTOptions(t *testing.T) {
+	key := signingKey(t, "k1")
+	keys := publicSet(t, key)
+	ctx := context.Background()
+	opts := ValidateOptions{Audience: service, Lxm: "quest.dis.createTopic", Leeway: 30 * time.Second}
+
+	claims, err := ParseAndValidateJWT(ctx, serviceToken(t, key, "quest.dis.createTopic", -time.Minute), keys, opts)
+	if err != nil {
+		t.Fatalf("ParseAndValidateJWT: %v", err)
+	}
+	if claims.Lxm != "quest.dis.createTopic" || !slices.Equal(claims.Aud, []string{service}) {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// The leeway applies where jwx alone would refuse the token
+	skewed := serviceToken(t, key, "quest.dis.createTopic", 10*time.Second)
+	if _, err := ParseAndValidateJWT(ctx, skewed, keys); err == nil {
+		t.Error("expected an expired token refused without options")
+	}
+	if _, err := ParseAndValidateJWT(ctx, skewed, keys, opts); err != nil {
+		t.Errorf("expected a token expired within the leeway accepted: %v", err)
+	}
+
+	if _, err := ParseAndValidateJWT(ctx, serviceToken(t, key, "quest.dis.deleteTopic", -time.Minute), keys, opts); !errors.Is(err, ErrMethodMismatch) {
+		t.Errorf("ParseAndValidateJWT error = %v, want ErrMethodMismatch", err)
+	}
+
+	// Options replace jwx's time checks, so nbf must still be enforced
+	early, err := jwt.NewBuilder().Issuer("did:plc:alice").Audience([]string{service}).
+		NotBefore(time.Now().Add(5 * time.Minute)).Expiration(time.Now().Add(10 * time.Minute)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = early.Set("lxm", "quest.dis.createTopic")
+	signed, err := jwt.Sign(early, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAndValidateJWT(ctx, string(signed), keys, opts); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Errorf("ParseAndValidateJWT error = %v, want ErrTokenNotYetValid", err)
+	}
+
+	// A token without exp is refused as such, not as expired long ago
+	endless, err := jwt.NewBuilder().Issuer("did:plc:alice").Audience([]string{service}).IssuedAt(time.Now()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = endless.Set("lxm", "quest.dis.createTopic")
+	signed, err = jwt.Sign(endless, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAndValidateJWT(ctx, string(signed), keys, opts); !errors.Is(err, ErrMissingExpiry) {
+		t.Errorf("ParseAndValidateJWT error = %v, want ErrMissingExpiry", err)
+	}
+
+	// The service may be any one of the token's audiences
+	shared, err := jwt.NewBuilder().Issuer("did:plc:alice").Audience([]string{"did:web:other.example", service}).
+		Expiration(time.Now().Add(time.Minute)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = shared.Set("lxm", "quest.dis.createTopic")
+	signed, err = jwt.Sign(shared, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = ParseAndValidateJWT(ctx, string(signed), keys, opts)
+	if err != nil {
+		t.Fatalf("ParseAndValidateJWT with several audiences: %v", err)
+	}
+	if !slices.Equal(claims.Aud, []string{"did:web:other.example", service}) {
+		t.Errorf("Aud = %v, want both audiences", claims.Aud)
+	}
+}
+
+func TestKeyCacheVerifyClaimsNotRefetched(t *testing.T) {
+	key := signingKey(t, "k1")
+	issuers := &fakeIssuers{sets: map[string]jwk.Set{"did:plc:alice": publicSet(t, key)}}
+	now := time.Now()
+	c := newTestCache(issuers, &now)
+	opts := ValidateOptions{Audience: service, Lxm: "quest.dis.createTopic"}
+	ctx := context.Background()
+
+	if _, err := c.Verify(ctx, serviceToken(t, key, "quest.dis.createTopic", -time.Minute), opts); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	now = now.Add(minRefetchInterval)
+	if _, err := c.Verify(ctx, serviceToken(t, key, "quest.dis.deleteTopic", -time.Minute), opts); !errors.Is(err, ErrMethodMismatch) || issuers.fetches != 1 {
+		t.Errorf("Verify error = %v after %d fetches, want ErrMethodMismatch without a refetch", err, issuers.fetches)
+	}
+}
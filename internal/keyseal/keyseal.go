@@ -0,0 +1,64 @@
+// Package keyseal encrypts ECDSA private keys for storage. Each key is
+// sealed under the ID it is stored by, so a sealed key copied to another ID
+// will not open.
+package keyseal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// Sealer encrypts and decrypts keys with AES-GCM
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// New creates a sealer whose AES key is derived from secret and label, so
+// one secret can seal keys for several purposes that cannot read each
+// other's
+func New(secret []byte, label string) (*Sealer, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts key for id, returning the nonce followed by the ciphertext
+func (s *Sealer) Seal(id string, key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, der, []byte(id)), nil
+}
+
+// Open decrypts a key Seal sealed for id
+func (s *Sealer) Open(id string, sealed []byte) (*ecdsa.PrivateKey, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, errors.New("malformed sealed key")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	der, err := s.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sealed key: %w", err)
+	}
+	return x509.ParseECPrivateKey(der)
+}
@@ -0,0 +1,41 @@
+package keyseal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealer, err := New([]byte("secret"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealer.Seal("id-1", key)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	opened, err := sealer.Open("id-1", sealed)
+	if err != nil || !opened.Equal(key) {
+		t.Fatalf("Open = %v, %v", opened, err)
+	}
+
+	if _, err := sealer.Open("id-2", sealed); err == nil {
+		t.Error("key opened under another ID")
+	}
+	other, err := New([]byte("secret"), "another use")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Open("id-1", sealed); err == nil {
+		t.Error("key opened with another label")
+	}
+	if _, err := sealer.Open("id-1", sealed[:4]); err == nil {
+		t.Error("truncated key opened")
+	}
+}
@@ -0,0 +1,157 @@
+package labeler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+// Label values the instance emits. A leading "!" marks values every atproto
+// client understands without a labeler-defined description.
+const (
+	// ValueHide asks clients to hide the labeled content
+	ValueHide = "!hide"
+)
+
+// Version is the com.atproto.label.defs#label format version labels are
+// signed in
+const Version = 1
+
+// Label is a com.atproto.label.defs#label. A negation (Neg) withdraws an
+// earlier label with the same Src, URI and Val.
+type Label struct {
+	Ver int64  `json:"ver"`
+	Src string `json:"src"`
+	URI string `json:"uri"`
+	CID string `json:"cid,omitempty"`
+	Val string `json:"val"`
+	Neg bool   `json:"neg,omitempty"`
+	Cts string `json:"cts"`
+	Sig Bytes  `json:"sig,omitempty"`
+
+	// Seq orders labels in the subscribeLabels stream
+	Seq int64 `json:"-"`
+}
+
+// cbor returns the label as the map it is signed and streamed as,
+// leaving out the signature unless withSig is set
+func (l Label) cbor(withSig bool) map[string]any {
+	m := map[string]any{
+		"ver": l.Ver,
+		"src": l.Src,
+		"uri": l.URI,
+		"val": l.Val,
+		"cts": l.Cts,
+	}
+	if l.CID != "" {
+		m["cid"] = l.CID
+	}
+	if l.Neg {
+		m["neg"] = true
+	}
+	if withSig {
+		m["sig"] = []byte(l.Sig)
+	}
+	return m
+}
+
+// Bytes is binary data, written in JSON as atproto's {"$bytes": base64}
+type Bytes []byte
+
+// MarshalJSON implements json.Marshaler
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"$bytes": base64.RawStdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Bytes string `json:"$bytes"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(v.Bytes, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// Signer signs labels as the labeler identified by DID. The DID document
+// must publish the public key as its #atproto_label verification method.
+type Signer struct {
+	DID string
+	key *ecdsa.PrivateKey
+}
+
+// NewSigner returns a signer for did using key, a P-256 private key
+func NewSigner(did string, key *ecdsa.PrivateKey) (*Signer, error) {
+	if !strings.HasPrefix(did, "did:") {
+		return nil, fmt.Errorf("labeler DID %q is not a DID", did)
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("labeler signing key must be a P-256 key")
+	}
+	return &Signer{DID: did, key: key}, nil
+}
+
+// ParseSigningKey parses a PEM-encoded P-256 private key, as written by
+// `openssl ecparam -name prime256v1 -genkey -noout`, in SEC 1 or PKCS #8 form
+func ParseSigningKey(data string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("labeler signing key is not PEM encoded")
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("labeler signing key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("labeler signing key is not an ECDSA key")
+	}
+	return key, nil
+}
+
+// Sign stamps label with the signer's DID and the label format version and
+// signs it
+func (s *Signer) Sign(label *Label) error {
+	label.Ver = Version
+	label.Src = s.DID
+	digest := sha256.Sum256(dagcbor.Append(nil, label.cbor(false)))
+	sig, err := multikey.Sign(s.key, digest[:])
+	if err != nil {
+		return err
+	}
+	label.Sig = sig
+	return nil
+}
+
+// PublicKeyMultibase returns the signer's public key as a multibase
+// multikey, the form the DID document's #atproto_label method publishes
+func (s *Signer) PublicKeyMultibase() string {
+	pub := s.key.PublicKey
+	compressed := elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y)
+	return multikey.Encode(multikey.P256, compressed)
+}
+
+// Verify reports whether label carries a valid low-S signature by pub
+func Verify(label Label, pub *ecdsa.PublicKey) bool {
+	digest := sha256.Sum256(dagcbor.Append(nil, label.cbor(false)))
+	return multikey.Verify(pub, digest[:], label.Sig)
+}
@@ -0,0 +1,150 @@
+// Package labeler publishes moderation decisions as signed atproto labels,
+// so other services can subscribe to a community's moderation with
+// com.atproto.label.subscribeLabels or look labels up with queryLabels.
+package labeler
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxEmitAttempts bounds retries when another instance has stored a
+	// label at the seq this one picked
+	maxEmitAttempts = 3
+	// MaxQueryLimit is the most labels one queryLabels call returns
+	MaxQueryLimit = 250
+)
+
+// Store persists labels in seq order
+type Store interface {
+	// Append stores a label at label.Seq, failing if that seq is taken
+	Append(ctx context.Context, label Label) error
+	// Since returns up to limit labels with Seq > cursor, oldest first
+	Since(ctx context.Context, cursor int64, limit int) ([]Label, error)
+	// Latest returns the highest stored Seq, or zero when there are none
+	Latest(ctx context.Context) (int64, error)
+	// ForURI returns up to limit labels with Seq > cursor on uri, or on
+	// every URI starting with uri when prefix is set, oldest first
+	ForURI(ctx context.Context, uri string, prefix bool, cursor int64, limit int) ([]Label, error)
+}
+
+// Labeler signs, stores and announces labels
+type Labeler struct {
+	signer *Signer
+	store  Store
+	now    func() time.Time
+
+	// mu serializes emitting so seqs are stored in order
+	mu     sync.Mutex
+	last   int64
+	loaded bool
+	// changed is closed, and replaced, whenever a label is emitted
+	changed chan struct{}
+}
+
+// New returns a labeler that signs with signer and stores labels in store
+func New(signer *Signer, store Store) *Labeler {
+	return &Labeler{signer: signer, store: store, now: time.Now, changed: make(chan struct{})}
+}
+
+// DID returns the DID the labeler signs as
+func (l *Labeler) DID() string {
+	return l.signer.DID
+}
+
+// Emit signs and stores a label of val on uri, or its negation when neg is
+// set, and wakes streams waiting in Changed. An empty cid labels every
+// version of the record.
+func (l *Labeler) Emit(ctx context.Context, uri, cid, val string, neg bool) (Label, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded {
+		latest, err := l.store.Latest(ctx)
+		if err != nil {
+			return Label{}, err
+		}
+		l.last, l.loaded = latest, true
+	}
+
+	label := Label{
+		URI: uri,
+		CID: cid,
+		Val: val,
+		Neg: neg,
+		Cts: l.now().UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+	if err := l.signer.Sign(&label); err != nil {
+		return Label{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		label.Seq = l.last + 1
+		err := l.store.Append(ctx, label)
+		if err == nil {
+			break
+		}
+		latest, latestErr := l.store.Latest(ctx)
+		if latestErr != nil || latest < label.Seq || attempt == maxEmitAttempts {
+			return Label{}, err
+		}
+		l.last = latest
+	}
+	l.last = label.Seq
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return label, nil
+}
+
+// Changed returns a channel that is closed when this instance next emits a
+// label. Streams read it before reading the store so no label is missed.
+func (l *Labeler) Changed() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.changed
+}
+
+// Since returns up to limit stored labels after cursor, oldest first
+func (l *Labeler) Since(ctx context.Context, cursor int64, limit int) ([]Label, error) {
+	return l.store.Since(ctx, cursor, limit)
+}
+
+// Latest returns the seq of the newest stored label
+func (l *Labeler) Latest(ctx context.Context) (int64, error) {
+	return l.store.Latest(ctx)
+}
+
+// Query returns up to limit labels after cursor on URIs matching any of
+// patterns, oldest first. A pattern ending in "*" matches URIs starting
+// with the rest of it. When sources is non-empty and does not include the
+// labeler's DID nothing matches, since every stored label is its own.
+func (l *Labeler) Query(ctx context.Context, patterns, sources []string, cursor int64, limit int) ([]Label, error) {
+	if len(sources) > 0 && !slices.Contains(sources, l.DID()) {
+		return []Label{}, nil
+	}
+	seen := make(map[int64]bool)
+	labels := []Label{}
+	for _, pattern := range patterns {
+		uri, prefix := strings.CutSuffix(pattern, "*")
+		matched, err := l.store.ForURI(ctx, uri, prefix, cursor, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range matched {
+			if !seen[label.Seq] {
+				seen[label.Seq] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	slices.SortFunc(labels, func(a, b Label) int { return cmp.Compare(a.Seq, b.Seq) })
+	if len(labels) > limit {
+		labels = labels[:limit]
+	}
+	return labels, nil
+}
@@ -0,0 +1,27 @@
+package labeler
+
+import "github.com/jrschumacher/dis.quest/internal/dagcbor"
+
+// Each subscribeLabels message is a CBOR header followed by a CBOR body,
+// as in every atproto event stream
+const (
+	frameMessage = 1
+	frameError   = -1
+)
+
+// LabelsFrame encodes a #labels message carrying labels at seq
+func LabelsFrame(seq int64, labels ...Label) []byte {
+	items := make([]any, 0, len(labels))
+	for _, label := range labels {
+		items = append(items, label.cbor(true))
+	}
+	frame := dagcbor.Append(nil, map[string]any{"op": int64(frameMessage), "t": "#labels"})
+	return dagcbor.Append(frame, map[string]any{"seq": seq, "labels": items})
+}
+
+// ErrorFrame encodes an error message, such as FutureCursor, sent before
+// the server closes the stream
+func ErrorFrame(name, message string) []byte {
+	frame := dagcbor.Append(nil, map[string]any{"op": int64(frameError)})
+	return dagcbor.Append(frame, map[string]any{"error": name, "message": message})
+}
@@ -0,0 +1,260 @@
+// Package lexicon holds the quest.dis.* record types defined in lexicons/ and
+// decodes them from the generic maps records arrive as over XRPC and the
+// firehose
+package lexicon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Record collection NSIDs
+const (
+	TopicNSID         = "quest.dis.topic"
+	MessageNSID       = "quest.dis.message"
+	ParticipationNSID = "quest.dis.participation"
+)
+
+// Participation roles
+const (
+	RoleModerator   = "moderator"
+	RoleContributor = "contributor"
+	RoleFollower    = "follower"
+)
+
+// ErrInvalidRecord is matched by every error the FromMap functions return
+var ErrInvalidRecord = errors.New("invalid record")
+
+// FieldError reports a record field that does not match its lexicon
+type FieldError struct {
+	NSID   string
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s: %s %s", ErrInvalidRecord, e.NSID, e.Field, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidRecord
+func (e *FieldError) Is(target error) bool {
+	return target == ErrInvalidRecord
+}
+
+// Topic is a quest.dis.topic record
+type Topic struct {
+	Type           string   `json:"$type"`
+	Title          string   `json:"title"`
+	Summary        string   `json:"summary,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	SelectedAnswer string   `json:"selectedAnswer,omitempty"`
+	CreatedBy      string   `json:"createdBy"`
+	CreatedAt      string   `json:"createdAt"`
+	RecordVersion  int      `json:"recordVersion,omitempty"`
+}
+
+// Message is a quest.dis.message record
+type Message struct {
+	Type          string `json:"$type"`
+	Topic         string `json:"topic"`
+	Content       string `json:"content"`
+	ReplyTo       string `json:"replyTo,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+	RecordVersion int    `json:"recordVersion,omitempty"`
+}
+
+// Participation is a quest.dis.participation record
+type Participation struct {
+	Type          string `json:"$type"`
+	Topic         string `json:"topic"`
+	Participant   string `json:"participant"`
+	JoinedAt      string `json:"joinedAt"`
+	Role          string `json:"role,omitempty"`
+	RecordVersion int    `json:"recordVersion,omitempty"`
+}
+
+// TopicFromMap decodes and checks a topic record
+func TopicFromMap(m map[string]any) (Topic, error) {
+	f := fields{nsid: TopicNSID, m: m}
+	t := Topic{
+		Type:           f.recordType(),
+		Title:          f.str("title", true, 256),
+		Summary:        f.str("summary", false, 2048),
+		Tags:           f.strs("tags"),
+		SelectedAnswer: f.str("selectedAnswer", false, 0),
+		CreatedBy:      f.did("createdBy", true),
+		CreatedAt:      f.datetime("createdAt", true),
+		RecordVersion:  f.version(),
+	}
+	return t, f.err
+}
+
+// MessageFromMap decodes and checks a message record
+func MessageFromMap(m map[string]any) (Message, error) {
+	f := fields{nsid: MessageNSID, m: m}
+	msg := Message{
+		Type:          f.recordType(),
+		Topic:         f.str("topic", true, 0),
+		Content:       f.str("content", true, 8192),
+		ReplyTo:       f.str("replyTo", false, 0),
+		CreatedAt:     f.datetime("createdAt", true),
+		RecordVersion: f.version(),
+	}
+	return msg, f.err
+}
+
+// ParticipationFromMap decodes and checks a participation record
+func ParticipationFromMap(m map[string]any) (Participation, error) {
+	f := fields{nsid: ParticipationNSID, m: m}
+	p := Participation{
+		Type:          f.recordType(),
+		Topic:         f.str("topic", true, 0),
+		Participant:   f.did("participant", true),
+		JoinedAt:      f.datetime("joinedAt", true),
+		Role:          f.str("role", false, 0),
+		RecordVersion: f.version(),
+	}
+	if f.err == nil && p.Role != "" && !slices.Contains([]string{RoleModerator, RoleContributor, RoleFollower}, p.Role) {
+		f.fail("role", "must be moderator, contributor or follower")
+	}
+	return p, f.err
+}
+
+// Check checks a record written to collection against its quest.dis.*
+// lexicon. Records of other collections are not checked.
+func Check(collection string, m map[string]any) error {
+	var err error
+	switch collection {
+	case TopicNSID:
+		_, err = TopicFromMap(m)
+	case MessageNSID:
+		_, err = MessageFromMap(m)
+	case ParticipationNSID:
+		_, err = ParticipationFromMap(m)
+	}
+	return err
+}
+
+// fields reads typed values out of a record map, keeping the first error
+type fields struct {
+	nsid string
+	m    map[string]any
+	err  error
+}
+
+func (f *fields) fail(field, reason string) {
+	if f.err == nil {
+		f.err = &FieldError{NSID: f.nsid, Field: field, Reason: reason}
+	}
+}
+
+// recordType checks $type, which may be omitted
+func (f *fields) recordType() string {
+	if _, ok := f.m["$type"]; !ok {
+		return f.nsid
+	}
+	if t := f.str("$type", true, 0); t != f.nsid && f.err == nil {
+		f.fail("$type", fmt.Sprintf("is %q, want %q", t, f.nsid))
+	}
+	return f.nsid
+}
+
+// str returns a string field. maxLength counts UTF-8 bytes as lexicons do;
+// zero means unlimited.
+func (f *fields) str(field string, required bool, maxLength int) string {
+	v, ok := f.m[field]
+	if !ok || v == nil {
+		if required {
+			f.fail(field, "is required")
+		}
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		f.fail(field, "must be a string")
+		return ""
+	}
+	if required && s == "" {
+		f.fail(field, "is required")
+	}
+	if maxLength > 0 && len(s) > maxLength {
+		f.fail(field, fmt.Sprintf("must be at most %d bytes", maxLength))
+	}
+	return s
+}
+
+func (f *fields) strs(field string) []string {
+	v, ok := f.m[field]
+	if !ok || v == nil {
+		return nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		f.fail(field, "must be an array of strings")
+		return nil
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			f.fail(field, "must be an array of strings")
+			return nil
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (f *fields) did(field string, required bool) string {
+	s := f.str(field, required, 0)
+	if s != "" && (!strings.HasPrefix(s, "did:") || strings.Count(s, ":") < 2) {
+		f.fail(field, "must be a DID")
+	}
+	return s
+}
+
+func (f *fields) datetime(field string, required bool) string {
+	s := f.str(field, required, 0)
+	if s != "" {
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			f.fail(field, "must be an RFC 3339 datetime")
+		}
+	}
+	return s
+}
+
+// version returns recordVersion, 0 when absent. Records arrive with numbers
+// as float64 or, from decoders using UseNumber, json.Number.
+func (f *fields) version() int {
+	v, ok := f.m[RecordVersionField]
+	if !ok || v == nil {
+		return 0
+	}
+	var n float64
+	switch v := v.(type) {
+	case float64:
+		n = v
+	case int:
+		n = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			f.fail(RecordVersionField, "must be an integer")
+			return 0
+		}
+		n = parsed
+	default:
+		f.fail(RecordVersionField, "must be an integer")
+		return 0
+	}
+	if n < 0 || n != math.Trunc(n) || n > math.MaxInt32 {
+		f.fail(RecordVersionField, "must be a non-negative integer")
+		return 0
+	}
+	return int(n)
+}
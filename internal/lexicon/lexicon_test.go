@@ -0,0 +1,103 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func decode(t testing.TB, s string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("unmarshal %s: %v", s, err)
+	}
+	return m
+}
+
+func TestTopicFromMap(t *testing.T) {
+	got, err := TopicFromMap(decode(t, `{"$type":"quest.dis.topic","title":"Hello","tags":["go"],"createdBy":"did:plc:abc","createdAt":"2025-03-01T12:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("TopicFromMap: %v", err)
+	}
+	if got.Title != "Hello" || len(got.Tags) != 1 || got.Tags[0] != "go" || got.CreatedBy != "did:plc:abc" || got.Type != TopicNSID {
+		t.Errorf("unexpected topic: %+v", got)
+	}
+}
+
+func TestMessageFromMap(t *testing.T) {
+	got, err := MessageFromMap(decode(t, `{"topic":"at://did:plc:abc/quest.dis.topic/t1","content":"hi","createdAt":"2025-03-01T12:00:00.123Z"}`))
+	if err != nil {
+		t.Fatalf("MessageFromMap: %v", err)
+	}
+	if got.Content != "hi" || got.Type != MessageNSID {
+		t.Errorf("unexpected message: %+v", got)
+	}
+}
+
+func TestFromMap_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(map[string]any) error
+		input string
+		field string
+	}{
+		{"topic missing title", topicErr, `{"createdBy":"did:plc:a","createdAt":"2025-03-01T12:00:00Z"}`, "title"},
+		{"topic title wrong type", topicErr, `{"title":7,"createdBy":"did:plc:a","createdAt":"2025-03-01T12:00:00Z"}`, "title"},
+		{"topic tags not strings", topicErr, `{"title":"x","tags":[1],"createdBy":"did:plc:a","createdAt":"2025-03-01T12:00:00Z"}`, "tags"},
+		{"topic bad creator", topicErr, `{"title":"x","createdBy":"alice","createdAt":"2025-03-01T12:00:00Z"}`, "createdBy"},
+		{"topic wrong type", topicErr, `{"$type":"quest.dis.message","title":"x","createdBy":"did:plc:a","createdAt":"2025-03-01T12:00:00Z"}`, "$type"},
+		{"message bad date", messageErr, `{"topic":"t","content":"x","createdAt":"yesterday"}`, "createdAt"},
+		{"participation bad role", participationErr, `{"topic":"t","participant":"did:plc:a","joinedAt":"2025-03-01T12:00:00Z","role":"owner"}`, "role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(decode(t, tt.input))
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("error = %v, want *FieldError", err)
+			}
+			if fieldErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", fieldErr.Field, tt.field)
+			}
+			if !errors.Is(err, ErrInvalidRecord) {
+				t.Error("error does not match ErrInvalidRecord")
+			}
+		})
+	}
+}
+
+func topicErr(m map[string]any) error         { _, err := TopicFromMap(m); return err }
+func messageErr(m map[string]any) error       { _, err := MessageFromMap(m); return err }
+func participationErr(m map[string]any) error { _, err := ParticipationFromMap(m); return err }
+
+// fuzzFromMap feeds JSON objects to parse, which must either succeed or fail
+// with ErrInvalidRecord
+func fuzzFromMap(f *testing.F, parse func(map[string]any) error, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Add(`{}`)
+	f.Add(`{"$type":null}`)
+	f.Fuzz(func(t *testing.T, input string) {
+		var m map[string]any
+		if json.Unmarshal([]byte(input), &m) != nil {
+			return
+		}
+		if err := parse(m); err != nil && !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("error %v does not match ErrInvalidRecord", err)
+		}
+	})
+}
+
+func FuzzTopicFromMap(f *testing.F) {
+	fuzzFromMap(f, topicErr, `{"title":"x","tags":["a",{}],"createdBy":"did:plc:a","createdAt":"2025-03-01T12:00:00Z"}`)
+}
+
+func FuzzMessageFromMap(f *testing.F) {
+	fuzzFromMap(f, messageErr, `{"topic":"t","content":"x","replyTo":1,"createdAt":"2025-03-01T12:00:00Z"}`)
+}
+
+func FuzzParticipationFromMap(f *testing.F) {
+	fuzzFromMap(f, participationErr, `{"topic":"t","participant":"did:plc:a","joinedAt":"2025-03-01T12:00:00Z","role":"follower"}`)
+}
@@ -0,0 +1,63 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/lexicons"
+)
+
+// SchemaNSID is the collection lexicon documents are published in, keyed
+// by the NSID they define
+const SchemaNSID = "com.atproto.lexicon.schema"
+
+// SchemaNSIDs lists the lexicons shipped in lexicons/, sorted
+func SchemaNSIDs() []string {
+	entries, err := fs.ReadDir(lexicons.FS, ".")
+	if err != nil {
+		return nil
+	}
+	var nsids []string
+	for _, entry := range entries {
+		if nsid, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			nsids = append(nsids, nsid)
+		}
+	}
+	sort.Strings(nsids)
+	return nsids
+}
+
+// Schema returns the lexicon document defining nsid, or false when we do
+// not ship one
+func Schema(nsid string) ([]byte, bool) {
+	if !fs.ValidPath(nsid) || strings.Contains(nsid, "/") {
+		return nil, false
+	}
+	data, err := fs.ReadFile(lexicons.FS, nsid+".json")
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// SchemaRecord returns the lexicon document defining nsid as a
+// com.atproto.lexicon.schema record, ready to put at rkey nsid
+func SchemaRecord(nsid string) (map[string]any, error) {
+	data, ok := Schema(nsid)
+	if !ok {
+		return nil, fmt.Errorf("no lexicon document for %s", nsid)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("lexicon document for %s: %w", nsid, err)
+	}
+	if id, _ := record["id"].(string); id != nsid {
+		return nil, fmt.Errorf("lexicon document for %s has id %q", nsid, id)
+	}
+	record["$type"] = SchemaNSID
+	record["lexicon"] = 1
+	return record, nil
+}
@@ -0,0 +1,32 @@
+package lexicon
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSchemas(t *testing.T) {
+	nsids := SchemaNSIDs()
+	for _, want := range []string{TopicNSID, MessageNSID, ParticipationNSID} {
+		if !slices.Contains(nsids, want) {
+			t.Errorf("SchemaNSIDs() = %v, missing %s", nsids, want)
+		}
+	}
+
+	for _, nsid := range nsids {
+		record, err := SchemaRecord(nsid)
+		if err != nil {
+			t.Errorf("SchemaRecord(%s): %v", nsid, err)
+			continue
+		}
+		if record["$type"] != SchemaNSID || record["lexicon"] != 1 || record["defs"] == nil {
+			t.Errorf("SchemaRecord(%s) = %v", nsid, record)
+		}
+	}
+
+	for _, nsid := range []string{"quest.dis.missing", "../go.mod", "embed.go", ""} {
+		if _, ok := Schema(nsid); ok {
+			t.Errorf("Schema(%q) found a document", nsid)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package lexicon
+
+import "fmt"
+
+// RecordVersionField is the record field holding its schema version.
+// Records written before versioning have none and are version 0.
+const RecordVersionField = "recordVersion"
+
+// RecordNSIDs lists the collections whose records carry a version
+var RecordNSIDs = []string{TopicNSID, MessageNSID, ParticipationNSID}
+
+// Migration upgrades a record from version From to From+1 in place. Upgrade
+// sets the new version afterwards, so Apply only has to move fields; a nil
+// Apply changes nothing else.
+type Migration struct {
+	From  int
+	Apply func(record map[string]any) error
+}
+
+// migrations lists each collection's migrations in order, the last one
+// producing the current version. When a lexicon changes shape, append a
+// migration here and the runner rewrites old records on its next pass.
+var migrations = map[string][]Migration{
+	// Version 1 introduced recordVersion itself
+	TopicNSID:         {{From: 0}},
+	MessageNSID:       {{From: 0}},
+	ParticipationNSID: {{From: 0}},
+}
+
+// CurrentVersion returns the version records of nsid are written at
+func CurrentVersion(nsid string) int {
+	return len(migrations[nsid])
+}
+
+// RecordVersion returns the version of a record of nsid, or 0 when it has
+// none
+func RecordVersion(nsid string, record map[string]any) (int, error) {
+	f := fields{nsid: nsid, m: record}
+	version := f.version()
+	return version, f.err
+}
+
+// Upgrade migrates record in place to the current version of nsid and
+// reports whether it changed. Fields Upgrade does not know about are kept.
+// Records at or beyond the current version are left alone: a newer build
+// wrote them and this one must not undo its changes.
+func Upgrade(nsid string, record map[string]any) (bool, error) {
+	version, err := RecordVersion(nsid, record)
+	if err != nil {
+		return false, err
+	}
+	steps := migrations[nsid]
+	if version >= len(steps) {
+		return false, nil
+	}
+	for _, step := range steps[version:] {
+		if step.Apply != nil {
+			if err := step.Apply(record); err != nil {
+				return false, fmt.Errorf("migrating %s from version %d: %w", nsid, step.From, err)
+			}
+		}
+		record[RecordVersionField] = step.From + 1
+	}
+	return true, nil
+}
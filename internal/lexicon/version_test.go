@@ -0,0 +1,87 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMigrationsAreSequential(t *testing.T) {
+	for _, nsid := range RecordNSIDs {
+		for i, step := range migrations[nsid] {
+			if step.From != i {
+				t.Errorf("%s migration %d starts from version %d", nsid, i, step.From)
+			}
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	record := map[string]any{"title": "Old", "createdBy": "did:plc:a", "future": "kept"}
+	changed, err := Upgrade(TopicNSID, record)
+	if err != nil || !changed {
+		t.Fatalf("Upgrade(unversioned) = %v, %v", changed, err)
+	}
+	if record[RecordVersionField] != CurrentVersion(TopicNSID) || record["future"] != "kept" {
+		t.Errorf("upgraded record = %v", record)
+	}
+	if changed, err := Upgrade(TopicNSID, record); err != nil || changed {
+		t.Errorf("Upgrade(current) = %v, %v, want no change", changed, err)
+	}
+
+	newer := map[string]any{RecordVersionField: json.Number("99")}
+	if changed, err := Upgrade(TopicNSID, newer); err != nil || changed {
+		t.Errorf("Upgrade(newer) = %v, %v, want it left alone", changed, err)
+	}
+
+	for _, bad := range []any{"1", -1.0, 1.5, json.Number("x")} {
+		if _, err := Upgrade(TopicNSID, map[string]any{RecordVersionField: bad}); !errors.Is(err, ErrInvalidRecord) {
+			t.Errorf("Upgrade(recordVersion %v) = %v, want ErrInvalidRecord", bad, err)
+		}
+	}
+}
+
+func TestUpgrade_Chain(t *testing.T) {
+	saved := migrations
+	defer func() { migrations = saved }()
+	migrations = map[string][]Migration{TopicNSID: {
+		{From: 0},
+		{From: 1, Apply: func(record map[string]any) error {
+			record["summary"] = record["body"]
+			delete(record, "body")
+			return nil
+		}},
+		{From: 2, Apply: func(map[string]any) error { return errors.New("boom") }},
+	}}
+
+	record := map[string]any{RecordVersionField: 1.0, "body": "text"}
+	if _, err := Upgrade(TopicNSID, record); err == nil {
+		t.Fatal("expected the failing migration to stop the upgrade")
+	}
+
+	migrations[TopicNSID] = migrations[TopicNSID][:2]
+	record = map[string]any{RecordVersionField: 1.0, "body": "text"}
+	if changed, err := Upgrade(TopicNSID, record); err != nil || !changed {
+		t.Fatalf("Upgrade = %v, %v", changed, err)
+	}
+	if record["summary"] != "text" || record["body"] != nil || record[RecordVersionField] != 2 {
+		t.Errorf("migrated record = %v", record)
+	}
+}
+
+func TestFromMap_UnknownFields(t *testing.T) {
+	record := map[string]any{
+		"topic":            "at://did:plc:a/quest.dis.topic/t1",
+		"content":          "hi",
+		"createdAt":        "2025-01-01T00:00:00Z",
+		RecordVersionField: 7.0,
+		"reactions":        []any{"+1"},
+	}
+	msg, err := MessageFromMap(record)
+	if err != nil {
+		t.Fatalf("MessageFromMap rejected a record from a newer version: %v", err)
+	}
+	if msg.RecordVersion != 7 {
+		t.Errorf("RecordVersion = %d, want 7", msg.RecordVersion)
+	}
+}
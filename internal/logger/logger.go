@@ -8,23 +8,30 @@ import (
 
 var defaultLogger *slog.Logger
 
+// level is the default logger's level once Init has run, so SetLevel can
+// change it while running
+var level = new(slog.LevelVar)
+
 // Init initializes the default logger with the specified level
-func Init(level string) {
-	var slogLevel slog.Level
-	switch strings.ToUpper(level) {
+func Init(lvl string) {
+	SetLevel(lvl)
+	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	defaultLogger = slog.New(h)
+}
+
+// SetLevel changes the level of the logger Init created. Unknown levels
+// mean INFO.
+func SetLevel(lvl string) {
+	switch strings.ToUpper(lvl) {
 	case "DEBUG":
-		slogLevel = slog.LevelDebug
-	case "INFO":
-		slogLevel = slog.LevelInfo
+		level.Set(slog.LevelDebug)
 	case "WARN":
-		slogLevel = slog.LevelWarn
+		level.Set(slog.LevelWarn)
 	case "ERROR":
-		slogLevel = slog.LevelError
+		level.Set(slog.LevelError)
 	default:
-		slogLevel = slog.LevelInfo
+		level.Set(slog.LevelInfo)
 	}
-	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slogLevel})
-	defaultLogger = slog.New(h)
 }
 
 func init() {
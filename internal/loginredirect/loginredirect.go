@@ -0,0 +1,126 @@
+// Package loginredirect remembers where a visitor was headed when they were
+// sent to sign in, so signing in can finish there. Targets must be paths on
+// this site, and travel signed and expiring, so wherever one is kept across
+// the sign-in, it cannot be swapped for another site or replayed later.
+package loginredirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+)
+
+// Param is the query parameter and form field carrying the target
+const Param = "next"
+
+// secretLabel derives the signing key from the server secret, so it differs
+// from keys derived from the same secret for other uses
+const secretLabel = "dis.quest login redirect v1"
+
+var (
+	// ErrUnsafeTarget is returned for targets that are not paths on this
+	// site
+	ErrUnsafeTarget = errors.New("redirect target is not a local path")
+	// ErrInvalid is returned for values that were not signed with the key
+	ErrInvalid = errors.New("invalid login redirect")
+	// ErrExpired is returned for values past their TTL
+	ErrExpired = errors.New("login redirect expired")
+)
+
+// Safe reports whether target is a path on this site, as
+// httputil.IsLocalPath decides
+func Safe(target string) bool {
+	return httputil.IsLocalPath(target)
+}
+
+// LoginURL returns the login page, set up to come back to r once signed in.
+// Only GET requests are come back to; a form post cannot be repeated.
+func LoginURL(r *http.Request) string {
+	if r.Method != http.MethodGet {
+		return "/login"
+	}
+	target := r.URL.RequestURI()
+	if !Safe(target) || target == "/login" || strings.HasPrefix(target, "/auth/") {
+		return "/login"
+	}
+	return "/login?" + url.Values{Param: {target}}.Encode()
+}
+
+// Target returns the target r carries in Param, as httputil.SafeRedirect
+// reduces it for publicDomain, or "" when it has none or it is unsafe
+func Target(r *http.Request, publicDomain string) string {
+	if target, ok := httputil.SafeRedirect(r.FormValue(Param), publicDomain); ok {
+		return target
+	}
+	return ""
+}
+
+// Signer signs targets so they can be kept anywhere across a sign-in, e.g.
+// in a cookie, and checked when the sign-in finishes
+type Signer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewSigner creates a signer whose values last ttl, with a key derived from
+// the server secret
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(secretLabel))
+	return &Signer{key: mac.Sum(nil), ttl: ttl, now: time.Now}
+}
+
+// Sign returns target as a signed value. Unsafe targets fail with
+// ErrUnsafeTarget.
+func (s *Signer) Sign(target string) (string, error) {
+	if !Safe(target) {
+		return "", ErrUnsafeTarget
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(target)) + "." + strconv.FormatInt(s.now().Add(s.ttl).Unix(), 10)
+	return payload + "." + s.sign(payload), nil
+}
+
+// Verify returns the target value was signed with. Values not signed with
+// the key fail with ErrInvalid, and values past their TTL with ErrExpired.
+func (s *Signer) Verify(value string) (string, error) {
+	dot := strings.LastIndexByte(value, '.')
+	if dot < 0 || !hmac.Equal([]byte(value[dot+1:]), []byte(s.sign(value[:dot]))) {
+		return "", ErrInvalid
+	}
+	encoded, expiry, ok := strings.Cut(value[:dot], ".")
+	if !ok {
+		return "", ErrInvalid
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return "", ErrExpired
+	}
+	target, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	// Only safe targets are signed, but the rules may have tightened since
+	if !Safe(string(target)) {
+		return "", ErrUnsafeTarget
+	}
+	return string(target), nil
+}
+
+func (s *Signer) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package loginredirect
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginURL(t *testing.T) {
+	for _, tc := range []struct {
+		method, target, want string
+	}{
+		{"GET", "/discussion/topic/42?tab=replies", "/login?next=" + url.QueryEscape("/discussion/topic/42?tab=replies")},
+		{"GET", "/login", "/login"},
+		{"GET", "/auth/callback?code=x", "/login"},
+		{"POST", "/discussion/new", "/login"},
+	} {
+		if got := LoginURL(httptest.NewRequest(tc.method, tc.target, nil)); got != tc.want {
+			t.Errorf("LoginURL(%s %s) = %q, want %q", tc.method, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	if got := Target(httptest.NewRequest("GET", "/login?next=%2Fdiscussion", nil), "https://dis.quest"); got != "/discussion" {
+		t.Errorf("Target = %q, want /discussion", got)
+	}
+	if got := Target(httptest.NewRequest("GET", "/login?next=https%3A%2F%2Fdis.quest%2Faccount", nil), "https://dis.quest"); got != "/account" {
+		t.Errorf("Target of a URL on the public domain = %q, want /account", got)
+	}
+	if got := Target(httptest.NewRequest("GET", "/login?next=%2F%2Fevil.example", nil), "https://dis.quest"); got != "" {
+		t.Errorf("Target of an unsafe next = %q, want none", got)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSigner([]byte("secret"), 10*time.Minute)
+	s.now = func() time.Time { return now }
+
+	value, err := s.Sign("/discussion/topic/42")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if got, err := s.Verify(value); err != nil || got != "/discussion/topic/42" {
+		t.Fatalf("Verify = %q, %v; want the signed target", got, err)
+	}
+
+	if _, err := s.Sign("//evil.example"); !errors.Is(err, ErrUnsafeTarget) {
+		t.Errorf("Sign of an unsafe target: error = %v, want ErrUnsafeTarget", err)
+	}
+
+	other := NewSigner([]byte("another secret"), 10*time.Minute)
+	if _, err := other.Verify(value); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify with another key: error = %v, want ErrInvalid", err)
+	}
+	forged, _ := other.Sign("/account")
+	if _, err := s.Verify(forged); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify of another key's value: error = %v, want ErrInvalid", err)
+	}
+	encoded, _, _ := strings.Cut(value, ".")
+	tampered := strings.Replace(value, encoded, encoded+"Lw", 1)
+	if _, err := s.Verify(tampered); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify of a tampered target: error = %v, want ErrInvalid", err)
+	}
+	for _, garbage := range []string{"", "nodots", "a.b"} {
+		if _, err := s.Verify(garbage); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Verify(%q): error = %v, want ErrInvalid", garbage, err)
+		}
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := s.Verify(value); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify after the TTL: error = %v, want ErrExpired", err)
+	}
+}
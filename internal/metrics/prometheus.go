@@ -0,0 +1,287 @@
+// Package metrics exposes the measurements taken by the PDS client and the
+// mirror followers in the Prometheus text format, without depending on the
+// Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the request duration
+// histogram
+var durationBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	nsid   string
+	status int
+}
+
+// collectionKey labels mirror counters kept per origin and collection
+type collectionKey struct {
+	origin     string
+	collection string
+}
+
+// mirrorStats are one mirrored origin's ingestion measurements
+type mirrorStats struct {
+	queueDepth int
+	lag        float64
+	committed  uint64
+	batches    uint64
+	parks      uint64
+	replayed   uint64
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Prometheus implements pds.MetricsSink and mirror.MetricsSink and serves
+// what it has recorded as a Prometheus scrape target
+type Prometheus struct {
+	mu           sync.Mutex
+	requests     map[requestKey]uint64
+	durations    map[string]*histogram
+	nonceRetries uint64
+	refreshes    uint64
+	mirrors      map[string]*mirrorStats
+	dropped      map[collectionKey]uint64
+	unverified   map[collectionKey]uint64
+}
+
+// NewPrometheus creates an empty set of metrics
+func NewPrometheus() *Prometheus {
+	return &Prometheus{
+		requests:   make(map[requestKey]uint64),
+		durations:  make(map[string]*histogram),
+		mirrors:    make(map[string]*mirrorStats),
+		dropped:    make(map[collectionKey]uint64),
+		unverified: make(map[collectionKey]uint64),
+	}
+}
+
+// ObserveRequest implements pds.MetricsSink
+func (p *Prometheus) ObserveRequest(nsid string, status int, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests[requestKey{nsid, status}]++
+
+	h := p.durations[nsid]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(durationBuckets)+1)}
+		p.durations[nsid] = h
+	}
+	seconds := duration.Seconds()
+	i, _ := slices.BinarySearch(durationBuckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+// CountNonceRetry implements pds.MetricsSink
+func (p *Prometheus) CountNonceRetry() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nonceRetries++
+}
+
+// CountRefresh implements pds.MetricsSink
+func (p *Prometheus) CountRefresh() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshes++
+}
+
+// ObserveQueue implements mirror.MetricsSink
+func (p *Prometheus) ObserveQueue(origin string, depth int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirror(origin).queueDepth = depth
+}
+
+// ObserveCommit implements mirror.MetricsSink
+func (p *Prometheus) ObserveCommit(origin string, events int, lag time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m := p.mirror(origin)
+	m.lag = lag.Seconds()
+	m.committed += uint64(events) // #nosec G115 -- a batch size is never negative
+	m.batches++
+}
+
+// CountDropped implements mirror.MetricsSink
+func (p *Prometheus) CountDropped(origin, collection string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirror(origin)
+	p.dropped[collectionKey{origin, collection}]++
+}
+
+// CountParked implements mirror.MetricsSink
+func (p *Prometheus) CountParked(origin string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirror(origin).parks++
+}
+
+// CountReplayed implements mirror.MetricsSink
+func (p *Prometheus) CountReplayed(origin string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirror(origin).replayed++
+}
+
+// CountUnverified implements mirror.MetricsSink
+func (p *Prometheus) CountUnverified(origin, collection string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mirror(origin)
+	p.unverified[collectionKey{origin, collection}]++
+}
+
+// mirror returns origin's stats, creating them; p.mu must be held
+func (p *Prometheus) mirror(origin string) *mirrorStats {
+	m := p.mirrors[origin]
+	if m == nil {
+		m = &mirrorStats{}
+		p.mirrors[origin] = m
+	}
+	return m
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.write(w)
+}
+
+// write writes every metric to w, with series in a stable order
+func (p *Prometheus) write(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP atproto_xrpc_requests_total XRPC requests by method and response status; status 0 means no response.")
+	fmt.Fprintln(w, "# TYPE atproto_xrpc_requests_total counter")
+	keys := make([]requestKey, 0, len(p.requests))
+	for key := range p.requests {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b requestKey) int {
+		if c := strings.Compare(a.nsid, b.nsid); c != 0 {
+			return c
+		}
+		return a.status - b.status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "atproto_xrpc_requests_total{nsid=%s,status=\"%d\"} %d\n", quote(key.nsid), key.status, p.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP atproto_xrpc_request_duration_seconds Time until XRPC response headers arrived.")
+	fmt.Fprintln(w, "# TYPE atproto_xrpc_request_duration_seconds histogram")
+	nsids := make([]string, 0, len(p.durations))
+	for nsid := range p.durations {
+		nsids = append(nsids, nsid)
+	}
+	slices.Sort(nsids)
+	for _, nsid := range nsids {
+		h := p.durations[nsid]
+		var cumulative uint64
+		for i, bound := range durationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "atproto_xrpc_request_duration_seconds_bucket{nsid=%s,le=\"%s\"} %d\n",
+				quote(nsid), strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "atproto_xrpc_request_duration_seconds_bucket{nsid=%s,le=\"+Inf\"} %d\n", quote(nsid), h.count)
+		fmt.Fprintf(w, "atproto_xrpc_request_duration_seconds_sum{nsid=%s} %s\n", quote(nsid), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "atproto_xrpc_request_duration_seconds_count{nsid=%s} %d\n", quote(nsid), h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP atproto_dpop_nonce_retries_total Requests repeated because the server asked for a fresh DPoP nonce.")
+	fmt.Fprintln(w, "# TYPE atproto_dpop_nonce_retries_total counter")
+	fmt.Fprintf(w, "atproto_dpop_nonce_retries_total %d\n", p.nonceRetries)
+	fmt.Fprintln(w, "# HELP atproto_token_refreshes_total Attempts to refresh an access token.")
+	fmt.Fprintln(w, "# TYPE atproto_token_refreshes_total counter")
+	fmt.Fprintf(w, "atproto_token_refreshes_total %d\n", p.refreshes)
+
+	p.writeMirrors(w)
+}
+
+// writeMirrors writes the mirror ingestion metrics; p.mu must be held
+func (p *Prometheus) writeMirrors(w io.Writer) {
+	if len(p.mirrors) == 0 {
+		return
+	}
+	origins := make([]string, 0, len(p.mirrors))
+	for origin := range p.mirrors {
+		origins = append(origins, origin)
+	}
+	slices.Sort(origins)
+	series := []struct {
+		name, kind, help string
+		value            func(*mirrorStats) string
+	}{
+		{"mirror_queue_depth", "gauge", "Events read from the origin and waiting to be committed.",
+			func(m *mirrorStats) string { return strconv.Itoa(m.queueDepth) }},
+		{"mirror_commit_lag_seconds", "gauge", "Time between the origin publishing the newest committed event and its commit.",
+			func(m *mirrorStats) string { return strconv.FormatFloat(m.lag, 'g', -1, 64) }},
+		{"mirror_events_committed_total", "counter", "Events committed from the origin, including those skipped as not mirrored.",
+			func(m *mirrorStats) string { return strconv.FormatUint(m.committed, 10) }},
+		{"mirror_batches_total", "counter", "Batches committed from the origin, each with one cursor update.",
+			func(m *mirrorStats) string { return strconv.FormatUint(m.batches, 10) }},
+		{"mirror_parks_total", "counter", "Times the origin was parked because its queue was full.",
+			func(m *mirrorStats) string { return strconv.FormatUint(m.parks, 10) }},
+		{"mirror_events_replayed_total", "counter", "Events skipped because their record already had that change or a newer one.",
+			func(m *mirrorStats) string { return strconv.FormatUint(m.replayed, 10) }},
+	}
+	for _, s := range series {
+		fmt.Fprintf(w, "# HELP %s %s\n", s.name, s.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.kind)
+		for _, origin := range origins {
+			fmt.Fprintf(w, "%s{origin=%s} %s\n", s.name, quote(origin), s.value(p.mirrors[origin]))
+		}
+	}
+
+	writeCollectionCounter(w, "mirror_events_dropped_total", "Events discarded because the origin's queue was full.", p.dropped)
+	writeCollectionCounter(w, "mirror_records_unverified_total", "Records from the origin that could not be verified against their author's repository.", p.unverified)
+}
+
+// writeCollectionCounter writes a mirror counter labelled by origin and
+// collection
+func writeCollectionCounter(w io.Writer, name, help string, counts map[collectionKey]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+	keys := make([]collectionKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b collectionKey) int {
+		if c := strings.Compare(a.origin, b.origin); c != 0 {
+			return c
+		}
+		return strings.Compare(a.collection, b.collection)
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{origin=%s,collection=%s} %d\n", name, quote(key.origin), quote(key.collection), counts[key])
+	}
+}
+
+// quote escapes a label value as the exposition format requires
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
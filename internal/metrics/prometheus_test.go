@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+	p.ObserveRequest("com.atproto.repo.getRecord", 200, 30*time.Millisecond)
+	p.ObserveRequest("com.atproto.repo.getRecord", 200, 2*time.Second)
+	p.ObserveRequest("com.atproto.repo.getRecord", 0, 20*time.Second)
+	p.ObserveRequest("com.atproto.repo.createRecord", 401, 10*time.Millisecond)
+	p.CountNonceRetry()
+	p.CountRefresh()
+	p.CountRefresh()
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`atproto_xrpc_requests_total{nsid="com.atproto.repo.createRecord",status="401"} 1`,
+		`atproto_xrpc_requests_total{nsid="com.atproto.repo.getRecord",status="0"} 1`,
+		`atproto_xrpc_requests_total{nsid="com.atproto.repo.getRecord",status="200"} 2`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.getRecord",le="0.025"} 0`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.getRecord",le="0.05"} 1`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.getRecord",le="2.5"} 2`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.getRecord",le="10"} 2`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.getRecord",le="+Inf"} 3`,
+		`atproto_xrpc_request_duration_seconds_bucket{nsid="com.atproto.repo.createRecord",le="0.01"} 1`,
+		`atproto_xrpc_request_duration_seconds_count{nsid="com.atproto.repo.getRecord"} 3`,
+		`atproto_dpop_nonce_retries_total 1`,
+		`atproto_token_refreshes_total 2`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, `status="401"`) > strings.Index(body, `status="0"`) {
+		t.Error("series are not sorted by nsid")
+	}
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
+
+func TestPrometheusMirrors(t *testing.T) {
+	p := NewPrometheus()
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "mirror_") {
+		t.Error("mirror metrics should be omitted until a follower reports")
+	}
+
+	p.ObserveQueue("https://b.example", 12)
+	p.ObserveCommit("https://b.example", 100, 1500*time.Millisecond)
+	p.ObserveCommit("https://b.example", 20, 250*time.Millisecond)
+	p.ObserveQueue("https://a.example", 0)
+	p.CountDropped("https://a.example", "quest.dis.message")
+	p.CountDropped("https://a.example", "quest.dis.message")
+	p.CountParked("https://a.example")
+	p.CountReplayed("https://b.example")
+	p.CountUnverified("https://b.example", "quest.dis.topic")
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`mirror_queue_depth{origin="https://b.example"} 12`,
+		`mirror_commit_lag_seconds{origin="https://b.example"} 0.25`,
+		`mirror_events_committed_total{origin="https://b.example"} 120`,
+		`mirror_batches_total{origin="https://b.example"} 2`,
+		`mirror_parks_total{origin="https://a.example"} 1`,
+		`mirror_events_replayed_total{origin="https://b.example"} 1`,
+		`mirror_records_unverified_total{origin="https://b.example",collection="quest.dis.topic"} 1`,
+		`mirror_events_dropped_total{origin="https://a.example",collection="quest.dis.message"} 2`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, `mirror_queue_depth{origin="https://b.example"}`) < strings.Index(body, `mirror_queue_depth{origin="https://a.example"}`) {
+		t.Error("series are not sorted by origin")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quote = %s", got)
+	}
+}
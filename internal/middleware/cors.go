@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+)
+
+const (
+	// corsPathPrefix is the route prefix of the JSON API, the only routes
+	// other origins may be allowed to call
+	corsPathPrefix = "/api/"
+
+	corsAllowMethods = "GET, HEAD, POST, PUT, PATCH, DELETE"
+	corsAllowHeaders = "Accept, Accept-Version, Authorization, Content-Type, Last-Event-ID"
+	// corsExposeHeaders are the response headers API clients read
+	corsExposeHeaders = "API-Version, Retry-After"
+)
+
+// corsPolicy decides which origins may call the API
+type corsPolicy struct {
+	// origins are allowed exactly; patterns like https://*.example.com allow
+	// any subdomain
+	origins     []string
+	patterns    []string
+	anyOrigin   bool
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(cfg *config.Config) corsPolicy {
+	p := corsPolicy{
+		credentials: cfg.CORSAllowCredentials,
+		maxAge:      strconv.Itoa(cfg.CORSMaxAgeSeconds),
+	}
+	for _, origin := range strings.Fields(strings.ToLower(cfg.CORSAllowedOrigins)) {
+		origin = strings.TrimRight(origin, "/")
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			p.patterns = append(p.patterns, strings.Replace(origin, "://*.", "://", 1))
+		default:
+			p.origins = append(p.origins, origin)
+		}
+	}
+	return p
+}
+
+// listed reports whether origin is allowed by name or pattern rather than
+// by a wildcard
+func (p corsPolicy) listed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.origins, origin) {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		scheme, domain, _ := strings.Cut(pattern, "://")
+		if rest, ok := strings.CutPrefix(origin, scheme+"://"); ok && strings.HasSuffix(rest, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS returns middleware that lets the origins in cfg.CORSAllowedOrigins
+// call the JSON API from the browser. Listed origins are echoed back and,
+// with cfg.CORSAllowCredentials, may send cookies; a "*" entry lets any
+// other origin make uncredentialed calls, such as with an access token.
+// Preflight requests are answered here and cached for cfg.CORSMaxAgeSeconds.
+// Other routes are left alone.
+func CORS(cfg *config.Config) func(http.Handler) http.Handler {
+	policy := newCORSPolicy(cfg)
+	enabled := policy.anyOrigin || len(policy.origins) > 0 || len(policy.patterns) > 0
+
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !strings.HasPrefix(r.URL.Path, corsPathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			listed := policy.listed(origin)
+			switch {
+			case listed:
+				h.Set("Access-Control-Allow-Origin", origin)
+				if policy.credentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			case policy.anyOrigin:
+				h.Set("Access-Control-Allow-Origin", "*")
+			case preflight:
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			default:
+				// Without CORS headers the browser keeps the response from
+				// the calling page
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", policy.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := &config.Config{
+		CORSAllowedOrigins:   "https://app.example.com https://*.example.org",
+		CORSAllowCredentials: true,
+		CORSMaxAgeSeconds:    600,
+	}
+	wildcard := &config.Config{CORSAllowedOrigins: "* https://app.example.com", CORSMaxAgeSeconds: 600}
+
+	tests := []struct {
+		name            string
+		cfg             *config.Config
+		method          string
+		path            string
+		origin          string
+		preflight       bool
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{"listed origin", cfg, "GET", "/api/topics", "https://app.example.com", false, http.StatusTeapot, "https://app.example.com", true},
+		{"subdomain pattern", cfg, "POST", "/api/topics", "https://forum.example.org", false, http.StatusTeapot, "https://forum.example.org", true},
+		{"pattern excludes the bare domain", cfg, "GET", "/api/topics", "https://example.org", false, http.StatusTeapot, "", false},
+		{"pattern checks the scheme", cfg, "GET", "/api/topics", "http://forum.example.org", false, http.StatusTeapot, "", false},
+		{"unlisted origin", cfg, "GET", "/api/topics", "https://evil.example.net", false, http.StatusTeapot, "", false},
+		{"same-origin request", cfg, "GET", "/api/topics", "", false, http.StatusTeapot, "", false},
+		{"pages are left alone", cfg, "GET", "/discussion", "https://app.example.com", false, http.StatusTeapot, "", false},
+		{"preflight", cfg, "OPTIONS", "/api/topics", "https://app.example.com", true, http.StatusNoContent, "https://app.example.com", true},
+		{"unlisted preflight", cfg, "OPTIONS", "/api/topics", "https://evil.example.net", true, http.StatusForbidden, "", false},
+		{"wildcard omits credentials", wildcard, "GET", "/api/topics", "https://evil.example.net", false, http.StatusTeapot, "*", false},
+		{"wildcard still echoes listed origins", wildcard, "GET", "/api/topics", "https://app.example.com", false, http.StatusTeapot, "https://app.example.com", false},
+		{"disabled by default", &config.Config{}, "OPTIONS", "/api/topics", "https://app.example.com", true, http.StatusTeapot, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CORS(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}))
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %v, want %v", got, tt.wantCredentials)
+			}
+			if tt.preflight && tt.wantStatus == http.StatusNoContent {
+				if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+					t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+				}
+				if w.Header().Get("Access-Control-Allow-Headers") == "" {
+					t.Error("Expected Access-Control-Allow-Headers on a preflight")
+				}
+			}
+		})
+	}
+}
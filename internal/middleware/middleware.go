@@ -4,14 +4,16 @@ import (
 	"net/http"
 
 	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
 )
 
-// AuthMiddleware checks for a valid session cookie and redirects to /login if missing
+// AuthMiddleware checks for a valid session cookie and redirects to /login,
+// set up to come back, if missing
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, err := auth.GetSessionCookie(r)
 		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			http.Redirect(w, r, loginredirect.LoginURL(r), http.StatusSeeOther)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// RequestIDHeader carries a request's ID, from a proxy that assigned one
+// and back to the client
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// validRequestID matches the incoming request IDs worth keeping
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID returns the ID Recover gave the request of ctx, or "" outside
+// Recover
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Recover gives each request an ID, kept from the X-Request-ID header when
+// a proxy set one, and returns it in that header and in error responses. A
+// panicking handler answers a JSON 500 instead of dropping the connection.
+// Panics and 5xx responses are sent to the error reporter, except 503s,
+// which tell the client an upstream such as the user's PDS is down.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+		rw := &recorder{ResponseWriter: w, id: id}
+		event := errreport.Event{RequestID: id, Method: r.Method, Path: r.URL.Path}
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				// The handler gave up on the response on purpose
+				panic(p)
+			}
+			event.Err, event.Stack, event.Status = &errreport.PanicError{Value: p}, debug.Stack(), http.StatusInternalServerError
+			logger.Error("Handler panicked", "error", event.Err, "request_id", id, "method", r.Method, "path", r.URL.Path, "stack", string(event.Stack))
+			errreport.Report(r.Context(), event)
+			if !rw.wrote {
+				httputil.WriteJSON(rw, http.StatusInternalServerError, httputil.ErrorResponse{
+					Error:     http.StatusText(http.StatusInternalServerError),
+					RequestID: id,
+				})
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+		if rw.status < http.StatusInternalServerError || rw.status == http.StatusServiceUnavailable {
+			return
+		}
+		event.Err, event.Status = rw.err, rw.status
+		if event.Err == nil {
+			event.Err = fmt.Errorf("%s %s answered %d", r.Method, r.URL.Path, rw.status)
+		}
+		errreport.Report(r.Context(), event)
+	})
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// recorder remembers the status a handler answered and the error behind
+// it, and lets httputil put the request ID in error responses
+type recorder struct {
+	http.ResponseWriter
+	id     string
+	status int
+	wrote  bool
+	err    error
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.status, w.wrote = http.StatusOK, true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// RequestID returns the ID of the request being answered
+func (w *recorder) RequestID() string {
+	return w.id
+}
+
+// RecordError keeps the error an error response was written for, to be
+// reported with it
+func (w *recorder) RecordError(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, for streaming
+// handlers' flushes and deadlines
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush supports handlers that assert http.Flusher directly
+func (w *recorder) Flush() {
+	if !w.wrote {
+		w.status, w.wrote = http.StatusOK, true
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack supports handlers that assert http.Hijacker directly
+func (w *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.wrote = true
+	}
+	return conn, rw, err
+}
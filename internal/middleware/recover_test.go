@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+)
+
+// recordingReporter keeps the events it is given
+type recordingReporter struct {
+	mu     sync.Mutex
+	events []errreport.Event
+}
+
+func (r *recordingReporter) Report(_ context.Context, event errreport.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestRecover(t *testing.T) {
+	dbErr := errors.New("database is locked")
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		// reported is the error reported, nil when nothing should be
+		reported error
+		panicked bool
+	}{
+		{
+			name:    "ok",
+			handler: func(w http.ResponseWriter, _ *http.Request) { httputil.WriteSuccess(w, "ok") },
+			status:  http.StatusOK,
+		},
+		{
+			name:     "panic",
+			handler:  func(http.ResponseWriter, *http.Request) { panic(dbErr) },
+			status:   http.StatusInternalServerError,
+			reported: dbErr,
+			panicked: true,
+		},
+		{
+			name:     "internal error",
+			handler:  func(w http.ResponseWriter, _ *http.Request) { httputil.WriteInternalError(w, dbErr, "Failed to fetch topics") },
+			status:   http.StatusInternalServerError,
+			reported: dbErr,
+		},
+		{
+			name: "PDS unavailable",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				httputil.WritePDSUnavailable(w, time.Minute)
+			},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:    "client error",
+			handler: func(w http.ResponseWriter, _ *http.Request) { httputil.WriteError(w, http.StatusNotFound, "Topic not found") },
+			status:  http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			errreport.SetReporter(reporter)
+			t.Cleanup(func() { errreport.SetReporter(nil) })
+
+			rec := httptest.NewRecorder()
+			Recover(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/topics", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			id := rec.Header().Get(RequestIDHeader)
+			if id == "" {
+				t.Fatal("response lacks a request ID")
+			}
+			if tt.status >= http.StatusBadRequest {
+				var body httputil.ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.RequestID != id {
+					t.Errorf("body = %s, want the request ID %s", rec.Body, id)
+				}
+			}
+
+			if tt.reported == nil {
+				if len(reporter.events) != 0 {
+					t.Errorf("reported %+v, want nothing", reporter.events)
+				}
+				return
+			}
+			if len(reporter.events) != 1 {
+				t.Fatalf("reported %d events, want 1", len(reporter.events))
+			}
+			event := reporter.events[0]
+			if !errors.Is(event.Err, tt.reported) || event.Panicked() != tt.panicked {
+				t.Errorf("event error = %v (panicked %t), want %v", event.Err, event.Panicked(), tt.reported)
+			}
+			if event.RequestID != id || event.Status != tt.status || event.Path != "/api/topics" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestRecoverRequestID(t *testing.T) {
+	var seen string
+	handler := Recover(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		incoming string
+		keep     bool
+	}{
+		{incoming: "", keep: false},
+		{incoming: "7f3a9c0e-proxy.1", keep: true},
+		{incoming: "has spaces\tand tabs", keep: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.incoming != "" {
+			req.Header.Set(RequestIDHeader, tt.incoming)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		id := rec.Header().Get(RequestIDHeader)
+		if id == "" || id != seen {
+			t.Errorf("incoming %q: header %q, handler saw %q", tt.incoming, id, seen)
+		}
+		if (id == tt.incoming) != tt.keep {
+			t.Errorf("incoming %q: request ID = %q, keep = %t", tt.incoming, id, tt.keep)
+		}
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler re-raised", p)
+		}
+	}()
+	Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoverStreaming(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expected the response to stay flushable")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !rec.Flushed {
+		t.Error("expected the flush to reach the connection")
+	}
+}
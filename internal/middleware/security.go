@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+)
+
+const (
+	// EmbedPathPrefix is the route prefix for the embeddable widget, which is
+	// the only content allowed to be framed by other sites
+	EmbedPathPrefix = "/embed/"
+
+	contentTypeOptions = "nosniff"
+	frameOptions       = "DENY"
+	xssProtection      = "1; mode=block"
+	referrerPolicy     = "strict-origin-when-cross-origin"
+	hstsPolicy         = "max-age=63072000; includeSubDomains"
+
+	// baseContentSecurityPolicy allows only same-origin scripts and
+	// connections (htmx requests and SSE streams). Inline styles are allowed
+	// because templates use style attributes and htmx injects its indicator
+	// styles. The login form posts to our own /auth routes, which redirect to
+	// the user's authorization server, so form-action must allow https.
+	baseContentSecurityPolicy = "default-src 'self'; " +
+		"script-src 'self'; " +
+		"style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' data: https:; " +
+		"connect-src 'self'; " +
+		"font-src 'self'; " +
+		"object-src 'none'; " +
+		"base-uri 'self'; " +
+		"form-action 'self' https:"
+)
+
+// SecurityHeaders returns middleware that sets security headers on every
+// response: a strict Content-Security-Policy, HSTS in production, and frame
+// protection that is relaxed only for the embed widget route. On a
+// community's verified custom domain the embed may also be framed by pages on
+// that same domain.
+func SecurityHeaders(cfg *config.Config) func(http.Handler) http.Handler {
+	embedAncestors := strings.TrimSpace(cfg.EmbedFrameAncestors)
+	if embedAncestors == "" {
+		embedAncestors = "'none'"
+	}
+	defaultPolicy := baseContentSecurityPolicy + "; frame-ancestors 'none'"
+	embedPolicy := baseContentSecurityPolicy + "; frame-ancestors " + embedAncestors
+	domainEmbedPolicy := baseContentSecurityPolicy + "; frame-ancestors 'self'"
+	if embedAncestors != "'none'" {
+		domainEmbedPolicy += " " + embedAncestors
+	}
+	hsts := cfg.AppEnv == config.EnvProd
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", contentTypeOptions)
+			h.Set("X-XSS-Protection", xssProtection)
+			h.Set("Referrer-Policy", referrerPolicy)
+			if strings.HasPrefix(r.URL.Path, EmbedPathPrefix) {
+				// X-Frame-Options cannot express an allow-list, so rely on
+				// frame-ancestors alone for the embed route
+				if _, ok := domains.FromContext(r.Context()); ok {
+					h.Set("Content-Security-Policy", domainEmbedPolicy)
+				} else {
+					h.Set("Content-Security-Policy", embedPolicy)
+				}
+			} else {
+				h.Set("X-Frame-Options", frameOptions)
+				h.Set("Content-Security-Policy", defaultPolicy)
+			}
+			if hsts {
+				h.Set("Strict-Transport-Security", hstsPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name              string
+		cfg               *config.Config
+		path              string
+		customDomain      bool
+		wantHSTS          bool
+		wantFrameOptions  string
+		wantFrameAncestor string
+	}{
+		{
+			name:              "development page",
+			cfg:               &config.Config{AppEnv: config.EnvDev},
+			path:              "/discussion",
+			wantFrameOptions:  "DENY",
+			wantFrameAncestor: "frame-ancestors 'none'",
+		},
+		{
+			name:              "production page enables HSTS",
+			cfg:               &config.Config{AppEnv: config.EnvProd},
+			path:              "/",
+			wantHSTS:          true,
+			wantFrameOptions:  "DENY",
+			wantFrameAncestor: "frame-ancestors 'none'",
+		},
+		{
+			name:              "embed route uses configured ancestors",
+			cfg:               &config.Config{AppEnv: config.EnvProd, EmbedFrameAncestors: "https://blog.example.com"},
+			path:              "/embed/topic",
+			wantHSTS:          true,
+			wantFrameAncestor: "frame-ancestors https://blog.example.com",
+		},
+		{
+			name:              "embed route denies framing when unconfigured",
+			cfg:               &config.Config{AppEnv: config.EnvDev},
+			path:              "/embed/topic",
+			wantFrameAncestor: "frame-ancestors 'none'",
+		},
+		{
+			name:              "embed route on a community domain allows same-origin framing",
+			cfg:               &config.Config{AppEnv: config.EnvDev, EmbedFrameAncestors: "https://blog.example.com"},
+			path:              "/embed/topic",
+			customDomain:      true,
+			wantFrameAncestor: "frame-ancestors 'self' https://blog.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SecurityHeaders(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.customDomain {
+				req = req.WithContext(domains.WithDomain(req.Context(), domains.Domain{Host: "forum.example.com", Community: "golang"}))
+			}
+			handler.ServeHTTP(w, req)
+
+			h := w.Header()
+			if got := h.Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("expected nosniff, got %q", got)
+			}
+			if got := h.Get("X-Frame-Options"); got != tt.wantFrameOptions {
+				t.Errorf("expected X-Frame-Options %q, got %q", tt.wantFrameOptions, got)
+			}
+			csp := h.Get("Content-Security-Policy")
+			if !strings.Contains(csp, "script-src 'self'") {
+				t.Errorf("expected strict script-src, got %q", csp)
+			}
+			if !strings.HasSuffix(csp, tt.wantFrameAncestor) {
+				t.Errorf("expected CSP to end with %q, got %q", tt.wantFrameAncestor, csp)
+			}
+			if got := h.Get("Strict-Transport-Security") != ""; got != tt.wantHSTS {
+				t.Errorf("expected HSTS %v, got %v", tt.wantHSTS, got)
+			}
+		})
+	}
+}
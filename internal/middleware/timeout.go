@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// writeGrace is the time a handler has past its timeout to write its
+// response, such as the error for a PDS call the deadline cut short
+const writeGrace = 5 * time.Second
+
+// RouteTimeout overrides the timeout of requests matching Pattern, a
+// http.ServeMux pattern
+type RouteTimeout struct {
+	Pattern string
+	// Timeout of 0 or less exempts the route, for event streams and
+	// WebSockets that stay open by design
+	Timeout time.Duration
+}
+
+// Timeouts says how long requests may take
+type Timeouts struct {
+	// Default applies to requests no route matches; 0 or less means none
+	Default time.Duration
+	// Routes are matched as http.ServeMux matches patterns, so the most
+	// specific wins: "/api/" can set a short timeout while
+	// "/api/topics/{id}/events" exempts a stream beneath it
+	Routes []RouteTimeout
+}
+
+// Timeout bounds requests by route. A request's context is cancelled at its
+// deadline, so database and PDS calls made with it give up, and its write
+// deadline is moved to match, past the server-wide write timeout when the
+// route allows longer. A handler that returns at the deadline without
+// answering gets a 503. Like http.ServeMux, Timeout panics on invalid or
+// conflicting patterns.
+func Timeout(t Timeouts) func(http.Handler) http.Handler {
+	routes := http.NewServeMux()
+	timeouts := map[string]time.Duration{}
+	for _, route := range t.Routes {
+		routes.Handle(route.Pattern, http.NotFoundHandler())
+		timeouts[route.Pattern] = route.Timeout
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := t.Default
+			if _, pattern := routes.Handler(r); pattern != "" {
+				if d, ok := timeouts[pattern]; ok {
+					timeout = d
+				}
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+			if err := http.NewResponseController(w).SetWriteDeadline(deadline.Add(writeGrace)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Warn("Failed to set write deadline", "error", err)
+			}
+
+			tw := &timeoutWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			if !tw.wrote && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				httputil.WriteError(w, http.StatusServiceUnavailable, "Request timed out", "path", r.URL.Path, "timeout", timeout)
+			}
+		})
+	}
+}
+
+// timeoutWriter remembers whether the handler answered
+type timeoutWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	timeouts := Timeouts{
+		Default: time.Minute,
+		Routes: []RouteTimeout{
+			{Pattern: "/api/", Timeout: time.Second},
+			{Pattern: "/api/topics/{id}/events"},
+		},
+	}
+	tests := []struct {
+		path string
+		// want is the request's timeout, 0 for none
+		want time.Duration
+	}{
+		{"/discussion", time.Minute},
+		{"/api/topics", time.Second},
+		{"/api/topics/42/events", 0},
+		{"/api/topics/42/messages", time.Second},
+	}
+	for _, tt := range tests {
+		var deadline time.Time
+		var bounded bool
+		handler := Timeout(timeouts)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			deadline, bounded = r.Context().Deadline()
+		}))
+		start := time.Now()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if bounded != (tt.want > 0) {
+			t.Errorf("%s: bounded = %t, want timeout %s", tt.path, bounded, tt.want)
+			continue
+		}
+		if got := deadline.Sub(start); bounded && (got < tt.want || got > tt.want+time.Second/2) {
+			t.Errorf("%s: timeout = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTimeoutExpired(t *testing.T) {
+	timeouts := Timeouts{Default: 10 * time.Millisecond}
+
+	// A handler giving up at the deadline without answering gets a 503
+	rec := httptest.NewRecorder()
+	Timeout(timeouts)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/topics", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+
+	// One that answered keeps its answer
+	rec = httptest.NewRecorder()
+	Timeout(timeouts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		http.Error(w, "PDS timed out", http.StatusBadGateway)
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/topics", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want the handler's 502", rec.Code)
+	}
+}
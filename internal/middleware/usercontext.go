@@ -2,39 +2,79 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
+	"sync/atomic"
 
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
 	"github.com/jrschumacher/dis.quest/internal/auth"
-	"github.com/jrschumacher/dis.quest/internal/jwtutil"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
 	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/session"
 )
 
-// UserContext holds user information extracted from JWT
+// UserContext holds user information extracted from JWT, with the profile
+// recorded when the user's session started
 type UserContext struct {
-	DID    string
-	Handle string
-	PDS    string
-	Scope  string
+	DID         string
+	Handle      string
+	DisplayName string
+	Avatar      string
+	PDS         string
+	Scope       string
+	// TokenID is the personal access token the request was made with, empty
+	// for browser sessions
+	TokenID string
 }
 
 type contextKey string
 
 const userContextKey contextKey = "user"
 
+// SessionValidator reports whether the server-side session behind a request
+// is still live for the DID in its token, and returns its profile
+type SessionValidator func(r *http.Request, did string) (session.Profile, bool)
+
+var sessionValidator atomic.Pointer[SessionValidator]
+
+// SetSessionValidator installs the check UserContextMiddleware uses to turn
+// away revoked or expired sessions. Without one, any parseable token is accepted.
+func SetSessionValidator(v SessionValidator) {
+	sessionValidator.Store(&v)
+}
+
+// TokenAuthenticator returns the live personal access token whose secret a
+// request presents
+type TokenAuthenticator func(r *http.Request, secret string) (accesstoken.Token, bool)
+
+var tokenAuthenticator atomic.Pointer[TokenAuthenticator]
+
+// SetTokenAuthenticator installs the check UserContextMiddleware uses for
+// requests carrying a personal access token. Without one, such requests are
+// refused.
+func SetTokenAuthenticator(a TokenAuthenticator) {
+	tokenAuthenticator.Store(&a)
+}
+
 // UserContextMiddleware extracts user information from JWT and adds it to request context
 func UserContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the session token
-		token, err := auth.GetSessionCookie(r)
-		if err != nil {
-			// No token - continue without user context
-			next.ServeHTTP(w, r)
+		// Scripts authenticate with a personal access token instead of cookies
+		if secret, ok := bearerAccessToken(r); ok {
+			serveWithAccessToken(w, r, secret, next)
 			return
 		}
 
-		// Parse JWT to get claims (without verification for now in development)
+		// Read the session token (without verification for now in development)
 		// TODO: In production, implement proper JWT verification with JWKS
-		claims, err := jwtutil.ParseJWTWithoutVerification(token)
+		info, err := auth.TokenInfo(r)
+		if errors.Is(err, http.ErrNoCookie) {
+			// No token - continue without user context
+			next.ServeHTTP(w, r)
+			return
+		}
 		if err != nil {
 			logger.Warn("Failed to parse JWT claims", "error", err)
 			// Continue without user context rather than failing
@@ -42,18 +82,26 @@ func UserContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Validate that we have the minimum required claims
-		if claims.Sub == "" {
-			logger.Warn("JWT missing required subject (DID)")
-			next.ServeHTTP(w, r)
-			return
+		// Tokens from revoked sessions stay in the browser until they expire,
+		// so the server-side session decides whether they still count
+		var profile session.Profile
+		if v := sessionValidator.Load(); v != nil && *v != nil {
+			var live bool
+			if profile, live = (*v)(r, info.DID); !live {
+				logger.Debug("Session revoked or expired", "did", info.DID)
+				next.ServeHTTP(w, r)
+				return
+			}
 		}
 
 		// Create user context with available information
 		userCtx := &UserContext{
-			DID:   claims.Sub,
-			PDS:   claims.Iss,
-			Scope: claims.Scope,
+			DID:         info.DID,
+			Handle:      profile.Handle,
+			DisplayName: profile.DisplayName,
+			Avatar:      profile.Avatar,
+			PDS:         info.PDS,
+			Scope:       info.Scope,
 		}
 
 		// Log user context creation for debugging
@@ -65,6 +113,47 @@ func UserContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// bearerAccessToken returns the personal access token in the request's
+// Authorization header. Other bearer tokens are left alone.
+func bearerAccessToken(r *http.Request) (string, bool) {
+	scheme, secret, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(secret, accesstoken.Prefix) {
+		return "", false
+	}
+	return secret, true
+}
+
+// serveWithAccessToken serves a request made with a personal access token as
+// the token's owner. Unlike a stale cookie, a bad token is refused rather
+// than served anonymously, so scripts learn their token no longer works.
+func serveWithAccessToken(w http.ResponseWriter, r *http.Request, secret string, next http.Handler) {
+	var token accesstoken.Token
+	var ok bool
+	if a := tokenAuthenticator.Load(); a != nil && *a != nil {
+		token, ok = (*a)(r, secret)
+	}
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		httputil.WriteError(w, http.StatusUnauthorized, "Access token is invalid, expired or revoked")
+		return
+	}
+
+	scope := accesstoken.ScopeWrite
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		scope = accesstoken.ScopeRead
+	}
+	if !token.Allows(scope) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		httputil.WriteError(w, http.StatusForbidden, "Access token lacks the "+scope+" scope", "did", token.DID, "token", token.ID)
+		return
+	}
+
+	userCtx := &UserContext{DID: token.DID, TokenID: token.ID}
+	ctx := context.WithValue(r.Context(), userContextKey, userCtx)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // GetUserContext extracts user context from request context
 func GetUserContext(r *http.Request) (*UserContext, bool) {
 	userCtx, ok := r.Context().Value(userContextKey).(*UserContext)
@@ -76,7 +165,7 @@ func RequireUserContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userCtx, ok := GetUserContext(r)
 		if !ok || userCtx.DID == "" {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			http.Redirect(w, r, loginredirect.LoginURL(r), http.StatusSeeOther)
 			return
 		}
 		next.ServeHTTP(w, r)
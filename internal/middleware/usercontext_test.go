@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
+)
+
+func TestUserContextMiddlewareAccessTokens(t *testing.T) {
+	tokens := map[string]accesstoken.Token{
+		"dqp_reader": {ID: "tok1", DID: "did:plc:alice", Scopes: []string{accesstoken.ScopeRead}},
+		"dqp_writer": {ID: "tok2", DID: "did:plc:alice", Scopes: []string{accesstoken.ScopeWrite}},
+	}
+	SetTokenAuthenticator(func(_ *http.Request, secret string) (accesstoken.Token, bool) {
+		token, ok := tokens[secret]
+		return token, ok
+	})
+	t.Cleanup(func() { SetTokenAuthenticator(nil) })
+
+	handler := UserContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userCtx, ok := GetUserContext(r)
+		if !ok {
+			_, _ = w.Write([]byte("anonymous"))
+			return
+		}
+		_, _ = w.Write([]byte(userCtx.DID + " " + userCtx.TokenID))
+	}))
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{"read token reads", "GET", "Bearer dqp_reader", http.StatusOK, "did:plc:alice tok1", ""},
+		{"scheme is case-insensitive", "GET", "bearer dqp_reader", http.StatusOK, "did:plc:alice tok1", ""},
+		{"read token cannot write", "POST", "Bearer dqp_reader", http.StatusForbidden, "", `error="insufficient_scope", scope="write"`},
+		{"write token writes", "DELETE", "Bearer dqp_writer", http.StatusOK, "did:plc:alice tok2", ""},
+		{"write token reads", "GET", "Bearer dqp_writer", http.StatusOK, "did:plc:alice tok2", ""},
+		{"unknown token is refused", "GET", "Bearer dqp_revoked", http.StatusUnauthorized, "", `error="invalid_token"`},
+		{"other bearer tokens are ignored", "GET", "Bearer eyJhbGciOi", http.StatusOK, "anonymous", ""},
+		{"no credentials", "GET", "", http.StatusOK, "anonymous", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/topics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.wantChallenge) || (tt.wantChallenge == "" && got != "") {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestUserContextMiddlewareRefusesTokensWithoutAuthenticator(t *testing.T) {
+	SetTokenAuthenticator(nil)
+	handler := UserContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	req := httptest.NewRequest("GET", "/api/topics", nil)
+	req.Header.Set("Authorization", "Bearer dqp_anything")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,54 @@
+package mirror
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricsSink receives measurements of mirror ingestion. Implementations
+// must be safe for concurrent use.
+type MetricsSink interface {
+	// ObserveQueue records how many events wait in origin's queue
+	ObserveQueue(origin string, depth int)
+	// ObserveCommit records a batch of events committed from origin and
+	// how long ago the origin published the newest of them
+	ObserveCommit(origin string, events int, lag time.Duration)
+	// CountDropped records an event discarded because origin's queue was full
+	CountDropped(origin, collection string)
+	// CountParked records origin being parked because its queue was full
+	CountParked(origin string)
+	// CountReplayed records an event from origin skipped as a replay
+	CountReplayed(origin string)
+	// CountUnverified records a record from origin that could not be
+	// verified against its author's repository
+	CountUnverified(origin, collection string)
+}
+
+// sinkHolder lets a nil sink be stored in an atomic.Value
+type sinkHolder struct{ sink MetricsSink }
+
+var defaultSink atomic.Value
+
+// SetMetrics sends measurements from every follower to sink. A nil sink
+// turns them off.
+func SetMetrics(sink MetricsSink) {
+	defaultSink.Store(sinkHolder{sink})
+}
+
+// Metrics returns the sink set by SetMetrics, or one that discards
+// everything
+func Metrics() MetricsSink {
+	if h, ok := defaultSink.Load().(sinkHolder); ok && h.sink != nil {
+		return h.sink
+	}
+	return discard{}
+}
+
+type discard struct{}
+
+func (discard) ObserveQueue(string, int)                 {}
+func (discard) ObserveCommit(string, int, time.Duration) {}
+func (discard) CountDropped(string, string)              {}
+func (discard) CountParked(string)                       {}
+func (discard) CountReplayed(string)                     {}
+func (discard) CountUnverified(string, string)           {}
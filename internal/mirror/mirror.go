@@ -0,0 +1,335 @@
+// Package mirror follows the firehose of other dis.quest instances and
+// copies the topics in selected categories, with their messages, into a
+// local read-only index. Every mirrored record keeps the origin it came
+// from, and only that origin's stream can change or remove it. Other
+// lexicons are mirrored by registering a Handler for their collection.
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/repoproof"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+const (
+	// reconnectDelay is the wait before the first reconnect; it doubles
+	// with each failed attempt up to maxReconnectDelay
+	reconnectDelay    = time.Second
+	maxReconnectDelay = 5 * time.Minute
+)
+
+// Source is a remote instance and the categories mirrored from it
+type Source struct {
+	// Origin is the instance's public URL, e.g. https://forum.example.com
+	Origin     string
+	Categories []string
+}
+
+// ParseSources parses space-separated origin=category[,category...]
+// entries, as in the mirror_sources setting
+func ParseSources(spec string) ([]Source, error) {
+	var sources []Source
+	for _, entry := range strings.Fields(spec) {
+		origin, categories, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("mirror source %q: expected origin=categories", entry)
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return nil, fmt.Errorf("mirror source %q: origin must be an http(s) URL without a path", entry)
+		}
+		source := Source{Origin: u.Scheme + "://" + u.Host}
+		for _, category := range strings.Split(categories, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				source.Categories = append(source.Categories, category)
+			}
+		}
+		if len(source.Categories) == 0 {
+			return nil, fmt.Errorf("mirror source %q: at least one category is required", entry)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// SubscribeURL returns the origin's firehose URL for collections,
+// replaying from cursor
+func (s Source) SubscribeURL(cursor int64, collections []string) string {
+	query := url.Values{
+		"wantedCollections": collections,
+		"cursor":            {strconv.FormatInt(cursor, 10)},
+	}
+	origin := s.Origin
+	if rest, ok := strings.CutPrefix(origin, "https://"); ok {
+		origin = "wss://" + rest
+	} else if rest, ok := strings.CutPrefix(origin, "http://"); ok {
+		origin = "ws://" + rest
+	}
+	return origin + "/subscribe?" + query.Encode()
+}
+
+// category returns the first of tags that the source mirrors
+func (s Source) category(tags []string) (string, bool) {
+	for _, tag := range tags {
+		if slices.ContainsFunc(s.Categories, func(c string) bool { return strings.EqualFold(c, tag) }) {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// Topic is a mirrored quest.dis.topic record
+type Topic struct {
+	Origin         string
+	Did            string
+	Rkey           string
+	Subject        string
+	InitialMessage string
+	Category       string
+	CreatedAt      time.Time
+	// Verified is set when the record was proven against its author's
+	// signed repository
+	Verified bool
+}
+
+// Message is a mirrored quest.dis.message record in a mirrored topic
+type Message struct {
+	Origin            string
+	Did               string
+	Rkey              string
+	TopicDid          string
+	TopicRkey         string
+	ParentMessageRkey string
+	Content           string
+	CreatedAt         time.Time
+	Verified          bool
+}
+
+// Revision is the newest event applied to a mirrored record
+type Revision struct {
+	Did        string
+	Collection string
+	Rkey       string
+	// CID identifies the record's content: its repository CID when the
+	// origin sent it or the record was verified, otherwise a hash of the
+	// record. It is empty for deletes.
+	CID     string
+	TimeUS  int64
+	Deleted bool
+}
+
+// Store keeps mirrored records and how far each origin has been followed.
+// Records are keyed by origin as well as DID and rkey, since two origins
+// may index the same record.
+type Store interface {
+	// Cursor returns the last event applied from origin, or 0 if none
+	Cursor(ctx context.Context, origin string) (int64, error)
+	SaveCursor(ctx context.Context, origin string, cursor int64) error
+	// Revision returns the newest event applied to a record, reporting
+	// false when none has been
+	Revision(ctx context.Context, origin, did, collection, rkey string) (Revision, bool, error)
+	SaveRevision(ctx context.Context, origin string, rev Revision) error
+	HasTopic(ctx context.Context, origin, did, rkey string) (bool, error)
+	SaveTopic(ctx context.Context, topic Topic) error
+	SaveMessage(ctx context.Context, message Message) error
+	// DeleteTopic removes a topic and its messages
+	DeleteTopic(ctx context.Context, origin, did, rkey string) error
+	DeleteMessage(ctx context.Context, origin, did, rkey string) error
+}
+
+// Verifier proves records against their authors' signed repositories, as
+// *repoproof.Verifier does
+type Verifier interface {
+	Verify(ctx context.Context, did, collection, rkey string) (repoproof.Record, error)
+}
+
+// stream is the client end of a firehose connection
+type stream interface {
+	ReadMessage() (opcode int, data []byte, err error)
+	Close(code int, reason string) error
+}
+
+// Follower mirrors a single source
+type Follower struct {
+	source  Source
+	store   Store
+	options Options
+	dial    func(ctx context.Context, rawURL string) (stream, error)
+	now     func() time.Time
+}
+
+// NewFollower returns a follower that mirrors source into store. Zero
+// options take their defaults, and without a registry topics and messages
+// are indexed into store.
+func NewFollower(source Source, store Store, options Options) *Follower {
+	if options.Registry == nil {
+		options.Registry = DefaultRegistry(store)
+	}
+	return &Follower{
+		source:  source,
+		store:   store,
+		options: options.withDefaults(),
+		dial: func(ctx context.Context, rawURL string) (stream, error) {
+			return websocket.Dial(ctx, rawURL)
+		},
+		now: time.Now,
+	}
+}
+
+// Apply mirrors a single event from the source and advances the cursor to
+// it; a replayed event never moves the cursor back
+func (f *Follower) Apply(ctx context.Context, event firehose.Event) error {
+	if err := f.apply(ctx, event); err != nil {
+		return err
+	}
+	cursor, err := f.store.Cursor(ctx, f.source.Origin)
+	if err != nil || cursor >= event.TimeUS {
+		return err
+	}
+	return f.store.SaveCursor(ctx, f.source.Origin, event.TimeUS)
+}
+
+// apply passes a single event to its collection's handler unless it is a
+// replay: an event no newer than the last one applied to its record, which
+// arrives again after a reconnect or out of order, or one repeating the
+// record's content. Records the origin serves but this build cannot decode
+// are skipped rather than stalling the stream. With a verifier, created and
+// updated records are mirrored as their authors' repositories prove them;
+// see verify.
+func (f *Follower) apply(ctx context.Context, event firehose.Event) error {
+	collection := collectionOf(event)
+	handler, ok := f.options.Registry.Handler(collection)
+	if !ok {
+		return nil
+	}
+	origin := f.source.Origin
+	rev := revisionOf(event)
+	last, seen, err := f.store.Revision(ctx, origin, rev.Did, collection, rev.Rkey)
+	if err != nil {
+		return err
+	}
+	if seen && rev.TimeUS <= last.TimeUS {
+		Metrics().CountReplayed(origin)
+		return nil
+	}
+	if seen && rev.CID == last.CID && rev.Deleted == last.Deleted {
+		// Nothing changed; the newer time still has to be recorded so
+		// older events stay stale
+		Metrics().CountReplayed(origin)
+		return f.store.SaveRevision(ctx, origin, rev)
+	}
+
+	change := Change{
+		Source:     f.source,
+		Did:        event.Did,
+		Collection: collection,
+		Rkey:       rev.Rkey,
+		Record:     event.Commit.Record,
+		Deleted:    rev.Deleted,
+	}
+	if apply, err := f.verify(ctx, &change, &rev); err != nil || !apply {
+		return err
+	}
+	if err := handler.Apply(ctx, change); err != nil && !errors.Is(err, lexicon.ErrInvalidRecord) {
+		return err
+	}
+	return f.store.SaveRevision(ctx, origin, rev)
+}
+
+// verify proves a created or updated record against its author's
+// repository, replacing the change's record with the one the repository
+// holds, since the origin may relay a stale or altered copy. A record that
+// cannot be proven is applied unverified, or skipped under
+// Options.RequireVerified; its revision is not recorded, so a replay tries
+// again. Deletes are applied as they are: they only remove content. It
+// reports whether the change should be applied.
+func (f *Follower) verify(ctx context.Context, change *Change, rev *Revision) (bool, error) {
+	if f.options.Verifier == nil || change.Deleted {
+		return true, nil
+	}
+	record, err := f.options.Verifier.Verify(ctx, change.Did, change.Collection, change.Rkey)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		Metrics().CountUnverified(f.source.Origin, change.Collection)
+		return !f.options.RequireVerified, nil
+	}
+	change.Record = record.Value
+	change.Verified = true
+	rev.CID = record.CID
+	return true, nil
+}
+
+// revisionOf returns the revision an event would make its record
+func revisionOf(event firehose.Event) Revision {
+	rev := Revision{
+		Did:        event.Did,
+		Collection: event.Commit.Collection,
+		Rkey:       event.Commit.Rkey,
+		TimeUS:     event.TimeUS,
+		Deleted:    event.Commit.Operation == firehose.OperationDelete,
+	}
+	switch {
+	case rev.Deleted:
+	case event.Commit.CID != "":
+		rev.CID = event.Commit.CID
+	default:
+		sum := sha256.Sum256(event.Commit.Record)
+		rev.CID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return rev
+}
+
+// Follow streams events from the source until the connection drops or ctx
+// is done. A source followed for the first time is replayed from the start
+// of the origin's retention window. It returns ErrParked when the source
+// was parked for overload.
+func (f *Follower) Follow(ctx context.Context) error {
+	_, err := f.follow(ctx)
+	return err
+}
+
+// Run follows the source until ctx is done, reconnecting with backoff, or
+// after parkDelay when the source was parked. Connection and store errors
+// are passed to onError.
+func (f *Follower) Run(ctx context.Context, onError func(error)) {
+	delay := reconnectDelay
+	for {
+		received, err := f.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if received {
+			delay = reconnectDelay
+		}
+		wait := delay
+		switch {
+		case errors.Is(err, ErrParked):
+			wait = parkDelay
+		case err != nil:
+			onError(fmt.Errorf("mirror %s: %w", f.source.Origin, err))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
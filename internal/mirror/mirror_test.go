@@ -0,0 +1,424 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/repoproof"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+const origin = "https://forum.example.com"
+
+type key struct{ origin, did, rkey string }
+
+type revisionKey struct{ origin, did, collection, rkey string }
+
+type memStore struct {
+	mu        sync.Mutex
+	cursors   map[string]int64
+	revisions map[revisionKey]Revision
+	topics    map[key]Topic
+	messages  map[key]Message
+	// writes counts record saves and deletes
+	writes int
+	err    error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		cursors:   map[string]int64{},
+		revisions: map[revisionKey]Revision{},
+		topics:    map[key]Topic{},
+		messages:  map[key]Message{},
+	}
+}
+
+func (s *memStore) Cursor(_ context.Context, origin string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursors[origin], s.err
+}
+
+func (s *memStore) SaveCursor(_ context.Context, origin string, cursor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[origin] = cursor
+	return s.err
+}
+
+func (s *memStore) Revision(_ context.Context, origin, did, collection, rkey string) (Revision, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rev, ok := s.revisions[revisionKey{origin, did, collection, rkey}]
+	return rev, ok, s.err
+}
+
+func (s *memStore) SaveRevision(_ context.Context, origin string, rev Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revisions[revisionKey{origin, rev.Did, rev.Collection, rev.Rkey}] = rev
+	return s.err
+}
+
+func (s *memStore) HasTopic(_ context.Context, origin, did, rkey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.topics[key{origin, did, rkey}]
+	return ok, s.err
+}
+
+func (s *memStore) SaveTopic(_ context.Context, topic Topic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topics[key{topic.Origin, topic.Did, topic.Rkey}] = topic
+	s.writes++
+	return s.err
+}
+
+func (s *memStore) SaveMessage(_ context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[key{message.Origin, message.Did, message.Rkey}] = message
+	s.writes++
+	return s.err
+}
+
+func (s *memStore) DeleteTopic(_ context.Context, origin, did, rkey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.topics, key{origin, did, rkey})
+	s.writes++
+	for k, message := range s.messages {
+		if k.origin == origin && message.TopicDid == did && message.TopicRkey == rkey {
+			delete(s.messages, k)
+		}
+	}
+	return s.err
+}
+
+func (s *memStore) DeleteMessage(_ context.Context, origin, did, rkey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, key{origin, did, rkey})
+	s.writes++
+	return s.err
+}
+
+func commit(timeUS int64, did, operation, collection, rkey string, record map[string]any) firehose.Event {
+	event := firehose.Event{Did: did, TimeUS: timeUS, Kind: firehose.KindCommit, Commit: &firehose.Commit{
+		Operation: operation, Collection: collection, Rkey: rkey,
+	}}
+	if record != nil {
+		event.Commit.Record, _ = json.Marshal(record)
+	}
+	return event
+}
+
+func topicRecord(title string, tags ...string) map[string]any {
+	return map[string]any{
+		"$type": "quest.dis.topic", "title": title, "summary": "Details",
+		"tags": tags, "createdBy": "did:plc:alice", "createdAt": "2025-01-02T03:04:05Z",
+	}
+}
+
+func messageRecord(topicRkey, content string) map[string]any {
+	return map[string]any{
+		"$type": "quest.dis.message", "topic": "at://did:plc:alice/quest.dis.topic/" + topicRkey,
+		"content": content, "createdAt": "2025-01-02T04:00:00Z",
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources(" https://a.example/=go,Rust  http://b.example:8080=ops ")
+	if err != nil {
+		t.Fatalf("ParseSources: %v", err)
+	}
+	want := []Source{
+		{Origin: "https://a.example", Categories: []string{"go", "Rust"}},
+		{Origin: "http://b.example:8080", Categories: []string{"ops"}},
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("ParseSources = %+v, want %+v", sources, want)
+	}
+	for i := range want {
+		if sources[i].Origin != want[i].Origin || !slices.Equal(sources[i].Categories, want[i].Categories) {
+			t.Errorf("source %d = %+v, want %+v", i, sources[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"https://a.example", "https://a.example=", "ftp://a.example=go", "https://a.example/forum=go"} {
+		if _, err := ParseSources(bad); err == nil {
+			t.Errorf("ParseSources(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSubscribeURL(t *testing.T) {
+	got := Source{Origin: origin}.SubscribeURL(42, DefaultRegistry(newMemStore()).Collections())
+	want := "wss://forum.example.com/subscribe?cursor=42&wantedCollections=quest.dis.topic&wantedCollections=quest.dis.message"
+	if got != want {
+		t.Errorf("SubscribeURL = %q, want %q", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{})
+	alice := "did:plc:alice"
+	apply := func(event firehose.Event) {
+		t.Helper()
+		if err := f.Apply(ctx, event); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+
+	apply(commit(1, alice, firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "Go")))
+	apply(commit(2, alice, firehose.OperationCreate, "quest.dis.topic", "t2", topicRecord("Sourdough", "baking")))
+	apply(commit(3, "did:plc:bob", firehose.OperationCreate, "quest.dis.message", "m1", messageRecord("t1", "Use constraints")))
+	apply(commit(4, "did:plc:bob", firehose.OperationCreate, "quest.dis.message", "m2", messageRecord("t2", "More water")))
+	apply(commit(5, alice, firehose.OperationCreate, "quest.dis.topic", "bad", map[string]any{"title": 7}))
+
+	topic, ok := store.topics[key{origin, alice, "t1"}]
+	if !ok || topic.Subject != "Generics" || topic.Category != "Go" || topic.InitialMessage != "Details" || topic.CreatedAt.IsZero() {
+		t.Errorf("expected the go topic mirrored with provenance, got %+v", topic)
+	}
+	if len(store.topics) != 1 {
+		t.Errorf("expected only the topic in a mirrored category, got %+v", store.topics)
+	}
+	if message, ok := store.messages[key{origin, "did:plc:bob", "m1"}]; !ok || message.TopicRkey != "t1" || message.Content != "Use constraints" {
+		t.Errorf("expected the message in the mirrored topic, got %+v", store.messages)
+	}
+	if len(store.messages) != 1 {
+		t.Errorf("expected messages in unmirrored topics to be skipped, got %+v", store.messages)
+	}
+	if store.cursors[origin] != 5 {
+		t.Errorf("cursor = %d, want 5 after skipping the invalid record", store.cursors[origin])
+	}
+
+	// Retagging a topic out of the mirrored categories drops it with its messages
+	apply(commit(6, alice, firehose.OperationUpdate, "quest.dis.topic", "t1", topicRecord("Generics", "off-topic")))
+	if len(store.topics) != 0 || len(store.messages) != 0 {
+		t.Errorf("expected the retagged topic removed, got %+v %+v", store.topics, store.messages)
+	}
+
+	store.err = errors.New("database down")
+	if err := f.Apply(ctx, commit(7, alice, firehose.OperationDelete, "quest.dis.topic", "t1", nil)); !errors.Is(err, store.err) {
+		t.Errorf("Apply error = %v, want %v", err, store.err)
+	}
+}
+
+func TestApplyReplays(t *testing.T) {
+	ctx := context.Background()
+	alice := "did:plc:alice"
+	create := commit(1, alice, firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "go"))
+	update := commit(2, alice, firehose.OperationUpdate, "quest.dis.topic", "t1", topicRecord("Generics, revisited", "go"))
+	remove := commit(3, alice, firehose.OperationDelete, "quest.dis.topic", "t1", nil)
+	// sameContent repeats the create's record in a later event, as a
+	// backfill overlapping the live stream would
+	sameContent := create
+	sameContent.TimeUS = 4
+
+	tests := []struct {
+		name         string
+		events       []firehose.Event
+		wantSubject  string
+		wantWrites   int
+		wantReplayed int
+	}{
+		{"duplicate create", []firehose.Event{create, create}, "Generics", 1, 1},
+		{"update before create", []firehose.Event{update, create}, "Generics, revisited", 1, 1},
+		{"delete before create and update", []firehose.Event{remove, create, update}, "", 1, 2},
+		{"stream replayed after a reconnect", []firehose.Event{create, update, create, update}, "Generics, revisited", 2, 2},
+		{"same content in a newer event outdates older ones", []firehose.Event{create, sameContent, update}, "Generics", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			sink := newRecordingSink(t)
+			f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{})
+			for _, event := range tt.events {
+				if err := f.Apply(ctx, event); err != nil {
+					t.Fatalf("Apply: %v", err)
+				}
+			}
+
+			topic, ok := store.topics[key{origin, alice, "t1"}]
+			if topic.Subject != tt.wantSubject || ok != (tt.wantSubject != "") {
+				t.Errorf("topic = %+v (%t), want subject %q", topic, ok, tt.wantSubject)
+			}
+			if store.writes != tt.wantWrites {
+				t.Errorf("writes = %d, want %d", store.writes, tt.wantWrites)
+			}
+			if sink.replayed != tt.wantReplayed {
+				t.Errorf("replayed = %d, want %d", sink.replayed, tt.wantReplayed)
+			}
+			if want := slices.MaxFunc(tt.events, func(a, b firehose.Event) int { return int(a.TimeUS - b.TimeUS) }).TimeUS; store.cursors[origin] != want {
+				t.Errorf("cursor = %d, want %d", store.cursors[origin], want)
+			}
+		})
+	}
+}
+
+func TestRevisionOf(t *testing.T) {
+	event := commit(1, "did:plc:alice", firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "go"))
+	hashed := revisionOf(event)
+	if !strings.HasPrefix(hashed.CID, "sha256:") || hashed.Deleted {
+		t.Errorf("expected a content hash without a CID, got %+v", hashed)
+	}
+	event.Commit.CID = "bafyrecord"
+	if rev := revisionOf(event); rev.CID != "bafyrecord" {
+		t.Errorf("expected the origin's CID, got %+v", rev)
+	}
+	if rev := revisionOf(commit(2, "did:plc:alice", firehose.OperationDelete, "quest.dis.topic", "t1", nil)); rev.CID != "" || !rev.Deleted {
+		t.Errorf("expected a tombstone, got %+v", rev)
+	}
+}
+
+// fakeVerifier proves the records it holds, keyed by rkey
+type fakeVerifier struct {
+	mu      sync.Mutex
+	records map[string]repoproof.Record
+	calls   int
+}
+
+func (v *fakeVerifier) Verify(_ context.Context, _, _, rkey string) (repoproof.Record, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.calls++
+	record, ok := v.records[rkey]
+	if !ok {
+		return repoproof.Record{}, fmt.Errorf("%w: record is not in the repository", repoproof.ErrUnverified)
+	}
+	return record, nil
+}
+
+func TestApplyVerifies(t *testing.T) {
+	ctx := context.Background()
+	alice := "did:plc:alice"
+	proven, _ := json.Marshal(topicRecord("Generics", "go"))
+	newVerifier := func() *fakeVerifier {
+		return &fakeVerifier{records: map[string]repoproof.Record{"t1": {CID: "bafyproven", Value: proven}}}
+	}
+	// The origin relays an altered copy of t1, and t2 is not in its
+	// author's repository at all
+	altered := commit(1, alice, firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics (sponsored)", "go"))
+	unproven := commit(2, alice, firehose.OperationCreate, "quest.dis.topic", "t2", topicRecord("Sourdough", "go"))
+
+	t.Run("records are mirrored as their repository proves them", func(t *testing.T) {
+		store := newMemStore()
+		sink := newRecordingSink(t)
+		f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{Verifier: newVerifier()})
+		for _, event := range []firehose.Event{altered, unproven} {
+			if err := f.Apply(ctx, event); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+		}
+		if topic := store.topics[key{origin, alice, "t1"}]; topic.Subject != "Generics" || !topic.Verified {
+			t.Errorf("expected the proven topic, got %+v", topic)
+		}
+		if rev := store.revisions[revisionKey{origin, alice, "quest.dis.topic", "t1"}]; rev.CID != "bafyproven" {
+			t.Errorf("expected the proven CID recorded, got %+v", rev)
+		}
+		if topic, ok := store.topics[key{origin, alice, "t2"}]; !ok || topic.Verified {
+			t.Errorf("expected the unproven topic mirrored unverified, got %+v", topic)
+		}
+		if sink.unverified != 1 {
+			t.Errorf("unverified = %d, want 1", sink.unverified)
+		}
+	})
+
+	t.Run("unverified records can be required away", func(t *testing.T) {
+		store := newMemStore()
+		verifier := newVerifier()
+		f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{Verifier: verifier, RequireVerified: true})
+		for _, event := range []firehose.Event{altered, unproven} {
+			if err := f.Apply(ctx, event); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+		}
+		if _, ok := store.topics[key{origin, alice, "t2"}]; ok || len(store.topics) != 1 {
+			t.Errorf("expected only the proven topic, got %+v", store.topics)
+		}
+
+		// The skipped record is tried again when it is replayed, and
+		// deletes need no proof
+		verifier.records["t2"] = repoproof.Record{CID: "bafyt2", Value: unproven.Commit.Record}
+		if err := f.Apply(ctx, unproven); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if topic := store.topics[key{origin, alice, "t2"}]; !topic.Verified {
+			t.Errorf("expected the replayed topic verified, got %+v", topic)
+		}
+		calls := verifier.calls
+		if err := f.Apply(ctx, commit(3, alice, firehose.OperationDelete, "quest.dis.topic", "t1", nil)); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if _, ok := store.topics[key{origin, alice, "t1"}]; ok || verifier.calls != calls {
+			t.Errorf("expected the delete applied without a proof, got %+v after %d calls", store.topics, verifier.calls-calls)
+		}
+	})
+}
+
+type fakeStream struct {
+	mu       sync.Mutex
+	messages [][]byte
+	closed   bool
+}
+
+func (s *fakeStream) ReadMessage() (int, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.messages) == 0 {
+		return 0, nil, websocket.ErrClosed
+	}
+	data := s.messages[0]
+	s.messages = s.messages[1:]
+	return websocket.OpText, data, nil
+}
+
+func (s *fakeStream) Close(int, string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func TestFollow(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	store.cursors[origin] = 10
+	event, _ := json.Marshal(commit(11, "did:plc:alice", firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "go")))
+	conn := &fakeStream{messages: [][]byte{event}}
+
+	var dialed string
+	f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{})
+	f.dial = func(_ context.Context, rawURL string) (stream, error) {
+		dialed = rawURL
+		return conn, nil
+	}
+
+	if err := f.Follow(ctx); !errors.Is(err, websocket.ErrClosed) {
+		t.Fatalf("Follow error = %v, want ErrClosed", err)
+	}
+	if !strings.Contains(dialed, "cursor=11") {
+		t.Errorf("expected to resume after the stored cursor, dialed %q", dialed)
+	}
+	if len(store.topics) != 1 || store.cursors[origin] != 11 || !conn.isClosed() {
+		t.Errorf("expected the streamed topic applied and the stream closed, got %+v cursor %d", store.topics, store.cursors[origin])
+	}
+}
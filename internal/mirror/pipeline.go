@@ -0,0 +1,248 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+// What a follower does when its queue is full
+const (
+	// OverloadBlock stops reading until the queue has room. The origin may
+	// disconnect a follower that stays behind; it resumes from its cursor.
+	OverloadBlock = "block"
+	// OverloadPark closes the stream once the queue fills, commits what was
+	// queued, and resumes from the cursor after parkDelay
+	OverloadPark = "park"
+	// OverloadDrop discards events that arrive while the queue is full.
+	// They are never mirrored, so use it only where keeping up matters
+	// more than completeness.
+	OverloadDrop = "drop"
+)
+
+const (
+	defaultQueueSize = 1000
+	defaultWorkers   = 4
+	defaultBatchSize = 100
+	// parkDelay is how long a parked source waits before reconnecting
+	parkDelay = 30 * time.Second
+)
+
+// ErrParked is returned by Follow when the queue filled under OverloadPark
+var ErrParked = errors.New("mirror: source parked while the queue drains")
+
+// Options tunes how a follower ingests its stream. Events are read into a
+// bounded queue and committed in batches: each collection's events in a
+// batch are spread over its workers, with every record always handled by
+// the same worker so its changes stay in order, and the cursor is saved
+// once the whole batch is applied. A failed batch is retried from the
+// cursor after reconnecting; every store write is idempotent.
+type Options struct {
+	// QueueSize bounds the events read ahead of the database
+	QueueSize int
+	// Workers is how many events of each collection are applied at once
+	Workers int
+	// BatchSize bounds the events committed under one cursor update
+	BatchSize int
+	// Overload is OverloadBlock, OverloadPark or OverloadDrop
+	Overload string
+	// Verifier, if set, proves each created or updated record against its
+	// author's signed repository before it is mirrored
+	Verifier Verifier
+	// RequireVerified skips records the verifier cannot prove instead of
+	// mirroring them marked unverified
+	RequireVerified bool
+	// Registry holds the handlers of the collections to mirror
+	Registry *Registry
+}
+
+func (o Options) withDefaults() Options {
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	if o.Workers <= 0 {
+		o.Workers = defaultWorkers
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBatchSize
+	}
+	if o.Overload == "" {
+		o.Overload = OverloadBlock
+	}
+	return o
+}
+
+// follow streams events from the source into the queue and commits them
+// until the connection drops, the source is parked or ctx is done. It
+// reports whether any event arrived.
+func (f *Follower) follow(ctx context.Context) (received bool, err error) {
+	cursor, err := f.store.Cursor(ctx, f.source.Origin)
+	if err != nil {
+		return false, err
+	}
+	conn, err := f.dial(ctx, f.source.SubscribeURL(cursor+1, f.options.Registry.Collections()))
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer func() { _ = conn.Close(websocket.CloseNormal, "") }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close(websocket.CloseGoingAway, "") })
+	defer stop()
+
+	queue := make(chan firehose.Event, f.options.QueueSize)
+	var readErr error
+	var got atomic.Bool
+	go func() {
+		defer close(queue)
+		readErr = f.read(ctx, conn, queue, &got)
+	}()
+
+	for {
+		batch, open := nextBatch(queue, f.options.BatchSize)
+		Metrics().ObserveQueue(f.source.Origin, len(queue))
+		if len(batch) > 0 {
+			if err := f.commit(ctx, batch); err != nil {
+				return got.Load(), err
+			}
+		}
+		if !open {
+			// The reader has finished, so readErr is set
+			if ctx.Err() != nil {
+				return got.Load(), ctx.Err()
+			}
+			return got.Load(), readErr
+		}
+	}
+}
+
+// read decodes events from conn into queue, applying the overload policy
+// when it is full, until the stream ends or ctx is done
+func (f *Follower) read(ctx context.Context, conn stream, queue chan<- firehose.Event, got *atomic.Bool) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		got.Store(true)
+		var event firehose.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("malformed event: %w", err)
+		}
+
+		select {
+		case queue <- event:
+			continue
+		default:
+		}
+		switch f.options.Overload {
+		case OverloadDrop:
+			Metrics().CountDropped(f.source.Origin, collectionOf(event))
+		case OverloadPark:
+			Metrics().CountParked(f.source.Origin)
+			// The cursor stops before this event, so it is read again
+			return ErrParked
+		default:
+			select {
+			case queue <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// nextBatch waits for an event, then takes up to size events without
+// waiting further. It reports false once queue is closed and empty.
+func nextBatch(queue <-chan firehose.Event, size int) ([]firehose.Event, bool) {
+	event, ok := <-queue
+	if !ok {
+		return nil, false
+	}
+	batch := []firehose.Event{event}
+	for len(batch) < size {
+		select {
+		case event, ok := <-queue:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, event)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// commit applies a batch and saves the cursor of its last event
+func (f *Follower) commit(ctx context.Context, batch []firehose.Event) error {
+	for _, collection := range f.options.Registry.Collections() {
+		if err := f.applyCollection(ctx, collection, batch); err != nil {
+			return err
+		}
+	}
+	last := batch[len(batch)-1]
+	if err := f.store.SaveCursor(ctx, f.source.Origin, last.TimeUS); err != nil {
+		return err
+	}
+	Metrics().ObserveCommit(f.source.Origin, len(batch), f.now().Sub(time.UnixMicro(last.TimeUS)))
+	return nil
+}
+
+// applyCollection applies the batch's events in collection, spreading
+// records over the workers
+func (f *Follower) applyCollection(ctx context.Context, collection string, batch []firehose.Event) error {
+	shards := make([][]firehose.Event, f.options.Workers)
+	for _, event := range batch {
+		if collectionOf(event) == collection {
+			i := shard(event.Did, event.Commit.Rkey, len(shards))
+			shards[i] = append(shards[i], event)
+		}
+	}
+
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, events := range shards {
+		if len(events) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, event := range events {
+				if err := f.apply(ctx, event); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// collectionOf returns the collection a commit event touched, or "" for
+// other events
+func collectionOf(event firehose.Event) string {
+	if event.Kind != firehose.KindCommit || event.Commit == nil {
+		return ""
+	}
+	return event.Commit.Collection
+}
+
+// shard picks the worker for a record
+func shard(did, rkey string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(did))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(rkey))
+	return int(h.Sum32() % uint32(n)) // #nosec G115 -- n is a small positive worker count
+}
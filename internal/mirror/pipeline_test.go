@@ -0,0 +1,182 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+)
+
+// recordingSink counts overload, replay and verification measurements and
+// calls onOverload on the first overload
+type recordingSink struct {
+	mu         sync.Mutex
+	dropped    int
+	parked     int
+	replayed   int
+	unverified int
+	committed  int
+	onOverload sync.Once
+	overloaded chan struct{}
+}
+
+func newRecordingSink(t *testing.T) *recordingSink {
+	sink := &recordingSink{overloaded: make(chan struct{})}
+	SetMetrics(sink)
+	t.Cleanup(func() { SetMetrics(nil) })
+	return sink
+}
+
+func (s *recordingSink) ObserveQueue(string, int) {}
+
+func (s *recordingSink) ObserveCommit(_ string, events int, _ time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.committed += events
+}
+
+func (s *recordingSink) CountDropped(string, string) {
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+	s.onOverload.Do(func() { close(s.overloaded) })
+}
+
+func (s *recordingSink) CountReplayed(string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replayed++
+}
+
+func (s *recordingSink) CountUnverified(string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unverified++
+}
+
+func (s *recordingSink) CountParked(string) {
+	s.mu.Lock()
+	s.parked++
+	s.mu.Unlock()
+	s.onOverload.Do(func() { close(s.overloaded) })
+}
+
+// stalledStore holds the first topic write until release is closed
+type stalledStore struct {
+	*memStore
+	once    sync.Once
+	release <-chan struct{}
+}
+
+func (s *stalledStore) SaveTopic(ctx context.Context, topic Topic) error {
+	s.once.Do(func() { <-s.release })
+	return s.memStore.SaveTopic(ctx, topic)
+}
+
+func topicEvents(n int) [][]byte {
+	var messages [][]byte
+	for i := 1; i <= n; i++ {
+		data, _ := json.Marshal(commit(int64(i), "did:plc:alice", firehose.OperationCreate, "quest.dis.topic", fmt.Sprintf("t%d", i), topicRecord("Generics", "go")))
+		messages = append(messages, data)
+	}
+	return messages
+}
+
+// overloadedFollower follows five topic events with room for one queued
+// event while the first is stuck in the store until the queue overflows
+func overloadedFollower(t *testing.T, overload string) (*Follower, *memStore, *recordingSink) {
+	sink := newRecordingSink(t)
+	store := newMemStore()
+	conn := &fakeStream{messages: topicEvents(5)}
+	f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, &stalledStore{memStore: store, release: sink.overloaded},
+		Options{QueueSize: 1, BatchSize: 1, Workers: 2, Overload: overload})
+	f.dial = func(context.Context, string) (stream, error) { return conn, nil }
+	return f, store, sink
+}
+
+func TestFollowDropsWhenOverloaded(t *testing.T) {
+	f, store, sink := overloadedFollower(t, OverloadDrop)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := f.Follow(ctx); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Follow error = %v, want the stream to end", err)
+	}
+	if sink.dropped == 0 {
+		t.Fatal("expected events dropped while the queue was full")
+	}
+	if len(store.topics)+sink.dropped != 5 || sink.committed != len(store.topics) {
+		t.Errorf("mirrored %d and dropped %d of 5 events, committed %d", len(store.topics), sink.dropped, sink.committed)
+	}
+}
+
+func TestFollowParksWhenOverloaded(t *testing.T) {
+	f, store, sink := overloadedFollower(t, OverloadPark)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := f.Follow(ctx); !errors.Is(err, ErrParked) {
+		t.Fatalf("Follow error = %v, want ErrParked", err)
+	}
+	if sink.parked != 1 || sink.dropped != 0 {
+		t.Errorf("parked %d times and dropped %d events", sink.parked, sink.dropped)
+	}
+	// The first event was being applied and the second queued; the third
+	// parked the source and is read again after reconnecting
+	if store.cursors[origin] != 2 || len(store.topics) != 2 {
+		t.Errorf("cursor = %d with %d topics, want both queued events committed", store.cursors[origin], len(store.topics))
+	}
+}
+
+func TestCommitAppliesTopicsBeforeMessages(t *testing.T) {
+	store := newMemStore()
+	f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{Workers: 3})
+	alice, bob := "did:plc:alice", "did:plc:bob"
+	batch := []firehose.Event{
+		commit(1, bob, firehose.OperationCreate, "quest.dis.message", "m1", messageRecord("t1", "First!")),
+		commit(2, alice, firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "go")),
+		commit(3, bob, firehose.OperationCreate, "quest.dis.message", "m2", messageRecord("t1", "Second")),
+		commit(4, bob, firehose.OperationDelete, "quest.dis.message", "m2", nil),
+		commit(5, alice, firehose.OperationCreate, "quest.dis.topic", "t2", topicRecord("Sourdough", "baking")),
+	}
+	if err := f.commit(context.Background(), batch); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if len(store.topics) != 1 || len(store.messages) != 1 {
+		t.Errorf("expected the topic and its surviving message, got %+v %+v", store.topics, store.messages)
+	}
+	if _, ok := store.messages[key{origin, bob, "m1"}]; !ok {
+		t.Error("a message before its topic in the same batch should be mirrored")
+	}
+	if store.cursors[origin] != 5 {
+		t.Errorf("cursor = %d, want 5", store.cursors[origin])
+	}
+
+	store.err = errors.New("database down")
+	if err := f.commit(context.Background(), batch[1:2]); !errors.Is(err, store.err) {
+		t.Errorf("commit error = %v, want %v", err, store.err)
+	}
+}
+
+func TestNextBatch(t *testing.T) {
+	queue := make(chan firehose.Event, 5)
+	for i := range 5 {
+		queue <- firehose.Event{TimeUS: int64(i)}
+	}
+	close(queue)
+
+	if batch, open := nextBatch(queue, 3); len(batch) != 3 || !open {
+		t.Errorf("first batch = %d events, open %v; want 3, true", len(batch), open)
+	}
+	if batch, open := nextBatch(queue, 3); len(batch) != 2 || open {
+		t.Errorf("second batch = %d events, open %v; want 2, false", len(batch), open)
+	}
+	if batch, open := nextBatch(queue, 3); len(batch) != 0 || open {
+		t.Errorf("drained batch = %d events, open %v", len(batch), open)
+	}
+}
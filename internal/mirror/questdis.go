@@ -0,0 +1,90 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// topicHandler mirrors the topics tagged with one of their source's
+// categories
+type topicHandler struct{ store Store }
+
+func (h topicHandler) Apply(ctx context.Context, change Change) error {
+	origin := change.Source.Origin
+	if change.Deleted {
+		return h.store.DeleteTopic(ctx, origin, change.Did, change.Rkey)
+	}
+	record, err := decode(lexicon.TopicNSID, change.Record, lexicon.TopicFromMap)
+	if err != nil {
+		return err
+	}
+	category, ok := change.Source.category(record.Tags)
+	if !ok {
+		// An edit may have moved the topic out of the mirrored categories
+		return h.store.DeleteTopic(ctx, origin, change.Did, change.Rkey)
+	}
+	// FromMap has checked the format
+	createdAt, _ := time.Parse(time.RFC3339Nano, record.CreatedAt)
+	return h.store.SaveTopic(ctx, Topic{
+		Origin:         origin,
+		Did:            change.Did,
+		Rkey:           change.Rkey,
+		Subject:        record.Title,
+		InitialMessage: record.Summary,
+		Category:       category,
+		CreatedAt:      createdAt,
+		Verified:       change.Verified,
+	})
+}
+
+// messageHandler mirrors the messages in mirrored topics
+type messageHandler struct{ store Store }
+
+func (h messageHandler) Apply(ctx context.Context, change Change) error {
+	origin := change.Source.Origin
+	if change.Deleted {
+		return h.store.DeleteMessage(ctx, origin, change.Did, change.Rkey)
+	}
+	record, err := decode(lexicon.MessageNSID, change.Record, lexicon.MessageFromMap)
+	if err != nil {
+		return err
+	}
+	topic, err := pds.ParseATURI(record.Topic)
+	if err != nil || topic.Collection != lexicon.TopicNSID || topic.Rkey == "" {
+		return &lexicon.FieldError{NSID: lexicon.MessageNSID, Field: "topic", Reason: "must be a topic AT URI"}
+	}
+	mirrored, err := h.store.HasTopic(ctx, origin, topic.Repo, topic.Rkey)
+	if err != nil || !mirrored {
+		return err
+	}
+	// FromMap has checked the format
+	createdAt, _ := time.Parse(time.RFC3339Nano, record.CreatedAt)
+	return h.store.SaveMessage(ctx, Message{
+		Origin:            origin,
+		Did:               change.Did,
+		Rkey:              change.Rkey,
+		TopicDid:          topic.Repo,
+		TopicRkey:         topic.Rkey,
+		ParentMessageRkey: record.ReplyTo,
+		Content:           record.Content,
+		CreatedAt:         createdAt,
+		Verified:          change.Verified,
+	})
+}
+
+// decode upgrades a raw record to the current version of nsid and checks it
+func decode[T any](nsid string, raw json.RawMessage, fromMap func(map[string]any) (T, error)) (T, error) {
+	var zero T
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil || m == nil {
+		return zero, &lexicon.FieldError{NSID: nsid, Field: "record", Reason: "must be a JSON object"}
+	}
+	if _, err := lexicon.Upgrade(nsid, m); err != nil {
+		return zero, err
+	}
+	return fromMap(m)
+}
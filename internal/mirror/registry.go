@@ -0,0 +1,89 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+// Change is a record created, updated or deleted in a mirrored stream
+type Change struct {
+	Source     Source
+	Did        string
+	Collection string
+	Rkey       string
+	// Record is the record's JSON; it is nil when Deleted is set
+	Record  json.RawMessage
+	Deleted bool
+	// Verified is set when the record was proven against its author's
+	// signed repository
+	Verified bool
+}
+
+// Handler indexes the records of one collection. A record is always
+// handled by the same worker, so its changes arrive in order, but changes
+// to other records may be applied concurrently. Apply must be idempotent,
+// since a failed batch is applied again. Records that cannot be decoded
+// should be reported as lexicon.ErrInvalidRecord; they are skipped rather
+// than stalling the stream.
+type Handler interface {
+	Apply(ctx context.Context, change Change) error
+}
+
+// HandlerFunc adapts a function to a Handler
+type HandlerFunc func(ctx context.Context, change Change) error
+
+// Apply implements Handler
+func (f HandlerFunc) Apply(ctx context.Context, change Change) error {
+	return f(ctx, change)
+}
+
+// Registry maps collection NSIDs to the handlers that index them, so a new
+// lexicon is mirrored by registering a handler rather than changing the
+// follower. Followers subscribe to the registered collections and apply
+// each batch's events collection by collection, in the order they were
+// registered: register a collection before those whose records refer to
+// it. Register every handler before a follower starts.
+type Registry struct {
+	collections []string
+	handlers    map[string]Handler
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]Handler{}}
+}
+
+// DefaultRegistry returns a registry indexing quest.dis topics and their
+// messages into store
+func DefaultRegistry(store Store) *Registry {
+	r := NewRegistry()
+	r.Register(lexicon.TopicNSID, topicHandler{store})
+	r.Register(lexicon.MessageNSID, messageHandler{store})
+	return r
+}
+
+// Register adds the handler for the collection nsid. Registering a
+// collection twice is a programming error and panics.
+func (r *Registry) Register(nsid string, handler Handler) {
+	if _, ok := r.handlers[nsid]; ok {
+		panic(fmt.Sprintf("mirror: collection %s registered twice", nsid))
+	}
+	r.collections = append(r.collections, nsid)
+	r.handlers[nsid] = handler
+}
+
+// Collections returns the registered collections in the order they are
+// applied
+func (r *Registry) Collections() []string {
+	return slices.Clone(r.collections)
+}
+
+// Handler returns the handler registered for nsid
+func (r *Registry) Handler(nsid string) (Handler, bool) {
+	handler, ok := r.handlers[nsid]
+	return handler, ok
+}
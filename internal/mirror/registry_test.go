@@ -0,0 +1,99 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+const pollNSID = "quest.dis.poll"
+
+// pollIndex is a handler for a lexicon the follower knows nothing about
+type pollIndex struct {
+	mu    sync.Mutex
+	polls map[string]string
+	// topicsSeen records how many topics were mirrored when each poll
+	// arrived
+	topicsSeen []int
+	store      *memStore
+}
+
+func (p *pollIndex) Apply(_ context.Context, change Change) error {
+	p.store.mu.Lock()
+	topics := len(p.store.topics)
+	p.store.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topicsSeen = append(p.topicsSeen, topics)
+	if change.Deleted {
+		delete(p.polls, change.Rkey)
+		return nil
+	}
+	var record struct {
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal(change.Record, &record); err != nil || record.Question == "" {
+		return &lexicon.FieldError{NSID: pollNSID, Field: "question", Reason: "is required"}
+	}
+	p.polls[change.Rkey] = record.Question
+	return nil
+}
+
+func TestRegistryHandlesNewLexicons(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	polls := &pollIndex{polls: map[string]string{}, store: store}
+	registry := DefaultRegistry(store)
+	registry.Register(pollNSID, polls)
+
+	f := NewFollower(Source{Origin: origin, Categories: []string{"go"}}, store, Options{Registry: registry})
+	var dialed string
+	f.dial = func(_ context.Context, rawURL string) (stream, error) {
+		dialed = rawURL
+		return &fakeStream{}, nil
+	}
+	if err := f.Follow(ctx); err == nil || !strings.Contains(dialed, "wantedCollections="+pollNSID) {
+		t.Fatalf("expected the poll collection subscribed to, dialed %q (%v)", dialed, err)
+	}
+
+	alice := "did:plc:alice"
+	batch := []firehose.Event{
+		commit(1, alice, firehose.OperationCreate, pollNSID, "p1", map[string]any{"question": "Tabs or spaces?"}),
+		commit(2, alice, firehose.OperationCreate, "quest.dis.topic", "t1", topicRecord("Generics", "go")),
+		commit(3, alice, firehose.OperationCreate, pollNSID, "p2", map[string]any{"question": ""}),
+		commit(4, alice, firehose.OperationCreate, "app.bsky.feed.post", "x1", map[string]any{"text": "hi"}),
+		// A backfill overlapping the stream repeats the first poll
+		commit(5, alice, firehose.OperationCreate, pollNSID, "p1", map[string]any{"question": "Tabs or spaces?"}),
+	}
+	if err := f.commit(ctx, batch); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if len(polls.polls) != 1 || polls.polls["p1"] != "Tabs or spaces?" {
+		t.Errorf("polls = %v, want only the valid poll", polls.polls)
+	}
+	// Polls were registered after topics, so a batch applies them later
+	if len(polls.topicsSeen) != 2 || polls.topicsSeen[0] != 1 {
+		t.Errorf("expected polls applied once each after the batch's topics, saw %v", polls.topicsSeen)
+	}
+	if store.cursors[origin] != 5 || len(store.topics) != 1 {
+		t.Errorf("expected the batch committed, got cursor %d and %+v", store.cursors[origin], store.topics)
+	}
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	registry := NewRegistry()
+	noop := HandlerFunc(func(context.Context, Change) error { return nil })
+	registry.Register(pollNSID, noop)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a collection twice to panic")
+		}
+	}()
+	registry.Register(pollNSID, noop)
+}
@@ -0,0 +1,153 @@
+// Package multikey reads and writes the multibase multikeys DID documents
+// publish in publicKeyMultibase, and makes and verifies signatures with them.
+// atproto signs with two kinds of key: P-256 and secp256k1.
+package multikey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	k256ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Multicodecs of the compressed public keys atproto signs with
+const (
+	P256 uint64 = 0x1200
+	K256 uint64 = 0xe7
+)
+
+// Parse parses a multibase multikey into an *ecdsa.PublicKey on P-256 or a
+// *secp256k1.PublicKey
+func Parse(multibase string) (crypto.PublicKey, error) {
+	encoded, ok := strings.CutPrefix(multibase, "z")
+	if !ok {
+		return nil, fmt.Errorf("unsupported multibase key %q", multibase)
+	}
+	data, err := base58Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	codec, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("malformed multikey")
+	}
+	key := data[n:]
+	switch codec {
+	case P256:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), key)
+		if x == nil {
+			return nil, fmt.Errorf("malformed P-256 key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case K256:
+		pub, err := secp256k1.ParsePubKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("malformed secp256k1 key: %w", err)
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %#x", codec)
+}
+
+// Encode returns the multibase multikey of a compressed public key of the
+// given multicodec
+func Encode(codec uint64, compressed []byte) string {
+	return "z" + base58Encode(append(binary.AppendUvarint(nil, codec), compressed...))
+}
+
+// Sign signs digest with key, an *ecdsa.PrivateKey or a
+// *secp256k1.PrivateKey, returning r and s as 32 bytes each with s in the
+// low-S form Verify accepts
+func Sign(key crypto.PrivateKey, digest []byte) ([]byte, error) {
+	sig := make([]byte, 64)
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		n := key.Curve.Params().N
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			s.Sub(n, s)
+		}
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case *secp256k1.PrivateKey:
+		// Sign already produces the canonical low-S form
+		signed := k256ecdsa.Sign(key, digest)
+		r, s := signed.R(), signed.S()
+		r.PutBytesUnchecked(sig[:32])
+		s.PutBytesUnchecked(sig[32:])
+	default:
+		return nil, fmt.Errorf("unsupported signing key %T", key)
+	}
+	return sig, nil
+}
+
+// Verify reports whether sig, 64 bytes of r and s, signs digest with key.
+// atproto only accepts the low-S form of each signature, so they are not
+// malleable.
+func Verify(key crypto.PublicKey, digest, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		halfOrder := new(big.Int).Rsh(key.Curve.Params().N, 1)
+		return s.Cmp(halfOrder) <= 0 && ecdsa.Verify(key, digest, r, s)
+	case *secp256k1.PublicKey:
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) || s.IsOverHalfOrder() {
+			return false
+		}
+		return k256ecdsa.NewSignature(&r, &s).Verify(digest, key)
+	}
+	return false
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode encodes data with the Bitcoin alphabet, as multibase "z" does
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	slices.Reverse(out)
+	return string(out)
+}
+
+// base58Decode decodes data written with the Bitcoin alphabet
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix).Add(n, big.NewInt(int64(digit)))
+	}
+	zeros := len(s) - len(strings.TrimLeft(s, base58Alphabet[:1]))
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
@@ -0,0 +1,162 @@
+package multikey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	k256ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func TestBase58RoundTrip(t *testing.T) {
+	for _, data := range [][]byte{{}, {0}, {0, 0, 1}, {0xff, 0x00, 0x10}, []byte("hello world")} {
+		got, err := base58Decode(base58Encode(data))
+		if err != nil {
+			t.Fatalf("base58Decode: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("round trip of %x = %x", data, got)
+		}
+	}
+	if got := base58Encode([]byte("hello world")); got != "StV1DL6CwTryKyV" {
+		t.Errorf("base58Encode = %q, want StV1DL6CwTryKyV", got)
+	}
+	if _, err := base58Decode("0OIl"); err == nil {
+		t.Error("expected characters outside the alphabet refused")
+	}
+}
+
+func TestParseEncode(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k256, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// did:key prefixes for each curve
+	p256Multikey := Encode(P256, elliptic.MarshalCompressed(elliptic.P256(), p256.X, p256.Y))
+	if !strings.HasPrefix(p256Multikey, "zDn") {
+		t.Errorf("P-256 multikey = %q, want zDn...", p256Multikey)
+	}
+	k256Multikey := Encode(K256, k256.PubKey().SerializeCompressed())
+	if !strings.HasPrefix(k256Multikey, "zQ3s") {
+		t.Errorf("secp256k1 multikey = %q, want zQ3s...", k256Multikey)
+	}
+
+	key, err := Parse(p256Multikey)
+	if err != nil {
+		t.Fatalf("Parse P-256: %v", err)
+	}
+	if pub, ok := key.(*ecdsa.PublicKey); !ok || !pub.Equal(&p256.PublicKey) {
+		t.Errorf("Parse P-256 = %#v", key)
+	}
+	key, err = Parse(k256Multikey)
+	if err != nil {
+		t.Fatalf("Parse secp256k1: %v", err)
+	}
+	if pub, ok := key.(*secp256k1.PublicKey); !ok || !pub.IsEqual(k256.PubKey()) {
+		t.Errorf("Parse secp256k1 = %#v", key)
+	}
+
+	for _, bad := range []string{
+		"uAAAA",
+		"z",
+		Encode(0xed, make([]byte, 32)),
+		Encode(P256, make([]byte, 33)),
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	digest := sha256.Sum256([]byte("commit"))
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, p256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := elliptic.P256().Params().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+	low := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	high := append(r.FillBytes(make([]byte, 32)), new(big.Int).Sub(n, s).FillBytes(make([]byte, 32))...)
+	if !Verify(&p256.PublicKey, digest[:], low) {
+		t.Error("expected a low-S P-256 signature accepted")
+	}
+	if Verify(&p256.PublicKey, digest[:], high) {
+		t.Error("expected a high-S P-256 signature refused")
+	}
+
+	k256, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := k256ecdsa.Sign(k256, digest[:])
+	kr, ks := sig.R(), sig.S()
+	var out [64]byte
+	kr.PutBytesUnchecked(out[:32])
+	ks.PutBytesUnchecked(out[32:])
+	if !Verify(k256.PubKey(), digest[:], out[:]) {
+		t.Error("expected a secp256k1 signature accepted")
+	}
+	other := sha256.Sum256([]byte("another commit"))
+	if Verify(k256.PubKey(), other[:], out[:]) {
+		t.Error("expected a signature over another digest refused")
+	}
+	if Verify(k256.PubKey(), digest[:], out[:63]) {
+		t.Error("expected a short signature refused")
+	}
+}
+
+func TestSign(t *testing.T) {
+	digest := sha256.Sum256([]byte("commit"))
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k256, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, key := range map[string]struct {
+		priv crypto.PrivateKey
+		pub  crypto.PublicKey
+	}{
+		"P-256":     {p256, &p256.PublicKey},
+		"secp256k1": {k256, k256.PubKey()},
+	} {
+		// Enough signatures that a high S would turn up if it were kept
+		for range 16 {
+			sig, err := Sign(key.priv, digest[:])
+			if err != nil {
+				t.Fatalf("%s: Sign: %v", name, err)
+			}
+			if !Verify(key.pub, digest[:], sig) {
+				t.Fatalf("%s: signature not accepted by Verify", name)
+			}
+		}
+	}
+
+	if _, err := Sign(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), digest[:]); err == nil {
+		t.Error("expected an Ed25519 key refused")
+	}
+}
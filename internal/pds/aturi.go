@@ -0,0 +1,142 @@
+package pds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidATURI is matched by every error ParseATURI returns
+var ErrInvalidATURI = errors.New("invalid AT URI")
+
+// ATURIError describes which part of an AT URI is malformed
+type ATURIError struct {
+	URI    string
+	Reason string
+}
+
+func (e *ATURIError) Error() string {
+	return fmt.Sprintf("%s %q: %s", ErrInvalidATURI, e.URI, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidATURI
+func (e *ATURIError) Is(target error) bool {
+	return target == ErrInvalidATURI
+}
+
+// maxRkeyLength is the longest record key the AT Protocol allows
+const maxRkeyLength = 512
+
+// ATURI identifies a repo, a collection within it, or a record:
+// at://<repo>[/<collection>[/<rkey>]]
+type ATURI struct {
+	// Repo is the DID or handle that owns the repository
+	Repo       string
+	Collection string
+	Rkey       string
+}
+
+// ParseATURI parses an AT URI. Query strings and fragments are not
+// supported.
+func ParseATURI(uri string) (ATURI, error) {
+	invalid := func(reason string) (ATURI, error) {
+		return ATURI{}, &ATURIError{URI: uri, Reason: reason}
+	}
+
+	rest, ok := strings.CutPrefix(uri, "at://")
+	if !ok {
+		return invalid("missing at:// scheme")
+	}
+	if strings.ContainsAny(rest, "?#") {
+		return invalid("query and fragment are not supported")
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) > 3 {
+		return invalid("too many path segments")
+	}
+
+	var u ATURI
+	u.Repo = parts[0]
+	if !validAuthority(u.Repo) {
+		return invalid("repo must be a DID or handle")
+	}
+	if len(parts) > 1 {
+		u.Collection = parts[1]
+		if !validNSID(u.Collection) {
+			return invalid("collection must be an NSID")
+		}
+	}
+	if len(parts) > 2 {
+		u.Rkey = parts[2]
+		if !validRkey(u.Rkey) {
+			return invalid("malformed record key")
+		}
+	}
+	return u, nil
+}
+
+// String formats u as an AT URI
+func (u ATURI) String() string {
+	s := "at://" + u.Repo
+	if u.Collection != "" {
+		s += "/" + u.Collection
+		if u.Rkey != "" {
+			s += "/" + u.Rkey
+		}
+	}
+	return s
+}
+
+// validAuthority accepts a DID (did:<method>:<id>) or a dotted handle
+func validAuthority(s string) bool {
+	if strings.HasPrefix(s, "did:") {
+		method, id, ok := strings.Cut(s[len("did:"):], ":")
+		return ok && method != "" && id != "" && !strings.HasSuffix(id, ":") &&
+			allBytes(method, func(c byte) bool { return 'a' <= c && c <= 'z' }) &&
+			allBytes(id, func(c byte) bool { return isAlnum(c) || strings.IndexByte("._:%-", c) >= 0 })
+	}
+	return strings.Contains(s, ".") && validLabels(s)
+}
+
+// validNSID accepts a reversed domain name with at least three segments,
+// e.g. quest.dis.topic
+func validNSID(s string) bool {
+	return strings.Count(s, ".") >= 2 && validLabels(s)
+}
+
+// validLabels checks that every dot-separated label is a non-empty run of
+// letters, digits and inner hyphens
+func validLabels(s string) bool {
+	if len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		if !allBytes(label, func(c byte) bool { return isAlnum(c) || c == '-' }) {
+			return false
+		}
+	}
+	return true
+}
+
+func validRkey(s string) bool {
+	if s == "" || s == "." || s == ".." || len(s) > maxRkeyLength {
+		return false
+	}
+	return allBytes(s, func(c byte) bool { return isAlnum(c) || strings.IndexByte("._:~-", c) >= 0 })
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func allBytes(s string, ok func(byte) bool) bool {
+	for i := 0; i < len(s); i++ {
+		if !ok(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package pds
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseATURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want ATURI
+	}{
+		{"at://did:plc:abc123", ATURI{Repo: "did:plc:abc123"}},
+		{"at://alice.bsky.social/quest.dis.topic", ATURI{Repo: "alice.bsky.social", Collection: "quest.dis.topic"}},
+		{"at://did:web:example.com/quest.dis.message/msg-1700000000", ATURI{Repo: "did:web:example.com", Collection: "quest.dis.message", Rkey: "msg-1700000000"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseATURI(tt.uri)
+		if err != nil {
+			t.Errorf("ParseATURI(%q): %v", tt.uri, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseATURI(%q) = %+v, want %+v", tt.uri, got, tt.want)
+		}
+		if got.String() != tt.uri {
+			t.Errorf("String() = %q, want %q", got.String(), tt.uri)
+		}
+	}
+}
+
+func TestParseATURI_Invalid(t *testing.T) {
+	for _, uri := range []string{
+		"",
+		"at://",
+		"https://did:plc:abc/quest.dis.topic/1",
+		"at://did:plc:abc/quest.dis.topic/1/extra",
+		"at://did:plc:abc/quest.dis.topic/",
+		"at://did:plc:abc/quest.dis.topic/..",
+		"at://did:plc:abc/quest.dis.topic/1?x=y",
+		"at://did:plc:abc/topic/1",
+		"at://did::abc",
+		"at://did:plc:",
+		"at://localhost",
+		"at://did:plc:abc/quest.dis.topic/" + strings.Repeat("a", maxRkeyLength+1),
+	} {
+		_, err := ParseATURI(uri)
+		if !errors.Is(err, ErrInvalidATURI) {
+			t.Errorf("ParseATURI(%q) error = %v, want ErrInvalidATURI", uri, err)
+		}
+		var uriErr *ATURIError
+		if errors.As(err, &uriErr) && uriErr.URI != uri {
+			t.Errorf("ATURIError.URI = %q, want %q", uriErr.URI, uri)
+		}
+	}
+}
+
+func FuzzParseATURI(f *testing.F) {
+	f.Add("at://did:plc:abc123/quest.dis.topic/topic-1")
+	f.Add("at://alice.bsky.social")
+	f.Add("at://did:web:example.com%3A8080/quest.dis.message/msg:1~2")
+	f.Add("at:///")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, uri string) {
+		parsed, err := ParseATURI(uri)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidATURI) {
+				t.Errorf("error %v does not match ErrInvalidATURI", err)
+			}
+			return
+		}
+		// Anything accepted must survive a round trip
+		again, err := ParseATURI(parsed.String())
+		if err != nil || again != parsed {
+			t.Errorf("round trip of %q: got %+v, %v; want %+v", uri, again, err, parsed)
+		}
+	})
+}
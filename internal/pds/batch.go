@@ -0,0 +1,145 @@
+package pds
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultRecordParallelism bounds the getRecord calls a RecordBatcher makes
+// at once when no other limit is given
+const DefaultRecordParallelism = 8
+
+// RecordResult is the outcome of fetching one record
+type RecordResult struct {
+	Record *Record
+	Err    error
+}
+
+// RecordFetcher fetches one record. Client.GetRecordAt is one; a fetcher
+// may also route each URI to the PDS hosting its repo.
+type RecordFetcher func(ctx context.Context, uri ATURI) (*Record, error)
+
+// RecordBatcher fetches records by AT URI for rendering, where one page
+// needs many of them. Concurrent requests for the same URI share a single
+// fetch, and no more than the batcher's parallelism run at once. It is safe
+// for concurrent use.
+type RecordBatcher struct {
+	fetcher RecordFetcher
+	slots   chan struct{}
+
+	mu       sync.Mutex
+	inflight map[string]*recordCall
+}
+
+// recordCall is a fetch shared by every caller waiting for it. The
+// call is cancelled once no caller is left waiting.
+type recordCall struct {
+	done    chan struct{}
+	result  RecordResult
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewRecordBatcher creates a batcher fetching with fetcher with at most
+// parallelism fetches in flight; parallelism below 1 uses
+// DefaultRecordParallelism
+func NewRecordBatcher(fetcher RecordFetcher, parallelism int) *RecordBatcher {
+	if parallelism < 1 {
+		parallelism = DefaultRecordParallelism
+	}
+	return &RecordBatcher{
+		fetcher:  fetcher,
+		slots:    make(chan struct{}, parallelism),
+		inflight: make(map[string]*recordCall),
+	}
+}
+
+// Get fetches the record at uri, joining a fetch of it already in flight
+func (b *RecordBatcher) Get(ctx context.Context, uri string) (*Record, error) {
+	b.mu.Lock()
+	call, ok := b.inflight[uri]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &recordCall{done: make(chan struct{}), cancel: cancel}
+		b.inflight[uri] = call
+		go b.fetch(fetchCtx, uri, call)
+	}
+	call.waiters++
+	b.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result.Record, call.result.Err
+	case <-ctx.Done():
+		b.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Later callers start a fetch of their own
+			call.cancel()
+			b.forget(uri, call)
+		}
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// GetRecords fetches the records at uris, returning a result for each
+// distinct URI. A failure fetching one URI does not stop the others.
+func (b *RecordBatcher) GetRecords(ctx context.Context, uris []string) map[string]RecordResult {
+	results := make(map[string]RecordResult, len(uris))
+	seen := make(map[string]bool, len(uris))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, uri := range uris {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := b.Get(ctx, uri)
+			mu.Lock()
+			results[uri] = RecordResult{Record: record, Err: err}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (b *RecordBatcher) fetch(ctx context.Context, uri string, call *recordCall) {
+	defer call.cancel()
+	select {
+	case b.slots <- struct{}{}:
+		call.result = b.getRecord(ctx, uri)
+		<-b.slots
+	case <-ctx.Done():
+		call.result = RecordResult{Err: ctx.Err()}
+	}
+
+	b.mu.Lock()
+	b.forget(uri, call)
+	b.mu.Unlock()
+	close(call.done)
+}
+
+// forget removes call from the in-flight calls unless a newer call for uri
+// has replaced it. b.mu must be held.
+func (b *RecordBatcher) forget(uri string, call *recordCall) {
+	if b.inflight[uri] == call {
+		delete(b.inflight, uri)
+	}
+}
+
+func (b *RecordBatcher) getRecord(ctx context.Context, uri string) RecordResult {
+	at, err := ParseATURI(uri)
+	if err != nil {
+		return RecordResult{Err: err}
+	}
+	if at.Rkey == "" {
+		return RecordResult{Err: &ATURIError{URI: uri, Reason: "not a record URI"}}
+	}
+	record, err := b.fetcher(ctx, at)
+	return RecordResult{Record: record, Err: err}
+}
@@ -0,0 +1,115 @@
+package pds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRecordBatcher(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	fetcher := func(ctx context.Context, uri ATURI) (*Record, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		calls[uri.String()]++
+		mu.Unlock()
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if uri.Rkey == "missing" {
+			return nil, &XRPCError{Status: 400, Name: "RecordNotFound"}
+		}
+		return &Record{URI: uri.String()}, nil
+	}
+	batcher := NewRecordBatcher(fetcher, 3)
+	ctx := context.Background()
+
+	var uris []string
+	for i := range 10 {
+		uris = append(uris, fmt.Sprintf("at://did:plc:alice/quest.dis.message/m%d", i))
+	}
+	uris = append(uris, uris[0], uris[1], "at://did:plc:alice/quest.dis.message/missing", "not a uri")
+
+	// Concurrent callers for the same URIs share fetches
+	var wg sync.WaitGroup
+	results := make([]map[string]RecordResult, 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = batcher.GetRecords(ctx, uris)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, got := range results {
+		if len(got) != 12 {
+			t.Fatalf("got %d results, want one per distinct URI", len(got))
+		}
+		for _, uri := range uris[:10] {
+			if got[uri].Err != nil || got[uri].Record.URI != uri {
+				t.Errorf("%s: %+v", uri, got[uri])
+			}
+		}
+		var xrpcErr *XRPCError
+		if !errors.As(got["at://did:plc:alice/quest.dis.message/missing"].Err, &xrpcErr) {
+			t.Errorf("missing record: %+v", got["at://did:plc:alice/quest.dis.message/missing"])
+		}
+		if !errors.Is(got["not a uri"].Err, ErrInvalidATURI) {
+			t.Errorf("invalid URI: %+v", got["not a uri"])
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d fetches ran at once, want at most 3", peak.Load())
+	}
+	for uri, n := range calls {
+		if n != 1 {
+			t.Errorf("%s fetched %d times while callers waited together, want 1", uri, n)
+		}
+	}
+}
+
+func TestRecordBatcherCancel(t *testing.T) {
+	fetchCtx := make(chan context.Context, 1)
+	batcher := NewRecordBatcher(func(ctx context.Context, _ ATURI) (*Record, error) {
+		fetchCtx <- ctx
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := batcher.Get(ctx, "at://did:plc:alice/quest.dis.topic/t1")
+		done <- err
+	}()
+	fetch := <-fetchCtx
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get = %v, want context.Canceled", err)
+	}
+	// With its only caller gone the fetch is abandoned
+	select {
+	case <-fetch.Done():
+	case <-time.After(time.Second):
+		t.Fatal("fetch kept running without callers")
+	}
+}
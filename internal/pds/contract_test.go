@@ -0,0 +1,219 @@
+//go:build contract
+
+// Contract tests run the PDS client against a real PDS to catch protocol
+// drift. They are opt-in: build with -tags contract and point them at a
+// sandbox account with
+//
+//	PDS_CONTRACT_HOST          PDS base URL, e.g. https://bsky.social
+//	PDS_CONTRACT_HANDLE        account handle
+//	PDS_CONTRACT_APP_PASSWORD  app password for the account
+//
+// The OAuth refresh test additionally needs a session created by signing in
+// to the app with that account:
+//
+//	PDS_CONTRACT_AUTH_SERVER                authorization server base URL
+//	PDS_CONTRACT_CLIENT_ID                  client_id the session was issued to
+//	PDS_CONTRACT_DPOP_KEY                   the session's DPoP key, as set in the dpop_key cookie during sign-in
+//	PDS_CONTRACT_OAUTH_REFRESH_TOKEN_FILE   file holding the refresh token; rewritten with the rotated one
+package pds_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// contractTimeout bounds each test's calls to the PDS
+const contractTimeout = time.Minute
+
+func requireEnv(t *testing.T, names ...string) []string {
+	t.Helper()
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = os.Getenv(name)
+		if values[i] == "" {
+			t.Skipf("%s not set", name)
+		}
+	}
+	return values
+}
+
+// appPasswordSession signs in with the contract account's app password
+func appPasswordSession(t *testing.T) (host string, session *auth.CreateSessionResponse) {
+	t.Helper()
+	env := requireEnv(t, "PDS_CONTRACT_HOST", "PDS_CONTRACT_HANDLE", "PDS_CONTRACT_APP_PASSWORD")
+	session, err := auth.CreateSession(env[0], env[1], env[2])
+	if err != nil {
+		t.Fatalf("createSession: %v", err)
+	}
+	return env[0], session
+}
+
+func TestContract_RecordLifecycle(t *testing.T) {
+	host, session := appPasswordSession(t)
+	ctx, cancel := context.WithTimeout(context.Background(), contractTimeout)
+	defer cancel()
+
+	client := pds.NewClient(host, pds.BearerToken(session.AccessJwt), nil)
+	rkey := fmt.Sprintf("contract-%d", time.Now().UnixNano())
+	topic := lexicon.Topic{
+		Type:      lexicon.TopicNSID,
+		Title:     "Contract test",
+		CreatedBy: session.Did,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	t.Cleanup(func() {
+		_ = client.DeleteRecord(context.Background(), session.Did, lexicon.TopicNSID, rkey)
+	})
+
+	ref, err := client.CreateRecord(ctx, session.Did, lexicon.TopicNSID, rkey, topic)
+	if err != nil {
+		t.Fatalf("createRecord: %v", err)
+	}
+	uri, err := pds.ParseATURI(ref.URI)
+	if err != nil || uri.Repo != session.Did || uri.Collection != lexicon.TopicNSID || uri.Rkey != rkey {
+		t.Fatalf("createRecord returned uri %q (%v)", ref.URI, err)
+	}
+
+	topic.Title = "Contract test, edited"
+	if _, err := client.PutRecord(ctx, session.Did, lexicon.TopicNSID, rkey, topic); err != nil {
+		t.Fatalf("putRecord: %v", err)
+	}
+	record, err := client.GetRecord(ctx, session.Did, lexicon.TopicNSID, rkey)
+	if err != nil {
+		t.Fatalf("getRecord: %v", err)
+	}
+	var value map[string]any
+	if err := json.Unmarshal(record.Value, &value); err != nil {
+		t.Fatalf("getRecord value: %v", err)
+	}
+	got, err := lexicon.TopicFromMap(value)
+	if err != nil || got.Title != topic.Title {
+		t.Fatalf("getRecord returned %s (%v)", record.Value, err)
+	}
+
+	found := false
+	for cursor := ""; !found; {
+		page, err := client.ListRecords(ctx, session.Did, lexicon.TopicNSID, cursor, 100)
+		if err != nil {
+			t.Fatalf("listRecords: %v", err)
+		}
+		for _, r := range page.Records {
+			found = found || r.Rkey() == rkey
+		}
+		if page.Cursor == "" || len(page.Records) == 0 {
+			break
+		}
+		cursor = page.Cursor
+	}
+	if !found {
+		t.Error("listRecords did not include the new record")
+	}
+
+	if err := client.DeleteRecord(ctx, session.Did, lexicon.TopicNSID, rkey); err != nil {
+		t.Fatalf("deleteRecord: %v", err)
+	}
+	_, err = client.GetRecord(ctx, session.Did, lexicon.TopicNSID, rkey)
+	var xrpcErr *pds.XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Name != "RecordNotFound" {
+		t.Errorf("getRecord after delete = %v, want a RecordNotFound XRPC error", err)
+	}
+}
+
+func TestContract_AppPasswordRefresh(t *testing.T) {
+	host, session := appPasswordSession(t)
+	ctx, cancel := context.WithTimeout(context.Background(), contractTimeout)
+	defer cancel()
+
+	refreshed, err := auth.RefreshSession(ctx, host, session.RefreshJwt)
+	if err != nil {
+		t.Fatalf("refreshSession: %v", err)
+	}
+	if refreshed.AccessJwt == "" || refreshed.RefreshJwt == "" || refreshed.Did != session.Did {
+		t.Fatalf("refreshSession returned %+v", refreshed)
+	}
+	client := pds.NewClient(host, pds.BearerToken(refreshed.AccessJwt), nil)
+	if _, err := client.DescribeRepo(ctx, session.Did); err != nil {
+		t.Errorf("describeRepo with the refreshed token: %v", err)
+	}
+}
+
+// TestContract_OAuthRefresh refreshes a DPoP-bound OAuth session and uses it
+// against the PDS, which must go through the use_dpop_nonce handshake
+func TestContract_OAuthRefresh(t *testing.T) {
+	env := requireEnv(t, "PDS_CONTRACT_HOST", "PDS_CONTRACT_AUTH_SERVER", "PDS_CONTRACT_CLIENT_ID",
+		"PDS_CONTRACT_DPOP_KEY", "PDS_CONTRACT_OAUTH_REFRESH_TOKEN_FILE")
+	host, authServer, clientID, dpopKey, tokenFile := env[0], env[1], env[2], env[3], env[4]
+	ctx, cancel := context.WithTimeout(context.Background(), contractTimeout)
+	defer cancel()
+
+	key, err := auth.DecodeDPoPPrivateKeyFromPEM(dpopKey)
+	if err != nil {
+		t.Fatalf("PDS_CONTRACT_DPOP_KEY: %v", err)
+	}
+	refreshToken, err := os.ReadFile(tokenFile) // #nosec G304 -- path comes from the test environment
+	if err != nil {
+		t.Fatalf("read refresh token: %v", err)
+	}
+	metadata := fetchAuthServerMetadata(ctx, t, authServer)
+
+	cfg := &config.Config{OAuthClientID: clientID}
+	token, err := auth.RefreshTokenWithDPoP(ctx, metadata, strings.TrimSpace(string(refreshToken)), key, cfg)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	// The old refresh token is spent; keep the rotated one for the next run
+	// before anything else can fail
+	if err := os.WriteFile(tokenFile, []byte(token.RefreshToken), 0o600); err != nil {
+		t.Fatalf("save rotated refresh token: %v", err)
+	}
+
+	if scope, _ := token.Extra("scope").(string); !strings.Contains(" "+scope+" ", " atproto ") {
+		t.Errorf("refreshed token scope = %q, want it to include atproto", scope)
+	}
+	sub, _ := token.Extra("sub").(string)
+	if !strings.HasPrefix(sub, "did:") {
+		t.Fatalf("refreshed token sub = %q, want a DID", sub)
+	}
+
+	client := pds.NewClient(host, auth.DPoPAuthorizer{AccessToken: token.AccessToken, Key: key}, nil)
+	if _, err := client.DescribeRepo(ctx, sub); err != nil {
+		t.Errorf("describeRepo with the DPoP-bound token: %v", err)
+	}
+}
+
+func fetchAuthServerMetadata(ctx context.Context, t *testing.T, authServer string) *auth.AuthorizationServerMetadata {
+	t.Helper()
+	url := strings.TrimSuffix(authServer, "/") + "/.well-known/oauth-authorization-server"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("fetch %s: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("fetch %s: status %d", url, resp.StatusCode)
+	}
+	var metadata auth.AuthorizationServerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		t.Fatalf("decode %s: %v", url, err)
+	}
+	if metadata.TokenEndpoint == "" {
+		t.Fatalf("%s has no token_endpoint", url)
+	}
+	return &metadata
+}
@@ -0,0 +1,94 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// RecordHook runs on a record before the client writes it to collection
+// and may change it in place. An error stops the write.
+type RecordHook func(ctx context.Context, collection string, record map[string]any) error
+
+// AddRecordHooks appends hooks to those run, in order, on every record
+// CreateRecord and PutRecord write and every record ApplyWrites creates or
+// updates, so invariants such as $type and createdAt are enforced in one
+// place rather than by each caller
+func (c *Client) AddRecordHooks(hooks ...RecordHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hooks...)
+}
+
+// SetType is a RecordHook that sets a missing $type to the collection
+func SetType(_ context.Context, collection string, record map[string]any) error {
+	if _, ok := record["$type"]; !ok {
+		record["$type"] = collection
+	}
+	return nil
+}
+
+// SetCreatedAt returns a RecordHook that sets a missing createdAt to now
+func SetCreatedAt(now func() time.Time) RecordHook {
+	return func(_ context.Context, _ string, record map[string]any) error {
+		if _, ok := record["createdAt"]; !ok {
+			record["createdAt"] = now().UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+	}
+}
+
+// ForCollections returns a RecordHook that runs hook only on records
+// written to one of collections
+func ForCollections(hook RecordHook, collections ...string) RecordHook {
+	return func(ctx context.Context, collection string, record map[string]any) error {
+		if !slices.Contains(collections, collection) {
+			return nil
+		}
+		return hook(ctx, collection, record)
+	}
+}
+
+// runHooks returns record as the client's hooks leave it. Records are
+// passed to hooks as maps decoded with json.Number, so values no hook
+// touches are written exactly; with no hooks record is returned as is.
+func (c *Client) runHooks(ctx context.Context, collection string, record any) (any, error) {
+	c.mu.Lock()
+	hooks := c.hooks
+	c.mu.Unlock()
+	if len(hooks) == 0 || record == nil {
+		return record, nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decodeRecordValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s record: %w", collection, err)
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx, collection, value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// hookWrites returns writes with the client's hooks run on the record each
+// creates or updates, leaving the caller's slice unchanged
+func (c *Client) hookWrites(ctx context.Context, writes []Write) ([]Write, error) {
+	hooked := make([]Write, len(writes))
+	for i, write := range writes {
+		value, err := c.runHooks(ctx, write.Collection, write.Value)
+		if err != nil {
+			return nil, err
+		}
+		write.Value = value
+		hooked[i] = write
+	}
+	return hooked, nil
+}
@@ -0,0 +1,82 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecordHooks(t *testing.T) {
+	var written []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Record map[string]any   `json:"record"`
+			Writes []map[string]any `json:"writes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Record != nil {
+			written = append(written, body.Record)
+		}
+		for _, write := range body.Writes {
+			if value, ok := write["value"].(map[string]any); ok {
+				written = append(written, value)
+			}
+		}
+		_, _ = w.Write([]byte(`{"uri":"at://did:plc:alice/quest.dis.topic/1","cid":"c"}`))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := NewClient(srv.URL, Anonymous{}, nil)
+	client.SetValidationPolicy(ValidationPolicy{Default: ValidateNever})
+	errRejected := errors.New("rejected")
+	client.AddRecordHooks(
+		SetType,
+		ForCollections(SetCreatedAt(func() time.Time { return now }), "quest.dis.topic"),
+		func(_ context.Context, _ string, record map[string]any) error {
+			if record["title"] == "" {
+				return errRejected
+			}
+			return nil
+		},
+	)
+	ctx := context.Background()
+
+	type topic struct {
+		Title     string `json:"title"`
+		CreatedAt string `json:"createdAt,omitempty"`
+		Count     int64  `json:"count"`
+	}
+	if _, err := client.CreateRecord(ctx, "did:plc:alice", "quest.dis.topic", "1", topic{Title: "Hi", Count: 1 << 60}); err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if _, err := client.PutRecord(ctx, "did:plc:alice", "quest.dis.topic", "1", topic{Title: "Hi", CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatalf("PutRecord: %v", err)
+	}
+	if err := client.ApplyWrites(ctx, "did:plc:alice", []Write{UpdateWrite("quest.dis.other", "1", map[string]any{"title": "Hi"}), DeleteWrite("quest.dis.topic", "2")}); err != nil {
+		t.Fatalf("ApplyWrites: %v", err)
+	}
+	if len(written) != 3 {
+		t.Fatalf("wrote %d records, want 3: %v", len(written), written)
+	}
+	if written[0]["$type"] != "quest.dis.topic" || written[0]["createdAt"] != "2025-03-01T12:00:00Z" || written[0]["count"] != float64(1<<60) {
+		t.Errorf("created record = %v", written[0])
+	}
+	if written[1]["createdAt"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("hook replaced an existing createdAt: %v", written[1])
+	}
+	if _, ok := written[2]["createdAt"]; ok || written[2]["$type"] != "quest.dis.other" {
+		t.Errorf("applyWrites record = %v", written[2])
+	}
+
+	if _, err := client.CreateRecord(ctx, "did:plc:alice", "quest.dis.topic", "3", topic{}); !errors.Is(err, errRejected) {
+		t.Errorf("err = %v, want the hook's error", err)
+	}
+	if len(written) != 3 {
+		t.Error("a record a hook rejected was written")
+	}
+}
@@ -0,0 +1,62 @@
+package pds
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricsSink receives measurements of the calls made to PDSes and
+// authorization servers. Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	// ObserveRequest records one XRPC call. status is 0 when no response
+	// arrived.
+	ObserveRequest(nsid string, status int, duration time.Duration)
+	// CountNonceRetry records a request repeated with a fresh DPoP nonce
+	CountNonceRetry()
+	// CountRefresh records an attempt to refresh an access token
+	CountRefresh()
+}
+
+// sinkHolder lets a nil sink be stored in an atomic.Value
+type sinkHolder struct{ sink MetricsSink }
+
+var defaultSink atomic.Value
+
+// SetMetrics sends measurements from every client without its own sink, and
+// from the token refreshes in package auth, to sink. A nil sink turns them
+// off.
+func SetMetrics(sink MetricsSink) {
+	defaultSink.Store(sinkHolder{sink})
+}
+
+// Metrics returns the sink set by SetMetrics, or one that discards
+// everything
+func Metrics() MetricsSink {
+	if h, ok := defaultSink.Load().(sinkHolder); ok && h.sink != nil {
+		return h.sink
+	}
+	return discard{}
+}
+
+// SetMetrics sends the client's measurements to sink instead of the one set
+// by the package-level SetMetrics
+func (c *Client) SetMetrics(sink MetricsSink) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = sink
+}
+
+func (c *Client) metricsSink() MetricsSink {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metrics != nil {
+		return c.metrics
+	}
+	return Metrics()
+}
+
+type discard struct{}
+
+func (discard) ObserveRequest(string, int, time.Duration) {}
+func (discard) CountNonceRetry()                          {}
+func (discard) CountRefresh()                             {}
@@ -0,0 +1,94 @@
+package pds
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps every observation
+type recordingSink struct {
+	mu           sync.Mutex
+	requests     []string
+	statuses     []int
+	nonceRetries int
+	refreshes    int
+}
+
+func (s *recordingSink) ObserveRequest(nsid string, status int, _ time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, nsid)
+	s.statuses = append(s.statuses, status)
+}
+
+func (s *recordingSink) CountNonceRetry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonceRetries++
+}
+
+func (s *recordingSink) CountRefresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshes++
+}
+
+func TestClientMetrics(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{"quest.dis.topic": {"t1"}}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	sink := &recordingSink{}
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	client.SetMetrics(sink)
+	client.SetNonceCache(NewNonceCache())
+	if _, err := client.DescribeRepo(context.Background(), "did:plc:test"); err != nil {
+		t.Fatalf("DescribeRepo: %v", err)
+	}
+	if _, err := client.DescribeRepo(context.Background(), "did:plc:test"); err != nil {
+		t.Fatalf("DescribeRepo: %v", err)
+	}
+
+	// The first call is refused for lacking the nonce and retried
+	if sink.nonceRetries != 1 {
+		t.Errorf("nonce retries = %d, want 1", sink.nonceRetries)
+	}
+	wantStatuses := []int{401, 200, 200}
+	if len(sink.statuses) != len(wantStatuses) {
+		t.Fatalf("statuses = %v, want %v", sink.statuses, wantStatuses)
+	}
+	for i, status := range wantStatuses {
+		if sink.statuses[i] != status || sink.requests[i] != "com.atproto.repo.describeRepo" {
+			t.Errorf("request %d = %s %d", i, sink.requests[i], sink.statuses[i])
+		}
+	}
+
+	srv.Close()
+	_, _ = client.DescribeRepo(context.Background(), "did:plc:test")
+	if last := sink.statuses[len(sink.statuses)-1]; last != 0 {
+		t.Errorf("unreachable PDS recorded status %d, want 0", last)
+	}
+}
+
+func TestDefaultMetrics(t *testing.T) {
+	if _, ok := Metrics().(discard); !ok {
+		t.Fatalf("Metrics() = %T before SetMetrics, want discard", Metrics())
+	}
+	sink := &recordingSink{}
+	SetMetrics(sink)
+	defer SetMetrics(nil)
+	if Metrics() != sink {
+		t.Error("Metrics() does not return the sink set")
+	}
+	client := NewClient("http://example.invalid", nonceAuth{}, nil)
+	if client.metricsSink() != sink {
+		t.Error("client without its own sink does not use the default")
+	}
+	SetMetrics(nil)
+	if _, ok := Metrics().(discard); !ok {
+		t.Errorf("Metrics() = %T after SetMetrics(nil), want discard", Metrics())
+	}
+}
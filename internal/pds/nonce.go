@@ -0,0 +1,70 @@
+package pds
+
+import (
+	"net/url"
+	"sync"
+)
+
+// maxNonceOrigins bounds how many servers' nonces a NonceCache holds
+const maxNonceOrigins = 10000
+
+// NonceCache keeps the latest DPoP nonce each server has issued, by origin,
+// so the first request a new client makes to a server carries a nonce it
+// accepts instead of being refused and retried. Servers rotate nonces every
+// few minutes; a stale one costs only the retry that would have happened
+// anyway. It is safe for concurrent use.
+type NonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]string
+}
+
+// NewNonceCache creates an empty cache
+func NewNonceCache() *NonceCache {
+	return &NonceCache{nonces: map[string]string{}}
+}
+
+var sharedNonces = NewNonceCache()
+
+// Nonces returns the cache shared by every client without its own, and by
+// the token requests in package auth
+func Nonces() *NonceCache {
+	return sharedNonces
+}
+
+// Get returns the latest nonce issued by the server rawURL is on, or ""
+// before it has issued one
+func (c *NonceCache) Get(rawURL string) string {
+	origin := nonceOrigin(rawURL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nonces[origin]
+}
+
+// Set records nonce as the latest issued by the server rawURL is on. Empty
+// nonces are ignored.
+func (c *NonceCache) Set(rawURL, nonce string) {
+	if nonce == "" {
+		return
+	}
+	origin := nonceOrigin(rawURL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.nonces[origin]; !ok && len(c.nonces) >= maxNonceOrigins {
+		// Forget an arbitrary server; it only costs that server a retry
+		for other := range c.nonces {
+			delete(c.nonces, other)
+			break
+		}
+	}
+	c.nonces[origin] = nonce
+}
+
+// nonceOrigin returns the scheme and host of rawURL, which a server's nonces
+// are scoped to
+func nonceOrigin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -1,4 +1,4 @@
-// Package pds provides interfaces and mocks for Personal Data Server (PDS) interactions.
+// Package pds provides a repository client and mocks for Personal Data Server (PDS) interactions.
 package pds
 
 import "fmt"
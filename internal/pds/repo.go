@@ -0,0 +1,448 @@
+package pds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxWritesPerBatch is the most operations a PDS accepts in one applyWrites call
+const MaxWritesPerBatch = 200
+
+// listRecordsPageSize is the page size used when walking a collection
+const listRecordsPageSize = 100
+
+// Authorizer adds credentials to a PDS request. nonce is the latest DPoP
+// nonce the PDS has issued, or empty before it has issued one.
+type Authorizer interface {
+	Authorize(req *http.Request, nonce string) error
+}
+
+// Anonymous sends requests without credentials, for public reads such as
+// getRecord
+type Anonymous struct{}
+
+// Authorize implements Authorizer
+func (Anonymous) Authorize(*http.Request, string) error {
+	return nil
+}
+
+// BearerToken authorizes requests with an app-password session token
+type BearerToken string
+
+// Authorize implements Authorizer
+func (t BearerToken) Authorize(req *http.Request, _ string) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// XRPCError is an error response from a PDS
+type XRPCError struct {
+	Status  int
+	Name    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *XRPCError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("xrpc %d %s: %s", e.Status, e.Name, e.Message)
+	}
+	return fmt.Sprintf("xrpc %d %s", e.Status, e.Name)
+}
+
+// Record is a repository record as returned by listRecords
+type Record struct {
+	URI   string          `json:"uri"`
+	CID   string          `json:"cid"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Rkey returns the record key, the last segment of the record's AT URI
+func (r Record) Rkey() string {
+	return r.URI[strings.LastIndex(r.URI, "/")+1:]
+}
+
+// ListRecordsResponse is one page of records in a collection
+type ListRecordsResponse struct {
+	Records []Record `json:"records"`
+	Cursor  string   `json:"cursor,omitempty"`
+}
+
+// Write is one applyWrites operation
+type Write struct {
+	Type       string `json:"$type"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey,omitempty"`
+	Value      any    `json:"value,omitempty"`
+}
+
+// DeleteWrite returns an applyWrites operation deleting one record
+func DeleteWrite(collection, rkey string) Write {
+	return Write{Type: "com.atproto.repo.applyWrites#delete", Collection: collection, Rkey: rkey}
+}
+
+// Client calls com.atproto.repo methods on a PDS on behalf of one account.
+// It is safe for concurrent use: requests share the lexicon support learned
+// so far, and with other clients the PDS's latest DPoP nonce, and its
+// Authorizer must be safe for concurrent use too.
+type Client struct {
+	host       string
+	auth       Authorizer
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	nonces     *NonceCache
+	validation ValidationPolicy
+	lexicons   map[string]bool // collection -> PDS can validate it
+	metrics    MetricsSink
+	hooks      []RecordHook
+}
+
+// NewClient creates a client for the PDS at host. A nil httpClient uses
+// http.DefaultClient.
+func NewClient(host string, auth Authorizer, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{host: strings.TrimSuffix(host, "/"), auth: auth, httpClient: httpClient}
+}
+
+// RepoDescription is what a PDS reports about a repo it hosts
+type RepoDescription struct {
+	Handle      string   `json:"handle"`
+	DID         string   `json:"did"`
+	Collections []string `json:"collections"`
+}
+
+// Describe returns the handle and collections of repo
+func (c *Client) Describe(ctx context.Context, repo string) (*RepoDescription, error) {
+	var out RepoDescription
+	query := url.Values{"repo": {repo}}
+	if err := c.do(ctx, http.MethodGet, "com.atproto.repo.describeRepo", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DescribeRepo returns the collections that hold records in repo
+func (c *Client) DescribeRepo(ctx context.Context, repo string) ([]string, error) {
+	desc, err := c.Describe(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	return desc.Collections, nil
+}
+
+// ListRecords returns one page of records in a collection
+func (c *Client) ListRecords(ctx context.Context, repo, collection, cursor string, limit int) (*ListRecordsResponse, error) {
+	return c.ListRecordsRange(ctx, repo, collection, ListOptions{Cursor: cursor, Limit: limit})
+}
+
+// ListOptions narrows a listRecords call. Records come newest rkey first
+// unless Reverse is set. RkeyStart and RkeyEnd are exclusive bounds, so
+// with TID rkeys they select records created in a time range.
+type ListOptions struct {
+	Cursor    string
+	Limit     int
+	RkeyStart string
+	RkeyEnd   string
+	Reverse   bool
+}
+
+// ListRecordsRange returns one page of records in a collection matching
+// opts. Some PDS versions ignore the rkey bounds, so records outside them
+// are dropped from the page here too; the cursor is passed through as is.
+func (c *Client) ListRecordsRange(ctx context.Context, repo, collection string, opts ListOptions) (*ListRecordsResponse, error) {
+	query := url.Values{
+		"repo":       {repo},
+		"collection": {collection},
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.RkeyStart != "" {
+		query.Set("rkeyStart", opts.RkeyStart)
+	}
+	if opts.RkeyEnd != "" {
+		query.Set("rkeyEnd", opts.RkeyEnd)
+	}
+	if opts.Reverse {
+		query.Set("reverse", "true")
+	}
+	var out ListRecordsResponse
+	if err := c.do(ctx, http.MethodGet, "com.atproto.repo.listRecords", query, nil, &out); err != nil {
+		return nil, err
+	}
+	if opts.RkeyStart != "" || opts.RkeyEnd != "" {
+		records := out.Records[:0]
+		for _, record := range out.Records {
+			if opts.contains(record.Rkey()) {
+				records = append(records, record)
+			}
+		}
+		out.Records = records
+	}
+	return &out, nil
+}
+
+// contains reports whether rkey lies strictly between the bounds that are set
+func (o ListOptions) contains(rkey string) bool {
+	return (o.RkeyStart == "" || rkey > o.RkeyStart) && (o.RkeyEnd == "" || rkey < o.RkeyEnd)
+}
+
+// ApplyWrites applies up to MaxWritesPerBatch operations to repo atomically,
+// after running the client's record hooks on the records written
+func (c *Client) ApplyWrites(ctx context.Context, repo string, writes []Write) error {
+	if len(writes) > MaxWritesPerBatch {
+		return fmt.Errorf("applyWrites accepts at most %d operations, got %d", MaxWritesPerBatch, len(writes))
+	}
+	writes, err := c.hookWrites(ctx, writes)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Repo   string  `json:"repo"`
+		Writes []Write `json:"writes"`
+	}{repo, writes})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "com.atproto.repo.applyWrites", nil, body, nil)
+}
+
+// RecordRef identifies the version of a record a write produced
+type RecordRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// recordInput is the body shared by createRecord, putRecord and deleteRecord
+type recordInput struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey,omitempty"`
+	Record     any    `json:"record,omitempty"`
+	Validate   *bool  `json:"validate,omitempty"`
+}
+
+// CreateRecord writes a new record, after running the client's record
+// hooks on it. An empty rkey lets the PDS choose one. Whether the PDS
+// validates it follows the client's ValidationPolicy.
+func (c *Client) CreateRecord(ctx context.Context, repo, collection, rkey string, record any) (*RecordRef, error) {
+	var out RecordRef
+	in := recordInput{Repo: repo, Collection: collection, Rkey: rkey, Record: record}
+	if err := c.writeRecord(ctx, "com.atproto.repo.createRecord", in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRecordAt fetches the record uri names. It is a RecordFetcher.
+func (c *Client) GetRecordAt(ctx context.Context, uri ATURI) (*Record, error) {
+	return c.GetRecord(ctx, uri.Repo, uri.Collection, uri.Rkey)
+}
+
+// GetRecord fetches one record
+func (c *Client) GetRecord(ctx context.Context, repo, collection, rkey string) (*Record, error) {
+	query := url.Values{
+		"repo":       {repo},
+		"collection": {collection},
+		"rkey":       {rkey},
+	}
+	var out Record
+	if err := c.do(ctx, http.MethodGet, "com.atproto.repo.getRecord", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutRecord creates or replaces the record at rkey, after running the
+// client's record hooks on it
+func (c *Client) PutRecord(ctx context.Context, repo, collection, rkey string, record any) (*RecordRef, error) {
+	var out RecordRef
+	in := recordInput{Repo: repo, Collection: collection, Rkey: rkey, Record: record}
+	if err := c.writeRecord(ctx, "com.atproto.repo.putRecord", in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteRecord deletes the record at rkey. Deleting a missing record is not
+// an error.
+func (c *Client) DeleteRecord(ctx context.Context, repo, collection, rkey string) error {
+	in := recordInput{Repo: repo, Collection: collection, Rkey: rkey}
+	return c.post(ctx, "com.atproto.repo.deleteRecord", in, nil)
+}
+
+func (c *Client) post(ctx context.Context, nsid string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, nsid, nil, body, out)
+}
+
+// DeleteCollections deletes every record in repo's collections whose NSID
+// starts with prefix, in batches of MaxWritesPerBatch. It returns how many
+// records were deleted, including when it stops early on an error.
+func (c *Client) DeleteCollections(ctx context.Context, repo, prefix string) (int, error) {
+	collections, err := c.DescribeRepo(ctx, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, collection := range collections {
+		if !strings.HasPrefix(collection, prefix) {
+			continue
+		}
+		// Collect the keys first: deleting while paging would shift the cursor
+		var writes []Write
+		cursor := ""
+		for {
+			page, err := c.ListRecords(ctx, repo, collection, cursor, listRecordsPageSize)
+			if err != nil {
+				return deleted, err
+			}
+			for _, record := range page.Records {
+				writes = append(writes, DeleteWrite(collection, record.Rkey()))
+			}
+			if page.Cursor == "" || len(page.Records) == 0 {
+				break
+			}
+			cursor = page.Cursor
+		}
+		for start := 0; start < len(writes); start += MaxWritesPerBatch {
+			batch := writes[start:min(start+MaxWritesPerBatch, len(writes))]
+			if err := c.ApplyWrites(ctx, repo, batch); err != nil {
+				return deleted, err
+			}
+			deleted += len(batch)
+		}
+	}
+	return deleted, nil
+}
+
+// do sends an XRPC request with the PDS's latest DPoP nonce, retrying once
+// when the PDS asks for a fresh one, and decodes the response into out when it is non-nil
+func (c *Client) do(ctx context.Context, method, nsid string, query url.Values, body []byte, out any) error {
+	endpoint := c.host + "/xrpc/" + nsid
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	metrics := c.metricsSink()
+	nonce := c.currentNonce()
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if err := c.auth.Authorize(req, nonce); err != nil {
+			return fmt.Errorf("failed to authorize %s: %w", nsid, err)
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			metrics.ObserveRequest(nsid, 0, time.Since(start))
+			return Unavailable(c.host, nil, err)
+		}
+		metrics.ObserveRequest(nsid, resp.StatusCode, time.Since(start))
+		fresh := resp.Header.Get("DPoP-Nonce")
+		if fresh != "" {
+			c.setNonce(fresh)
+		}
+		// Retry with the nonce this response carried: a concurrent request
+		// may have stored an older one since
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && fresh != "" && fresh != nonce {
+			_ = resp.Body.Close()
+			metrics.CountNonceRetry()
+			nonce = fresh
+			continue
+		}
+		return Unavailable(c.host, resp, decodeResponse(resp, out))
+	}
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		xrpcErr := &XRPCError{Status: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		_ = json.Unmarshal(data, xrpcErr)
+		return asScopeError(xrpcErr, resp.Header)
+	}
+	if out == nil {
+		return nil
+	}
+	switch out := out.(type) {
+	case *rawBody:
+		data, err := readLimited(resp.Body, maxRecordProof)
+		*out = data
+		return err
+	case *blobBody:
+		data, err := readLimited(resp.Body, maxBlobSize)
+		*out = data
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readLimited reads r to the end, failing when it holds more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err == nil && int64(len(data)) > limit {
+		err = fmt.Errorf("response exceeds %d bytes", limit)
+	}
+	return data, err
+}
+
+// rawBody receives a response that is not JSON, such as a CAR file
+type rawBody []byte
+
+// blobBody receives a blob, which may be larger than a record proof
+type blobBody []byte
+
+// SetNonceCache keeps the PDS's DPoP nonces in cache instead of the one
+// shared by all clients
+func (c *Client) SetNonceCache(cache *NonceCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonces = cache
+}
+
+func (c *Client) nonceCache() *NonceCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nonces != nil {
+		return c.nonces
+	}
+	return Nonces()
+}
+
+func (c *Client) currentNonce() string {
+	return c.nonceCache().Get(c.host)
+}
+
+func (c *Client) setNonce(nonce string) {
+	c.nonceCache().Set(c.host, nonce)
+}
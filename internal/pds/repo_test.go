@@ -0,0 +1,354 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakePDS serves describeRepo, listRecords, applyWrites and the single
+// record methods over an in-memory repo, requiring a DPoP nonce like a real
+// PDS does. Writes asking for validation fail unless the collection is in
+// lexicons.
+type fakePDS struct {
+	mu        sync.Mutex
+	records   map[string][]string        // collection -> rkeys
+	values    map[string]json.RawMessage // collection/rkey -> record
+	lexicons  map[string]bool
+	validated []bool // validate flag of each record write
+	batches   []int
+	nonce     string
+}
+
+func (f *fakePDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("DPoP-Nonce", f.nonce)
+	if r.Header.Get("X-Nonce") != f.nonce {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "use_dpop_nonce"})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/xrpc/com.atproto.repo.describeRepo":
+		var collections []string
+		for collection := range f.records {
+			collections = append(collections, collection)
+		}
+		sort.Strings(collections)
+		_ = json.NewEncoder(w).Encode(map[string]any{"collections": collections})
+	case "/xrpc/com.atproto.repo.listRecords":
+		collection := r.URL.Query().Get("collection")
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		rkeys := f.records[collection]
+		end := min(start+limit, len(rkeys))
+		page := ListRecordsResponse{Records: []Record{}}
+		for _, rkey := range rkeys[start:end] {
+			page.Records = append(page.Records, Record{
+				URI:   fmt.Sprintf("at://did:plc:test/%s/%s", collection, rkey),
+				Value: f.values[collection+"/"+rkey],
+			})
+		}
+		if end < len(rkeys) {
+			page.Cursor = strconv.Itoa(end)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	case "/xrpc/com.atproto.repo.applyWrites":
+		var body struct {
+			Writes []Write `json:"writes"`
+		}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil || len(body.Writes) > MaxWritesPerBatch {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidRequest", "message": "bad writes"})
+			return
+		}
+		for _, write := range body.Writes {
+			if write.Type == "com.atproto.repo.applyWrites#update" {
+				f.values[write.Collection+"/"+write.Rkey], _ = json.Marshal(write.Value)
+				continue
+			}
+			rkeys := f.records[write.Collection]
+			for i, rkey := range rkeys {
+				if rkey == write.Rkey {
+					f.records[write.Collection] = append(rkeys[:i], rkeys[i+1:]...)
+					break
+				}
+			}
+			if len(f.records[write.Collection]) == 0 {
+				delete(f.records, write.Collection)
+			}
+		}
+		f.batches = append(f.batches, len(body.Writes))
+		_ = json.NewEncoder(w).Encode(map[string]any{})
+	case "/xrpc/com.atproto.repo.createRecord", "/xrpc/com.atproto.repo.putRecord":
+		var in struct {
+			Collection string          `json:"collection"`
+			Rkey       string          `json:"rkey"`
+			Record     json.RawMessage `json:"record"`
+			Validate   bool            `json:"validate"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.validated = append(f.validated, in.Validate)
+		if in.Validate && !f.lexicons[in.Collection] {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidRequest", "message": "Lexicon not found: lex:" + in.Collection})
+			return
+		}
+		key := in.Collection + "/" + in.Rkey
+		if _, exists := f.values[key]; exists && r.URL.Path == "/xrpc/com.atproto.repo.createRecord" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "InvalidRequest", "message": "record already exists"})
+			return
+		}
+		if f.values == nil {
+			f.values = map[string]json.RawMessage{}
+		}
+		f.values[key] = in.Record
+		_ = json.NewEncoder(w).Encode(RecordRef{URI: "at://did:plc:test/" + key, CID: fmt.Sprintf("cid-%d", len(in.Record))})
+	case "/xrpc/com.atproto.repo.getRecord":
+		key := r.URL.Query().Get("collection") + "/" + r.URL.Query().Get("rkey")
+		value, ok := f.values[key]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "RecordNotFound", "message": "Could not locate record"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Record{URI: "at://did:plc:test/" + key, Value: value})
+	case "/xrpc/com.atproto.repo.deleteRecord":
+		var in struct {
+			Collection string `json:"collection"`
+			Rkey       string `json:"rkey"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		delete(f.values, in.Collection+"/"+in.Rkey)
+		_ = json.NewEncoder(w).Encode(map[string]any{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// nonceAuth records the nonce it was given so fakePDS can check it
+type nonceAuth struct{}
+
+func (nonceAuth) Authorize(req *http.Request, nonce string) error {
+	req.Header.Set("X-Nonce", nonce)
+	return nil
+}
+
+func TestDeleteCollections(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{}, nonce: "n1"}
+	for i := 0; i < 250; i++ {
+		fake.records["quest.dis.message"] = append(fake.records["quest.dis.message"], fmt.Sprintf("m%d", i))
+	}
+	fake.records["quest.dis.topic"] = []string{"t1", "t2"}
+	fake.records["app.bsky.feed.post"] = []string{"p1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	deleted, err := client.DeleteCollections(context.Background(), "did:plc:test", "quest.dis.")
+	if err != nil {
+		t.Fatalf("DeleteCollections: %v", err)
+	}
+	if deleted != 252 {
+		t.Errorf("deleted = %d, want 252", deleted)
+	}
+	if len(fake.records) != 1 || len(fake.records["app.bsky.feed.post"]) != 1 {
+		t.Errorf("unexpected remaining records: %v", fake.records)
+	}
+	for _, n := range fake.batches {
+		if n > MaxWritesPerBatch {
+			t.Errorf("batch of %d exceeds %d", n, MaxWritesPerBatch)
+		}
+	}
+}
+
+func TestClientsShareNonces(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{"quest.dis.topic": {"t1"}}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	nonces := NewNonceCache()
+	sink := &recordingSink{}
+	for range 3 {
+		// Handlers create a client per request
+		client := NewClient(srv.URL, nonceAuth{}, nil)
+		client.SetNonceCache(nonces)
+		client.SetMetrics(sink)
+		if _, err := client.DescribeRepo(context.Background(), "did:plc:test"); err != nil {
+			t.Fatalf("DescribeRepo: %v", err)
+		}
+	}
+	if sink.nonceRetries != 1 || len(sink.statuses) != 4 {
+		t.Errorf("%d nonce retries in %d requests, want only the first client's", sink.nonceRetries, len(sink.statuses))
+	}
+	if got := nonces.Get(srv.URL + "/xrpc/com.atproto.repo.describeRepo"); got != "n1" {
+		t.Errorf("cached nonce = %q, want n1", got)
+	}
+	if got := nonces.Get("http://elsewhere.example"); got != "" {
+		t.Errorf("nonce for another server = %q, want none", got)
+	}
+}
+
+func TestRecordLifecycle(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	ctx := context.Background()
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	ref, err := client.CreateRecord(ctx, "did:plc:test", "quest.dis.topic", "t1", map[string]string{"title": "first"})
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+	if ref.URI != "at://did:plc:test/quest.dis.topic/t1" || ref.CID == "" {
+		t.Errorf("unexpected ref: %+v", ref)
+	}
+	if _, err := client.PutRecord(ctx, "did:plc:test", "quest.dis.topic", "t1", map[string]string{"title": "second"}); err != nil {
+		t.Fatalf("PutRecord: %v", err)
+	}
+	record, err := client.GetRecord(ctx, "did:plc:test", "quest.dis.topic", "t1")
+	if err != nil {
+		t.Fatalf("GetRecord: %v", err)
+	}
+	if string(record.Value) != `{"title":"second"}` || record.Rkey() != "t1" {
+		t.Errorf("unexpected record: %s %s", record.URI, record.Value)
+	}
+	if err := client.DeleteRecord(ctx, "did:plc:test", "quest.dis.topic", "t1"); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	_, err = client.GetRecord(ctx, "did:plc:test", "quest.dis.topic", "t1")
+	var xrpcErr *XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Name != "RecordNotFound" {
+		t.Errorf("GetRecord after delete = %v, want RecordNotFound", err)
+	}
+}
+
+// TestClientConcurrentUse shares one client between goroutines, as web
+// handlers do, while the PDS rotates its nonce; run it with -race
+func TestClientConcurrentUse(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	const workers, writes = 8, 20
+	ctx := context.Background()
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	client.AddRecordHooks(SetType)
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*writes)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				if w == 0 && i == writes/2 {
+					fake.mu.Lock()
+					fake.nonce = "n2"
+					fake.mu.Unlock()
+				}
+				rkey := fmt.Sprintf("t%d-%d", w, i)
+				if _, err := client.CreateRecord(ctx, "did:plc:test", "quest.dis.topic", rkey, map[string]string{"title": rkey}); err != nil {
+					errs <- fmt.Errorf("CreateRecord %s: %w", rkey, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if len(fake.values) != workers*writes {
+		t.Errorf("PDS holds %d records, want %d", len(fake.values), workers*writes)
+	}
+	if supported, known := client.KnowsLexicon("quest.dis.topic"); !known || supported {
+		t.Errorf("KnowsLexicon = %v, %v; want false, true", supported, known)
+	}
+}
+
+func TestListRecordsRange(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		// Like a PDS that ignores the rkey bounds
+		page := ListRecordsResponse{Cursor: "3kb"}
+		for _, rkey := range []string{"3ka", "3kb", "3kc", "3kd"} {
+			page.Records = append(page.Records, Record{URI: "at://did:plc:test/quest.dis.message/" + rkey})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, Anonymous{}, nil)
+	page, err := client.ListRecordsRange(context.Background(), "did:plc:test", "quest.dis.message", ListOptions{
+		Limit: 10, RkeyStart: "3ka", RkeyEnd: "3kd", Reverse: true,
+	})
+	if err != nil {
+		t.Fatalf("ListRecordsRange: %v", err)
+	}
+	want := url.Values{
+		"repo": {"did:plc:test"}, "collection": {"quest.dis.message"}, "limit": {"10"},
+		"rkeyStart": {"3ka"}, "rkeyEnd": {"3kd"}, "reverse": {"true"},
+	}
+	if query.Encode() != want.Encode() {
+		t.Errorf("query = %s, want %s", query.Encode(), want.Encode())
+	}
+	var rkeys []string
+	for _, record := range page.Records {
+		rkeys = append(rkeys, record.Rkey())
+	}
+	if strings.Join(rkeys, ",") != "3kb,3kc" || page.Cursor != "3kb" {
+		t.Errorf("records = %v, cursor %q", rkeys, page.Cursor)
+	}
+
+	if _, err := client.ListRecords(context.Background(), "did:plc:test", "quest.dis.message", "c1", 5); err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if query.Get("cursor") != "c1" || query.Has("reverse") || query.Has("rkeyStart") {
+		t.Errorf("ListRecords query = %s", query.Encode())
+	}
+}
+
+func TestClientXRPCError(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	writes := make([]Write, MaxWritesPerBatch+1)
+	if err := client.ApplyWrites(context.Background(), "did:plc:test", writes); err == nil {
+		t.Fatal("expected oversized batch to be refused")
+	}
+
+	err := client.do(context.Background(), http.MethodGet, "com.atproto.repo.unknown", nil, nil, nil)
+	var xrpcErr *XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Status != http.StatusNotFound {
+		t.Fatalf("expected 404 XRPCError, got %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if err := BearerToken("abc").Authorize(req, ""); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
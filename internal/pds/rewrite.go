@@ -0,0 +1,80 @@
+package pds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// UpdateWrite returns an applyWrites operation replacing one record
+func UpdateWrite(collection, rkey string, value any) Write {
+	return Write{Type: "com.atproto.repo.applyWrites#update", Collection: collection, Rkey: rkey, Value: value}
+}
+
+// RewriteResult counts what RewriteRecords did to a collection
+type RewriteResult struct {
+	Scanned   int `json:"scanned"`
+	Rewritten int `json:"rewritten"`
+	// Skipped lists the rkeys of records rewrite rejected
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// RewriteRecords passes every record in a collection to rewrite and writes
+// back the ones it changed, in applyWrites batches of MaxWritesPerBatch.
+// Records are decoded with json.Number so values rewrite leaves alone are
+// written back exactly. A record rewrite returns an error for is skipped
+// rather than stopping the run. The result counts what was written,
+// including when RewriteRecords stops early on a PDS error.
+func (c *Client) RewriteRecords(ctx context.Context, repo, collection string, rewrite func(record map[string]any) (bool, error)) (RewriteResult, error) {
+	var result RewriteResult
+	// Collect the changes first: writing while paging would disturb the cursor
+	var writes []Write
+	cursor := ""
+	for {
+		page, err := c.ListRecords(ctx, repo, collection, cursor, listRecordsPageSize)
+		if err != nil {
+			return result, err
+		}
+		for _, record := range page.Records {
+			result.Scanned++
+			value, err := decodeRecordValue(record.Value)
+			changed := false
+			if err == nil {
+				changed, err = rewrite(value)
+			}
+			switch {
+			case err != nil:
+				result.Skipped = append(result.Skipped, record.Rkey())
+			case changed:
+				writes = append(writes, UpdateWrite(collection, record.Rkey(), value))
+			}
+		}
+		if page.Cursor == "" || len(page.Records) == 0 {
+			break
+		}
+		cursor = page.Cursor
+	}
+
+	for start := 0; start < len(writes); start += MaxWritesPerBatch {
+		batch := writes[start:min(start+MaxWritesPerBatch, len(writes))]
+		if err := c.ApplyWrites(ctx, repo, batch); err != nil {
+			return result, err
+		}
+		result.Rewritten += len(batch)
+	}
+	return result, nil
+}
+
+func decodeRecordValue(data json.RawMessage) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value map[string]any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("record is not an object")
+	}
+	return value, nil
+}
@@ -0,0 +1,51 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRewriteRecords(t *testing.T) {
+	fake := &fakePDS{
+		records: map[string][]string{"quest.dis.topic": {"old", "new", "bad"}},
+		values: map[string]json.RawMessage{
+			"quest.dis.topic/old": json.RawMessage(`{"title":"Old","score":12345678901234567890,"extra":{"kept":true}}`),
+			"quest.dis.topic/new": json.RawMessage(`{"title":"New","recordVersion":1}`),
+			"quest.dis.topic/bad": json.RawMessage(`{"title":"Bad"}`),
+		},
+		nonce: "n1",
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, nonceAuth{}, nil)
+	result, err := client.RewriteRecords(context.Background(), "did:plc:test", "quest.dis.topic", func(record map[string]any) (bool, error) {
+		switch {
+		case record["title"] == "Bad":
+			return false, errors.New("cannot migrate")
+		case record["recordVersion"] != nil:
+			return false, nil
+		}
+		record["recordVersion"] = 1
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("RewriteRecords: %v", err)
+	}
+	if result.Scanned != 3 || result.Rewritten != 1 || !slices.Equal(result.Skipped, []string{"bad"}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if got := string(fake.values["quest.dis.topic/old"]); got != `{"extra":{"kept":true},"recordVersion":1,"score":12345678901234567890,"title":"Old"}` {
+		t.Errorf("rewritten record = %s", got)
+	}
+	if got := string(fake.values["quest.dis.topic/bad"]); got != `{"title":"Bad"}` {
+		t.Errorf("skipped record was changed: %s", got)
+	}
+	if !slices.Equal(fake.batches, []int{1}) {
+		t.Errorf("batches = %v, want one write", fake.batches)
+	}
+}
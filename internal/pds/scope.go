@@ -0,0 +1,75 @@
+package pds
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInsufficientScope matches PDS errors refusing a request because the
+// access token's scope does not cover it
+var ErrInsufficientScope = errors.New("insufficient token scope")
+
+// ScopeError is a PDS refusing a request because of its token's scope. It
+// matches both ErrInsufficientScope and *XRPCError.
+type ScopeError struct {
+	*XRPCError
+	// Required is the scope the PDS asked for in its WWW-Authenticate
+	// challenge, empty when it did not name one
+	Required string
+}
+
+// Unwrap exposes the XRPC error and ErrInsufficientScope to errors.Is and
+// errors.As
+func (e *ScopeError) Unwrap() []error {
+	return []error{e.XRPCError, ErrInsufficientScope}
+}
+
+// asScopeError returns xrpcErr as a *ScopeError when the response refused
+// the token's scope. RFC 6750 servers say so with an insufficient_scope
+// challenge; atproto PDSes reply InvalidToken "Bad token scope".
+func asScopeError(xrpcErr *XRPCError, header http.Header) error {
+	params := challengeParams(header.Get("WWW-Authenticate"))
+	if params["error"] == "insufficient_scope" {
+		return &ScopeError{XRPCError: xrpcErr, Required: params["scope"]}
+	}
+	switch xrpcErr.Status {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		if strings.Contains(strings.ToLower(xrpcErr.Message), "scope") {
+			return &ScopeError{XRPCError: xrpcErr, Required: params["scope"]}
+		}
+	}
+	return xrpcErr
+}
+
+// challengeParams reads the auth-params of a WWW-Authenticate challenge such
+// as `DPoP error="insufficient_scope", scope="atproto"`. Only the first
+// challenge is read.
+func challengeParams(challenge string) map[string]string {
+	params := make(map[string]string)
+	if _, rest, ok := strings.Cut(strings.TrimSpace(challenge), " "); ok {
+		challenge = rest
+	}
+	for challenge != "" {
+		var param string
+		name, rest, ok := strings.Cut(challenge, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			param, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			param, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		params[name] = strings.TrimSpace(param)
+		_, challenge, _ = strings.Cut(rest, ",")
+	}
+	return params
+}
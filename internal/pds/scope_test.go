@@ -0,0 +1,51 @@
+package pds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScopeError(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		challenge string
+		status    int
+		body      string
+		wantScope bool
+		required  string
+	}{
+		{name: "atproto bad token scope", status: http.StatusBadRequest, body: `{"error":"InvalidToken","message":"Bad token scope"}`, wantScope: true},
+		{name: "insufficient_scope challenge", challenge: `DPoP error="insufficient_scope", scope="transition:generic"`, status: http.StatusForbidden, body: `{"error":"Forbidden"}`, wantScope: true, required: "transition:generic"},
+		{name: "unquoted params", challenge: `Bearer error=insufficient_scope,scope=atproto`, status: http.StatusUnauthorized, body: `{}`, wantScope: true, required: "atproto"},
+		{name: "other errors", challenge: `DPoP error="invalid_token"`, status: http.StatusUnauthorized, body: `{"error":"ExpiredToken"}`},
+		{name: "scope mentioned on a server error", status: http.StatusInternalServerError, body: `{"error":"InternalServerError","message":"scope lookup failed"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tc.challenge != "" {
+					w.Header().Set("WWW-Authenticate", tc.challenge)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			client := NewClient(srv.URL, Anonymous{}, nil)
+			_, err := client.PutRecord(context.Background(), "did:plc:test", "quest.dis.topic", "t1", map[string]any{})
+			var xrpcErr *XRPCError
+			if !errors.As(err, &xrpcErr) || xrpcErr.Status != tc.status {
+				t.Fatalf("expected a %d XRPCError, got %v", tc.status, err)
+			}
+			if got := errors.Is(err, ErrInsufficientScope); got != tc.wantScope {
+				t.Fatalf("errors.Is(err, ErrInsufficientScope) = %v, want %v", got, tc.wantScope)
+			}
+			var scopeErr *ScopeError
+			if tc.wantScope && (!errors.As(err, &scopeErr) || scopeErr.Required != tc.required) {
+				t.Fatalf("expected ScopeError requiring %q, got %v", tc.required, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package pds
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// listReposPageSize is the page size used when walking the repos of a host
+	listReposPageSize = 500
+	// maxRecordProof bounds the CAR file of a record proof
+	maxRecordProof = 2 << 20
+	// maxBlobSize bounds a blob fetched with com.atproto.sync.getBlob,
+	// above the 1MB limit Bluesky's lexicons put on images
+	maxBlobSize = 5 << 20
+)
+
+// RepoInfo is a repo as listed by com.atproto.sync.listRepos
+type RepoInfo struct {
+	DID    string `json:"did"`
+	Head   string `json:"head"`
+	Rev    string `json:"rev"`
+	Active *bool  `json:"active,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// IsActive reports whether the repo can be read. Hosts that predate the
+// active flag leave it unset, which counts as active.
+func (r RepoInfo) IsActive() bool {
+	return r.Active == nil || *r.Active
+}
+
+// ListReposResponse is one page of the repos a host serves
+type ListReposResponse struct {
+	Repos  []RepoInfo `json:"repos"`
+	Cursor string     `json:"cursor,omitempty"`
+}
+
+// ListRepos returns one page of the repos hosted by the PDS, or known to
+// the relay, the client talks to
+func (c *Client) ListRepos(ctx context.Context, cursor string, limit int) (*ListReposResponse, error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	var out ListReposResponse
+	if err := c.do(ctx, http.MethodGet, "com.atproto.sync.listRepos", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WalkRepos passes every repo the host lists to fn, page by page, stopping
+// at the first error from the host or fn
+func (c *Client) WalkRepos(ctx context.Context, fn func(RepoInfo) error) error {
+	cursor := ""
+	for {
+		page, err := c.ListRepos(ctx, cursor, listReposPageSize)
+		if err != nil {
+			return err
+		}
+		for _, repo := range page.Repos {
+			if err := fn(repo); err != nil {
+				return err
+			}
+		}
+		if page.Cursor == "" || len(page.Repos) == 0 {
+			return nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// FindRepos passes fn each active repo on a PDS holding a collection whose
+// NSID starts with prefix, with those collections, so a crawler can
+// backfill only the repos that use them. Repos the PDS cannot describe,
+// such as ones deleted since they were listed, are skipped.
+func (c *Client) FindRepos(ctx context.Context, prefix string, fn func(did string, collections []string) error) error {
+	return c.WalkRepos(ctx, func(repo RepoInfo) error {
+		if !repo.IsActive() {
+			return nil
+		}
+		collections, err := c.DescribeRepo(ctx, repo.DID)
+		if err != nil {
+			if ctx.Err() != nil || IsUnavailable(err) {
+				return err
+			}
+			return nil
+		}
+		var matched []string
+		for _, collection := range collections {
+			if strings.HasPrefix(collection, prefix) {
+				matched = append(matched, collection)
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		return fn(repo.DID, matched)
+	})
+}
+
+// GetRecordProof returns the CAR file com.atproto.sync.getRecord serves for
+// a record: the repository's signed commit, the MST nodes leading to the
+// record, and the record itself
+func (c *Client) GetRecordProof(ctx context.Context, repo, collection, rkey string) ([]byte, error) {
+	query := url.Values{"did": {repo}, "collection": {collection}, "rkey": {rkey}}
+	var car rawBody
+	if err := c.do(ctx, http.MethodGet, "com.atproto.sync.getRecord", query, nil, &car); err != nil {
+		return nil, err
+	}
+	return car, nil
+}
+
+// GetBlob returns the blob a repo holds under cid, as
+// com.atproto.sync.getBlob serves it. Callers check the bytes against the
+// CID themselves.
+func (c *Client) GetBlob(ctx context.Context, did, cid string) ([]byte, error) {
+	query := url.Values{"did": {did}, "cid": {cid}}
+	var blob blobBody
+	if err := c.do(ctx, http.MethodGet, "com.atproto.sync.getBlob", query, nil, &blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
@@ -0,0 +1,100 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindRepos(t *testing.T) {
+	inactive := false
+	pages := map[string]ListReposResponse{
+		"": {Repos: []RepoInfo{{DID: "did:plc:alice"}, {DID: "did:plc:bob"}}, Cursor: "2"},
+		"2": {Repos: []RepoInfo{
+			{DID: "did:plc:carol", Active: &inactive, Status: "deactivated"},
+			{DID: "did:plc:gone"},
+			{DID: "did:plc:dave"},
+		}},
+	}
+	collections := map[string][]string{
+		"did:plc:alice": {"app.bsky.feed.post", "quest.dis.topic"},
+		"did:plc:bob":   {"app.bsky.feed.post"},
+		"did:plc:carol": {"quest.dis.topic"},
+		"did:plc:dave":  {"quest.dis.message", "quest.dis.participation"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.sync.listRepos":
+			_ = json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+		case "/xrpc/com.atproto.repo.describeRepo":
+			repo, ok := collections[r.URL.Query().Get("repo")]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "RepoNotFound"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"collections": repo})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	found := map[string][]string{}
+	client := NewClient(srv.URL, Anonymous{}, nil)
+	err := client.FindRepos(context.Background(), "quest.dis.", func(did string, collections []string) error {
+		found[did] = collections
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FindRepos: %v", err)
+	}
+	want := map[string][]string{
+		"did:plc:alice": {"quest.dis.topic"},
+		"did:plc:dave":  {"quest.dis.message", "quest.dis.participation"},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("found %v, want %v", found, want)
+	}
+}
+
+func TestGetBlob(t *testing.T) {
+	blobs := map[string][]byte{
+		"bafkreismall": []byte("image bytes"),
+		"bafkreihuge":  make([]byte, maxBlobSize+1),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/xrpc/com.atproto.sync.getBlob" || r.URL.Query().Get("did") != "did:plc:alice" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		blob, ok := blobs[r.URL.Query().Get("cid")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "BlobNotFound"})
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(blob)
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, Anonymous{}, nil)
+	blob, err := client.GetBlob(context.Background(), "did:plc:alice", "bafkreismall")
+	if err != nil || string(blob) != "image bytes" {
+		t.Errorf("GetBlob = %q, %v", blob, err)
+	}
+	if _, err := client.GetBlob(context.Background(), "did:plc:alice", "bafkreihuge"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("expected an oversized blob refused, got %v", err)
+	}
+	_, err = client.GetBlob(context.Background(), "did:plc:alice", "bafkreimissing")
+	var xrpcErr *XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Name != "BlobNotFound" {
+		t.Errorf("GetBlob of a missing blob = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package pds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrUnavailable is matched by errors caused by a PDS (or authorization
+// server) being down, in maintenance or too slow to answer, as opposed to
+// rejecting the request. Retrying later may succeed.
+var ErrUnavailable = errors.New("PDS unavailable")
+
+// UnavailableError reports that host could not serve a request
+type UnavailableError struct {
+	Host string
+	// RetryAfter is how long the server asked clients to wait, or zero
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrUnavailable, e.Host, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnavailable
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// IsUnavailable reports whether err means the server could not be reached
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// RetryAfter returns how long to wait before retrying after err, or zero
+// when err is not an *UnavailableError or the server gave no hint
+func RetryAfter(err error) time.Duration {
+	var unavailable *UnavailableError
+	if errors.As(err, &unavailable) {
+		return unavailable.RetryAfter
+	}
+	return 0
+}
+
+// Unavailable wraps the outcome of a request to host in an *UnavailableError
+// when it failed because host could not serve it: a network error or timeout
+// (resp is nil) or a 502, 503 or 504 response. Otherwise err is returned
+// unchanged. A request the caller cancelled is not an outage.
+func Unavailable(host string, resp *http.Response, err error) error {
+	if resp == nil {
+		var urlErr *url.Error
+		if err == nil || !errors.As(err, &urlErr) || errors.Is(err, context.Canceled) {
+			return err
+		}
+		return &UnavailableError{Host: host, Err: err}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if err == nil {
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return &UnavailableError{Host: host, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Err: err}
+	}
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds. HTTP dates
+// are rare from a PDS and are ignored.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,67 @@
+package pds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientUnavailable(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":"Maintenance","message":"back soon"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, BearerToken("t"), nil)
+	ctx := context.Background()
+
+	_, err := client.DescribeRepo(ctx, "did:plc:test")
+	if !IsUnavailable(err) {
+		t.Fatalf("503: IsUnavailable(%v) = false", err)
+	}
+	if got := RetryAfter(err); got != 30*time.Second {
+		t.Errorf("RetryAfter = %v, want 30s", got)
+	}
+	var xrpcErr *XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Name != "Maintenance" {
+		t.Errorf("503 should still unwrap to the XRPC error, got %v", err)
+	}
+
+	status = http.StatusBadRequest
+	if _, err := client.DescribeRepo(ctx, "did:plc:test"); err == nil || IsUnavailable(err) {
+		t.Errorf("400: got %v, want a non-outage error", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.DescribeRepo(cancelled, "did:plc:test"); err == nil || IsUnavailable(err) {
+		t.Errorf("cancelled request: got %v, want a non-outage error", err)
+	}
+
+	srv.Close()
+	if _, err := client.DescribeRepo(ctx, "did:plc:test"); !IsUnavailable(err) {
+		t.Errorf("unreachable host: IsUnavailable(%v) = false", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewClient(srv.URL, BearerToken("t"), &http.Client{Timeout: 50 * time.Millisecond})
+	if _, err := client.DescribeRepo(context.Background(), "did:plc:test"); !IsUnavailable(err) {
+		t.Errorf("timeout: IsUnavailable(%v) = false", err)
+	}
+}
@@ -0,0 +1,121 @@
+package pds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Validation says whether a PDS should check records against their
+// collection's lexicon when they are written
+type Validation int
+
+const (
+	// ValidateAuto validates when the PDS knows the collection's lexicon and
+	// skips validation when it does not. The first write to a collection
+	// probes the PDS; the answer is remembered for the client's lifetime.
+	ValidateAuto Validation = iota
+	// ValidateAlways requires validation; writes fail if the PDS does not
+	// know the lexicon
+	ValidateAlways
+	// ValidateNever writes records unchecked
+	ValidateNever
+)
+
+// ValidationPolicy chooses a Validation per collection NSID. The zero value
+// uses ValidateAuto everywhere.
+type ValidationPolicy struct {
+	Default     Validation
+	Collections map[string]Validation
+}
+
+// For returns the Validation to use for collection
+func (p ValidationPolicy) For(collection string) Validation {
+	if v, ok := p.Collections[collection]; ok {
+		return v
+	}
+	return p.Default
+}
+
+// SetValidationPolicy sets how the client asks the PDS to validate records
+// written by CreateRecord and PutRecord
+func (c *Client) SetValidationPolicy(policy ValidationPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validation = policy
+}
+
+// KnowsLexicon reports what the client has learned about whether the PDS
+// can validate collection. known is false until a ValidateAuto write has
+// probed it.
+func (c *Client) KnowsLexicon(collection string) (supported, known bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	supported, known = c.lexicons[collection]
+	return supported, known
+}
+
+// writeRecord posts a createRecord or putRecord call, setting validate from
+// the client's policy. Under ValidateAuto a collection the PDS has not been
+// probed for is written with validation; if the PDS does not know the
+// lexicon the write is repeated without it and the answer remembered.
+func (c *Client) writeRecord(ctx context.Context, nsid string, in recordInput, out any) error {
+	record, err := c.runHooks(ctx, in.Collection, in.Record)
+	if err != nil {
+		return err
+	}
+	in.Record = record
+	validate, probing := c.validateFor(in.Collection)
+	in.Validate = &validate
+	err = c.post(ctx, nsid, in, out)
+	if !probing {
+		return err
+	}
+	if isLexiconNotFound(err) {
+		c.learnLexicon(in.Collection, false)
+		skip := false
+		in.Validate = &skip
+		return c.post(ctx, nsid, in, out)
+	}
+	if err == nil {
+		c.learnLexicon(in.Collection, true)
+	}
+	return err
+}
+
+// validateFor returns the validate flag for a write to collection and
+// whether the write doubles as a probe for the collection's lexicon
+func (c *Client) validateFor(collection string) (validate, probing bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.validation.For(collection) {
+	case ValidateAlways:
+		return true, false
+	case ValidateNever:
+		return false, false
+	}
+	if supported, known := c.lexicons[collection]; known {
+		return supported, false
+	}
+	return true, true
+}
+
+func (c *Client) learnLexicon(collection string, supported bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lexicons == nil {
+		c.lexicons = make(map[string]bool)
+	}
+	c.lexicons[collection] = supported
+}
+
+// isLexiconNotFound reports whether err is a PDS refusing to validate a
+// record because it has no schema for the collection
+func isLexiconNotFound(err error) bool {
+	var xrpcErr *XRPCError
+	if !errors.As(err, &xrpcErr) || xrpcErr.Status != http.StatusBadRequest {
+		return false
+	}
+	return xrpcErr.Name == "LexiconNotFound" || strings.Contains(strings.ToLower(xrpcErr.Message), "lexicon not found")
+}
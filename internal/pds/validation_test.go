@@ -0,0 +1,86 @@
+package pds
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestValidationPolicy(t *testing.T) {
+	fake := &fakePDS{records: map[string][]string{}, lexicons: map[string]bool{"app.bsky.feed.post": true}, nonce: "n1"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+
+	write := func(client *Client, collection, rkey string) error {
+		_, err := client.CreateRecord(ctx, "did:plc:test", collection, rkey, map[string]string{"text": rkey})
+		return err
+	}
+
+	tests := []struct {
+		name          string
+		policy        ValidationPolicy
+		collection    string
+		wantErr       bool
+		wantValidated []bool
+		wantSupported bool
+		wantKnown     bool
+	}{
+		{
+			name:          "auto with a known lexicon validates",
+			collection:    "app.bsky.feed.post",
+			wantValidated: []bool{true, true},
+			wantSupported: true,
+			wantKnown:     true,
+		},
+		{
+			name:          "auto with an unknown lexicon probes once then skips",
+			collection:    "quest.dis.topic",
+			wantValidated: []bool{true, false, false},
+			wantKnown:     true,
+		},
+		{
+			name:          "never skips validation without probing",
+			policy:        ValidationPolicy{Default: ValidateNever},
+			collection:    "app.bsky.feed.post",
+			wantValidated: []bool{false, false},
+		},
+		{
+			name:          "always fails when the PDS lacks the lexicon",
+			policy:        ValidationPolicy{Collections: map[string]Validation{"quest.dis.topic": ValidateAlways}},
+			collection:    "quest.dis.topic",
+			wantErr:       true,
+			wantValidated: []bool{true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.values, fake.validated = nil, nil
+			client := NewClient(srv.URL, nonceAuth{}, nil)
+			client.SetValidationPolicy(tt.policy)
+
+			err := write(client, tt.collection, "r1")
+			if tt.wantErr {
+				if !isLexiconNotFound(err) {
+					t.Fatalf("got %v, want a lexicon not found error", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("first write: %v", err)
+				}
+				if err := write(client, tt.collection, "r2"); err != nil {
+					t.Fatalf("second write: %v", err)
+				}
+			}
+
+			if !slices.Equal(fake.validated, tt.wantValidated) {
+				t.Errorf("validate flags = %v, want %v", fake.validated, tt.wantValidated)
+			}
+			supported, known := client.KnowsLexicon(tt.collection)
+			if supported != tt.wantSupported || known != tt.wantKnown {
+				t.Errorf("KnowsLexicon = %v, %v, want %v, %v", supported, known, tt.wantSupported, tt.wantKnown)
+			}
+		})
+	}
+}
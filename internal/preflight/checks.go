@@ -0,0 +1,204 @@
+package preflight
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+)
+
+// fetchAttempts is how many times a URL is fetched before a check gives up,
+// since a proxy may not route to a freshly started instance straight away
+const fetchAttempts = 3
+
+// maxClientMetadata caps the client metadata document read
+const maxClientMetadata = 64 << 10
+
+// retryDelay is the wait between fetch attempts
+var retryDelay = 2 * time.Second
+
+// Schema is the database whose migrations are checked
+type Schema interface {
+	SchemaVersion(ctx context.Context) (int, error)
+	IsSQLite() bool
+}
+
+// Startup returns the checks run when the server starts: the client
+// metadata and JWKS are critical, since sign-in fails without them, as is a
+// database missing migrations. An unreachable callback only warns, as the
+// instance may not be able to reach its own public address.
+func Startup(cfg *config.Config, database Schema, latestMigration int, client *http.Client) []Check {
+	return []Check{
+		ClientMetadata(cfg, client),
+		RedirectURI(cfg, client),
+		JWKS(cfg),
+		Migrations(database, latestMigration),
+	}
+}
+
+// ClientMetadata checks that the client metadata document at the configured
+// client ID is served, names that client ID and lists every configured
+// redirect URL
+func ClientMetadata(cfg *config.Config, client *http.Client) Check {
+	return Check{Name: "client_metadata", Critical: true, Run: func(ctx context.Context) error {
+		if cfg.OAuthLoopback {
+			return fmt.Errorf("%w: loopback clients have no hosted metadata", ErrSkipped)
+		}
+		resp, err := fetch(ctx, client, cfg.OAuthClientID)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s answered %s", cfg.OAuthClientID, resp.Status)
+		}
+
+		var metadata struct {
+			ClientID              string   `json:"client_id"`
+			RedirectURIs          []string `json:"redirect_uris"`
+			DPoPBoundAccessTokens bool     `json:"dpop_bound_access_tokens"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxClientMetadata)).Decode(&metadata); err != nil {
+			return fmt.Errorf("%s is not client metadata: %w", cfg.OAuthClientID, err)
+		}
+		if metadata.ClientID != cfg.OAuthClientID {
+			return fmt.Errorf("%s names client %q, not itself", cfg.OAuthClientID, metadata.ClientID)
+		}
+		for _, redirectURL := range cfg.RedirectURLs() {
+			if !slices.Contains(metadata.RedirectURIs, redirectURL) {
+				return fmt.Errorf("%s does not list redirect URI %s", cfg.OAuthClientID, redirectURL)
+			}
+		}
+		if !metadata.DPoPBoundAccessTokens {
+			return fmt.Errorf("%s does not ask for DPoP-bound access tokens", cfg.OAuthClientID)
+		}
+		return nil
+	}}
+}
+
+// RedirectURI checks that the OAuth callback answers at the configured
+// redirect URL. The callback rejects a request without a sign-in in
+// progress, so any answer but a missing route or a server error will do.
+func RedirectURI(cfg *config.Config, client *http.Client) Check {
+	return Check{Name: "redirect_uri", Run: func(ctx context.Context) error {
+		if cfg.OAuthLoopback {
+			return fmt.Errorf("%w: loopback clients redirect to this machine", ErrSkipped)
+		}
+		noRedirects := *client
+		noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		resp, err := fetch(ctx, &noRedirects, cfg.OAuthRedirectURL)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s answered %s", cfg.OAuthRedirectURL, resp.Status)
+		}
+		return nil
+	}}
+}
+
+// JWKS checks that the private JWKS holds only private keys, the public
+// JWKS only public ones, and that every private key is published. The
+// public set may hold more keys, such as ones being rotated out.
+func JWKS(cfg *config.Config) Check {
+	return Check{Name: "jwks", Critical: true, Run: func(context.Context) error {
+		private, err := jwk.Parse([]byte(cfg.JWKSPrivate))
+		if err != nil {
+			return fmt.Errorf("private JWKS: %w", err)
+		}
+		public, err := jwk.Parse([]byte(cfg.JWKSPublic))
+		if err != nil {
+			return fmt.Errorf("public JWKS: %w", err)
+		}
+		if private.Len() == 0 {
+			return errors.New("private JWKS has no keys")
+		}
+
+		published := map[string]bool{}
+		for i := range public.Len() {
+			key, _ := public.Key(i)
+			if isPrivate, err := jwk.IsPrivateKey(key); err != nil || isPrivate {
+				return fmt.Errorf("public JWKS key %q is not a public key", key.KeyID())
+			}
+			thumbprint, err := key.Thumbprint(crypto.SHA256)
+			if err != nil {
+				return fmt.Errorf("public JWKS key %q: %w", key.KeyID(), err)
+			}
+			published[string(thumbprint)] = true
+		}
+		for i := range private.Len() {
+			key, _ := private.Key(i)
+			if isPrivate, err := jwk.IsPrivateKey(key); err != nil || !isPrivate {
+				return fmt.Errorf("private JWKS key %q is not a private key", key.KeyID())
+			}
+			pub, err := key.PublicKey()
+			if err != nil {
+				return fmt.Errorf("private JWKS key %q: %w", key.KeyID(), err)
+			}
+			thumbprint, err := pub.Thumbprint(crypto.SHA256)
+			if err != nil {
+				return fmt.Errorf("private JWKS key %q: %w", key.KeyID(), err)
+			}
+			if !published[string(thumbprint)] {
+				return fmt.Errorf("private JWKS key %q is not in the public JWKS", key.KeyID())
+			}
+		}
+		return nil
+	}}
+}
+
+// Migrations checks that the database has every migration up to latest. A
+// newer schema passes, since migrations only add to it. SQLite databases
+// are not migrated with tern, so have no version to check.
+func Migrations(database Schema, latest int) Check {
+	return Check{Name: "migrations", Critical: true, Run: func(ctx context.Context) error {
+		version, err := database.SchemaVersion(ctx)
+		switch {
+		case errors.Is(err, db.ErrUnversioned) && database.IsSQLite():
+			return fmt.Errorf("%w: SQLite schemas are not versioned", ErrSkipped)
+		case errors.Is(err, db.ErrUnversioned):
+			return fmt.Errorf("no migrations have been applied; %d pending", latest)
+		case err != nil:
+			return err
+		case version < latest:
+			return fmt.Errorf("database is at migration %d; %d pending", version, latest-version)
+		}
+		return nil
+	}}
+}
+
+// fetch GETs rawURL, retrying connection failures and server errors
+func fetch(ctx context.Context, client *http.Client, rawURL string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil && (resp.StatusCode < http.StatusInternalServerError || attempt == fetchAttempts) {
+			return resp, nil
+		}
+		if err != nil && attempt == fetchAttempts {
+			return nil, err
+		}
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
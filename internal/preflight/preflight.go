@@ -0,0 +1,111 @@
+// Package preflight checks on startup that the configuration agrees with
+// the world it describes: that the hosted client metadata and OAuth callback
+// are served as configured, that the private and public JWKS are one key
+// set, and that the database has every migration. The results are logged as
+// a single startup report, so a misdeployed instance says what is wrong
+// before the first sign-in fails.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// ErrSkipped is returned, wrapped with the reason, by a check that does not
+// apply to the configuration
+var ErrSkipped = errors.New("skipped")
+
+// Check is one startup check. A critical check guards something the
+// instance cannot work without, such as signing in or reading its tables.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string
+	Critical bool
+	Status   string
+	// Detail explains why the check failed or was skipped
+	Detail   string
+	Duration time.Duration
+}
+
+// Report is the outcome of the startup checks, in the order they were given
+type Report struct {
+	Results []Result
+}
+
+// Run runs checks concurrently, since several wait on the network, and
+// reports their results
+func Run(ctx context.Context, checks ...Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Run(ctx)
+			result := Result{Name: check.Name, Critical: check.Critical, Status: StatusOK, Duration: time.Since(start)}
+			switch {
+			case errors.Is(err, ErrSkipped):
+				result.Status = StatusSkipped
+				result.Detail = err.Error()
+			case err != nil:
+				result.Status = StatusFailed
+				result.Detail = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	return Report{Results: results}
+}
+
+// CriticalFailures returns the critical checks that failed
+func (r Report) CriticalFailures() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Critical && result.Status == StatusFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Log writes the report to l as one record: at error level when a critical
+// check failed, at warning level when another did, and at info otherwise
+func (r Report) Log(l *slog.Logger) {
+	level := slog.LevelInfo
+	counts := map[string]int{}
+	attrs := make([]any, 0, len(r.Results)+3)
+	for _, result := range r.Results {
+		counts[result.Status]++
+		if result.Status == StatusFailed {
+			level = max(level, slog.LevelWarn)
+			if result.Critical {
+				level = slog.LevelError
+			}
+		}
+		group := []any{"status", result.Status, "critical", result.Critical, "ms", result.Duration.Milliseconds()}
+		if result.Detail != "" {
+			group = append(group, "detail", result.Detail)
+		}
+		attrs = append(attrs, slog.Group(result.Name, group...))
+	}
+	attrs = append([]any{"ok", counts[StatusOK], "failed", counts[StatusFailed], "skipped", counts[StatusSkipped]}, attrs...)
+	l.Log(context.Background(), level, "startup report", attrs...)
+}
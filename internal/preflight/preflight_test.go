@@ -0,0 +1,250 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+)
+
+func init() {
+	retryDelay = 0
+}
+
+// keySet returns a private JWKS and its public counterpart
+func keySet(t *testing.T, kid string) (private, public string) {
+	t.Helper()
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = key.Set(jwk.KeyIDKey, kid)
+	pub, err := key.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshal := func(k jwk.Key) string {
+		set := jwk.NewSet()
+		_ = set.AddKey(k)
+		out, err := json.Marshal(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	return marshal(key), marshal(pub)
+}
+
+// instance serves client metadata and an OAuth callback as configured, with
+// metadata changing what is served
+func instance(t *testing.T, metadata func(cfg *config.Config, doc map[string]any)) *config.Config {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{
+		PublicDomain:      srv.URL,
+		OAuthClientID:     srv.URL + "/auth/client-metadata.json",
+		OAuthRedirectURL:  srv.URL + "/auth/callback",
+		OAuthRedirectURLs: "http://127.0.0.1:3000/auth/callback",
+	}
+	mux.HandleFunc("/auth/client-metadata.json", func(w http.ResponseWriter, _ *http.Request) {
+		doc := map[string]any{
+			"client_id":                cfg.OAuthClientID,
+			"redirect_uris":            cfg.RedirectURLs(),
+			"dpop_bound_access_tokens": true,
+		}
+		if metadata != nil {
+			metadata(cfg, doc)
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	})
+	mux.HandleFunc("/auth/callback", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "Missing handle context", http.StatusBadRequest)
+	})
+	return cfg
+}
+
+func TestClientMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata func(cfg *config.Config, doc map[string]any)
+		// configure changes the config after the instance is serving
+		configure func(cfg *config.Config)
+		wantErr   string
+	}{
+		{name: "served as configured"},
+		{
+			name:     "another client ID",
+			metadata: func(cfg *config.Config, doc map[string]any) { doc["client_id"] = cfg.PublicDomain + "/.well-known/bluesky-client-metadata.json" },
+			wantErr:  "names client",
+		},
+		{
+			name:     "redirect URI missing",
+			metadata: func(_ *config.Config, doc map[string]any) { doc["redirect_uris"] = []string{"https://elsewhere.example/auth/callback"} },
+			wantErr:  "does not list redirect URI",
+		},
+		{
+			name:     "tokens not DPoP-bound",
+			metadata: func(_ *config.Config, doc map[string]any) { delete(doc, "dpop_bound_access_tokens") },
+			wantErr:  "DPoP-bound",
+		},
+		{
+			name:      "not served",
+			configure: func(cfg *config.Config) { cfg.OAuthClientID = cfg.PublicDomain + "/client-metadata.json" },
+			wantErr:   "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := instance(t, tt.metadata)
+			if tt.configure != nil {
+				tt.configure(cfg)
+			}
+			err := ClientMetadata(cfg, http.DefaultClient).Run(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("ClientMetadata: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ClientMetadata error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectURI(t *testing.T) {
+	cfg := instance(t, nil)
+	if err := RedirectURI(cfg, http.DefaultClient).Run(context.Background()); err != nil {
+		t.Errorf("RedirectURI: %v", err)
+	}
+	cfg.OAuthRedirectURL = cfg.PublicDomain + "/oauth/callback"
+	if err := RedirectURI(cfg, http.DefaultClient).Run(context.Background()); err == nil {
+		t.Error("expected an unserved callback to fail")
+	}
+	cfg.OAuthLoopback = true
+	if err := RedirectURI(cfg, http.DefaultClient).Run(context.Background()); !errors.Is(err, ErrSkipped) {
+		t.Errorf("RedirectURI error = %v, want ErrSkipped in loopback mode", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	private, public := keySet(t, "current")
+	otherPrivate, otherPublic := keySet(t, "other")
+	rotated := strings.Replace(public, `{"keys":[`, `{"keys":[`+strings.TrimSuffix(strings.TrimPrefix(otherPublic, `{"keys":[`), `]}`)+`,`, 1)
+
+	tests := []struct {
+		name            string
+		private, public string
+		wantErr         string
+	}{
+		{"matching", private, public, ""},
+		{"public set holds a rotated key", private, rotated, ""},
+		{"mismatched", private, otherPublic, `"current" is not in the public JWKS`},
+		{"private key published", private, otherPrivate, "is not a public key"},
+		{"public key as private", public, public, "is not a private key"},
+		{"empty", `{"keys":[]}`, public, "no keys"},
+		{"not a JWKS", "secret", public, "private JWKS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := JWKS(&config.Config{JWKSPrivate: tt.private, JWKSPublic: tt.public}).Run(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("JWKS: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("JWKS error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeSchema struct {
+	version int
+	err     error
+	sqlite  bool
+}
+
+func (s fakeSchema) SchemaVersion(context.Context) (int, error) { return s.version, s.err }
+func (s fakeSchema) IsSQLite() bool                             { return s.sqlite }
+
+func TestMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  fakeSchema
+		status  string
+		wantErr string
+	}{
+		{"current", fakeSchema{version: 18}, StatusOK, ""},
+		{"ahead", fakeSchema{version: 19}, StatusOK, ""},
+		{"behind", fakeSchema{version: 16}, StatusFailed, "at migration 16; 2 pending"},
+		{"never migrated", fakeSchema{err: db.ErrUnversioned}, StatusFailed, "18 pending"},
+		{"SQLite", fakeSchema{err: db.ErrUnversioned, sqlite: true}, StatusSkipped, "SQLite"},
+		{"unreadable", fakeSchema{err: errors.New("connection refused")}, StatusFailed, "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Run(context.Background(), Migrations(tt.schema, 18)).Results[0]
+			if result.Status != tt.status || !strings.Contains(result.Detail, tt.wantErr) {
+				t.Errorf("result = %+v, want %s with %q", result, tt.status, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReportLog(t *testing.T) {
+	check := func(name string, critical bool, err error) Check {
+		return Check{Name: name, Critical: critical, Run: func(context.Context) error { return err }}
+	}
+	tests := []struct {
+		name   string
+		checks []Check
+		level  string
+	}{
+		{"all pass", []Check{check("jwks", true, nil), check("migrations", true, fmt.Errorf("%w: SQLite", ErrSkipped))}, "INFO"},
+		{"warning", []Check{check("jwks", true, nil), check("redirect_uri", false, errors.New("answered 404"))}, "WARN"},
+		{"critical failure", []Check{check("jwks", true, errors.New("mismatched")), check("redirect_uri", false, errors.New("answered 404"))}, "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(context.Background(), tt.checks...)
+			var buf bytes.Buffer
+			report.Log(slog.New(slog.NewJSONHandler(&buf, nil)))
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Fatalf("logged %d records, want one report", lines)
+			}
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+			if record["msg"] != "startup report" || record["level"] != tt.level {
+				t.Errorf("record = %v, want a startup report at %s", record, tt.level)
+			}
+			for _, c := range tt.checks {
+				if _, ok := record[c.Name].(map[string]any); !ok {
+					t.Errorf("report lacks check %s: %v", c.Name, record)
+				}
+			}
+			if critical := len(report.CriticalFailures()) > 0; critical != (tt.level == "ERROR") {
+				t.Errorf("CriticalFailures = %v", report.CriticalFailures())
+			}
+		})
+	}
+}
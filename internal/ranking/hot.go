@@ -0,0 +1,22 @@
+// Package ranking computes sort keys for topic listings.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// DecayPeriod is how much newer a topic must be to outrank one with ten
+// times its activity.
+const DecayPeriod = 12*time.Hour + 30*time.Minute
+
+// epoch anchors rank values so they stay small enough to compare precisely
+var epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Hot returns the hot rank of a topic with the given number of messages whose
+// latest activity happened at lastActivity. Ranks only grow with time, so a
+// stored rank never needs recomputing until the topic sees new activity.
+func Hot(messages int, lastActivity time.Time) float64 {
+	order := math.Log10(math.Max(float64(messages), 1))
+	return order + lastActivity.Sub(epoch).Seconds()/DecayPeriod.Seconds()
+}
@@ -0,0 +1,34 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHot(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		higher, lower float64
+	}{
+		{"more activity wins at the same time", Hot(20, now), Hot(2, now)},
+		{"newer activity wins at the same count", Hot(5, now), Hot(5, now.Add(-time.Hour))},
+		{"recency outweighs activity after a decay period", Hot(1, now), Hot(9, now.Add(-DecayPeriod))},
+		{"ten times activity outweighs less than a decay period", Hot(100, now.Add(-DecayPeriod/2)), Hot(10, now)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.higher <= tt.lower {
+				t.Errorf("expected %f > %f", tt.higher, tt.lower)
+			}
+		})
+	}
+}
+
+func TestHotZeroMessages(t *testing.T) {
+	now := time.Now()
+	if Hot(0, now) != Hot(1, now) {
+		t.Errorf("expected zero and one message to rank equally")
+	}
+}
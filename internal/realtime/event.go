@@ -0,0 +1,62 @@
+// Package realtime provides in-memory fan-out of live topic events to
+// connected Server-Sent Events clients. When the app runs as several
+// instances, Hub.Relay joins their hubs through a broker.
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+// MaxEventSize is the largest event payload written to a stream. Larger
+// events are skipped so one oversized message cannot stall every viewer.
+const MaxEventSize = 64 << 10
+
+// ErrEventTooLarge is returned by WriteEvent for payloads over MaxEventSize
+var ErrEventTooLarge = errors.New("event exceeds maximum size")
+
+// Event types broadcast to subscribers; package events documents their
+// payloads
+const (
+	EventPresence         = events.TypePresence
+	EventTyping           = events.TypeTyping
+	EventMessagePending   = events.TypeMessagePending
+	EventMessageConfirmed = events.TypeMessageConfirmed
+	EventMessageFailed    = events.TypeMessageFailed
+	EventMessageDeleted   = events.TypeMessageDeleted
+	EventMessageRestored  = events.TypeMessageRestored
+	EventUnread           = events.TypeUnread
+)
+
+// Event is a single message delivered to topic subscribers
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// WriteEvent writes an event in SSE wire format and flushes it to the client
+func WriteEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event.Type, err)
+	}
+	if len(data) > MaxEventSize {
+		return fmt.Errorf("%s event of %d bytes: %w", event.Type, len(data), ErrEventTooLarge)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
+
+// WriteComment writes an SSE comment line, used as a keep-alive heartbeat
+func WriteComment(w http.ResponseWriter, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
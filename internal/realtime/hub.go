@@ -0,0 +1,356 @@
+package realtime
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+const (
+	// defaultBufferSize is the number of events queued per subscriber before
+	// it is considered too slow and dropped
+	defaultBufferSize = 64
+	// defaultTypingTTL is how long a typing signal lasts without being renewed
+	defaultTypingTTL = 5 * time.Second
+)
+
+// Viewer identifies who is behind a subscription
+type Viewer struct {
+	// DID is the viewer's DID, empty for anonymous viewers
+	DID string
+	// Hidden opts the viewer out of presence; they still receive events but
+	// are neither counted nor listed
+	Hidden bool
+}
+
+// Presence is a snapshot of who is viewing a topic
+type Presence = events.Presence
+
+// Typing is a snapshot of who is currently typing in a topic
+type Typing = events.Typing
+
+type typingEntry struct {
+	timer   *time.Timer
+	expires time.Time
+}
+
+// Subscriber is a single connection listening to a topic, or to a user's
+// own notifications when topicID is empty
+type Subscriber struct {
+	topicID string
+	viewer  Viewer
+	events  chan Event
+	dropped atomic.Bool
+}
+
+// Events returns the channel of events for this subscriber. The channel is
+// closed when the subscriber is removed from the hub.
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped reports whether the subscriber was removed for falling behind.
+// It is only meaningful once Events has been closed.
+func (s *Subscriber) Dropped() bool {
+	return s.dropped.Load()
+}
+
+// Hub tracks subscribers per topic and per user and fans events out to them
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscriber]struct{}
+	users  map[string]map[*Subscriber]struct{}
+
+	typingMu  sync.Mutex
+	typing    map[string]map[string]*typingEntry
+	typingTTL time.Duration
+
+	// relay is set while Relay runs
+	relay atomic.Pointer[hubRelay]
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{
+		topics:    make(map[string]map[*Subscriber]struct{}),
+		users:     make(map[string]map[*Subscriber]struct{}),
+		typing:    make(map[string]map[string]*typingEntry),
+		typingTTL: defaultTypingTTL,
+	}
+}
+
+// Subscribe registers a viewer on a topic and broadcasts the updated presence
+func (h *Hub) Subscribe(topicID string, viewer Viewer) *Subscriber {
+	sub := &Subscriber{
+		topicID: topicID,
+		viewer:  viewer,
+		events:  make(chan Event, defaultBufferSize),
+	}
+
+	h.mu.Lock()
+	subs, ok := h.topics[topicID]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		h.topics[topicID] = subs
+	}
+	subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	h.broadcastPresence(topicID)
+	return sub
+}
+
+// SubscribeUser registers a connection for notifications addressed to a DID
+// regardless of which topic they concern, such as unread badges. User
+// subscriptions take no part in presence.
+func (h *Hub) SubscribeUser(did string) *Subscriber {
+	sub := &Subscriber{
+		viewer: Viewer{DID: did, Hidden: true},
+		events: make(chan Event, defaultBufferSize),
+	}
+
+	h.mu.Lock()
+	subs, ok := h.users[did]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		h.users[did] = subs
+	}
+	subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a subscriber, closes its event channel and broadcasts
+// the updated presence. It is safe to call more than once.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	if sub.topicID == "" {
+		h.unsubscribeUser(sub)
+		return
+	}
+
+	h.mu.Lock()
+	subs, ok := h.topics[sub.topicID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(subs, sub)
+	close(sub.events)
+	if len(subs) == 0 {
+		delete(h.topics, sub.topicID)
+	}
+	h.mu.Unlock()
+
+	h.broadcastPresence(sub.topicID)
+}
+
+// Publish sends an event to every subscriber of a topic. A subscriber whose
+// buffer is full is dropped rather than allowed to block the publisher or
+// silently miss events; its client reconnects and resyncs.
+func (h *Hub) Publish(topicID string, event Event) {
+	h.publish(topicID, event)
+	h.forward(relayed{Op: opPublish, Topic: topicID, Event: event})
+}
+
+func (h *Hub) publish(topicID string, event Event) {
+	h.mu.RLock()
+	slow := deliver(h.topics[topicID], event, nil)
+	h.mu.RUnlock()
+
+	h.drop(slow)
+}
+
+// PublishUser sends an event to every user subscription of a DID
+func (h *Hub) PublishUser(did string, event Event) {
+	h.publishUser(did, event)
+	h.forward(relayed{Op: opPublishUser, DID: did, Event: event})
+}
+
+func (h *Hub) publishUser(did string, event Event) {
+	h.mu.RLock()
+	slow := deliver(h.users[did], event, nil)
+	h.mu.RUnlock()
+
+	h.drop(slow)
+}
+
+// deliver queues event for each subscriber accepted by match (all of them
+// when match is nil) and returns those whose buffer was full
+func deliver(subs map[*Subscriber]struct{}, event Event, match func(*Subscriber) bool) []*Subscriber {
+	var slow []*Subscriber
+	for sub := range subs {
+		if match != nil && !match(sub) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	return slow
+}
+
+// drop removes subscribers that fell behind. It must be called without
+// holding h.mu since unsubscribing takes the write lock.
+func (h *Hub) drop(slow []*Subscriber) {
+	for _, sub := range slow {
+		sub.dropped.Store(true)
+		h.Unsubscribe(sub)
+	}
+}
+
+func (h *Hub) unsubscribeUser(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.users[sub.viewer.DID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	close(sub.events)
+	if len(subs) == 0 {
+		delete(h.users, sub.viewer.DID)
+	}
+}
+
+// PublishTo sends an event only to the given DID's subscriptions on a topic,
+// such as echoing a pending message back to every tab its author has open
+func (h *Hub) PublishTo(topicID, did string, event Event) {
+	h.publishTo(topicID, did, event)
+	h.forward(relayed{Op: opPublishTo, Topic: topicID, DID: did, Event: event})
+}
+
+func (h *Hub) publishTo(topicID, did string, event Event) {
+	h.mu.RLock()
+	slow := deliver(h.topics[topicID], event, func(sub *Subscriber) bool { return sub.viewer.DID == did })
+	h.mu.RUnlock()
+
+	h.drop(slow)
+}
+
+// SetTyping marks a DID as typing in a topic. The signal expires after a
+// short TTL unless renewed; a typing event is broadcast only when the set of
+// typing users changes.
+func (h *Hub) SetTyping(topicID, did string) {
+	h.setTyping(topicID, did)
+	h.forward(relayed{Op: opTyping, Topic: topicID, DID: did})
+}
+
+func (h *Hub) setTyping(topicID, did string) {
+	h.typingMu.Lock()
+	users, ok := h.typing[topicID]
+	if !ok {
+		users = make(map[string]*typingEntry)
+		h.typing[topicID] = users
+	}
+	expires := time.Now().Add(h.typingTTL)
+	if entry, ok := users[did]; ok {
+		entry.expires = expires
+		entry.timer.Reset(h.typingTTL)
+		h.typingMu.Unlock()
+		return
+	}
+	users[did] = &typingEntry{
+		timer:   time.AfterFunc(h.typingTTL, func() { h.expireTyping(topicID, did) }),
+		expires: expires,
+	}
+	h.typingMu.Unlock()
+
+	h.broadcastTyping(topicID)
+}
+
+// ClearTyping removes a DID's typing signal, e.g. once their message is sent
+func (h *Hub) ClearTyping(topicID, did string) {
+	h.removeTyping(topicID, did, false)
+	h.forward(relayed{Op: opClearTyping, Topic: topicID, DID: did})
+}
+
+// expireTyping runs when a typing timer fires; a renewal racing with the
+// timer moves the deadline forward, so only clear if it has really passed
+func (h *Hub) expireTyping(topicID, did string) {
+	h.removeTyping(topicID, did, true)
+}
+
+func (h *Hub) removeTyping(topicID, did string, onlyExpired bool) {
+	h.typingMu.Lock()
+	entry, ok := h.typing[topicID][did]
+	if !ok || (onlyExpired && time.Now().Before(entry.expires)) {
+		h.typingMu.Unlock()
+		return
+	}
+	entry.timer.Stop()
+	delete(h.typing[topicID], did)
+	if len(h.typing[topicID]) == 0 {
+		delete(h.typing, topicID)
+	}
+	h.typingMu.Unlock()
+
+	h.broadcastTyping(topicID)
+}
+
+// Typing returns the DIDs currently typing in a topic
+func (h *Hub) Typing(topicID string) Typing {
+	h.typingMu.Lock()
+	defer h.typingMu.Unlock()
+
+	typing := Typing{TopicID: topicID, Typing: []string{}}
+	for did := range h.typing[topicID] {
+		typing.Typing = append(typing.Typing, did)
+	}
+	sort.Strings(typing.Typing)
+	return typing
+}
+
+func (h *Hub) broadcastTyping(topicID string) {
+	h.publish(topicID, Event{Type: EventTyping, Data: h.Typing(topicID)})
+}
+
+// Presence returns the current presence snapshot for a topic. Signed-in
+// viewers with several open connections are counted once; anonymous
+// connections are counted individually but never listed.
+func (h *Hub) Presence(topicID string) Presence {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.presenceLocked(topicID)
+}
+
+func (h *Hub) presenceLocked(topicID string) Presence {
+	presence := Presence{TopicID: topicID, Viewers: []string{}}
+	seen := make(map[string]struct{})
+	for sub := range h.topics[topicID] {
+		if sub.viewer.Hidden {
+			continue
+		}
+		if sub.viewer.DID == "" {
+			presence.Viewing++
+			continue
+		}
+		if _, ok := seen[sub.viewer.DID]; ok {
+			continue
+		}
+		seen[sub.viewer.DID] = struct{}{}
+		presence.Viewing++
+		presence.Viewers = append(presence.Viewers, sub.viewer.DID)
+	}
+	sort.Strings(presence.Viewers)
+	return presence
+}
+
+func (h *Hub) broadcastPresence(topicID string) {
+	h.mu.RLock()
+	presence := h.presenceLocked(topicID)
+	h.mu.RUnlock()
+
+	h.publish(topicID, Event{Type: EventPresence, Data: presence})
+}
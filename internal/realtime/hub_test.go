@@ -0,0 +1,326 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHubPresence(t *testing.T) {
+	tests := []struct {
+		name        string
+		viewers     []Viewer
+		wantViewing int
+		wantViewers []string
+	}{
+		{
+			name:        "no viewers",
+			wantViewing: 0,
+			wantViewers: []string{},
+		},
+		{
+			name:        "signed-in viewers are listed",
+			viewers:     []Viewer{{DID: "did:plc:bob"}, {DID: "did:plc:alice"}},
+			wantViewing: 2,
+			wantViewers: []string{"did:plc:alice", "did:plc:bob"},
+		},
+		{
+			name:        "multiple connections count once",
+			viewers:     []Viewer{{DID: "did:plc:alice"}, {DID: "did:plc:alice"}},
+			wantViewing: 1,
+			wantViewers: []string{"did:plc:alice"},
+		},
+		{
+			name:        "anonymous viewers are counted but not listed",
+			viewers:     []Viewer{{}, {}, {DID: "did:plc:alice"}},
+			wantViewing: 3,
+			wantViewers: []string{"did:plc:alice"},
+		},
+		{
+			name:        "hidden viewers are excluded",
+			viewers:     []Viewer{{DID: "did:plc:alice", Hidden: true}, {DID: "did:plc:bob"}},
+			wantViewing: 1,
+			wantViewers: []string{"did:plc:bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub()
+			for _, v := range tt.viewers {
+				hub.Subscribe("topic", v)
+			}
+			got := hub.Presence("topic")
+			if got.Viewing != tt.wantViewing {
+				t.Errorf("expected viewing %d, got %d", tt.wantViewing, got.Viewing)
+			}
+			if !reflect.DeepEqual(got.Viewers, tt.wantViewers) {
+				t.Errorf("expected viewers %v, got %v", tt.wantViewers, got.Viewers)
+			}
+		})
+	}
+}
+
+func TestHubBroadcastsPresenceOnJoinAndLeave(t *testing.T) {
+	hub := NewHub()
+	first := hub.Subscribe("topic", Viewer{DID: "did:plc:alice"})
+	assertPresence(t, first, 1)
+
+	second := hub.Subscribe("topic", Viewer{DID: "did:plc:bob"})
+	assertPresence(t, first, 2)
+	assertPresence(t, second, 2)
+
+	hub.Unsubscribe(second)
+	assertPresence(t, first, 1)
+
+	if _, ok := <-second.Events(); ok {
+		t.Error("expected unsubscribed channel to be closed")
+	}
+
+	// Unsubscribing twice must not panic
+	hub.Unsubscribe(second)
+}
+
+func TestHubPublishIsScopedToTopic(t *testing.T) {
+	hub := NewHub()
+	a := hub.Subscribe("a", Viewer{})
+	b := hub.Subscribe("b", Viewer{})
+	<-a.Events()
+	<-b.Events()
+
+	hub.Publish("a", Event{Type: "test", Data: "hello"})
+
+	select {
+	case ev := <-a.Events():
+		if ev.Type != "test" {
+			t.Errorf("expected test event, got %q", ev.Type)
+		}
+	default:
+		t.Fatal("expected event on topic a")
+	}
+	select {
+	case ev := <-b.Events():
+		t.Errorf("unexpected event on topic b: %+v", ev)
+	default:
+	}
+}
+
+func TestHubPublishDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe("topic", Viewer{DID: "did:plc:slow"})
+	fast := hub.Subscribe("topic", Viewer{DID: "did:plc:fast"})
+	drain(fast)
+
+	for i := 0; i < defaultBufferSize*2; i++ {
+		hub.Publish("topic", Event{Type: "test"})
+		drain(fast)
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != defaultBufferSize || !slow.Dropped() {
+		t.Errorf("expected slow subscriber to be dropped after %d events, got %d (dropped %v)", defaultBufferSize, received, slow.Dropped())
+	}
+	if fast.Dropped() {
+		t.Error("expected fast subscriber to stay connected")
+	}
+	if got := hub.Presence("topic"); got.Viewing != 1 {
+		t.Errorf("expected dropped subscriber to leave presence, got %+v", got)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := WriteEvent(rec, Event{Type: EventPresence, Data: Presence{TopicID: "t", Viewing: 1, Viewers: []string{}}})
+	if err != nil {
+		t.Fatalf("WriteEvent failed: %v", err)
+	}
+	want := "event: presence\ndata: {\"topic_id\":\"t\",\"viewing\":1,\"viewers\":[]}\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rec.Body.String())
+	}
+	if !rec.Flushed {
+		t.Error("expected response to be flushed")
+	}
+}
+
+func assertPresence(t *testing.T, sub *Subscriber, viewing int) {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		p, ok := ev.Data.(Presence)
+		if ev.Type != EventPresence || !ok {
+			t.Fatalf("expected presence event, got %+v", ev)
+		}
+		if p.Viewing != viewing {
+			t.Errorf("expected viewing %d, got %d", viewing, p.Viewing)
+		}
+	default:
+		t.Fatal("expected a presence event")
+	}
+}
+
+func TestHubPublishTo(t *testing.T) {
+	hub := NewHub()
+	alice := hub.Subscribe("topic", Viewer{DID: "did:plc:alice"})
+	bob := hub.Subscribe("topic", Viewer{DID: "did:plc:bob"})
+	drain(alice)
+	drain(bob)
+
+	hub.PublishTo("topic", "did:plc:alice", Event{Type: EventMessagePending})
+
+	if got := len(alice.Events()); got != 1 {
+		t.Errorf("expected 1 event for alice, got %d", got)
+	}
+	if got := len(bob.Events()); got != 0 {
+		t.Errorf("expected no events for bob, got %d", got)
+	}
+}
+
+func TestHubPublishUser(t *testing.T) {
+	hub := NewHub()
+	viewer := hub.Subscribe("topic", Viewer{DID: "did:plc:alice"})
+	drain(viewer)
+	alice := hub.SubscribeUser("did:plc:alice")
+	bob := hub.SubscribeUser("did:plc:bob")
+
+	if got := len(viewer.Events()); got != 0 {
+		t.Errorf("expected user subscription not to affect presence, got %d events", got)
+	}
+	if p := hub.Presence("topic"); p.Viewing != 1 {
+		t.Errorf("expected 1 viewer, got %d", p.Viewing)
+	}
+
+	hub.PublishUser("did:plc:alice", Event{Type: EventUnread})
+
+	if got := len(alice.Events()); got != 1 {
+		t.Errorf("expected 1 event for alice, got %d", got)
+	}
+	if got := len(bob.Events()); got != 0 {
+		t.Errorf("expected no events for bob, got %d", got)
+	}
+	if got := len(viewer.Events()); got != 0 {
+		t.Errorf("expected topic subscription to miss user events, got %d", got)
+	}
+
+	drain(alice)
+	hub.Unsubscribe(alice)
+	hub.Unsubscribe(alice)
+	if _, ok := <-alice.Events(); ok {
+		t.Error("expected channel to be closed after unsubscribe")
+	}
+	hub.PublishUser("did:plc:alice", Event{Type: EventUnread})
+}
+
+func TestHubTyping(t *testing.T) {
+	hub := NewHub()
+	hub.typingTTL = 20 * time.Millisecond
+	sub := hub.Subscribe("topic", Viewer{})
+	drain(sub)
+
+	hub.SetTyping("topic", "did:plc:alice")
+	assertTyping(t, sub, []string{"did:plc:alice"})
+
+	// Renewing an active signal does not rebroadcast
+	hub.SetTyping("topic", "did:plc:alice")
+	if got := len(sub.Events()); got != 0 {
+		t.Errorf("expected no event on renewal, got %d", got)
+	}
+
+	// The signal expires on its own
+	select {
+	case ev := <-sub.Events():
+		if ev.Type != EventTyping {
+			t.Fatalf("expected typing event, got %q", ev.Type)
+		}
+		if typing := ev.Data.(Typing); len(typing.Typing) != 0 {
+			t.Errorf("expected typing to expire, got %v", typing.Typing)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("typing signal did not expire")
+	}
+
+	hub.SetTyping("topic", "did:plc:bob")
+	assertTyping(t, sub, []string{"did:plc:bob"})
+	hub.ClearTyping("topic", "did:plc:bob")
+	assertTyping(t, sub, []string{})
+
+	// Clearing an unknown signal is a no-op
+	hub.ClearTyping("topic", "did:plc:bob")
+	if got := len(sub.Events()); got != 0 {
+		t.Errorf("expected no event for no-op clear, got %d", got)
+	}
+}
+
+func drain(sub *Subscriber) {
+	for len(sub.Events()) > 0 {
+		<-sub.Events()
+	}
+}
+
+func assertTyping(t *testing.T, sub *Subscriber, want []string) {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		typing, ok := ev.Data.(Typing)
+		if ev.Type != EventTyping || !ok {
+			t.Fatalf("expected typing event, got %+v", ev)
+		}
+		if !reflect.DeepEqual(typing.Typing, want) {
+			t.Errorf("expected typing %v, got %v", want, typing.Typing)
+		}
+	default:
+		t.Fatal("expected a typing event")
+	}
+}
+
+func BenchmarkHubPublish(b *testing.B) {
+	for _, subscribers := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			hub := NewHub()
+			var wg sync.WaitGroup
+			subs := make([]*Subscriber, 0, subscribers)
+			for i := 0; i < subscribers; i++ {
+				sub := hub.Subscribe("topic", Viewer{DID: fmt.Sprintf("did:plc:viewer%d", i)})
+				subs = append(subs, sub)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range sub.Events() {
+					}
+				}()
+			}
+			event := Event{Type: EventMessageConfirmed, Data: map[string]string{"content": "hello"}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hub.Publish("topic", event)
+			}
+			b.StopTimer()
+
+			for _, sub := range subs {
+				hub.Unsubscribe(sub)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestWriteEventTooLarge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := WriteEvent(rec, Event{Type: "test", Data: strings.Repeat("x", MaxEventSize)})
+	if !errors.Is(err, ErrEventTooLarge) {
+		t.Fatalf("expected ErrEventTooLarge, got %v", err)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jrschumacher/dis.quest/internal/broker"
+)
+
+// relayChannel is the broker channel hub operations travel on
+const relayChannel = "disquest.realtime"
+
+// Operations forwarded between hubs
+const (
+	opPublish     = "publish"
+	opPublishTo   = "publish_to"
+	opPublishUser = "publish_user"
+	opTyping      = "typing"
+	opClearTyping = "typing_clear"
+)
+
+// relayed is a hub call forwarded to the hubs on other instances
+type relayed struct {
+	Op    string `json:"op"`
+	Topic string `json:"topic,omitempty"`
+	DID   string `json:"did,omitempty"`
+	Event Event  `json:"event"`
+}
+
+type hubRelay struct {
+	relay   *broker.Relay
+	onError func(error)
+}
+
+// Relay joins the hub to the hubs of other instances through b until ctx is
+// done: events published here are delivered to their subscribers too, and
+// typing signals are shared. Presence is not; each instance reports the
+// viewers connected to it. Errors, including events dropped because the
+// broker is not keeping up, are passed to onError.
+func (h *Hub) Relay(ctx context.Context, b broker.Broker, onError func(error)) {
+	relay := broker.NewRelay(b, relayChannel)
+	h.relay.Store(&hubRelay{relay: relay, onError: onError})
+	defer h.relay.Store(nil)
+
+	relay.Run(ctx, func(payload []byte) {
+		if err := h.receive(payload); err != nil {
+			onError(err)
+		}
+	}, onError)
+}
+
+// forward sends a hub call to the other instances, if relaying
+func (h *Hub) forward(call relayed) {
+	r := h.relay.Load()
+	if r == nil {
+		return
+	}
+	payload, err := json.Marshal(call)
+	if err != nil {
+		r.onError(fmt.Errorf("failed to relay %s event: %w", call.Event.Type, err))
+		return
+	}
+	if !r.relay.Send(payload) {
+		r.onError(broker.ErrOutboxFull)
+	}
+}
+
+// receive applies a call forwarded by another instance to local
+// subscribers only
+func (h *Hub) receive(payload []byte) error {
+	var call struct {
+		relayed
+		Event struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &call); err != nil {
+		return fmt.Errorf("malformed relayed event: %w", err)
+	}
+	event := Event{Type: call.Event.Type, Data: call.Event.Data}
+
+	switch call.Op {
+	case opPublish:
+		h.publish(call.Topic, event)
+	case opPublishTo:
+		h.publishTo(call.Topic, call.DID, event)
+	case opPublishUser:
+		h.publishUser(call.DID, event)
+	case opTyping:
+		h.setTyping(call.Topic, call.DID)
+	case opClearTyping:
+		h.removeTyping(call.Topic, call.DID, false)
+	default:
+		return fmt.Errorf("unknown relayed operation %q", call.Op)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/broker"
+)
+
+func TestHubRelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	memory := broker.NewMemory()
+	local, remote := NewHub(), NewHub()
+	for _, hub := range []*Hub{local, remote} {
+		go hub.Relay(ctx, memory, func(err error) { t.Errorf("relay: %v", err) })
+	}
+
+	sub := remote.Subscribe("topic", Viewer{DID: "did:plc:viewer"})
+	defer remote.Unsubscribe(sub)
+	<-sub.Events() // initial presence
+
+	next := func(want string) Event {
+		t.Helper()
+		for {
+			select {
+			case event := <-sub.Events():
+				if event.Type == want {
+					return event
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for a %s event", want)
+			}
+		}
+	}
+
+	// The relays subscribe in the background, so publish until one arrives
+	deadline := time.Now().Add(5 * time.Second)
+	var confirmed Event
+	for received := false; !received; {
+		local.Publish("topic", Event{Type: EventMessageConfirmed, Data: map[string]string{"rkey": "m1"}})
+		select {
+		case confirmed = <-sub.Events():
+			received = true
+		case <-time.After(20 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for a relayed event")
+			}
+		}
+	}
+	data, _ := json.Marshal(confirmed.Data)
+	if confirmed.Type != EventMessageConfirmed || string(data) != `{"rkey":"m1"}` {
+		t.Errorf("relayed event = %s %s", confirmed.Type, data)
+	}
+
+	local.SetTyping("topic", "did:plc:typist")
+	typing := next(EventTyping)
+	data, _ = json.Marshal(typing.Data)
+	if string(data) != `{"topic_id":"topic","typing":["did:plc:typist"]}` {
+		t.Errorf("relayed typing = %s", data)
+	}
+	local.ClearTyping("topic", "did:plc:typist")
+	next(EventTyping)
+	if got := remote.Typing("topic").Typing; len(got) != 0 {
+		t.Errorf("expected the cleared typing signal to be relayed, still typing: %v", got)
+	}
+
+	// Presence stays per instance
+	other := local.Subscribe("topic", Viewer{DID: "did:plc:other"})
+	defer local.Unsubscribe(other)
+	if got := remote.Presence("topic"); got.Viewing != 1 {
+		t.Errorf("expected remote presence to count only its own viewers, got %+v", got)
+	}
+}
@@ -0,0 +1,113 @@
+package realtime
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTooManyStreams is returned by Streams.Acquire when a limit is reached
+var ErrTooManyStreams = errors.New("too many concurrent event streams")
+
+// Limits bound concurrent event streams. Zero means unlimited.
+type Limits struct {
+	// MaxStreams caps open streams across all clients
+	MaxStreams int
+	// MaxStreamsPerClient caps open streams per client key (a DID, or a
+	// remote address for anonymous viewers)
+	MaxStreamsPerClient int
+}
+
+// StreamStats is a snapshot of stream activity since startup
+type StreamStats struct {
+	Active   int    `json:"active"`
+	Clients  int    `json:"clients"`
+	Opened   uint64 `json:"opened"`
+	Rejected uint64 `json:"rejected"`
+	// Dropped counts streams closed because the client fell behind
+	Dropped uint64 `json:"dropped"`
+	// Oversized counts events skipped for exceeding MaxEventSize
+	Oversized uint64 `json:"oversized"`
+}
+
+// Streams is a registry of open event streams that enforces Limits and
+// keeps counters for monitoring
+type Streams struct {
+	mu      sync.Mutex
+	limits  Limits
+	active  int
+	clients map[string]int
+
+	opened    atomic.Uint64
+	rejected  atomic.Uint64
+	dropped   atomic.Uint64
+	oversized atomic.Uint64
+}
+
+// NewStreams creates a registry enforcing limits
+func NewStreams(limits Limits) *Streams {
+	return &Streams{limits: limits, clients: make(map[string]int)}
+}
+
+// SetLimits changes the limits for streams opened from now on. Streams
+// already open stay open even if they exceed the new limits.
+func (s *Streams) SetLimits(limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+}
+
+// Acquire registers a stream for client, or returns ErrTooManyStreams when
+// either limit is reached. The returned release must be called once the
+// stream closes; calling it more than once has no further effect.
+func (s *Streams) Acquire(client string) (release func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if (s.limits.MaxStreams > 0 && s.active >= s.limits.MaxStreams) ||
+		(s.limits.MaxStreamsPerClient > 0 && s.clients[client] >= s.limits.MaxStreamsPerClient) {
+		s.rejected.Add(1)
+		return nil, ErrTooManyStreams
+	}
+	s.active++
+	s.clients[client]++
+	s.opened.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.active--
+			if s.clients[client]--; s.clients[client] <= 0 {
+				delete(s.clients, client)
+			}
+		})
+	}, nil
+}
+
+// RecordDropped counts a stream closed for falling behind
+func (s *Streams) RecordDropped() {
+	s.dropped.Add(1)
+}
+
+// RecordOversized counts an event skipped for being too large
+func (s *Streams) RecordOversized() {
+	s.oversized.Add(1)
+}
+
+// Stats returns the current counters
+func (s *Streams) Stats() StreamStats {
+	s.mu.Lock()
+	active, clients := s.active, len(s.clients)
+	s.mu.Unlock()
+
+	return StreamStats{
+		Active:    active,
+		Clients:   clients,
+		Opened:    s.opened.Load(),
+		Rejected:  s.rejected.Load(),
+		Dropped:   s.dropped.Load(),
+		Oversized: s.oversized.Load(),
+	}
+}
@@ -0,0 +1,73 @@
+package realtime
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStreamsLimits(t *testing.T) {
+	streams := NewStreams(Limits{MaxStreams: 3, MaxStreamsPerClient: 2})
+
+	releaseA1, err := streams.Acquire("did:plc:alice")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := streams.Acquire("did:plc:alice"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := streams.Acquire("did:plc:alice"); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("expected per-client limit, got %v", err)
+	}
+	if _, err := streams.Acquire("did:plc:bob"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := streams.Acquire("did:plc:carol"); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("expected global limit, got %v", err)
+	}
+
+	releaseA1()
+	releaseA1()
+	if _, err := streams.Acquire("did:plc:carol"); err != nil {
+		t.Errorf("expected released slot to be reusable, got %v", err)
+	}
+
+	streams.RecordDropped()
+	got := streams.Stats()
+	want := StreamStats{Active: 3, Clients: 3, Opened: 4, Rejected: 2, Dropped: 1}
+	if got != want {
+		t.Errorf("expected stats %+v, got %+v", want, got)
+	}
+}
+
+func TestStreamsUnlimited(t *testing.T) {
+	streams := NewStreams(Limits{})
+	for i := 0; i < 100; i++ {
+		if _, err := streams.Acquire("did:plc:alice"); err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+	}
+}
+
+func TestStreamsSetLimits(t *testing.T) {
+	streams := NewStreams(Limits{MaxStreamsPerClient: 1})
+	if _, err := streams.Acquire("did:plc:alice"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := streams.Acquire("did:plc:alice"); !errors.Is(err, ErrTooManyStreams) {
+		t.Fatalf("expected per-client limit, got %v", err)
+	}
+
+	streams.SetLimits(Limits{MaxStreamsPerClient: 2})
+	if _, err := streams.Acquire("did:plc:alice"); err != nil {
+		t.Errorf("expected the raised limit to apply, got %v", err)
+	}
+
+	// Lowering a limit leaves open streams alone but refuses new ones
+	streams.SetLimits(Limits{MaxStreamsPerClient: 1})
+	if _, err := streams.Acquire("did:plc:alice"); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("expected the lowered limit to apply, got %v", err)
+	}
+	if got := streams.Stats().Active; got != 2 {
+		t.Errorf("Active = %d, want 2", got)
+	}
+}
@@ -0,0 +1,237 @@
+// Package related suggests topics similar to a given one. Similarity blends
+// how many tags two topics share with the TF-IDF cosine similarity of their
+// titles and summaries. Suggestions for every topic are computed together
+// in the background and cached, so looking them up is a map read.
+package related
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// tagWeight is the share of a score that comes from tag overlap; the rest
+// comes from text similarity
+const tagWeight = 0.3
+
+// stopWords are common words too short of meaning to relate topics
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "can": true, "has": true, "have": true, "how": true,
+	"this": true, "that": true, "with": true, "what": true, "when": true, "why": true,
+	"from": true, "there": true, "their": true, "about": true, "which": true, "would": true,
+	"does": true, "into": true, "any": true, "was": true, "will": true, "your": true,
+}
+
+// Document is a topic as the similarity model sees it
+type Document struct {
+	ID      string
+	Title   string
+	Summary string
+	Tags    []string
+}
+
+// Suggestion is a topic related to another, with a score between 0 and 1
+type Suggestion struct {
+	ID    string  `json:"topic_id"`
+	Title string  `json:"subject"`
+	Score float64 `json:"score"`
+}
+
+// terms splits text into lowercase words, dropping stop words and words
+// shorter than three characters
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !stopWords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// tfidf returns each document's unit-length TF-IDF vector
+func tfidf(docs []Document) []map[string]float64 {
+	counts := make([]map[string]float64, len(docs))
+	df := make(map[string]int)
+	for i, doc := range docs {
+		counts[i] = make(map[string]float64)
+		for _, term := range terms(doc.Title + " " + doc.Summary) {
+			if counts[i][term] == 0 {
+				df[term]++
+			}
+			counts[i][term]++
+		}
+	}
+
+	n := float64(len(docs))
+	for _, vector := range counts {
+		var norm float64
+		for term, tf := range vector {
+			// Smoothed so a term in every document still carries some weight
+			weight := tf * (math.Log((1+n)/(1+float64(df[term]))) + 1)
+			vector[term] = weight
+			norm += weight * weight
+		}
+		norm = math.Sqrt(norm)
+		for term := range vector {
+			vector[term] /= norm
+		}
+	}
+	return counts
+}
+
+// Compute returns up to limit suggestions for every document, best first.
+// Documents sharing no tags or terms are never suggested for each other.
+func Compute(docs []Document, limit int) map[string][]Suggestion {
+	vectors := tfidf(docs)
+
+	// Inverted indexes mean each document is only compared with the
+	// documents it has something in common with
+	postings := make(map[string][]int)
+	for i, vector := range vectors {
+		for term := range vector {
+			postings[term] = append(postings[term], i)
+		}
+	}
+	tagged := make(map[string][]int)
+	tagSets := make([][]string, len(docs))
+	for i, doc := range docs {
+		for _, tag := range doc.Tags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" && !slices.Contains(tagSets[i], tag) {
+				tagSets[i] = append(tagSets[i], tag)
+				tagged[tag] = append(tagged[tag], i)
+			}
+		}
+	}
+
+	result := make(map[string][]Suggestion, len(docs))
+	for i, doc := range docs {
+		text := make(map[int]float64)
+		for term, weight := range vectors[i] {
+			for _, j := range postings[term] {
+				if j != i {
+					text[j] += weight * vectors[j][term]
+				}
+			}
+		}
+		shared := make(map[int]int)
+		for _, tag := range tagSets[i] {
+			for _, j := range tagged[tag] {
+				if j != i {
+					shared[j]++
+				}
+			}
+		}
+
+		candidates := make(map[int]bool, len(text)+len(shared))
+		for j := range text {
+			candidates[j] = true
+		}
+		for j := range shared {
+			candidates[j] = true
+		}
+		suggestions := make([]Suggestion, 0, len(candidates))
+		for j := range candidates {
+			var overlap float64
+			if shared[j] > 0 {
+				// Jaccard similarity of the two tag sets
+				overlap = float64(shared[j]) / float64(len(tagSets[i])+len(tagSets[j])-shared[j])
+			}
+			score := tagWeight*overlap + (1-tagWeight)*min(text[j], 1)
+			if score > 0 {
+				suggestions = append(suggestions, Suggestion{ID: docs[j].ID, Title: docs[j].Title, Score: score})
+			}
+		}
+		slices.SortFunc(suggestions, func(a, b Suggestion) int {
+			if a.Score != b.Score {
+				if a.Score > b.Score {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+		result[doc.ID] = suggestions[:min(limit, len(suggestions))]
+	}
+	return result
+}
+
+// Source lists the documents to relate
+type Source interface {
+	Documents(ctx context.Context) ([]Document, error)
+}
+
+// Index holds the suggestions computed from a source
+type Index struct {
+	source Source
+	limit  int
+
+	mu          sync.RWMutex
+	suggestions map[string][]Suggestion
+}
+
+// NewIndex creates an index keeping up to limit suggestions per document.
+// It is empty until the first Rebuild.
+func NewIndex(source Source, limit int) *Index {
+	return &Index{
+		source:      source,
+		limit:       limit,
+		suggestions: make(map[string][]Suggestion),
+	}
+}
+
+// Related returns the suggestions for the document with the given ID, best
+// first. Documents the index has not seen have none.
+func (x *Index) Related(id string) []Suggestion {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return slices.Clone(x.suggestions[id])
+}
+
+// Rebuild recomputes every document's suggestions from the source. If the
+// source fails the previous suggestions are kept.
+func (x *Index) Rebuild(ctx context.Context) error {
+	docs, err := x.source.Documents(ctx)
+	if err != nil {
+		return err
+	}
+	suggestions := Compute(docs, x.limit)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.suggestions = suggestions
+	return nil
+}
+
+// Run rebuilds immediately and then every interval until ctx is done.
+// Rebuild errors are passed to onError.
+func (x *Index) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	rebuild := func() {
+		if err := x.Rebuild(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			onError(err)
+		}
+	}
+	rebuild()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rebuild()
+		}
+	}
+}
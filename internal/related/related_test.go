@@ -0,0 +1,74 @@
+package related
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var corpus = []Document{
+	{ID: "a", Title: "Deploying Go services to Kubernetes", Summary: "Helm charts for Go microservices", Tags: []string{"go"}},
+	{ID: "b", Title: "Go services on Kubernetes", Summary: "How do you roll out Go microservices?", Tags: []string{"Go", "ops"}},
+	{ID: "c", Title: "Sourdough starter tips", Summary: "My starter will not rise", Tags: []string{"baking"}},
+	{ID: "d", Title: "Error handling patterns", Summary: "Wrapping errors", Tags: []string{"go"}},
+}
+
+func TestTerms(t *testing.T) {
+	got := terms("How do I use Go's HTTP/2 client, and why?")
+	want := []string{"use", "http", "client"}
+	if !slices.Equal(got, want) {
+		t.Errorf("terms() = %v, want %v", got, want)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	got := Compute(corpus, 2)
+
+	if a := got["a"]; len(a) != 2 || a[0].ID != "b" || a[1].ID != "d" {
+		t.Fatalf("suggestions for a = %+v, want b then d", a)
+	}
+	if a := got["a"]; a[0].Score <= a[1].Score || a[0].Score > 1 || a[0].Title != corpus[1].Title {
+		t.Errorf("expected text and tag overlap to outscore tag overlap alone, got %+v", a)
+	}
+	// d shares only its tag, fully with a and half of b's tag set
+	if d := got["d"]; len(d) != 2 || d[0].ID != "a" || d[0].Score != tagWeight || d[1].Score != tagWeight/2 {
+		t.Errorf("expected tag-only suggestions for d scored by overlap, got %+v", d)
+	}
+	if c := got["c"]; len(c) != 0 {
+		t.Errorf("expected nothing related to c, got %+v", c)
+	}
+}
+
+type fakeSource struct {
+	docs []Document
+	err  error
+}
+
+func (s *fakeSource) Documents(context.Context) ([]Document, error) {
+	return s.docs, s.err
+}
+
+func TestIndex_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{docs: corpus}
+	index := NewIndex(source, 3)
+
+	if got := index.Related("a"); len(got) != 0 {
+		t.Fatalf("expected no suggestions before the first rebuild, got %+v", got)
+	}
+	if err := index.Rebuild(ctx); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if got := index.Related("a"); len(got) != 2 {
+		t.Fatalf("Related(a) = %+v, want b and d", got)
+	}
+
+	source.err = errors.New("database down")
+	if err := index.Rebuild(ctx); !errors.Is(err, source.err) {
+		t.Fatalf("Rebuild error = %v, want %v", err, source.err)
+	}
+	if got := index.Related("a"); len(got) != 2 {
+		t.Errorf("expected suggestions to survive a failed rebuild, got %+v", got)
+	}
+}
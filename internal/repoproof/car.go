@@ -0,0 +1,58 @@
+package repoproof
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+)
+
+// readCAR reads a version 1 CAR file, returning its root and its blocks by
+// CID. Every block is checked against its CID.
+func readCAR(data []byte) (dagcbor.CID, map[dagcbor.CID][]byte, error) {
+	header, rest, err := readSection(data)
+	if err != nil {
+		return "", nil, err
+	}
+	decoded, err := dagcbor.Decode(header)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: CAR header: %w", ErrUnverified, err)
+	}
+	h, _ := decoded.(map[string]any)
+	roots, _ := h["roots"].([]any)
+	if h["version"] != int64(1) || len(roots) == 0 {
+		return "", nil, fmt.Errorf("%w: unsupported CAR header", ErrUnverified)
+	}
+	root, ok := roots[0].(dagcbor.CID)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: CAR root is not a CID", ErrUnverified)
+	}
+
+	blocks := map[dagcbor.CID][]byte{}
+	for len(rest) > 0 {
+		var section []byte
+		if section, rest, err = readSection(rest); err != nil {
+			return "", nil, err
+		}
+		cid, n, err := dagcbor.ReadCID(section)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: CAR block: %w", ErrUnverified, err)
+		}
+		block := section[n:]
+		if !cid.Matches(block) {
+			return "", nil, fmt.Errorf("%w: block %s does not match its CID", ErrUnverified, cid)
+		}
+		blocks[cid] = block
+	}
+	return root, blocks, nil
+}
+
+// readSection splits off the length-prefixed section data starts with
+func readSection(data []byte) ([]byte, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return nil, nil, fmt.Errorf("%w: truncated CAR file", ErrUnverified)
+	}
+	end := n + int(size) // #nosec G115 -- size is bounded by len(data)
+	return data[n:end], data[end:], nil
+}
@@ -0,0 +1,29 @@
+package repoproof
+
+import (
+	"crypto"
+
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+// PublicKey is a repository signing key: P-256 or secp256k1, the two curves
+// atproto allows
+type PublicKey struct {
+	key crypto.PublicKey
+}
+
+// ParsePublicKey parses a multibase multikey, as DID documents publish in
+// publicKeyMultibase
+func ParsePublicKey(multibase string) (PublicKey, error) {
+	key, err := multikey.Parse(multibase)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	return PublicKey{key: key}, nil
+}
+
+// verify reports whether sig, 64 bytes of r and s, signs digest in the
+// low-S form atproto requires
+func (k PublicKey) verify(digest, sig []byte) bool {
+	return multikey.Verify(k.key, digest, sig)
+}
@@ -0,0 +1,68 @@
+package repoproof
+
+import (
+	"fmt"
+
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+)
+
+// lookup finds key in the Merkle search tree rooted at root, returning the
+// CID of its record. A record that is not in the tree is reported as
+// missing only if the blocks prove it; a node the path needs but blocks
+// lack is an error.
+func lookup(blocks map[dagcbor.CID][]byte, root dagcbor.CID, key string) (dagcbor.CID, bool, error) {
+	node := root
+	for {
+		data, ok := blocks[node]
+		if !ok {
+			return "", false, fmt.Errorf("%w: proof lacks MST node %s", ErrUnverified, node)
+		}
+		decoded, err := dagcbor.Decode(data)
+		if err != nil {
+			return "", false, fmt.Errorf("%w: MST node: %w", ErrUnverified, err)
+		}
+		n, _ := decoded.(map[string]any)
+		entries, ok := n["e"].([]any)
+		if !ok {
+			return "", false, fmt.Errorf("%w: MST node without entries", ErrUnverified)
+		}
+
+		// Each entry's subtree t holds the keys between it and the next
+		// entry; l holds those before the first
+		next := n["l"]
+		prev := ""
+		for _, item := range entries {
+			e, _ := item.(map[string]any)
+			prefix, _ := e["p"].(int64)
+			suffix, ok := e["k"].([]byte)
+			if !ok || prefix < 0 || prefix > int64(len(prev)) {
+				return "", false, fmt.Errorf("%w: malformed MST entry", ErrUnverified)
+			}
+			entryKey := prev[:prefix] + string(suffix)
+			if entryKey <= prev && prev != "" {
+				return "", false, fmt.Errorf("%w: MST entries out of order", ErrUnverified)
+			}
+			if key == entryKey {
+				value, ok := e["v"].(dagcbor.CID)
+				if !ok {
+					return "", false, fmt.Errorf("%w: malformed MST entry", ErrUnverified)
+				}
+				return value, true, nil
+			}
+			if key < entryKey {
+				break
+			}
+			next = e["t"]
+			prev = entryKey
+		}
+
+		switch subtree := next.(type) {
+		case nil:
+			return "", false, nil
+		case dagcbor.CID:
+			node = subtree
+		default:
+			return "", false, fmt.Errorf("%w: malformed MST subtree link", ErrUnverified)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package repoproof verifies records against the signed repositories of
+// their authors. A PDS serves each record with a proof: a CAR file holding
+// the repository's current commit, signed with the key in the author's DID
+// document, and the Merkle search tree nodes leading from the commit to the
+// record. A record that checks out is exactly what its author published,
+// whoever relayed it.
+package repoproof
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// identityTTL is how long a resolved PDS and signing key are trusted before
+// the DID document is fetched again
+const identityTTL = 10 * time.Minute
+
+// ErrUnverified is returned when a proof does not show the record was
+// signed into its author's repository
+var ErrUnverified = errors.New("record could not be verified")
+
+// Record is a record proven to be in its author's repository
+type Record struct {
+	// CID is the record's CID, in base32
+	CID string
+	// Value is the record in JSON
+	Value json.RawMessage
+}
+
+// Verify checks that car, a proof as com.atproto.sync.getRecord serves it,
+// holds a commit of did's repository signed by key, and returns the record
+// at collection/rkey it proves
+func Verify(car []byte, did, collection, rkey string, key PublicKey) (Record, error) {
+	root, blocks, err := readCAR(car)
+	if err != nil {
+		return Record{}, err
+	}
+	data, ok := blocks[root]
+	if !ok {
+		return Record{}, fmt.Errorf("%w: proof lacks its commit", ErrUnverified)
+	}
+	decoded, err := dagcbor.Decode(data)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: commit: %w", ErrUnverified, err)
+	}
+	commit, _ := decoded.(map[string]any)
+	version, _ := commit["version"].(int64)
+	if version != 2 && version != 3 {
+		return Record{}, fmt.Errorf("%w: unsupported commit version %d", ErrUnverified, version)
+	}
+	if commit["did"] != did {
+		return Record{}, fmt.Errorf("%w: commit is for %v, not %s", ErrUnverified, commit["did"], did)
+	}
+
+	// The signature covers the commit without its sig field
+	sig, _ := commit["sig"].([]byte)
+	unsigned := make(map[string]any, len(commit))
+	for k, v := range commit {
+		if k != "sig" {
+			unsigned[k] = v
+		}
+	}
+	digest := sha256.Sum256(dagcbor.Append(nil, unsigned))
+	if !key.verify(digest[:], sig) {
+		return Record{}, fmt.Errorf("%w: bad commit signature", ErrUnverified)
+	}
+
+	tree, ok := commit["data"].(dagcbor.CID)
+	if !ok {
+		return Record{}, fmt.Errorf("%w: commit without data", ErrUnverified)
+	}
+	cid, found, err := lookup(blocks, tree, collection+"/"+rkey)
+	if err != nil {
+		return Record{}, err
+	}
+	if !found {
+		return Record{}, fmt.Errorf("%w: record is not in the repository", ErrUnverified)
+	}
+	block, ok := blocks[cid]
+	if !ok || cid.Codec() != dagcbor.CodecDAGCBOR {
+		return Record{}, fmt.Errorf("%w: proof lacks the record", ErrUnverified)
+	}
+	value, err := dagcbor.Decode(block)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: record: %w", ErrUnverified, err)
+	}
+	encoded, err := json.Marshal(dagcbor.JSON(value))
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{CID: cid.String(), Value: encoded}, nil
+}
+
+// Verifier fetches and checks the proofs of records from their authors'
+// PDSes, found through their DID documents
+type Verifier struct {
+	resolver   *auth.IdentityResolver
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu         sync.Mutex
+	identities map[string]identity
+}
+
+// identity is what a DID document says about verifying its repository
+type identity struct {
+	pds      string
+	key      PublicKey
+	resolved time.Time
+}
+
+// NewVerifier creates a verifier resolving DIDs with resolver and fetching
+// proofs with httpClient, or http.DefaultClient when it is nil
+func NewVerifier(resolver *auth.IdentityResolver, httpClient *http.Client) *Verifier {
+	return &Verifier{
+		resolver:   resolver,
+		httpClient: httpClient,
+		now:        time.Now,
+		identities: map[string]identity{},
+	}
+}
+
+// Verify fetches the proof of the record at collection/rkey in did's
+// repository and returns the record it proves. When the proof does not
+// check out against a cached signing key, the DID document is fetched
+// again in case the key has rotated.
+func (v *Verifier) Verify(ctx context.Context, did, collection, rkey string) (Record, error) {
+	id, cached, err := v.identity(ctx, did, false)
+	if err != nil {
+		return Record{}, err
+	}
+	record, err := v.verify(ctx, id, did, collection, rkey)
+	if cached && errors.Is(err, ErrUnverified) {
+		if id, _, err = v.identity(ctx, did, true); err != nil {
+			return Record{}, err
+		}
+		record, err = v.verify(ctx, id, did, collection, rkey)
+	}
+	return record, err
+}
+
+func (v *Verifier) verify(ctx context.Context, id identity, did, collection, rkey string) (Record, error) {
+	car, err := pds.NewClient(id.pds, pds.Anonymous{}, v.httpClient).GetRecordProof(ctx, did, collection, rkey)
+	if err != nil {
+		return Record{}, err
+	}
+	return Verify(car, did, collection, rkey, id.key)
+}
+
+// identity returns did's PDS and signing key, reporting whether they came
+// from the cache. refresh skips the cache.
+func (v *Verifier) identity(ctx context.Context, did string, refresh bool) (identity, bool, error) {
+	v.mu.Lock()
+	id, ok := v.identities[did]
+	v.mu.Unlock()
+	if ok && !refresh && v.now().Sub(id.resolved) < identityTTL {
+		return id, true, nil
+	}
+
+	doc, err := v.resolver.ResolveDID(ctx, did)
+	if err != nil {
+		return identity{}, false, err
+	}
+	id = identity{pds: doc.PDSEndpoint(), resolved: v.now()}
+	if id.pds == "" {
+		return identity{}, false, fmt.Errorf("%w: DID document of %s lists no PDS", ErrUnverified, did)
+	}
+	if id.key, err = ParsePublicKey(doc.SigningKey()); err != nil {
+		return identity{}, false, fmt.Errorf("%w: signing key of %s: %w", ErrUnverified, did, err)
+	}
+	v.mu.Lock()
+	v.identities[did] = id
+	v.mu.Unlock()
+	return id, false, nil
+}
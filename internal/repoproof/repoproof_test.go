@@ -0,0 +1,283 @@
+package repoproof
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	k256ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+	"github.com/jrschumacher/dis.quest/internal/multikey"
+)
+
+const did = "did:plc:alice"
+
+// signer is a repository signing key as tests hold it
+type signer struct {
+	multikey string
+	sign     func(digest []byte) []byte
+}
+
+func p256Signer(t *testing.T) signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer{
+		multikey: multikey.Encode(multikey.P256, elliptic.MarshalCompressed(elliptic.P256(), key.X, key.Y)),
+		sign: func(digest []byte) []byte {
+			r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := elliptic.P256().Params().N
+			if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+				s.Sub(n, s)
+			}
+			return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		},
+	}
+}
+
+func k256Signer(t *testing.T) signer {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer{
+		multikey: multikey.Encode(multikey.K256, key.PubKey().SerializeCompressed()),
+		sign: func(digest []byte) []byte {
+			sig := k256ecdsa.Sign(key, digest)
+			r, s := sig.R(), sig.S()
+			var out [64]byte
+			r.PutBytesUnchecked(out[:32])
+			s.PutBytesUnchecked(out[32:])
+			return out[:]
+		},
+	}
+}
+
+// car writes a CAR file of root and blocks
+func car(root dagcbor.CID, blocks [][]byte) []byte {
+	header := dagcbor.Append(nil, map[string]any{"version": int64(1), "roots": []any{root}})
+	out := append(binary.AppendUvarint(nil, uint64(len(header))), header...)
+	for _, block := range blocks {
+		cid := dagcbor.Sum(dagcbor.CodecDAGCBOR, block)
+		out = binary.AppendUvarint(out, uint64(len(cid)+len(block)))
+		out = append(append(out, cid...), block...)
+	}
+	return out
+}
+
+// repo builds the proof of a repository holding records, keyed by
+// collection/rkey, in a two-level tree so lookups cross subtrees
+type repo struct {
+	did     string
+	records map[string]map[string]any
+	// mutate may change a block before the CAR is written
+	mutate func(blocks [][]byte)
+}
+
+func (r repo) proof(s signer) []byte {
+	keys := make([]string, 0, len(r.records))
+	var blocks [][]byte
+	values := map[string]dagcbor.CID{}
+	for key, record := range r.records {
+		keys = append(keys, key)
+		block := dagcbor.Append(nil, record)
+		blocks = append(blocks, block)
+		values[key] = dagcbor.Sum(dagcbor.CodecDAGCBOR, block)
+	}
+	slices.Sort(keys)
+
+	node := func(left any, keys []string, right any) dagcbor.CID {
+		entries := []any{}
+		prev := ""
+		for i, key := range keys {
+			p := 0
+			for p < len(prev) && p < len(key) && prev[p] == key[p] {
+				p++
+			}
+			var t any
+			if i == len(keys)-1 {
+				t = right
+			}
+			entries = append(entries, map[string]any{"p": int64(p), "k": []byte(key[p:]), "v": values[key], "t": t})
+			prev = key
+		}
+		block := dagcbor.Append(nil, map[string]any{"l": left, "e": entries})
+		blocks = append(blocks, block)
+		return dagcbor.Sum(dagcbor.CodecDAGCBOR, block)
+	}
+	mid := len(keys) / 2
+	var left, right any
+	if mid > 0 {
+		left = node(nil, keys[:mid], nil)
+	}
+	if mid+1 < len(keys) {
+		right = node(nil, keys[mid+1:], nil)
+	}
+	data := node(left, keys[mid:mid+1], right)
+
+	commit := map[string]any{"did": r.did, "version": int64(3), "data": data, "rev": "3l6x3ckqz2k2a", "prev": nil}
+	digest := sha256.Sum256(dagcbor.Append(nil, commit))
+	commit["sig"] = s.sign(digest[:])
+	block := dagcbor.Append(nil, commit)
+	blocks = append(blocks, block)
+	if r.mutate != nil {
+		r.mutate(blocks)
+	}
+	return car(dagcbor.Sum(dagcbor.CodecDAGCBOR, block), blocks)
+}
+
+func topic(title string) map[string]any {
+	return map[string]any{"$type": "quest.dis.topic", "title": title, "createdAt": "2025-01-02T03:04:05Z"}
+}
+
+func testRepo() repo {
+	return repo{did: did, records: map[string]map[string]any{
+		"quest.dis.message/m1": {"$type": "quest.dis.message", "content": "Use constraints"},
+		"quest.dis.topic/t1":   topic("Generics"),
+		"quest.dis.topic/t2":   topic("Sourdough"),
+		"quest.dis.topic/t3":   topic("Iterators"),
+	}}
+}
+
+func TestVerify(t *testing.T) {
+	for name, s := range map[string]signer{"P-256": p256Signer(t), "secp256k1": k256Signer(t)} {
+		t.Run(name, func(t *testing.T) {
+			key, err := ParsePublicKey(s.multikey)
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			proof := testRepo().proof(s)
+			for rkey, title := range map[string]string{"t1": "Generics", "t2": "Sourdough", "t3": "Iterators"} {
+				record, err := Verify(proof, did, "quest.dis.topic", rkey, key)
+				if err != nil {
+					t.Fatalf("Verify %s: %v", rkey, err)
+				}
+				var value map[string]any
+				if err := json.Unmarshal(record.Value, &value); err != nil || value["title"] != title {
+					t.Errorf("Verify %s = %s (%v)", rkey, record.Value, err)
+				}
+				if !strings.HasPrefix(record.CID, "bafyrei") {
+					t.Errorf("CID = %q, want a DAG-CBOR CID", record.CID)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	s := p256Signer(t)
+	key, _ := ParsePublicKey(s.multikey)
+	other, _ := ParsePublicKey(p256Signer(t).multikey)
+	tampered := testRepo()
+	tampered.mutate = func(blocks [][]byte) {
+		// Swap the title of a record without updating its CID
+		for i, block := range blocks {
+			if j := strings.Index(string(block), "Generics"); j >= 0 {
+				blocks[i] = append(slices.Clone(block[:j]), strings.Replace(string(block[j:]), "Generics", "Genetics", 1)...)
+			}
+		}
+	}
+	forged := testRepo()
+	forged.did = "did:plc:mallory"
+
+	tests := []struct {
+		name  string
+		proof []byte
+		rkey  string
+		key   PublicKey
+	}{
+		{"tampered record", tampered.proof(s), "t1", key},
+		{"another key", testRepo().proof(s), "t1", other},
+		{"another repository", forged.proof(s), "t1", key},
+		{"missing record", testRepo().proof(s), "t9", key},
+		{"truncated", testRepo().proof(s)[:40], "t1", key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.proof, did, "quest.dis.topic", tt.rkey, tt.key); !errors.Is(err, ErrUnverified) {
+				t.Errorf("Verify error = %v, want ErrUnverified", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsHighS(t *testing.T) {
+	s := p256Signer(t)
+	key, _ := ParsePublicKey(s.multikey)
+	// Flipping s to n-s keeps the signature valid ECDSA but malleable
+	highS := s
+	highS.sign = func(digest []byte) []byte {
+		sig := s.sign(digest)
+		n := elliptic.P256().Params().N
+		flipped := new(big.Int).Sub(n, new(big.Int).SetBytes(sig[32:]))
+		return append(sig[:32], flipped.FillBytes(make([]byte, 32))...)
+	}
+	if _, err := Verify(testRepo().proof(highS), did, "quest.dis.topic", "t1", key); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Verify error = %v, want ErrUnverified", err)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	s := p256Signer(t)
+	rotated := k256Signer(t)
+	current := s
+	var resolved int
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/"+did, func(w http.ResponseWriter, _ *http.Request) {
+		resolved++
+		_ = json.NewEncoder(w).Encode(auth.DIDDocument{
+			ID:      did,
+			Service: []auth.DIDService{{ID: "#atproto_pds", Type: "AtprotoPersonalDataServer", ServiceEndpoint: srv.URL}},
+			VerificationMethod: []auth.DIDVerificationMethod{
+				{ID: did + "#atproto", Type: "Multikey", Controller: did, PublicKeyMultibase: current.multikey},
+			},
+		})
+	})
+	mux.HandleFunc("/xrpc/com.atproto.sync.getRecord", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("did") != did {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		_, _ = w.Write(testRepo().proof(current))
+	})
+
+	resolver := &auth.IdentityResolver{HTTPClient: srv.Client(), PLCDirectory: srv.URL, Scheme: "http"}
+	v := NewVerifier(resolver, srv.Client())
+	ctx := context.Background()
+	if _, err := v.Verify(ctx, did, "quest.dis.topic", "t2"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := v.Verify(ctx, did, "quest.dis.topic", "t3"); err != nil || resolved != 1 {
+		t.Fatalf("expected the identity cached, resolved %d times (%v)", resolved, err)
+	}
+
+	// A rotated key is picked up from the DID document
+	current = rotated
+	if _, err := v.Verify(ctx, did, "quest.dis.topic", "t1"); err != nil || resolved != 2 {
+		t.Errorf("expected the rotated key resolved, resolved %d times (%v)", resolved, err)
+	}
+	if _, err := v.Verify(ctx, did, "quest.dis.topic", "t9"); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Verify error = %v, want ErrUnverified", err)
+	}
+}
@@ -5,43 +5,38 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/db"
 )
 
 // messageRepository implements MessageRepository
 type messageRepository struct {
 	dbService *db.Service
+	clock     clock.Clock
 }
 
 // CreateMessage creates a new message
 func (r *messageRepository) CreateMessage(ctx context.Context, params CreateMessageParams) (*MessageDetail, error) {
-	now := time.Now()
+	now := r.clock.Now()
 	
-	message, err := r.dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
+	created, err := r.dbService.CreateMessageWithActivity(ctx, db.CreateMessageParams{
 		Did:               params.Did,
 		Rkey:              params.Rkey,
 		TopicDid:          params.TopicDID,
 		TopicRkey:         params.TopicRkey,
 		ParentMessageRkey: sql.NullString{String: params.ParentMessageRkey, Valid: params.ParentMessageRkey != ""},
-		Content:           params.Content,
+		Content:           db.MessageContent(params.Content),
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create message: %w", err)
+		return nil, err
 	}
+	message, topic := created.Message, created.Topic
 	
 	// Check if this message is the selected answer
-	topic, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{
-		Did:  params.TopicDID,
-		Rkey: params.TopicRkey,
-	})
-	isAnswer := false
-	if err == nil && topic.SelectedAnswer.Valid {
-		isAnswer = topic.SelectedAnswer.String == params.Rkey
-	}
+	isAnswer := topic.SelectedAnswer.Valid && topic.SelectedAnswer.String == params.Rkey
 	
 	return &MessageDetail{
 		DID:               message.Did,
@@ -49,7 +44,7 @@ func (r *messageRepository) CreateMessage(ctx context.Context, params CreateMess
 		TopicDID:          message.TopicDid,
 		TopicRkey:         message.TopicRkey,
 		ParentMessageRkey: message.ParentMessageRkey.String,
-		Content:           message.Content,
+		Content:           string(message.Content),
 		CreatedAt:         message.CreatedAt,
 		UpdatedAt:         message.UpdatedAt,
 		IsAnswer:          isAnswer,
@@ -97,7 +92,7 @@ func (r *messageRepository) GetMessage(ctx context.Context, did, rkey string) (*
 		TopicDID:          message.TopicDid,
 		TopicRkey:         message.TopicRkey,
 		ParentMessageRkey: message.ParentMessageRkey.String,
-		Content:           message.Content,
+		Content:           string(message.Content),
 		CreatedAt:         message.CreatedAt,
 		UpdatedAt:         message.UpdatedAt,
 		IsAnswer:          isAnswer,
@@ -144,7 +139,7 @@ func (r *messageRepository) GetMessagesByTopic(ctx context.Context, topicDID, to
 			TopicDID:          message.TopicDid,
 			TopicRkey:         message.TopicRkey,
 			ParentMessageRkey: message.ParentMessageRkey.String,
-			Content:           message.Content,
+			Content:           string(message.Content),
 			CreatedAt:         message.CreatedAt,
 			UpdatedAt:         message.UpdatedAt,
 			IsAnswer:          selectedAnswer == message.Rkey,
@@ -174,7 +169,7 @@ func (r *messageRepository) GetRepliesByMessage(ctx context.Context, topicDID, t
 			TopicDID:          reply.TopicDid,
 			TopicRkey:         reply.TopicRkey,
 			ParentMessageRkey: reply.ParentMessageRkey.String,
-			Content:           reply.Content,
+			Content:           string(reply.Content),
 			CreatedAt:         reply.CreatedAt,
 			UpdatedAt:         reply.UpdatedAt,
 			IsAnswer:          false, // Replies can't be selected answers
@@ -203,13 +198,15 @@ func (r *messageRepository) DeleteMessage(ctx context.Context, did, rkey string,
 		return fmt.Errorf("unauthorized: only message author can delete")
 	}
 	
-	// Delete the message
-	err = r.dbService.Queries().DeleteMessage(ctx, db.DeleteMessageParams{
-		Did:  did,
-		Rkey: rkey,
-	})
+	// Tombstone the message so the deletion can be undone during the grace period
+	_, err = r.dbService.SoftDeleteMessage(ctx, db.SoftDeleteMessageParams{
+		DeletedAt: sql.NullTime{Time: r.clock.Now(), Valid: true},
+		DeletedBy: sql.NullString{String: userDID, Valid: true},
+		Did:       did,
+		Rkey:      rkey,
+	}, message.TopicDid, message.TopicRkey)
 	if err != nil {
-		return fmt.Errorf("failed to delete message: %w", err)
+		return err
 	}
 	
 	return nil
@@ -4,19 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/db"
 )
 
 // participationRepository implements ParticipationRepository
 type participationRepository struct {
 	dbService *db.Service
+	clock     clock.Clock
 }
 
 // CreateParticipation creates a new participation record
 func (r *participationRepository) CreateParticipation(ctx context.Context, params CreateParticipationParams) (*ParticipationDetail, error) {
-	now := time.Now()
+	now := r.clock.Now()
 	
 	participation, err := r.dbService.Queries().CreateParticipation(ctx, db.CreateParticipationParams{
 		Did:       params.Did,
@@ -115,7 +116,7 @@ func (r *participationRepository) GetParticipationsByTopic(ctx context.Context,
 func (r *participationRepository) UpdateParticipationStatus(ctx context.Context, userDID, topicDID, topicRkey, status string) error {
 	err := r.dbService.Queries().UpdateParticipationStatus(ctx, db.UpdateParticipationStatusParams{
 		Status:    status,
-		UpdatedAt: time.Now(),
+		UpdatedAt: r.clock.Now(),
 		Did:       userDID,
 		TopicDid:  topicDID,
 		TopicRkey: topicRkey,
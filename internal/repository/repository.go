@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/db"
 )
 
@@ -85,8 +86,10 @@ type TopicDetail struct {
 	SelectedAnswer string            `json:"selected_answer,omitempty"`
 	CreatedAt      time.Time         `json:"created_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
-	MessageCount   int               `json:"message_count,omitempty"`
-	Participants   []ParticipantInfo `json:"participants,omitempty"`
+	MessageCount     int               `json:"message_count,omitempty"`
+	ParticipantCount int               `json:"participant_count"`
+	LastActivity     time.Time         `json:"last_activity"`
+	Participants     []ParticipantInfo `json:"participants,omitempty"`
 }
 
 // TopicSummary represents a topic summary for listings
@@ -95,8 +98,9 @@ type TopicSummary struct {
 	Rkey           string    `json:"rkey"`
 	Subject        string    `json:"subject"`
 	Category       string    `json:"category,omitempty"`
-	MessageCount   int       `json:"message_count"`
-	LastActivity   time.Time `json:"last_activity"`
+	MessageCount     int       `json:"message_count"`
+	ParticipantCount int       `json:"participant_count"`
+	LastActivity     time.Time `json:"last_activity"`
 	CreatedAt      time.Time `json:"created_at"`
 	HasAnswer      bool      `json:"has_answer"`
 }
@@ -141,13 +145,19 @@ type repositoryImpl struct {
 
 // NewRepository creates a new repository instance
 func NewRepository(dbService *db.Service) Repository {
+	return NewRepositoryWithClock(dbService, clock.System{})
+}
+
+// NewRepositoryWithClock creates a repository that stamps records with the
+// time read from clk
+func NewRepositoryWithClock(dbService *db.Service, clk clock.Clock) Repository {
 	repo := &repositoryImpl{
 		dbService: dbService,
 	}
 	
-	repo.topics = &topicRepository{dbService: dbService}
-	repo.messages = &messageRepository{dbService: dbService}
-	repo.participation = &participationRepository{dbService: dbService}
+	repo.topics = &topicRepository{dbService: dbService, clock: clk}
+	repo.messages = &messageRepository{dbService: dbService, clock: clk}
+	repo.participation = &participationRepository{dbService: dbService, clock: clk}
 	
 	return repo
 }
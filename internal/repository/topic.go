@@ -6,17 +6,19 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/db"
 )
 
 // topicRepository implements TopicRepository
 type topicRepository struct {
 	dbService *db.Service
+	clock     clock.Clock
 }
 
 // CreateTopic creates a new topic with automatic participation
 func (r *topicRepository) CreateTopic(ctx context.Context, params CreateTopicParams) (*TopicDetail, error) {
-	now := time.Now()
+	now := r.clock.Now()
 	
 	// Use the service's transaction-based method
 	result, err := r.dbService.CreateTopicWithParticipation(ctx, db.CreateTopicWithParticipationParams{
@@ -34,15 +36,17 @@ func (r *topicRepository) CreateTopic(ctx context.Context, params CreateTopicPar
 	
 	// Convert to repository model
 	return &TopicDetail{
-		DID:            result.Topic.Did,
-		Rkey:           result.Topic.Rkey,
-		Subject:        result.Topic.Subject,
-		InitialMessage: result.Topic.InitialMessage,
-		Category:       result.Topic.Category.String,
-		SelectedAnswer: result.Topic.SelectedAnswer.String,
-		CreatedAt:      result.Topic.CreatedAt,
-		UpdatedAt:      result.Topic.UpdatedAt,
-		MessageCount:   0, // New topic has no messages yet
+		DID:              result.Topic.Did,
+		Rkey:             result.Topic.Rkey,
+		Subject:          result.Topic.Subject,
+		InitialMessage:   result.Topic.InitialMessage,
+		Category:         result.Topic.Category.String,
+		SelectedAnswer:   result.Topic.SelectedAnswer.String,
+		CreatedAt:        result.Topic.CreatedAt,
+		UpdatedAt:        result.Topic.UpdatedAt,
+		MessageCount:     0, // New topic has no messages yet
+		ParticipantCount: int(result.Topic.ParticipantCount),
+		LastActivity:     lastActivity(result.Topic),
 	}, nil
 }
 
@@ -59,15 +63,6 @@ func (r *topicRepository) GetTopic(ctx context.Context, did, rkey string) (*Topi
 		return nil, fmt.Errorf("failed to get topic: %w", err)
 	}
 	
-	// Get message count for this topic
-	messages, err := r.dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{
-		TopicDid:  did,
-		TopicRkey: rkey,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get message count: %w", err)
-	}
-	
 	// Get participants
 	participations, err := r.dbService.Queries().GetParticipationsByTopic(ctx, db.GetParticipationsByTopicParams{
 		TopicDid:  did,
@@ -86,16 +81,18 @@ func (r *topicRepository) GetTopic(ctx context.Context, did, rkey string) (*Topi
 	}
 	
 	return &TopicDetail{
-		DID:            topic.Did,
-		Rkey:           topic.Rkey,
-		Subject:        topic.Subject,
-		InitialMessage: topic.InitialMessage,
-		Category:       topic.Category.String,
-		SelectedAnswer: topic.SelectedAnswer.String,
-		CreatedAt:      topic.CreatedAt,
-		UpdatedAt:      topic.UpdatedAt,
-		MessageCount:   len(messages),
-		Participants:   participants,
+		DID:              topic.Did,
+		Rkey:             topic.Rkey,
+		Subject:          topic.Subject,
+		InitialMessage:   topic.InitialMessage,
+		Category:         topic.Category.String,
+		SelectedAnswer:   topic.SelectedAnswer.String,
+		CreatedAt:        topic.CreatedAt,
+		UpdatedAt:        topic.UpdatedAt,
+		MessageCount:     int(topic.MessageCount),
+		ParticipantCount: int(topic.ParticipantCount),
+		LastActivity:     lastActivity(topic),
+		Participants:     participants,
 	}, nil
 }
 
@@ -121,33 +118,7 @@ func (r *topicRepository) ListTopics(ctx context.Context, params ListTopicsParam
 	
 	summaries := make([]*TopicSummary, len(topics))
 	for i, topic := range topics {
-		// Get message count for each topic
-		messages, err := r.dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{
-			TopicDid:  topic.Did,
-			TopicRkey: topic.Rkey,
-		})
-		messageCount := 0
-		lastActivity := topic.CreatedAt
-		if err == nil {
-			messageCount = len(messages)
-			// Find the most recent message timestamp
-			for _, msg := range messages {
-				if msg.CreatedAt.After(lastActivity) {
-					lastActivity = msg.CreatedAt
-				}
-			}
-		}
-		
-		summaries[i] = &TopicSummary{
-			DID:          topic.Did,
-			Rkey:         topic.Rkey,
-			Subject:      topic.Subject,
-			Category:     topic.Category.String,
-			MessageCount: messageCount,
-			LastActivity: lastActivity,
-			CreatedAt:    topic.CreatedAt,
-			HasAnswer:    topic.SelectedAnswer.Valid && topic.SelectedAnswer.String != "",
-		}
+		summaries[i] = newTopicSummary(topic)
 	}
 	
 	return summaries, nil
@@ -170,26 +141,7 @@ func (r *topicRepository) GetTopicsByCategory(ctx context.Context, category stri
 	
 	summaries := make([]*TopicSummary, len(topics))
 	for i, topic := range topics {
-		// Get message count for each topic (simplified for category view)
-		messages, err := r.dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{
-			TopicDid:  topic.Did,
-			TopicRkey: topic.Rkey,
-		})
-		messageCount := 0
-		if err == nil {
-			messageCount = len(messages)
-		}
-		
-		summaries[i] = &TopicSummary{
-			DID:          topic.Did,
-			Rkey:         topic.Rkey,
-			Subject:      topic.Subject,
-			Category:     topic.Category.String,
-			MessageCount: messageCount,
-			LastActivity: topic.UpdatedAt,
-			CreatedAt:    topic.CreatedAt,
-			HasAnswer:    topic.SelectedAnswer.Valid && topic.SelectedAnswer.String != "",
-		}
+		summaries[i] = newTopicSummary(topic)
 	}
 	
 	return summaries, nil
@@ -216,7 +168,7 @@ func (r *topicRepository) UpdateSelectedAnswer(ctx context.Context, topicDID, to
 	// Update the selected answer
 	err = r.dbService.Queries().UpdateTopicSelectedAnswer(ctx, db.UpdateTopicSelectedAnswerParams{
 		SelectedAnswer: sql.NullString{String: messageRkey, Valid: messageRkey != ""},
-		UpdatedAt:      time.Now(),
+		UpdatedAt:      r.clock.Now(),
 		Did:            topicDID,
 		Rkey:           topicRkey,
 	})
@@ -225,4 +177,29 @@ func (r *topicRepository) UpdateSelectedAnswer(ctx context.Context, topicDID, to
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// newTopicSummary builds a listing entry from the counters stored on the
+// topic row, so listings need no per-topic message queries
+func newTopicSummary(topic db.Topic) *TopicSummary {
+	return &TopicSummary{
+		DID:              topic.Did,
+		Rkey:             topic.Rkey,
+		Subject:          topic.Subject,
+		Category:         topic.Category.String,
+		MessageCount:     int(topic.MessageCount),
+		ParticipantCount: int(topic.ParticipantCount),
+		LastActivity:     lastActivity(topic),
+		CreatedAt:        topic.CreatedAt,
+		HasAnswer:        topic.SelectedAnswer.Valid && topic.SelectedAnswer.String != "",
+	}
+}
+
+// lastActivity returns when a topic last had a message, or when it was
+// created if nobody has posted yet
+func lastActivity(topic db.Topic) time.Time {
+	if topic.LastActivityAt.Valid {
+		return topic.LastActivityAt.Time
+	}
+	return topic.CreatedAt
+}
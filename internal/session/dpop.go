@@ -0,0 +1,83 @@
+package session
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/keyseal"
+)
+
+// keySecretLabel separates the key that seals DPoP keys from other uses of
+// the same secret
+const keySecretLabel = "dis.quest session dpop key"
+
+var (
+	// ErrNoDPoPKey is returned for sessions signed in without OAuth, which
+	// have no DPoP key
+	ErrNoDPoPKey = errors.New("session has no DPoP key")
+	// ErrNoKeySecret is returned when storing a DPoP key before SetKeySecret
+	ErrNoKeySecret = errors.New("no secret set for sealing DPoP keys")
+)
+
+// SetKeySecret sets the secret DPoP keys are encrypted with before they are
+// stored. Every instance sharing a store must use the same secret; changing
+// it leaves existing OAuth sessions unable to refresh.
+func (m *Manager) SetKeySecret(secret []byte) error {
+	sealer, err := keyseal.New(secret, keySecretLabel)
+	if err != nil {
+		return err
+	}
+	m.keys = sealer
+	return nil
+}
+
+// DPoPKey returns the DPoP key of the request's session, which must be live
+// and belong to did
+func (m *Manager) DPoPKey(req *http.Request, did string) (*ecdsa.PrivateKey, error) {
+	id, ok := IDFromRequest(req)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s, err := m.Validate(req.Context(), id, did)
+	if err != nil {
+		return nil, err
+	}
+	if s.DPoPKey == "" {
+		return nil, ErrNoDPoPKey
+	}
+	return m.openKey(s.ID, s.DPoPKey)
+}
+
+// sealKey encrypts key for the session with ID id. The ID is authenticated
+// with it, so a sealed key copied to another session will not open.
+func (m *Manager) sealKey(id string, key *ecdsa.PrivateKey) (string, error) {
+	if key == nil {
+		return "", nil
+	}
+	if m.keys == nil {
+		return "", ErrNoKeySecret
+	}
+	sealed, err := m.keys.Seal(id, key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) openKey(id, sealed string) (*ecdsa.PrivateKey, error) {
+	if m.keys == nil {
+		return nil, ErrNoKeySecret
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("malformed DPoP key")
+	}
+	key, err := m.keys.Open(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DPoP key: %w", err)
+	}
+	return key, nil
+}
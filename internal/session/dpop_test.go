@@ -0,0 +1,66 @@
+package session
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestManagerDPoPKey(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.StartWithDPoPKey(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil), key); !errors.Is(err, ErrNoKeySecret) {
+		t.Fatalf("StartWithDPoPKey without a secret = %v, want ErrNoKeySecret", err)
+	}
+	if err := m.SetKeySecret([]byte("secret")); err != nil {
+		t.Fatalf("SetKeySecret: %v", err)
+	}
+	s, err := m.StartWithDPoPKey(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil), key)
+	if err != nil {
+		t.Fatalf("StartWithDPoPKey: %v", err)
+	}
+	if s.DPoPKey == "" {
+		t.Fatal("expected the key to be stored")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: s.ID})
+	got, err := m.DPoPKey(req, "did:plc:alice")
+	if err != nil {
+		t.Fatalf("DPoPKey: %v", err)
+	}
+	if !got.Equal(key) {
+		t.Error("DPoPKey returned a different key")
+	}
+	if _, err := m.DPoPKey(req, "did:plc:bob"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DPoPKey for another DID = %v, want ErrNotFound", err)
+	}
+
+	// A sealed key only opens for the session it was stored with
+	if _, err := m.openKey("other-session", s.DPoPKey); err == nil {
+		t.Error("expected a key moved to another session to fail to open")
+	}
+	other, _ := newTestManager()
+	other.store = m.store
+	_ = other.SetKeySecret([]byte("rotated"))
+	if _, err := other.DPoPKey(req, "did:plc:alice"); err == nil {
+		t.Error("expected a key sealed with another secret to fail to open")
+	}
+
+	plain, _ := m.Start(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil))
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: plain.ID})
+	if _, err := m.DPoPKey(req, "did:plc:alice"); !errors.Is(err, ErrNoDPoPKey) {
+		t.Errorf("DPoPKey for an app-password session = %v, want ErrNoDPoPKey", err)
+	}
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on
+// restart, so it suits tests and single-instance development servers.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]Session)}
+}
+
+// Create implements Store
+func (s *MemoryStore) Create(_ context.Context, sess Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return sess, nil
+}
+
+// Touch implements Store
+func (s *MemoryStore) Touch(_ context.Context, id string, lastSeenAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		sess.LastSeenAt = lastSeenAt
+		s.sessions[id] = sess
+	}
+	return nil
+}
+
+// List implements Store
+func (s *MemoryStore) List(_ context.Context, did string) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := []Session{}
+	for _, sess := range s.sessions {
+		if sess.DID == did {
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(_ context.Context, did, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok || sess.DID != did {
+		return false, nil
+	}
+	delete(s.sessions, id)
+	return true, nil
+}
+
+// DeleteAll implements Store
+func (s *MemoryStore) DeleteAll(_ context.Context, did string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, sess := range s.sessions {
+		if sess.DID == did {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteIdle implements Store
+func (s *MemoryStore) DeleteIdle(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, sess := range s.sessions {
+		if sess.LastSeenAt.Before(before) {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,14 @@
+package session_test
+
+import (
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/session"
+	"github.com/jrschumacher/dis.quest/internal/session/sessiontest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	sessiontest.RunStorageTests(t, func(*testing.T) session.Store {
+		return session.NewMemoryStore()
+	})
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"net/http"
+)
+
+// Profile is how an account presents itself, as of when its session started
+type Profile struct {
+	Handle      string `json:"handle,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	// Avatar is the URL of the profile picture, if there is one
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// Name returns what to call the account: the display name, else the handle,
+// else fallback
+func (p Profile) Name(fallback string) string {
+	switch {
+	case p.DisplayName != "":
+		return p.DisplayName
+	case p.Handle != "":
+		return p.Handle
+	}
+	return fallback
+}
+
+// ProfileLoader looks up an account's profile when a session starts
+type ProfileLoader interface {
+	LoadProfile(ctx context.Context, did string) (Profile, error)
+}
+
+// SetProfileLoader makes new sessions record their owner's profile. Without
+// one, sessions start with an empty profile.
+func (m *Manager) SetProfileLoader(loader ProfileLoader) {
+	m.profiles = loader
+}
+
+// Lookup returns the profile of the request's session if it is live for
+// did. It matches middleware.SessionValidator.
+func (m *Manager) Lookup(req *http.Request, did string) (Profile, bool) {
+	id, ok := IDFromRequest(req)
+	if !ok {
+		return Profile{}, false
+	}
+	s, err := m.Validate(req.Context(), id, did)
+	if err != nil {
+		return Profile{}, false
+	}
+	return s.Profile, true
+}
+
+// loadProfile fetches did's profile. A profile is a convenience, so failing
+// to load one leaves it empty rather than failing the sign-in.
+func (m *Manager) loadProfile(ctx context.Context, did string) Profile {
+	if m.profiles == nil {
+		return Profile{}
+	}
+	profile, err := m.profiles.LoadProfile(ctx, did)
+	if err != nil {
+		return Profile{}
+	}
+	return profile
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type profileLoaderFunc func(ctx context.Context, did string) (Profile, error)
+
+func (f profileLoaderFunc) LoadProfile(ctx context.Context, did string) (Profile, error) {
+	return f(ctx, did)
+}
+
+func TestManagerProfile(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager()
+	m.SetProfileLoader(profileLoaderFunc(func(_ context.Context, did string) (Profile, error) {
+		if did == "did:plc:down" {
+			return Profile{}, errors.New("PDS unreachable")
+		}
+		return Profile{Handle: "alice.example.com", DisplayName: "Alice"}, nil
+	}))
+
+	s, err := m.Start(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: s.ID})
+	profile, ok := m.Lookup(req, "did:plc:alice")
+	if !ok || profile.Handle != "alice.example.com" || profile.DisplayName != "Alice" {
+		t.Errorf("Lookup = %+v, %v", profile, ok)
+	}
+	if _, ok := m.Lookup(req, "did:plc:bob"); ok {
+		t.Error("expected Lookup for another DID to fail")
+	}
+
+	// A profile that cannot be loaded must not stop the sign-in
+	down, err := m.Start(ctx, "did:plc:down", httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Start with a failing profile lookup: %v", err)
+	}
+	if down.Profile != (Profile{}) {
+		t.Errorf("expected an empty profile, got %+v", down.Profile)
+	}
+}
+
+func TestProfileName(t *testing.T) {
+	for _, tc := range []struct {
+		profile Profile
+		want    string
+	}{
+		{Profile{Handle: "alice.example.com", DisplayName: "Alice"}, "Alice"},
+		{Profile{Handle: "alice.example.com"}, "alice.example.com"},
+		{Profile{}, "did:plc:alice"},
+	} {
+		if got := tc.profile.Name("did:plc:alice"); got != tc.want {
+			t.Errorf("%+v.Name() = %q, want %q", tc.profile, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package session keeps a server-side record of each signed-in browser so
+// users can see where they are signed in and revoke sessions. Tokens stay in
+// their cookies; a session ties a random ID cookie to its owner's DID and,
+// for OAuth sign-ins, holds the encrypted DPoP key the tokens are bound to.
+package session
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/keyseal"
+)
+
+const (
+	// CookieName is the cookie holding the session ID
+	CookieName = "dsq_sid"
+	// IdleTimeout is how long a session may go unused before it expires
+	IdleTimeout = 30 * 24 * time.Hour
+	// touchInterval limits how often last-seen times are written, so an
+	// active session costs one write every few minutes rather than per request
+	touchInterval = 5 * time.Minute
+	// maxUserAgentLength bounds the stored User-Agent header
+	maxUserAgentLength = 256
+)
+
+// ErrNotFound is returned for sessions that do not exist, have been revoked,
+// have expired, or belong to someone else
+var ErrNotFound = errors.New("session not found")
+
+// Session is one signed-in browser
+type Session struct {
+	ID         string    `json:"id"`
+	DID        string    `json:"did"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Profile is the owner's profile when the session started
+	Profile Profile `json:"profile"`
+	// DPoPKey is the sealed DPoP key of an OAuth sign-in, empty otherwise
+	DPoPKey string `json:"-"`
+}
+
+// Store persists sessions
+type Store interface {
+	Create(ctx context.Context, s Session) error
+	// Get returns ErrNotFound when no session has the ID
+	Get(ctx context.Context, id string) (Session, error)
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+	// List returns did's sessions, most recently used first
+	List(ctx context.Context, did string) ([]Session, error)
+	// Delete removes one of did's sessions, reporting whether it existed
+	Delete(ctx context.Context, did, id string) (bool, error)
+	DeleteAll(ctx context.Context, did string) (int64, error)
+	// DeleteIdle removes sessions last seen before the cutoff
+	DeleteIdle(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Manager creates, checks and revokes sessions
+type Manager struct {
+	store Store
+	now   func() time.Time
+	keys  *keyseal.Sealer // seals DPoP keys; nil until SetKeySecret
+
+	profiles ProfileLoader
+}
+
+// NewManager creates a manager backed by store
+func NewManager(store Store) *Manager {
+	return NewManagerWithClock(store, clock.System{})
+}
+
+// NewManagerWithClock creates a manager backed by store that reads the time
+// from clk when stamping and expiring sessions
+func NewManagerWithClock(store Store, clk clock.Clock) *Manager {
+	return &Manager{store: store, now: clk.Now}
+}
+
+// Start records a new session for did, signed in from req
+func (m *Manager) Start(ctx context.Context, did string, req *http.Request) (Session, error) {
+	return m.StartWithDPoPKey(ctx, did, req, nil)
+}
+
+// StartWithDPoPKey records a new session for did like Start, keeping the
+// DPoP key its OAuth tokens are bound to. A nil key starts a session
+// without one.
+func (m *Manager) StartWithDPoPKey(ctx context.Context, did string, req *http.Request, key *ecdsa.PrivateKey) (Session, error) {
+	s, err := m.newSession(did, req)
+	if err != nil {
+		return Session{}, err
+	}
+	if s.DPoPKey, err = m.sealKey(s.ID, key); err != nil {
+		return Session{}, err
+	}
+	s.Profile = m.loadProfile(ctx, did)
+	if err := m.store.Create(ctx, s); err != nil {
+		return Session{}, err
+	}
+	return s, nil
+}
+
+// newSession describes a session for did, signed in from req, starting now
+func (m *Manager) newSession(did string, req *http.Request) (Session, error) {
+	id, err := newID()
+	if err != nil {
+		return Session{}, err
+	}
+	userAgent := req.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	now := m.now().UTC()
+	s := Session{
+		ID:         id,
+		DID:        did,
+		UserAgent:  userAgent,
+		IP:         clientIP(req),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	return s, nil
+}
+
+// Validate returns the session if it exists, belongs to did and has not
+// expired, refreshing its last-seen time
+func (m *Manager) Validate(ctx context.Context, id, did string) (Session, error) {
+	s, err := m.store.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if s.DID != did {
+		return Session{}, ErrNotFound
+	}
+	now := m.now().UTC()
+	if now.Sub(s.LastSeenAt) > IdleTimeout {
+		_, _ = m.store.Delete(ctx, s.DID, s.ID)
+		return Session{}, ErrNotFound
+	}
+	if now.Sub(s.LastSeenAt) > touchInterval {
+		// A failed touch only makes the last-seen time stale; the session
+		// itself is still valid
+		if err := m.store.Touch(ctx, s.ID, now); err == nil {
+			s.LastSeenAt = now
+		}
+	}
+	return s, nil
+}
+
+// Check reports whether req carries a live session for did
+func (m *Manager) Check(req *http.Request, did string) bool {
+	_, ok := m.Lookup(req, did)
+	return ok
+}
+
+// List returns did's sessions, most recently used first
+func (m *Manager) List(ctx context.Context, did string) ([]Session, error) {
+	return m.store.List(ctx, did)
+}
+
+// Revoke ends one of did's sessions
+func (m *Manager) Revoke(ctx context.Context, did, id string) error {
+	found, err := m.store.Delete(ctx, did, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAll ends every session did has, returning how many were ended
+func (m *Manager) RevokeAll(ctx context.Context, did string) (int64, error) {
+	return m.store.DeleteAll(ctx, did)
+}
+
+// PurgeIdle removes sessions unused for longer than IdleTimeout
+func (m *Manager) PurgeIdle(ctx context.Context) (int64, error) {
+	return m.store.DeleteIdle(ctx, m.now().UTC().Add(-IdleTimeout))
+}
+
+// SetCookie stores the session ID in a secure, HttpOnly cookie
+func SetCookie(w http.ResponseWriter, id string, isDev bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !isDev,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearCookie removes the session ID cookie
+func ClearCookie(w http.ResponseWriter, isDev bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   !isDev,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// IDFromRequest returns the session ID cookie's value
+func IDFromRequest(req *http.Request) (string, bool) {
+	cookie, err := req.Cookie(CookieName)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func newID() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,161 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestManager() (*Manager, *time.Time) {
+	m := NewManager(NewMemoryStore())
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestManagerStart(t *testing.T) {
+	m, _ := newTestManager()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("User-Agent", strings.Repeat("a", maxUserAgentLength+10))
+
+	s, err := m.Start(context.Background(), "did:plc:alice", req)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if s.ID == "" || s.DID != "did:plc:alice" {
+		t.Fatalf("unexpected session: %+v", s)
+	}
+	if s.IP != "203.0.113.7" {
+		t.Errorf("IP = %q, want 203.0.113.7", s.IP)
+	}
+	if len(s.UserAgent) != maxUserAgentLength {
+		t.Errorf("user agent not truncated: %d bytes", len(s.UserAgent))
+	}
+
+	other, err := m.Start(context.Background(), "did:plc:alice", req)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if other.ID == s.ID {
+		t.Error("expected distinct session IDs")
+	}
+}
+
+func TestManagerValidate(t *testing.T) {
+	ctx := context.Background()
+	m, now := newTestManager()
+	s, err := m.Start(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	t.Run("owner", func(t *testing.T) {
+		if _, err := m.Validate(ctx, s.ID, "did:plc:alice"); err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+	})
+
+	t.Run("other DID", func(t *testing.T) {
+		if _, err := m.Validate(ctx, s.ID, "did:plc:mallory"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("touches last seen", func(t *testing.T) {
+		*now = now.Add(touchInterval + time.Minute)
+		got, err := m.Validate(ctx, s.ID, "did:plc:alice")
+		if err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+		if !got.LastSeenAt.Equal(*now) {
+			t.Errorf("LastSeenAt = %v, want %v", got.LastSeenAt, *now)
+		}
+	})
+
+	t.Run("expires when idle", func(t *testing.T) {
+		*now = now.Add(IdleTimeout + time.Minute)
+		if _, err := m.Validate(ctx, s.ID, "did:plc:alice"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestManagerRevoke(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager()
+	req := httptest.NewRequest("GET", "/", nil)
+	first, _ := m.Start(ctx, "did:plc:alice", req)
+	second, _ := m.Start(ctx, "did:plc:alice", req)
+	bob, _ := m.Start(ctx, "did:plc:bob", req)
+
+	if err := m.Revoke(ctx, "did:plc:bob", first.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("revoking someone else's session: expected ErrNotFound, got %v", err)
+	}
+	if err := m.Revoke(ctx, "did:plc:alice", first.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := m.Validate(ctx, first.ID, "did:plc:alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("revoked session still valid: %v", err)
+	}
+
+	sessions, err := m.List(ctx, "did:plc:alice")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != second.ID {
+		t.Fatalf("List = %+v, %v; want only the second session", sessions, err)
+	}
+
+	n, err := m.RevokeAll(ctx, "did:plc:alice")
+	if err != nil || n != 1 {
+		t.Fatalf("RevokeAll = %d, %v; want 1", n, err)
+	}
+	if _, err := m.Validate(ctx, bob.ID, "did:plc:bob"); err != nil {
+		t.Fatalf("other user's session was revoked: %v", err)
+	}
+}
+
+func TestManagerPurgeIdle(t *testing.T) {
+	ctx := context.Background()
+	m, now := newTestManager()
+	req := httptest.NewRequest("GET", "/", nil)
+	stale, _ := m.Start(ctx, "did:plc:alice", req)
+	*now = now.Add(IdleTimeout)
+	fresh, _ := m.Start(ctx, "did:plc:alice", req)
+	*now = now.Add(time.Hour)
+
+	n, err := m.PurgeIdle(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeIdle = %d, %v; want 1", n, err)
+	}
+	if _, err := m.store.Get(ctx, stale.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("stale session not purged: %v", err)
+	}
+	if _, err := m.store.Get(ctx, fresh.ID); err != nil {
+		t.Errorf("fresh session purged: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager()
+	s, _ := m.Start(ctx, "did:plc:alice", httptest.NewRequest("GET", "/", nil))
+
+	rr := httptest.NewRecorder()
+	SetCookie(rr, s.ID, true)
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	if !m.Check(req, "did:plc:alice") {
+		t.Error("expected session cookie to be accepted")
+	}
+	if m.Check(req, "did:plc:bob") {
+		t.Error("expected session cookie to be rejected for another DID")
+	}
+	if m.Check(httptest.NewRequest("GET", "/", nil), "did:plc:alice") {
+		t.Error("expected request without a session cookie to be rejected")
+	}
+}
@@ -0,0 +1,263 @@
+// Package sessiontest checks that session.Store implementations behave
+// alike, so a backend can be swapped without the Manager noticing
+package sessiontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/session"
+)
+
+// largePayloadSize is the size of the DPoP key stored by the large payload
+// test, well past any sealed key the Manager writes
+const largePayloadSize = 64 << 10
+
+// base is the time sessions in the tests are created at
+var base = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// RunStorageTests runs the conformance tests against stores made by
+// newStore. Each test gets a store of its own.
+func RunStorageTests(t *testing.T, newStore func(t *testing.T) session.Store) {
+	t.Helper()
+	for name, test := range map[string]func(*testing.T, session.Store){
+		"CreateGet":    testCreateGet,
+		"Touch":        testTouch,
+		"List":         testList,
+		"Delete":       testDelete,
+		"DeleteAll":    testDeleteAll,
+		"DeleteIdle":   testDeleteIdle,
+		"LargePayload": testLargePayload,
+		"Concurrent":   testConcurrent,
+	} {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+func newSession(id, did string, lastSeenAt time.Time) session.Session {
+	return session.Session{
+		ID:         id,
+		DID:        did,
+		UserAgent:  "sessiontest",
+		IP:         "203.0.113.7",
+		CreatedAt:  base,
+		LastSeenAt: lastSeenAt,
+		Profile:    session.Profile{Handle: "alice.test", DisplayName: "Alice"},
+		DPoPKey:    "sealed-key",
+	}
+}
+
+func create(t *testing.T, store session.Store, sessions ...session.Session) {
+	t.Helper()
+	for _, s := range sessions {
+		if err := store.Create(context.Background(), s); err != nil {
+			t.Fatalf("Create %s: %v", s.ID, err)
+		}
+	}
+}
+
+func get(t *testing.T, store session.Store, id string) session.Session {
+	t.Helper()
+	s, err := store.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get %s: %v", id, err)
+	}
+	return s
+}
+
+func assertNotFound(t *testing.T, store session.Store, id string) {
+	t.Helper()
+	if _, err := store.Get(context.Background(), id); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Get %s: err = %v, want ErrNotFound", id, err)
+	}
+}
+
+func assertEqual(t *testing.T, got, want session.Session) {
+	t.Helper()
+	if !got.CreatedAt.Equal(want.CreatedAt) || !got.LastSeenAt.Equal(want.LastSeenAt) {
+		t.Errorf("%s times = %v, %v; want %v, %v", want.ID, got.CreatedAt, got.LastSeenAt, want.CreatedAt, want.LastSeenAt)
+	}
+	got.CreatedAt, got.LastSeenAt = want.CreatedAt, want.LastSeenAt
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func ids(sessions []session.Session) string {
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func testCreateGet(t *testing.T, store session.Store) {
+	want := newSession("s1", "did:plc:alice", base)
+	want.Profile.Avatar = "https://cdn.example.com/avatar.jpg"
+	create(t, store, want)
+	assertEqual(t, get(t, store, "s1"), want)
+	assertNotFound(t, store, "missing")
+}
+
+func testTouch(t *testing.T, store session.Store) {
+	ctx := context.Background()
+	create(t, store, newSession("s1", "did:plc:alice", base))
+	later := base.Add(time.Hour)
+	if err := store.Touch(ctx, "s1", later); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if got := get(t, store, "s1"); !got.LastSeenAt.Equal(later) || !got.CreatedAt.Equal(base) {
+		t.Errorf("after Touch created, last seen = %v, %v; want %v, %v", got.CreatedAt, got.LastSeenAt, base, later)
+	}
+	// Touching a session that does not exist is not an error and creates nothing
+	if err := store.Touch(ctx, "missing", later); err != nil {
+		t.Errorf("Touch missing: %v", err)
+	}
+	assertNotFound(t, store, "missing")
+}
+
+func testList(t *testing.T, store session.Store) {
+	create(t, store,
+		newSession("old", "did:plc:alice", base),
+		newSession("new", "did:plc:alice", base.Add(2*time.Hour)),
+		newSession("mid", "did:plc:alice", base.Add(time.Hour)),
+		newSession("bob", "did:plc:bob", base.Add(3*time.Hour)),
+	)
+	sessions, err := store.List(context.Background(), "did:plc:alice")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := ids(sessions); got != "new,mid,old" {
+		t.Errorf("List = %s, want new,mid,old", got)
+	}
+	sessions, err = store.List(context.Background(), "did:plc:nobody")
+	if err != nil || sessions == nil || len(sessions) != 0 {
+		t.Errorf("List of no sessions = %v, %v; want empty", sessions, err)
+	}
+}
+
+func testDelete(t *testing.T, store session.Store) {
+	ctx := context.Background()
+	create(t, store, newSession("s1", "did:plc:alice", base))
+
+	// Only the owner may delete a session
+	if ok, err := store.Delete(ctx, "did:plc:bob", "s1"); err != nil || ok {
+		t.Errorf("Delete by another DID = %v, %v; want false", ok, err)
+	}
+	get(t, store, "s1")
+
+	if ok, err := store.Delete(ctx, "did:plc:alice", "s1"); err != nil || !ok {
+		t.Errorf("Delete = %v, %v; want true", ok, err)
+	}
+	assertNotFound(t, store, "s1")
+	if ok, err := store.Delete(ctx, "did:plc:alice", "s1"); err != nil || ok {
+		t.Errorf("second Delete = %v, %v; want false", ok, err)
+	}
+}
+
+func testDeleteAll(t *testing.T, store session.Store) {
+	create(t, store,
+		newSession("a1", "did:plc:alice", base),
+		newSession("a2", "did:plc:alice", base),
+		newSession("b1", "did:plc:bob", base),
+	)
+	if n, err := store.DeleteAll(context.Background(), "did:plc:alice"); err != nil || n != 2 {
+		t.Errorf("DeleteAll = %d, %v; want 2", n, err)
+	}
+	assertNotFound(t, store, "a1")
+	assertNotFound(t, store, "a2")
+	get(t, store, "b1")
+}
+
+func testDeleteIdle(t *testing.T, store session.Store) {
+	ctx := context.Background()
+	cutoff := base.Add(-session.IdleTimeout)
+	create(t, store,
+		newSession("idle", "did:plc:alice", cutoff.Add(-time.Second)),
+		newSession("edge", "did:plc:alice", cutoff),
+		newSession("active", "did:plc:bob", base),
+		newSession("touched", "did:plc:bob", cutoff.Add(-time.Hour)),
+	)
+	// Touching keeps an otherwise idle session alive
+	if err := store.Touch(ctx, "touched", base); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+
+	if n, err := store.DeleteIdle(ctx, cutoff); err != nil || n != 1 {
+		t.Errorf("DeleteIdle = %d, %v; want 1", n, err)
+	}
+	assertNotFound(t, store, "idle")
+	for _, id := range []string{"edge", "active", "touched"} {
+		get(t, store, id)
+	}
+	if n, err := store.DeleteIdle(ctx, cutoff); err != nil || n != 0 {
+		t.Errorf("repeated DeleteIdle = %d, %v; want 0", n, err)
+	}
+}
+
+func testLargePayload(t *testing.T, store session.Store) {
+	want := newSession("big", "did:plc:alice", base)
+	want.DPoPKey = strings.Repeat("k", largePayloadSize)
+	want.UserAgent = strings.Repeat("u", 256)
+	want.Profile = session.Profile{
+		Handle:      "alice.test",
+		DisplayName: strings.Repeat("Ålïçé 🧭 ", 64),
+		Avatar:      "https://cdn.example.com/" + strings.Repeat("a", 2000),
+	}
+	create(t, store, want)
+	assertEqual(t, get(t, store, "big"), want)
+}
+
+func testConcurrent(t *testing.T, store session.Store) {
+	const workers, perWorker = 8, 10
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			did := fmt.Sprintf("did:plc:user%d", w)
+			for i := range perWorker {
+				id := fmt.Sprintf("s%d-%d", w, i)
+				if err := store.Create(ctx, newSession(id, did, base)); err != nil {
+					errs <- fmt.Errorf("Create %s: %w", id, err)
+					continue
+				}
+				if err := store.Touch(ctx, id, base.Add(time.Duration(i)*time.Minute)); err != nil {
+					errs <- fmt.Errorf("Touch %s: %w", id, err)
+				}
+				if _, err := store.Get(ctx, id); err != nil {
+					errs <- fmt.Errorf("Get %s: %w", id, err)
+				}
+				if i%2 == 1 {
+					if ok, err := store.Delete(ctx, did, id); err != nil || !ok {
+						errs <- fmt.Errorf("Delete %s = %v, %w", id, ok, err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for w := range workers {
+		sessions, err := store.List(ctx, fmt.Sprintf("did:plc:user%d", w))
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(sessions) != perWorker/2 {
+			t.Errorf("worker %d has %d sessions, want %d", w, len(sessions), perWorker/2)
+		}
+	}
+}
@@ -18,6 +18,13 @@ func CreateTestSchema(db *sql.DB) error {
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
 		selected_answer TEXT,
+		deleted_at DATETIME,
+		deleted_by TEXT,
+		message_count INTEGER NOT NULL DEFAULT 0,
+		last_activity_at DATETIME,
+		hot_rank REAL NOT NULL DEFAULT 0,
+		community TEXT NOT NULL DEFAULT '',
+		participant_count INTEGER NOT NULL DEFAULT 1,
 		PRIMARY KEY (did, rkey)
 	);
 
@@ -31,6 +38,8 @@ func CreateTestSchema(db *sql.DB) error {
 		content TEXT NOT NULL,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
+		deleted_at DATETIME,
+		deleted_by TEXT,
 		PRIMARY KEY (did, rkey),
 		FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey)
 	);
@@ -47,13 +56,189 @@ func CreateTestSchema(db *sql.DB) error {
 		FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey)
 	);
 
+	-- Read markers table
+	CREATE TABLE IF NOT EXISTS quest_dis_read_marker (
+		did TEXT NOT NULL,
+		topic_did TEXT NOT NULL,
+		topic_rkey TEXT NOT NULL,
+		last_read_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (did, topic_did, topic_rkey),
+		FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_firehose_event (
+		time_us BIGINT PRIMARY KEY,
+		did TEXT NOT NULL,
+		collection TEXT NOT NULL,
+		rkey TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		record TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_topic_stats (
+		topic_did TEXT NOT NULL,
+		topic_rkey TEXT NOT NULL,
+		day DATETIME NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		messages INTEGER NOT NULL DEFAULT 0,
+		participants INTEGER NOT NULL DEFAULT 0,
+		first_responses INTEGER NOT NULL DEFAULT 0,
+		response_seconds BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (topic_did, topic_rkey, day),
+		FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_session (
+		id TEXT PRIMARY KEY,
+		did TEXT NOT NULL,
+		user_agent TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		last_seen_at DATETIME NOT NULL,
+		dpop_key TEXT NOT NULL DEFAULT '',
+		handle TEXT NOT NULL DEFAULT '',
+		display_name TEXT NOT NULL DEFAULT '',
+		avatar TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_community (
+		slug TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		created_by TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_community_category (
+		community TEXT NOT NULL,
+		slug TEXT NOT NULL,
+		name TEXT NOT NULL,
+		PRIMARY KEY (community, slug),
+		FOREIGN KEY (community) REFERENCES quest_dis_community(slug)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_community_moderator (
+		community TEXT NOT NULL,
+		did TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (community, did),
+		FOREIGN KEY (community) REFERENCES quest_dis_community(slug)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_community_domain (
+		domain TEXT PRIMARY KEY,
+		community TEXT NOT NULL,
+		token TEXT NOT NULL,
+		verification_method TEXT,
+		verified_at DATETIME,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (community) REFERENCES quest_dis_community(slug)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_participation_retry (
+		did TEXT NOT NULL,
+		topic_did TEXT NOT NULL,
+		topic_rkey TEXT NOT NULL,
+		rkey TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (did, topic_did, topic_rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_mirror_source (
+		origin TEXT PRIMARY KEY,
+		time_us BIGINT NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_mirror_record (
+		origin TEXT NOT NULL,
+		did TEXT NOT NULL,
+		collection TEXT NOT NULL,
+		rkey TEXT NOT NULL,
+		cid TEXT NOT NULL,
+		time_us BIGINT NOT NULL,
+		deleted BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY (origin, did, collection, rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_mirror_topic (
+		did TEXT NOT NULL,
+		rkey TEXT NOT NULL,
+		origin TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		initial_message TEXT NOT NULL,
+		category TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		mirrored_at DATETIME NOT NULL,
+		verified BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY (did, rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_mirror_message (
+		did TEXT NOT NULL,
+		rkey TEXT NOT NULL,
+		origin TEXT NOT NULL,
+		topic_did TEXT NOT NULL,
+		topic_rkey TEXT NOT NULL,
+		parent_message_rkey TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		mirrored_at DATETIME NOT NULL,
+		verified BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY (did, rkey)
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_label (
+		seq BIGINT PRIMARY KEY,
+		src TEXT NOT NULL,
+		uri TEXT NOT NULL,
+		cid TEXT NOT NULL DEFAULT '',
+		val TEXT NOT NULL,
+		neg BOOLEAN NOT NULL DEFAULT FALSE,
+		cts TEXT NOT NULL,
+		sig BLOB NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS quest_dis_access_token (
+		id TEXT PRIMARY KEY,
+		did TEXT NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL,
+		last_used_at DATETIME
+	);
+
 	-- Indexes for better performance
 	CREATE INDEX IF NOT EXISTS idx_topic_category ON quest_dis_topic(category);
 	CREATE INDEX IF NOT EXISTS idx_topic_created_at ON quest_dis_topic(created_at);
 	CREATE INDEX IF NOT EXISTS idx_message_topic ON quest_dis_message(topic_did, topic_rkey);
 	CREATE INDEX IF NOT EXISTS idx_message_parent ON quest_dis_message(parent_message_rkey);
+	CREATE INDEX IF NOT EXISTS idx_topic_deleted_at ON quest_dis_topic(deleted_at);
+	CREATE INDEX IF NOT EXISTS idx_topic_hot_rank ON quest_dis_topic(hot_rank);
+	CREATE INDEX IF NOT EXISTS idx_topic_message_count ON quest_dis_topic(message_count);
+	CREATE INDEX IF NOT EXISTS idx_message_deleted_at ON quest_dis_message(deleted_at);
 	CREATE INDEX IF NOT EXISTS idx_participation_user ON quest_dis_participation(did);
 	CREATE INDEX IF NOT EXISTS idx_participation_topic ON quest_dis_participation(topic_did, topic_rkey);
+	CREATE INDEX IF NOT EXISTS idx_read_marker_user ON quest_dis_read_marker(did);
+	CREATE INDEX IF NOT EXISTS idx_topic_stats_day ON quest_dis_topic_stats(day);
+	CREATE INDEX IF NOT EXISTS idx_session_did ON quest_dis_session(did);
+	CREATE INDEX IF NOT EXISTS idx_session_last_seen_at ON quest_dis_session(last_seen_at);
+	CREATE INDEX IF NOT EXISTS idx_topic_community ON quest_dis_topic(community, created_at);
+	CREATE INDEX IF NOT EXISTS idx_community_moderator_did ON quest_dis_community_moderator(did);
+	CREATE INDEX IF NOT EXISTS idx_community_domain_community ON quest_dis_community_domain(community);
+	CREATE INDEX IF NOT EXISTS idx_participation_retry_next_attempt_at ON quest_dis_participation_retry(next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_mirror_topic_category ON quest_dis_mirror_topic(category, created_at);
+	CREATE INDEX IF NOT EXISTS idx_mirror_topic_created_at ON quest_dis_mirror_topic(created_at);
+	CREATE INDEX IF NOT EXISTS idx_mirror_message_topic ON quest_dis_mirror_message(topic_did, topic_rkey);
+	CREATE INDEX IF NOT EXISTS idx_label_uri ON quest_dis_label(uri, seq);
+	CREATE INDEX IF NOT EXISTS idx_access_token_did ON quest_dis_access_token(did);
 	`
 
 	_, err := db.Exec(schema)
@@ -14,7 +14,7 @@ import (
 )
 
 // TestDatabase creates an in-memory SQLite database for testing
-func TestDatabase(t *testing.T) *db.Service {
+func TestDatabase(t testing.TB) *db.Service {
 	t.Helper()
 
 	cfg := &config.Config{
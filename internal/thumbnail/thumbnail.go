@@ -0,0 +1,138 @@
+// Package thumbnail scales images down for the blob proxy. It uses only the
+// standard library's codecs: it reads JPEG, PNG and GIF, and writes JPEG for
+// opaque images and PNG for ones with transparency.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers GIF decoding
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// maxPixels bounds the images decoded, so a small file claiming huge
+	// dimensions cannot exhaust memory. 16 MP covers phone camera photos.
+	maxPixels = 16_000_000
+	// jpegQuality is the quality opaque thumbnails are encoded at
+	jpegQuality = 80
+)
+
+var (
+	// ErrUnsupported is returned for images in formats the standard library
+	// cannot decode, such as WebP
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrTooLarge is returned for images with more than maxPixels pixels
+	ErrTooLarge = errors.New("image too large to thumbnail")
+)
+
+// Thumbnail is an encoded, scaled-down image
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+}
+
+// Make decodes data and encodes it again at most width pixels wide. Images
+// narrower than width keep their size.
+func Make(data []byte, width int) (*Thumbnail, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	dst := Resize(src, width)
+
+	var buf bytes.Buffer
+	if dst.Opaque() {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		return &Thumbnail{Data: buf.Bytes(), ContentType: "image/jpeg"}, err
+	}
+	err = png.Encode(&buf, dst)
+	return &Thumbnail{Data: buf.Bytes(), ContentType: "image/png"}, err
+}
+
+// Resize scales src down to width pixels wide, keeping its aspect ratio, by
+// averaging the source pixels each destination pixel covers. Images
+// narrower than width keep their size. RGBA and NRGBA images are read in
+// place; others are converted a few rows at a time, so only the result is
+// held at full size.
+func Resize(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dw, dh := sw, sh
+	if width > 0 && sw > width {
+		dw = width
+		dh = max((sh*dw+sw/2)/sw, 1)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	rows := rowReader{src: src}
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, max((dy+1)*sh/dh, dy*sh/dh+1)
+		pix, stride, premultiplied := rows.read(y0, y1)
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, max((dx+1)*sw/dw, dx*sw/dw+1)
+			var sum [4]int
+			for y := 0; y < y1-y0; y++ {
+				row := pix[y*stride:]
+				for x := x0; x < x1; x++ {
+					p := row[4*x : 4*x+4]
+					a := int(p[3])
+					if premultiplied {
+						sum[0] += int(p[0])
+						sum[1] += int(p[1])
+						sum[2] += int(p[2])
+					} else {
+						sum[0] += int(p[0]) * a / 0xff
+						sum[1] += int(p[1]) * a / 0xff
+						sum[2] += int(p[2]) * a / 0xff
+					}
+					sum[3] += a
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			out := dst.Pix[dy*dst.Stride+4*dx:]
+			for c := range sum {
+				out[c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// rowReader returns runs of an image's rows as 8-bit RGBA pixels
+type rowReader struct {
+	src  image.Image
+	band *image.RGBA
+}
+
+// read returns the pixels of rows y0 to y1 of the image, counted from its
+// top, with their stride and whether their colour is premultiplied by alpha
+func (r *rowReader) read(y0, y1 int) ([]byte, int, bool) {
+	bounds := r.src.Bounds()
+	switch src := r.src.(type) {
+	case *image.RGBA:
+		return src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y0):], src.Stride, true
+	case *image.NRGBA:
+		return src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y0):], src.Stride, false
+	}
+	rect := image.Rect(0, 0, bounds.Dx(), y1-y0)
+	if r.band == nil || r.band.Rect.Dy() < rect.Dy() {
+		r.band = image.NewRGBA(rect)
+	}
+	draw.Draw(r.band, rect, r.src, image.Pt(bounds.Min.X, bounds.Min.Y+y0), draw.Src)
+	return r.band.Pix, r.band.Stride, true
+}
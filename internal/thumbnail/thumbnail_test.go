@@ -0,0 +1,116 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMake(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			opaque.Set(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	thumb, err := Make(encodePNG(t, opaque), 100)
+	if err != nil {
+		t.Fatalf("Make: %v", err)
+	}
+	if thumb.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %s, want image/jpeg for an opaque image", thumb.ContentType)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb.Data))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(100, 50) {
+		t.Errorf("thumbnail size = %v, want 100x50", got)
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+	thumb, err = Make(encodePNG(t, transparent), 100)
+	if err != nil {
+		t.Fatalf("Make: %v", err)
+	}
+	if thumb.ContentType != "image/png" {
+		t.Errorf("ContentType = %s, want image/png for a transparent image", thumb.ContentType)
+	}
+
+	// Images are never scaled up
+	thumb, err = Make(encodePNG(t, image.NewRGBA(image.Rect(0, 0, 40, 30))), 100)
+	if err != nil {
+		t.Fatalf("Make: %v", err)
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb.Data)); err != nil || cfg.Width != 40 || cfg.Height != 30 {
+		t.Errorf("small image became %dx%d, %v", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestMakeRejects(t *testing.T) {
+	if _, err := Make([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), 100); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Make(WebP) = %v, want ErrUnsupported", err)
+	}
+
+	// A tiny GIF whose header claims 65535x65535 pixels
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	huge := buf.Bytes()
+	copy(huge[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := Make(huge, 100); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Make(huge GIF) = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestResizeAverages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.White)
+	src.Set(1, 1, color.White)
+	src.Set(1, 0, color.Black)
+	src.Set(0, 1, color.Black)
+	got := Resize(src, 1).RGBAAt(0, 0)
+	if got.R != 0x80 || got.A != 0xff {
+		t.Errorf("average of two white and two black pixels = %v, want mid grey", got)
+	}
+
+	// NRGBA pixels are weighted by their alpha, read in place
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	nrgba.SetNRGBA(0, 0, color.NRGBA{0xff, 0, 0, 0xff})
+	nrgba.SetNRGBA(1, 0, color.NRGBA{0, 0xff, 0, 0})
+	if got := Resize(nrgba, 1).RGBAAt(0, 0); got != (color.RGBA{0x80, 0, 0, 0x80}) {
+		t.Errorf("average of opaque red and transparent green = %v, want half-transparent red", got)
+	}
+
+	// Other images are converted a band of rows at a time
+	paletted := image.NewPaletted(image.Rect(10, 20, 14, 26), color.Palette{color.Black, color.White})
+	for y := 20; y < 26; y++ {
+		for x := 10; x < 14; x++ {
+			paletted.SetColorIndex(x, y, uint8(y%2))
+		}
+	}
+	resized := Resize(paletted, 2)
+	if resized.Bounds() != image.Rect(0, 0, 2, 3) {
+		t.Fatalf("resized bounds = %v, want 2x3", resized.Bounds())
+	}
+	for y := 0; y < 3; y++ {
+		if got := resized.RGBAAt(1, y); got.R != 0x80 || got.A != 0xff {
+			t.Errorf("row %d = %v, want mid grey", y, got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package trending ranks the topics and tags with the most activity over
+// sliding windows. Rankings are recomputed periodically and cached, so
+// serving them never touches the database.
+package trending
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Window is a span of recent activity that rankings are computed over
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Windows are the sliding windows every snapshot covers, shortest first
+var Windows = []Window{
+	{Name: "6h", Duration: 6 * time.Hour},
+	{Name: "24h", Duration: 24 * time.Hour},
+	{Name: "7d", Duration: 7 * 24 * time.Hour},
+}
+
+// Lookup returns the window with the given name
+func Lookup(name string) (Window, bool) {
+	for _, window := range Windows {
+		if window.Name == name {
+			return window, true
+		}
+	}
+	return Window{}, false
+}
+
+// Topic is a topic's activity within a window
+type Topic struct {
+	TopicDid     string `json:"topic_did"`
+	TopicRkey    string `json:"topic_rkey"`
+	Subject      string `json:"subject"`
+	Messages     int64  `json:"messages"`
+	Participants int64  `json:"participants"`
+}
+
+// Tag is the activity across all topics with a tag within a window
+type Tag struct {
+	Tag      string `json:"tag"`
+	Messages int64  `json:"messages"`
+	Topics   int64  `json:"topics"`
+}
+
+// Ranking is the trending topics and tags for one window, most active first
+type Ranking struct {
+	Window string    `json:"window"`
+	Since  time.Time `json:"since"`
+	Topics []Topic   `json:"topics"`
+	Tags   []Tag     `json:"tags"`
+}
+
+// Snapshot is the rankings for every window as of ComputedAt. A zero
+// ComputedAt means nothing has been computed yet.
+type Snapshot struct {
+	ComputedAt time.Time `json:"computed_at"`
+	Windows    []Ranking `json:"windows"`
+}
+
+// Window returns the ranking for the named window. A known window that has
+// not been computed yet is returned empty.
+func (s Snapshot) Window(name string) (Ranking, bool) {
+	for _, ranking := range s.Windows {
+		if ranking.Window == name {
+			return ranking, true
+		}
+	}
+	if _, ok := Lookup(name); !ok {
+		return Ranking{}, false
+	}
+	return Ranking{Window: name, Topics: []Topic{}, Tags: []Tag{}}, true
+}
+
+// Source ranks activity since a point in time, most active first
+type Source interface {
+	Topics(ctx context.Context, since time.Time, limit int) ([]Topic, error)
+	Tags(ctx context.Context, since time.Time, limit int) ([]Tag, error)
+}
+
+// Cache holds the latest snapshot computed from a source
+type Cache struct {
+	source Source
+	limit  int
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// NewCache creates a cache ranking up to limit topics and tags per window.
+// It is empty until the first Refresh.
+func NewCache(source Source, limit int) *Cache {
+	return &Cache{
+		source:   source,
+		limit:    limit,
+		now:      time.Now,
+		snapshot: Snapshot{Windows: []Ranking{}},
+	}
+}
+
+// Snapshot returns the most recently computed rankings
+func (c *Cache) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot
+}
+
+// Refresh recomputes every window. If any window fails the previous
+// snapshot is kept, so readers never see a partial ranking.
+func (c *Cache) Refresh(ctx context.Context) error {
+	now := c.now()
+	snapshot := Snapshot{ComputedAt: now, Windows: make([]Ranking, 0, len(Windows))}
+	for _, window := range Windows {
+		since := now.Add(-window.Duration)
+		topics, err := c.source.Topics(ctx, since, c.limit)
+		if err != nil {
+			return fmt.Errorf("trending topics for %s: %w", window.Name, err)
+		}
+		tags, err := c.source.Tags(ctx, since, c.limit)
+		if err != nil {
+			return fmt.Errorf("trending tags for %s: %w", window.Name, err)
+		}
+		snapshot.Windows = append(snapshot.Windows, Ranking{Window: window.Name, Since: since, Topics: topics, Tags: tags})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot = snapshot
+	return nil
+}
+
+// Run refreshes immediately and then every interval until ctx is done.
+// Refresh errors are passed to onError.
+func (c *Cache) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	refresh := func() {
+		if err := c.Refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			onError(err)
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
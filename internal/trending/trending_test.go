@@ -0,0 +1,83 @@
+package trending
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeSource returns one topic and tag per query and records the windows
+// it was asked for
+type fakeSource struct {
+	since []time.Time
+	err   error
+}
+
+func (s *fakeSource) Topics(_ context.Context, since time.Time, limit int) ([]Topic, error) {
+	s.since = append(s.since, since)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []Topic{{TopicDid: "did:plc:a", TopicRkey: "t1", Messages: int64(limit)}}, nil
+}
+
+func (s *fakeSource) Tags(_ context.Context, _ time.Time, _ int) ([]Tag, error) {
+	return []Tag{{Tag: "go", Messages: 1, Topics: 1}}, nil
+}
+
+func TestCache_Refresh(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	cache := NewCache(source, 5)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	if snapshot := cache.Snapshot(); !snapshot.ComputedAt.IsZero() || len(snapshot.Windows) != 0 {
+		t.Fatalf("expected an empty snapshot before the first refresh, got %+v", snapshot)
+	}
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	snapshot := cache.Snapshot()
+	if !snapshot.ComputedAt.Equal(now) || len(snapshot.Windows) != len(Windows) {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	for i, window := range Windows {
+		ranking, ok := snapshot.Window(window.Name)
+		if !ok {
+			t.Fatalf("missing window %s", window.Name)
+		}
+		if want := now.Add(-window.Duration); !ranking.Since.Equal(want) || !source.since[i].Equal(want) {
+			t.Errorf("window %s covers since %v, want %v", window.Name, ranking.Since, want)
+		}
+		if len(ranking.Topics) != 1 || ranking.Topics[0].Messages != 5 || len(ranking.Tags) != 1 {
+			t.Errorf("window %s = %+v", window.Name, ranking)
+		}
+	}
+	if _, ok := snapshot.Window("1y"); ok {
+		t.Error("expected an unknown window to be missing")
+	}
+	if ranking, ok := (Snapshot{}).Window("6h"); !ok || ranking.Topics == nil {
+		t.Errorf("expected an empty ranking for a known window before the first refresh, got %+v", ranking)
+	}
+}
+
+func TestCache_RefreshKeepsSnapshotOnError(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	cache := NewCache(source, 5)
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	before := cache.Snapshot()
+
+	source.err = errors.New("database down")
+	if err := cache.Refresh(ctx); !errors.Is(err, source.err) {
+		t.Fatalf("Refresh error = %v, want %v", err, source.err)
+	}
+	if after := cache.Snapshot(); !after.ComputedAt.Equal(before.ComputedAt) || len(after.Windows) != len(before.Windows) {
+		t.Errorf("expected the previous snapshot to be kept, got %+v", after)
+	}
+}
@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error codes identify what failed independently of the human-readable
+// message, so clients can localize errors themselves
+const (
+	CodeRequired         = "required"
+	CodeMinLength        = "min_length"
+	CodeMaxLength        = "max_length"
+	CodeInvalidDID       = "invalid_did"
+	CodeInvalidDIDFormat = "invalid_did_format"
+	CodeNoSpaces         = "no_spaces"
+	CodeOneOf            = "one_of"
+	CodeInvalidSlug      = "invalid_slug"
+)
+
+// Catalog maps error codes to message templates. Templates reference error
+// params by name in braces, e.g. "must not exceed {max} characters".
+type Catalog map[string]string
+
+// DefaultCatalog holds the English messages used when building errors
+var DefaultCatalog = Catalog{
+	CodeRequired:         "is required",
+	CodeMinLength:        "must be at least {min} characters",
+	CodeMaxLength:        "must not exceed {max} characters",
+	CodeInvalidDID:       "must be a valid DID",
+	CodeInvalidDIDFormat: "must be a valid DID format",
+	CodeNoSpaces:         "cannot contain spaces",
+	CodeOneOf:            "must be one of: {allowed}",
+	CodeInvalidSlug:      "must contain only lowercase letters, numbers and hyphens",
+}
+
+// Format renders the message for code, falling back to the default catalog
+// and finally to the code itself
+func (c Catalog) Format(code string, params map[string]any) string {
+	tmpl, ok := c[code]
+	if !ok {
+		tmpl, ok = DefaultCatalog[code]
+	}
+	if !ok {
+		return code
+	}
+	for name, value := range params {
+		tmpl = strings.ReplaceAll(tmpl, "{"+name+"}", fmt.Sprint(value))
+	}
+	return tmpl
+}
+
+// Localize returns a copy of the errors with messages rendered from catalog
+func (ve Errors) Localize(catalog Catalog) Errors {
+	out := make(Errors, len(ve))
+	for i, err := range ve {
+		out[i] = err
+		if err.Code != "" {
+			out[i].Message = catalog.Format(err.Code, err.Params)
+		}
+	}
+	return out
+}
+
+func newError(code string, params map[string]any) *Error {
+	return &Error{
+		Code:    code,
+		Message: DefaultCatalog.Format(code, params),
+		Params:  params,
+	}
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule checks a single value and returns a field error, or nil if the value
+// is valid. The Field of the returned error is filled in by the Validator.
+type Rule func(value string) *Error
+
+// Required rejects empty or whitespace-only values
+func Required() Rule {
+	return func(value string) *Error {
+		if strings.TrimSpace(value) == "" {
+			return newError(CodeRequired, nil)
+		}
+		return nil
+	}
+}
+
+// MinLength rejects values shorter than min characters, ignoring surrounding whitespace
+func MinLength(min int) Rule {
+	return func(value string) *Error {
+		if utf8.RuneCountInString(strings.TrimSpace(value)) < min {
+			return newError(CodeMinLength, map[string]any{"min": min})
+		}
+		return nil
+	}
+}
+
+// MaxLength rejects values longer than max characters
+func MaxLength(max int) Rule {
+	return func(value string) *Error {
+		if utf8.RuneCountInString(value) > max {
+			return newError(CodeMaxLength, map[string]any{"max": max})
+		}
+		return nil
+	}
+}
+
+// DID rejects values that are not shaped like a DID (did:method:identifier)
+func DID() Rule {
+	return func(value string) *Error {
+		if !strings.HasPrefix(value, "did:") {
+			return newError(CodeInvalidDID, nil)
+		}
+		if len(strings.Split(value, ":")) < 3 {
+			return newError(CodeInvalidDIDFormat, nil)
+		}
+		return nil
+	}
+}
+
+// Rkey rejects values that cannot be used as a record key
+func Rkey() Rule {
+	return func(value string) *Error {
+		if strings.TrimSpace(value) == "" {
+			return newError(CodeRequired, nil)
+		}
+		if strings.Contains(value, " ") {
+			return newError(CodeNoSpaces, nil)
+		}
+		if len(value) > 100 {
+			return newError(CodeMaxLength, map[string]any{"max": 100})
+		}
+		return nil
+	}
+}
+
+// OneOf rejects values that are not in the allowed set
+func OneOf(allowed ...string) Rule {
+	return func(value string) *Error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return newError(CodeOneOf, map[string]any{"allowed": strings.Join(allowed, ", ")})
+	}
+}
+
+// Slug rejects values that are not lowercase letters, digits and inner
+// hyphens, so they can be used unescaped in URLs
+func Slug() Rule {
+	return func(value string) *Error {
+		if value == "" || strings.HasPrefix(value, "-") || strings.HasSuffix(value, "-") || strings.Contains(value, "--") {
+			return newError(CodeInvalidSlug, nil)
+		}
+		for _, c := range value {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return newError(CodeInvalidSlug, nil)
+			}
+		}
+		return nil
+	}
+}
@@ -4,13 +4,15 @@ package validation
 import (
 	"fmt"
 	"strings"
-	"unicode/utf8"
 )
 
-// Error represents a validation error with field-specific details
+// Error represents a validation error with field-specific details. Code and
+// Params identify the failure so clients can render their own messages.
 type Error struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+	Field   string         `json:"field"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
 }
 
 // Errors represents multiple validation errors
@@ -46,82 +48,35 @@ func (ve Errors) HasErrors() bool {
 
 // ValidateRequired checks if a value is not empty
 func ValidateRequired(value string, fieldName string) *Error {
-	if strings.TrimSpace(value) == "" {
-		return &Error{
-			Field:   fieldName,
-			Message: "is required",
-		}
-	}
-	return nil
+	return applyRule(Required(), value, fieldName)
 }
 
 // ValidateMaxLength checks if a string doesn't exceed the maximum length
 func ValidateMaxLength(value string, maxLength int, fieldName string) *Error {
-	if utf8.RuneCountInString(value) > maxLength {
-		return &Error{
-			Field:   fieldName,
-			Message: fmt.Sprintf("must not exceed %d characters", maxLength),
-		}
-	}
-	return nil
+	return applyRule(MaxLength(maxLength), value, fieldName)
 }
 
 // ValidateMinLength checks if a string meets the minimum length
 func ValidateMinLength(value string, minLength int, fieldName string) *Error {
-	if utf8.RuneCountInString(strings.TrimSpace(value)) < minLength {
-		return &Error{
-			Field:   fieldName,
-			Message: fmt.Sprintf("must be at least %d characters", minLength),
-		}
-	}
-	return nil
+	return applyRule(MinLength(minLength), value, fieldName)
 }
 
 // ValidateDID checks if a string looks like a valid DID
 func ValidateDID(value string, fieldName string) *Error {
-	if !strings.HasPrefix(value, "did:") {
-		return &Error{
-			Field:   fieldName,
-			Message: "must be a valid DID",
-		}
-	}
-
-	parts := strings.Split(value, ":")
-	if len(parts) < 3 {
-		return &Error{
-			Field:   fieldName,
-			Message: "must be a valid DID format",
-		}
-	}
-
-	return nil
+	return applyRule(DID(), value, fieldName)
 }
 
 // ValidateRkey checks if a string is a valid record key
 func ValidateRkey(value string, fieldName string) *Error {
-	if strings.TrimSpace(value) == "" {
-		return &Error{
-			Field:   fieldName,
-			Message: "is required",
-		}
-	}
-
-	// Basic rkey validation - no spaces, reasonable length
-	if strings.Contains(value, " ") {
-		return &Error{
-			Field:   fieldName,
-			Message: "cannot contain spaces",
-		}
-	}
+	return applyRule(Rkey(), value, fieldName)
+}
 
-	if len(value) > 100 {
-		return &Error{
-			Field:   fieldName,
-			Message: "must not exceed 100 characters",
-		}
+func applyRule(rule Rule, value, fieldName string) *Error {
+	err := rule(value)
+	if err != nil {
+		err.Field = fieldName
 	}
-
-	return nil
+	return err
 }
 
 // TopicValidation validates topic creation parameters
@@ -133,78 +88,74 @@ type TopicValidation struct {
 
 // Validate validates topic fields
 func (tv *TopicValidation) Validate() error {
-	var errors Errors
-
-	// Validate subject
-	if err := ValidateRequired(tv.Subject, "subject"); err != nil {
-		errors.Add(err.Field, err.Message)
-	} else {
-		if err := ValidateMinLength(tv.Subject, 3, "subject"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-		if err := ValidateMaxLength(tv.Subject, 200, "subject"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-	}
+	return New().
+		Field("subject", tv.Subject, Required(), MinLength(3), MaxLength(200)).
+		Field("initial_message", tv.InitialMessage, Required(), MinLength(10), MaxLength(5000)).
+		OptionalField("category", tv.Category, MaxLength(50)).
+		Err()
+}
 
-	// Validate initial message
-	if err := ValidateRequired(tv.InitialMessage, "initial_message"); err != nil {
-		errors.Add(err.Field, err.Message)
-	} else {
-		if err := ValidateMinLength(tv.InitialMessage, 10, "initial_message"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-		if err := ValidateMaxLength(tv.InitialMessage, 5000, "initial_message"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-	}
+// CommunityValidation validates community creation parameters
+type CommunityValidation struct {
+	Slug        string
+	Name        string
+	Description string
+}
 
-	// Validate category (optional)
-	if tv.Category != "" {
-		if err := ValidateMaxLength(tv.Category, 50, "category"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-	}
+// Validate validates community fields
+func (cv *CommunityValidation) Validate() error {
+	return New().
+		Field("slug", cv.Slug, Required(), MinLength(2), MaxLength(32), Slug()).
+		Field("name", cv.Name, Required(), MaxLength(100)).
+		OptionalField("description", cv.Description, MaxLength(500)).
+		Err()
+}
 
-	if errors.HasErrors() {
-		return errors
-	}
+// CategoryValidation validates a community category
+type CategoryValidation struct {
+	Slug string
+	Name string
+}
 
-	return nil
+// Validate validates category fields
+func (cv *CategoryValidation) Validate() error {
+	return New().
+		Field("slug", cv.Slug, Required(), MaxLength(50), Slug()).
+		Field("name", cv.Name, Required(), MaxLength(50)).
+		Err()
 }
 
 // MessageValidation validates message creation parameters
 type MessageValidation struct {
 	Content           string
 	ParentMessageRkey string
+	ClientID          string
 }
 
 // Validate validates message fields
 func (mv *MessageValidation) Validate() error {
-	var errors Errors
-
-	// Validate content
-	if err := ValidateRequired(mv.Content, "content"); err != nil {
-		errors.Add(err.Field, err.Message)
-	} else {
-		if err := ValidateMinLength(mv.Content, 1, "content"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-		if err := ValidateMaxLength(mv.Content, 2000, "content"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-	}
+	return New().
+		Field("content", mv.Content, Required(), MinLength(1), MaxLength(2000)).
+		OptionalField("parent_message_rkey", mv.ParentMessageRkey, Rkey()).
+		OptionalField("client_id", mv.ClientID, MaxLength(64)).
+		Err()
+}
 
-	// Validate parent message rkey (optional)
-	if mv.ParentMessageRkey != "" {
-		if err := ValidateRkey(mv.ParentMessageRkey, "parent_message_rkey"); err != nil {
-			errors.Add(err.Field, err.Message)
-		}
-	}
+// Participation statuses accepted from clients
+const (
+	ParticipationActive    = "active"
+	ParticipationFollowing = "following"
+	ParticipationMuted     = "muted"
+)
 
-	if errors.HasErrors() {
-		return errors
-	}
+// ParticipationValidation validates participation updates
+type ParticipationValidation struct {
+	Status string
+}
 
-	return nil
+// Validate validates participation fields
+func (pv *ParticipationValidation) Validate() error {
+	return New().
+		Field("status", pv.Status, Required(), OneOf(ParticipationActive, ParticipationFollowing, ParticipationMuted)).
+		Err()
 }
@@ -0,0 +1,178 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTopicValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     TopicValidation
+		wantCodes map[string]string
+	}{
+		{
+			name:  "valid",
+			input: TopicValidation{Subject: "A subject", InitialMessage: "A long enough message"},
+		},
+		{
+			name:      "missing subject reports only required",
+			input:     TopicValidation{InitialMessage: "A long enough message"},
+			wantCodes: map[string]string{"subject": CodeRequired},
+		},
+		{
+			name:      "short fields",
+			input:     TopicValidation{Subject: "ab", InitialMessage: "short"},
+			wantCodes: map[string]string{"subject": CodeMinLength, "initial_message": CodeMinLength},
+		},
+		{
+			name:      "long category",
+			input:     TopicValidation{Subject: "A subject", InitialMessage: "A long enough message", Category: strings.Repeat("c", 51)},
+			wantCodes: map[string]string{"category": CodeMaxLength},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCodes(t, tt.input.Validate(), tt.wantCodes)
+		})
+	}
+}
+
+func TestMessageValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     MessageValidation
+		wantCodes map[string]string
+	}{
+		{name: "valid", input: MessageValidation{Content: "hi"}},
+		{name: "empty content", input: MessageValidation{}, wantCodes: map[string]string{"content": CodeRequired}},
+		{name: "parent rkey with spaces", input: MessageValidation{Content: "hi", ParentMessageRkey: "a b"}, wantCodes: map[string]string{"parent_message_rkey": CodeNoSpaces}},
+		{name: "long client id", input: MessageValidation{Content: "hi", ClientID: strings.Repeat("x", 65)}, wantCodes: map[string]string{"client_id": CodeMaxLength}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCodes(t, tt.input.Validate(), tt.wantCodes)
+		})
+	}
+}
+
+func TestParticipationValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		wantCodes map[string]string
+	}{
+		{name: "following", status: ParticipationFollowing},
+		{name: "muted", status: ParticipationMuted},
+		{name: "missing", status: "", wantCodes: map[string]string{"status": CodeRequired}},
+		{name: "unknown", status: "banned", wantCodes: map[string]string{"status": CodeOneOf}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := ParticipationValidation{Status: tt.status}
+			assertCodes(t, pv.Validate(), tt.wantCodes)
+		})
+	}
+}
+
+func TestCommunityValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		cv        CommunityValidation
+		wantCodes map[string]string
+	}{
+		{name: "valid", cv: CommunityValidation{Slug: "go-lang", Name: "Go"}},
+		{name: "missing slug", cv: CommunityValidation{Name: "Go"}, wantCodes: map[string]string{"slug": CodeRequired}},
+		{name: "short slug", cv: CommunityValidation{Slug: "g", Name: "Go"}, wantCodes: map[string]string{"slug": CodeMinLength}},
+		{name: "uppercase slug", cv: CommunityValidation{Slug: "GoLang", Name: "Go"}, wantCodes: map[string]string{"slug": CodeInvalidSlug}},
+		{name: "trailing hyphen", cv: CommunityValidation{Slug: "go-", Name: "Go"}, wantCodes: map[string]string{"slug": CodeInvalidSlug}},
+		{name: "missing name", cv: CommunityValidation{Slug: "go"}, wantCodes: map[string]string{"name": CodeRequired}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertCodes(t, tt.cv.Validate(), tt.wantCodes)
+		})
+	}
+}
+
+func TestErrorsLocalize(t *testing.T) {
+	err := (&TopicValidation{Subject: "ab", InitialMessage: "A long enough message"}).Validate()
+	var ve Errors
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if ve[0].Message != "must be at least 3 characters" {
+		t.Errorf("unexpected default message %q", ve[0].Message)
+	}
+
+	localized := ve.Localize(Catalog{CodeMinLength: "doit contenir au moins {min} caractères"})
+	if localized[0].Message != "doit contenir au moins 3 caractères" {
+		t.Errorf("unexpected localized message %q", localized[0].Message)
+	}
+	if ve[0].Message != "must be at least 3 characters" {
+		t.Error("Localize must not modify the original errors")
+	}
+
+	// Codes missing from a catalog fall back to English
+	fallback := ve.Localize(Catalog{})
+	if fallback[0].Message != ve[0].Message {
+		t.Errorf("expected fallback message %q, got %q", ve[0].Message, fallback[0].Message)
+	}
+}
+
+func assertCodes(t *testing.T, err error, want map[string]string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("expected no errors, got %v", err)
+		}
+		return
+	}
+	var ve Errors
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if len(ve) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(ve), ve)
+	}
+	for _, e := range ve {
+		if want[e.Field] != e.Code {
+			t.Errorf("field %s: expected code %q, got %q", e.Field, want[e.Field], e.Code)
+		}
+	}
+}
+
+func BenchmarkTopicValidation(b *testing.B) {
+	tv := TopicValidation{
+		Subject:        "How should we shard the index?",
+		InitialMessage: strings.Repeat("Some context about the question. ", 40),
+		Category:       "infrastructure",
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := tv.Validate(); err != nil {
+			b.Fatalf("Validate error: %v", err)
+		}
+	}
+}
+
+func BenchmarkMessageValidation(b *testing.B) {
+	mv := MessageValidation{
+		Content:           strings.Repeat("A reasonably long reply. ", 20),
+		ParentMessageRkey: "3jui7kd54zh2y",
+		ClientID:          "client-123",
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := mv.Validate(); err != nil {
+			b.Fatalf("Validate error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package validation
+
+// Validator collects field errors from a sequence of rule checks
+type Validator struct {
+	errors Errors
+}
+
+// New creates an empty Validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Field applies rules to a value in order, recording only the first failure
+// so a missing value is not also reported as too short
+func (v *Validator) Field(field, value string, rules ...Rule) *Validator {
+	for _, rule := range rules {
+		if err := rule(value); err != nil {
+			err.Field = field
+			v.errors = append(v.errors, *err)
+			break
+		}
+	}
+	return v
+}
+
+// OptionalField applies rules only when the value is non-empty
+func (v *Validator) OptionalField(field, value string, rules ...Rule) *Validator {
+	if value == "" {
+		return v
+	}
+	return v.Field(field, value, rules...)
+}
+
+// Errors returns the collected field errors
+func (v *Validator) Errors() Errors {
+	return v.errors
+}
+
+// Err returns the collected errors as an error, or nil if there are none
+func (v *Validator) Err() error {
+	if v.errors.HasErrors() {
+		return v.errors
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+// Package websocket implements the WebSocket protocol (RFC 6455) for
+// push-style streams: the server writes messages and reads only what it
+// needs to answer pings and close handshakes. Dial opens the client side,
+// which is used to follow another server's stream. Extensions such as
+// permessage-deflate are not negotiated.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- RFC 6455 mandates SHA-1 for the accept key
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is the fixed GUID RFC 6455 appends to the client key
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	// writeWait bounds how long a single frame write may block on a slow peer
+	writeWait = 10 * time.Second
+	// maxMessageSize caps messages read from the peer
+	maxMessageSize = 64 << 10
+)
+
+// Message opcodes
+const (
+	OpText   = 0x1
+	OpBinary = 0x2
+
+	opContinuation = 0x0
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close status codes
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+var (
+	// ErrClosed is returned once the connection has been closed by either side
+	ErrClosed = errors.New("websocket: connection closed")
+	// ErrNotWebSocket is returned by Upgrade when the request is not a valid
+	// WebSocket handshake; a 4xx response has already been written
+	ErrNotWebSocket = errors.New("websocket: not a websocket handshake")
+
+	errProtocol = errors.New("websocket: protocol error")
+	errTooBig   = errors.New("websocket: message too big")
+)
+
+// Conn is an upgraded WebSocket connection. Writes are safe for concurrent
+// use; ReadMessage must only be called from one goroutine.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// client is set on dialed connections, which mask what they send and
+	// expect unmasked frames back
+	client bool
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Upgrade completes the WebSocket handshake and takes over the connection.
+// On failure an error response has been written and no Conn is returned.
+func Upgrade(w http.ResponseWriter, req *http.Request) (*Conn, error) {
+	if req.Method != http.MethodGet ||
+		!headerHasToken(req.Header, "Connection", "upgrade") ||
+		!headerHasToken(req.Header, "Upgrade", "websocket") {
+		http.Error(w, "Expected WebSocket upgrade", http.StatusUpgradeRequired)
+		return nil, ErrNotWebSocket
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrNotWebSocket
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrNotWebSocket
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("websocket: hijack failed: %w", err)
+	}
+	// Clear the server's read and write timeouts; streams are long lived
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+// acceptKey derives the Sec-WebSocket-Accept value for a client key
+func acceptKey(key string) string {
+	h := sha1.New() // #nosec G401 -- required by RFC 6455
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerHasToken reports whether a comma-separated header contains token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteText sends data as a single text message
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(OpText, data)
+}
+
+// WriteBinary sends data as a single binary message
+func (c *Conn) WriteBinary(data []byte) error {
+	return c.writeFrame(OpBinary, data)
+}
+
+// Ping sends a ping, which keeps intermediaries from timing out idle streams
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame with code and reason, then closes the
+// connection. Only the first call has any effect.
+func (c *Conn) Close(code int, reason string) error {
+	err := ErrClosed
+	c.closeOnce.Do(func() {
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		payload := binary.BigEndian.AppendUint16(nil, uint16(code)) // #nosec G115 -- close codes fit in 16 bits
+		_ = c.writeFrame(opClose, append(payload, reason...))
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(n)) // #nosec G115 -- n <= 0xFFFF
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(n)) // #nosec G115 -- n is non-negative
+	}
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		header[1] |= 0x80
+		header = append(header, mask[:]...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return ErrClosed
+	}
+	buffers := net.Buffers{header, payload}
+	if _, err := buffers.WriteTo(c.conn); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadMessage returns the next text or binary message from the peer,
+// answering pings and close frames along the way. It returns ErrClosed once
+// the peer has closed the connection.
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	var message []byte
+	var messageOp byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			switch {
+			case errors.Is(err, errTooBig):
+				_ = c.Close(CloseMessageTooBig, "")
+			case errors.Is(err, errProtocol):
+				_ = c.Close(CloseProtocolError, "")
+			case errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+				_ = c.conn.Close()
+				return 0, nil, ErrClosed
+			}
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = c.Close(CloseNormal, "")
+			return 0, nil, ErrClosed
+		case opContinuation:
+			if messageOp == 0 {
+				_ = c.Close(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+			message = append(message, payload...)
+		case OpText, OpBinary:
+			if messageOp != 0 {
+				_ = c.Close(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+			messageOp = op
+			message = payload
+		default:
+			_ = c.Close(CloseProtocolError, "")
+			return 0, nil, errProtocol
+		}
+
+		if len(message) > maxMessageSize {
+			_ = c.Close(CloseMessageTooBig, "")
+			return 0, nil, errTooBig
+		}
+		if fin {
+			return int(messageOp), message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame, unmasking it if it came from a client
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, errProtocol
+	}
+	// Clients must mask every frame they send and servers must not
+	masked := head[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, errProtocol
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, errProtocol
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
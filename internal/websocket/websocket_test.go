@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient speaks just enough of the client side of RFC 6455 to exercise Conn
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, url string) (*testClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, _ = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n"+
+		"Connection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse: %v", err)
+	}
+	return &testClient{conn: conn, reader: reader}, resp
+}
+
+func (c *testClient) send(opcode byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, _ = c.conn.Write(frame)
+}
+
+func (c *testClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(c.reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestConn(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := Upgrade(w, req)
+		if err != nil {
+			return
+		}
+		_ = conn.WriteText([]byte(strings.Repeat("x", 200)))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- string(data)
+		}
+	}))
+	defer srv.Close()
+
+	client, resp := dial(t, srv.URL)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept header %q", got)
+	}
+
+	t.Run("Server writes text", func(t *testing.T) {
+		op, payload := client.read(t)
+		if op != OpText || len(payload) != 200 {
+			t.Errorf("Expected 200 byte text frame, got opcode %d with %d bytes", op, len(payload))
+		}
+	})
+
+	t.Run("Ping is answered", func(t *testing.T) {
+		client.send(opPing, []byte("hi"))
+		op, payload := client.read(t)
+		if op != opPong || string(payload) != "hi" {
+			t.Errorf("Expected pong echoing payload, got opcode %d %q", op, payload)
+		}
+	})
+
+	t.Run("Fragmented message is reassembled", func(t *testing.T) {
+		mask := [4]byte{}
+		_, _ = client.conn.Write(append([]byte{OpText, 0x80 | 3}, append(mask[:], "hel"...)...))
+		_, _ = client.conn.Write(append([]byte{0x80 | opContinuation, 0x80 | 2}, append(mask[:], "lo"...)...))
+		if got := <-received; got != "hello" {
+			t.Errorf("Expected hello, got %q", got)
+		}
+	})
+
+	t.Run("Close is echoed", func(t *testing.T) {
+		client.send(opClose, binary.BigEndian.AppendUint16(nil, CloseNormal))
+		op, payload := client.read(t)
+		if op != opClose || binary.BigEndian.Uint16(payload) != CloseNormal {
+			t.Errorf("Expected close frame, got opcode %d %v", op, payload)
+		}
+		if _, ok := <-received; ok {
+			t.Error("Expected ReadMessage to stop after close")
+		}
+	})
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	if _, err := Upgrade(w, httptest.NewRequest("GET", "/", nil)); err != ErrNotWebSocket {
+		t.Errorf("Expected ErrNotWebSocket, got %v", err)
+	}
+	if w.Code != http.StatusUpgradeRequired {
+		t.Errorf("Expected status %d, got %d", http.StatusUpgradeRequired, w.Code)
+	}
+}
+
+func TestDial(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("cursor") != "42" {
+			http.Error(w, "missing cursor", http.StatusBadRequest)
+			return
+		}
+		conn, err := Upgrade(w, req)
+		if err != nil {
+			return
+		}
+		_ = conn.WriteText([]byte(strings.Repeat("y", 300)))
+		if _, data, err := conn.ReadMessage(); err == nil {
+			received <- string(data)
+		}
+		_ = conn.Close(CloseNormal, "")
+	}))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, wsURL+"/subscribe?cursor=42")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+
+	op, data, err := conn.ReadMessage()
+	if err != nil || op != OpText || len(data) != 300 {
+		t.Fatalf("Expected 300 byte text message, got opcode %d with %d bytes (%v)", op, len(data), err)
+	}
+	// The server rejects unmasked frames, so this only arrives if masked
+	if err := conn.WriteText([]byte("hello")); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	if got := <-received; got != "hello" {
+		t.Errorf("Expected hello, got %q", got)
+	}
+	if _, _, err := conn.ReadMessage(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after the server closed, got %v", err)
+	}
+
+	if _, err := Dial(ctx, wsURL+"/subscribe"); err == nil {
+		t.Error("Expected a failed handshake to return an error")
+	}
+	if _, err := Dial(ctx, srv.URL); err == nil {
+		t.Error("Expected an http URL to be rejected")
+	}
+}
@@ -0,0 +1,10 @@
+// Package lexicons embeds the quest.dis.* lexicon documents so the server
+// and CLI ship the same schemas that live in this directory
+package lexicons
+
+import "embed"
+
+// FS holds one <nsid>.json document per lexicon
+//
+//go:embed *.json
+var FS embed.FS
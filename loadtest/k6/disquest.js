@@ -0,0 +1,94 @@
+// k6 scenarios for the topic index and streaming endpoints.
+//
+//   k6 run loadtest/k6/disquest.js
+//   BASE_URL=https://staging.dis.quest SESSION=<dsq_session> k6 run loadtest/k6/disquest.js
+//
+// Thresholds fail the run when latency or error rates regress, so the
+// script can gate CI against a staging deploy.
+import http from 'k6/http';
+import ws from 'k6/ws';
+import { check, sleep } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:3000';
+const WS_URL = BASE_URL.replace(/^http/, 'ws');
+const SESSION = __ENV.SESSION || '';
+const TOPIC_ID = __ENV.TOPIC_ID || '';
+
+const scenarios = {
+  browse: {
+    executor: 'constant-arrival-rate',
+    exec: 'browse',
+    rate: Number(__ENV.BROWSE_RATE || 50),
+    timeUnit: '1s',
+    duration: __ENV.DURATION || '1m',
+    preAllocatedVUs: 20,
+    maxVUs: 100,
+  },
+  firehose: {
+    executor: 'constant-vus',
+    exec: 'firehose',
+    vus: Number(__ENV.FIREHOSE_VUS || 20),
+    duration: __ENV.DURATION || '1m',
+  },
+};
+
+// Posting needs a session; skip it rather than fail every request
+if (SESSION && TOPIC_ID) {
+  scenarios.post = {
+    executor: 'constant-arrival-rate',
+    exec: 'post',
+    rate: Number(__ENV.POST_RATE || 5),
+    timeUnit: '1s',
+    duration: __ENV.DURATION || '1m',
+    preAllocatedVUs: 5,
+    maxVUs: 20,
+  };
+}
+
+export const options = {
+  scenarios,
+  thresholds: {
+    'http_req_failed': ['rate<0.01'],
+    'http_req_duration{scenario:browse}': ['p(95)<200'],
+    'http_req_duration{scenario:post}': ['p(95)<500'],
+    'checks': ['rate>0.99'],
+  },
+};
+
+const sorts = ['latest', 'active', 'top', 'hot'];
+
+export function browse() {
+  const sort = sorts[Math.floor(Math.random() * sorts.length)];
+  const res = http.get(`${BASE_URL}/api/topics?sort=${sort}&limit=20`, { tags: { name: 'list-topics' } });
+  check(res, { 'topics listed': (r) => r.status === 200 });
+
+  const topics = res.status === 200 ? res.json() : [];
+  if (topics.length > 0) {
+    const topic = topics[Math.floor(Math.random() * topics.length)];
+    const messages = http.get(`${BASE_URL}/api/topics/${topic.did}:${topic.rkey}/messages`, {
+      tags: { name: 'list-messages' },
+    });
+    check(messages, { 'messages listed': (r) => r.status === 200 });
+  }
+}
+
+export function post() {
+  const res = http.post(
+    `${BASE_URL}/api/topics/${TOPIC_ID}/messages`,
+    JSON.stringify({ content: `load test message ${__VU}-${__ITER}` }),
+    {
+      headers: { 'Content-Type': 'application/json', Cookie: `dsq_session=${SESSION}` },
+      tags: { name: 'create-message' },
+    },
+  );
+  check(res, { 'message created': (r) => r.status === 201 });
+}
+
+// firehose holds a Jetstream-style subscription open and counts events
+export function firehose() {
+  const res = ws.connect(`${WS_URL}/subscribe?wantedCollections=quest.dis.*`, null, (socket) => {
+    socket.setTimeout(() => socket.close(), 30000);
+  });
+  check(res, { 'firehose upgraded': (r) => r && r.status === 101 });
+  sleep(1);
+}
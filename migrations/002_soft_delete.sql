@@ -0,0 +1,26 @@
+-- Soft-delete support for topics and messages
+-- Deleted or hidden rows are tombstoned with deleted_at and purged after a grace period
+
+ALTER TABLE quest_dis_topic
+    ADD COLUMN deleted_at TIMESTAMP,
+    ADD COLUMN deleted_by TEXT; -- DID of the author or admin who removed the row
+
+ALTER TABLE quest_dis_message
+    ADD COLUMN deleted_at TIMESTAMP,
+    ADD COLUMN deleted_by TEXT;
+
+CREATE INDEX idx_quest_dis_topic_deleted_at ON quest_dis_topic(deleted_at);
+CREATE INDEX idx_quest_dis_message_deleted_at ON quest_dis_message(deleted_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_message_deleted_at;
+DROP INDEX IF EXISTS idx_quest_dis_topic_deleted_at;
+
+ALTER TABLE quest_dis_message
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE quest_dis_topic
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,20 @@
+-- Topic ranking for sort=active|top|hot
+-- Maintained incrementally as messages are ingested so list reads stay cheap
+
+ALTER TABLE quest_dis_topic
+    ADD COLUMN message_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_activity_at TIMESTAMP, -- NULL until the first reply; falls back to created_at
+    ADD COLUMN hot_rank DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_quest_dis_topic_hot_rank ON quest_dis_topic(hot_rank);
+CREATE INDEX idx_quest_dis_topic_message_count ON quest_dis_topic(message_count);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_topic_message_count;
+DROP INDEX IF EXISTS idx_quest_dis_topic_hot_rank;
+
+ALTER TABLE quest_dis_topic
+    DROP COLUMN IF EXISTS hot_rank,
+    DROP COLUMN IF EXISTS last_activity_at,
+    DROP COLUMN IF EXISTS message_count;
@@ -0,0 +1,19 @@
+-- Read markers - tracks how far each user has read in each topic
+
+CREATE TABLE quest_dis_read_marker (
+    did TEXT NOT NULL,
+    topic_did TEXT NOT NULL,
+    topic_rkey TEXT NOT NULL,
+    last_read_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (did, topic_did, topic_rkey),
+    FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_quest_dis_read_marker_user ON quest_dis_read_marker(did);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_read_marker_user;
+DROP TABLE IF EXISTS quest_dis_read_marker;
@@ -0,0 +1,15 @@
+-- Firehose events - ordered log of record changes replayed to firehose
+-- subscribers that resume from a cursor
+
+CREATE TABLE quest_dis_firehose_event (
+    time_us BIGINT PRIMARY KEY,
+    did TEXT NOT NULL,
+    collection TEXT NOT NULL,
+    rkey TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    record TEXT
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS quest_dis_firehose_event;
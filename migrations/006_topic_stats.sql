@@ -0,0 +1,22 @@
+-- Topic stats - daily activity totals per topic. Only counts are kept; no
+-- row identifies who viewed or posted.
+
+CREATE TABLE quest_dis_topic_stats (
+    topic_did TEXT NOT NULL,
+    topic_rkey TEXT NOT NULL,
+    day TIMESTAMP NOT NULL,
+    views INTEGER NOT NULL DEFAULT 0,
+    messages INTEGER NOT NULL DEFAULT 0,
+    participants INTEGER NOT NULL DEFAULT 0,
+    first_responses INTEGER NOT NULL DEFAULT 0,
+    response_seconds BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (topic_did, topic_rkey, day),
+    FOREIGN KEY (topic_did, topic_rkey) REFERENCES quest_dis_topic(did, rkey) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_quest_dis_topic_stats_day ON quest_dis_topic_stats(day);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_topic_stats_day;
+DROP TABLE IF EXISTS quest_dis_topic_stats;
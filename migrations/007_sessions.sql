@@ -0,0 +1,21 @@
+-- Sessions - one row per signed-in browser, so users can see where they are
+-- signed in and revoke sessions. Tokens stay in cookies; only the session ID,
+-- owner and coarse client details are stored.
+
+CREATE TABLE quest_dis_session (
+    id TEXT PRIMARY KEY,
+    did TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_quest_dis_session_did ON quest_dis_session(did);
+CREATE INDEX idx_quest_dis_session_last_seen_at ON quest_dis_session(last_seen_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_session_last_seen_at;
+DROP INDEX IF EXISTS idx_quest_dis_session_did;
+DROP TABLE IF EXISTS quest_dis_session;
@@ -0,0 +1,41 @@
+-- Communities - separate discussion spaces hosted by one deployment. Each has
+-- its own category taxonomy and moderators; sign-in is shared. Topics outside
+-- any community keep an empty slug and make up the default space.
+
+CREATE TABLE quest_dis_community (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE quest_dis_community_category (
+    community TEXT NOT NULL REFERENCES quest_dis_community(slug) ON DELETE CASCADE,
+    slug TEXT NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (community, slug)
+);
+
+CREATE TABLE quest_dis_community_moderator (
+    community TEXT NOT NULL REFERENCES quest_dis_community(slug) ON DELETE CASCADE,
+    did TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (community, did)
+);
+
+ALTER TABLE quest_dis_topic ADD COLUMN community TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_quest_dis_topic_community ON quest_dis_topic(community, created_at);
+CREATE INDEX idx_quest_dis_community_moderator_did ON quest_dis_community_moderator(did);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_community_moderator_did;
+DROP INDEX IF EXISTS idx_quest_dis_topic_community;
+
+ALTER TABLE quest_dis_topic DROP COLUMN community;
+
+DROP TABLE IF EXISTS quest_dis_community_moderator;
+DROP TABLE IF EXISTS quest_dis_community_category;
+DROP TABLE IF EXISTS quest_dis_community;
@@ -0,0 +1,20 @@
+-- Custom domains for communities. A domain is claimed with a random token and
+-- only serves the community once the token has been found in a DNS TXT record
+-- or the domain's well-known file.
+
+CREATE TABLE quest_dis_community_domain (
+    domain TEXT PRIMARY KEY,
+    community TEXT NOT NULL REFERENCES quest_dis_community(slug) ON DELETE CASCADE,
+    token TEXT NOT NULL,
+    verification_method TEXT,
+    verified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_quest_dis_community_domain_community ON quest_dis_community_domain(community);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_community_domain_community;
+
+DROP TABLE IF EXISTS quest_dis_community_domain;
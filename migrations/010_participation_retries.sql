@@ -0,0 +1,22 @@
+-- Participation retries - creator participation records that could not be
+-- published when their topic was created. The retry job publishes them with
+-- backoff and deletes the row once one succeeds.
+
+CREATE TABLE quest_dis_participation_retry (
+    did TEXT NOT NULL,
+    topic_did TEXT NOT NULL,
+    topic_rkey TEXT NOT NULL,
+    rkey TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (did, topic_did, topic_rkey)
+);
+
+CREATE INDEX idx_quest_dis_participation_retry_next_attempt_at ON quest_dis_participation_retry(next_attempt_at);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_participation_retry_next_attempt_at;
+DROP TABLE IF EXISTS quest_dis_participation_retry;
@@ -0,0 +1,17 @@
+-- Topic participant count for list and detail responses
+-- Counts the creator plus everyone who has posted in the topic; maintained
+-- alongside message_count as messages are ingested
+
+ALTER TABLE quest_dis_topic
+    ADD COLUMN participant_count INTEGER NOT NULL DEFAULT 1;
+
+UPDATE quest_dis_topic t
+SET participant_count = 1 + (
+    SELECT COUNT(DISTINCT m.did) FROM quest_dis_message m
+    WHERE m.topic_did = t.did AND m.topic_rkey = t.rkey AND m.did <> t.did
+);
+
+---- create above / drop below ----
+
+ALTER TABLE quest_dis_topic
+    DROP COLUMN IF EXISTS participant_count;
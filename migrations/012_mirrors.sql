@@ -0,0 +1,47 @@
+-- Mirrored topics and messages copied from other dis.quest instances'
+-- firehoses. Mirrored records are read-only and record the origin they came
+-- from; only events from that origin update or remove them.
+
+CREATE TABLE quest_dis_mirror_source (
+    origin TEXT PRIMARY KEY,
+    time_us BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE quest_dis_mirror_topic (
+    did TEXT NOT NULL,
+    rkey TEXT NOT NULL,
+    origin TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    initial_message TEXT NOT NULL,
+    category TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    mirrored_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (did, rkey)
+);
+
+CREATE TABLE quest_dis_mirror_message (
+    did TEXT NOT NULL,
+    rkey TEXT NOT NULL,
+    origin TEXT NOT NULL,
+    topic_did TEXT NOT NULL,
+    topic_rkey TEXT NOT NULL,
+    parent_message_rkey TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    mirrored_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (did, rkey)
+);
+
+CREATE INDEX idx_quest_dis_mirror_topic_category ON quest_dis_mirror_topic(category, created_at);
+CREATE INDEX idx_quest_dis_mirror_topic_created_at ON quest_dis_mirror_topic(created_at);
+CREATE INDEX idx_quest_dis_mirror_message_topic ON quest_dis_mirror_message(topic_did, topic_rkey);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_mirror_message_topic;
+DROP INDEX IF EXISTS idx_quest_dis_mirror_topic_created_at;
+DROP INDEX IF EXISTS idx_quest_dis_mirror_topic_category;
+DROP TABLE IF EXISTS quest_dis_mirror_message;
+DROP TABLE IF EXISTS quest_dis_mirror_topic;
+DROP TABLE IF EXISTS quest_dis_mirror_source;
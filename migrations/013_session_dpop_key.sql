@@ -0,0 +1,12 @@
+-- DPoP keys of OAuth sessions, kept server-side so token refresh and PDS
+-- writes keep working after the sign-in cookies are gone. The key is
+-- encrypted by the application before it is stored; sessions signed in
+-- with an app password have none.
+
+ALTER TABLE quest_dis_session
+    ADD COLUMN dpop_key TEXT NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE quest_dis_session
+    DROP COLUMN IF EXISTS dpop_key;
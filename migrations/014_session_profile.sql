@@ -0,0 +1,19 @@
+-- Profile of the account behind each session, looked up when the session
+-- starts so pages can greet users by name without asking their PDS on every
+-- request. Empty when the lookup failed.
+
+ALTER TABLE quest_dis_session
+    ADD COLUMN handle TEXT NOT NULL DEFAULT '';
+ALTER TABLE quest_dis_session
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE quest_dis_session
+    ADD COLUMN avatar TEXT NOT NULL DEFAULT '';
+
+---- create above / drop below ----
+
+ALTER TABLE quest_dis_session
+    DROP COLUMN IF EXISTS avatar;
+ALTER TABLE quest_dis_session
+    DROP COLUMN IF EXISTS display_name;
+ALTER TABLE quest_dis_session
+    DROP COLUMN IF EXISTS handle;
@@ -0,0 +1,21 @@
+-- Labels published by the instance's labeler, served by
+-- com.atproto.label.queryLabels and streamed in seq order by
+-- com.atproto.label.subscribeLabels. sig signs the label without it.
+
+CREATE TABLE quest_dis_label (
+    seq BIGINT PRIMARY KEY,
+    src TEXT NOT NULL,
+    uri TEXT NOT NULL,
+    cid TEXT NOT NULL DEFAULT '',
+    val TEXT NOT NULL,
+    neg BOOLEAN NOT NULL DEFAULT FALSE,
+    cts TEXT NOT NULL,
+    sig BYTEA NOT NULL
+);
+
+CREATE INDEX idx_quest_dis_label_uri ON quest_dis_label(uri, seq);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_label_uri;
+DROP TABLE IF EXISTS quest_dis_label;
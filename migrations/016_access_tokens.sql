@@ -0,0 +1,21 @@
+-- Personal access tokens - let scripts call the REST API as the user who
+-- issued them. Only a SHA-256 hash of each token is stored; the token itself
+-- is shown once when it is issued. scopes is a space-separated list.
+
+CREATE TABLE quest_dis_access_token (
+    id TEXT PRIMARY KEY,
+    did TEXT NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX idx_quest_dis_access_token_did ON quest_dis_access_token(did);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_quest_dis_access_token_did;
+DROP TABLE IF EXISTS quest_dis_access_token;
@@ -0,0 +1,19 @@
+-- The newest firehose event applied to each mirrored record, keyed by
+-- origin and record. Events at or before it, or carrying the same CID, are
+-- replays and are skipped; deleted records keep their row so a late create
+-- cannot bring them back.
+
+CREATE TABLE quest_dis_mirror_record (
+    origin TEXT NOT NULL,
+    did TEXT NOT NULL,
+    collection TEXT NOT NULL,
+    rkey TEXT NOT NULL,
+    cid TEXT NOT NULL,
+    time_us BIGINT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (origin, did, collection, rkey)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS quest_dis_mirror_record;
@@ -0,0 +1,10 @@
+-- Whether each mirrored record was proven against its author's signed
+-- repository, rather than taken on the origin's word
+
+ALTER TABLE quest_dis_mirror_topic ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE quest_dis_mirror_message ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+---- create above / drop below ----
+
+ALTER TABLE quest_dis_mirror_message DROP COLUMN verified;
+ALTER TABLE quest_dis_mirror_topic DROP COLUMN verified;
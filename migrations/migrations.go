@@ -0,0 +1,27 @@
+// Package migrations embeds the tern migrations that define the database
+// schema, so a running server can tell whether its database is current
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Latest returns the number of the newest migration, the version tern
+// records in schema_version once every migration has been applied
+func Latest() int {
+	names, _ := fs.Glob(files, "*.sql")
+	latest := 0
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		if n, err := strconv.Atoi(prefix); err == nil && n > latest {
+			latest = n
+		}
+	}
+	return latest
+}
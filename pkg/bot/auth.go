@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry an access token is refreshed
+const refreshMargin = time.Minute
+
+// AppPasswordAuth authenticates a bot account with an ATProto app password.
+// It creates a session on the account's PDS and refreshes the access token
+// shortly before it expires. It is safe for concurrent use.
+type AppPasswordAuth struct {
+	service    string
+	identifier string
+	password   string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	session *pdsSession
+}
+
+// pdsSession is the body of createSession and refreshSession responses
+type pdsSession struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	Did        string `json:"did"`
+	Handle     string `json:"handle"`
+}
+
+// AppPassword returns a token source that logs in to the PDS at service
+// (e.g. "https://bsky.social") as identifier, a handle or DID. Create the
+// password under the account's app password settings rather than using the
+// main account password.
+func AppPassword(service, identifier, password string) *AppPasswordAuth {
+	return &AppPasswordAuth{
+		service:    strings.TrimRight(service, "/"),
+		identifier: identifier,
+		password:   password,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Token returns a current access token, logging in on first use and
+// refreshing or re-creating the session as it nears expiry
+func (a *AppPasswordAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.session != nil && !expiresWithin(a.session.AccessJwt, refreshMargin) {
+		return a.session.AccessJwt, nil
+	}
+
+	if a.session != nil && !expiresWithin(a.session.RefreshJwt, 0) {
+		session, err := a.call(ctx, "com.atproto.server.refreshSession", a.session.RefreshJwt, nil)
+		if err == nil {
+			a.session = session
+			return session.AccessJwt, nil
+		}
+	}
+
+	session, err := a.call(ctx, "com.atproto.server.createSession", "", map[string]string{
+		"identifier": a.identifier,
+		"password":   a.password,
+	})
+	if err != nil {
+		return "", err
+	}
+	a.session = session
+	return session.AccessJwt, nil
+}
+
+// call invokes an XRPC session procedure on the PDS
+func (a *AppPasswordAuth) call(ctx context.Context, nsid, bearer string, body any) (*pdsSession, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.service+"/xrpc/"+nsid, &payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", nsid, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s failed: %s", nsid, resp.Status)
+	}
+	var session pdsSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %w", nsid, err)
+	}
+	if session.AccessJwt == "" {
+		return nil, fmt.Errorf("%s: response has no access token", nsid)
+	}
+	return &session, nil
+}
+
+// tokenClaims are the unverified JWT claims the bot relies on
+type tokenClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+}
+
+var errMalformedToken = errors.New("malformed session token")
+
+// parseClaims decodes a JWT payload without verifying it. The server
+// verifies tokens; the bot only needs to know who it is and when to refresh.
+func parseClaims(token string) (tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tokenClaims{}, errMalformedToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return tokenClaims{}, errMalformedToken
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return tokenClaims{}, errMalformedToken
+	}
+	return claims, nil
+}
+
+// expiresWithin reports whether token expires within d. Tokens without a
+// readable exp claim are treated as not expiring.
+func expiresWithin(token string, d time.Duration) bool {
+	claims, err := parseClaims(token)
+	if err != nil || claims.Exp == 0 {
+		return false
+	}
+	return time.Until(time.Unix(claims.Exp, 0)) <= d
+}
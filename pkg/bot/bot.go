@@ -0,0 +1,254 @@
+// Package bot is a harness for automated dis.quest participants such as FAQ
+// responders and CI status reporters.
+//
+// A Bot authenticates as a regular account, receives topic events either by
+// holding Server-Sent Events streams open (Run) or from pushed webhooks
+// (WebhookHandler), and dispatches them to registered handlers. Handlers
+// answer through the helpers on Message, or post directly with Bot.Post.
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/pkg/disquestclient"
+)
+
+// Backoff bounds for reconnecting dropped event streams
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// MessageHandler is called for each new message posted by someone other
+// than the bot
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// EventHandler is called for each event of the type it was registered for
+type EventHandler func(ctx context.Context, topicID string, event disquestclient.Event) error
+
+// Bot dispatches topic events to handlers. Register handlers before calling
+// Run or serving webhooks.
+type Bot struct {
+	client *disquestclient.Client
+	auth   disquestclient.TokenSource
+	logger *slog.Logger
+
+	messageHandlers []MessageHandler
+	eventHandlers   map[string][]EventHandler
+}
+
+// Option configures a Bot
+type Option func(*botConfig)
+
+type botConfig struct {
+	logger        *slog.Logger
+	clientOptions []disquestclient.Option
+}
+
+// WithLogger sets the logger used to report handler and stream errors
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *botConfig) {
+		c.logger = logger
+	}
+}
+
+// WithClientOptions passes options, such as a custom HTTP client or user
+// agent, to the underlying API client
+func WithClientOptions(opts ...disquestclient.Option) Option {
+	return func(c *botConfig) {
+		c.clientOptions = append(c.clientOptions, opts...)
+	}
+}
+
+// New creates a bot for the instance at baseURL. auth is usually
+// AppPassword; tokens from the OAuth login flow can be supplied with
+// disquestclient.StaticToken or any other TokenSource.
+func New(baseURL string, auth disquestclient.TokenSource, opts ...Option) (*Bot, error) {
+	if auth == nil {
+		return nil, errors.New("bot: auth is required")
+	}
+
+	cfg := botConfig{
+		logger:        slog.Default(),
+		clientOptions: []disquestclient.Option{disquestclient.WithUserAgent("disquest-bot")},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client, err := disquestclient.New(baseURL, append(cfg.clientOptions, disquestclient.WithTokenSource(auth))...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bot{
+		client:        client,
+		auth:          auth,
+		logger:        cfg.logger,
+		eventHandlers: make(map[string][]EventHandler),
+	}, nil
+}
+
+// Client returns the API client the bot uses, for calls without a helper
+func (b *Bot) Client() *disquestclient.Client {
+	return b.client
+}
+
+// DID returns the DID of the bot account, taken from its session token
+func (b *Bot) DID(ctx context.Context) (string, error) {
+	token, err := b.auth.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	claims, err := parseClaims(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.Sub == "" {
+		return "", errMalformedToken
+	}
+	return claims.Sub, nil
+}
+
+// OnMessage registers a handler for new messages. The bot's own messages
+// are never delivered, so replying cannot loop.
+func (b *Bot) OnMessage(handler MessageHandler) {
+	b.messageHandlers = append(b.messageHandlers, handler)
+}
+
+// On registers a handler for a raw event type such as
+// disquestclient.EventMessageDeleted
+func (b *Bot) On(eventType string, handler EventHandler) {
+	b.eventHandlers[eventType] = append(b.eventHandlers[eventType], handler)
+}
+
+// Post starts a new top-level message in a topic
+func (b *Bot) Post(ctx context.Context, topicID, content string) (*disquestclient.Message, error) {
+	return b.client.CreateMessage(ctx, topicID, disquestclient.CreateMessageRequest{Content: content})
+}
+
+// Run watches the given topics and dispatches their events until ctx is
+// cancelled. Dropped streams are reopened with exponential backoff; a topic
+// that no longer exists stops being watched.
+func (b *Bot) Run(ctx context.Context, topicIDs ...string) error {
+	if len(topicIDs) == 0 {
+		return errors.New("bot: no topics to watch")
+	}
+
+	var wg sync.WaitGroup
+	for _, topicID := range topicIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.watch(ctx, topicID)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// watch keeps a topic stream open until ctx is cancelled
+func (b *Bot) watch(ctx context.Context, topicID string) {
+	delay := minReconnectDelay
+	for {
+		connected, err := b.stream(ctx, topicID)
+		if ctx.Err() != nil {
+			return
+		}
+		if disquestclient.IsNotFound(err) {
+			b.logger.Error("Topic not found, no longer watching", "topicID", topicID)
+			return
+		}
+		if connected {
+			delay = minReconnectDelay
+		}
+		b.logger.Warn("Topic stream dropped, reconnecting", "topicID", topicID, "error", err, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// stream reads one connection's worth of events, reporting whether the
+// subscription was established before it ended
+func (b *Bot) stream(ctx context.Context, topicID string) (bool, error) {
+	stream, err := b.client.SubscribeTopic(ctx, topicID, &disquestclient.SubscribeOptions{HidePresence: true})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = stream.Close() }()
+
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			return true, err
+		}
+		b.Dispatch(ctx, topicID, event)
+	}
+}
+
+// Dispatch delivers an event from topicID to the registered handlers. Run
+// and WebhookHandler call it; other transports can too. Handler errors are
+// logged rather than returned so one failing handler doesn't stop the rest.
+func (b *Bot) Dispatch(ctx context.Context, topicID string, event disquestclient.Event) {
+	for _, handler := range b.eventHandlers[event.Type] {
+		if err := handler(ctx, topicID, event); err != nil {
+			b.logger.Error("Event handler failed", "type", event.Type, "topicID", topicID, "error", err)
+		}
+	}
+
+	if event.Type != disquestclient.EventMessageConfirmed || len(b.messageHandlers) == 0 {
+		return
+	}
+
+	var confirmed disquestclient.ConfirmedMessage
+	if err := event.Decode(&confirmed); err != nil {
+		b.logger.Error("Failed to decode message event", "topicID", topicID, "error", err)
+		return
+	}
+	self, err := b.DID(ctx)
+	if err != nil {
+		b.logger.Error("Failed to determine bot identity", "error", err)
+		return
+	}
+	if confirmed.Message.Did == self {
+		return
+	}
+
+	msg := &Message{Message: confirmed.Message, bot: b}
+	for _, handler := range b.messageHandlers {
+		if err := handler(ctx, msg); err != nil {
+			b.logger.Error("Message handler failed", "messageID", msg.ID(), "error", err)
+		}
+	}
+}
+
+// Message is a new message delivered to a MessageHandler
+type Message struct {
+	disquestclient.Message
+	bot *Bot
+}
+
+// Reply answers the message in its thread
+func (m *Message) Reply(ctx context.Context, content string) (*disquestclient.Message, error) {
+	return m.bot.client.Reply(ctx, m.Message, content)
+}
+
+// Send posts a new top-level message in the message's topic
+func (m *Message) Send(ctx context.Context, content string) (*disquestclient.Message, error) {
+	return m.bot.Post(ctx, m.TopicID(), content)
+}
+
+// Typing shows the bot as typing in the message's topic, which is useful
+// before a slow reply
+func (m *Message) Typing(ctx context.Context) error {
+	return m.bot.client.SendTyping(ctx, m.TopicID())
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/pkg/disquestclient"
+)
+
+// testToken builds an unsigned JWT carrying the claims the bot reads
+func testToken(sub string, exp time.Time) string {
+	payload, _ := json.Marshal(tokenClaims{Sub: sub, Exp: exp.Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func TestAppPassword(t *testing.T) {
+	var creates, refreshes atomic.Int32
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var session pdsSession
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["identifier"] != "bot.example.com" || body["password"] != "app-pass" {
+				http.Error(w, "AuthenticationRequired", http.StatusUnauthorized)
+				return
+			}
+			creates.Add(1)
+			// Expires inside the refresh margin so the next call refreshes
+			session = pdsSession{AccessJwt: testToken("did:plc:bot", time.Now().Add(10*time.Second)), RefreshJwt: "refresh-1"}
+		case "/xrpc/com.atproto.server.refreshSession":
+			if r.Header.Get("Authorization") != "Bearer refresh-1" {
+				http.Error(w, "ExpiredToken", http.StatusBadRequest)
+				return
+			}
+			refreshes.Add(1)
+			session = pdsSession{AccessJwt: testToken("did:plc:bot", time.Now().Add(time.Hour)), RefreshJwt: "refresh-2"}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(session)
+	}))
+	defer pds.Close()
+
+	ctx := context.Background()
+
+	t.Run("Invalid password", func(t *testing.T) {
+		if _, err := AppPassword(pds.URL, "bot.example.com", "wrong").Token(ctx); err == nil {
+			t.Error("Expected login with the wrong password to fail")
+		}
+	})
+
+	t.Run("Login and refresh", func(t *testing.T) {
+		auth := AppPassword(pds.URL+"/", "bot.example.com", "app-pass")
+		for i := 0; i < 3; i++ {
+			token, err := auth.Token(ctx)
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+			if claims, _ := parseClaims(token); claims.Sub != "did:plc:bot" {
+				t.Errorf("Expected token for did:plc:bot, got %q", claims.Sub)
+			}
+		}
+		if creates.Load() != 1 || refreshes.Load() != 1 {
+			t.Errorf("Expected 1 login and 1 refresh, got %d and %d", creates.Load(), refreshes.Load())
+		}
+	})
+}
+
+func TestRunRepliesToMessages(t *testing.T) {
+	botDID := "did:plc:bot"
+	topicID := "did:plc:author:topic-1"
+	replies := make(chan string, 1)
+
+	confirmed := func(did, rkey, content string) string {
+		data, _ := json.Marshal(disquestclient.ConfirmedMessage{Message: disquestclient.Message{
+			Did: did, Rkey: rkey, TopicDid: "did:plc:author", TopicRkey: "topic-1", Content: content,
+		}})
+		return fmt.Sprintf("event: %s\ndata: %s\n\n", disquestclient.EventMessageConfirmed, data)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/topics/" + topicID + "/events":
+			if r.URL.Query().Get("presence") != "hidden" {
+				t.Error("Expected bot to hide its presence")
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, confirmed(botDID, "own", "ping"))
+			_, _ = io.WriteString(w, confirmed("did:plc:user", "msg-1", "ping"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/api/topics/" + topicID + "/messages":
+			var body disquestclient.CreateMessageRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			replies <- body.ParentMessageRkey + ":" + body.Content
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"did":"did:plc:bot","rkey":"reply"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	b, err := New(srv.URL, disquestclient.StaticToken(testToken(botDID, time.Now().Add(time.Hour))))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	b.OnMessage(func(ctx context.Context, msg *Message) error {
+		_, err := msg.Reply(ctx, "pong")
+		return err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx, topicID) }()
+
+	select {
+	case reply := <-replies:
+		if reply != "msg-1:pong" {
+			t.Errorf("Expected reply to msg-1, got %q", reply)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for reply")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+	if len(replies) != 0 {
+		t.Error("Expected the bot to ignore its own message")
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	secret := []byte("webhook-secret")
+	b, err := New("http://localhost", disquestclient.StaticToken(testToken("did:plc:bot", time.Time{})))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := b.WebhookHandler(nil); err == nil {
+		t.Error("Expected an empty secret to be rejected")
+	}
+
+	var received []string
+	b.On("ci.status", func(_ context.Context, topicID string, event disquestclient.Event) error {
+		received = append(received, topicID+" "+string(event.Data))
+		return nil
+	})
+	handler, err := b.WebhookHandler(secret)
+	if err != nil {
+		t.Fatalf("WebhookHandler: %v", err)
+	}
+
+	body := []byte(`{"topic_id":"did:plc:a:t","type":"ci.status","data":{"state":"passed"}}`)
+	tests := []struct {
+		name           string
+		method         string
+		signature      string
+		expectedStatus int
+	}{
+		{"Valid signature", "POST", Sign(secret, body), http.StatusNoContent},
+		{"Wrong secret", "POST", Sign([]byte("other"), body), http.StatusUnauthorized},
+		{"Missing signature", "POST", "", http.StatusUnauthorized},
+		{"Wrong method", "GET", Sign(secret, body), http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/webhook", bytes.NewReader(body))
+			req.Header.Set(SignatureHeader, tt.signature)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	if len(received) != 1 || received[0] != `did:plc:a:t {"state":"passed"}` {
+		t.Errorf("Expected one dispatched event, got %v", received)
+	}
+}
@@ -0,0 +1,40 @@
+package bot_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/pkg/bot"
+)
+
+// An FAQ bot that answers common questions in a topic
+func Example() {
+	faq := map[string]string{
+		"how do i install":   "Run `go install github.com/jrschumacher/dis.quest@latest`.",
+		"where are the docs": "See the docs/ directory in the repository.",
+	}
+
+	b, err := bot.New("https://dis.quest", bot.AppPassword("https://bsky.social", "faq.example.com", os.Getenv("BOT_APP_PASSWORD")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	b.OnMessage(func(ctx context.Context, msg *bot.Message) error {
+		question := strings.ToLower(msg.Content)
+		for prefix, answer := range faq {
+			if strings.HasPrefix(question, prefix) {
+				_, err := msg.Reply(ctx, answer)
+				return err
+			}
+		}
+		return nil
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := b.Run(ctx, "did:plc:example:3kq2topic"); err != nil {
+		log.Fatal(err)
+	}
+}
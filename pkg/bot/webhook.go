@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/pkg/disquestclient"
+)
+
+// SignatureHeader carries the webhook body's HMAC-SHA256 signature in the
+// form "sha256=<hex>"
+const SignatureHeader = "X-Disquest-Signature"
+
+// maxWebhookBody caps the size of a webhook request body
+const maxWebhookBody = 1 << 20
+
+// WebhookEvent is the JSON body of a webhook delivery
+type WebhookEvent struct {
+	TopicID string          `json:"topic_id"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Sign returns the SignatureHeader value for body, for senders such as CI
+// jobs that push events to a bot
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookHandler returns a handler that accepts signed WebhookEvent POSTs
+// and dispatches them like streamed events. Use it when events are pushed
+// to the bot instead of it holding streams open. Requests without a valid
+// signature for secret are rejected.
+func (b *Bot) WebhookHandler(secret []byte) (http.Handler, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("bot: webhook secret is required")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		signature := req.Header.Get(SignatureHeader)
+		if !strings.HasPrefix(signature, "sha256=") || !hmac.Equal([]byte(signature), []byte(Sign(secret, body))) {
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil || event.Type == "" {
+			http.Error(w, "Invalid event", http.StatusBadRequest)
+			return
+		}
+
+		b.Dispatch(req.Context(), event.TopicID, disquestclient.Event{Type: event.Type, Data: event.Data})
+		w.WriteHeader(http.StatusNoContent)
+	}), nil
+}
@@ -0,0 +1,179 @@
+// Package disquestclient is a typed Go client for the dis.quest REST API.
+//
+// It covers topics, message threads, participation, read markers and the
+// Server-Sent Events streams, so bots and integrations don't need to
+// hand-roll HTTP calls. Request and response types mirror the schemas in
+// api/openapi.yaml.
+package disquestclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SessionCookieName is the cookie the server reads the session token from
+const SessionCookieName = "dsq_session"
+
+const defaultUserAgent = "disquestclient"
+
+// Client calls a dis.quest instance
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	tokenSource TokenSource
+	// bearer sends tokens in the Authorization header rather than the
+	// session cookie
+	bearer    bool
+	userAgent string
+}
+
+// TokenSource supplies the session token for each request, which lets
+// long-running callers such as bots refresh tokens before they expire
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token
+type StaticToken string
+
+// Token implements TokenSource
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. Streams are long
+// lived, so avoid clients with a short overall Timeout when subscribing.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithSessionToken authenticates requests with a session token obtained
+// through the dis.quest login flow
+func WithSessionToken(token string) Option {
+	return WithTokenSource(StaticToken(token))
+}
+
+// WithAccessToken authenticates requests with a personal access token
+// issued at /account/tokens, which suits scripts and bots that cannot go
+// through the login flow
+func WithAccessToken(token string) Option {
+	return func(c *Client) {
+		c.tokenSource = StaticToken(token)
+		c.bearer = true
+	}
+}
+
+// WithTokenSource authenticates each request with a session token from src
+func WithTokenSource(src TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = src
+		c.bearer = false
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the instance at baseURL, e.g. "https://dis.quest"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  defaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// newRequest builds a request for path (which may include a query string)
+// with body encoded as JSON when non-nil
+func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.tokenSource != nil {
+		token, err := c.tokenSource.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		switch {
+		case token == "":
+		case c.bearer:
+			req.Header.Set("Authorization", "Bearer "+token)
+		default:
+			req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+		}
+	}
+	return req, nil
+}
+
+// do sends a JSON request and decodes a successful response into out, which
+// may be nil for endpoints without a body
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// escapeID escapes a did:rkey identifier for use as a single path segment
+func escapeID(id string) string {
+	return url.PathEscape(id)
+}
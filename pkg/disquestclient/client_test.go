@@ -0,0 +1,309 @@
+package disquestclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := New(srv.URL+"/", WithSessionToken("token"), WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return client
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		wantErr bool
+	}{
+		{"https://dis.quest", false},
+		{"http://localhost:3000/", false},
+		{"dis.quest", true},
+		{"ftp://dis.quest", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			_, err := New(tt.baseURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New(%q) error = %v, wantErr %v", tt.baseURL, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	topicID := "did:plc:author:topic-1"
+
+	tests := []struct {
+		name       string
+		call       func(*Client) error
+		wantMethod string
+		wantURI    string
+		wantBody   string
+		respond    string
+	}{
+		{
+			name: "list topics",
+			call: func(c *Client) error {
+				topics, err := c.ListTopics(context.Background(), &ListTopicsOptions{Sort: SortHot, PageOptions: PageOptions{Limit: 5}})
+				if err == nil && (len(topics) != 1 || topics[0].UnreadCount != 3 || topics[0].ID() != topicID) {
+					err = fmt.Errorf("unexpected topics %+v", topics)
+				}
+				return err
+			},
+			wantMethod: "GET",
+			wantURI:    "/api/topics?limit=5&sort=hot",
+			respond:    `[{"did":"did:plc:author","rkey":"topic-1","category":{"String":"go","Valid":true},"unread_count":3}]`,
+		},
+		{
+			name: "create topic",
+			call: func(c *Client) error {
+				topic, err := c.CreateTopic(context.Background(), CreateTopicRequest{Subject: "Hi", InitialMessage: "Hello"})
+				if err == nil && topic.Subject != "Hi" {
+					err = fmt.Errorf("unexpected topic %+v", topic)
+				}
+				return err
+			},
+			wantMethod: "POST",
+			wantURI:    "/api/topics",
+			wantBody:   `{"subject":"Hi","initial_message":"Hello"}`,
+			respond:    `{"did":"did:plc:author","rkey":"topic-1","subject":"Hi"}`,
+		},
+		{
+			name: "get topic",
+			call: func(c *Client) error {
+				topic, err := c.GetTopic(context.Background(), topicID)
+				if err == nil && (topic.MessageCount != 4 || topic.ParticipantCount != 2 || !topic.LastActivityAt.Valid) {
+					err = fmt.Errorf("unexpected topic %+v", topic)
+				}
+				return err
+			},
+			wantMethod: "GET",
+			wantURI:    "/api/topics/" + topicID,
+			respond:    `{"did":"did:plc:author","rkey":"topic-1","message_count":4,"participant_count":2,"last_activity_at":{"Time":"2025-01-01T00:00:00Z","Valid":true}}`,
+		},
+		{
+			name: "reply",
+			call: func(c *Client) error {
+				parent := Message{Did: "did:plc:other", Rkey: "msg-1", TopicDid: "did:plc:author", TopicRkey: "topic-1"}
+				_, err := c.Reply(context.Background(), parent, "Thanks!")
+				return err
+			},
+			wantMethod: "POST",
+			wantURI:    "/api/topics/" + topicID + "/messages",
+			wantBody:   `{"content":"Thanks!","parent_message_rkey":"msg-1"}`,
+			respond:    `{"did":"did:plc:bot","rkey":"msg-2"}`,
+		},
+		{
+			name:       "mark unread",
+			call:       func(c *Client) error { return c.MarkUnread(context.Background(), topicID) },
+			wantMethod: "DELETE",
+			wantURI:    "/api/topics/" + topicID + "/read",
+		},
+		{
+			name: "actor messages",
+			call: func(c *Client) error {
+				_, err := c.ActorMessages(context.Background(), "did:plc:author", &PageOptions{Offset: 20})
+				return err
+			},
+			wantMethod: "GET",
+			wantURI:    "/api/v1/actors/did:plc:author/messages?offset=20",
+			respond:    `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.wantMethod || r.URL.RequestURI() != tt.wantURI {
+					t.Errorf("got %s %s, want %s %s", r.Method, r.URL.RequestURI(), tt.wantMethod, tt.wantURI)
+				}
+				if cookie, err := r.Cookie(SessionCookieName); err != nil || cookie.Value != "token" {
+					t.Errorf("expected session cookie, got %v", cookie)
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("got body %s, want %s", body, tt.wantBody)
+				}
+				if tt.respond == "" {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.respond))
+			})
+
+			if err := tt.call(client); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+		wantFields  int
+		check       func(error) bool
+	}{
+		{
+			name:        "validation failure",
+			status:      http.StatusBadRequest,
+			body:        `{"error":"Validation Failed","code":"validation_failed","message":"subject: is required","details":[{"field":"subject","code":"required","message":"is required"}]}`,
+			wantMessage: "subject: is required",
+			wantFields:  1,
+		},
+		{
+			name:        "not found",
+			status:      http.StatusNotFound,
+			body:        `{"error":"Not Found","message":"Topic not found"}`,
+			wantMessage: "Topic not found",
+			check:       IsNotFound,
+		},
+		{
+			name:        "pds unavailable",
+			status:      http.StatusServiceUnavailable,
+			body:        `{"error":"Service Unavailable","code":"pds_unavailable","message":"Your PDS is unreachable right now. Please try again later."}`,
+			wantMessage: "Your PDS is unreachable right now. Please try again later.",
+			check:       IsPDSUnavailable,
+		},
+		{
+			name:        "insufficient scope",
+			status:      http.StatusForbidden,
+			body:        `{"error":"Forbidden","code":"insufficient_scope","message":"Your PDS does not let this sign-in rewrite your dis.quest records.","remedy":{"missing_scope":"transition:generic","relogin":true,"app_password":false}}`,
+			wantMessage: "Your PDS does not let this sign-in rewrite your dis.quest records.",
+			check: func(err error) bool {
+				var apiErr *APIError
+				return IsInsufficientScope(err) && errors.As(err, &apiErr) && apiErr.Remedy != nil && apiErr.Remedy.Relogin
+			},
+		},
+		{
+			name:        "plain text",
+			status:      http.StatusMethodNotAllowed,
+			body:        "Method not allowed\n",
+			wantMessage: "Method not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			_, err := client.CreateTopic(context.Background(), CreateTopicRequest{})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || len(apiErr.Details) != tt.wantFields {
+				t.Errorf("unexpected error %+v", apiErr)
+			}
+			if tt.check != nil && !tt.check(err) {
+				t.Errorf("expected %v to match status helper", err)
+			}
+		})
+	}
+}
+
+func TestSubscribeTopic(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RequestURI() != "/api/topics/did:plc:a:t/events?presence=hidden" {
+			t.Errorf("unexpected request %s", r.URL.RequestURI())
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, ": ping\n\n")
+		_, _ = io.WriteString(w, "event: presence\ndata: {\"topic_id\":\"did:plc:a:t\",\"viewing\":2,\"viewers\":[\"did:plc:a\"]}\n\n")
+		_, _ = io.WriteString(w, "event: unread\r\ndata: {\"topic_id\":\"did:plc:a:t\",\r\ndata: \"unread_count\":4}\r\n\r\n")
+	})
+
+	stream, err := client.SubscribeTopic(context.Background(), "did:plc:a:t", &SubscribeOptions{HidePresence: true})
+	if err != nil {
+		t.Fatalf("SubscribeTopic: %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	event, err := stream.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	var presence Presence
+	if event.Type != EventPresence || event.Decode(&presence) != nil || presence.Viewing != 2 {
+		t.Errorf("unexpected presence event %s %s", event.Type, event.Data)
+	}
+
+	event, err = stream.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	var badge UnreadBadge
+	if event.Type != EventUnread || event.Decode(&badge) != nil || badge.UnreadCount != 4 {
+		t.Errorf("unexpected unread event %s %s", event.Type, event.Data)
+	}
+
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+}
+
+func TestSubscribeUserUnauthorized(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized", "message": "Authentication required"})
+	})
+
+	if _, err := client.SubscribeUser(context.Background()); !IsUnauthorized(err) {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		opt        Option
+		wantHeader string
+		wantCookie string
+	}{
+		{"session token", WithSessionToken("session"), "", "session"},
+		{"access token", WithAccessToken("dqp_secret"), "Bearer dqp_secret", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New("https://dis.quest", tt.opt)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			req, err := client.newRequest(context.Background(), "GET", "/api/topics", nil)
+			if err != nil {
+				t.Fatalf("newRequest: %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.wantHeader {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantHeader)
+			}
+			var gotCookie string
+			if cookie, err := req.Cookie(SessionCookieName); err == nil {
+				gotCookie = cookie.Value
+			}
+			if gotCookie != tt.wantCookie {
+				t.Errorf("session cookie = %q, want %q", gotCookie, tt.wantCookie)
+			}
+		})
+	}
+}
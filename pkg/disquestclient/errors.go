@@ -0,0 +1,104 @@
+package disquestclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxErrorBodyBytes bounds how much of an error response is read
+const maxErrorBodyBytes = 64 << 10
+
+// CodeValidationFailed is the APIError code for rejected input; Details
+// lists the offending fields
+const CodeValidationFailed = "validation_failed"
+
+// CodePDSUnavailable is the APIError code for requests that failed because
+// the user's PDS could not be reached; retrying later may succeed
+const CodePDSUnavailable = "pds_unavailable"
+
+// CodeInsufficientScope is the APIError code for writes the user's PDS
+// refused because their sign-in's scope does not cover them; Remedy says
+// what may help
+const CodeInsufficientScope = "insufficient_scope"
+
+// FieldError describes why one request field was rejected
+type FieldError struct {
+	Field   string         `json:"field"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+// APIError is returned for any non-2xx response
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Status     string       `json:"error"`
+	Code       string       `json:"code,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	Details    []FieldError `json:"details,omitempty"`
+	Remedy     *ScopeRemedy `json:"remedy,omitempty"`
+}
+
+// ScopeRemedy accompanies CodeInsufficientScope errors
+type ScopeRemedy struct {
+	// MissingScope is the scope the PDS asked for, when it named one
+	MissingScope string `json:"missing_scope,omitempty"`
+	// Relogin reports whether signing in again can grant the scope
+	Relogin bool `json:"relogin"`
+	// AppPassword reports whether the request may be repeated with an
+	// app password
+	AppPassword bool `json:"app_password"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("disquest: %d %s: %s", e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("disquest: %d %s", e.StatusCode, e.Status)
+}
+
+// IsNotFound reports whether err is an APIError with status 404
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError with status 401
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsPDSUnavailable reports whether err is an APIError caused by the user's
+// PDS being unreachable
+func IsPDSUnavailable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == CodePDSUnavailable
+}
+
+// IsInsufficientScope reports whether err is an APIError caused by the
+// user's PDS refusing their sign-in's scope
+func IsInsufficientScope(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == CodeInsufficientScope
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+func newAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	if json.Unmarshal(body, apiErr) != nil || apiErr.Status == "" {
+		// Plain-text errors from http.Error carry the message as the body
+		apiErr.Status = http.StatusText(resp.StatusCode)
+		if apiErr.Message == "" {
+			apiErr.Message = string(bytes.TrimSpace(body))
+		}
+	}
+	return apiErr
+}
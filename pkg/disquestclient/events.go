@@ -0,0 +1,166 @@
+package disquestclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+// Event types delivered on topic and user streams; package events
+// documents them
+const (
+	EventPresence         = events.TypePresence
+	EventTyping           = events.TypeTyping
+	EventMessagePending   = events.TypeMessagePending
+	EventMessageConfirmed = events.TypeMessageConfirmed
+	EventMessageFailed    = events.TypeMessageFailed
+	EventMessageDeleted   = events.TypeMessageDeleted
+	EventMessageRestored  = events.TypeMessageRestored
+	EventUnread           = events.TypeUnread
+)
+
+// Event is a single Server-Sent Event. Data holds the raw JSON payload;
+// use Decode to unmarshal it into the type matching Type.
+type Event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// Decode unmarshals the event payload into v
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Presence is the payload of EventPresence
+type Presence = events.Presence
+
+// Typing is the payload of EventTyping
+type Typing = events.Typing
+
+// PendingMessage is the payload of EventMessagePending
+type PendingMessage = events.PendingMessage
+
+// FailedMessage is the payload of EventMessageFailed
+type FailedMessage = events.FailedMessage
+
+// DeletedMessage is the payload of EventMessageDeleted
+type DeletedMessage = events.DeletedMessage
+
+// ConfirmedMessage is the payload of EventMessageConfirmed and
+// EventMessageRestored. It matches events.ConfirmedMessage but carries the
+// client's Message.
+type ConfirmedMessage struct {
+	ClientID string  `json:"client_id,omitempty"`
+	URI      string  `json:"uri"`
+	CID      string  `json:"cid,omitempty"`
+	Message  Message `json:"message"`
+}
+
+// UnreadBadge is the payload of EventUnread
+type UnreadBadge = events.UnreadBadge
+
+// SubscribeOptions configures SubscribeTopic
+type SubscribeOptions struct {
+	// HidePresence keeps the subscriber out of presence counts and typing
+	HidePresence bool
+}
+
+// Stream reads events from a Server-Sent Events response. It is not safe
+// for concurrent use.
+type Stream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+}
+
+// SubscribeTopic opens the live event stream of a topic
+func (c *Client) SubscribeTopic(ctx context.Context, topicID string, opts *SubscribeOptions) (*Stream, error) {
+	path := "/api/topics/" + escapeID(topicID) + "/events"
+	if opts != nil && opts.HidePresence {
+		path += "?presence=hidden"
+	}
+	return c.subscribe(ctx, path)
+}
+
+// SubscribeUser opens the authenticated user's notification stream, which
+// carries EventUnread badges for followed topics
+func (c *Client) SubscribeUser(ctx context.Context) (*Stream, error) {
+	return c.subscribe(ctx, "/api/events")
+}
+
+func (c *Client) subscribe(ctx context.Context, path string) (*Stream, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, newAPIError(resp)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected stream content type %q", ct)
+	}
+
+	return &Stream{body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// Next blocks until the next event arrives. Comments such as heartbeats are
+// skipped. It returns io.EOF when the server ends the stream and the
+// context's error once the subscription context is cancelled.
+func (s *Stream) Next() (Event, error) {
+	var event Event
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return Event{}, io.EOF
+			}
+			return Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if event.Type == "" && data.Len() == 0 {
+				continue
+			}
+			if event.Type == "" {
+				event.Type = "message"
+			}
+			event.Data = json.RawMessage(data.String())
+			return event, nil
+		case strings.HasPrefix(line, ":"):
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Type = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+// Close ends the stream
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,48 @@
+package disquestclient_test
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/pkg/disquestclient"
+)
+
+// A bot that answers "ping" with "pong" in a single topic
+func Example() {
+	ctx := context.Background()
+
+	client, err := disquestclient.New("https://dis.quest",
+		disquestclient.WithAccessToken("dqp_your-access-token"),
+		disquestclient.WithUserAgent("pingbot/1.0"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stream, err := client.SubscribeTopic(ctx, "did:plc:example:3kq2topic", &disquestclient.SubscribeOptions{HidePresence: true})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if event.Type != disquestclient.EventMessageConfirmed {
+			continue
+		}
+
+		var confirmed disquestclient.ConfirmedMessage
+		if err := event.Decode(&confirmed); err != nil {
+			log.Fatal(err)
+		}
+		if strings.TrimSpace(confirmed.Message.Content) == "ping" {
+			if _, err := client.Reply(ctx, confirmed.Message, "pong"); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package disquestclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateMessageRequest is the body of CreateMessage
+type CreateMessageRequest struct {
+	Content string `json:"content"`
+	// ParentMessageRkey makes the message a reply within the thread
+	ParentMessageRkey string `json:"parent_message_rkey,omitempty"`
+	// ClientID correlates the optimistic message.pending echo on the
+	// topic stream with the stored message
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// ListMessages returns a topic's thread, oldest first
+func (c *Client) ListMessages(ctx context.Context, topicID string) ([]Message, error) {
+	var messages []Message
+	if err := c.do(ctx, http.MethodGet, "/api/topics/"+escapeID(topicID)+"/messages", nil, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CreateMessage posts a message to a topic as the authenticated user
+func (c *Client) CreateMessage(ctx context.Context, topicID string, req CreateMessageRequest) (*Message, error) {
+	var message Message
+	if err := c.do(ctx, http.MethodPost, "/api/topics/"+escapeID(topicID)+"/messages", req, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Reply posts content as a reply to parent in the parent's topic
+func (c *Client) Reply(ctx context.Context, parent Message, content string) (*Message, error) {
+	return c.CreateMessage(ctx, parent.TopicID(), CreateMessageRequest{
+		Content:           content,
+		ParentMessageRkey: parent.Rkey,
+	})
+}
+
+// DeleteMessage removes a message. It can be restored until the tombstone's UndoUntil.
+func (c *Client) DeleteMessage(ctx context.Context, messageID string) (*Tombstone, error) {
+	var tombstone Tombstone
+	if err := c.do(ctx, http.MethodDelete, "/api/messages/"+escapeID(messageID), nil, &tombstone); err != nil {
+		return nil, err
+	}
+	return &tombstone, nil
+}
+
+// RestoreMessage undoes DeleteMessage within the grace period
+func (c *Client) RestoreMessage(ctx context.Context, messageID string) (*Message, error) {
+	var message Message
+	if err := c.do(ctx, http.MethodPost, "/api/messages/"+escapeID(messageID)+"/restore", nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// SendTyping signals that the authenticated user is typing in a topic. The
+// signal expires after a few seconds, so repeat it while typing continues.
+func (c *Client) SendTyping(ctx context.Context, topicID string) error {
+	return c.do(ctx, http.MethodPost, "/api/topics/"+escapeID(topicID)+"/typing", nil, nil)
+}
+
+// ActorMessages lists the messages an actor has posted, newest first
+func (c *Client) ActorMessages(ctx context.Context, did string, opts *PageOptions) ([]Message, error) {
+	query := url.Values{}
+	opts.encode(query)
+
+	var messages []Message
+	if err := c.do(ctx, http.MethodGet, withQuery("/api/v1/actors/"+url.PathEscape(did)+"/messages", query), nil, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
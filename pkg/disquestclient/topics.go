@@ -0,0 +1,141 @@
+package disquestclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Topic list orderings for ListTopicsOptions.Sort
+const (
+	SortLatest = "latest"
+	SortActive = "active"
+	SortTop    = "top"
+	SortHot    = "hot"
+)
+
+// ListTopicsOptions filters and orders ListTopics
+type ListTopicsOptions struct {
+	PageOptions
+	// Sort is one of the Sort constants; empty means SortLatest
+	Sort string
+}
+
+// CreateTopicRequest is the body of CreateTopic
+type CreateTopicRequest struct {
+	Subject        string `json:"subject"`
+	InitialMessage string `json:"initial_message"`
+	Category       string `json:"category,omitempty"`
+}
+
+// ListTopics lists topics with the caller's unread counts
+func (c *Client) ListTopics(ctx context.Context, opts *ListTopicsOptions) ([]TopicSummary, error) {
+	query := url.Values{}
+	if opts != nil {
+		opts.PageOptions.encode(query)
+		if opts.Sort != "" {
+			query.Set("sort", opts.Sort)
+		}
+	}
+
+	var topics []TopicSummary
+	if err := c.do(ctx, http.MethodGet, withQuery("/api/topics", query), nil, &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+// CreateTopic starts a topic as the authenticated user
+func (c *Client) CreateTopic(ctx context.Context, req CreateTopicRequest) (*Topic, error) {
+	var topic Topic
+	if err := c.do(ctx, http.MethodPost, "/api/topics", req, &topic); err != nil {
+		return nil, err
+	}
+	return &topic, nil
+}
+
+// GetTopic fetches a topic with its message and participant counts
+func (c *Client) GetTopic(ctx context.Context, topicID string) (*Topic, error) {
+	var topic Topic
+	if err := c.do(ctx, http.MethodGet, "/api/topics/"+escapeID(topicID), nil, &topic); err != nil {
+		return nil, err
+	}
+	return &topic, nil
+}
+
+// DeleteTopic removes a topic. It can be restored until the tombstone's UndoUntil.
+func (c *Client) DeleteTopic(ctx context.Context, topicID string) (*Tombstone, error) {
+	var tombstone Tombstone
+	if err := c.do(ctx, http.MethodDelete, "/api/topics/"+escapeID(topicID), nil, &tombstone); err != nil {
+		return nil, err
+	}
+	return &tombstone, nil
+}
+
+// RestoreTopic undoes DeleteTopic within the grace period
+func (c *Client) RestoreTopic(ctx context.Context, topicID string) (*Topic, error) {
+	var topic Topic
+	if err := c.do(ctx, http.MethodPost, "/api/topics/"+escapeID(topicID)+"/restore", nil, &topic); err != nil {
+		return nil, err
+	}
+	return &topic, nil
+}
+
+// SetParticipation sets the authenticated user's participation status in a topic
+func (c *Client) SetParticipation(ctx context.Context, topicID, status string) (*Participation, error) {
+	body := struct {
+		Status string `json:"status"`
+	}{status}
+
+	var participation Participation
+	if err := c.do(ctx, http.MethodPut, "/api/topics/"+escapeID(topicID)+"/participation", body, &participation); err != nil {
+		return nil, err
+	}
+	return &participation, nil
+}
+
+// MarkRead marks every message currently in a topic as read
+func (c *Client) MarkRead(ctx context.Context, topicID string) (*ReadMarker, error) {
+	var marker ReadMarker
+	if err := c.do(ctx, http.MethodPost, "/api/topics/"+escapeID(topicID)+"/read", nil, &marker); err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+// MarkUnread clears the read marker so the whole topic counts as unread
+func (c *Client) MarkUnread(ctx context.Context, topicID string) error {
+	return c.do(ctx, http.MethodDelete, "/api/topics/"+escapeID(topicID)+"/read", nil, nil)
+}
+
+// ActorTopics lists the topics an actor has started, newest first
+func (c *Client) ActorTopics(ctx context.Context, did string, opts *PageOptions) ([]Topic, error) {
+	query := url.Values{}
+	opts.encode(query)
+
+	var topics []Topic
+	if err := c.do(ctx, http.MethodGet, withQuery("/api/v1/actors/"+url.PathEscape(did)+"/topics", query), nil, &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+func (p *PageOptions) encode(query url.Values) {
+	if p == nil {
+		return
+	}
+	if p.Limit > 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		query.Set("offset", strconv.Itoa(p.Offset))
+	}
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,111 @@
+package disquestclient
+
+import "time"
+
+// NullString is an optional text field. The server encodes these as
+// {"String": "...", "Valid": true}; Valid is false when the field is unset.
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+// NullTime is an optional timestamp field, encoded like NullString
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Topic is a discussion topic
+type Topic struct {
+	Did              string     `json:"did"`
+	Rkey             string     `json:"rkey"`
+	Subject          string     `json:"subject"`
+	InitialMessage   string     `json:"initial_message"`
+	Category         NullString `json:"category"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	SelectedAnswer   NullString `json:"selected_answer"`
+	DeletedAt        NullTime   `json:"deleted_at"`
+	DeletedBy        NullString `json:"deleted_by"`
+	MessageCount     int32      `json:"message_count"`
+	LastActivityAt   NullTime   `json:"last_activity_at"`
+	ParticipantCount int32      `json:"participant_count"`
+	HotRank          float64    `json:"hot_rank"`
+}
+
+// ID returns the topic's did:rkey identifier used in API paths
+func (t Topic) ID() string {
+	return t.Did + ":" + t.Rkey
+}
+
+// TopicSummary is a topic in a list response along with the caller's
+// unread count, which is always zero for anonymous clients
+type TopicSummary struct {
+	Topic
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// Message is a message in a topic thread
+type Message struct {
+	Did               string     `json:"did"`
+	Rkey              string     `json:"rkey"`
+	TopicDid          string     `json:"topic_did"`
+	TopicRkey         string     `json:"topic_rkey"`
+	ParentMessageRkey NullString `json:"parent_message_rkey"`
+	Content           string     `json:"content"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         NullTime   `json:"deleted_at"`
+	DeletedBy         NullString `json:"deleted_by"`
+}
+
+// ID returns the message's did:rkey identifier used in API paths
+func (m Message) ID() string {
+	return m.Did + ":" + m.Rkey
+}
+
+// TopicID returns the did:rkey identifier of the message's topic
+func (m Message) TopicID() string {
+	return m.TopicDid + ":" + m.TopicRkey
+}
+
+// Participation is a user's relationship to a topic
+type Participation struct {
+	Did       string    `json:"did"`
+	TopicDid  string    `json:"topic_did"`
+	TopicRkey string    `json:"topic_rkey"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Participation statuses accepted by SetParticipation
+const (
+	ParticipationActive    = "active"
+	ParticipationFollowing = "following"
+	ParticipationMuted     = "muted"
+)
+
+// ReadMarker records how far a user has read in a topic
+type ReadMarker struct {
+	Did        string    `json:"did"`
+	TopicDid   string    `json:"topic_did"`
+	TopicRkey  string    `json:"topic_rkey"`
+	LastReadAt time.Time `json:"last_read_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Tombstone describes deleted content and how long the deletion can be undone
+type Tombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+	UndoUntil time.Time `json:"undo_until"`
+}
+
+// PageOptions pages through list endpoints. Zero values use server defaults.
+type PageOptions struct {
+	Limit  int
+	Offset int
+}
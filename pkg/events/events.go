@@ -0,0 +1,151 @@
+// Package events defines the live events dis.quest sends on its Server-Sent
+// Events streams: their names, their JSON payloads and a JSON Schema
+// describing both, so clients in any language can consume the streams.
+//
+// The payloads are versioned as a whole. Adding event types or optional
+// fields keeps the version; removing, renaming or retyping a field bumps
+// it. Streams report the version they speak in the VersionHeader response
+// header, and clients should ignore event types and fields they do not know.
+package events
+
+import "time"
+
+const (
+	// Version is the version of the event payloads described here
+	Version = 1
+	// VersionHeader is the stream response header carrying Version
+	VersionHeader = "Event-Schema-Version"
+)
+
+// Event types. Topic streams carry the presence, typing and message
+// events; the user stream carries TypeUnread.
+const (
+	// TypePresence carries a Presence snapshot
+	TypePresence = "presence"
+	// TypeTyping carries a Typing snapshot
+	TypeTyping = "typing"
+	// TypeMessagePending echoes a PendingMessage to its author before it is stored
+	TypeMessagePending = "message.pending"
+	// TypeMessageConfirmed announces a stored message as a ConfirmedMessage
+	TypeMessageConfirmed = "message.confirmed"
+	// TypeMessageFailed tells the author a pending message could not be stored
+	TypeMessageFailed = "message.failed"
+	// TypeMessageDeleted tells subscribers to remove a deleted or hidden message
+	TypeMessageDeleted = "message.deleted"
+	// TypeMessageRestored announces a restored message as a ConfirmedMessage
+	TypeMessageRestored = "message.restored"
+	// TypeUnread carries an UnreadBadge for one of the user's topics
+	TypeUnread = "unread"
+)
+
+// Presence is who is viewing a topic. Viewing counts anonymous viewers too;
+// Viewers lists only signed-in ones.
+type Presence struct {
+	TopicID string   `json:"topic_id"`
+	Viewing int      `json:"viewing"`
+	Viewers []string `json:"viewers"`
+}
+
+// Typing is who is currently typing in a topic
+type Typing struct {
+	TopicID string   `json:"topic_id"`
+	Typing  []string `json:"typing"`
+}
+
+// PendingMessage is a message as submitted, before it is stored. ClientID
+// is the ID the author's client sent with it.
+type PendingMessage struct {
+	ClientID          string `json:"client_id"`
+	TopicID           string `json:"topic_id"`
+	Author            string `json:"author"`
+	Content           string `json:"content"`
+	ParentMessageRkey string `json:"parent_message_rkey,omitempty"`
+}
+
+// ConfirmedMessage is a stored message. ClientID matches the PendingMessage
+// it confirms, if any.
+type ConfirmedMessage struct {
+	ClientID string  `json:"client_id,omitempty"`
+	URI      string  `json:"uri"`
+	CID      string  `json:"cid,omitempty"`
+	Message  Message `json:"message"`
+}
+
+// FailedMessage tells the author a PendingMessage was not stored
+type FailedMessage struct {
+	ClientID string `json:"client_id"`
+	Error    string `json:"error"`
+}
+
+// DeletedMessage names a message to remove
+type DeletedMessage struct {
+	URI string `json:"uri"`
+}
+
+// UnreadBadge is the user's unread message count in one topic
+type UnreadBadge struct {
+	TopicID     string `json:"topic_id"`
+	UnreadCount int64  `json:"unread_count"`
+}
+
+// Message is a message as carried in events, in the version 1 encoding of
+// the REST API
+type Message struct {
+	Did               string     `json:"did"`
+	Rkey              string     `json:"rkey"`
+	TopicDid          string     `json:"topic_did"`
+	TopicRkey         string     `json:"topic_rkey"`
+	ParentMessageRkey NullString `json:"parent_message_rkey"`
+	Content           string     `json:"content"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         NullTime   `json:"deleted_at"`
+	DeletedBy         NullString `json:"deleted_by"`
+}
+
+// NullString is an optional text field; Valid is false when it is unset
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+// NullTime is an optional timestamp, encoded like NullString
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Definition describes one event type
+type Definition struct {
+	Type        string
+	Description string
+	// Payload is a zero value of the event's payload type
+	Payload any
+}
+
+// definitions lists every event type, in the order the schema documents them
+var definitions = []Definition{
+	{TypePresence, "Who is viewing the topic, sent whenever it changes", Presence{}},
+	{TypeTyping, "Who is typing in the topic, sent whenever it changes", Typing{}},
+	{TypeMessagePending, "A message the subscriber just submitted, sent only to its author", PendingMessage{}},
+	{TypeMessageConfirmed, "A message that was stored", ConfirmedMessage{}},
+	{TypeMessageFailed, "A submitted message that could not be stored, sent only to its author", FailedMessage{}},
+	{TypeMessageDeleted, "A message that was deleted or hidden", DeletedMessage{}},
+	{TypeMessageRestored, "A deleted message that was restored", ConfirmedMessage{}},
+	{TypeUnread, "The user's unread count in a followed topic", UnreadBadge{}},
+}
+
+// Definitions returns every event type
+func Definitions() []Definition {
+	return append([]Definition(nil), definitions...)
+}
+
+// Lookup returns the definition of an event type
+func Lookup(eventType string) (Definition, bool) {
+	for _, d := range definitions {
+		if d.Type == eventType {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaID identifies the JSON Schema of this version of the events
+var SchemaID = fmt.Sprintf("https://dis.quest/schemas/events/v%d.json", Version)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	schemaJSON = sync.OnceValue(func() []byte {
+		data, err := json.MarshalIndent(buildSchema(), "", "  ")
+		if err != nil {
+			panic(fmt.Sprintf("events: failed to marshal schema: %v", err))
+		}
+		return data
+	})
+)
+
+// Schema returns a JSON Schema (draft 2020-12) for events as
+// {"type": ..., "data": ...} objects, where type is the SSE event name and
+// data the JSON on its data line. Payload types are under $defs.
+func Schema() []byte {
+	return slices.Clone(schemaJSON())
+}
+
+func buildSchema() map[string]any {
+	defs := make(map[string]any)
+	types := make([]string, 0, len(definitions))
+	variants := make([]any, 0, len(definitions))
+	for _, d := range definitions {
+		types = append(types, d.Type)
+		variants = append(variants, map[string]any{
+			"description": d.Description,
+			"properties": map[string]any{
+				"type": map[string]any{"const": d.Type},
+				"data": schemaFor(reflect.TypeOf(d.Payload), defs),
+			},
+		})
+	}
+	return map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         SchemaID,
+		"title":       "dis.quest live events",
+		"description": fmt.Sprintf("Events on dis.quest Server-Sent Events streams, version %d. Clients should ignore unknown event types and fields.", Version),
+		"type":        "object",
+		"required":    []string{"type", "data"},
+		"properties": map[string]any{
+			"type": map[string]any{"type": "string", "enum": types},
+			"data": map[string]any{},
+		},
+		"oneOf": variants,
+		"$defs": defs,
+	}
+}
+
+// schemaFor describes values of type t, adding struct types to defs
+func schemaFor(t reflect.Type, defs map[string]any) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), defs)
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			defs[t.Name()] = nil
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema describes a struct as encoding/json writes it. Fields
+// without omitempty are required.
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, defs)
+		if !slices.Contains(strings.Split(opts, ","), "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	var schema struct {
+		ID    string `json:"$id"`
+		OneOf []struct {
+			Properties struct {
+				Type struct {
+					Const string `json:"const"`
+				} `json:"type"`
+				Data struct {
+					Ref string `json:"$ref"`
+				} `json:"data"`
+			} `json:"properties"`
+		} `json:"oneOf"`
+		Defs map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+			Required   []string                  `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if schema.ID != "https://dis.quest/schemas/events/v1.json" {
+		t.Errorf("$id = %q", schema.ID)
+	}
+
+	var types []string
+	for _, variant := range schema.OneOf {
+		types = append(types, variant.Properties.Type.Const)
+		ref := variant.Properties.Data.Ref
+		if _, ok := schema.Defs[ref[len("#/$defs/"):]]; !ok {
+			t.Errorf("%s refers to missing %s", variant.Properties.Type.Const, ref)
+		}
+	}
+	for _, d := range Definitions() {
+		if !slices.Contains(types, d.Type) {
+			t.Errorf("schema is missing %s", d.Type)
+		}
+	}
+
+	pending := schema.Defs["PendingMessage"]
+	if slices.Contains(pending.Required, "parent_message_rkey") || !slices.Contains(pending.Required, "client_id") {
+		t.Errorf("PendingMessage required = %v, want omitempty fields optional", pending.Required)
+	}
+	if got := schema.Defs["Message"].Properties["created_at"]["format"]; got != "date-time" {
+		t.Errorf("Message.created_at format = %v, want date-time", got)
+	}
+	if got := schema.Defs["Message"].Properties["deleted_at"]["$ref"]; got != "#/$defs/NullTime" {
+		t.Errorf("Message.deleted_at = %v, want a NullTime reference", got)
+	}
+	if got := schema.Defs["NullString"].Required; !slices.Equal(got, []string{"String", "Valid"}) {
+		t.Errorf("NullString required = %v", got)
+	}
+}
+
+func TestSchemaPropertiesMatchPayloads(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	payload := ConfirmedMessage{
+		ClientID: "c-1",
+		URI:      "at://did:plc:alice/quest.dis.message/1",
+		CID:      "bafy",
+		Message:  Message{Did: "did:plc:alice", CreatedAt: time.Now(), DeletedBy: NullString{String: "did:plc:mod", Valid: true}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		t.Fatal(err)
+	}
+	for name := range encoded {
+		if _, ok := schema.Defs["ConfirmedMessage"].Properties[name]; !ok {
+			t.Errorf("encoded field %q is not in the schema", name)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	d, ok := Lookup(TypeMessageRestored)
+	if !ok {
+		t.Fatal("message.restored is not defined")
+	}
+	if _, ok := d.Payload.(ConfirmedMessage); !ok {
+		t.Errorf("message.restored payload = %T, want ConfirmedMessage", d.Payload)
+	}
+	if _, ok := Lookup("reaction.added"); ok {
+		t.Error("unknown types must not be found")
+	}
+}
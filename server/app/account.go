@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// questCollectionPrefix matches every dis.quest lexicon collection
+const questCollectionPrefix = "quest.dis."
+
+// errConfirmationMismatch is returned when the typed confirmation is not the user's DID
+var errConfirmationMismatch = errors.New("confirmation does not match your DID")
+
+// errPDSDeleteFailed wraps failures deleting records from the user's PDS
+var errPDSDeleteFailed = errors.New("failed to delete records from your PDS")
+
+// deactivateRequest confirms an account wipe by repeating the account's DID
+type deactivateRequest struct {
+	Confirm string `json:"confirm"`
+	// AppPassword is used when the PDS refuses the session's scope
+	AppPassword string `json:"app_password,omitempty"`
+}
+
+// accountDeletion reports what an account wipe removed
+type accountDeletion struct {
+	RecordsDeleted int              `json:"records_deleted"`
+	Local          *db.AccountPurge `json:"local"`
+}
+
+// DeactivateAccountHandler deletes every quest.dis.* record from the user's
+// PDS, then everything stored about them here, and signs them out
+func (r *Router) DeactivateAccountHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var body deactivateRequest
+	if err := httputil.DecodeJSON(w, req, &body); err != nil {
+		httputil.WriteDecodeError(w, err)
+		return
+	}
+
+	result, err := r.deactivateAccount(req, userCtx.DID, body.Confirm, body.AppPassword)
+	switch {
+	case errors.Is(err, errConfirmationMismatch):
+		httputil.WriteError(w, http.StatusBadRequest, "Confirmation does not match your DID")
+		return
+	case errors.Is(err, pds.ErrInsufficientScope):
+		r.writeScopeRefused(w, userCtx.DID, opDeleteAccount, "delete your dis.quest records", err)
+		return
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		httputil.WriteError(w, http.StatusUnauthorized, "Invalid app password", "did", userCtx.DID)
+		return
+	case pds.IsUnavailable(err):
+		httputil.WritePDSUnavailable(w, pds.RetryAfter(err), "did", userCtx.DID, "error", err)
+		return
+	case errors.Is(err, errPDSDeleteFailed):
+		logger.Error("Failed to delete PDS records", "did", userCtx.DID, "error", err)
+		httputil.WriteError(w, http.StatusBadGateway, "Failed to delete records from your PDS; nothing was removed here, so you can try again")
+		return
+	case err != nil:
+		httputil.WriteInternalError(w, err, "Failed to delete account data", "did", userCtx.DID)
+		return
+	}
+
+	r.clearSessionCookies(w)
+	httputil.WriteSuccess(w, result)
+}
+
+// DeleteAccountPageHandler shows the account deletion form (GET) and runs
+// the deletion when the form is submitted (POST)
+func (r *Router) DeleteAccountPageHandler(w http.ResponseWriter, req *http.Request) {
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		http.Redirect(w, req, loginredirect.LoginURL(req), http.StatusSeeOther)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		renderPage(w, req, http.StatusOK, components.DeleteAccount(userCtx.DID, "", false, false))
+	case http.MethodPost:
+		result, err := r.deactivateAccount(req, userCtx.DID, req.FormValue("confirm"), req.FormValue("app_password"))
+		switch {
+		case errors.Is(err, errConfirmationMismatch):
+			renderPage(w, req, http.StatusBadRequest, components.DeleteAccount(userCtx.DID, "That doesn't match your DID. Nothing was deleted.", false, false))
+		case errors.Is(err, pds.ErrInsufficientScope):
+			remedy := r.scopeRemedy(userCtx.DID, opDeleteAccount, err)
+			logger.Warn("PDS refused token scope", "did", userCtx.DID, "missing_scope", remedy.MissingScope, "error", err)
+			renderPage(w, req, http.StatusForbidden, components.DeleteAccount(userCtx.DID, scopeMessage("delete your dis.quest records", remedy)+" Nothing was deleted.", false, remedy.AppPassword))
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			renderPage(w, req, http.StatusUnauthorized, components.DeleteAccount(userCtx.DID, "That app password was not accepted. Nothing was deleted.", false, true))
+		case pds.IsUnavailable(err):
+			logger.Warn("PDS unavailable", "did", userCtx.DID, "error", err)
+			renderPage(w, req, http.StatusServiceUnavailable, components.DeleteAccount(userCtx.DID, "Nothing was deleted.", true, false))
+		case errors.Is(err, errPDSDeleteFailed):
+			logger.Error("Failed to delete PDS records", "did", userCtx.DID, "error", err)
+			renderPage(w, req, http.StatusBadGateway, components.DeleteAccount(userCtx.DID, "We couldn't delete your records from your PDS. Nothing was removed here, so you can try again.", false, false))
+		case err != nil:
+			logger.Error("Failed to delete account data", "did", userCtx.DID, "error", err)
+			http.Error(w, "Failed to delete account data", http.StatusInternalServerError)
+		default:
+			r.clearSessionCookies(w)
+			renderPage(w, req, http.StatusOK, components.AccountDeleted(result.RecordsDeleted, *result.Local))
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deactivateAccount wipes an account after checking the confirmation. PDS
+// records go first: if that fails, local data is kept so the user can retry
+// and the index still matches what is published.
+func (r *Router) deactivateAccount(req *http.Request, did, confirm, appPassword string) (*accountDeletion, error) {
+	ctx := req.Context()
+	if confirm != did {
+		return nil, errConfirmationMismatch
+	}
+
+	var deleted int
+	err := r.withRepo(req, did, opDeleteAccount, appPassword, func(client RepoClient) error {
+		n, err := client.DeleteCollections(ctx, did, questCollectionPrefix)
+		deleted += n
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w after %d records: %w", errPDSDeleteFailed, deleted, err)
+	}
+
+	// Write out buffered counters first so none are left for deleted topics
+	if err := r.analytics.Flush(ctx); err != nil {
+		logger.Warn("Failed to flush analytics before account purge", "error", err)
+	}
+	purge, err := r.dbService.PurgeAccount(context.WithoutCancel(ctx), did)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Account data deleted", "did", did, "records", deleted, "topics", purge.Topics, "messages", purge.Messages)
+
+	return &accountDeletion{RecordsDeleted: deleted, Local: purge}, nil
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+// accountPDS is a fake PDS holding a couple of quest records and one
+// unrelated post; applyWrites fails while failWrites is set
+type accountPDS struct {
+	mu         sync.Mutex
+	records    map[string][]string
+	failWrites bool
+}
+
+func (p *accountPDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case "/xrpc/com.atproto.repo.describeRepo":
+		collections := []string{}
+		for collection := range p.records {
+			collections = append(collections, collection)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"collections": collections})
+	case "/xrpc/com.atproto.repo.listRecords":
+		collection := r.URL.Query().Get("collection")
+		records := []map[string]string{}
+		for _, rkey := range p.records[collection] {
+			records = append(records, map[string]string{"uri": "at://did:plc:user/" + collection + "/" + rkey})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"records": records})
+	case "/xrpc/com.atproto.repo.applyWrites":
+		if p.failWrites {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var body struct {
+			Writes []struct {
+				Collection string `json:"collection"`
+			} `json:"writes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, write := range body.Writes {
+			delete(p.records, write.Collection)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDeactivateAccount_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	userDID := "did:plc:user"
+	otherDID := "did:plc:other"
+	queries := dbService.Queries()
+	now := time.Now()
+
+	for _, topic := range []db.CreateTopicParams{
+		{Did: userDID, Rkey: "mine", Subject: "Mine", InitialMessage: "Hi"},
+		{Did: otherDID, Rkey: "theirs", Subject: "Theirs", InitialMessage: "Hi"},
+	} {
+		topic.CreatedAt, topic.UpdatedAt = now, now
+		if _, err := queries.CreateTopic(ctx, topic); err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+	for _, message := range []db.CreateMessageParams{
+		{Did: userDID, Rkey: "reply", TopicDid: otherDID, TopicRkey: "theirs", Content: "mine"},
+		{Did: otherDID, Rkey: "in-mine", TopicDid: userDID, TopicRkey: "mine", Content: "in my topic"},
+		{Did: otherDID, Rkey: "kept", TopicDid: otherDID, TopicRkey: "theirs", Content: "kept"},
+	} {
+		message.CreatedAt, message.UpdatedAt = now, now
+		if _, err := queries.CreateMessage(ctx, message); err != nil {
+			t.Fatalf("Failed to create test message: %v", err)
+		}
+	}
+	sessions := NewSessionManager(dbService, clock.System{})
+	if _, err := sessions.Start(ctx, userDID, httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	fake := &accountPDS{records: map[string][]string{
+		"quest.dis.topic":    {"mine"},
+		"quest.dis.message":  {"reply"},
+		"app.bsky.feed.post": {"post"},
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	// The PLC directory points the user at their own PDS, not bsky.social
+	var plcDown bool
+	plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if plcDown || r.URL.Path != "/"+userDID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id":%q,"service":[{"id":"#atproto_pds","type":"AtprotoPersonalDataServer","serviceEndpoint":%q}]}`, userDID, srv.URL)
+	}))
+	defer plc.Close()
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test", DatabaseURL: ":memory:"}, dbService, userDID)
+	identity := &auth.IdentityResolver{HTTPClient: plc.Client(), PLCDirectory: plc.URL, Scheme: "http"}
+	router.repos = pdsRepos{resolve: identity.ResolvePDS}
+
+	deactivate := func(confirm string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(deactivateRequest{Confirm: confirm})
+		req := httptest.NewRequest("POST", "/api/v1/me/deactivate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "dsq_session", Value: "access-token"})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	topicExists := func(did, rkey string) bool {
+		t.Helper()
+		_, err := queries.GetTopic(ctx, db.GetTopicParams{Did: did, Rkey: rkey})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Failed to get topic: %v", err)
+		}
+		return err == nil
+	}
+
+	t.Run("Confirmation must match the DID", func(t *testing.T) {
+		w := deactivate(otherDID)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", w.Code)
+		}
+		if !topicExists(userDID, "mine") || len(fake.records) != 3 {
+			t.Fatal("Nothing should be deleted on a mismatched confirmation")
+		}
+	})
+
+	t.Run("PDS failure keeps local data", func(t *testing.T) {
+		fake.failWrites = true
+		defer func() { fake.failWrites = false }()
+		w := deactivate(userDID)
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("Expected status 502, got %d: %s", w.Code, w.Body.String())
+		}
+		if !topicExists(userDID, "mine") {
+			t.Fatal("Local data should be kept when the PDS wipe fails")
+		}
+	})
+
+	t.Run("Unresolvable DID keeps local data", func(t *testing.T) {
+		plcDown = true
+		defer func() { plcDown = false }()
+		w := deactivate(userDID)
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("Expected status 502, got %d: %s", w.Code, w.Body.String())
+		}
+		if !topicExists(userDID, "mine") || len(fake.records) != 3 {
+			t.Fatal("Nothing should be deleted when the user's PDS cannot be found")
+		}
+	})
+
+	t.Run("Deletes PDS records and local data", func(t *testing.T) {
+		w := deactivate(userDID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var got accountDeletion
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got.RecordsDeleted != 2 {
+			t.Errorf("Expected 2 records deleted, got %d", got.RecordsDeleted)
+		}
+		if got.Local.Topics != 1 || got.Local.Messages != 2 || got.Local.Sessions != 1 {
+			t.Errorf("Unexpected local purge: %+v", got.Local)
+		}
+		if len(fake.records) != 1 || fake.records["app.bsky.feed.post"] == nil {
+			t.Errorf("Only quest records should be deleted, left %v", fake.records)
+		}
+		if topicExists(userDID, "mine") {
+			t.Error("Expected user's topic to be deleted")
+		}
+		if !topicExists(otherDID, "theirs") {
+			t.Error("Other user's topic should be kept")
+		}
+		if _, err := queries.GetMessage(ctx, db.GetMessageParams{Did: otherDID, Rkey: "kept"}); err != nil {
+			t.Errorf("Other user's message should be kept: %v", err)
+		}
+		cleared := false
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "dsq_session" && c.MaxAge < 0 {
+				cleared = true
+			}
+		}
+		if !cleared {
+			t.Error("Expected the session cookie to be cleared")
+		}
+		if remaining, err := sessions.List(ctx, userDID); err != nil || len(remaining) != 0 {
+			t.Errorf("Expected no sessions left, got %d (%v)", len(remaining), err)
+		}
+	})
+}
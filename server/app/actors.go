@@ -0,0 +1,73 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+// ActorTopicsHandler lists the topics an actor has started, newest first.
+// Topics the author deleted or an admin hid are never included.
+func (r *Router) ActorTopicsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	did, ok := actorDID(w, req)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(req)
+
+	topics, err := r.dbService.Queries().ListTopicsByAuthor(req.Context(), db.ListTopicsByAuthorParams{
+		Did:    did,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to fetch topics", "did", did)
+		return
+	}
+
+	httputil.WriteSuccess(w, versioned(w, req, topics, func() any { return newTopicViews(topics) }))
+}
+
+// ActorMessagesHandler lists the messages an actor has posted, newest first.
+// Removed messages and messages in removed topics are never included.
+func (r *Router) ActorMessagesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	did, ok := actorDID(w, req)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(req)
+
+	messages, err := r.dbService.Queries().ListMessagesByAuthor(req.Context(), db.ListMessagesByAuthorParams{
+		Did:    did,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to fetch messages", "did", did)
+		return
+	}
+
+	httputil.WriteSuccess(w, versioned(w, req, messages, func() any { return newMessageViews(messages) }))
+}
+
+// actorDID validates the {did} path value, writing a 400 when it is malformed
+func actorDID(w http.ResponseWriter, req *http.Request) (string, bool) {
+	did := req.PathValue("did")
+	if err := validation.New().Field("did", did, validation.Required(), validation.DID()).Err(); err != nil {
+		httputil.WriteValidationFailure(w, err)
+		return "", false
+	}
+	return did, true
+}
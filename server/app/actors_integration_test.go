@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestActorListings_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	actorDID := "did:plc:actor"
+	otherDID := "did:plc:other"
+	queries := dbService.Queries()
+	now := time.Now()
+
+	for _, topic := range []db.CreateTopicParams{
+		{Did: actorDID, Rkey: "visible", Subject: "Visible", InitialMessage: "Hi"},
+		{Did: actorDID, Rkey: "removed", Subject: "Removed", InitialMessage: "Hi"},
+		{Did: otherDID, Rkey: "elsewhere", Subject: "Elsewhere", InitialMessage: "Hi"},
+		{Did: otherDID, Rkey: "hidden", Subject: "Hidden", InitialMessage: "Hi"},
+	} {
+		topic.CreatedAt, topic.UpdatedAt = now, now
+		if _, err := queries.CreateTopic(ctx, topic); err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+	for _, message := range []db.CreateMessageParams{
+		{Did: actorDID, Rkey: "kept", TopicDid: otherDID, TopicRkey: "elsewhere", Content: "kept"},
+		{Did: actorDID, Rkey: "deleted", TopicDid: otherDID, TopicRkey: "elsewhere", Content: "deleted"},
+		{Did: actorDID, Rkey: "in-hidden", TopicDid: otherDID, TopicRkey: "hidden", Content: "in hidden topic"},
+		{Did: otherDID, Rkey: "not-mine", TopicDid: actorDID, TopicRkey: "visible", Content: "not mine"},
+	} {
+		message.CreatedAt, message.UpdatedAt = now, now
+		if _, err := queries.CreateMessage(ctx, message); err != nil {
+			t.Fatalf("Failed to create test message: %v", err)
+		}
+	}
+
+	tombstone := func(softDelete func() (int64, error)) {
+		t.Helper()
+		if _, err := softDelete(); err != nil {
+			t.Fatalf("Failed to tombstone: %v", err)
+		}
+	}
+	deletedAt := sql.NullTime{Time: now, Valid: true}
+	tombstone(func() (int64, error) {
+		return queries.SoftDeleteTopic(ctx, db.SoftDeleteTopicParams{DeletedAt: deletedAt, Did: actorDID, Rkey: "removed"})
+	})
+	tombstone(func() (int64, error) {
+		return queries.SoftDeleteTopic(ctx, db.SoftDeleteTopicParams{DeletedAt: deletedAt, Did: otherDID, Rkey: "hidden"})
+	})
+	tombstone(func() (int64, error) {
+		return queries.SoftDeleteMessage(ctx, db.SoftDeleteMessageParams{DeletedAt: deletedAt, Did: actorDID, Rkey: "deleted"})
+	})
+
+	mux := CreateTestServer(t, dbService, otherDID)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedRkeys  []string
+	}{
+		{"Topics", "/api/v1/actors/" + actorDID + "/topics", http.StatusOK, []string{"visible"}},
+		{"Messages", "/api/v1/actors/" + actorDID + "/messages", http.StatusOK, []string{"kept"}},
+		{"Unknown actor", "/api/v1/actors/did:plc:nobody/topics", http.StatusOK, []string{}},
+		{"Invalid DID", "/api/v1/actors/not-a-did/messages", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedRkeys == nil {
+				return
+			}
+
+			var records []struct {
+				Rkey string `json:"rkey"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(records) != len(tt.expectedRkeys) {
+				t.Fatalf("Expected %v, got %+v", tt.expectedRkeys, records)
+			}
+			for i, record := range records {
+				if record.Rkey != tt.expectedRkeys[i] {
+					t.Errorf("Expected %v, got %+v", tt.expectedRkeys, records)
+				}
+			}
+		})
+	}
+}
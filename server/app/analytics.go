@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/analytics"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+const (
+	// analyticsFlushInterval is how often buffered counters are written
+	analyticsFlushInterval = time.Minute
+
+	defaultStatsDays = 30
+	maxStatsDays     = 90
+	dashboardTopics  = 10
+)
+
+// dailyStats is one day of a topic's activity
+type dailyStats struct {
+	Day          string `json:"day"`
+	Views        int32  `json:"views"`
+	Messages     int32  `json:"messages"`
+	Participants int32  `json:"participants"`
+}
+
+// topicStats summarizes a topic's activity over a window of days.
+// Participants are unique per day, so they are not totalled across days.
+type topicStats struct {
+	TopicID              string       `json:"topic_id"`
+	Since                string       `json:"since"`
+	Views                int64        `json:"views"`
+	Messages             int64        `json:"messages"`
+	FirstResponseSeconds *int64       `json:"first_response_seconds,omitempty"`
+	Days                 []dailyStats `json:"days"`
+}
+
+// recordMessageStats counts a new message. Whether the author is a new
+// participant today, and whether this is the topic's first response, are
+// worked out from the messages already stored so no extra per-user state
+// is kept.
+func (r *Router) recordMessageStats(ctx context.Context, topicDid, topicRkey, authorDID string, postedAt time.Time) {
+	queries := r.dbService.Queries()
+
+	todays, err := queries.CountAuthorMessagesSince(ctx, db.CountAuthorMessagesSinceParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+		Did:       authorDID,
+		CreatedAt: analytics.Day(postedAt),
+	})
+	if err != nil {
+		logger.Error("Failed to count author messages", "error", err, "topicID", formatTopicID(topicDid, topicRkey))
+		return
+	}
+	r.analytics.Message(topicDid, topicRkey, todays == 1)
+
+	if authorDID == topicDid {
+		return
+	}
+	responses, err := queries.CountTopicResponses(ctx, db.CountTopicResponsesParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+		Did:       topicDid,
+	})
+	if err != nil || responses != 1 {
+		if err != nil {
+			logger.Error("Failed to count topic responses", "error", err, "topicID", formatTopicID(topicDid, topicRkey))
+		}
+		return
+	}
+	topic, err := queries.GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey})
+	if err != nil {
+		logger.Error("Failed to fetch topic for response time", "error", err, "topicID", formatTopicID(topicDid, topicRkey))
+		return
+	}
+	r.analytics.FirstResponse(topicDid, topicRkey, postedAt.Sub(topic.CreatedAt))
+}
+
+// parseStatsDays reads the days query parameter, falling back to the
+// default for missing or out-of-range values
+func parseStatsDays(req *http.Request) int {
+	if d, err := strconv.Atoi(req.URL.Query().Get("days")); err == nil && d > 0 && d <= maxStatsDays {
+		return d
+	}
+	return defaultStatsDays
+}
+
+// statsSince returns the first day included in a window of days ending on
+// the day of now
+func statsSince(now time.Time, days int) time.Time {
+	return analytics.Day(now).AddDate(0, 0, 1-days)
+}
+
+// TopicStatsHandler returns a topic's daily view, message and participant
+// counts for the last ?days= days (default 30, at most 90)
+func (r *Router) TopicStatsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	topicID := formatTopicID(topicDid, topicRkey)
+
+	if _, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicID", topicID)
+		return
+	}
+
+	// Include counters still buffered in memory
+	if err := r.analytics.Flush(ctx); err != nil {
+		logger.Error("Failed to flush analytics", "error", err)
+	}
+
+	since := statsSince(r.clock.Now(), parseStatsDays(req))
+	rows, err := r.dbService.Queries().ListTopicStats(ctx, db.ListTopicStatsParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+		Day:       since,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to fetch topic stats", "topicID", topicID)
+		return
+	}
+
+	stats := topicStats{
+		TopicID: topicID,
+		Since:   since.Format(time.DateOnly),
+		Days:    make([]dailyStats, 0, len(rows)),
+	}
+	for _, row := range rows {
+		stats.Views += int64(row.Views)
+		stats.Messages += int64(row.Messages)
+		if row.FirstResponses > 0 {
+			seconds := row.ResponseSeconds / int64(row.FirstResponses)
+			stats.FirstResponseSeconds = &seconds
+		}
+		stats.Days = append(stats.Days, dailyStats{
+			Day:          row.Day.UTC().Format(time.DateOnly),
+			Views:        row.Views,
+			Messages:     row.Messages,
+			Participants: row.Participants,
+		})
+	}
+
+	httputil.WriteSuccess(w, stats)
+}
+
+// AdminAnalyticsHandler shows site-wide daily activity and the most viewed
+// topics to admins
+func (r *Router) AdminAnalyticsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := r.analytics.Flush(ctx); err != nil {
+		logger.Error("Failed to flush analytics", "error", err)
+	}
+
+	since := statsSince(r.clock.Now(), parseStatsDays(req))
+	days, err := r.dbService.Queries().ListDailyStats(ctx, since)
+	if err != nil {
+		logger.Error("Failed to fetch daily stats", "error", err)
+		http.Error(w, "Failed to load analytics", http.StatusInternalServerError)
+		return
+	}
+	topics, err := r.dbService.Queries().ListTopTopicsByViews(ctx, db.ListTopTopicsByViewsParams{
+		Day:   since,
+		Limit: dashboardTopics,
+	})
+	if err != nil {
+		logger.Error("Failed to fetch top topics", "error", err)
+		http.Error(w, "Failed to load analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := components.AdminAnalytics(days, topics).Render(ctx, w); err != nil {
+		logger.Error("Failed to render analytics dashboard", "error", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+	}
+}
+
+// analyticsStore adds buffered counters to the daily stats table
+type analyticsStore struct {
+	dbService *db.Service
+}
+
+func (s analyticsStore) Add(ctx context.Context, key analytics.Key, delta analytics.Counts) error {
+	return s.dbService.Queries().AddTopicStats(ctx, db.AddTopicStatsParams{
+		TopicDid:        key.TopicDid,
+		TopicRkey:       key.TopicRkey,
+		Day:             key.Day,
+		Views:           delta.Views,
+		Messages:        delta.Messages,
+		Participants:    delta.Participants,
+		FirstResponses:  delta.FirstResponses,
+		ResponseSeconds: delta.ResponseSeconds,
+	})
+}
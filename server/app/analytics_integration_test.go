@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestTopicAnalytics_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	responderDID := "did:plc:responder"
+	adminDID := "did:plc:admin"
+	createdAt := time.Now().Add(-2 * time.Hour)
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "stats-topic",
+		Subject:        "Stats Topic",
+		InitialMessage: "Initial message",
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	topicPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey)
+
+	// Each user gets their own router; all of them must flush before stats are read
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID}
+	muxes := make(map[string]*http.ServeMux)
+	var routers []*Router
+	for _, did := range []string{authorDID, responderDID, adminDID} {
+		muxes[did] = http.NewServeMux()
+		routers = append(routers, RegisterTestRoutes(muxes[did], "/", cfg, dbService, did))
+	}
+	serve := func(did, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		muxes[did].ServeHTTP(w, req)
+		return w
+	}
+
+	serve(authorDID, "GET", topicPath+"/messages", "")
+	serve(responderDID, "GET", topicPath+"/messages", "")
+	serve(responderDID, "GET", "/api/topics/did:plc:nobody:missing/messages", "")
+	for did, content := range map[string]string{authorDID: "Bump", responderDID: "First answer"} {
+		if w := serve(did, "POST", topicPath+"/messages", `{"content":"`+content+`"}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(responderDID, "POST", topicPath+"/messages", `{"content":"Follow-up"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	for _, router := range routers {
+		if err := router.analytics.Flush(ctx); err != nil {
+			t.Fatalf("Failed to flush analytics: %v", err)
+		}
+	}
+
+	t.Run("Topic stats", func(t *testing.T) {
+		w := serve(authorDID, "GET", topicPath+"/stats?days=7", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var stats topicStats
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+
+		if stats.Views != 2 || stats.Messages != 3 {
+			t.Errorf("Expected 2 views and 3 messages, got %d and %d", stats.Views, stats.Messages)
+		}
+		if len(stats.Days) != 1 || stats.Days[0].Participants != 2 {
+			t.Errorf("Expected one day with 2 participants, got %+v", stats.Days)
+		}
+		if stats.FirstResponseSeconds == nil || *stats.FirstResponseSeconds < 7200 || *stats.FirstResponseSeconds > 7260 {
+			t.Errorf("Expected first response after about 2h, got %v", stats.FirstResponseSeconds)
+		}
+	})
+
+	t.Run("Unknown topic", func(t *testing.T) {
+		if w := serve(authorDID, "GET", "/api/topics/did:plc:nobody:missing/stats", ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Admin dashboard", func(t *testing.T) {
+		if w := serve(authorDID, "GET", "/admin/analytics", ""); w.Code != http.StatusForbidden {
+			t.Errorf("Expected non-admin to be forbidden, got %d", w.Code)
+		}
+
+		w := serve(adminDID, "GET", "/admin/analytics", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if body := w.Body.String(); !strings.Contains(body, "Stats Topic") || !strings.Contains(body, "2h0m") {
+			t.Errorf("Expected dashboard to list the topic and its response time, got %s", body)
+		}
+	})
+}
@@ -2,68 +2,399 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
 	"github.com/a-h/templ"
 	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
+	"github.com/jrschumacher/dis.quest/internal/analytics"
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/blobstore"
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/config"
 	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
 	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/labeler"
 	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
 	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/internal/related"
+	"github.com/jrschumacher/dis.quest/internal/session"
 	"github.com/jrschumacher/dis.quest/internal/svrlib"
+	"github.com/jrschumacher/dis.quest/internal/trending"
 	"github.com/jrschumacher/dis.quest/internal/validation"
+	"github.com/jrschumacher/dis.quest/pkg/events"
 )
 
 // Router handles application-specific HTTP routes
 type Router struct {
 	*svrlib.Router
 	dbService *db.Service
+	hub       *realtime.Hub
+	firehose  *firehose.Firehose
+	analytics *analytics.Tracker
+	trending  *trending.Cache
+	related   *related.Index
+	streams   *realtime.Streams
+	sessions  *session.Manager
+	tokens    *accesstoken.Manager
+	domains   *domains.Resolver
+	verifier  DomainVerifier
+	blobs     blobstore.Store
+	repos     RepoClients
+	clock     clock.Clock
+	ids       clock.IDGenerator
+	// labeler publishes moderation as atproto labels; nil when disabled
+	labeler *labeler.Labeler
+
+	// authServers fetches the authorization server metadata for an account
+	authServers func(did string) (*auth.AuthorizationServerMetadata, error)
+	// identity finds the PDS serving an account's repository and blobs
+	identity *auth.IdentityResolver
+	// thumbnailSlots holds a token for each thumbnail being made
+	thumbnailSlots chan struct{}
+	// loadConfig reads the configuration for a reload
+	loadConfig func() *config.Config
 }
 
-// RegisterRoutes registers all application routes and returns a Router
-func RegisterRoutes(mux *http.ServeMux, _ string, cfg *config.Config, dbService *db.Service) *Router {
+// newRouter creates a Router over deps. Handler state that lives only as
+// long as the router, such as the live event hub, is created here.
+func newRouter(mux *http.ServeMux, cfg *config.Config, deps *Deps) *Router {
 	router := &Router{
 		Router:    svrlib.NewRouter(mux, "/", cfg),
-		dbService: dbService,
+		dbService: deps.DB,
+		hub:       realtime.NewHub(),
+		firehose:  firehose.New(firehoseStore{dbService: deps.DB}),
+		analytics: analytics.NewTracker(analyticsStore{dbService: deps.DB}),
+		trending:  trending.NewCache(trendingSource{dbService: deps.DB}, trendingLimit),
+		related:   related.NewIndex(relatedSource{dbService: deps.DB}, relatedLimit),
+		streams:   newStreams(cfg),
+		sessions:  deps.Sessions,
+		tokens:    deps.Tokens,
+		domains:   deps.Domains,
+		verifier:  deps.Verifier,
+		blobs:     deps.Blobs,
+		repos:     deps.Repos,
+		clock:     deps.Clock,
+		ids:       deps.IDs,
+
+		authServers:    auth.DiscoverAuthorizationServer,
+		identity:       auth.NewIdentityResolver(),
+		thumbnailSlots: make(chan struct{}, maxThumbnailJobs),
+		loadConfig:     config.Load,
+	}
+	if deps.Labeler != nil {
+		router.labeler = labeler.New(deps.Labeler, labelStore{dbService: deps.DB})
+	}
+	return router
+}
+
+// jobFailed returns the onError callback of the background job named job,
+// which logs each error with message and reports it
+func jobFailed(job, message string) func(error) {
+	return func(err error) {
+		logger.Error(message, "error", err)
+		errreport.Job(job, err)
+	}
+}
+
+// RegisterRoutes registers all application routes and returns a Router
+func RegisterRoutes(mux *http.ServeMux, _ string, cfg *config.Config, deps *Deps) *Router {
+	router := newRouter(mux, cfg, deps)
+	warnAppPasswordFallback(cfg)
+	cfg.OnReload(warnAppPasswordFallback)
+	errreport.Go("analytics", func() {
+		router.analytics.Run(context.Background(), analyticsFlushInterval, jobFailed("analytics", "Failed to flush analytics"))
+	})
+	errreport.Go("trending", func() {
+		router.trending.Run(context.Background(), trendingRefreshInterval, jobFailed("trending", "Failed to compute trending topics"))
+	})
+	errreport.Go("related", func() {
+		router.related.Run(context.Background(), relatedRebuildInterval, jobFailed("related", "Failed to compute related topics"))
+	})
+	if deps.Broker != nil {
+		errreport.Go("live_relay", func() {
+			router.hub.Relay(context.Background(), deps.Broker, jobFailed("live_relay", "Failed to relay live events"))
+		})
+		errreport.Go("firehose_relay", func() {
+			router.firehose.Relay(context.Background(), deps.Broker, jobFailed("firehose_relay", "Failed to relay firehose events"))
+		})
 	}
 
 	// Public routes
-	mux.Handle("/", templ.Handler(components.Page(cfg.AppEnv)))
-	mux.Handle("/login", templ.Handler(components.Login()))
+	// The page is rendered per request, since branding can be reloaded
+	mux.Handle("/", router.communityDomainHome(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		templ.Handler(components.Page(cfg.AppEnv, cfg.Instance())).ServeHTTP(w, req)
+	})))
+	mux.HandleFunc("/login", router.LoginPageHandler)
+	mux.HandleFunc("/subscribe", router.FirehoseHandler)
+	mux.HandleFunc("/lexicons/", router.LexiconsHandler)
+	mux.HandleFunc("/lexicons/{nsid}", router.LexiconsHandler)
 	
 	// Protected routes with clean middleware chains
 	mux.Handle("/discussion", 
 		middleware.WithProtectionFunc(router.DiscussionHandler))
 	
+	mux.Handle("/discussion/{id}",
+		middleware.WithProtectionFunc(router.ThreadPageHandler))
+	
 	mux.Handle("/topics", 
 		middleware.WithUserContextFunc(router.TopicsHandler))
+
+	mux.Handle("/admin/analytics",
+		middleware.WithProtectionFunc(router.AdminAnalyticsHandler))
+
+	mux.Handle("/account/sessions",
+		middleware.WithProtectionFunc(router.SessionsPageHandler))
+
+	mux.Handle("/account/tokens",
+		middleware.WithProtectionFunc(router.TokensPageHandler))
+
+	mux.Handle("/account/delete",
+		middleware.WithProtectionFunc(router.DeleteAccountPageHandler))
+
+	mux.Handle("/c/{slug}",
+		middleware.WithUserContextFunc(router.CommunityPageHandler))
 	
 	// API routes with custom middleware chains
 	mux.Handle("/api/topics", 
 		middleware.WithMiddleware(
 			middleware.UserContextMiddleware,
 		).ThenFunc(router.TopicsAPIHandler))
+
+	mux.Handle("/api/topics/{id}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.TopicAPIHandler))
+
+	mux.Handle("/api/topics/{id}/restore",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.RestoreTopicHandler))
 	
 	mux.Handle("/api/topics/{id}/messages", 
 		middleware.WithMiddleware(
 			middleware.UserContextMiddleware,
 		).ThenFunc(router.MessagesAPIHandler))
 
+	mux.Handle("/api/topics/{id}/events",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.TopicEventsHandler))
+
+	mux.Handle("/api/topics/{id}/typing",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.TypingHandler))
+
+	mux.Handle("/api/topics/{id}/participation",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ParticipationAPIHandler))
+
+	mux.Handle("/api/topics/{id}/read",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ReadMarkerHandler))
+
+	mux.Handle("/api/topics/{id}/stats",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.TopicStatsHandler))
+
+	mux.Handle("/api/events",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.UserEventsHandler))
+
+	mux.Handle("/api/admin/streams",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.StreamStatsHandler))
+
+	mux.Handle("/api/admin/config/reload",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ConfigReloadHandler))
+
+	mux.Handle("/api/admin/queries",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.QueryStatsHandler))
+
+	mux.Handle("/api/admin/participation",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ParticipationReportHandler))
+
+	mux.Handle("/api/messages/{id}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MessageAPIHandler))
+
+	mux.Handle("/api/messages/{id}/restore",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.RestoreMessageHandler))
+
+	mux.HandleFunc("/api/v1/instance", router.InstanceHandler)
+	mux.HandleFunc("/api/v1/events/schema", router.EventSchemaHandler)
+	mux.HandleFunc("/api/v1/trending", router.TrendingHandler)
+	mux.HandleFunc("/api/v1/topics/{id}/related", router.RelatedTopicsHandler)
+	mux.HandleFunc("/api/v1/mirrors/topics", router.MirrorTopicsHandler)
+	mux.HandleFunc("/api/v1/mirrors/topics/{id}/messages", router.MirrorMessagesHandler)
+	mux.HandleFunc("/blob/{did}/{cid}", router.BlobHandler)
+
+	if router.labeler != nil {
+		mux.HandleFunc("/xrpc/com.atproto.label.queryLabels", router.QueryLabelsHandler)
+		mux.HandleFunc("/xrpc/com.atproto.label.subscribeLabels", router.SubscribeLabelsHandler)
+	}
+
+	mux.Handle("/api/v1/actors/{did}/topics",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ActorTopicsHandler))
+
+	mux.Handle("/api/v1/actors/{did}/messages",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.ActorMessagesHandler))
+
+	mux.Handle("/api/v1/me",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MeHandler))
+
+	mux.Handle("/api/v1/me/expiry",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.SessionExpiryHandler))
+
+	mux.Handle("/api/v1/me/sessions",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MySessionsHandler))
+
+	mux.Handle("/api/v1/me/sessions/{id}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MySessionHandler))
+
+	mux.Handle("/api/v1/me/tokens",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MyTokensHandler))
+
+	mux.Handle("/api/v1/me/tokens/{id}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MyTokenHandler))
+
+	mux.Handle("/api/v1/me/deactivate",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.DeactivateAccountHandler))
+
+	mux.Handle("/api/v1/me/records/migrate",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.MigrateRecordsHandler))
+
+	mux.Handle("/api/v1/communities",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunitiesAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/topics",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityTopicsAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/categories/{category}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityCategoryAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/moderators/{did}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityModeratorAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/domains",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityDomainsAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/domains/{domain}",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.CommunityDomainAPIHandler))
+
+	mux.Handle("/api/v1/communities/{slug}/domains/{domain}/verify",
+		middleware.WithMiddleware(
+			middleware.UserContextMiddleware,
+		).ThenFunc(router.VerifyCommunityDomainHandler))
+
 	return router
 }
 
-// DiscussionHandler shows the discussion page with real data
+// LoginPageHandler shows the sign-in form, set up to come back to the page
+// that sent the visitor there
+func (r *Router) LoginPageHandler(w http.ResponseWriter, req *http.Request) {
+	renderPage(w, req, http.StatusOK, components.Login(loginredirect.Target(req, r.Config.PublicDomain)))
+}
+
+// DiscussionHandler shows the discussion page with real data. Its new topic
+// form posts here, so topics can be started without JavaScript.
 func (r *Router) DiscussionHandler(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	
+	status := http.StatusOK
+	var draft components.TopicDraft
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		userCtx, ok := middleware.GetUserContext(req)
+		if !ok {
+			http.Redirect(w, req, loginredirect.LoginURL(req), http.StatusSeeOther)
+			return
+		}
+		draft = components.TopicDraft{Subject: req.FormValue("subject"), InitialMessage: req.FormValue("initial_message")}
+		topic, err := r.createTopic(ctx, userCtx.DID, "", newTopic{Subject: draft.Subject, InitialMessage: draft.InitialMessage})
+		var invalid validation.Errors
+		switch {
+		case errors.As(err, &invalid):
+			status = http.StatusBadRequest
+			draft.Error = "The topic could not be posted: " + invalid.Error()
+		case err != nil:
+			logger.Error("Failed to create topic", "did", userCtx.DID, "error", err)
+			http.Error(w, "Failed to create topic", http.StatusInternalServerError)
+			return
+		default:
+			http.Redirect(w, req, threadPath(topic.Did, topic.Rkey), http.StatusSeeOther)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
 	// Get topics from database
-	_, err := r.dbService.Queries().ListTopics(ctx, db.ListTopicsParams{
+	topics, err := r.dbService.Queries().ListTopics(ctx, db.ListTopicsParams{
 		Limit:  10,
 		Offset: 0,
 	})
@@ -73,14 +404,7 @@ func (r *Router) DiscussionHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	
-	// Render discussion component with real data
-	// TODO: Pass topics data to component once we update the component interface
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	component := components.Discussion()
-	if err := component.Render(ctx, w); err != nil {
-		logger.Error("Failed to render discussion page", "error", err)
-		http.Error(w, "Failed to render page", http.StatusInternalServerError)
-	}
+	renderPage(w, req, status, components.Discussion(topics, draft))
 }
 
 // TopicsHandler shows the topics listing page
@@ -98,9 +422,22 @@ func (r *Router) TopicsHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	
-	// For now, return JSON (later we'll create a proper template)
+	// htmx requests get the server-rendered fragment; everything else gets JSON
+	if isFragmentRequest(req) {
+		renderFragment(w, req, components.TopicList(topics))
+		return
+	}
+	
+	summaries, err := r.withUnreadCounts(ctx, req, topics)
+	if err != nil {
+		logger.Error("Failed to fetch unread counts", "error", err)
+		http.Error(w, "Failed to fetch topics", http.StatusInternalServerError)
+		return
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(topics); err != nil {
+	body := versioned(w, req, summaries, func() any { return newTopicSummaryViews(summaries) })
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		logger.Error("Failed to encode topics", "error", err)
 	}
 }
@@ -109,48 +446,30 @@ func (r *Router) TopicsHandler(w http.ResponseWriter, req *http.Request) {
 func (r *Router) TopicsAPIHandler(w http.ResponseWriter, req *http.Request) {
 	switch req.Method {
 	case http.MethodGet:
-		r.listTopicsAPI(w, req)
+		r.listTopicsAPI(w, req, "")
 	case http.MethodPost:
-		r.createTopicAPI(w, req)
+		r.createTopicAPI(w, req, "")
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func (r *Router) listTopicsAPI(w http.ResponseWriter, req *http.Request) {
+// listTopicsAPI lists topics in one community; the empty slug is the default space
+func (r *Router) listTopicsAPI(w http.ResponseWriter, req *http.Request, community string) {
 	ctx := req.Context()
 	
-	// Parse pagination parameters
-	limitStr := req.URL.Query().Get("limit")
-	offsetStr := req.URL.Query().Get("offset")
-	
-	limit := int64(20) // default
-	if limitStr != "" {
-		if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
+	limit, offset := parsePagination(req)
 	
-	offset := int64(0) // default
-	if offsetStr != "" {
-		if o, err := strconv.ParseInt(offsetStr, 10, 64); err == nil && o >= 0 {
-			offset = o
-		}
+	sort := req.URL.Query().Get("sort")
+	if err := validation.New().OptionalField("sort", sort, validation.OneOf(topicSorts...)).Err(); err != nil {
+		httputil.WriteValidationFailure(w, err)
+		return
 	}
 	
-	topics, err := r.dbService.Queries().ListTopics(ctx, db.ListTopicsParams{
-		Limit:  func() int32 {
-			if limit < 0 || limit > 2147483647 {
-				return 2147483647
-			}
-			return int32(limit) // #nosec G115
-		}(),
-		Offset: func() int32 {
-			if offset < 0 || offset > 2147483647 {
-				return 0
-			}
-			return int32(offset) // #nosec G115
-		}(),
+	topics, err := r.listTopicsSorted(ctx, sort, db.ListTopicsParams{
+		Community: community,
+		Limit:     limit,
+		Offset:    offset,
 	})
 	if err != nil {
 		logger.Error("Failed to fetch topics", "error", err)
@@ -158,15 +477,47 @@ func (r *Router) listTopicsAPI(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	
+	summaries, err := r.withUnreadCounts(ctx, req, topics)
+	if err != nil {
+		logger.Error("Failed to fetch unread counts", "error", err)
+		http.Error(w, "Failed to fetch topics", http.StatusInternalServerError)
+		return
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(topics); err != nil {
+	body := versioned(w, req, summaries, func() any { return newTopicSummaryViews(summaries) })
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		logger.Error("Failed to encode topics", "error", err)
 	}
 }
 
-func (r *Router) createTopicAPI(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	
+// Topic list orderings accepted by the sort query parameter
+const (
+	sortLatest = "latest"
+	sortActive = "active"
+	sortTop    = "top"
+	sortHot    = "hot"
+)
+
+var topicSorts = []string{sortLatest, sortActive, sortTop, sortHot}
+
+// listTopicsSorted lists topics in the requested order, defaulting to latest
+func (r *Router) listTopicsSorted(ctx context.Context, sort string, params db.ListTopicsParams) ([]db.Topic, error) {
+	queries := r.dbService.Queries()
+	switch sort {
+	case sortActive:
+		return queries.ListTopicsByActivity(ctx, db.ListTopicsByActivityParams(params))
+	case sortTop:
+		return queries.ListTopicsByMessageCount(ctx, db.ListTopicsByMessageCountParams(params))
+	case sortHot:
+		return queries.ListTopicsByHotRank(ctx, db.ListTopicsByHotRankParams(params))
+	default:
+		return queries.ListTopics(ctx, params)
+	}
+}
+
+// createTopicAPI creates a topic in one community; the empty slug is the default space
+func (r *Router) createTopicAPI(w http.ResponseWriter, req *http.Request, community string) {
 	// Get user context
 	userCtx, ok := middleware.GetUserContext(req)
 	if !ok {
@@ -175,63 +526,81 @@ func (r *Router) createTopicAPI(w http.ResponseWriter, req *http.Request) {
 	}
 	
 	// Parse request body
-	var createReq struct {
-		Subject        string `json:"subject"`
-		InitialMessage string `json:"initial_message"`
-		Category       string `json:"category,omitempty"`
+	var createReq newTopic
+	if err := httputil.DecodeJSON(w, req, &createReq); err != nil {
+		httputil.WriteDecodeError(w, err)
+		return
 	}
 	
-	if err := json.NewDecoder(req.Body).Decode(&createReq); err != nil {
-		httputil.WriteError(w, http.StatusBadRequest, "Invalid JSON in request body")
+	topic, err := r.createTopic(req.Context(), userCtx.DID, community, createReq)
+	if err != nil {
+		var invalid validation.Errors
+		if errors.As(err, &invalid) {
+			httputil.WriteValidationFailure(w, err)
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to create topic", "did", userCtx.DID, "community", community)
 		return
 	}
 	
+	httputil.WriteCreated(w, versioned(w, req, topic, func() any { return newTopicView(topic) }))
+}
+
+// newTopic is a topic to start, from the API or the discussion page's form
+type newTopic struct {
+	Subject        string `json:"subject"`
+	InitialMessage string `json:"initial_message"`
+	Category       string `json:"category,omitempty"`
+}
+
+// createTopic starts a topic by did in community, with its author as its
+// first participant. Invalid input fails with validation.Errors.
+func (r *Router) createTopic(ctx context.Context, did, community string, in newTopic) (db.Topic, error) {
 	// Validate input
 	validator := validation.TopicValidation{
-		Subject:        createReq.Subject,
-		InitialMessage: createReq.InitialMessage,
-		Category:       createReq.Category,
+		Subject:        in.Subject,
+		InitialMessage: in.InitialMessage,
+		Category:       in.Category,
 	}
 	
 	if err := validator.Validate(); err != nil {
-		if validationErrors, ok := err.(validation.Errors); ok {
-			httputil.WriteValidationError(w, validationErrors)
-		} else {
-			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return db.Topic{}, err
+	}
+	
+	if err := r.validateCategory(ctx, community, in.Category); err != nil {
+		var invalid validation.Errors
+		if errors.As(err, &invalid) {
+			return db.Topic{}, err
 		}
-		return
+		return db.Topic{}, fmt.Errorf("loading categories: %w", err)
 	}
 	
-	// Generate a simple rkey (timestamp-based for now)
-	rkey := fmt.Sprintf("topic-%d", time.Now().UnixNano())
+	rkey := r.ids.NewID("topic")
 	
 	// Create topic with automatic participation using transaction
-	now := time.Now()
+	now := r.clock.Now()
 	result, err := r.dbService.CreateTopicWithParticipation(ctx, db.CreateTopicWithParticipationParams{
-		Did:            userCtx.DID,
+		Did:            did,
 		Rkey:           rkey,
-		Subject:        createReq.Subject,
-		InitialMessage: createReq.InitialMessage,
-		Category:       sql.NullString{String: createReq.Category, Valid: createReq.Category != ""},
+		Subject:        in.Subject,
+		InitialMessage: in.InitialMessage,
+		Category:       sql.NullString{String: in.Category, Valid: in.Category != ""},
 		CreatedAt:      now,
 		UpdatedAt:      now,
+		Community:      community,
 	})
 	if err != nil {
-		httputil.WriteInternalError(w, err, "Failed to create topic", "did", userCtx.DID)
-		return
+		return db.Topic{}, err
 	}
 	
-	httputil.WriteCreated(w, result.Topic)
+	r.publishRecord(ctx, result.Topic.Did, topicCollection, result.Topic.Rkey, firehose.OperationCreate, newTopicRecord(result.Topic))
+	r.publishCreatorParticipation(ctx, result.Participation)
+	return result.Topic, nil
 }
 
 // MessagesAPIHandler handles REST API operations for messages within a topic
 func (r *Router) MessagesAPIHandler(w http.ResponseWriter, req *http.Request) {
-	// Extract topic ID from URL path
-	// Note: In Go 1.22+, we can use path parameters directly
-	topicID := req.URL.Path[len("/api/topics/"):]
-	if idx := len(topicID) - len("/messages"); idx > 0 && topicID[idx:] == "/messages" {
-		topicID = topicID[:idx]
-	}
+	topicID := req.PathValue("id")
 	
 	switch req.Method {
 	case http.MethodGet:
@@ -246,17 +615,15 @@ func (r *Router) MessagesAPIHandler(w http.ResponseWriter, req *http.Request) {
 func (r *Router) listMessagesAPI(w http.ResponseWriter, req *http.Request, topicID string) {
 	ctx := req.Context()
 	
-	// For now, assume topicID format is "did:rkey"
-	// TODO: Implement proper topic ID parsing
-	parts := []string{topicID, topicID} // placeholder
-	if len(parts) != 2 {
-		http.Error(w, "Invalid topic ID format", http.StatusBadRequest)
+	topicDid, topicRkey, err := parseTopicID(topicID)
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	
 	messages, err := r.dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{
-		TopicDid:  parts[0],
-		TopicRkey: parts[1],
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
 	})
 	if err != nil {
 		logger.Error("Failed to fetch messages", "error", err, "topicID", topicID)
@@ -264,15 +631,19 @@ func (r *Router) listMessagesAPI(w http.ResponseWriter, req *http.Request, topic
 		return
 	}
 	
+	// Only count views of topics that exist so stray IDs never reach the stats table
+	if _, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err == nil {
+		r.analytics.View(topicDid, topicRkey)
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(messages); err != nil {
+	body := versioned(w, req, messages, func() any { return newMessageViews(messages) })
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		logger.Error("Failed to encode messages", "error", err)
 	}
 }
 
 func (r *Router) createMessageAPI(w http.ResponseWriter, req *http.Request, topicID string) {
-	ctx := req.Context()
-	
 	// Get user context
 	userCtx, ok := middleware.GetUserContext(req)
 	if !ok {
@@ -281,58 +652,107 @@ func (r *Router) createMessageAPI(w http.ResponseWriter, req *http.Request, topi
 	}
 	
 	// Parse request body
-	var createReq struct {
-		Content           string `json:"content"`
-		ParentMessageRkey string `json:"parent_message_rkey,omitempty"`
+	var createReq newMessage
+	if err := httputil.DecodeJSON(w, req, &createReq); err != nil {
+		httputil.WriteDecodeError(w, err)
+		return
 	}
 	
-	if err := json.NewDecoder(req.Body).Decode(&createReq); err != nil {
-		httputil.WriteError(w, http.StatusBadRequest, "Invalid JSON in request body")
+	message, err := r.createMessage(req.Context(), userCtx.DID, topicID, createReq)
+	if err != nil {
+		var invalid validation.Errors
+		switch {
+		case errors.As(err, &invalid):
+			httputil.WriteValidationFailure(w, err)
+		case errors.Is(err, errInvalidTopicID):
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			httputil.WriteInternalError(w, err, "Failed to create message", "did", userCtx.DID, "topicID", topicID)
+		}
 		return
 	}
 	
+	httputil.WriteCreated(w, versioned(w, req, message, func() any { return newMessageView(message) }))
+}
+
+// newMessage is a message to post, from the API or a thread page's reply form
+type newMessage struct {
+	Content           string `json:"content"`
+	ParentMessageRkey string `json:"parent_message_rkey,omitempty"`
+	ClientID          string `json:"client_id,omitempty"`
+}
+
+// createMessage posts a message by did to the topic topicID and tells the
+// topic's live subscribers. Invalid input fails with validation.Errors, an
+// invalid topic ID with errInvalidTopicID.
+func (r *Router) createMessage(ctx context.Context, did, topicID string, in newMessage) (db.Message, error) {
 	// Validate input
 	validator := validation.MessageValidation{
-		Content:           createReq.Content,
-		ParentMessageRkey: createReq.ParentMessageRkey,
+		Content:           in.Content,
+		ParentMessageRkey: in.ParentMessageRkey,
+		ClientID:          in.ClientID,
 	}
 	
 	if err := validator.Validate(); err != nil {
-		if validationErrors, ok := err.(validation.Errors); ok {
-			httputil.WriteValidationError(w, validationErrors)
-		} else {
-			httputil.WriteError(w, http.StatusBadRequest, err.Error())
-		}
-		return
+		return db.Message{}, err
 	}
 	
-	// For now, assume topicID format is "did:rkey"
-	// TODO: Implement proper topic ID parsing
-	parts := []string{topicID, topicID} // placeholder
-	if len(parts) != 2 {
-		httputil.WriteError(w, http.StatusBadRequest, "Invalid topic ID format")
-		return
+	topicDid, topicRkey, err := parseTopicID(topicID)
+	if err != nil {
+		return db.Message{}, err
+	}
+	
+	hubTopicID := formatTopicID(topicDid, topicRkey)
+	r.hub.ClearTyping(hubTopicID, did)
+	if in.ClientID != "" {
+		r.hub.PublishTo(hubTopicID, did, realtime.Event{
+			Type: realtime.EventMessagePending,
+			Data: events.PendingMessage{
+				ClientID:          in.ClientID,
+				TopicID:           hubTopicID,
+				Author:            did,
+				Content:           in.Content,
+				ParentMessageRkey: in.ParentMessageRkey,
+			},
+		})
 	}
 	
-	// Generate a simple rkey for the message
-	rkey := fmt.Sprintf("msg-%d", time.Now().UnixNano())
+	rkey := r.ids.NewID("msg")
 	
 	// Create message
-	now := time.Now()
-	message, err := r.dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
-		Did:               userCtx.DID,
+	now := r.clock.Now()
+	created, err := r.dbService.CreateMessageWithActivity(ctx, db.CreateMessageParams{
+		Did:               did,
 		Rkey:              rkey,
-		TopicDid:          parts[0],
-		TopicRkey:         parts[1],
-		ParentMessageRkey: sql.NullString{String: createReq.ParentMessageRkey, Valid: createReq.ParentMessageRkey != ""},
-		Content:           createReq.Content,
+		TopicDid:          topicDid,
+		TopicRkey:         topicRkey,
+		ParentMessageRkey: sql.NullString{String: in.ParentMessageRkey, Valid: in.ParentMessageRkey != ""},
+		Content:           db.MessageContent(in.Content),
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	})
 	if err != nil {
-		httputil.WriteInternalError(w, err, "Failed to create message", "did", userCtx.DID, "topicID", topicID)
-		return
+		if in.ClientID != "" {
+			r.hub.PublishTo(hubTopicID, did, realtime.Event{
+				Type: realtime.EventMessageFailed,
+				Data: events.FailedMessage{ClientID: in.ClientID, Error: "Failed to create message"},
+			})
+		}
+		return db.Message{}, err
 	}
 	
-	httputil.WriteCreated(w, message)
+	message := created.Message
+	r.notifyUnread(ctx, topicDid, topicRkey, did)
+	r.recordMessageStats(ctx, topicDid, topicRkey, did, now)
+	r.publishRecord(ctx, message.Did, messageCollection, message.Rkey, firehose.OperationCreate, newMessageRecord(message))
+	
+	r.hub.Publish(hubTopicID, realtime.Event{
+		Type: realtime.EventMessageConfirmed,
+		Data: events.ConfirmedMessage{
+			ClientID: in.ClientID,
+			URI:      messageURI(message.Did, message.Rkey),
+			Message:  newEventMessage(message),
+		},
+	})
+	return message, nil
 }
@@ -4,33 +4,99 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/blobstore"
+	"github.com/jrschumacher/dis.quest/internal/clock"
 	"github.com/jrschumacher/dis.quest/internal/config"
 	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
 	"github.com/jrschumacher/dis.quest/internal/middleware"
 )
 
-// RegisterTestRoutes registers routes with test middleware for testing
-func RegisterTestRoutes(mux *http.ServeMux, _ string, _ *config.Config, dbService *db.Service, testUserDID string) *Router {
-	router := &Router{
-		Router:    nil, // We don't need the full router for tests
-		dbService: dbService,
+// testDeps wires database-backed and in-memory services for tests. PDS and
+// domain verification keep their real implementations; tests that reach
+// them swap in fakes on the returned Router. Tests that need fixed times or
+// keys replace the clock and ID generator the same way.
+func testDeps(dbService *db.Service) *Deps {
+	clk := clock.System{}
+	return &Deps{
+		DB:       dbService,
+		Sessions: NewSessionManager(dbService, clk),
+		Tokens:   NewTokenManager(dbService, clk),
+		Domains:  NewDomainResolver(dbService),
+		Verifier: domains.NewVerifier(),
+		Blobs:    blobstore.NewMemory(),
+		Repos:    pdsRepos{resolve: auth.NewIdentityResolver().ResolvePDS},
+		Clock:    clk,
+		IDs:      clock.NewNanoIDs(clk),
 	}
+}
+
+// RegisterTestRoutes registers routes with test middleware for testing
+func RegisterTestRoutes(mux *http.ServeMux, _ string, cfg *config.Config, dbService *db.Service, testUserDID string) *Router {
+	router := newRouter(mux, cfg, testDeps(dbService))
 
 	// Public routes (same as production)
-	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	mux.Handle("/", router.communityDomainHome(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		_, _ = w.Write([]byte("test home"))
-	}))
-	mux.Handle("/login", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		_, _ = w.Write([]byte("test login"))
-	}))
+	})))
+	mux.HandleFunc("/login", router.LoginPageHandler)
+	mux.HandleFunc("/subscribe", router.FirehoseHandler)
+	mux.HandleFunc("/lexicons/", router.LexiconsHandler)
+	mux.HandleFunc("/lexicons/{nsid}", router.LexiconsHandler)
 	
 	// Protected routes with test middleware
 	testChain := middleware.TestProtectedChain(testUserDID)
 	
 	mux.Handle("/discussion", testChain.ThenFunc(router.DiscussionHandler))
+	mux.Handle("/discussion/{id}", testChain.ThenFunc(router.ThreadPageHandler))
 	mux.Handle("/topics", testChain.ThenFunc(router.TopicsHandler))
+	mux.Handle("/admin/analytics", testChain.ThenFunc(router.AdminAnalyticsHandler))
+	mux.Handle("/account/sessions", testChain.ThenFunc(router.SessionsPageHandler))
+	mux.Handle("/account/tokens", testChain.ThenFunc(router.TokensPageHandler))
+	mux.Handle("/account/delete", testChain.ThenFunc(router.DeleteAccountPageHandler))
+	mux.Handle("/c/{slug}", testChain.ThenFunc(router.CommunityPageHandler))
 	mux.Handle("/api/topics", testChain.ThenFunc(router.TopicsAPIHandler))
+	mux.Handle("/api/topics/{id}", testChain.ThenFunc(router.TopicAPIHandler))
+	mux.Handle("/api/topics/{id}/restore", testChain.ThenFunc(router.RestoreTopicHandler))
 	mux.Handle("/api/topics/{id}/messages", testChain.ThenFunc(router.MessagesAPIHandler))
+	mux.Handle("/api/topics/{id}/events", testChain.ThenFunc(router.TopicEventsHandler))
+	mux.Handle("/api/topics/{id}/typing", testChain.ThenFunc(router.TypingHandler))
+	mux.Handle("/api/topics/{id}/participation", testChain.ThenFunc(router.ParticipationAPIHandler))
+	mux.Handle("/api/topics/{id}/read", testChain.ThenFunc(router.ReadMarkerHandler))
+	mux.Handle("/api/topics/{id}/stats", testChain.ThenFunc(router.TopicStatsHandler))
+	mux.Handle("/api/events", testChain.ThenFunc(router.UserEventsHandler))
+	mux.Handle("/api/admin/streams", testChain.ThenFunc(router.StreamStatsHandler))
+	mux.Handle("/api/admin/config/reload", testChain.ThenFunc(router.ConfigReloadHandler))
+	mux.Handle("/api/admin/queries", testChain.ThenFunc(router.QueryStatsHandler))
+	mux.Handle("/api/admin/participation", testChain.ThenFunc(router.ParticipationReportHandler))
+	mux.Handle("/api/messages/{id}", testChain.ThenFunc(router.MessageAPIHandler))
+	mux.Handle("/api/messages/{id}/restore", testChain.ThenFunc(router.RestoreMessageHandler))
+	mux.HandleFunc("/api/v1/instance", router.InstanceHandler)
+	mux.HandleFunc("/api/v1/events/schema", router.EventSchemaHandler)
+	mux.HandleFunc("/api/v1/trending", router.TrendingHandler)
+	mux.HandleFunc("/api/v1/topics/{id}/related", router.RelatedTopicsHandler)
+	mux.HandleFunc("/api/v1/mirrors/topics", router.MirrorTopicsHandler)
+	mux.HandleFunc("/api/v1/mirrors/topics/{id}/messages", router.MirrorMessagesHandler)
+	mux.HandleFunc("/blob/{did}/{cid}", router.BlobHandler)
+	mux.Handle("/api/v1/actors/{did}/topics", testChain.ThenFunc(router.ActorTopicsHandler))
+	mux.Handle("/api/v1/actors/{did}/messages", testChain.ThenFunc(router.ActorMessagesHandler))
+	mux.Handle("/api/v1/me", testChain.ThenFunc(router.MeHandler))
+	mux.Handle("/api/v1/me/expiry", testChain.ThenFunc(router.SessionExpiryHandler))
+	mux.Handle("/api/v1/me/sessions", testChain.ThenFunc(router.MySessionsHandler))
+	mux.Handle("/api/v1/me/sessions/{id}", testChain.ThenFunc(router.MySessionHandler))
+	mux.Handle("/api/v1/me/tokens", testChain.ThenFunc(router.MyTokensHandler))
+	mux.Handle("/api/v1/me/tokens/{id}", testChain.ThenFunc(router.MyTokenHandler))
+	mux.Handle("/api/v1/me/deactivate", testChain.ThenFunc(router.DeactivateAccountHandler))
+	mux.Handle("/api/v1/me/records/migrate", testChain.ThenFunc(router.MigrateRecordsHandler))
+	mux.Handle("/api/v1/communities", testChain.ThenFunc(router.CommunitiesAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}", testChain.ThenFunc(router.CommunityAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/topics", testChain.ThenFunc(router.CommunityTopicsAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/categories/{category}", testChain.ThenFunc(router.CommunityCategoryAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/moderators/{did}", testChain.ThenFunc(router.CommunityModeratorAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/domains", testChain.ThenFunc(router.CommunityDomainsAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/domains/{domain}", testChain.ThenFunc(router.CommunityDomainAPIHandler))
+	mux.Handle("/api/v1/communities/{slug}/domains/{domain}/verify", testChain.ThenFunc(router.VerifyCommunityDomainHandler))
 
 	return router
 }
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+func BenchmarkMessageRecord(b *testing.B) {
+	message := db.Message{
+		Did:       "did:plc:author",
+		Rkey:      "msg-1",
+		TopicDid:  "did:plc:author",
+		TopicRkey: "topic-1",
+		Content:   db.MessageContent(strings.Repeat("A reasonably long reply. ", 20)),
+		CreatedAt: time.Now(),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mv := validation.MessageValidation{Content: string(message.Content)}
+		if err := mv.Validate(); err != nil {
+			b.Fatalf("Validate: %v", err)
+		}
+		if _, err := json.Marshal(newMessageRecord(message)); err != nil {
+			b.Fatalf("Marshal: %v", err)
+		}
+	}
+}
+
+func BenchmarkTopicsAPI(b *testing.B) {
+	dbService := testutil.TestDatabase(b)
+	mux := http.NewServeMux()
+	RegisterTestRoutes(mux, "/", nil, dbService, "did:plc:author")
+
+	serve := func(method, path, body string, want int) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != want {
+			b.Fatalf("%s %s: expected status %d, got %d: %s", method, path, want, w.Code, w.Body.String())
+		}
+	}
+	for i := 0; i < 100; i++ {
+		serve("POST", "/api/topics", `{"subject":"Benchmark topic","initial_message":"Benchmark topic body"}`, http.StatusCreated)
+	}
+	topics, err := dbService.Queries().ListTopics(context.Background(), db.ListTopicsParams{Limit: 1})
+	if err != nil || len(topics) == 0 {
+		b.Fatalf("Failed to fetch seeded topic: %v", err)
+	}
+	messagesPath := "/api/topics/" + formatTopicID(topics[0].Did, topics[0].Rkey) + "/messages"
+
+	b.Run("ListTopics", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			serve("GET", "/api/topics?sort=hot&limit=20", "", http.StatusOK)
+		}
+	})
+	b.Run("CreateMessage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			serve("POST", messagesPath, `{"content":"Benchmark reply"}`, http.StatusCreated)
+		}
+	})
+	b.Run("ListMessages", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			serve("GET", messagesPath, "", http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/jrschumacher/dis.quest/internal/blobstore"
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/thumbnail"
+)
+
+const (
+	// maxThumbnailJobs bounds the images decoded at once, each of which may
+	// take tens of megabytes
+	maxThumbnailJobs = 4
+	// thumbnailRetryAfter is the Retry-After hint sent when all of them are
+	// busy
+	thumbnailRetryAfter = "5"
+)
+
+// blobImageTypes are the sniffed content types the blob proxy serves.
+// Anything else could be a page or script that would run on our origin.
+var blobImageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// BlobHandler serves an image blob from an actor's repository once it
+// matches its CID. With ?size= set to one of the configured thumbnail
+// widths it serves the image scaled down, made once and then cached in the
+// blob store, answering 503 while maxThumbnailJobs others are being made.
+// Images the standard library cannot decode, such as WebP, are served at
+// their own size.
+func (r *Router) BlobHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	did, ok := actorDID(w, req)
+	if !ok {
+		return
+	}
+	cid, err := dagcbor.ParseCID(req.PathValue("cid"))
+	if err != nil || cid.Codec() != dagcbor.CodecRaw {
+		httputil.WriteError(w, http.StatusBadRequest, "Invalid blob CID")
+		return
+	}
+	width := 0
+	if size := req.URL.Query().Get("size"); size != "" {
+		width, err = strconv.Atoi(size)
+		if err != nil || !slices.Contains(r.Config.ThumbnailWidths(), width) {
+			httputil.WriteError(w, http.StatusBadRequest, "Unsupported thumbnail size")
+			return
+		}
+	}
+
+	key := fmt.Sprintf("thumbnails/%s/%s/%d", did, cid, width)
+	if width > 0 {
+		obj, err := r.blobs.Get(req.Context(), key)
+		if err == nil {
+			defer func() { _ = obj.Body.Close() }()
+			writeBlob(w, obj.ContentType, obj.Body)
+			return
+		}
+		if !errors.Is(err, blobstore.ErrNotFound) {
+			logger.Warn("Failed to read cached thumbnail", "key", key, "error", err)
+		}
+	}
+
+	host, err := r.identity.ResolvePDS(req.Context(), did)
+	if err != nil {
+		httputil.WriteError(w, http.StatusNotFound, "Could not find the account's PDS", "did", did, "error", err)
+		return
+	}
+	data, err := pds.NewClient(host, pds.Anonymous{}, nil).GetBlob(req.Context(), did, cid.String())
+	var xrpcErr *pds.XRPCError
+	switch {
+	case err == nil:
+	case pds.IsUnavailable(err):
+		httputil.WritePDSUnavailable(w, pds.RetryAfter(err), "did", did, "error", err)
+		return
+	case errors.As(err, &xrpcErr) && xrpcErr.Status < http.StatusInternalServerError:
+		httputil.WriteError(w, http.StatusNotFound, "Blob not found")
+		return
+	default:
+		httputil.WriteError(w, http.StatusBadGateway, "Failed to fetch the blob from its PDS", "did", did, "error", err)
+		return
+	}
+	if !cid.Matches(data) {
+		httputil.WriteError(w, http.StatusBadGateway, "The PDS served a blob that does not match its CID", "did", did, "cid", cid.String())
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(blobImageTypes, contentType) {
+		httputil.WriteError(w, http.StatusUnsupportedMediaType, "Only image blobs are served")
+		return
+	}
+	if width == 0 {
+		writeBlob(w, contentType, bytes.NewReader(data))
+		return
+	}
+
+	select {
+	case r.thumbnailSlots <- struct{}{}:
+		defer func() { <-r.thumbnailSlots }()
+	default:
+		w.Header().Set("Retry-After", thumbnailRetryAfter)
+		httputil.WriteError(w, http.StatusServiceUnavailable, "Too many thumbnails are being made")
+		return
+	}
+	thumb, err := thumbnail.Make(data, width)
+	switch {
+	case errors.Is(err, thumbnail.ErrUnsupported):
+		writeBlob(w, contentType, bytes.NewReader(data))
+		return
+	case errors.Is(err, thumbnail.ErrTooLarge):
+		httputil.WriteError(w, http.StatusUnprocessableEntity, "Image too large to thumbnail")
+		return
+	case err != nil:
+		httputil.WriteError(w, http.StatusUnprocessableEntity, "Image could not be decoded", "did", did, "cid", cid.String(), "error", err)
+		return
+	}
+	if err := r.blobs.Put(req.Context(), key, bytes.NewReader(thumb.Data), thumb.ContentType); err != nil {
+		logger.Warn("Failed to cache thumbnail", "key", key, "error", err)
+	}
+	writeBlob(w, thumb.ContentType, bytes.NewReader(thumb.Data))
+}
+
+// writeBlob writes an image. A CID names its content forever, so browsers
+// and CDNs may keep it without revalidating.
+func writeBlob(w http.ResponseWriter, contentType string, body io.Reader) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.Copy(w, body)
+}
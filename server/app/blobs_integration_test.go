@@ -0,0 +1,142 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/dagcbor"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestBlobProxy_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	const did = "did:plc:artist"
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0x40, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	picture := buf.Bytes()
+	page := []byte("<!DOCTYPE html><script>alert(1)</script>")
+
+	pictureCID := dagcbor.Sum(dagcbor.CodecRaw, picture).String()
+	pageCID := dagcbor.Sum(dagcbor.CodecRaw, page).String()
+	swappedCID := dagcbor.Sum(dagcbor.CodecRaw, []byte("the original")).String()
+	missingCID := dagcbor.Sum(dagcbor.CodecRaw, []byte("never uploaded")).String()
+	blobs := map[string][]byte{pictureCID: picture, pageCID: page, swappedCID: picture}
+
+	// One server plays the PLC directory and the account's own PDS, which
+	// is not bsky.social
+	var pdsURL string
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + did:
+			_, _ = fmt.Fprintf(w, `{"id":%q,"service":[{"id":"#atproto_pds","type":"AtprotoPersonalDataServer","serviceEndpoint":%q}]}`, did, pdsURL)
+			return
+		case "/did:plc:nopds":
+			_, _ = fmt.Fprint(w, `{"id":"did:plc:nopds"}`)
+			return
+		}
+		blob, ok := blobs[r.URL.Query().Get("cid")]
+		if r.URL.Path != "/xrpc/com.atproto.sync.getBlob" || r.URL.Query().Get("did") != did || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "BlobNotFound"})
+			return
+		}
+		fetches++
+		_, _ = w.Write(blob)
+	}))
+	defer srv.Close()
+	pdsURL = srv.URL
+
+	mux := http.NewServeMux()
+	cfg := &config.Config{AppEnv: "test", DatabaseURL: ":memory:", ThumbnailSizes: "160 320"}
+	router := RegisterTestRoutes(mux, "/", cfg, dbService, did)
+	router.identity = &auth.IdentityResolver{HTTPClient: srv.Client(), PLCDirectory: srv.URL, Scheme: "http"}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/blob/" + did + "/" + pictureCID)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), picture) {
+		t.Fatalf("original: status %d, %d bytes", w.Code, w.Body.Len())
+	}
+	for header, want := range map[string]string{
+		"Content-Type":           "image/png",
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	for i := range 2 {
+		w = get("/blob/" + did + "/" + pictureCID + "?size=160")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("thumbnail %d: status %d, type %s: %s", i, w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+		thumb, err := jpeg.Decode(w.Body)
+		if err != nil {
+			t.Fatalf("thumbnail is not a JPEG: %v", err)
+		}
+		if got := thumb.Bounds().Size(); got != image.Pt(160, 80) {
+			t.Errorf("thumbnail size = %v, want 160x80", got)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("PDS fetched %d times, want 2: the second thumbnail should come from the cache", fetches)
+	}
+
+	// Thumbnails past the limit being made at once are refused for now
+	for range maxThumbnailJobs {
+		router.thumbnailSlots <- struct{}{}
+	}
+	w = get("/blob/" + did + "/" + pictureCID + "?size=320")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("thumbnail while all slots are busy: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	for range maxThumbnailJobs {
+		<-router.thumbnailSlots
+	}
+	if w = get("/blob/" + did + "/" + pictureCID + "?size=320"); w.Code != http.StatusOK {
+		t.Errorf("thumbnail once slots are free: status %d", w.Code)
+	}
+
+	for _, tc := range []struct {
+		name, path string
+		want       int
+	}{
+		{"unlisted size", "/blob/" + did + "/" + pictureCID + "?size=100", http.StatusBadRequest},
+		{"malformed CID", "/blob/" + did + "/not-a-cid", http.StatusBadRequest},
+		{"record CID", "/blob/" + did + "/" + dagcbor.Sum(dagcbor.CodecDAGCBOR, picture).String(), http.StatusBadRequest},
+		{"malformed DID", "/blob/artist/" + pictureCID, http.StatusBadRequest},
+		{"not an image", "/blob/" + did + "/" + pageCID, http.StatusUnsupportedMediaType},
+		{"CID mismatch", "/blob/" + did + "/" + swappedCID, http.StatusBadGateway},
+		{"missing", "/blob/" + did + "/" + missingCID, http.StatusNotFound},
+		{"no PDS", "/blob/did:plc:nopds/" + pictureCID, http.StatusNotFound},
+	} {
+		if w := get(tc.path); w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, w.Code, tc.want, w.Body)
+		}
+	}
+}
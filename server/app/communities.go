@@ -0,0 +1,346 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+// communityView is a community with its category taxonomy and moderators
+type communityView struct {
+	db.Community
+	Categories []db.CommunityCategory `json:"categories"`
+	Moderators []string               `json:"moderators"`
+}
+
+// createCommunityRequest is the body of POST /api/v1/communities
+type createCommunityRequest struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// categoryRequest is the body of PUT /api/v1/communities/{slug}/categories/{category}
+type categoryRequest struct {
+	Name string `json:"name"`
+}
+
+// CommunitiesAPIHandler lists communities (GET) or creates one (POST). Only
+// admins can create communities; the creator becomes the first moderator.
+func (r *Router) CommunitiesAPIHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		limit, offset := parsePagination(req)
+		communities, err := r.dbService.Queries().ListCommunities(req.Context(), db.ListCommunitiesParams{
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to fetch communities")
+			return
+		}
+		httputil.WriteSuccess(w, communities)
+	case http.MethodPost:
+		r.createCommunityAPI(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Router) createCommunityAPI(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var createReq createCommunityRequest
+	if err := httputil.DecodeJSON(w, req, &createReq); err != nil {
+		httputil.WriteDecodeError(w, err)
+		return
+	}
+	validator := validation.CommunityValidation{
+		Slug:        createReq.Slug,
+		Name:        createReq.Name,
+		Description: createReq.Description,
+	}
+	if err := validator.Validate(); err != nil {
+		httputil.WriteValidationFailure(w, err)
+		return
+	}
+
+	if _, err := r.dbService.Queries().GetCommunity(ctx, createReq.Slug); err == nil {
+		httputil.WriteError(w, http.StatusConflict, "A community with this slug already exists")
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		httputil.WriteInternalError(w, err, "Failed to check community", "slug", createReq.Slug)
+		return
+	}
+
+	community, err := r.dbService.CreateCommunityWithModerator(ctx, db.CreateCommunityParams{
+		Slug:        createReq.Slug,
+		Name:        createReq.Name,
+		Description: createReq.Description,
+		CreatedBy:   userCtx.DID,
+		CreatedAt:   r.clock.Now(),
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to create community", "slug", createReq.Slug)
+		return
+	}
+
+	httputil.WriteCreated(w, community)
+}
+
+// CommunityAPIHandler returns one community with its categories and moderators
+func (r *Router) CommunityAPIHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	community, ok := r.lookupCommunity(w, req)
+	if !ok {
+		return
+	}
+	httputil.WriteSuccess(w, community)
+}
+
+// CommunityTopicsAPIHandler lists (GET) or creates (POST) topics in a
+// community. Topics created here only appear in the community's listings.
+func (r *Router) CommunityTopicsAPIHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	community, ok := r.lookupCommunity(w, req)
+	if !ok {
+		return
+	}
+	if req.Method == http.MethodGet {
+		r.listTopicsAPI(w, req, community.Slug)
+		return
+	}
+	r.createTopicAPI(w, req, community.Slug)
+}
+
+// CommunityCategoryAPIHandler adds or renames (PUT) or removes (DELETE) a
+// category in a community's taxonomy. Community moderators and admins only.
+func (r *Router) CommunityCategoryAPIHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	community, ok := r.lookupCommunity(w, req)
+	if !ok {
+		return
+	}
+	if !r.isAdmin(userCtx.DID) && !slices.Contains(community.Moderators, userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Only a moderator of this community can change its categories")
+		return
+	}
+	slug := req.PathValue("category")
+
+	switch req.Method {
+	case http.MethodPut:
+		var categoryReq categoryRequest
+		if err := httputil.DecodeJSON(w, req, &categoryReq); err != nil {
+			httputil.WriteDecodeError(w, err)
+			return
+		}
+		validator := validation.CategoryValidation{Slug: slug, Name: categoryReq.Name}
+		if err := validator.Validate(); err != nil {
+			httputil.WriteValidationFailure(w, err)
+			return
+		}
+		category, err := r.dbService.Queries().UpsertCommunityCategory(ctx, db.UpsertCommunityCategoryParams{
+			Community: community.Slug,
+			Slug:      slug,
+			Name:      categoryReq.Name,
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to save category", "community", community.Slug, "category", slug)
+			return
+		}
+		httputil.WriteSuccess(w, category)
+	case http.MethodDelete:
+		affected, err := r.dbService.Queries().DeleteCommunityCategory(ctx, db.DeleteCommunityCategoryParams{
+			Community: community.Slug,
+			Slug:      slug,
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to delete category", "community", community.Slug, "category", slug)
+			return
+		}
+		if affected == 0 {
+			httputil.WriteError(w, http.StatusNotFound, "Category not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CommunityModeratorAPIHandler adds (PUT) or removes (DELETE) a community
+// moderator. Admins only.
+func (r *Router) CommunityModeratorAPIHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+	community, ok := r.lookupCommunity(w, req)
+	if !ok {
+		return
+	}
+	did := req.PathValue("did")
+	if err := validation.New().Field("did", did, validation.DID()).Err(); err != nil {
+		httputil.WriteValidationFailure(w, err)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		if err := r.dbService.Queries().AddCommunityModerator(ctx, db.AddCommunityModeratorParams{
+			Community: community.Slug,
+			Did:       did,
+			CreatedAt: r.clock.Now(),
+		}); err != nil {
+			httputil.WriteInternalError(w, err, "Failed to add moderator", "community", community.Slug, "did", did)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		affected, err := r.dbService.Queries().RemoveCommunityModerator(ctx, db.RemoveCommunityModeratorParams{
+			Community: community.Slug,
+			Did:       did,
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to remove moderator", "community", community.Slug, "did", did)
+			return
+		}
+		if affected == 0 {
+			httputil.WriteError(w, http.StatusNotFound, "Moderator not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CommunityPageHandler shows a community's latest topics
+func (r *Router) CommunityPageHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	community, err := r.getCommunity(ctx, req.PathValue("slug"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch community", "error", err, "slug", req.PathValue("slug"))
+		http.Error(w, "Failed to load community", http.StatusInternalServerError)
+		return
+	}
+
+	topics, err := r.dbService.Queries().ListTopics(ctx, db.ListTopicsParams{
+		Community: community.Slug,
+		Limit:     defaultPageLimit,
+	})
+	if err != nil {
+		logger.Error("Failed to fetch topics", "error", err, "community", community.Slug)
+		http.Error(w, "Failed to load community", http.StatusInternalServerError)
+		return
+	}
+
+	renderPage(w, req, http.StatusOK, components.Community(community.Community, community.Categories, topics))
+}
+
+// lookupCommunity loads the community named by the slug path value, writing
+// a 404 or 500 response and returning false when it cannot
+func (r *Router) lookupCommunity(w http.ResponseWriter, req *http.Request) (*communityView, bool) {
+	slug := req.PathValue("slug")
+	community, err := r.getCommunity(req.Context(), slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		httputil.WriteError(w, http.StatusNotFound, "Community not found")
+		return nil, false
+	}
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to fetch community", "slug", slug)
+		return nil, false
+	}
+	return community, true
+}
+
+// getCommunity loads a community with its categories and moderators
+func (r *Router) getCommunity(ctx context.Context, slug string) (*communityView, error) {
+	queries := r.dbService.Queries()
+	community, err := queries.GetCommunity(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := queries.ListCommunityCategories(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	moderators, err := queries.ListCommunityModerators(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &communityView{Community: community, Categories: categories, Moderators: make([]string, 0, len(moderators))}
+	for _, m := range moderators {
+		view.Moderators = append(view.Moderators, m.Did)
+	}
+	return view, nil
+}
+
+// validateCategory checks a topic's category against its community's
+// taxonomy. The default space and communities without categories accept any
+// category, as topics always have.
+func (r *Router) validateCategory(ctx context.Context, community, category string) error {
+	if community == "" || category == "" {
+		return nil
+	}
+	categories, err := r.dbService.Queries().ListCommunityCategories(ctx, community)
+	if err != nil {
+		return err
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	allowed := make([]string, 0, len(categories))
+	for _, c := range categories {
+		allowed = append(allowed, c.Slug)
+	}
+	return validation.New().Field("category", category, validation.OneOf(allowed...)).Err()
+}
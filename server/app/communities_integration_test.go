@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestCommunities_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	adminDID := "did:plc:admin"
+	moderatorDID := "did:plc:moderator"
+	memberDID := "did:plc:member"
+
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID}
+	serve := func(did, method, path, body string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		RegisterTestRoutes(mux, "/", cfg, dbService, did)
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	listTopics := func(path string) []db.Topic {
+		t.Helper()
+		w := serve(memberDID, "GET", path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 listing %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var topics []db.Topic
+		if err := json.NewDecoder(w.Body).Decode(&topics); err != nil {
+			t.Fatalf("Failed to decode topics: %v", err)
+		}
+		return topics
+	}
+
+	t.Run("Only admins create communities", func(t *testing.T) {
+		body := `{"slug":"golang","name":"Go"}`
+		if w := serve(memberDID, "POST", "/api/v1/communities", body); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403, got %d", w.Code)
+		}
+		if w := serve(adminDID, "POST", "/api/v1/communities", body); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := serve(adminDID, "POST", "/api/v1/communities", body); w.Code != http.StatusConflict {
+			t.Fatalf("Expected status 409 for a duplicate slug, got %d", w.Code)
+		}
+		if w := serve(adminDID, "POST", "/api/v1/communities", `{"slug":"Bad Slug","name":"Bad"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for an invalid slug, got %d", w.Code)
+		}
+	})
+
+	t.Run("Admins appoint moderators who manage categories", func(t *testing.T) {
+		if w := serve(moderatorDID, "PUT", "/api/v1/communities/golang/moderators/"+moderatorDID, ""); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403, got %d", w.Code)
+		}
+		if w := serve(adminDID, "PUT", "/api/v1/communities/golang/moderators/"+moderatorDID, ""); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := serve(memberDID, "PUT", "/api/v1/communities/golang/categories/help", `{"name":"Help"}`); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403, got %d", w.Code)
+		}
+		if w := serve(moderatorDID, "PUT", "/api/v1/communities/golang/categories/help", `{"name":"Help"}`); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w := serve(memberDID, "GET", "/api/v1/communities/golang", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var got communityView
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode community: %v", err)
+		}
+		if len(got.Categories) != 1 || got.Categories[0].Slug != "help" {
+			t.Errorf("Unexpected categories: %+v", got.Categories)
+		}
+		if len(got.Moderators) != 2 {
+			t.Errorf("Expected creator and appointed moderator, got %v", got.Moderators)
+		}
+	})
+
+	t.Run("Topics are isolated per community", func(t *testing.T) {
+		path := "/api/v1/communities/golang/topics"
+		if w := serve(memberDID, "POST", path, `{"subject":"Generics","initial_message":"How do I constrain this?","category":"offtopic"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for a category outside the taxonomy, got %d", w.Code)
+		}
+		if w := serve(memberDID, "POST", path, `{"subject":"Generics","initial_message":"How do I constrain this?","category":"help"}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := serve(memberDID, "POST", "/api/topics", `{"subject":"Hello","initial_message":"Hello default space","category":"anything"}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+
+		community := listTopics(path)
+		if len(community) != 1 || community[0].Community != "golang" {
+			t.Fatalf("Expected only the community topic, got %+v", community)
+		}
+		for _, topic := range listTopics("/api/topics") {
+			if topic.Community != "" {
+				t.Errorf("Community topic %s leaked into the default space", topic.Rkey)
+			}
+		}
+		if w := serve(memberDID, "GET", "/api/v1/communities/missing/topics", ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for an unknown community, got %d", w.Code)
+		}
+	})
+
+	t.Run("Moderators hide content only in their community", func(t *testing.T) {
+		now := time.Now()
+		for _, topic := range []db.CreateTopicParams{
+			{Did: memberDID, Rkey: "in-community", Subject: "Spam", InitialMessage: "Spam", Community: "golang"},
+			{Did: memberDID, Rkey: "elsewhere", Subject: "Spam", InitialMessage: "Spam"},
+		} {
+			topic.CreatedAt, topic.UpdatedAt = now, now
+			if _, err := dbService.Queries().CreateTopic(ctx, topic); err != nil {
+				t.Fatalf("Failed to create test topic: %v", err)
+			}
+		}
+
+		if w := serve(moderatorDID, "DELETE", "/api/topics/"+formatTopicID(memberDID, "elsewhere"), ""); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 outside the community, got %d", w.Code)
+		}
+		if w := serve(moderatorDID, "DELETE", "/api/topics/"+formatTopicID(memberDID, "in-community"), ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := serve(memberDID, "POST", "/api/topics/"+formatTopicID(memberDID, "in-community")+"/restore", ""); w.Code != http.StatusForbidden {
+			t.Errorf("Expected author to be unable to undo a moderator hide, got %d", w.Code)
+		}
+		if _, err := dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: memberDID, Rkey: "in-community"}); err != sql.ErrNoRows {
+			t.Errorf("Expected hidden topic to be gone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+// ConfigReloadHandler rereads the configuration file and environment and
+// applies the settings that can change while running, as SIGHUP does. It
+// reports which changed settings took effect and which need a restart.
+func (r *Router) ConfigReloadHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	result, err := r.Config.Reload(r.loadConfig())
+	if errors.Is(err, config.ErrNotReloadable) {
+		httputil.WriteError(w, http.StatusConflict, "This instance's configuration cannot be reloaded")
+		return
+	}
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnprocessableEntity, "Invalid configuration: "+err.Error())
+		return
+	}
+	logger.Info("Reloaded config", "by", userCtx.DID, "changed", result.Changed, "ignored", result.Ignored)
+	httputil.WriteSuccess(w, result)
+}
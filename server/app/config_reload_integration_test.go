@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestConfigReload_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	adminDID := "did:plc:admin"
+	cfg := config.Load()
+	cfg.AppEnv = "test"
+	cfg.AdminDIDs = adminDID
+	cfg.JWKSPrivate = `{"keys":[]}`
+	cfg.JWKSPublic = `{"keys":[]}`
+	cfg.PublicDomain = "https://forum.example.com"
+	cfg.AppName = "Forum"
+	cfg.OAuthClientID = "https://forum.example.com/auth/client-metadata.json"
+	cfg.OAuthRedirectURL = "https://forum.example.com/auth/callback"
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", cfg, dbService, adminDID)
+	next := *cfg
+	next.MaxStreamsPerUser = 1
+	next.DefaultCategories = "help, meta"
+	next.PublicDomain = "https://elsewhere.example"
+	router.loadConfig = func() *config.Config { return &next }
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result config.ReloadResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if !slices.Equal(result.Changed, []string{"default_categories", "max_streams_per_user"}) || !slices.Equal(result.Ignored, []string{"public_domain"}) {
+		t.Errorf("Expected categories and stream limit changed and the domain ignored, got %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/instance", nil))
+	var instance config.Instance
+	if err := json.NewDecoder(w.Body).Decode(&instance); err != nil {
+		t.Fatalf("Failed to decode instance: %v", err)
+	}
+	if !slices.Equal(instance.DefaultCategories, []string{"help", "meta"}) {
+		t.Errorf("Expected reloaded categories, got %q", instance.DefaultCategories)
+	}
+	if _, err := router.streams.Acquire("did:plc:alice"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err := router.streams.Acquire("did:plc:alice"); !errors.Is(err, realtime.ErrTooManyStreams) {
+		t.Errorf("Expected the reloaded stream limit, got %v", err)
+	}
+	if cfg.PublicDomain != "https://forum.example.com" {
+		t.Errorf("Expected the public domain to need a restart, got %s", cfg.PublicDomain)
+	}
+
+	invalid := next
+	invalid.LogLevel = "LOUD"
+	router.loadConfig = func() *config.Config { return &invalid }
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an invalid config to be refused with 422, got %d", w.Code)
+	}
+
+	strangerMux := http.NewServeMux()
+	RegisterTestRoutes(strangerMux, "/", cfg, dbService, "did:plc:stranger")
+	w = httptest.NewRecorder()
+	strangerMux.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected non-admin to be forbidden, got %d", w.Code)
+	}
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/blobstore"
+	"github.com/jrschumacher/dis.quest/internal/broker"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/labeler"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/session"
+)
+
+// DomainVerifier checks that whoever claimed a domain has published its
+// token, reporting the method that found it
+type DomainVerifier interface {
+	Verify(ctx context.Context, domain, token string) (string, error)
+}
+
+// RepoClient is the part of a user's PDS repo the handlers write to
+type RepoClient interface {
+	DeleteCollections(ctx context.Context, repo, prefix string) (int, error)
+	RewriteRecords(ctx context.Context, repo, collection string, rewrite func(record map[string]any) (bool, error)) (pds.RewriteResult, error)
+}
+
+// RepoClients opens a client for a user's PDS repo, authorized with the
+// session of the request being handled
+type RepoClients interface {
+	ForRequest(req *http.Request, did string) (RepoClient, error)
+	// WithAppPassword runs write with a client signed in to did's PDS with
+	// an app password, ending that PDS session once write returns
+	WithAppPassword(ctx context.Context, did, password string, write func(RepoClient) error) error
+}
+
+// Deps are the services the application handlers use. NewDeps builds them
+// for the running server; tests build their own with fakes where the real
+// service would leave the process.
+type Deps struct {
+	DB       *db.Service
+	Sessions *session.Manager
+	// Tokens issues the personal access tokens scripts call the API with
+	Tokens   *accesstoken.Manager
+	Domains  *domains.Resolver
+	Verifier DomainVerifier
+	Blobs    blobstore.Store
+	Repos    RepoClients
+	Clock    clock.Clock
+	IDs      clock.IDGenerator
+	// Broker relays live events between instances; nil for a single one
+	Broker broker.Broker
+	// Labeler signs the labels moderation publishes; nil unless the
+	// instance is configured as a labeler
+	Labeler *labeler.Signer
+	// DPoPKeys holds DPoP keys during sign-in; nil keeps them in a cookie
+	DPoPKeys auth.DPoPKeyStore
+	// OAuth runs sign-ins with the authorization server; nil uses an
+	// auth.PublicClient
+	OAuth auth.Provider
+}
+
+// NewDeps wires the production services for cfg
+func NewDeps(cfg *config.Config, dbService *db.Service) (*Deps, error) {
+	blobs, err := blobstore.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("blob store: %w", err)
+	}
+	var b broker.Broker
+	if cfg.RedisURL != "" {
+		if b, err = broker.NewRedis(cfg.RedisURL); err != nil {
+			return nil, fmt.Errorf("broker: %w", err)
+		}
+	}
+	dpopKeys, err := auth.NewDPoPKeyStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("DPoP key store: %w", err)
+	}
+	clk := clock.System{}
+	sessions := NewSessionManager(dbService, clk)
+	if err := sessions.SetKeySecret([]byte(cfg.JWKSPrivate)); err != nil {
+		return nil, fmt.Errorf("sessions: %w", err)
+	}
+	sessions.SetProfileLoader(pdsProfiles{resolve: auth.DiscoverPDS})
+	var signer *labeler.Signer
+	if cfg.LabelerDID != "" {
+		key, err := labeler.ParseSigningKey(cfg.LabelerSigningKey)
+		if err != nil {
+			return nil, err
+		}
+		if signer, err = labeler.NewSigner(cfg.LabelerDID, key); err != nil {
+			return nil, err
+		}
+	}
+	return &Deps{
+		DB:       dbService,
+		Sessions: sessions,
+		Tokens:   NewTokenManager(dbService, clk),
+		Domains:  NewDomainResolver(dbService),
+		Verifier: domains.NewVerifier(),
+		Blobs:    blobs,
+		Repos:    pdsRepos{resolve: auth.NewIdentityResolver().ResolvePDS, keys: sessions.DPoPKey, now: clk.Now, httpClient: pdsHTTPClient(cfg)},
+		Clock:    clk,
+		IDs:      clock.NewNanoIDs(clk),
+		Broker:   b,
+		Labeler:  signer,
+		DPoPKeys: dpopKeys,
+	}, nil
+}
+
+// pdsHTTPClient returns the client PDS requests are sent with, which
+// explains refused DPoP requests in the log when cfg.DPoPDebug is set
+func pdsHTTPClient(cfg *config.Config) *http.Client {
+	if !cfg.DPoPDebug {
+		return nil
+	}
+	return &http.Client{Transport: &auth.DPoPDebugTransport{
+		Report: func(report *auth.DPoPReport) {
+			logger.Warn("PDS refused DPoP request", "method", report.Method, "url", report.URL, "challenge", report.Challenge, "problems", report.Problems)
+		},
+	}}
+}
+
+// pdsRepos opens repo clients on the PDS that hosts each account
+type pdsRepos struct {
+	// resolve finds the PDS hosting an account's repo from its DID document
+	resolve func(ctx context.Context, did string) (string, error)
+	// keys returns the DPoP key stored with the request's session. Without
+	// it every client uses bearer tokens.
+	keys func(req *http.Request, did string) (*ecdsa.PrivateKey, error)
+	// now stamps records written without a createdAt; defaults to the
+	// system clock
+	now func() time.Time
+	// httpClient sends PDS requests; nil uses http.DefaultClient
+	httpClient *http.Client
+}
+
+// newClient creates a client for the PDS at host that fills in $type and
+// createdAt on the records it writes and checks quest.dis.* records
+// against their lexicons before they leave the server
+func (p pdsRepos) newClient(host string, authorizer pds.Authorizer) *pds.Client {
+	now := p.now
+	if now == nil {
+		now = time.Now
+	}
+	client := pds.NewClient(host, authorizer, p.httpClient)
+	client.AddRecordHooks(
+		pds.SetType,
+		pds.ForCollections(pds.SetCreatedAt(now), lexicon.TopicNSID, lexicon.MessageNSID),
+		func(_ context.Context, collection string, record map[string]any) error {
+			return lexicon.Check(collection, record)
+		},
+	)
+	return client
+}
+
+// ForRequest returns a client for the user's PDS, authorized with the
+// request's session: DPoP-bound for OAuth sign-ins, bearer for app passwords
+func (p pdsRepos) ForRequest(req *http.Request, did string) (RepoClient, error) {
+	accessToken, err := auth.GetSessionCookie(req)
+	if err != nil {
+		return nil, fmt.Errorf("no access token: %w", err)
+	}
+	host, err := p.resolve(req.Context(), did)
+	if err != nil {
+		return nil, err
+	}
+
+	var authorizer pds.Authorizer = pds.BearerToken(accessToken)
+	if p.keys != nil {
+		key, err := p.keys(req, did)
+		switch {
+		case err == nil:
+			authorizer = auth.DPoPAuthorizer{AccessToken: accessToken, Key: key}
+		case !errors.Is(err, session.ErrNoDPoPKey) && !errors.Is(err, session.ErrNotFound):
+			return nil, fmt.Errorf("failed to load DPoP key: %w", err)
+		}
+	}
+	return p.newClient(host, authorizer), nil
+}
+
+// WithAppPassword implements RepoClients. The password is only sent to the
+// account's own PDS, and the session it creates is deleted afterwards.
+func (p pdsRepos) WithAppPassword(ctx context.Context, did, password string, write func(RepoClient) error) error {
+	host, err := p.resolve(ctx, did)
+	if err != nil {
+		return err
+	}
+	created, err := auth.CreateSession(host, did, password)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := auth.DeleteSession(context.WithoutCancel(ctx), host, created.RefreshJwt); err != nil {
+			logger.Warn("Failed to end app password session", "did", did, "error", err)
+		}
+	}()
+	if created.Did != did {
+		return fmt.Errorf("%w: app password belongs to %s", auth.ErrInvalidCredentials, created.Did)
+	}
+	return write(p.newClient(host, pds.BearerToken(created.AccessJwt)))
+}
+
+// profileLookupTimeout bounds the profile lookup that sign-in waits for
+const profileLookupTimeout = 5 * time.Second
+
+// pdsProfiles reads profiles from the PDS hosting each account: the handle
+// from describeRepo, and the display name and avatar from the account's
+// app.bsky.actor.profile record when it has one
+type pdsProfiles struct {
+	// resolve finds the PDS hosting an account's repo
+	resolve func(did string) (string, error)
+}
+
+// LoadProfile implements session.ProfileLoader
+func (p pdsProfiles) LoadProfile(ctx context.Context, did string) (session.Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, profileLookupTimeout)
+	defer cancel()
+
+	host, err := p.resolve(did)
+	if err != nil {
+		return session.Profile{}, err
+	}
+	client := pds.NewClient(host, pds.Anonymous{}, nil)
+	desc, err := client.Describe(ctx, did)
+	if err != nil {
+		return session.Profile{}, err
+	}
+	profile := session.Profile{Handle: desc.Handle}
+
+	// Accounts that never set up a Bluesky profile still have a handle
+	record, err := client.GetRecord(ctx, did, "app.bsky.actor.profile", "self")
+	if err != nil {
+		return profile, nil
+	}
+	var value struct {
+		DisplayName string `json:"displayName"`
+		Avatar      struct {
+			Ref struct {
+				Link string `json:"$link"`
+			} `json:"ref"`
+		} `json:"avatar"`
+	}
+	if err := json.Unmarshal(record.Value, &value); err != nil {
+		return profile, nil
+	}
+	profile.DisplayName = value.DisplayName
+	if cid := value.Avatar.Ref.Link; cid != "" {
+		profile.Avatar = strings.TrimSuffix(host, "/") + "/xrpc/com.atproto.sync.getBlob?" + url.Values{"did": {did}, "cid": {cid}}.Encode()
+	}
+	return profile, nil
+}
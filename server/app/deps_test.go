@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPDSProfiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.repo.describeRepo":
+			_, _ = w.Write([]byte(`{"handle":"alice.example.com","did":"did:plc:alice","collections":[]}`))
+		case "/xrpc/com.atproto.repo.getRecord":
+			if r.URL.Query().Get("repo") != "did:plc:alice" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"RecordNotFound"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"uri":"at://did:plc:alice/app.bsky.actor.profile/self","value":{"displayName":"Alice","avatar":{"$type":"blob","ref":{"$link":"bafkavatar"},"mimeType":"image/jpeg","size":100}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	profiles := pdsProfiles{resolve: func(string) (string, error) { return srv.URL, nil }}
+	profile, err := profiles.LoadProfile(context.Background(), "did:plc:alice")
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if profile.Handle != "alice.example.com" || profile.DisplayName != "Alice" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if !strings.HasPrefix(profile.Avatar, srv.URL+"/xrpc/com.atproto.sync.getBlob?") || !strings.Contains(profile.Avatar, "cid=bafkavatar") {
+		t.Errorf("avatar = %q", profile.Avatar)
+	}
+
+	// Without a profile record the handle is still known
+	profile, err = profiles.LoadProfile(context.Background(), "did:plc:bob")
+	if err != nil {
+		t.Fatalf("LoadProfile without a profile record: %v", err)
+	}
+	if profile.Handle != "alice.example.com" || profile.DisplayName != "" || profile.Avatar != "" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+}
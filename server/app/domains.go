@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+// domainCacheTTL is how long a host's community lookup is reused. Verifying
+// or removing a domain invalidates it immediately on this instance.
+const domainCacheTTL = time.Minute
+
+// domainView is a community domain claim along with how to verify it
+type domainView struct {
+	Domain             string     `json:"domain"`
+	Community          string     `json:"community"`
+	Verified           bool       `json:"verified"`
+	VerificationMethod string     `json:"verification_method,omitempty"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	Token              string     `json:"token"`
+	TXTRecordName      string     `json:"txt_record_name"`
+	TXTRecordValue     string     `json:"txt_record_value"`
+	WellKnownURL       string     `json:"well_known_url"`
+}
+
+// addDomainRequest is the body of POST /api/v1/communities/{slug}/domains
+type addDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// NewDomainResolver creates a resolver that maps hosts to the community that
+// verified them
+func NewDomainResolver(dbService *db.Service) *domains.Resolver {
+	return domains.NewResolver(func(ctx context.Context, host string) (string, error) {
+		community, err := dbService.Queries().GetVerifiedDomainCommunity(ctx, host)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return community, err
+	}, domainCacheTTL)
+}
+
+func newDomainView(d db.CommunityDomain) domainView {
+	view := domainView{
+		Domain:             d.Domain,
+		Community:          d.Community,
+		Verified:           d.VerifiedAt.Valid,
+		VerificationMethod: d.VerificationMethod.String,
+		Token:              d.Token,
+		TXTRecordName:      domains.TXTRecordPrefix + d.Domain,
+		TXTRecordValue:     domains.TXTValuePrefix + d.Token,
+		WellKnownURL:       "https://" + d.Domain + domains.WellKnownPath,
+	}
+	if d.VerifiedAt.Valid {
+		view.VerifiedAt = &d.VerifiedAt.Time
+	}
+	return view
+}
+
+// CommunityDomainsAPIHandler lists a community's custom domains (GET) or
+// claims a new one (POST). The response carries the token to publish in a
+// DNS TXT record or well-known file before calling verify. Community
+// moderators and admins only.
+func (r *Router) CommunityDomainsAPIHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	community, ok := r.lookupModeratedCommunity(w, req)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		claims, err := r.dbService.Queries().ListCommunityDomains(ctx, community.Slug)
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to fetch domains", "community", community.Slug)
+			return
+		}
+		views := make([]domainView, 0, len(claims))
+		for _, d := range claims {
+			views = append(views, newDomainView(d))
+		}
+		httputil.WriteSuccess(w, views)
+	case http.MethodPost:
+		var addReq addDomainRequest
+		if err := httputil.DecodeJSON(w, req, &addReq); err != nil {
+			httputil.WriteDecodeError(w, err)
+			return
+		}
+		domain, err := domains.Normalize(addReq.Domain)
+		if err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Domain must be a public host name such as forum.example.com")
+			return
+		}
+		if primary, err := url.Parse(r.Config.PublicDomain); err == nil && primary.Hostname() == domain {
+			httputil.WriteError(w, http.StatusBadRequest, "This domain already serves dis.quest")
+			return
+		}
+
+		if _, err := r.dbService.Queries().GetCommunityDomain(ctx, domain); err == nil {
+			httputil.WriteError(w, http.StatusConflict, "This domain has already been claimed")
+			return
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteInternalError(w, err, "Failed to check domain", "domain", domain)
+			return
+		}
+
+		token, err := domains.NewToken()
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to generate verification token")
+			return
+		}
+		claim, err := r.dbService.Queries().CreateCommunityDomain(ctx, db.CreateCommunityDomainParams{
+			Domain:    domain,
+			Community: community.Slug,
+			Token:     token,
+			CreatedAt: r.clock.Now(),
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to claim domain", "community", community.Slug, "domain", domain)
+			return
+		}
+		httputil.WriteCreated(w, newDomainView(claim))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CommunityDomainAPIHandler removes (DELETE) a community's custom domain.
+// Community moderators and admins only.
+func (r *Router) CommunityDomainAPIHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	community, ok := r.lookupModeratedCommunity(w, req)
+	if !ok {
+		return
+	}
+	domain := req.PathValue("domain")
+
+	affected, err := r.dbService.Queries().DeleteCommunityDomain(req.Context(), db.DeleteCommunityDomainParams{
+		Community: community.Slug,
+		Domain:    domain,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to remove domain", "community", community.Slug, "domain", domain)
+		return
+	}
+	if affected == 0 {
+		httputil.WriteError(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+	r.domains.Invalidate(domain)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyCommunityDomainHandler checks a claimed domain for its token (POST)
+// and, once found, starts serving the community on it. Community moderators
+// and admins only.
+func (r *Router) VerifyCommunityDomainHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	community, ok := r.lookupModeratedCommunity(w, req)
+	if !ok {
+		return
+	}
+	domain := req.PathValue("domain")
+
+	claim, err := r.dbService.Queries().GetCommunityDomain(ctx, domain)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && claim.Community != community.Slug) {
+		httputil.WriteError(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to fetch domain", "domain", domain)
+		return
+	}
+	if claim.VerifiedAt.Valid {
+		httputil.WriteSuccess(w, newDomainView(claim))
+		return
+	}
+
+	method, err := r.verifier.Verify(ctx, claim.Domain, claim.Token)
+	if err != nil {
+		httputil.WriteError(w, http.StatusUnprocessableEntity, "Verification token not found; publish the TXT record or well-known file and try again")
+		return
+	}
+
+	claim, err = r.dbService.Queries().MarkCommunityDomainVerified(ctx, db.MarkCommunityDomainVerifiedParams{
+		Domain:             claim.Domain,
+		VerificationMethod: sql.NullString{String: method, Valid: true},
+		VerifiedAt:         sql.NullTime{Time: r.clock.Now(), Valid: true},
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to mark domain verified", "domain", domain)
+		return
+	}
+	r.domains.Invalidate(claim.Domain)
+	httputil.WriteSuccess(w, newDomainView(claim))
+}
+
+// communityDomainHome serves a community's page at the root of its verified
+// custom domain and falls through to next everywhere else
+func (r *Router) communityDomainHome(next http.Handler) http.Handler {
+	communityPage := middleware.WithUserContextFunc(r.CommunityPageHandler)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if d, ok := domains.FromContext(req.Context()); ok && req.URL.Path == "/" {
+			req.SetPathValue("slug", d.Community)
+			communityPage.ServeHTTP(w, req)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// lookupModeratedCommunity loads the community named by the slug path value
+// and checks that the signed-in user moderates it, writing an error response
+// and returning false otherwise
+func (r *Router) lookupModeratedCommunity(w http.ResponseWriter, req *http.Request) (*communityView, bool) {
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return nil, false
+	}
+	community, ok := r.lookupCommunity(w, req)
+	if !ok {
+		return nil, false
+	}
+	if !r.isAdmin(userCtx.DID) && !slices.Contains(community.Moderators, userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Only a moderator of this community can manage its domains")
+		return nil, false
+	}
+	return community, true
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestCommunityDomains_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	adminDID := "did:plc:admin"
+	memberDID := "did:plc:member"
+
+	if _, err := dbService.CreateCommunityWithModerator(ctx, db.CreateCommunityParams{
+		Slug:      "golang",
+		Name:      "Go",
+		CreatedBy: adminDID,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to create community: %v", err)
+	}
+
+	// txtRecords stands in for DNS so verification never leaves the test
+	txtRecords := map[string][]string{}
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID, PublicDomain: "https://dis.quest"}
+	serve := func(did, method, path, body string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		router := RegisterTestRoutes(mux, "/", cfg, dbService, did)
+		router.verifier = &domains.Verifier{
+			LookupTXT: func(_ context.Context, name string) ([]string, error) {
+				if records, ok := txtRecords[name]; ok {
+					return records, nil
+				}
+				return nil, errors.New("no such host")
+			},
+			HTTPClient: &http.Client{Transport: offlineTransport{}},
+			Scheme:     "https",
+		}
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	var claim domainView
+	t.Run("Moderators claim a domain", func(t *testing.T) {
+		path := "/api/v1/communities/golang/domains"
+		if w := serve(memberDID, "POST", path, `{"domain":"forum.example.com"}`); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403, got %d", w.Code)
+		}
+		if w := serve(adminDID, "POST", path, `{"domain":"https://forum.example.com/"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for a URL, got %d", w.Code)
+		}
+		if w := serve(adminDID, "POST", path, `{"domain":"dis.quest"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for the primary domain, got %d", w.Code)
+		}
+
+		w := serve(adminDID, "POST", path, `{"domain":"Forum.Example.com"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if err := json.NewDecoder(w.Body).Decode(&claim); err != nil {
+			t.Fatalf("Failed to decode claim: %v", err)
+		}
+		if claim.Domain != "forum.example.com" || claim.Verified || claim.Token == "" {
+			t.Fatalf("Unexpected claim: %+v", claim)
+		}
+		if claim.TXTRecordName != "_disquest.forum.example.com" || claim.TXTRecordValue != "disquest-verification="+claim.Token {
+			t.Errorf("Unexpected TXT instructions: %+v", claim)
+		}
+
+		if w := serve(adminDID, "POST", path, `{"domain":"forum.example.com"}`); w.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 for a claimed domain, got %d", w.Code)
+		}
+	})
+
+	t.Run("Verification requires the published token", func(t *testing.T) {
+		path := "/api/v1/communities/golang/domains/forum.example.com/verify"
+		if w := serve(adminDID, "POST", path, ""); w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422 before the TXT record exists, got %d", w.Code)
+		}
+		if _, ok := NewDomainResolver(dbService).Resolve(ctx, "forum.example.com"); ok {
+			t.Fatal("Expected an unverified domain not to resolve")
+		}
+
+		txtRecords["_disquest.forum.example.com"] = []string{claim.TXTRecordValue}
+		w := serve(adminDID, "POST", path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var verified domainView
+		if err := json.NewDecoder(w.Body).Decode(&verified); err != nil {
+			t.Fatalf("Failed to decode claim: %v", err)
+		}
+		if !verified.Verified || verified.VerificationMethod != domains.MethodDNS {
+			t.Errorf("Expected a DNS-verified domain, got %+v", verified)
+		}
+
+		d, ok := NewDomainResolver(dbService).Resolve(ctx, "forum.example.com")
+		if !ok || d.Community != "golang" {
+			t.Fatalf("Expected the domain to resolve to golang, got %+v, %v", d, ok)
+		}
+	})
+
+	t.Run("Domain root serves the community", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterTestRoutes(mux, "/", cfg, dbService, memberDID)
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(domains.WithDomain(req.Context(), domains.Domain{Host: "forum.example.com", Community: "golang"}))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "test home") {
+			t.Errorf("Expected the community page, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Moderators remove a domain", func(t *testing.T) {
+		path := "/api/v1/communities/golang/domains/forum.example.com"
+		if w := serve(memberDID, "DELETE", path, ""); w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status 403, got %d", w.Code)
+		}
+		if w := serve(adminDID, "DELETE", path, ""); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", w.Code)
+		}
+		if w := serve(adminDID, "DELETE", path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 once removed, got %d", w.Code)
+		}
+	})
+}
+
+// offlineTransport fails every request, keeping well-known checks off the network
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
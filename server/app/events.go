@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+const (
+	// heartbeatInterval keeps idle SSE connections from being closed by proxies
+	heartbeatInterval = 30 * time.Second
+	// streamWriteTimeout is how long a single write to an event stream may
+	// block before the client is considered stalled and disconnected
+	streamWriteTimeout = 10 * time.Second
+	// streamRetryAfter is the Retry-After hint sent when a stream is refused
+	streamRetryAfter = "30"
+	// topicCollection is the lexicon NSID of topic records
+	topicCollection = lexicon.TopicNSID
+	// messageCollection is the lexicon NSID of message records
+	messageCollection = lexicon.MessageNSID
+	// participationCollection is the lexicon NSID of participation records
+	participationCollection = lexicon.ParticipationNSID
+)
+
+// newEventMessage converts a stored message to its event form
+func newEventMessage(m db.Message) events.Message {
+	return events.Message{
+		Did:               m.Did,
+		Rkey:              m.Rkey,
+		TopicDid:          m.TopicDid,
+		TopicRkey:         m.TopicRkey,
+		ParentMessageRkey: events.NullString{String: m.ParentMessageRkey.String, Valid: m.ParentMessageRkey.Valid},
+		Content:           string(m.Content),
+		CreatedAt:         m.CreatedAt,
+		UpdatedAt:         m.UpdatedAt,
+		DeletedAt:         events.NullTime{Time: m.DeletedAt.Time, Valid: m.DeletedAt.Valid},
+		DeletedBy:         events.NullString{String: m.DeletedBy.String, Valid: m.DeletedBy.Valid},
+	}
+}
+
+// EventSchemaHandler serves the JSON Schema of the events on topic and user
+// streams
+func (r *Router) EventSchemaHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Header().Set("Cache-Control", lexiconCacheControl)
+	w.Header().Set(events.VersionHeader, strconv.Itoa(events.Version))
+	_, _ = w.Write(events.Schema())
+}
+
+// TopicEventsHandler streams live topic events (presence and activity) as
+// Server-Sent Events. Viewers can opt out of presence with ?presence=hidden;
+// they still receive events but are not counted or listed.
+func (r *Router) TopicEventsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	viewer := realtime.Viewer{Hidden: req.URL.Query().Get("presence") == "hidden"}
+	if userCtx, ok := middleware.GetUserContext(req); ok {
+		viewer.DID = userCtx.DID
+	}
+
+	release, ok := r.acquireStream(w, req)
+	if !ok {
+		return
+	}
+	defer release()
+
+	openEventStream(w)
+	sub := r.hub.Subscribe(formatTopicID(topicDid, topicRkey), viewer)
+	defer r.hub.Unsubscribe(sub)
+
+	r.streamEvents(ctx, w, sub)
+}
+
+// acquireStream registers a new event stream for the requesting client,
+// answering 429 when the client or the server is at its stream limit
+func (r *Router) acquireStream(w http.ResponseWriter, req *http.Request) (release func(), ok bool) {
+	release, err := r.streams.Acquire(streamClient(req))
+	if err != nil {
+		w.Header().Set("Retry-After", streamRetryAfter)
+		httputil.WriteError(w, http.StatusTooManyRequests, "Too many open event streams")
+		return nil, false
+	}
+	return release, true
+}
+
+// streamClient identifies who a stream counts against: the signed-in DID,
+// or the remote address for anonymous viewers
+func streamClient(req *http.Request) string {
+	if userCtx, ok := middleware.GetUserContext(req); ok {
+		return userCtx.DID
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// newStreams builds the stream registry from configured limits, which
+// follow the config when it is reloaded
+func newStreams(cfg *config.Config) *realtime.Streams {
+	if cfg == nil {
+		return realtime.NewStreams(realtime.Limits{})
+	}
+	streams := realtime.NewStreams(streamLimits(cfg))
+	cfg.OnReload(func(reloaded *config.Config) { streams.SetLimits(streamLimits(reloaded)) })
+	return streams
+}
+
+// streamLimits returns the configured stream limits
+func streamLimits(cfg *config.Config) realtime.Limits {
+	return realtime.Limits{
+		MaxStreams:          cfg.MaxStreams,
+		MaxStreamsPerClient: cfg.MaxStreamsPerUser,
+	}
+}
+
+// openEventStream writes the SSE response headers
+func openEventStream(w http.ResponseWriter) {
+	// The server-wide write timeout would otherwise cut the stream short
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.Warn("Failed to clear write deadline for event stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set(events.VersionHeader, strconv.Itoa(events.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// streamEvents forwards a subscriber's events to the client until either side
+// goes away, sending heartbeats while idle. Each write must finish within
+// streamWriteTimeout, so a client that stops reading is disconnected rather
+// than left holding a goroutine; one that falls behind the hub's buffer is
+// dropped by the hub and told to reconnect.
+func (r *Router) streamEvents(ctx context.Context, w http.ResponseWriter, sub *realtime.Subscriber) {
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	rc := http.NewResponseController(w)
+	write := func(fn func() error) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return fn()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := write(func() error { return realtime.WriteComment(w, "ping") }); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					r.streams.RecordDropped()
+					_ = write(func() error { return realtime.WriteComment(w, "too slow, reconnect") })
+				}
+				return
+			}
+			err := write(func() error { return realtime.WriteEvent(w, event) })
+			if errors.Is(err, realtime.ErrEventTooLarge) {
+				r.streams.RecordOversized()
+				logger.Warn("Skipped oversized event", "error", err)
+				continue
+			}
+			if err != nil {
+				logger.Debug("Event stream closed", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// StreamStatsHandler reports live event stream counters to admins
+func (r *Router) StreamStatsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	httputil.WriteSuccess(w, r.streams.Stats())
+}
+
+// TypingHandler records that the current user is typing in a topic, which
+// must exist and not be deleted. The signal expires on its own shortly after the last call, so clients simply
+// repeat the request while the user keeps typing.
+func (r *Router) TypingHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := r.dbService.Queries().GetTopic(req.Context(), db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	// Viewers who hide their presence don't announce typing either
+	if req.URL.Query().Get("presence") != "hidden" {
+		r.hub.SetTyping(formatTopicID(topicDid, topicRkey), userCtx.DID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// topicURI returns the AT URI of a topic record
+func topicURI(did, rkey string) string {
+	return pds.ATURI{Repo: did, Collection: topicCollection, Rkey: rkey}.String()
+}
+
+// messageURI returns the AT URI of a message record
+func messageURI(did, rkey string) string {
+	return pds.ATURI{Repo: did, Collection: messageCollection, Rkey: rkey}.String()
+}
@@ -0,0 +1,306 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+func TestParseTopicID(t *testing.T) {
+	tests := []struct {
+		name     string
+		topicID  string
+		wantDid  string
+		wantRkey string
+		wantErr  bool
+	}{
+		{"plc did", "did:plc:abc123:topic-1", "did:plc:abc123", "topic-1", false},
+		{"web did", "did:web:example.com:3kq2", "did:web:example.com", "3kq2", false},
+		{"missing rkey", "did:plc:abc123:", "", "", true},
+		{"not a did", "topic-1", "", "", true},
+		{"rkey only", "plc:topic-1", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			did, rkey, err := parseTopicID(tt.topicID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTopicID(%q) error = %v, wantErr %v", tt.topicID, err, tt.wantErr)
+			}
+			if did != tt.wantDid || rkey != tt.wantRkey {
+				t.Errorf("parseTopicID(%q) = %q, %q; want %q, %q", tt.topicID, did, rkey, tt.wantDid, tt.wantRkey)
+			}
+		})
+	}
+}
+
+func TestTopicEvents_Presence_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	testDID := "did:plc:test123"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            testDID,
+		Rkey:           "presence-topic",
+		Subject:        "Presence Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+
+	server := httptest.NewServer(CreateTestServer(t, dbService, testDID))
+	defer server.Close()
+
+	t.Run("Unknown topic", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/topics/did:plc:test123:missing/events")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Visible viewer", "", `"viewing":1,"viewers":["did:plc:test123"]`},
+		{"Hidden viewer", "?presence=hidden", `"viewing":0,"viewers":[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			url := server.URL + "/api/topics/" + topic.Did + ":" + topic.Rkey + "/events" + tt.query
+			req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("Expected text/event-stream, got %q", ct)
+			}
+			if v := resp.Header.Get(events.VersionHeader); v != "1" {
+				t.Errorf("Expected event schema version 1, got %q", v)
+			}
+
+			// The previous subtest's stream may not have left yet; its
+			// leaving broadcasts the presence this one expects
+			var last string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.HasPrefix(line, "data: ") {
+					if strings.Contains(line, tt.want) {
+						return
+					}
+					last = line
+				}
+			}
+			if last != "" {
+				t.Fatalf("Expected presence %s, got %s", tt.want, last)
+			}
+			t.Fatalf("Stream ended before presence event: %v", scanner.Err())
+		})
+	}
+}
+
+func TestTopicEvents_TypingAndEcho_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	testDID := "did:plc:test123"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            testDID,
+		Rkey:           "echo-topic",
+		Subject:        "Echo Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	topicID := formatTopicID(topic.Did, topic.Rkey)
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", nil, dbService, testDID)
+	sub := router.hub.Subscribe(topicID, realtime.Viewer{DID: testDID})
+	defer router.hub.Unsubscribe(sub)
+	<-sub.Events() // own presence
+
+	t.Run("Typing", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/topics/"+topicID+"/typing", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		ev := <-sub.Events()
+		if ev.Type != realtime.EventTyping {
+			t.Fatalf("Expected typing event, got %q", ev.Type)
+		}
+	})
+
+	t.Run("Typing in unknown or deleted topics", func(t *testing.T) {
+		deleted, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            testDID,
+			Rkey:           "deleted-topic",
+			Subject:        "Deleted Topic",
+			InitialMessage: "Initial message",
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+		if _, err := dbService.Queries().SoftDeleteTopic(ctx, db.SoftDeleteTopicParams{
+			DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			DeletedBy: sql.NullString{String: testDID, Valid: true},
+			Did:       deleted.Did,
+			Rkey:      deleted.Rkey,
+		}); err != nil {
+			t.Fatalf("Failed to delete topic: %v", err)
+		}
+		for _, id := range []string{formatTopicID(testDID, "no-such-topic"), formatTopicID(deleted.Did, deleted.Rkey)} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/topics/"+id+"/typing", nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status %d, got %d", id, http.StatusNotFound, w.Code)
+			}
+			if typing := router.hub.Typing(id).Typing; len(typing) != 0 {
+				t.Errorf("%s: expected no typing entries, got %v", id, typing)
+			}
+		}
+	})
+
+	t.Run("Optimistic echo", func(t *testing.T) {
+		body := strings.NewReader(`{"content":"if a<b then Vec<String> & co","client_id":"c-1"}`)
+		req := httptest.NewRequest("POST", "/api/topics/"+topicID+"/messages", body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		// Posting clears the typing signal, then pending and confirmed follow
+		wantTypes := []string{realtime.EventTyping, realtime.EventMessagePending, realtime.EventMessageConfirmed}
+		for _, want := range wantTypes {
+			ev := <-sub.Events()
+			if ev.Type != want {
+				t.Fatalf("Expected %s event, got %q", want, ev.Type)
+			}
+			if pending, ok := ev.Data.(events.PendingMessage); ok {
+				if pending.Content != "if a<b then Vec<String> & co" {
+					t.Errorf("Expected the echo to match the posted content, got %q", pending.Content)
+				}
+			}
+			if confirmed, ok := ev.Data.(events.ConfirmedMessage); ok {
+				if confirmed.ClientID != "c-1" {
+					t.Errorf("Expected client_id c-1, got %q", confirmed.ClientID)
+				}
+				if !strings.HasPrefix(confirmed.URI, "at://"+testDID+"/quest.dis.message/") {
+					t.Errorf("Unexpected message URI %q", confirmed.URI)
+				}
+			}
+		}
+	})
+}
+
+func TestEventSchema_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	mux := CreateTestServer(t, dbService, "")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/events/schema", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var schema struct {
+		ID   string                     `json:"$id"`
+		Defs map[string]json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	if schema.ID != events.SchemaID || schema.Defs["ConfirmedMessage"] == nil {
+		t.Errorf("Unexpected schema: %s", w.Body.String())
+	}
+}
+
+func TestTopicEvents_StreamLimits_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	testDID := "did:plc:test123"
+	topic := testutil.CreateTestTopic(t, dbService, testDID)
+	eventsPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey) + "/events"
+
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: testDID, MaxStreamsPerUser: 1}
+	mux := http.NewServeMux()
+	RegisterTestRoutes(mux, "/", cfg, dbService, testDID)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+eventsPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	first, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() { _ = first.Body.Close() }()
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("Expected first stream to open, got %d", first.StatusCode)
+	}
+
+	second, err := http.Get(server.URL + "/api/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = second.Body.Close()
+	if second.StatusCode != http.StatusTooManyRequests || second.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d %q", second.StatusCode, second.Header.Get("Retry-After"))
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/streams", nil))
+	var stats realtime.StreamStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stream stats: %v", err)
+	}
+	if stats.Active != 1 || stats.Opened != 1 || stats.Rejected != 1 {
+		t.Errorf("Expected one active and one rejected stream, got %+v", stats)
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// PDS write operations, as named in the app_password_fallback setting
+const (
+	opMigrateRecords = "records.migrate"
+	opDeleteAccount  = "account.delete"
+)
+
+// fallbackOperations are the operations app_password_fallback may list
+var fallbackOperations = []string{opMigrateRecords, opDeleteAccount}
+
+// errNoRepoClient wraps failures opening a client with the request's session
+var errNoRepoClient = errors.New("no PDS session")
+
+// appPasswordRequest is the body of a PDS write the user may retry with an
+// app password
+type appPasswordRequest struct {
+	AppPassword string `json:"app_password"`
+}
+
+// withRepo runs write against the user's PDS with the request's session.
+// When the PDS refuses the session's scope and op is listed in
+// app_password_fallback, write runs again signed in with appPassword, which
+// is used for this call only; without one the pds.ErrInsufficientScope
+// error is returned, and scopeRemedy tells the user to supply it. Writes
+// must be safe to repeat after a partial first run.
+func (r *Router) withRepo(req *http.Request, did, op, appPassword string, write func(RepoClient) error) error {
+	client, err := r.repos.ForRequest(req, did)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errNoRepoClient, err)
+	}
+	err = write(client)
+	if !errors.Is(err, pds.ErrInsufficientScope) || !r.Config.AllowsAppPasswordFallback(op) || appPassword == "" {
+		return err
+	}
+	logger.Warn("Retrying PDS write with an app password", "did", did, "operation", op, "error", err)
+	return r.repos.WithAppPassword(req.Context(), did, appPassword, write)
+}
+
+// warnAppPasswordFallback logs that app password fallback is enabled, and
+// any operations listed that do not exist
+func warnAppPasswordFallback(cfg *config.Config) {
+	var enabled []string
+	for _, op := range strings.Fields(cfg.AppPasswordFallback) {
+		if !slices.Contains(fallbackOperations, op) {
+			logger.Warn("Ignoring unknown operation in app_password_fallback", "operation", op)
+			continue
+		}
+		enabled = append(enabled, op)
+	}
+	if len(enabled) > 0 {
+		logger.Warn("App password fallback is enabled: users whose PDS refuses OAuth writes will be asked for an app password, which grants full account access", "operations", enabled)
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/svrlib"
+)
+
+// fakeRepos hands out a client per sign-in, recording the app passwords used
+type fakeRepos struct {
+	session     RepoClient
+	appPassword RepoClient
+	passwords   []string
+}
+
+func (f *fakeRepos) ForRequest(*http.Request, string) (RepoClient, error) {
+	return f.session, nil
+}
+
+func (f *fakeRepos) WithAppPassword(_ context.Context, _, password string, write func(RepoClient) error) error {
+	f.passwords = append(f.passwords, password)
+	return write(f.appPassword)
+}
+
+// stubRepo fails or counts DeleteCollections calls
+type stubRepo struct {
+	err     error
+	deletes int
+}
+
+func (s *stubRepo) DeleteCollections(context.Context, string, string) (int, error) {
+	s.deletes++
+	return 0, s.err
+}
+
+func (s *stubRepo) RewriteRecords(context.Context, string, string, func(map[string]any) (bool, error)) (pds.RewriteResult, error) {
+	return pds.RewriteResult{}, s.err
+}
+
+func TestWithRepoAppPasswordFallback(t *testing.T) {
+	badScope := &pds.ScopeError{XRPCError: &pds.XRPCError{Status: http.StatusBadRequest, Name: "InvalidToken", Message: "Bad token scope"}}
+	write := func(client RepoClient) error {
+		_, err := client.DeleteCollections(context.Background(), "did:plc:alice", questCollectionPrefix)
+		return err
+	}
+
+	for _, tc := range []struct {
+		name        string
+		fallback    string
+		sessionErr  error
+		appPassword string
+		wantErr     error
+		wantRetry   bool
+	}{
+		{name: "session write succeeds", fallback: opDeleteAccount, appPassword: "pw"},
+		{name: "operation not opted in", fallback: opMigrateRecords, sessionErr: badScope, appPassword: "pw", wantErr: badScope},
+		{name: "other PDS errors do not fall back", fallback: opDeleteAccount, sessionErr: &pds.XRPCError{Status: http.StatusBadRequest, Name: "InvalidRequest"}, appPassword: "pw"},
+		{name: "asks for an app password", fallback: opDeleteAccount, sessionErr: badScope, wantErr: pds.ErrInsufficientScope},
+		{name: "retries with the app password", fallback: opDeleteAccount, sessionErr: badScope, appPassword: "pw", wantRetry: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repos := &fakeRepos{session: &stubRepo{err: tc.sessionErr}, appPassword: &stubRepo{}}
+			r := &Router{
+				Router: svrlib.NewRouter(nil, "/", &config.Config{AppPasswordFallback: tc.fallback}),
+				repos:  repos,
+			}
+
+			err := r.withRepo(httptest.NewRequest(http.MethodPost, "/", nil), "did:plc:alice", opDeleteAccount, tc.appPassword, write)
+			switch {
+			case tc.wantRetry || (tc.sessionErr == nil && tc.wantErr == nil):
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tc.wantErr)
+				}
+			default:
+				if !errors.Is(err, tc.sessionErr) {
+					t.Fatalf("error = %v, want %v", err, tc.sessionErr)
+				}
+			}
+			if retried := len(repos.passwords) > 0; retried != tc.wantRetry {
+				t.Fatalf("retried with app password = %v, want %v", retried, tc.wantRetry)
+			}
+			if tc.wantRetry && repos.appPassword.(*stubRepo).deletes != 1 {
+				t.Fatal("expected the write to run again with the app password")
+			}
+		})
+	}
+}
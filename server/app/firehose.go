@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+const (
+	// FirehoseRetention is how long firehose events stay available for replay
+	FirehoseRetention = 72 * time.Hour
+
+	// Jetstream's limits on subscription filters
+	maxWantedCollections = 100
+	maxWantedDIDs        = 10000
+)
+
+// newTopicRecord returns topic as a quest.dis.topic lexicon record
+func newTopicRecord(topic db.Topic) lexicon.Topic {
+	record := lexicon.Topic{
+		Type:           topicCollection,
+		Title:          topic.Subject,
+		Summary:        topic.InitialMessage,
+		SelectedAnswer: topic.SelectedAnswer.String,
+		CreatedBy:      topic.Did,
+		CreatedAt:      topic.CreatedAt.UTC().Format(time.RFC3339Nano),
+		RecordVersion:  lexicon.CurrentVersion(lexicon.TopicNSID),
+	}
+	if topic.Category.Valid {
+		record.Tags = []string{topic.Category.String}
+	}
+	return record
+}
+
+// newMessageRecord returns message as a quest.dis.message lexicon record
+func newMessageRecord(message db.Message) lexicon.Message {
+	return lexicon.Message{
+		Type:          messageCollection,
+		Topic:         pds.ATURI{Repo: message.TopicDid, Collection: topicCollection, Rkey: message.TopicRkey}.String(),
+		Content:       string(message.Content),
+		ReplyTo:       message.ParentMessageRkey.String,
+		CreatedAt:     message.CreatedAt.UTC().Format(time.RFC3339Nano),
+		RecordVersion: lexicon.CurrentVersion(lexicon.MessageNSID),
+	}
+}
+
+// publishRecord announces a record change on the firehose. Failures are
+// logged; the change itself has already been stored.
+func (r *Router) publishRecord(ctx context.Context, did, collection, rkey, operation string, record any) {
+	if err := r.publish(ctx, did, collection, rkey, operation, record); err != nil {
+		logger.Error("Failed to publish firehose event", "error", err, "collection", collection, "rkey", rkey)
+	}
+}
+
+// publish appends a record change to the firehose
+func (r *Router) publish(ctx context.Context, did, collection, rkey, operation string, record any) error {
+	commit := &firehose.Commit{Operation: operation, Collection: collection, Rkey: rkey}
+	if record != nil {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode firehose record: %w", err)
+		}
+		commit.Record = data
+	}
+
+	_, err := r.firehose.Publish(ctx, firehose.Event{Did: did, Commit: commit})
+	return err
+}
+
+// FirehoseHandler streams record changes over a WebSocket using Jetstream's
+// framing. As with Jetstream, wantedCollections and wantedDids filter the
+// stream and cursor (unix microseconds) replays stored events from that
+// point before switching to live delivery.
+func (r *Router) FirehoseHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	filter := firehose.Filter{
+		Collections: query["wantedCollections"],
+		DIDs:        query["wantedDids"],
+	}
+	if len(filter.Collections) > maxWantedCollections || len(filter.DIDs) > maxWantedDIDs {
+		httputil.WriteError(w, http.StatusBadRequest, "Too many wantedCollections or wantedDids")
+		return
+	}
+	var cursor int64
+	if raw := query.Get("cursor"); raw != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	// Subscribe before replaying so nothing published in between is missed
+	sub := r.firehose.Subscribe(filter)
+	defer r.firehose.Unsubscribe(sub)
+
+	conn, err := websocket.Upgrade(w, req)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close(websocket.CloseNormal, "") }()
+
+	// The hijacked connection no longer cancels the request context, so
+	// watch for the client going away by reading until it errors
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event firehose.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return conn.WriteText(data)
+	}
+
+	var replayed int64
+	if cursor > 0 {
+		if replayed, err = r.firehose.Replay(ctx, cursor, filter, send); err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to replay firehose", "error", err, "cursor", cursor)
+				_ = conn.Close(websocket.CloseInternalError, "replay failed")
+			}
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					_ = conn.Close(websocket.ClosePolicyViolation, "consumer too slow, reconnect with cursor")
+				}
+				return
+			}
+			if event.TimeUS <= replayed {
+				continue
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// firehoseStore keeps firehose events in the database for replay
+type firehoseStore struct {
+	dbService *db.Service
+}
+
+func (s firehoseStore) Append(ctx context.Context, event firehose.Event) error {
+	if event.Commit == nil {
+		return errors.New("only commit events are stored")
+	}
+	return s.dbService.Queries().AppendFirehoseEvent(ctx, db.AppendFirehoseEventParams{
+		TimeUs:     event.TimeUS,
+		Did:        event.Did,
+		Collection: event.Commit.Collection,
+		Rkey:       event.Commit.Rkey,
+		Operation:  event.Commit.Operation,
+		Record:     sql.NullString{String: string(event.Commit.Record), Valid: len(event.Commit.Record) > 0},
+	})
+}
+
+func (s firehoseStore) Since(ctx context.Context, cursor int64, limit int) ([]firehose.Event, error) {
+	rows, err := s.dbService.Queries().ListFirehoseEventsSince(ctx, db.ListFirehoseEventsSinceParams{
+		TimeUs: cursor,
+		Limit:  int32(limit), // #nosec G115 -- limit is a small internal batch size
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]firehose.Event, 0, len(rows))
+	for _, row := range rows {
+		commit := &firehose.Commit{Operation: row.Operation, Collection: row.Collection, Rkey: row.Rkey}
+		if row.Record.Valid {
+			commit.Record = json.RawMessage(row.Record.String)
+		}
+		events = append(events, firehose.Event{Did: row.Did, TimeUS: row.TimeUs, Kind: firehose.KindCommit, Commit: commit})
+	}
+	return events, nil
+}
+
+func (s firehoseStore) Latest(ctx context.Context) (int64, error) {
+	latest, err := s.dbService.Queries().GetLatestFirehoseEventTime(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return latest, err
+}
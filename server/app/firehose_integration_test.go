@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+// dialFirehose opens a WebSocket to the firehose and returns a function
+// that reads the next event
+func dialFirehose(t *testing.T, serverURL, query string) func() firehose.Event {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial firehose: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, _ = io.WriteString(conn, "GET /subscribe?"+query+" HTTP/1.1\r\nHost: test\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	return func() firehose.Event {
+		t.Helper()
+		var head [2]byte
+		if _, err := io.ReadFull(reader, head[:]); err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		length := int(head[1] & 0x7F)
+		if length == 126 {
+			var ext [2]byte
+			_, _ = io.ReadFull(reader, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			t.Fatalf("Failed to read frame payload: %v", err)
+		}
+		var event firehose.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			t.Fatalf("Failed to decode event %q: %v", payload, err)
+		}
+		return event
+	}
+}
+
+func TestFirehose_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	authorDID := "did:plc:author"
+	mux := CreateTestServer(t, dbService, authorDID)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	post := func(path, body string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var created map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&created)
+		return created
+	}
+
+	topic := post("/api/topics", `{"subject":"Firehose topic","initial_message":"Watch this space"}`)
+	topicID := formatTopicID(topic["did"].(string), topic["rkey"].(string))
+	first := post("/api/topics/"+topicID+"/messages", `{"content":"first"}`)
+
+	t.Run("Replays from cursor with filters", func(t *testing.T) {
+		next := dialFirehose(t, srv.URL, "cursor=1&wantedCollections=quest.dis.message")
+
+		event := next()
+		if event.Kind != firehose.KindCommit || event.Did != authorDID || event.Commit == nil {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+		if event.Commit.Collection != messageCollection || event.Commit.Rkey != first["rkey"] || event.Commit.Operation != firehose.OperationCreate {
+			t.Errorf("Expected replay of the first message, got %+v", event.Commit)
+		}
+		var record lexicon.Message
+		if err := json.Unmarshal(event.Commit.Record, &record); err != nil || record.Content != "first" || record.Type != messageCollection {
+			t.Errorf("Unexpected record %s", event.Commit.Record)
+		}
+
+		second := post("/api/topics/"+topicID+"/messages", `{"content":"second"}`)
+		live := next()
+		if live.Commit == nil || live.Commit.Rkey != second["rkey"] || live.TimeUS <= event.TimeUS {
+			t.Errorf("Expected live event for second message after cursor %d, got %+v", event.TimeUS, live)
+		}
+	})
+
+	t.Run("Live only without cursor", func(t *testing.T) {
+		next := dialFirehose(t, srv.URL, "wantedDids="+authorDID)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/topics/"+topicID, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		event := next()
+		if event.Commit == nil || event.Commit.Operation != firehose.OperationDelete || event.Commit.Collection != topicCollection || event.Commit.Record != nil {
+			t.Errorf("Expected topic delete event, got %+v", event)
+		}
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/subscribe?cursor=yesterday", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/a-h/templ"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// isFragmentRequest reports whether the request came from htmx and expects
+// an HTML fragment rather than a full page or JSON
+func isFragmentRequest(req *http.Request) bool {
+	return req.Header.Get("HX-Request") == "true"
+}
+
+// renderFragment renders a templ component as an HTML fragment. All user
+// content goes through templ's escaping, so handlers never build HTML by hand.
+func renderFragment(w http.ResponseWriter, req *http.Request, component templ.Component) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Add("Vary", "HX-Request")
+	if err := component.Render(req.Context(), w); err != nil {
+		logger.Error("Failed to render fragment", "error", err, "path", req.URL.Path)
+		http.Error(w, "Failed to render fragment", http.StatusInternalServerError)
+	}
+}
+
+// renderPage renders a full page with the given status. The status is
+// already sent when rendering fails, so failures are only logged.
+func renderPage(w http.ResponseWriter, req *http.Request, status int, component templ.Component) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := component.Render(req.Context(), w); err != nil {
+		logger.Error("Failed to render page", "error", err, "path", req.URL.Path)
+	}
+}
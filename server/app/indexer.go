@@ -0,0 +1,25 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+)
+
+// RunIndexer runs the application's jobs that write to the shared database,
+// retrying queued participation records and following mirror sources, until
+// ctx is done. Exactly one process should run them: the server when the
+// indexer setting is embedded, otherwise `disquest indexer`.
+func RunIndexer(ctx context.Context, cfg *config.Config, deps *Deps) {
+	router := newRouter(http.NewServeMux(), cfg, deps)
+	if deps.Broker != nil {
+		// Records published here reach firehose subscribers on the servers
+		errreport.Go("firehose_relay", func() {
+			router.firehose.Relay(ctx, deps.Broker, jobFailed("firehose_relay", "Failed to relay firehose events"))
+		})
+	}
+	router.startMirrors(ctx, cfg)
+	router.runParticipationRetries(ctx, participationRetryInterval)
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+)
+
+// instanceCacheControl lets clients cache the branding briefly; it only
+// changes when the deployment is reconfigured
+const instanceCacheControl = "public, max-age=300"
+
+// InstanceHandler serves the deployment's name, logo, welcome text and
+// default categories so clients can brand themselves to match
+func (r *Router) InstanceHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", instanceCacheControl)
+	httputil.WriteSuccess(w, r.Config.Instance())
+}
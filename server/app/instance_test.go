@@ -0,0 +1,36 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/svrlib"
+)
+
+func TestInstanceHandler(t *testing.T) {
+	cfg := &config.Config{AppName: "Rust Forum", InstanceWelcomeText: "Ask anything.", DefaultCategories: "help,show and tell"}
+	router := &Router{Router: svrlib.NewRouter(http.NewServeMux(), "/", cfg)}
+
+	w := httptest.NewRecorder()
+	router.InstanceHandler(w, httptest.NewRequest("GET", "/api/v1/instance", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != instanceCacheControl {
+		t.Fatalf("Expected a cacheable 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var instance config.Instance
+	if err := json.NewDecoder(w.Body).Decode(&instance); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if instance.Name != "Rust Forum" || instance.WelcomeText != "Ask anything." || !slices.Equal(instance.DefaultCategories, []string{"help", "show and tell"}) {
+		t.Errorf("Unexpected instance %+v", instance)
+	}
+
+	w = httptest.NewRecorder()
+	router.InstanceHandler(w, httptest.NewRequest("POST", "/api/v1/instance", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
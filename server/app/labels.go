@@ -0,0 +1,250 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/labeler"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+const (
+	// defaultLabelQueryLimit is queryLabels' page size when none is given
+	defaultLabelQueryLimit = 50
+	// labelStreamBatch is how many stored labels a stream reads at a time
+	labelStreamBatch = 500
+	// labelPollInterval is how often streams check the database for labels
+	// emitted by other instances
+	labelPollInterval = 5 * time.Second
+)
+
+// emitLabel publishes a label on a record when the instance is a labeler.
+// Failures are logged; the moderation action itself has already been stored.
+func (r *Router) emitLabel(ctx context.Context, uri, val string, neg bool) {
+	if r.labeler == nil {
+		return
+	}
+	if _, err := r.labeler.Emit(ctx, uri, "", val, neg); err != nil {
+		logger.Error("Failed to emit label", "error", err, "uri", uri, "val", val, "neg", neg)
+	}
+}
+
+// QueryLabelsHandler serves com.atproto.label.queryLabels: the labels on
+// records matching uriPatterns, oldest first, paged by cursor
+func (r *Router) QueryLabelsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	patterns := query["uriPatterns"]
+	if len(patterns) == 0 {
+		writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "uriPatterns is required")
+		return
+	}
+	limit := defaultLabelQueryLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > labeler.MaxQueryLimit {
+			writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "limit must be between 1 and 250")
+			return
+		}
+		limit = n
+	}
+	var cursor int64
+	if raw := query.Get("cursor"); raw != "" {
+		var err error
+		if cursor, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "Invalid cursor")
+			return
+		}
+	}
+
+	labels, err := r.labeler.Query(req.Context(), patterns, query["sources"], cursor, limit)
+	if err != nil {
+		logger.Error("Failed to query labels", "error", err)
+		writeXRPCError(w, http.StatusInternalServerError, "InternalServerError", "Failed to query labels")
+		return
+	}
+	out := struct {
+		Cursor string          `json:"cursor,omitempty"`
+		Labels []labeler.Label `json:"labels"`
+	}{Labels: labels}
+	if len(labels) == limit {
+		out.Cursor = strconv.FormatInt(labels[len(labels)-1].Seq, 10)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// SubscribeLabelsHandler serves com.atproto.label.subscribeLabels, streaming
+// labels as CBOR frames. With a cursor, stored labels after it are replayed
+// first; without one only new labels are sent.
+func (r *Router) SubscribeLabelsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := req.Context()
+	latest, err := r.labeler.Latest(ctx)
+	if err != nil {
+		logger.Error("Failed to read latest label", "error", err)
+		writeXRPCError(w, http.StatusInternalServerError, "InternalServerError", "Failed to read labels")
+		return
+	}
+	cursor := latest
+	if raw := req.URL.Query().Get("cursor"); raw != "" {
+		if cursor, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "Invalid cursor")
+			return
+		}
+	}
+
+	conn, err := websocket.Upgrade(w, req)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close(websocket.CloseNormal, "") }()
+	if cursor > latest {
+		_ = conn.WriteBinary(labeler.ErrorFrame("FutureCursor", "Cursor is ahead of the latest label"))
+		return
+	}
+
+	// The hijacked connection no longer cancels the request context, so
+	// watch for the client going away by reading until it errors
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	poll := time.NewTicker(labelPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		// Take the channel before reading so a label emitted meanwhile wakes us
+		changed := r.labeler.Changed()
+		labels, err := r.labeler.Since(ctx, cursor, labelStreamBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to read labels", "error", err, "cursor", cursor)
+				_ = conn.Close(websocket.CloseInternalError, "failed to read labels")
+			}
+			return
+		}
+		for _, label := range labels {
+			if err := conn.WriteBinary(labeler.LabelsFrame(label.Seq, label)); err != nil {
+				return
+			}
+			cursor = label.Seq
+		}
+		if len(labels) == labelStreamBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		case <-changed:
+		case <-poll.C:
+		}
+	}
+}
+
+// writeXRPCError writes an error in the shape XRPC clients expect
+func writeXRPCError(w http.ResponseWriter, status int, name, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": name, "message": message})
+}
+
+// labelStore keeps the labeler's labels in the database
+type labelStore struct {
+	dbService *db.Service
+}
+
+func (s labelStore) Append(ctx context.Context, label labeler.Label) error {
+	return s.dbService.Queries().AppendLabel(ctx, db.AppendLabelParams{
+		Seq: label.Seq,
+		Src: label.Src,
+		Uri: label.URI,
+		Cid: label.CID,
+		Val: label.Val,
+		Neg: label.Neg,
+		Cts: label.Cts,
+		Sig: label.Sig,
+	})
+}
+
+func (s labelStore) Since(ctx context.Context, cursor int64, limit int) ([]labeler.Label, error) {
+	rows, err := s.dbService.Queries().ListLabelsSince(ctx, db.ListLabelsSinceParams{
+		Seq:   cursor,
+		Limit: int32(limit), // #nosec G115 -- limit is a small internal batch size
+	})
+	return fromLabelRows(rows), err
+}
+
+func (s labelStore) Latest(ctx context.Context) (int64, error) {
+	latest, err := s.dbService.Queries().GetLatestLabelSeq(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return latest, err
+}
+
+func (s labelStore) ForURI(ctx context.Context, uri string, prefix bool, cursor int64, limit int) ([]labeler.Label, error) {
+	var rows []db.Label
+	var err error
+	if prefix {
+		rows, err = s.dbService.Queries().ListLabelsByURIPrefix(ctx, db.ListLabelsByURIPrefixParams{
+			Prefix:   uri,
+			Seq:      cursor,
+			RowLimit: int32(limit), // #nosec G115 -- limit is capped at labeler.MaxQueryLimit
+		})
+	} else {
+		rows, err = s.dbService.Queries().ListLabelsByURI(ctx, db.ListLabelsByURIParams{
+			Uri:   uri,
+			Seq:   cursor,
+			Limit: int32(limit), // #nosec G115 -- limit is capped at labeler.MaxQueryLimit
+		})
+	}
+	return fromLabelRows(rows), err
+}
+
+func fromLabelRows(rows []db.Label) []labeler.Label {
+	labels := make([]labeler.Label, 0, len(rows))
+	for _, row := range rows {
+		labels = append(labels, labeler.Label{
+			Ver: labeler.Version,
+			Src: row.Src,
+			URI: row.Uri,
+			CID: row.Cid,
+			Val: row.Val,
+			Neg: row.Neg,
+			Cts: row.Cts,
+			Sig: row.Sig,
+			Seq: row.Seq,
+		})
+	}
+	return labels
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/labeler"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/internal/websocket"
+)
+
+func TestModerationLabels_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	adminDID := "did:plc:admin"
+
+	for _, rkey := range []string{"hidden-topic", "own-topic"} {
+		if _, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            authorDID,
+			Rkey:           rkey,
+			Subject:        "Topic",
+			InitialMessage: "Initial message",
+			Category:       sql.NullString{},
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		}); err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := labeler.NewSigner("did:plc:labeler", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := testDeps(dbService)
+	deps.Labeler = signer
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID}
+	router := newRouter(http.NewServeMux(), cfg, deps)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/com.atproto.label.queryLabels", router.QueryLabelsHandler)
+	mux.HandleFunc("/xrpc/com.atproto.label.subscribeLabels", router.SubscribeLabelsHandler)
+	serve := func(did, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		chain := middleware.TestProtectedChain(did)
+		switch {
+		case strings.HasSuffix(path, "/restore"):
+			req.SetPathValue("id", strings.TrimSuffix(strings.TrimPrefix(path, "/api/topics/"), "/restore"))
+			chain.ThenFunc(router.RestoreTopicHandler).ServeHTTP(w, req)
+		case strings.HasPrefix(path, "/api/topics/"):
+			req.SetPathValue("id", strings.TrimPrefix(path, "/api/topics/"))
+			chain.ThenFunc(router.TopicAPIHandler).ServeHTTP(w, req)
+		default:
+			mux.ServeHTTP(w, req)
+		}
+		return w
+	}
+	queryLabels := func(query string) []labeler.Label {
+		t.Helper()
+		w := serve("", "GET", "/xrpc/com.atproto.label.queryLabels?"+query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("queryLabels: %d %s", w.Code, w.Body.String())
+		}
+		var out struct {
+			Labels []labeler.Label `json:"labels"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("Failed to decode labels: %v", err)
+		}
+		return out.Labels
+	}
+
+	hiddenPath := "/api/topics/" + formatTopicID(authorDID, "hidden-topic")
+	if w := serve(adminDID, "DELETE", hiddenPath); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin hide to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(authorDID, "DELETE", "/api/topics/"+formatTopicID(authorDID, "own-topic")); w.Code != http.StatusOK {
+		t.Fatalf("Expected author delete to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(adminDID, "POST", hiddenPath+"/restore"); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin restore to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	labels := queryLabels("uriPatterns=at://" + authorDID + "/*")
+	uri := topicURI(authorDID, "hidden-topic")
+	if len(labels) != 2 || labels[0].URI != uri || labels[0].Val != labeler.ValueHide || labels[0].Neg || !labels[1].Neg {
+		t.Fatalf("Expected a hide and its negation on %s only, got %+v", uri, labels)
+	}
+	for i, label := range labels {
+		if !labeler.Verify(label, &key.PublicKey) {
+			t.Errorf("Label %d does not verify", i)
+		}
+	}
+	if labels := queryLabels("uriPatterns=" + uri + "&cursor=1"); len(labels) != 1 || !labels[0].Neg {
+		t.Errorf("Expected the negation after cursor 1, got %+v", labels)
+	}
+	if w := serve("", "GET", "/xrpc/com.atproto.label.queryLabels"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected uriPatterns to be required, got %d", w.Code)
+	}
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(streamCtx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/xrpc/com.atproto.label.subscribeLabels?cursor=0")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer func() { _ = conn.Close(websocket.CloseNormal, "") }()
+	// Labels read back as JSON carry no seq; they were stored as 1 and 2
+	for i, label := range labels {
+		seq := int64(i + 1)
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read label frame: %v", err)
+		}
+		if opcode != websocket.OpBinary || !bytes.Equal(data, labeler.LabelsFrame(seq, label)) {
+			t.Errorf("Unexpected frame for label %d: %x", seq, data)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+// lexiconCacheControl lets clients cache schema documents; they only change
+// with a deploy
+const lexiconCacheControl = "public, max-age=3600"
+
+// LexiconsHandler serves the quest.dis.* lexicon documents so PDSes and
+// other clients can validate our records. GET /lexicons/ lists the NSIDs and
+// GET /lexicons/{nsid} (optionally with a .json suffix) returns a document.
+func (r *Router) LexiconsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", lexiconCacheControl)
+
+	nsid := strings.TrimSuffix(req.PathValue("nsid"), ".json")
+	if nsid == "" {
+		httputil.WriteSuccess(w, map[string][]string{"lexicons": lexicon.SchemaNSIDs()})
+		return
+	}
+
+	schema, ok := lexicon.Schema(nsid)
+	if !ok {
+		httputil.WriteError(w, http.StatusNotFound, "Lexicon not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(schema)
+}
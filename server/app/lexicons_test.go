@@ -0,0 +1,51 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+func TestLexiconsHandler(t *testing.T) {
+	router := &Router{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lexicons/", router.LexiconsHandler)
+	mux.HandleFunc("/lexicons/{nsid}", router.LexiconsHandler)
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := get("GET", "/lexicons/")
+	var index struct {
+		Lexicons []string `json:"lexicons"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&index); err != nil || !slices.Contains(index.Lexicons, lexicon.TopicNSID) {
+		t.Fatalf("Expected the index to list %s, got %d %v (%v)", lexicon.TopicNSID, w.Code, index.Lexicons, err)
+	}
+
+	for _, path := range []string{"/lexicons/quest.dis.topic", "/lexicons/quest.dis.topic.json"} {
+		w := get("GET", path)
+		var doc struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&doc); err != nil || doc.ID != lexicon.TopicNSID {
+			t.Errorf("GET %s: got %d id %q (%v)", path, w.Code, doc.ID, err)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("GET %s: schemas should be readable cross-origin", path)
+		}
+	}
+
+	if w := get("GET", "/lexicons/quest.dis.missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown lexicon, got %d", w.Code)
+	}
+	if w := get("POST", "/lexicons/"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", w.Code)
+	}
+}
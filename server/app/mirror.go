@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/mirror"
+	"github.com/jrschumacher/dis.quest/internal/repoproof"
+)
+
+// proofTimeout bounds fetching a mirrored record's proof from its PDS
+const proofTimeout = 10 * time.Second
+
+// startMirrors follows each source in the mirror_sources setting until ctx
+// is done, ingesting as the mirror_queue_size, mirror_workers and
+// mirror_overload settings say and verifying records against their
+// authors' repositories. An invalid setting is logged and disables
+// mirroring rather than stopping the server.
+func (r *Router) startMirrors(ctx context.Context, cfg *config.Config) {
+	sources, err := mirror.ParseSources(cfg.MirrorSources)
+	if err != nil {
+		logger.Error("Invalid mirror_sources, mirroring disabled", "error", err)
+		return
+	}
+	options := mirror.Options{
+		QueueSize:       cfg.MirrorQueueSize,
+		Workers:         cfg.MirrorWorkers,
+		Overload:        cfg.MirrorOverload,
+		Verifier:        repoproof.NewVerifier(auth.NewIdentityResolver(), &http.Client{Timeout: proofTimeout}),
+		RequireVerified: cfg.MirrorRequireVerified,
+	}
+	store := mirrorStore{dbService: r.dbService, clock: r.clock}
+	for _, source := range sources {
+		follower := mirror.NewFollower(source, store, options)
+		errreport.Go("mirror", func() {
+			follower.Run(ctx, jobFailed("mirror", "Mirror stream failed"))
+		})
+	}
+}
+
+// MirrorTopicsHandler lists topics mirrored from other instances, newest
+// first, optionally narrowed to one category. Each carries the origin it
+// was mirrored from.
+func (r *Router) MirrorTopicsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := req.Context()
+	limit, offset := parsePagination(req)
+	var topics []db.MirrorTopic
+	var err error
+	if category := req.URL.Query().Get("category"); category != "" {
+		topics, err = r.dbService.Queries().ListMirrorTopicsByCategory(ctx, db.ListMirrorTopicsByCategoryParams{
+			Category: category,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	} else {
+		topics, err = r.dbService.Queries().ListMirrorTopics(ctx, db.ListMirrorTopicsParams{Limit: limit, Offset: offset})
+	}
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to list mirrored topics")
+		return
+	}
+
+	httputil.WriteSuccess(w, topics)
+}
+
+// MirrorMessagesHandler lists the messages mirrored into a mirrored topic,
+// oldest first
+func (r *Router) MirrorMessagesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	topicID := formatTopicID(topicDid, topicRkey)
+
+	ctx := req.Context()
+	if _, err := r.dbService.Queries().GetMirrorTopic(ctx, db.GetMirrorTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch mirrored topic", "topicID", topicID)
+		return
+	}
+
+	limit, offset := parsePagination(req)
+	messages, err := r.dbService.Queries().ListMirrorMessages(ctx, db.ListMirrorMessagesParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to list mirrored messages", "topicID", topicID)
+		return
+	}
+
+	httputil.WriteSuccess(w, messages)
+}
+
+// mirrorStore keeps mirrored records in their own tables, apart from the
+// topics and messages this instance indexes itself
+type mirrorStore struct {
+	dbService *db.Service
+	clock     clock.Clock
+}
+
+func (s mirrorStore) Cursor(ctx context.Context, origin string) (int64, error) {
+	cursor, err := s.dbService.Queries().GetMirrorCursor(ctx, origin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return cursor, err
+}
+
+func (s mirrorStore) SaveCursor(ctx context.Context, origin string, cursor int64) error {
+	return s.dbService.Queries().SaveMirrorCursor(ctx, db.SaveMirrorCursorParams{
+		Origin:    origin,
+		TimeUs:    cursor,
+		UpdatedAt: s.clock.Now(),
+	})
+}
+
+func (s mirrorStore) Revision(ctx context.Context, origin, did, collection, rkey string) (mirror.Revision, bool, error) {
+	record, err := s.dbService.Queries().GetMirrorRecord(ctx, db.GetMirrorRecordParams{
+		Origin:     origin,
+		Did:        did,
+		Collection: collection,
+		Rkey:       rkey,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return mirror.Revision{}, false, nil
+	}
+	if err != nil {
+		return mirror.Revision{}, false, err
+	}
+	return mirror.Revision{
+		Did:        record.Did,
+		Collection: record.Collection,
+		Rkey:       record.Rkey,
+		CID:        record.Cid,
+		TimeUS:     record.TimeUs,
+		Deleted:    record.Deleted,
+	}, true, nil
+}
+
+func (s mirrorStore) SaveRevision(ctx context.Context, origin string, rev mirror.Revision) error {
+	return s.dbService.Queries().SaveMirrorRecord(ctx, db.SaveMirrorRecordParams{
+		Origin:     origin,
+		Did:        rev.Did,
+		Collection: rev.Collection,
+		Rkey:       rev.Rkey,
+		Cid:        rev.CID,
+		TimeUs:     rev.TimeUS,
+		Deleted:    rev.Deleted,
+	})
+}
+
+func (s mirrorStore) HasTopic(ctx context.Context, origin, did, rkey string) (bool, error) {
+	n, err := s.dbService.Queries().CountMirrorTopic(ctx, db.CountMirrorTopicParams{Origin: origin, Did: did, Rkey: rkey})
+	return n > 0, err
+}
+
+func (s mirrorStore) SaveTopic(ctx context.Context, topic mirror.Topic) error {
+	return s.dbService.Queries().UpsertMirrorTopic(ctx, db.UpsertMirrorTopicParams{
+		Did:            topic.Did,
+		Rkey:           topic.Rkey,
+		Origin:         topic.Origin,
+		Subject:        topic.Subject,
+		InitialMessage: topic.InitialMessage,
+		Category:       topic.Category,
+		CreatedAt:      topic.CreatedAt,
+		MirroredAt:     s.clock.Now(),
+		Verified:       topic.Verified,
+	})
+}
+
+func (s mirrorStore) SaveMessage(ctx context.Context, message mirror.Message) error {
+	return s.dbService.Queries().UpsertMirrorMessage(ctx, db.UpsertMirrorMessageParams{
+		Did:               message.Did,
+		Rkey:              message.Rkey,
+		Origin:            message.Origin,
+		TopicDid:          message.TopicDid,
+		TopicRkey:         message.TopicRkey,
+		ParentMessageRkey: message.ParentMessageRkey,
+		Content:           db.MessageContent(message.Content),
+		CreatedAt:         message.CreatedAt,
+		MirroredAt:        s.clock.Now(),
+		Verified:          message.Verified,
+	})
+}
+
+func (s mirrorStore) DeleteTopic(ctx context.Context, origin, did, rkey string) error {
+	return s.dbService.DeleteMirrorTopic(ctx, origin, did, rkey)
+}
+
+func (s mirrorStore) DeleteMessage(ctx context.Context, origin, did, rkey string) error {
+	return s.dbService.Queries().DeleteMirrorMessage(ctx, db.DeleteMirrorMessageParams{Origin: origin, Did: did, Rkey: rkey})
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/mirror"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestMirror_Integration(t *testing.T) {
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	cfg := &config.Config{AppEnv: "test"}
+
+	// The origin is a second instance serving its firehose over HTTP
+	originMux := http.NewServeMux()
+	origin := RegisterTestRoutes(originMux, "/", cfg, testutil.TestDatabase(t), authorDID)
+	originServer := httptest.NewServer(originMux)
+	defer originServer.Close()
+
+	now := time.Now().UTC()
+	for _, topic := range []struct{ rkey, subject, category string }{
+		{"generics", "Generics in practice", "go"},
+		{"bread", "Sourdough starter", "baking"},
+	} {
+		record := newTopicRecord(db.Topic{
+			Did:            authorDID,
+			Rkey:           topic.rkey,
+			Subject:        topic.subject,
+			InitialMessage: "Looking for advice",
+			Category:       sql.NullString{String: topic.category, Valid: true},
+			CreatedAt:      now,
+		})
+		if err := origin.publish(ctx, authorDID, topicCollection, topic.rkey, firehose.OperationCreate, record); err != nil {
+			t.Fatalf("Failed to publish topic: %v", err)
+		}
+	}
+	for _, message := range []db.Message{
+		{Did: "did:plc:replier", Rkey: "m1", TopicDid: authorDID, TopicRkey: "generics", Content: "For containers", CreatedAt: now},
+		{Did: "did:plc:replier", Rkey: "m2", TopicDid: authorDID, TopicRkey: "bread", Content: "Feed it more", CreatedAt: now},
+	} {
+		if err := origin.publish(ctx, message.Did, messageCollection, message.Rkey, firehose.OperationCreate, newMessageRecord(message)); err != nil {
+			t.Fatalf("Failed to publish message: %v", err)
+		}
+	}
+
+	dbService := testutil.TestDatabase(t)
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", cfg, dbService, authorDID)
+	store := mirrorStore{dbService: dbService, clock: router.clock}
+	follower := mirror.NewFollower(mirror.Source{Origin: originServer.URL, Categories: []string{"go"}}, store, mirror.Options{})
+
+	followCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- follower.Follow(followCtx) }()
+	// The stream stays open for live events, so wait for the replay to land
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages, err := dbService.Queries().ListMirrorMessages(ctx, db.ListMirrorMessagesParams{TopicDid: authorDID, TopicRkey: "generics", Limit: 10})
+		if err != nil {
+			t.Fatalf("Failed to list mirrored messages: %v", err)
+		}
+		if len(messages) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for mirrored records")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// A delete older than the mirrored create is a replay and changes nothing
+	stale := firehose.Event{Did: authorDID, TimeUS: 1, Kind: firehose.KindCommit, Commit: &firehose.Commit{
+		Operation: firehose.OperationDelete, Collection: topicCollection, Rkey: "generics",
+	}}
+	if err := follower.Apply(ctx, stale); err != nil {
+		t.Fatalf("Failed to apply the replayed event: %v", err)
+	}
+	record, err := dbService.Queries().GetMirrorRecord(ctx, db.GetMirrorRecordParams{
+		Origin: originServer.URL, Did: authorDID, Collection: topicCollection, Rkey: "generics",
+	})
+	if err != nil || record.Deleted || record.Cid == "" || record.TimeUs <= 1 {
+		t.Errorf("Expected the create to stay the newest revision, got %+v (%v)", record, err)
+	}
+	if cursor, _ := store.Cursor(ctx, originServer.URL); cursor <= 1 {
+		t.Errorf("Expected the replay to leave the cursor, got %d", cursor)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/api/v1/mirrors/topics?category=go")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var topics []db.MirrorTopic
+	if err := json.NewDecoder(w.Body).Decode(&topics); err != nil {
+		t.Fatalf("Failed to decode topics: %v", err)
+	}
+	if len(topics) != 1 || topics[0].Rkey != "generics" || topics[0].Origin != originServer.URL {
+		t.Fatalf("Expected only the go topic with its origin, got %+v", topics)
+	}
+
+	w = get("/api/v1/mirrors/topics/" + authorDID + ":generics/messages")
+	var messages []db.MirrorMessage
+	if err := json.NewDecoder(w.Body).Decode(&messages); err != nil {
+		t.Fatalf("Failed to decode messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "For containers" || messages[0].Origin != originServer.URL {
+		t.Errorf("Expected the mirrored reply, got %+v", messages)
+	}
+
+	// Mirrored topics stay out of the local index
+	if _, err := dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: authorDID, Rkey: "generics"}); err == nil {
+		t.Error("Expected the mirrored topic to stay out of the local topics")
+	}
+	if w := get("/api/v1/mirrors/topics/" + authorDID + ":bread/messages"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unmirrored topic, got %d", w.Code)
+	}
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the limit and offset query parameters, falling back
+// to defaults for missing or out-of-range values
+func parsePagination(req *http.Request) (limit, offset int32) {
+	limit, offset = defaultPageLimit, 0
+
+	if l, err := strconv.ParseInt(req.URL.Query().Get("limit"), 10, 32); err == nil && l > 0 && l <= maxPageLimit {
+		limit = int32(l)
+	}
+	if o, err := strconv.ParseInt(req.URL.Query().Get("offset"), 10, 32); err == nil && o >= 0 {
+		offset = int32(o)
+	}
+	return limit, offset
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+// ParticipationAPIHandler sets the current user's participation status in a topic
+func (r *Router) ParticipationAPIHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var updateReq struct {
+		Status string `json:"status"`
+	}
+	if err := httputil.DecodeJSON(w, req, &updateReq); err != nil {
+		httputil.WriteDecodeError(w, err)
+		return
+	}
+
+	validator := validation.ParticipationValidation{Status: updateReq.Status}
+	if err := validator.Validate(); err != nil {
+		httputil.WriteValidationFailure(w, err)
+		return
+	}
+
+	if _, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	participation, err := r.dbService.SetParticipationStatus(ctx, db.SetParticipationStatusParams{
+		Did:       userCtx.DID,
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+		Status:    updateReq.Status,
+		UpdatedAt: r.clock.Now(),
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to update participation", "did", userCtx.DID)
+		return
+	}
+
+	httputil.WriteSuccess(w, participation)
+}
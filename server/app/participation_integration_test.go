@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+func TestParticipationAPI_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	testDID := "did:plc:test123"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            "did:plc:author",
+		Rkey:           "participation-topic",
+		Subject:        "Participation Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	topicPath := "/api/topics/" + topic.Did + ":" + topic.Rkey + "/participation"
+
+	mux := CreateTestServer(t, dbService, testDID)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"Follow topic", topicPath, `{"status":"following"}`, http.StatusOK, ""},
+		{"Mute topic", topicPath, `{"status":"muted"}`, http.StatusOK, ""},
+		{"Invalid status", topicPath, `{"status":"banned"}`, http.StatusBadRequest, validation.CodeOneOf},
+		{"Missing status", topicPath, `{}`, http.StatusBadRequest, validation.CodeRequired},
+		{"Unknown topic", "/api/topics/did:plc:author:missing/participation", `{"status":"following"}`, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+
+			var resp httputil.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Code != httputil.CodeValidationFailed {
+				t.Errorf("Expected code %q, got %q", httputil.CodeValidationFailed, resp.Code)
+			}
+			if len(resp.Details) != 1 || resp.Details[0].Code != tt.expectedCode {
+				t.Errorf("Expected detail code %q, got %+v", tt.expectedCode, resp.Details)
+			}
+		})
+	}
+
+	participation, err := dbService.Queries().GetParticipation(ctx, db.GetParticipationParams{
+		Did:       testDID,
+		TopicDid:  topic.Did,
+		TopicRkey: topic.Rkey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch participation: %v", err)
+	}
+	if participation.Status != validation.ParticipationMuted {
+		t.Errorf("Expected status %q, got %q", validation.ParticipationMuted, participation.Status)
+	}
+}
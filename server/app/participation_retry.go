@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+const (
+	// participationRetryInterval is how often queued participation records are retried
+	participationRetryInterval = time.Minute
+	// participationRetryBatch bounds how many queued records one pass publishes
+	participationRetryBatch = 50
+	// participationRetryBackoff is the delay before the first retry; it
+	// doubles with each failed attempt up to maxParticipationRetryBackoff
+	participationRetryBackoff    = time.Minute
+	maxParticipationRetryBackoff = time.Hour
+	// participationReportLimit bounds each list in the reconciliation report
+	participationReportLimit = 100
+)
+
+// newCreatorParticipationRecord returns a topic creator's participation as
+// a quest.dis.participation record making them the topic's moderator
+func newCreatorParticipationRecord(participation db.Participation) lexicon.Participation {
+	return lexicon.Participation{
+		Type:          participationCollection,
+		Topic:         pds.ATURI{Repo: participation.TopicDid, Collection: topicCollection, Rkey: participation.TopicRkey}.String(),
+		Participant:   participation.Did,
+		JoinedAt:      participation.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Role:          lexicon.RoleModerator,
+		RecordVersion: lexicon.CurrentVersion(lexicon.ParticipationNSID),
+	}
+}
+
+// retryDelay returns how long to wait before the next attempt after the
+// given number of failed ones
+func retryDelay(attempts int32) time.Duration {
+	delay := participationRetryBackoff
+	for i := int32(0); i < attempts && delay < maxParticipationRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxParticipationRetryBackoff)
+}
+
+// publishCreatorParticipation publishes the record making a topic's creator
+// its moderator. If publishing fails the record is queued for the retry job
+// instead of being dropped.
+func (r *Router) publishCreatorParticipation(ctx context.Context, participation db.Participation) {
+	rkey := r.ids.NewID("participation")
+	record := newCreatorParticipationRecord(participation)
+	err := r.publish(ctx, participation.Did, participationCollection, rkey, firehose.OperationCreate, record)
+	if err == nil {
+		return
+	}
+
+	logger.Warn("Queueing participation record for retry", "error", err, "did", participation.Did, "topic", participation.TopicRkey)
+	now := r.clock.Now()
+	// The topic is already created, so queue even if the client has gone away
+	_, err = r.dbService.Queries().QueueParticipationRetry(context.WithoutCancel(ctx), db.QueueParticipationRetryParams{
+		Did:           participation.Did,
+		TopicDid:      participation.TopicDid,
+		TopicRkey:     participation.TopicRkey,
+		Rkey:          rkey,
+		LastError:     err.Error(),
+		NextAttemptAt: now.Add(retryDelay(0)),
+		CreatedAt:     now,
+	})
+	if err != nil {
+		logger.Error("Failed to queue participation retry", "error", err, "did", participation.Did, "topic", participation.TopicRkey)
+	}
+}
+
+// retryParticipations publishes the queued participation records that are
+// due and returns how many it published. Failures are rescheduled with
+// backoff; records whose participation has since been removed are dropped.
+func (r *Router) retryParticipations(ctx context.Context) (int, error) {
+	queries := r.dbService.Queries()
+	now := r.clock.Now()
+	due, err := queries.ListDueParticipationRetries(ctx, db.ListDueParticipationRetriesParams{
+		NextAttemptAt: now,
+		Limit:         participationRetryBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, retry := range due {
+		key := db.DeleteParticipationRetryParams{Did: retry.Did, TopicDid: retry.TopicDid, TopicRkey: retry.TopicRkey}
+		participation, err := queries.GetParticipation(ctx, db.GetParticipationParams(key))
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := queries.DeleteParticipationRetry(ctx, key); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err != nil {
+			return published, err
+		}
+
+		record := newCreatorParticipationRecord(participation)
+		if err := r.publish(ctx, retry.Did, participationCollection, retry.Rkey, firehose.OperationCreate, record); err != nil {
+			if err := queries.RescheduleParticipationRetry(ctx, db.RescheduleParticipationRetryParams{
+				Did:           retry.Did,
+				TopicDid:      retry.TopicDid,
+				TopicRkey:     retry.TopicRkey,
+				LastError:     err.Error(),
+				NextAttemptAt: now.Add(retryDelay(retry.Attempts + 1)),
+			}); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err := queries.DeleteParticipationRetry(ctx, key); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// runParticipationRetries retries queued participation records every
+// interval until ctx is done
+func (r *Router) runParticipationRetries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := r.retryParticipations(ctx)
+			if err != nil {
+				logger.Error("Failed to retry participation records", "error", err)
+				errreport.Job("participation_retry", err)
+			}
+			if published > 0 {
+				logger.Info("Published queued participation records", "count", published)
+			}
+		}
+	}
+}
+
+// participationReport lists topic creators who may be missing their
+// moderator participation
+type participationReport struct {
+	// Pending records failed to publish and are waiting for the retry job
+	Pending []db.ParticipationRetry `json:"pending"`
+	// Missing topics have no participation row for their creator at all
+	Missing []db.Topic `json:"missing"`
+}
+
+// ParticipationReportHandler reports creator participation records that
+// are queued for retry or missing, for admins reconciling topic ownership
+func (r *Router) ParticipationReportHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	pending, err := r.dbService.Queries().ListParticipationRetries(ctx, participationReportLimit)
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to load participation retries")
+		return
+	}
+	missing, err := r.dbService.Queries().ListTopicsMissingCreatorParticipation(ctx, participationReportLimit)
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to load topics missing participation")
+		return
+	}
+
+	report := participationReport{Pending: pending, Missing: missing}
+	httputil.WriteSuccess(w, versioned(w, req, report, func() any {
+		return participationReportView{Pending: pending, Missing: newTopicViews(missing)}
+	}))
+}
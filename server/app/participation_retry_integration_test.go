@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+// flakyFirehoseStore fails appends while down is set
+type flakyFirehoseStore struct {
+	firehose.Store
+	down bool
+}
+
+func (s *flakyFirehoseStore) Append(ctx context.Context, event firehose.Event) error {
+	if s.down {
+		return errors.New("firehose store down")
+	}
+	return s.Store.Append(ctx, event)
+}
+
+func TestParticipationRetry_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	adminDID := "did:plc:admin"
+	ctx := context.Background()
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(start)
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test", AdminDIDs: adminDID}, dbService, adminDID)
+	store := &flakyFirehoseStore{Store: firehoseStore{dbService: dbService}, down: true}
+	router.firehose = firehose.New(store)
+	router.clock = clk
+	router.ids = &clock.Sequence{}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	report := func() participationReport {
+		t.Helper()
+		w := do("GET", "/api/admin/participation", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var got participationReport
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		return got
+	}
+
+	w := do("POST", "/api/topics", `{"subject":"Queued","initial_message":"Firehose is down"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected topic creation to succeed while the firehose is down, got %d: %s", w.Code, w.Body.String())
+	}
+	var topic db.Topic
+	if err := json.NewDecoder(w.Body).Decode(&topic); err != nil {
+		t.Fatalf("Failed to decode topic: %v", err)
+	}
+
+	pending := report().Pending
+	if len(pending) != 1 || pending[0].TopicRkey != topic.Rkey || pending[0].Attempts != 0 || pending[0].LastError == "" {
+		t.Fatalf("Expected one queued participation for %s, got %+v", topic.Rkey, pending)
+	}
+
+	if n, err := router.retryParticipations(ctx); err != nil || n != 0 {
+		t.Fatalf("Expected nothing due before the backoff, got %d, %v", n, err)
+	}
+	clk.Advance(participationRetryBackoff)
+	if n, err := router.retryParticipations(ctx); err != nil || n != 0 {
+		t.Fatalf("Expected the retry to fail while the firehose is down, got %d, %v", n, err)
+	}
+	pending = report().Pending
+	if len(pending) != 1 || pending[0].Attempts != 1 || !pending[0].NextAttemptAt.Equal(clk.Now().Add(2*participationRetryBackoff)) {
+		t.Fatalf("Expected the retry to back off, got %+v", pending)
+	}
+
+	store.down = false
+	clk.Advance(2 * participationRetryBackoff)
+	if n, err := router.retryParticipations(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected the queued record to be published, got %d, %v", n, err)
+	}
+	if pending := report().Pending; len(pending) != 0 {
+		t.Errorf("Expected the queue to be empty, got %+v", pending)
+	}
+
+	var records []lexicon.Participation
+	filter := firehose.Filter{Collections: []string{participationCollection}}
+	if _, err := router.firehose.Replay(ctx, 0, filter, func(event firehose.Event) error {
+		var record lexicon.Participation
+		if err := json.Unmarshal(event.Commit.Record, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(records) != 1 || records[0].Participant != adminDID || records[0].Role != lexicon.RoleModerator {
+		t.Errorf("Expected one moderator participation record, got %+v", records)
+	}
+
+	if err := dbService.Queries().DeleteParticipation(ctx, db.DeleteParticipationParams{
+		Did: adminDID, TopicDid: topic.Did, TopicRkey: topic.Rkey,
+	}); err != nil {
+		t.Fatalf("Failed to delete participation: %v", err)
+	}
+	if missing := report().Missing; len(missing) != 1 || missing[0].Rkey != topic.Rkey {
+		t.Errorf("Expected %s to be reported missing its creator's participation, got %+v", topic.Rkey, missing)
+	}
+
+	strangerMux := http.NewServeMux()
+	RegisterTestRoutes(strangerMux, "/", &config.Config{AppEnv: "test", AdminDIDs: adminDID}, dbService, "did:plc:stranger")
+	w = httptest.NewRecorder()
+	strangerMux.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/participation", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected non-admin to be forbidden, got %d", w.Code)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{5, 32 * time.Minute},
+		{6, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+// QueryStatsHandler reports latency for each database query since startup,
+// slowest in total first, to find queries that need an index
+func (r *Router) QueryStatsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	if !r.isAdmin(userCtx.DID) {
+		httputil.WriteError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	httputil.WriteSuccess(w, r.dbService.QueryStats())
+}
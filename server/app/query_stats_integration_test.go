@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestQueryStats_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	adminDID := "did:plc:admin"
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID}
+
+	mux := http.NewServeMux()
+	RegisterTestRoutes(mux, "/", cfg, dbService, adminDID)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/topics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 listing topics, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/queries", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats []db.QueryStat
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	found := false
+	for _, stat := range stats {
+		if stat.Name == "ListTopics" && stat.Count > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected ListTopics among the timed queries, got %+v", stats)
+	}
+
+	strangerMux := http.NewServeMux()
+	RegisterTestRoutes(strangerMux, "/", cfg, dbService, "did:plc:stranger")
+	w = httptest.NewRecorder()
+	strangerMux.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/queries", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected non-admin to be forbidden, got %d", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// recordMigration reports what a migration run did to each collection
+type recordMigration struct {
+	Collections map[string]pds.RewriteResult `json:"collections"`
+}
+
+// MigrateRecordsHandler upgrades the user's quest.dis.* records to the
+// current lexicon versions, rewriting old-format records in their PDS with
+// applyWrites. Running it again once everything is current writes nothing.
+// When the PDS refuses the session's scope, the response says whether
+// signing in again or an app password in the body may help (see withRepo).
+func (r *Router) MigrateRecordsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var body appPasswordRequest
+	if req.ContentLength != 0 {
+		if err := httputil.DecodeJSON(w, req, &body); err != nil {
+			httputil.WriteDecodeError(w, err)
+			return
+		}
+	}
+
+	var result recordMigration
+	var nsid string
+	err := r.withRepo(req, userCtx.DID, opMigrateRecords, body.AppPassword, func(client RepoClient) error {
+		result = recordMigration{Collections: make(map[string]pds.RewriteResult)}
+		for _, nsid = range lexicon.RecordNSIDs {
+			rewritten, err := client.RewriteRecords(ctx, userCtx.DID, nsid, func(record map[string]any) (bool, error) {
+				return lexicon.Upgrade(nsid, record)
+			})
+			result.Collections[nsid] = rewritten
+			if err != nil {
+				return err
+			}
+			if rewritten.Rewritten > 0 || len(rewritten.Skipped) > 0 {
+				logger.Info("Migrated records", "did", userCtx.DID, "collection", nsid, "rewritten", rewritten.Rewritten, "skipped", len(rewritten.Skipped))
+			}
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, pds.ErrInsufficientScope):
+		r.writeScopeRefused(w, userCtx.DID, opMigrateRecords, "rewrite your dis.quest records", err)
+		return
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		httputil.WriteError(w, http.StatusUnauthorized, "Invalid app password", "did", userCtx.DID)
+		return
+	case pds.IsUnavailable(err):
+		httputil.WritePDSUnavailable(w, pds.RetryAfter(err), "did", userCtx.DID, "error", err)
+		return
+	case errors.Is(err, errNoRepoClient):
+		httputil.WriteError(w, http.StatusUnauthorized, "A PDS session is required to migrate records", "did", userCtx.DID, "error", err)
+		return
+	case err != nil:
+		httputil.WriteError(w, http.StatusBadGateway, "Failed to migrate records on your PDS; run the migration again to continue", "did", userCtx.DID, "collection", nsid, "error", err)
+		return
+	}
+
+	httputil.WriteSuccess(w, result)
+}
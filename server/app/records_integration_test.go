@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+// recordsPDS is a fake PDS serving listRecords from values and applying
+// applyWrites updates to them
+type recordsPDS struct {
+	mu     sync.Mutex
+	values map[string]map[string]json.RawMessage // collection -> rkey -> record
+	writes int
+}
+
+func (p *recordsPDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/xrpc/com.atproto.repo.listRecords":
+		collection := r.URL.Query().Get("collection")
+		records := []map[string]any{}
+		for rkey, value := range p.values[collection] {
+			records = append(records, map[string]any{"uri": "at://did:plc:user/" + collection + "/" + rkey, "value": value})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"records": records})
+	case "/xrpc/com.atproto.repo.applyWrites":
+		var body struct {
+			Writes []struct {
+				Collection string          `json:"collection"`
+				Rkey       string          `json:"rkey"`
+				Value      json.RawMessage `json:"value"`
+			} `json:"writes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, write := range body.Writes {
+			p.values[write.Collection][write.Rkey] = write.Value
+			p.writes++
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestMigrateRecords_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	userDID := "did:plc:user"
+
+	fake := &recordsPDS{values: map[string]map[string]json.RawMessage{
+		lexicon.TopicNSID: {
+			"old":     json.RawMessage(`{"title":"Old","createdBy":"did:plc:user","createdAt":"2025-01-01T00:00:00Z","future":"kept"}`),
+			"current": json.RawMessage(`{"title":"Current","recordVersion":1}`),
+		},
+		lexicon.MessageNSID:       {"bad": json.RawMessage(`{"content":"x","recordVersion":"one"}`)},
+		lexicon.ParticipationNSID: {},
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test"}, dbService, userDID)
+	router.repos = pdsRepos{resolve: func(context.Context, string) (string, error) { return srv.URL, nil }}
+	migrate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/me/records/migrate", nil)
+		req.AddCookie(&http.Cookie{Name: "dsq_session", Value: "access-token"})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := migrate()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result recordMigration
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if topics := result.Collections[lexicon.TopicNSID]; topics.Scanned != 2 || topics.Rewritten != 1 {
+		t.Errorf("Expected one of two topics rewritten, got %+v", topics)
+	}
+	if messages := result.Collections[lexicon.MessageNSID]; len(messages.Skipped) != 1 || messages.Rewritten != 0 {
+		t.Errorf("Expected the malformed message to be skipped, got %+v", messages)
+	}
+
+	var upgraded map[string]any
+	if err := json.Unmarshal(fake.values[lexicon.TopicNSID]["old"], &upgraded); err != nil {
+		t.Fatalf("Failed to decode upgraded record: %v", err)
+	}
+	if upgraded["recordVersion"] != float64(lexicon.CurrentVersion(lexicon.TopicNSID)) || upgraded["future"] != "kept" {
+		t.Errorf("Expected the old topic to be stamped and keep unknown fields, got %v", upgraded)
+	}
+
+	if w := migrate(); w.Code != http.StatusOK || fake.writes != 1 {
+		t.Errorf("Expected a second run to write nothing, got %d with %d writes", w.Code, fake.writes)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/related"
+)
+
+const (
+	// relatedRebuildInterval is how often related-topic suggestions are recomputed
+	relatedRebuildInterval = 15 * time.Minute
+	// relatedCorpusLimit bounds how many recent topics the model relates
+	relatedCorpusLimit = 5000
+	// relatedLimit is how many suggestions are kept for each topic
+	relatedLimit = 5
+)
+
+// relatedTopics is the related-topics response
+type relatedTopics struct {
+	TopicID string               `json:"topic_id"`
+	Related []related.Suggestion `json:"related"`
+}
+
+// RelatedTopicsHandler suggests topics related to the one in the path,
+// best first. Suggestions come from the background model, so a topic
+// created since its last rebuild has none yet.
+func (r *Router) RelatedTopicsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	topicID := formatTopicID(topicDid, topicRkey)
+
+	if _, err := r.dbService.Queries().GetTopic(req.Context(), db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicID", topicID)
+		return
+	}
+
+	httputil.WriteSuccess(w, relatedTopics{TopicID: topicID, Related: r.related.Related(topicID)})
+}
+
+// relatedSource feeds the most recent topics to the related-topics model.
+// A topic's category is its tag, as in its published record.
+type relatedSource struct {
+	dbService *db.Service
+}
+
+func (s relatedSource) Documents(ctx context.Context) ([]related.Document, error) {
+	rows, err := s.dbService.Queries().ListRelatedTopicCandidates(ctx, relatedCorpusLimit)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]related.Document, len(rows))
+	for i, row := range rows {
+		docs[i] = related.Document{
+			ID:      formatTopicID(row.Did, row.Rkey),
+			Title:   row.Subject,
+			Summary: row.InitialMessage,
+		}
+		if row.Category.Valid {
+			docs[i].Tags = []string{row.Category.String}
+		}
+	}
+	return docs, nil
+}
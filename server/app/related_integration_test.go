@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestRelatedTopics_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+
+	topics := []struct{ rkey, subject, category string }{
+		{"k8s", "Deploying Go services to Kubernetes", "go"},
+		{"helm", "Helm charts for Go services on Kubernetes", "ops"},
+		{"bread", "Sourdough starter tips", "baking"},
+	}
+	for _, topic := range topics {
+		_, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            authorDID,
+			Rkey:           topic.rkey,
+			Subject:        topic.subject,
+			InitialMessage: "Looking for advice",
+			Category:       sql.NullString{String: topic.category, Valid: true},
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test"}, dbService, authorDID)
+	if err := router.related.Rebuild(ctx); err != nil {
+		t.Fatalf("Failed to rebuild related topics: %v", err)
+	}
+	get := func(topicID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/topics/"+topicID+"/related", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(authorDID + ":k8s")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result relatedTopics
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// bread shares the boilerplate initial message, so it trails helm
+	if len(result.Related) != 2 || result.Related[0].ID != authorDID+":helm" || result.Related[0].Score <= result.Related[1].Score {
+		t.Errorf("Expected helm to be the best suggestion, got %+v", result.Related)
+	}
+
+	if w := get(authorDID + ":missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing topic, got %d", w.Code)
+	}
+	if w := get("not-a-topic"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bad topic ID, got %d", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// scopeRemedy works out what may fix the user's PDS refusing their
+// session's scope for op: signing in again when the authorization server
+// supports the scope this client asks for, or an app password when op may
+// fall back to one
+func (r *Router) scopeRemedy(did, op string, err error) httputil.ScopeRemedy {
+	remedy := httputil.ScopeRemedy{AppPassword: r.Config.AllowsAppPasswordFallback(op)}
+	var scopeErr *pds.ScopeError
+	if errors.As(err, &scopeErr) {
+		remedy.MissingScope = scopeErr.Required
+	}
+	metadata, err := r.authServers(did)
+	if err != nil {
+		logger.Warn("Failed to fetch authorization server metadata", "did", did, "error", err)
+		return remedy
+	}
+	remedy.Relogin = auth.CanGrantScope(metadata, remedy.MissingScope)
+	return remedy
+}
+
+// scopeMessage tells the user what to do after their PDS refused to let
+// their session do action, e.g. "delete dis.quest records"
+func scopeMessage(action string, remedy httputil.ScopeRemedy) string {
+	message := "Your PDS does not let this sign-in " + action + "."
+	switch {
+	case remedy.Relogin && remedy.AppPassword:
+		return message + " Sign out and sign in again to grant dis.quest access, or try again with an app password."
+	case remedy.Relogin:
+		return message + " Sign out and sign in again to grant dis.quest access, then try again."
+	case remedy.AppPassword:
+		return message + " Your authorization server cannot grant the access needed, but you can try again with an app password."
+	default:
+		return message + " Your authorization server cannot grant the access needed; ask the administrator of this dis.quest server for help."
+	}
+}
+
+// writeScopeRefused responds to a PDS refusing the session's scope for op
+func (r *Router) writeScopeRefused(w http.ResponseWriter, did, op, action string, err error) {
+	remedy := r.scopeRemedy(did, op, err)
+	httputil.WriteInsufficientScope(w, scopeMessage(action, remedy), remedy, "did", did, "operation", op, "error", err)
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/svrlib"
+)
+
+func TestWriteScopeRefused(t *testing.T) {
+	refused := &pds.ScopeError{
+		XRPCError: &pds.XRPCError{Status: http.StatusForbidden, Name: "Forbidden"},
+		Required:  auth.ScopeTransitionGeneric,
+	}
+
+	for _, tc := range []struct {
+		name      string
+		fallback  string
+		supported []string
+		discover  error
+		want      httputil.ScopeRemedy
+	}{
+		{
+			name:      "server supports the scope",
+			supported: []string{auth.ScopeATProto, auth.ScopeTransitionGeneric},
+			want:      httputil.ScopeRemedy{MissingScope: auth.ScopeTransitionGeneric, Relogin: true},
+		},
+		{
+			name:      "server lacks the scope",
+			fallback:  opMigrateRecords,
+			supported: []string{auth.ScopeATProto},
+			want:      httputil.ScopeRemedy{MissingScope: auth.ScopeTransitionGeneric, AppPassword: true},
+		},
+		{
+			name:     "metadata unavailable",
+			discover: errors.New("unreachable"),
+			want:     httputil.ScopeRemedy{MissingScope: auth.ScopeTransitionGeneric},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Router{
+				Router: svrlib.NewRouter(nil, "/", &config.Config{AppPasswordFallback: tc.fallback}),
+				authServers: func(string) (*auth.AuthorizationServerMetadata, error) {
+					if tc.discover != nil {
+						return nil, tc.discover
+					}
+					return &auth.AuthorizationServerMetadata{ScopesSupported: tc.supported}, nil
+				},
+			}
+
+			w := httptest.NewRecorder()
+			r.writeScopeRefused(w, "did:plc:alice", opMigrateRecords, "rewrite your dis.quest records", refused)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403", w.Code)
+			}
+			var body httputil.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != httputil.CodeInsufficientScope || body.Message == "" {
+				t.Errorf("unexpected error response: %+v", body)
+			}
+			if body.Remedy == nil || *body.Remedy != tc.want {
+				t.Errorf("remedy = %+v, want %+v", body.Remedy, tc.want)
+			}
+		})
+	}
+}
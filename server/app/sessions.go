@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/session"
+)
+
+// sessionView is a session as shown to its owner. Current marks the session
+// the request was made from.
+type sessionView struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// revokedSessions reports how many sessions a DELETE ended
+type revokedSessions struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// NewSessionManager creates a session manager backed by the database
+func NewSessionManager(dbService *db.Service, clk clock.Clock) *session.Manager {
+	return session.NewManagerWithClock(sessionStore{dbService: dbService}, clk)
+}
+
+// me is the signed-in user as pages greet them
+type me struct {
+	DID string `json:"did"`
+	session.Profile
+}
+
+// MeHandler returns the current user's DID and the profile recorded when
+// their session started
+func (r *Router) MeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	httputil.WriteSuccess(w, me{
+		DID: userCtx.DID,
+		Profile: session.Profile{
+			Handle:      userCtx.Handle,
+			DisplayName: userCtx.DisplayName,
+			Avatar:      userCtx.Avatar,
+		},
+	})
+}
+
+// sessionExpiryWarning is how close to expiry pages warn that the session
+// is ending, which only happens once renewals have failed
+const sessionExpiryWarning = 5 * time.Minute
+
+// sessionExpiry is how long the current session's access token has left.
+// RefreshDue says the page should renew it now.
+type sessionExpiry struct {
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn  int64      `json:"expires_in,omitempty"`
+	RefreshDue bool       `json:"refresh_due"`
+}
+
+// SessionExpiryHandler reports when the current session's access token
+// expires, in seconds, and whether it is due for renewal under the
+// configured refresh policy. Pages poll it with htmx, which gets a fragment
+// that renews the session silently once due and warns when it is about to
+// end.
+func (r *Router) SessionExpiryHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := middleware.GetUserContext(req); !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+	var resp sessionExpiry
+	var expiresIn time.Duration
+	// Requests made with a personal access token have no session to renew
+	if info, err := auth.TokenInfo(req); err == nil && !info.ExpiresAt.IsZero() {
+		now := r.clock.Now()
+		expiresIn = info.ExpiresAt.Sub(now)
+		resp.ExpiresAt = &info.ExpiresAt
+		resp.ExpiresIn = int64(max(expiresIn, 0) / time.Second)
+		if r.Config != nil {
+			policy := r.Config.SessionRefreshPolicy()
+			policy.Now = r.clock.Now
+			resp.RefreshDue = policy.ShouldRefresh(info.Token)
+		}
+	}
+	if isFragmentRequest(req) {
+		warn := resp.ExpiresAt != nil && expiresIn <= sessionExpiryWarning
+		renderFragment(w, req, components.SessionStatus(expiresIn, resp.RefreshDue, warn))
+		return
+	}
+	httputil.WriteSuccess(w, resp)
+}
+
+// MySessionsHandler lists the current user's sessions (GET) or signs them
+// out everywhere (DELETE), including the session making the request
+func (r *Router) MySessionsHandler(w http.ResponseWriter, req *http.Request) {
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		sessions, err := r.sessions.List(req.Context(), userCtx.DID)
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to fetch sessions", "did", userCtx.DID)
+			return
+		}
+		currentID, _ := session.IDFromRequest(req)
+		views := make([]sessionView, 0, len(sessions))
+		for _, s := range sessions {
+			views = append(views, sessionView{
+				ID:         s.ID,
+				UserAgent:  s.UserAgent,
+				IP:         s.IP,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				Current:    s.ID == currentID,
+			})
+		}
+		httputil.WriteSuccess(w, views)
+	case http.MethodDelete:
+		revoked, err := r.sessions.RevokeAll(req.Context(), userCtx.DID)
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to revoke sessions", "did", userCtx.DID)
+			return
+		}
+		r.clearSessionCookies(w)
+		httputil.WriteSuccess(w, revokedSessions{Revoked: revoked})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MySessionHandler signs the current user out of one session
+func (r *Router) MySessionHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	id := req.PathValue("id")
+	if err := r.sessions.Revoke(req.Context(), userCtx.DID, id); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to revoke session", "did", userCtx.DID)
+		return
+	}
+	if currentID, ok := session.IDFromRequest(req); ok && currentID == id {
+		r.clearSessionCookies(w)
+	}
+	httputil.WriteSuccess(w, revokedSessions{Revoked: 1})
+}
+
+// SessionsPageHandler shows the current user where they are signed in
+func (r *Router) SessionsPageHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		http.Redirect(w, req, loginredirect.LoginURL(req), http.StatusSeeOther)
+		return
+	}
+
+	sessions, err := r.sessions.List(ctx, userCtx.DID)
+	if err != nil {
+		logger.Error("Failed to fetch sessions", "did", userCtx.DID, "error", err)
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+	currentID, _ := session.IDFromRequest(req)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := components.Sessions(sessions, currentID).Render(ctx, w); err != nil {
+		logger.Error("Failed to render sessions page", "error", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+	}
+}
+
+// clearSessionCookies signs the requesting browser out after its own
+// session was revoked
+func (r *Router) clearSessionCookies(w http.ResponseWriter) {
+	isDev := r.Config != nil && r.Config.AppEnv == config.EnvDev
+	auth.ClearSessionCookieWithEnv(w, isDev)
+	session.ClearCookie(w, isDev)
+}
+
+// sessionStore keeps sessions in the session table
+type sessionStore struct {
+	dbService *db.Service
+}
+
+func (s sessionStore) Create(ctx context.Context, sess session.Session) error {
+	_, err := s.dbService.Queries().CreateSession(ctx, db.CreateSessionParams{
+		ID:          sess.ID,
+		Did:         sess.DID,
+		UserAgent:   sess.UserAgent,
+		Ip:          sess.IP,
+		CreatedAt:   sess.CreatedAt,
+		LastSeenAt:  sess.LastSeenAt,
+		DpopKey:     sess.DPoPKey,
+		Handle:      sess.Profile.Handle,
+		DisplayName: sess.Profile.DisplayName,
+		Avatar:      sess.Profile.Avatar,
+	})
+	return err
+}
+
+func (s sessionStore) Get(ctx context.Context, id string) (session.Session, error) {
+	row, err := s.dbService.Queries().GetSession(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.Session{}, session.ErrNotFound
+	}
+	if err != nil {
+		return session.Session{}, err
+	}
+	return fromSessionRow(row), nil
+}
+
+func (s sessionStore) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	return s.dbService.Queries().TouchSession(ctx, db.TouchSessionParams{ID: id, LastSeenAt: lastSeenAt})
+}
+
+func (s sessionStore) List(ctx context.Context, did string) ([]session.Session, error) {
+	rows, err := s.dbService.Queries().ListSessionsByDid(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]session.Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, fromSessionRow(row))
+	}
+	return sessions, nil
+}
+
+func (s sessionStore) Delete(ctx context.Context, did, id string) (bool, error) {
+	n, err := s.dbService.Queries().DeleteSession(ctx, db.DeleteSessionParams{ID: id, Did: did})
+	return n > 0, err
+}
+
+func (s sessionStore) DeleteAll(ctx context.Context, did string) (int64, error) {
+	return s.dbService.Queries().DeleteSessionsByDid(ctx, did)
+}
+
+func (s sessionStore) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	return s.dbService.Queries().PurgeIdleSessions(ctx, before)
+}
+
+func fromSessionRow(row db.Session) session.Session {
+	return session.Session{
+		ID:         row.ID,
+		DID:        row.Did,
+		UserAgent:  row.UserAgent,
+		IP:         row.Ip,
+		CreatedAt:  row.CreatedAt,
+		LastSeenAt: row.LastSeenAt,
+		Profile: session.Profile{
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Avatar:      row.Avatar,
+		},
+		DPoPKey: row.DpopKey,
+	}
+}
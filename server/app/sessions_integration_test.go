@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/session"
+	"github.com/jrschumacher/dis.quest/internal/session/sessiontest"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestMySessions_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	userDID := "did:plc:user"
+	sessions := NewSessionManager(dbService, clock.System{})
+
+	start := func(did, userAgent string) session.Session {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", userAgent)
+		s, err := sessions.Start(ctx, did, req)
+		if err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		return s
+	}
+	laptop := start(userDID, "laptop")
+	phone := start(userDID, "phone")
+	other := start("did:plc:other", "other")
+
+	mux := CreateTestServer(t, dbService, userDID)
+	serve := func(method, path string, current *session.Session) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if current != nil {
+			req.AddCookie(&http.Cookie{Name: session.CookieName, Value: current.ID})
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List marks the current session", func(t *testing.T) {
+		w := serve("GET", "/api/v1/me/sessions", &laptop)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var got []sessionView
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(got))
+		}
+		for _, s := range got {
+			if s.Current != (s.ID == laptop.ID) {
+				t.Errorf("Session %s (%s): current = %v", s.ID, s.UserAgent, s.Current)
+			}
+		}
+	})
+
+	t.Run("Cannot revoke another user's session", func(t *testing.T) {
+		w := serve("DELETE", "/api/v1/me/sessions/"+other.ID, &laptop)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", w.Code)
+		}
+		if _, err := sessions.Validate(ctx, other.ID, "did:plc:other"); err != nil {
+			t.Fatalf("Other user's session was revoked: %v", err)
+		}
+	})
+
+	t.Run("Revoke one session", func(t *testing.T) {
+		w := serve("DELETE", "/api/v1/me/sessions/"+phone.ID, &laptop)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if _, err := sessions.Validate(ctx, phone.ID, userDID); err == nil {
+			t.Fatal("Expected revoked session to be invalid")
+		}
+		if len(w.Result().Cookies()) != 0 {
+			t.Error("Revoking another device should not clear this browser's cookies")
+		}
+	})
+
+	t.Run("Sign out everywhere", func(t *testing.T) {
+		start(userDID, "tablet")
+		w := serve("DELETE", "/api/v1/me/sessions", &laptop)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var got revokedSessions
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got.Revoked != 2 {
+			t.Errorf("Expected 2 sessions revoked, got %d", got.Revoked)
+		}
+		cleared := map[string]bool{}
+		for _, c := range w.Result().Cookies() {
+			if c.MaxAge < 0 {
+				cleared[c.Name] = true
+			}
+		}
+		if !cleared[session.CookieName] || !cleared["dsq_session"] {
+			t.Errorf("Expected session cookies to be cleared, got %v", cleared)
+		}
+		remaining, err := sessions.List(ctx, userDID)
+		if err != nil || len(remaining) != 0 {
+			t.Fatalf("Expected no sessions left, got %d (%v)", len(remaining), err)
+		}
+		if _, err := sessions.Validate(ctx, other.ID, "did:plc:other"); err != nil {
+			t.Fatalf("Other user's session was revoked: %v", err)
+		}
+	})
+}
+
+func TestSessionDPoPKey_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	sessions := NewSessionManager(dbService, clock.System{})
+	if err := sessions.SetKeySecret([]byte("test secret")); err != nil {
+		t.Fatalf("Failed to set key secret: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	s, err := sessions.StartWithDPoPKey(context.Background(), "did:plc:user", httptest.NewRequest("GET", "/", nil), key)
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: session.CookieName, Value: s.ID})
+	got, err := sessions.DPoPKey(req, "did:plc:user")
+	if err != nil {
+		t.Fatalf("Failed to load DPoP key: %v", err)
+	}
+	if !got.Equal(key) {
+		t.Error("Expected the stored DPoP key back")
+	}
+
+	row, err := dbService.Queries().GetSession(context.Background(), s.ID)
+	if err != nil {
+		t.Fatalf("Failed to read session row: %v", err)
+	}
+	if row.DpopKey == "" || strings.Contains(row.DpopKey, "PRIVATE KEY") {
+		t.Errorf("Expected an encrypted key in the session row, got %q", row.DpopKey)
+	}
+}
+
+func TestSessionExpiry_Integration(t *testing.T) {
+	mux := CreateTestServer(t, testutil.TestDatabase(t), "did:plc:user")
+	serve := func(expiresIn time.Duration, fragment bool) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/me/expiry", nil)
+		if expiresIn != 0 {
+			enc := base64.RawURLEncoding.EncodeToString
+			token := enc([]byte(`{"alg":"ES256K"}`)) + "." + enc([]byte(fmt.Sprintf(`{"sub":"did:plc:user","exp":%d}`, time.Now().Add(expiresIn).Unix()))) + ".sig"
+			cookies := httptest.NewRecorder()
+			auth.SetSessionCookieWithEnv(cookies, token, nil, true)
+			for _, c := range cookies.Result().Cookies() {
+				req.AddCookie(c)
+			}
+		}
+		if fragment {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	t.Run("Reports the countdown", func(t *testing.T) {
+		var got sessionExpiry
+		if err := json.Unmarshal(serve(time.Hour, false).Body.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got.ExpiresAt == nil || got.ExpiresIn < 3590 || got.ExpiresIn > 3600 || got.RefreshDue {
+			t.Errorf("Expected an hour left and no renewal due, got %+v", got)
+		}
+	})
+
+	t.Run("Nothing to show while the session has time left", func(t *testing.T) {
+		if body := serve(time.Hour, true).Body.String(); body != "" {
+			t.Errorf("Expected an empty fragment, got %s", body)
+		}
+	})
+
+	t.Run("Warns before the session ends", func(t *testing.T) {
+		body := serve(3*time.Minute, true).Body.String()
+		if !strings.Contains(body, `role="alert"`) || !strings.Contains(body, "expires in 3 minutes") {
+			t.Errorf("Expected a warning, got %s", body)
+		}
+	})
+
+	t.Run("An expired session is renewed silently", func(t *testing.T) {
+		body := serve(-time.Minute, true).Body.String()
+		if !strings.Contains(body, `hx-post="/auth/refresh" hx-trigger="load"`) || !strings.Contains(body, "has expired") {
+			t.Errorf("Expected a renewal and a warning, got %s", body)
+		}
+	})
+
+	t.Run("Sessions without an expiring token report none", func(t *testing.T) {
+		if body := serve(0, false).Body.String(); strings.Contains(body, "expires_at") {
+			t.Errorf("Expected no expiry, got %s", body)
+		}
+	})
+}
+
+func TestSessionStore_Integration(t *testing.T) {
+	sessiontest.RunStorageTests(t, func(t *testing.T) session.Store {
+		return sessionStore{dbService: testutil.TestDatabase(t)}
+	})
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+// ThreadPageHandler shows a topic with its messages. The reply form posts
+// back here and a successful reply reloads the page, so threads work
+// without JavaScript; ?reply_to=rkey sets the form up to reply to a message.
+func (r *Router) ThreadPageHandler(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	topicID := req.PathValue("id")
+
+	topicDid, topicRkey, err := parseTopicID(topicID)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	topic, err := r.dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch topic", "error", err, "topicID", topicID)
+		http.Error(w, "Failed to load topic", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	draft := components.MessageDraft{ParentMessageRkey: req.URL.Query().Get("reply_to")}
+	switch req.Method {
+	case http.MethodGet:
+		r.analytics.View(topicDid, topicRkey)
+	case http.MethodPost:
+		userCtx, ok := middleware.GetUserContext(req)
+		if !ok {
+			http.Redirect(w, req, loginredirect.LoginURL(req), http.StatusSeeOther)
+			return
+		}
+		draft = components.MessageDraft{Content: req.FormValue("content"), ParentMessageRkey: req.FormValue("parent_message_rkey")}
+		message, err := r.createMessage(ctx, userCtx.DID, formatTopicID(topicDid, topicRkey), newMessage{
+			Content:           draft.Content,
+			ParentMessageRkey: draft.ParentMessageRkey,
+		})
+		var invalid validation.Errors
+		switch {
+		case errors.As(err, &invalid):
+			status = http.StatusBadRequest
+			draft.Error = "The reply could not be posted: " + invalid.Error()
+		case err != nil:
+			logger.Error("Failed to create message", "did", userCtx.DID, "topicID", topicID, "error", err)
+			http.Error(w, "Failed to create message", http.StatusInternalServerError)
+			return
+		default:
+			http.Redirect(w, req, threadPath(topicDid, topicRkey)+"#message-"+message.Rkey, http.StatusSeeOther)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messages, err := r.dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+	})
+	if err != nil {
+		logger.Error("Failed to fetch messages", "error", err, "topicID", topicID)
+		http.Error(w, "Failed to load messages", http.StatusInternalServerError)
+		return
+	}
+	renderPage(w, req, status, components.Thread(topic, messages, draft))
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestThreadPages_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	userDID := "did:plc:user"
+	mux := CreateTestServer(t, dbService, userDID)
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	t.Run("An invalid topic is shown again with the reason", func(t *testing.T) {
+		w := post("/discussion", url.Values{"subject": {"Hi"}, "initial_message": {"Too short"}})
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", w.Code)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, `role="alert"`) || !strings.Contains(body, `value="Hi"`) {
+			t.Errorf("Expected the error and the draft on the page, got %s", body)
+		}
+	})
+
+	var threadURL string
+	t.Run("Posting a topic redirects to its thread", func(t *testing.T) {
+		w := post("/discussion", url.Values{"subject": {"No JavaScript"}, "initial_message": {"Does this work with scripts off?"}})
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("Expected status 303, got %d: %s", w.Code, w.Body.String())
+		}
+		threadURL = w.Header().Get("Location")
+		if !strings.HasPrefix(threadURL, "/discussion/") {
+			t.Fatalf("Expected a thread page, redirected to %q", threadURL)
+		}
+
+		w = get("/discussion")
+		if !strings.Contains(w.Body.String(), `href="`+threadURL+`"`) {
+			t.Error("Expected the topic list to link to the thread")
+		}
+	})
+
+	t.Run("Replies post back to the thread", func(t *testing.T) {
+		w := get(threadURL)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "No replies yet.") {
+			t.Fatalf("Expected an empty thread, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w = post(threadURL, url.Values{"content": {"It does."}})
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("Expected status 303, got %d: %s", w.Code, w.Body.String())
+		}
+		location := w.Header().Get("Location")
+		if !strings.HasPrefix(location, threadURL+"#message-") {
+			t.Fatalf("Expected a redirect to the new message, got %q", location)
+		}
+
+		topics, err := dbService.Queries().ListTopics(ctx, db.ListTopicsParams{Limit: 1})
+		if err != nil || len(topics) != 1 {
+			t.Fatalf("ListTopics: %v", err)
+		}
+		topic := topics[0]
+		messages, err := dbService.Queries().GetMessagesByTopic(ctx, db.GetMessagesByTopicParams{TopicDid: topic.Did, TopicRkey: topic.Rkey})
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("Expected one message, got %d (%v)", len(messages), err)
+		}
+		if location != threadURL+"#message-"+messages[0].Rkey {
+			t.Errorf("Redirected to %q, want the new message", location)
+		}
+
+		replyTo := threadURL + "?reply_to=" + messages[0].Rkey
+		body := get(replyTo).Body.String()
+		if !strings.Contains(body, `name="parent_message_rkey" value="`+messages[0].Rkey+`"`) {
+			t.Fatal("Expected the reply form set up to reply to the message")
+		}
+		w = post(replyTo, url.Values{"content": {"Confirmed."}, "parent_message_rkey": {messages[0].Rkey}})
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("Expected status 303, got %d: %s", w.Code, w.Body.String())
+		}
+		replies, err := dbService.Queries().GetRepliesByMessage(ctx, db.GetRepliesByMessageParams{
+			TopicDid:          topic.Did,
+			TopicRkey:         topic.Rkey,
+			ParentMessageRkey: sql.NullString{String: messages[0].Rkey, Valid: true},
+		})
+		if err != nil || len(replies) != 1 {
+			t.Errorf("Expected one reply to the message, got %d (%v)", len(replies), err)
+		}
+	})
+
+	t.Run("An empty reply is refused", func(t *testing.T) {
+		w := post(threadURL, url.Values{"content": {""}})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `role="alert"`) {
+			t.Errorf("Expected the thread with an error, got %d", w.Code)
+		}
+	})
+
+	t.Run("Unknown threads are not found", func(t *testing.T) {
+		for _, path := range []string{"/discussion/nonsense", threadPath(userDID, "missing")} {
+			if w := get(path); w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status 404, got %d", path, w.Code)
+			}
+		}
+	})
+}
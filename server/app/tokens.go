@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+// accessTokenView is an access token as shown to its owner
+type accessTokenView struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newAccessTokenView(t accesstoken.Token) accessTokenView {
+	view := accessTokenView{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+	}
+	if !t.LastUsedAt.IsZero() {
+		view.LastUsedAt = &t.LastUsedAt
+	}
+	return view
+}
+
+// revokedTokens reports how many access tokens a DELETE revoked
+type revokedTokens struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// issueTokenRequest asks for a new access token. ExpiresInDays of zero
+// uses the default lifetime.
+type issueTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// issuedAccessToken is a new access token with its secret, which is only
+// ever returned here
+type issuedAccessToken struct {
+	accessTokenView
+	Token string `json:"token"`
+}
+
+// NewTokenManager creates an access token manager backed by the database
+func NewTokenManager(dbService *db.Service, clk clock.Clock) *accesstoken.Manager {
+	return accesstoken.NewManager(tokenStore{dbService: dbService}, clk)
+}
+
+// tokenOwner returns the user managing access tokens. Tokens cannot manage
+// tokens, so a leaked one cannot be used to mint more or outlive revocation.
+func tokenOwner(w http.ResponseWriter, req *http.Request) (*middleware.UserContext, bool) {
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return nil, false
+	}
+	if userCtx.TokenID != "" {
+		httputil.WriteError(w, http.StatusForbidden, "Access tokens cannot manage access tokens; sign in to manage them", "did", userCtx.DID, "token", userCtx.TokenID)
+		return nil, false
+	}
+	return userCtx, true
+}
+
+// MyTokensHandler lists the current user's access tokens (GET) or issues a
+// new one (POST)
+func (r *Router) MyTokensHandler(w http.ResponseWriter, req *http.Request) {
+	userCtx, ok := tokenOwner(w, req)
+	if !ok {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		tokens, err := r.tokens.List(req.Context(), userCtx.DID)
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to fetch access tokens", "did", userCtx.DID)
+			return
+		}
+		views := make([]accessTokenView, 0, len(tokens))
+		for _, t := range tokens {
+			views = append(views, newAccessTokenView(t))
+		}
+		httputil.WriteSuccess(w, views)
+	case http.MethodPost:
+		var body issueTokenRequest
+		if err := httputil.DecodeJSON(w, req, &body); err != nil {
+			httputil.WriteDecodeError(w, err)
+			return
+		}
+		secret, token, err := r.issueToken(req.Context(), userCtx.DID, body)
+		if errors.Is(err, accesstoken.ErrInvalid) {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, err, "Failed to issue access token", "did", userCtx.DID)
+			return
+		}
+		httputil.WriteCreated(w, issuedAccessToken{accessTokenView: newAccessTokenView(token), Token: secret})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MyTokenHandler revokes one of the current user's access tokens
+func (r *Router) MyTokenHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userCtx, ok := tokenOwner(w, req)
+	if !ok {
+		return
+	}
+
+	if err := r.tokens.Revoke(req.Context(), userCtx.DID, req.PathValue("id")); err != nil {
+		if errors.Is(err, accesstoken.ErrNotFound) {
+			httputil.WriteError(w, http.StatusNotFound, "Access token not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to revoke access token", "did", userCtx.DID)
+		return
+	}
+	httputil.WriteSuccess(w, revokedTokens{Revoked: 1})
+}
+
+// TokensPageHandler shows the current user's access tokens (GET) and issues
+// one when the form is submitted (POST), showing its secret once
+func (r *Router) TokensPageHandler(w http.ResponseWriter, req *http.Request) {
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		http.Redirect(w, req, loginredirect.LoginURL(req), http.StatusSeeOther)
+		return
+	}
+
+	status := http.StatusOK
+	var issued, errorMessage string
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		body := issueTokenRequest{Name: req.FormValue("name"), Scopes: req.Form["scope"]}
+		var err error
+		if days := req.FormValue("expires_in_days"); days != "" {
+			if body.ExpiresInDays, err = strconv.Atoi(days); err != nil {
+				err = fmt.Errorf("%w: expires in days must be a whole number", accesstoken.ErrInvalid)
+			}
+		}
+		var secret string
+		if err == nil {
+			secret, _, err = r.issueToken(req.Context(), userCtx.DID, body)
+		}
+		switch {
+		case errors.Is(err, accesstoken.ErrInvalid):
+			status = http.StatusBadRequest
+			errorMessage = "The token could not be created: " + strings.TrimPrefix(err.Error(), accesstoken.ErrInvalid.Error()+": ")
+		case err != nil:
+			logger.Error("Failed to issue access token", "did", userCtx.DID, "error", err)
+			http.Error(w, "Failed to create access token", http.StatusInternalServerError)
+			return
+		default:
+			issued = secret
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokens, err := r.tokens.List(req.Context(), userCtx.DID)
+	if err != nil {
+		logger.Error("Failed to fetch access tokens", "did", userCtx.DID, "error", err)
+		http.Error(w, "Failed to load access tokens", http.StatusInternalServerError)
+		return
+	}
+	// The page may show a secret, which must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	renderPage(w, req, status, components.AccessTokens(tokens, issued, errorMessage, r.clock.Now()))
+}
+
+func (r *Router) issueToken(ctx context.Context, did string, body issueTokenRequest) (string, accesstoken.Token, error) {
+	if body.ExpiresInDays < 0 {
+		return "", accesstoken.Token{}, fmt.Errorf("%w: expires_in_days must not be negative", accesstoken.ErrInvalid)
+	}
+	return r.tokens.Issue(ctx, did, body.Name, body.Scopes, time.Duration(body.ExpiresInDays)*24*time.Hour)
+}
+
+// tokenStore keeps access tokens in the access token table
+type tokenStore struct {
+	dbService *db.Service
+}
+
+func (s tokenStore) Create(ctx context.Context, t accesstoken.Token) error {
+	_, err := s.dbService.Queries().CreateAccessToken(ctx, db.CreateAccessTokenParams{
+		ID:        t.ID,
+		Did:       t.DID,
+		Name:      t.Name,
+		TokenHash: t.Hash,
+		Scopes:    accesstoken.FormatScopes(t.Scopes),
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+	})
+	return err
+}
+
+func (s tokenStore) GetByHash(ctx context.Context, hash string) (accesstoken.Token, error) {
+	row, err := s.dbService.Queries().GetAccessTokenByHash(ctx, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accesstoken.Token{}, accesstoken.ErrNotFound
+	}
+	if err != nil {
+		return accesstoken.Token{}, err
+	}
+	return fromAccessTokenRow(row), nil
+}
+
+func (s tokenStore) Touch(ctx context.Context, id string, lastUsedAt time.Time) error {
+	return s.dbService.Queries().TouchAccessToken(ctx, db.TouchAccessTokenParams{
+		ID:         id,
+		LastUsedAt: sql.NullTime{Time: lastUsedAt, Valid: true},
+	})
+}
+
+func (s tokenStore) List(ctx context.Context, did string) ([]accesstoken.Token, error) {
+	rows, err := s.dbService.Queries().ListAccessTokensByDid(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]accesstoken.Token, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, fromAccessTokenRow(row))
+	}
+	return tokens, nil
+}
+
+func (s tokenStore) Delete(ctx context.Context, did, id string) (bool, error) {
+	n, err := s.dbService.Queries().DeleteAccessToken(ctx, db.DeleteAccessTokenParams{ID: id, Did: did})
+	return n > 0, err
+}
+
+func fromAccessTokenRow(row db.AccessToken) accesstoken.Token {
+	return accesstoken.Token{
+		ID:         row.ID,
+		DID:        row.Did,
+		Name:       row.Name,
+		Scopes:     accesstoken.ParseScopes(row.Scopes),
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  row.ExpiresAt,
+		LastUsedAt: row.LastUsedAt.Time,
+		Hash:       row.TokenHash,
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/accesstoken"
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestAccessTokens_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	userDID := "did:plc:user"
+	mux := CreateTestServer(t, dbService, userDID)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	var issued issuedAccessToken
+	t.Run("Issue returns the secret once", func(t *testing.T) {
+		w := serve("POST", "/api/v1/me/tokens", `{"name":"backup","scopes":["read"],"expires_in_days":7}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !strings.HasPrefix(issued.Token, accesstoken.Prefix) || issued.Name != "backup" {
+			t.Fatalf("Unexpected token: %+v", issued)
+		}
+		if got := issued.ExpiresAt.Sub(issued.CreatedAt); got.Hours() != 7*24 {
+			t.Errorf("Expected a 7 day lifetime, got %v", got)
+		}
+
+		w = serve("GET", "/api/v1/me/tokens", "")
+		if strings.Contains(w.Body.String(), issued.Token) {
+			t.Error("Listing must not reveal token secrets")
+		}
+		var listed []accessTokenView
+		if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("Failed to decode list: %v", err)
+		}
+		if len(listed) != 1 || listed[0].ID != issued.ID || listed[0].LastUsedAt != nil {
+			t.Errorf("Unexpected list: %+v", listed)
+		}
+	})
+
+	t.Run("Invalid requests are rejected", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name":""}`,
+			`{"name":"x","scopes":["admin"]}`,
+			`{"name":"x","expires_in_days":400}`,
+			`{"name":"x","expires_in_days":-1}`,
+		} {
+			if w := serve("POST", "/api/v1/me/tokens", body); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", body, w.Code)
+			}
+		}
+	})
+
+	tokens := NewTokenManager(dbService, clock.System{})
+	t.Run("The token authenticates its owner", func(t *testing.T) {
+		token, err := tokens.Authenticate(ctx, issued.Token)
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+		if token.DID != userDID || !token.Allows(accesstoken.ScopeRead) || token.Allows(accesstoken.ScopeWrite) {
+			t.Errorf("Unexpected token: %+v", token)
+		}
+		listed, err := tokens.List(ctx, userDID)
+		if err != nil || len(listed) != 1 || listed[0].LastUsedAt.IsZero() {
+			t.Errorf("Expected the last use to be recorded, got %+v (%v)", listed, err)
+		}
+	})
+
+	t.Run("Tokens cannot manage tokens", func(t *testing.T) {
+		middleware.SetTokenAuthenticator(tokens.Lookup)
+		t.Cleanup(func() { middleware.SetTokenAuthenticator(nil) })
+		router := RegisterTestRoutes(http.NewServeMux(), "/", nil, dbService, userDID)
+		handler := middleware.UserContextMiddleware(http.HandlerFunc(router.MyTokensHandler))
+
+		req := httptest.NewRequest("GET", "/api/v1/me/tokens", nil)
+		req.Header.Set("Authorization", "Bearer "+issued.Token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Other users cannot revoke", func(t *testing.T) {
+		other := CreateTestServer(t, dbService, "did:plc:other")
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/v1/me/tokens/"+issued.ID, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("Revoked tokens stop working", func(t *testing.T) {
+		if w := serve("DELETE", "/api/v1/me/tokens/"+issued.ID, ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if _, err := tokens.Authenticate(ctx, issued.Token); err == nil {
+			t.Error("Expected the revoked token to be refused")
+		}
+		if w := serve("DELETE", "/api/v1/me/tokens/"+issued.ID, ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 revoking twice, got %d", w.Code)
+		}
+	})
+
+	t.Run("The page shows a new token once", func(t *testing.T) {
+		form := url.Values{"name": {"cron"}, "scope": {"read", "write"}, "expires_in_days": {"30"}}
+		req := httptest.NewRequest("POST", "/account/tokens", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), accesstoken.Prefix) || w.Header().Get("Cache-Control") != "no-store" {
+			t.Error("Expected the new token on an uncached page")
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/account/tokens", nil))
+		if strings.Contains(w.Body.String(), accesstoken.Prefix) {
+			t.Error("The token must not be shown again")
+		}
+		if !strings.Contains(w.Body.String(), "cron") {
+			t.Error("Expected the token to be listed")
+		}
+	})
+
+	t.Run("Account purge removes tokens", func(t *testing.T) {
+		purge, err := dbService.PurgeAccount(ctx, userDID)
+		if err != nil {
+			t.Fatalf("PurgeAccount: %v", err)
+		}
+		if purge.AccessTokens != 1 {
+			t.Errorf("Expected 1 access token purged, got %d", purge.AccessTokens)
+		}
+	})
+}
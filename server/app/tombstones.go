@@ -0,0 +1,333 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/firehose"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/labeler"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+// TombstoneGracePeriod is how long deleted or hidden content can be restored
+// before it becomes eligible for purging
+const TombstoneGracePeriod = 15 * time.Minute
+
+// tombstone describes a soft-deleted record and how long it can be restored
+type tombstone struct {
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+	UndoUntil time.Time `json:"undo_until"`
+}
+
+func newTombstone(id, deletedBy string, deletedAt time.Time) tombstone {
+	return tombstone{
+		ID:        id,
+		DeletedAt: deletedAt,
+		DeletedBy: deletedBy,
+		UndoUntil: deletedAt.Add(TombstoneGracePeriod),
+	}
+}
+
+// isAdmin reports whether did may hide content it does not own
+func (r *Router) isAdmin(did string) bool {
+	return r.Router != nil && r.Config.IsAdmin(did)
+}
+
+// moderatesTopic reports whether did may hide content in a topic it does
+// not own: admins anywhere, community moderators within their community
+func (r *Router) moderatesTopic(ctx context.Context, did, topicDid, topicRkey string) bool {
+	if r.isAdmin(did) {
+		return true
+	}
+	n, err := r.dbService.Queries().CountTopicModerator(ctx, db.CountTopicModeratorParams{
+		TopicDid:     topicDid,
+		TopicRkey:    topicRkey,
+		ModeratorDid: did,
+	})
+	if err != nil {
+		logger.Error("Failed to check moderator", "error", err, "did", did)
+		return false
+	}
+	return n > 0
+}
+
+// canRestore reports whether did may undo a deletion. Authors can only undo
+// their own deletions so a moderator's hide cannot be reverted by the author.
+func (r *Router) canRestore(ctx context.Context, did string, deletedBy sql.NullString, topicDid, topicRkey string) bool {
+	return (deletedBy.Valid && deletedBy.String == did) || r.moderatesTopic(ctx, did, topicDid, topicRkey)
+}
+
+// TopicAPIHandler handles REST API operations on a single topic
+func (r *Router) TopicAPIHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		r.getTopicAPI(w, req)
+	case http.MethodDelete:
+		r.deleteTopicAPI(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getTopicAPI returns a topic with its message and participant counters
+func (r *Router) getTopicAPI(w http.ResponseWriter, req *http.Request) {
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	topic, err := r.dbService.Queries().GetTopic(req.Context(), db.GetTopicParams{Did: topicDid, Rkey: topicRkey})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicID", formatTopicID(topicDid, topicRkey))
+		return
+	}
+
+	httputil.WriteSuccess(w, versioned(w, req, topic, func() any { return newTopicView(topic) }))
+}
+
+func (r *Router) deleteTopicAPI(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if userCtx.DID != topicDid && !r.moderatesTopic(ctx, userCtx.DID, topicDid, topicRkey) {
+		httputil.WriteError(w, http.StatusForbidden, "Only the author or a moderator can delete this topic")
+		return
+	}
+
+	now := r.clock.Now()
+	affected, err := r.dbService.Queries().SoftDeleteTopic(ctx, db.SoftDeleteTopicParams{
+		DeletedAt: sql.NullTime{Time: now, Valid: true},
+		DeletedBy: sql.NullString{String: userCtx.DID, Valid: true},
+		Did:       topicDid,
+		Rkey:      topicRkey,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to delete topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+	if affected == 0 {
+		httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+		return
+	}
+
+	r.publishRecord(ctx, topicDid, topicCollection, topicRkey, firehose.OperationDelete, nil)
+	if userCtx.DID != topicDid {
+		r.emitLabel(ctx, topicURI(topicDid, topicRkey), labeler.ValueHide, false)
+	}
+
+	httputil.WriteSuccess(w, newTombstone(formatTopicID(topicDid, topicRkey), userCtx.DID, now))
+}
+
+// RestoreTopicHandler undoes a topic deletion within the grace period
+func (r *Router) RestoreTopicHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	queries := r.dbService.Queries()
+	deleted, err := queries.GetDeletedTopic(ctx, db.GetDeletedTopicParams{Did: topicDid, Rkey: topicRkey})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Deleted topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch deleted topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+	if !r.canRestore(ctx, userCtx.DID, deleted.DeletedBy, topicDid, topicRkey) {
+		httputil.WriteError(w, http.StatusForbidden, "Only whoever deleted this topic or a moderator can restore it")
+		return
+	}
+	if r.clock.Now().Sub(deleted.DeletedAt.Time) > TombstoneGracePeriod {
+		httputil.WriteError(w, http.StatusGone, "Undo period has expired")
+		return
+	}
+
+	if _, err := queries.RestoreTopic(ctx, db.RestoreTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		httputil.WriteInternalError(w, err, "Failed to restore topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	hiddenBy := deleted.DeletedBy
+	deleted.DeletedAt = sql.NullTime{}
+	deleted.DeletedBy = sql.NullString{}
+	r.publishRecord(ctx, topicDid, topicCollection, topicRkey, firehose.OperationCreate, newTopicRecord(deleted))
+	if hiddenBy.Valid && hiddenBy.String != topicDid {
+		r.emitLabel(ctx, topicURI(topicDid, topicRkey), labeler.ValueHide, true)
+	}
+
+	httputil.WriteSuccess(w, deleted)
+}
+
+// MessageAPIHandler handles REST API operations on a single message
+func (r *Router) MessageAPIHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodDelete:
+		r.deleteMessageAPI(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Router) deleteMessageAPI(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	messageDid, messageRkey, err := parseMessageID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	queries := r.dbService.Queries()
+	message, err := queries.GetMessage(ctx, db.GetMessageParams{Did: messageDid, Rkey: messageRkey})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Message not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch message", "did", messageDid, "rkey", messageRkey)
+		return
+	}
+
+	if userCtx.DID != messageDid && !r.moderatesTopic(ctx, userCtx.DID, message.TopicDid, message.TopicRkey) {
+		httputil.WriteError(w, http.StatusForbidden, "Only the author or a moderator can delete this message")
+		return
+	}
+
+	now := r.clock.Now()
+	affected, err := r.dbService.SoftDeleteMessage(ctx, db.SoftDeleteMessageParams{
+		DeletedAt: sql.NullTime{Time: now, Valid: true},
+		DeletedBy: sql.NullString{String: userCtx.DID, Valid: true},
+		Did:       messageDid,
+		Rkey:      messageRkey,
+	}, message.TopicDid, message.TopicRkey)
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to delete message", "did", messageDid, "rkey", messageRkey)
+		return
+	}
+	if affected == 0 {
+		httputil.WriteError(w, http.StatusNotFound, "Message not found")
+		return
+	}
+
+	r.hub.Publish(formatTopicID(message.TopicDid, message.TopicRkey), realtime.Event{
+		Type: realtime.EventMessageDeleted,
+		Data: events.DeletedMessage{URI: messageURI(messageDid, messageRkey)},
+	})
+	r.publishRecord(ctx, messageDid, messageCollection, messageRkey, firehose.OperationDelete, nil)
+	if userCtx.DID != messageDid {
+		r.emitLabel(ctx, messageURI(messageDid, messageRkey), labeler.ValueHide, false)
+	}
+
+	httputil.WriteSuccess(w, newTombstone(formatTopicID(messageDid, messageRkey), userCtx.DID, now))
+}
+
+// RestoreMessageHandler undoes a message deletion within the grace period
+func (r *Router) RestoreMessageHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	messageDid, messageRkey, err := parseMessageID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	queries := r.dbService.Queries()
+	deleted, err := queries.GetDeletedMessage(ctx, db.GetDeletedMessageParams{Did: messageDid, Rkey: messageRkey})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Deleted message not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch deleted message", "did", messageDid, "rkey", messageRkey)
+		return
+	}
+	if !r.canRestore(ctx, userCtx.DID, deleted.DeletedBy, deleted.TopicDid, deleted.TopicRkey) {
+		httputil.WriteError(w, http.StatusForbidden, "Only whoever deleted this message or a moderator can restore it")
+		return
+	}
+	if r.clock.Now().Sub(deleted.DeletedAt.Time) > TombstoneGracePeriod {
+		httputil.WriteError(w, http.StatusGone, "Undo period has expired")
+		return
+	}
+
+	restored, err := r.dbService.RestoreMessage(ctx, db.RestoreMessageParams{Did: messageDid, Rkey: messageRkey}, deleted.TopicDid, deleted.TopicRkey)
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to restore message", "did", messageDid, "rkey", messageRkey)
+		return
+	}
+	if restored == 0 {
+		httputil.WriteError(w, http.StatusNotFound, "Deleted message not found")
+		return
+	}
+
+	hiddenBy := deleted.DeletedBy
+	deleted.DeletedAt = sql.NullTime{}
+	deleted.DeletedBy = sql.NullString{}
+	r.hub.Publish(formatTopicID(deleted.TopicDid, deleted.TopicRkey), realtime.Event{
+		Type: realtime.EventMessageRestored,
+		Data: events.ConfirmedMessage{URI: messageURI(messageDid, messageRkey), Message: newEventMessage(deleted)},
+	})
+	r.publishRecord(ctx, messageDid, messageCollection, messageRkey, firehose.OperationCreate, newMessageRecord(deleted))
+	if hiddenBy.Valid && hiddenBy.String != messageDid {
+		r.emitLabel(ctx, messageURI(messageDid, messageRkey), labeler.ValueHide, true)
+	}
+
+	httputil.WriteSuccess(w, deleted)
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/clock"
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestTopicSoftDelete_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "doomed-topic",
+		Subject:        "Doomed Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	topicPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey)
+
+	listTopics := func(mux *http.ServeMux) []db.Topic {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/topics", nil))
+		var topics []db.Topic
+		if err := json.NewDecoder(w.Body).Decode(&topics); err != nil {
+			t.Fatalf("Failed to decode topics: %v", err)
+		}
+		return topics
+	}
+
+	t.Run("Other users cannot delete", func(t *testing.T) {
+		mux := CreateTestServer(t, dbService, "did:plc:stranger")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", topicPath, nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	mux := CreateTestServer(t, dbService, authorDID)
+
+	t.Run("Author deletes and topic is hidden", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", topicPath, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var resp tombstone
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.DeletedBy != authorDID || !resp.UndoUntil.Equal(resp.DeletedAt.Add(TombstoneGracePeriod)) {
+			t.Errorf("Unexpected tombstone: %+v", resp)
+		}
+
+		if topics := listTopics(mux); len(topics) != 0 {
+			t.Errorf("Expected deleted topic to be excluded, got %d topics", len(topics))
+		}
+		if _, err := dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topic.Did, Rkey: topic.Rkey}); err != sql.ErrNoRows {
+			t.Errorf("Expected GetTopic to exclude tombstone, got %v", err)
+		}
+	})
+
+	t.Run("Deleting twice is not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("DELETE", topicPath, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Undo restores the topic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", topicPath+"/restore", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if topics := listTopics(mux); len(topics) != 1 {
+			t.Errorf("Expected restored topic to be listed, got %d topics", len(topics))
+		}
+	})
+
+	t.Run("Undo after grace period is gone", func(t *testing.T) {
+		if _, err := dbService.Queries().SoftDeleteTopic(ctx, db.SoftDeleteTopicParams{
+			DeletedAt: sql.NullTime{Time: time.Now().Add(-2 * TombstoneGracePeriod), Valid: true},
+			DeletedBy: sql.NullString{String: authorDID, Valid: true},
+			Did:       topic.Did,
+			Rkey:      topic.Rkey,
+		}); err != nil {
+			t.Fatalf("Failed to tombstone topic: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", topicPath+"/restore", nil))
+		if w.Code != http.StatusGone {
+			t.Errorf("Expected status %d, got %d", http.StatusGone, w.Code)
+		}
+	})
+
+	t.Run("Purge removes expired tombstones", func(t *testing.T) {
+		purged, err := dbService.PurgeTombstones(ctx, time.Now().Add(-TombstoneGracePeriod))
+		if err != nil {
+			t.Fatalf("Failed to purge tombstones: %v", err)
+		}
+		if purged != 1 {
+			t.Errorf("Expected 1 purged row, got %d", purged)
+		}
+		if _, err := dbService.Queries().GetDeletedTopic(ctx, db.GetDeletedTopicParams{Did: topic.Did, Rkey: topic.Rkey}); err != sql.ErrNoRows {
+			t.Errorf("Expected purged topic to be gone, got %v", err)
+		}
+	})
+}
+
+func TestMessageSoftDelete_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	adminDID := "did:plc:admin"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "moderated-topic",
+		Subject:        "Moderated Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	created, err := dbService.CreateMessageWithActivity(ctx, db.CreateMessageParams{
+		Did:       authorDID,
+		Rkey:      "spam",
+		TopicDid:  topic.Did,
+		TopicRkey: topic.Rkey,
+		Content:   "Buy now",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test message: %v", err)
+	}
+	message := created.Message
+	if created.Topic.MessageCount != 1 {
+		t.Errorf("Expected the new message to be counted, got %d", created.Topic.MessageCount)
+	}
+	messagePath := "/api/messages/" + formatTopicID(message.Did, message.Rkey)
+	messagesPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey) + "/messages"
+
+	cfg := &config.Config{AppEnv: "test", AdminDIDs: adminDID}
+	serve := func(did, method, path string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		RegisterTestRoutes(mux, "/", cfg, dbService, did)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	countMessages := func() int {
+		t.Helper()
+		var messages []db.Message
+		if err := json.NewDecoder(serve(authorDID, "GET", messagesPath).Body).Decode(&messages); err != nil {
+			t.Fatalf("Failed to decode messages: %v", err)
+		}
+		return len(messages)
+	}
+	messageCount := func() int32 {
+		t.Helper()
+		got, err := dbService.Queries().GetTopic(ctx, db.GetTopicParams{Did: topic.Did, Rkey: topic.Rkey})
+		if err != nil {
+			t.Fatalf("Failed to fetch topic: %v", err)
+		}
+		return got.MessageCount
+	}
+
+	if w := serve(adminDID, "DELETE", messagePath); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin hide to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countMessages(); n != 0 {
+		t.Errorf("Expected hidden message to be excluded, got %d messages", n)
+	}
+	if n := messageCount(); n != 0 {
+		t.Errorf("Expected hidden message to be uncounted, got message count %d", n)
+	}
+
+	if w := serve(authorDID, "POST", messagePath+"/restore"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected author to be unable to undo admin hide, got %d", w.Code)
+	}
+
+	if w := serve(adminDID, "POST", messagePath+"/restore"); w.Code != http.StatusOK {
+		t.Fatalf("Expected admin undo to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countMessages(); n != 1 {
+		t.Errorf("Expected restored message to be listed, got %d messages", n)
+	}
+	if n := messageCount(); n != 1 {
+		t.Errorf("Expected restored message to be counted again, got message count %d", n)
+	}
+
+	if w := serve("did:plc:stranger", "DELETE", messagePath); w.Code != http.StatusForbidden {
+		t.Errorf("Expected stranger delete to be forbidden, got %d", w.Code)
+	}
+	if w := serve(authorDID, "POST", "/api/messages/not-a-message/restore"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid message ID to be rejected, got %d", w.Code)
+	}
+}
+
+func TestTombstoneGracePeriod_Clock(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	authorDID := "did:plc:author"
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(start)
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test"}, dbService, authorDID)
+	router.clock = clk
+	router.ids = &clock.Sequence{}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/topics", `{"subject":"Timed","initial_message":"Watch the clock"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var topic db.Topic
+	if err := json.NewDecoder(w.Body).Decode(&topic); err != nil {
+		t.Fatalf("Failed to decode topic: %v", err)
+	}
+	if topic.Rkey != "topic-1" || !topic.CreatedAt.Equal(start) {
+		t.Fatalf("Expected rkey topic-1 created at %v, got %s at %v", start, topic.Rkey, topic.CreatedAt)
+	}
+	topicPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey)
+
+	if w := do("DELETE", topicPath, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	clk.Advance(TombstoneGracePeriod - time.Second)
+	if w := do("POST", topicPath+"/restore", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected undo just inside the grace period, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := do("DELETE", topicPath, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	clk.Advance(TombstoneGracePeriod + time.Second)
+	if w := do("POST", topicPath+"/restore", ""); w.Code != http.StatusGone {
+		t.Errorf("Expected status 410 just past the grace period, got %d", w.Code)
+	}
+}
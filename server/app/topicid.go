@@ -0,0 +1,55 @@
+package app
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/validation"
+)
+
+var (
+	errInvalidTopicID   = errors.New("topic ID must be in the form did:rkey")
+	errInvalidMessageID = errors.New("message ID must be in the form did:rkey")
+)
+
+// parseTopicID splits a "did:rkey" topic identifier. DIDs contain colons
+// themselves, so the record key is everything after the last one.
+func parseTopicID(topicID string) (did, rkey string, err error) {
+	did, rkey, ok := splitRecordID(topicID)
+	if !ok {
+		return "", "", errInvalidTopicID
+	}
+	return did, rkey, nil
+}
+
+// parseMessageID splits a "did:rkey" message identifier
+func parseMessageID(messageID string) (did, rkey string, err error) {
+	did, rkey, ok := splitRecordID(messageID)
+	if !ok {
+		return "", "", errInvalidMessageID
+	}
+	return did, rkey, nil
+}
+
+func splitRecordID(id string) (did, rkey string, ok bool) {
+	idx := strings.LastIndex(id, ":")
+	if idx < 0 {
+		return "", "", false
+	}
+	did, rkey = id[:idx], id[idx+1:]
+	if validation.ValidateDID(did, "did") != nil || validation.ValidateRkey(rkey, "rkey") != nil {
+		return "", "", false
+	}
+	return did, rkey, true
+}
+
+// formatTopicID joins a topic's DID and record key into its public identifier
+func formatTopicID(did, rkey string) string {
+	return did + ":" + rkey
+}
+
+// threadPath returns the path of a topic's thread page
+func threadPath(did, rkey string) string {
+	return "/discussion/" + url.PathEscape(formatTopicID(did, rkey))
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
 	"github.com/jrschumacher/dis.quest/internal/testutil"
 )
 
@@ -56,6 +58,25 @@ func TestTopicsAPI_CreateTopic_Integration(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectError:    true,
 		},
+		{
+			name: "Invalid topic - unknown field",
+			requestBody: map[string]interface{}{
+				"subject":         "Test Topic",
+				"initial_message": "This is a test message",
+				"pinned":          true,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectError:    true,
+		},
+		{
+			name: "Invalid topic - body too large",
+			requestBody: map[string]interface{}{
+				"subject":         "Test Topic",
+				"initial_message": strings.Repeat("a", httputil.DefaultMaxBodyBytes),
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectError:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -166,14 +187,227 @@ func TestMessagesAPI_Integration(t *testing.T) {
 	mux := CreateTestServer(t, dbService, testDID)
 
 	t.Run("List messages for topic", func(t *testing.T) {
-		path := fmt.Sprintf("/api/topics/%s/messages", topic.Rkey) // Simplified for testing
+		path := fmt.Sprintf("/api/topics/%s:%s/messages", topic.Did, topic.Rkey)
 		req := httptest.NewRequest("GET", path, nil)
 		w := httptest.NewRecorder()
 		mux.ServeHTTP(w, req)
 
-		// This might fail due to URL parsing, but demonstrates the test structure
-		t.Logf("Response status: %d", w.Code)
-		t.Logf("Response body: %s", w.Body.String())
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Invalid topic ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topics/not-a-topic/messages", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
 	})
 }
 
+
+func TestTopicsHandler_Fragment_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	testDID := "did:plc:test123"
+
+	_, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            testDID,
+		Rkey:           "xss-topic",
+		Subject:        `if a<b then <script>alert("xss")</script>`,
+		InitialMessage: `Vec<String> holds text & "&amp;" <img src=x onerror=alert(1)>`,
+		Category:       sql.NullString{String: `c&d<svg onload=alert(1)>`, Valid: true},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+
+	mux := CreateTestServer(t, dbService, testDID)
+
+	req := httptest.NewRequest("GET", "/topics", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected HTML fragment, got %q", ct)
+	}
+	body := w.Body.String()
+	for _, unsafe := range []string{"<script", "<img", "<svg"} {
+		if strings.Contains(body, unsafe) {
+			t.Errorf("Fragment contains unescaped user content %q: %s", unsafe, body)
+		}
+	}
+	// Prose with < and & must survive intact, escaped rather than stripped
+	for _, want := range []string{
+		"if a&lt;b then &lt;script&gt;",
+		"Vec&lt;String&gt; holds text &amp; &#34;&amp;amp;&#34; &lt;img",
+		"in c&amp;d&lt;svg onload=alert(1)&gt;",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected escaped %q in fragment: %s", want, body)
+		}
+	}
+}
+
+func TestTopicsAPI_Sort_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	testDID := "did:plc:test123"
+	now := time.Now()
+
+	// quiet: newest, no replies; busy: oldest, many old replies; recent: one fresh reply
+	for i, rkey := range []string{"busy", "recent", "quiet"} {
+		created := now.Add(time.Duration(i-3) * time.Hour)
+		_, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            testDID,
+			Rkey:           rkey,
+			Subject:        "Topic " + rkey,
+			InitialMessage: "Initial message",
+			CreatedAt:      created,
+			UpdatedAt:      created,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := dbService.RecordTopicActivity(ctx, testDID, "busy", testDID, now.Add(-3*24*time.Hour)); err != nil {
+			t.Fatalf("Failed to record activity: %v", err)
+		}
+	}
+	if _, err := dbService.RecordTopicActivity(ctx, testDID, "recent", testDID, now); err != nil {
+		t.Fatalf("Failed to record activity: %v", err)
+	}
+
+	mux := CreateTestServer(t, dbService, testDID)
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"", []string{"quiet", "recent", "busy"}},
+		{"latest", []string{"quiet", "recent", "busy"}},
+		{"active", []string{"recent", "quiet", "busy"}},
+		{"top", []string{"busy", "recent", "quiet"}},
+		{"hot", []string{"recent", "busy", "quiet"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("sort="+tt.sort, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/topics?sort="+tt.sort, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var topics []db.Topic
+			if err := json.NewDecoder(w.Body).Decode(&topics); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			got := make([]string, 0, len(topics))
+			for _, topic := range topics {
+				got = append(got, topic.Rkey)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected order %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Invalid sort", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topics?sort=random", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestTopicCounters_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "counted",
+		Subject:        "Counted topic",
+		InitialMessage: "Initial message",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	if topic.ParticipantCount != 1 || topic.LastActivityAt.Valid {
+		t.Fatalf("Expected a new topic to count only its creator, got %+v", topic)
+	}
+
+	author := CreateTestServer(t, dbService, authorDID)
+	other := CreateTestServer(t, dbService, "did:plc:other")
+	path := "/api/topics/" + topic.Did + ":" + topic.Rkey
+	for _, mux := range []*http.ServeMux{author, other, other} {
+		req := httptest.NewRequest("POST", path+"/messages", strings.NewReader(`{"content":"Hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	check := func(t *testing.T, got db.Topic) {
+		if got.MessageCount != 3 || got.ParticipantCount != 2 || !got.LastActivityAt.Valid {
+			t.Errorf("Expected 3 messages from 2 participants with activity, got %+v", got)
+		}
+	}
+
+	t.Run("Detail", func(t *testing.T) {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var got db.Topic
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		check(t, got)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topics", nil)
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var topics []db.Topic
+		if err := json.NewDecoder(w.Body).Decode(&topics); err != nil || len(topics) != 1 {
+			t.Fatalf("Expected one topic, got %v (%v)", topics, err)
+		}
+		check(t, topics[0])
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/topics/did:plc:author:missing", nil)
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/components"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/trending"
+)
+
+const (
+	// trendingRefreshInterval is how often trending rankings are recomputed
+	trendingRefreshInterval = 5 * time.Minute
+	// trendingLimit bounds the topics and tags ranked in each window
+	trendingLimit = 10
+	// homeTrendingWindow is the window shown on the home page
+	homeTrendingWindow = "24h"
+)
+
+// TrendingHandler serves the cached trending rankings. The window query
+// parameter narrows the response to one window. htmx requests get the
+// ranking rendered for the home page, defaulting to homeTrendingWindow.
+func (r *Router) TrendingHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := r.trending.Snapshot()
+	name := req.URL.Query().Get("window")
+	if name == "" && isFragmentRequest(req) {
+		name = homeTrendingWindow
+	}
+	if name != "" {
+		ranking, ok := snapshot.Window(name)
+		if !ok {
+			httputil.WriteError(w, http.StatusBadRequest, "Unknown window; use 6h, 24h or 7d")
+			return
+		}
+		if isFragmentRequest(req) {
+			renderFragment(w, req, components.Trending(ranking))
+			return
+		}
+		snapshot.Windows = []trending.Ranking{ranking}
+	}
+
+	httputil.WriteSuccess(w, snapshot)
+}
+
+// trendingSource ranks activity from the message index
+type trendingSource struct {
+	dbService *db.Service
+}
+
+func (s trendingSource) Topics(ctx context.Context, since time.Time, limit int) ([]trending.Topic, error) {
+	rows, err := s.dbService.Queries().ListTrendingTopics(ctx, db.ListTrendingTopicsParams{
+		CreatedAt: since,
+		Limit:     int32(limit), // #nosec G115 -- limit is a small constant
+	})
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]trending.Topic, len(rows))
+	for i, row := range rows {
+		topics[i] = trending.Topic(row)
+	}
+	return topics, nil
+}
+
+func (s trendingSource) Tags(ctx context.Context, since time.Time, limit int) ([]trending.Tag, error) {
+	rows, err := s.dbService.Queries().ListTrendingCategories(ctx, db.ListTrendingCategoriesParams{
+		CreatedAt: since,
+		Limit:     int32(limit), // #nosec G115 -- limit is a small constant
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]trending.Tag, len(rows))
+	for i, row := range rows {
+		tags[i] = trending.Tag{Tag: row.Category.String, Messages: row.Messages, Topics: row.Topics}
+	}
+	return tags, nil
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/internal/trending"
+)
+
+func TestTrending_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	now := time.Now()
+
+	for _, topic := range []struct{ rkey, category string }{{"busy", "go"}, {"slow", "rust"}} {
+		_, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+			Did:            authorDID,
+			Rkey:           topic.rkey,
+			Subject:        "Topic " + topic.rkey,
+			InitialMessage: "Initial message",
+			Category:       sql.NullString{String: topic.category, Valid: true},
+			CreatedAt:      now.Add(-48 * time.Hour),
+			UpdatedAt:      now.Add(-48 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test topic: %v", err)
+		}
+	}
+	// busy has two recent messages from two people; slow has three from
+	// twelve hours ago, so it only leads outside the 6h window
+	messages := []struct {
+		did, rkey, topic string
+		at               time.Time
+	}{
+		{authorDID, "m1", "busy", now.Add(-time.Hour)},
+		{"did:plc:other", "m2", "busy", now.Add(-time.Hour)},
+		{authorDID, "m3", "slow", now.Add(-12 * time.Hour)},
+		{authorDID, "m4", "slow", now.Add(-12 * time.Hour)},
+		{authorDID, "m5", "slow", now.Add(-12 * time.Hour)},
+	}
+	for _, m := range messages {
+		_, err := dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
+			Did:       m.did,
+			Rkey:      m.rkey,
+			TopicDid:  authorDID,
+			TopicRkey: m.topic,
+			Content:   "Hello",
+			CreatedAt: m.at,
+			UpdatedAt: m.at,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create test message: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test"}, dbService, authorDID)
+	if err := router.trending.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh trending: %v", err)
+	}
+	get := func(path string, fragment bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if fragment {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/trending", false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var snapshot trending.Snapshot
+	if err := json.NewDecoder(w.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(snapshot.Windows) != len(trending.Windows) {
+		t.Fatalf("Expected every window, got %+v", snapshot)
+	}
+	recent, _ := snapshot.Window("6h")
+	if len(recent.Topics) != 1 || recent.Topics[0].TopicRkey != "busy" || recent.Topics[0].Participants != 2 {
+		t.Errorf("Expected only busy in the 6h window, got %+v", recent.Topics)
+	}
+	day, _ := snapshot.Window("24h")
+	if len(day.Topics) != 2 || day.Topics[0].TopicRkey != "slow" || len(day.Tags) != 2 || day.Tags[0].Tag != "rust" {
+		t.Errorf("Expected slow and rust to lead the 24h window, got %+v", day)
+	}
+
+	t.Run("One window", func(t *testing.T) {
+		w := get("/api/v1/trending?window=7d", false)
+		var snapshot trending.Snapshot
+		if err := json.NewDecoder(w.Body).Decode(&snapshot); err != nil || len(snapshot.Windows) != 1 || snapshot.Windows[0].Window != "7d" {
+			t.Errorf("Expected only the 7d window, got %d %+v (%v)", w.Code, snapshot, err)
+		}
+	})
+
+	t.Run("Unknown window", func(t *testing.T) {
+		if w := get("/api/v1/trending?window=1y", false); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Home page fragment", func(t *testing.T) {
+		w := get("/api/v1/trending", true)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Trending in the last 24h") || !strings.Contains(w.Body.String(), "Topic slow") {
+			t.Errorf("Expected the 24h ranking as HTML, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestTrendingFragment_Escaping_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	now := time.Now()
+
+	_, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "xss",
+		Subject:        `Title <script>alert("title")</script>`,
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{String: `tag<img src=x onerror=alert(1)>&co`, Valid: true},
+		CreatedAt:      now.Add(-time.Hour),
+		UpdatedAt:      now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	_, err = dbService.Queries().CreateMessage(ctx, db.CreateMessageParams{
+		Did:       authorDID,
+		Rkey:      "m1",
+		TopicDid:  authorDID,
+		TopicRkey: "xss",
+		Content:   "Hello",
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test message: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	router := RegisterTestRoutes(mux, "/", &config.Config{AppEnv: "test"}, dbService, authorDID)
+	if err := router.trending.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh trending: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/api/v1/trending", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, body)
+	}
+	for _, unsafe := range []string{"<script", "<img"} {
+		if strings.Contains(body, unsafe) {
+			t.Errorf("Fragment contains unescaped user content %q: %s", unsafe, body)
+		}
+	}
+	for _, want := range []string{
+		"Title &lt;script&gt;alert(&#34;title&#34;)&lt;/script&gt;",
+		"#tag&lt;img src=x onerror=alert(1)&gt;&amp;co",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected escaped %q in fragment: %s", want, body)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+// topicSummary is a topic as it appears in list responses
+type topicSummary struct {
+	db.Topic
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// withUnreadCounts pairs topics with the viewer's unread counts. Anonymous
+// viewers have nothing to track, so every count is zero.
+func (r *Router) withUnreadCounts(ctx context.Context, req *http.Request, topics []db.Topic) ([]topicSummary, error) {
+	counts := make(map[string]int64)
+	if userCtx, ok := middleware.GetUserContext(req); ok {
+		rows, err := r.dbService.Queries().GetUnreadCounts(ctx, userCtx.DID)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts[formatTopicID(row.TopicDid, row.TopicRkey)] = row.UnreadCount
+		}
+	}
+
+	summaries := make([]topicSummary, 0, len(topics))
+	for _, topic := range topics {
+		summaries = append(summaries, topicSummary{
+			Topic:       topic,
+			UnreadCount: counts[formatTopicID(topic.Did, topic.Rkey)],
+		})
+	}
+	return summaries, nil
+}
+
+// ReadMarkerHandler marks a topic as read (POST) or unread (DELETE) for the
+// current user and pushes the new count to their other open tabs
+func (r *Router) ReadMarkerHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost && req.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := req.Context()
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	topicDid, topicRkey, err := parseTopicID(req.PathValue("id"))
+	if err != nil {
+		httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	queries := r.dbService.Queries()
+	if _, err := queries.GetTopic(ctx, db.GetTopicParams{Did: topicDid, Rkey: topicRkey}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httputil.WriteError(w, http.StatusNotFound, "Topic not found")
+			return
+		}
+		httputil.WriteInternalError(w, err, "Failed to fetch topic", "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	if req.Method == http.MethodDelete {
+		if err := queries.DeleteReadMarker(ctx, db.DeleteReadMarkerParams{
+			Did:       userCtx.DID,
+			TopicDid:  topicDid,
+			TopicRkey: topicRkey,
+		}); err != nil {
+			httputil.WriteInternalError(w, err, "Failed to mark topic unread", "did", userCtx.DID)
+			return
+		}
+		r.publishUnread(ctx, userCtx.DID, topicDid, topicRkey)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	now := r.clock.Now()
+	marker, err := queries.UpsertReadMarker(ctx, db.UpsertReadMarkerParams{
+		Did:        userCtx.DID,
+		TopicDid:   topicDid,
+		TopicRkey:  topicRkey,
+		LastReadAt: now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, err, "Failed to mark topic read", "did", userCtx.DID)
+		return
+	}
+	r.hub.PublishUser(userCtx.DID, realtime.Event{
+		Type: realtime.EventUnread,
+		Data: events.UnreadBadge{TopicID: formatTopicID(topicDid, topicRkey)},
+	})
+
+	httputil.WriteSuccess(w, marker)
+}
+
+// UserEventsHandler streams notifications addressed to the current user,
+// such as unread badge updates, as Server-Sent Events
+func (r *Router) UserEventsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx, ok := middleware.GetUserContext(req)
+	if !ok {
+		httputil.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	release, ok := r.acquireStream(w, req)
+	if !ok {
+		return
+	}
+	defer release()
+
+	openEventStream(w)
+	sub := r.hub.SubscribeUser(userCtx.DID)
+	defer r.hub.Unsubscribe(sub)
+
+	r.streamEvents(req.Context(), w, sub)
+}
+
+// notifyUnread pushes updated unread counts to everyone following a topic
+// except the author of the new message and participants who muted it
+func (r *Router) notifyUnread(ctx context.Context, topicDid, topicRkey, authorDID string) {
+	participants, err := r.dbService.Queries().GetParticipationsByTopic(ctx, db.GetParticipationsByTopicParams{
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+	})
+	if err != nil {
+		logger.Error("Failed to fetch participants for unread badges", "error", err, "topicDid", topicDid, "topicRkey", topicRkey)
+		return
+	}
+
+	for _, participant := range participants {
+		if participant.Did == authorDID || participant.Status == validation.ParticipationMuted {
+			continue
+		}
+		r.publishUnread(ctx, participant.Did, topicDid, topicRkey)
+	}
+}
+
+// publishUnread recounts a user's unread messages in a topic and sends the
+// result to their user streams
+func (r *Router) publishUnread(ctx context.Context, did, topicDid, topicRkey string) {
+	count, err := r.dbService.Queries().CountUnreadMessages(ctx, db.CountUnreadMessagesParams{
+		Did:       did,
+		TopicDid:  topicDid,
+		TopicRkey: topicRkey,
+	})
+	if err != nil {
+		logger.Error("Failed to count unread messages", "error", err, "did", did)
+		return
+	}
+
+	r.hub.PublishUser(did, realtime.Event{
+		Type: realtime.EventUnread,
+		Data: events.UnreadBadge{TopicID: formatTopicID(topicDid, topicRkey), UnreadCount: count},
+	})
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/realtime"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+	"github.com/jrschumacher/dis.quest/internal/validation"
+	"github.com/jrschumacher/dis.quest/pkg/events"
+)
+
+func TestUnreadTracking_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+	authorDID := "did:plc:author"
+	readerDID := "did:plc:reader"
+	mutedDID := "did:plc:muted"
+	now := time.Now()
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            authorDID,
+		Rkey:           "unread-topic",
+		Subject:        "Unread Topic",
+		InitialMessage: "Initial message",
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	for did, status := range map[string]string{readerDID: validation.ParticipationFollowing, mutedDID: validation.ParticipationMuted} {
+		if _, err := dbService.Queries().CreateParticipation(ctx, db.CreateParticipationParams{
+			Did:       did,
+			TopicDid:  topic.Did,
+			TopicRkey: topic.Rkey,
+			Status:    status,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("Failed to create participation: %v", err)
+		}
+	}
+	topicID := formatTopicID(topic.Did, topic.Rkey)
+
+	authorMux := http.NewServeMux()
+	authorRouter := RegisterTestRoutes(authorMux, "/", nil, dbService, authorDID)
+	readerMux := http.NewServeMux()
+	readerRouter := RegisterTestRoutes(readerMux, "/", nil, dbService, readerDID)
+
+	readerBadges := authorRouter.hub.SubscribeUser(readerDID)
+	defer authorRouter.hub.Unsubscribe(readerBadges)
+	mutedBadges := authorRouter.hub.SubscribeUser(mutedDID)
+	defer authorRouter.hub.Unsubscribe(mutedBadges)
+
+	expectBadge := func(t *testing.T, sub *realtime.Subscriber, want int64) {
+		t.Helper()
+		select {
+		case event := <-sub.Events():
+			badge, ok := event.Data.(events.UnreadBadge)
+			if event.Type != realtime.EventUnread || !ok {
+				t.Fatalf("Expected unread event, got %+v", event)
+			}
+			if badge.TopicID != topicID || badge.UnreadCount != want {
+				t.Errorf("Expected %d unread in %s, got %+v", want, topicID, badge)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for unread badge")
+		}
+	}
+	unreadCount := func(t *testing.T) int64 {
+		t.Helper()
+		w := httptest.NewRecorder()
+		readerMux.ServeHTTP(w, httptest.NewRequest("GET", "/api/topics", nil))
+		var topics []topicSummary
+		if err := json.NewDecoder(w.Body).Decode(&topics); err != nil {
+			t.Fatalf("Failed to decode topics: %v", err)
+		}
+		if len(topics) != 1 {
+			t.Fatalf("Expected 1 topic, got %d", len(topics))
+		}
+		return topics[0].UnreadCount
+	}
+
+	t.Run("New messages raise the badge", func(t *testing.T) {
+		for i := int64(1); i <= 2; i++ {
+			req := httptest.NewRequest("POST", "/api/topics/"+topicID+"/messages", bytes.NewBufferString(`{"content":"hello"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			authorMux.ServeHTTP(w, req)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+			expectBadge(t, readerBadges, i)
+		}
+		if got := len(mutedBadges.Events()); got != 0 {
+			t.Errorf("Expected muted participant to get no badges, got %d", got)
+		}
+		if got := unreadCount(t); got != 2 {
+			t.Errorf("Expected 2 unread, got %d", got)
+		}
+	})
+
+	otherTab := readerRouter.hub.SubscribeUser(readerDID)
+	defer readerRouter.hub.Unsubscribe(otherTab)
+
+	t.Run("Mark read clears the badge", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		readerMux.ServeHTTP(w, httptest.NewRequest("POST", "/api/topics/"+topicID+"/read", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		expectBadge(t, otherTab, 0)
+		if got := unreadCount(t); got != 0 {
+			t.Errorf("Expected 0 unread, got %d", got)
+		}
+	})
+
+	t.Run("Mark unread restores the count", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		readerMux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/topics/"+topicID+"/read", nil))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+		}
+		expectBadge(t, otherTab, 2)
+		if got := unreadCount(t); got != 2 {
+			t.Errorf("Expected 2 unread, got %d", got)
+		}
+	})
+
+	t.Run("Unknown topic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		readerMux.ServeHTTP(w, httptest.NewRequest("POST", "/api/topics/did:plc:author:missing/read", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+)
+
+// API response versions, chosen per request with the Accept-Version header.
+// Every field is snake_case in both. Version 1 encodes optional fields the
+// way the database models do, as {"String": ..., "Valid": ...} objects;
+// version 2 writes plain values and omits fields that are unset. Version 1
+// stays the default while clients move over.
+const (
+	apiVersionHeader  = "Accept-Version"
+	apiVersionLegacy  = 1
+	apiVersionCurrent = 2
+)
+
+// apiVersion returns the response version req asks for, announcing it in
+// the API-Version response header. Unknown versions get the default.
+func apiVersion(w http.ResponseWriter, req *http.Request) int {
+	version := apiVersionLegacy
+	if v, err := strconv.Atoi(strings.TrimSpace(req.Header.Get(apiVersionHeader))); err == nil && v == apiVersionCurrent {
+		version = v
+	}
+	w.Header().Set("API-Version", strconv.Itoa(version))
+	w.Header().Add("Vary", apiVersionHeader)
+	return version
+}
+
+// versioned returns legacy for clients of version 1 and current's view for
+// the rest
+func versioned(w http.ResponseWriter, req *http.Request, legacy any, current func() any) any {
+	if apiVersion(w, req) == apiVersionLegacy {
+		return legacy
+	}
+	return current()
+}
+
+// topicView is a topic in version 2 responses
+type topicView struct {
+	Did              string     `json:"did"`
+	Rkey             string     `json:"rkey"`
+	Subject          string     `json:"subject"`
+	InitialMessage   string     `json:"initial_message"`
+	Category         string     `json:"category,omitempty"`
+	Community        string     `json:"community,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	SelectedAnswer   string     `json:"selected_answer,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy        string     `json:"deleted_by,omitempty"`
+	MessageCount     int32      `json:"message_count"`
+	ParticipantCount int32      `json:"participant_count"`
+	LastActivityAt   *time.Time `json:"last_activity_at,omitempty"`
+	HotRank          float64    `json:"hot_rank"`
+}
+
+func newTopicView(topic db.Topic) topicView {
+	return topicView{
+		Did:              topic.Did,
+		Rkey:             topic.Rkey,
+		Subject:          topic.Subject,
+		InitialMessage:   topic.InitialMessage,
+		Category:         topic.Category.String,
+		Community:        topic.Community,
+		CreatedAt:        topic.CreatedAt,
+		UpdatedAt:        topic.UpdatedAt,
+		SelectedAnswer:   topic.SelectedAnswer.String,
+		DeletedAt:        optionalTime(topic.DeletedAt),
+		DeletedBy:        topic.DeletedBy.String,
+		MessageCount:     topic.MessageCount,
+		ParticipantCount: topic.ParticipantCount,
+		LastActivityAt:   optionalTime(topic.LastActivityAt),
+		HotRank:          topic.HotRank,
+	}
+}
+
+func newTopicViews(topics []db.Topic) []topicView {
+	views := make([]topicView, 0, len(topics))
+	for _, topic := range topics {
+		views = append(views, newTopicView(topic))
+	}
+	return views
+}
+
+// topicSummaryView is a topic in version 2 list responses
+type topicSummaryView struct {
+	topicView
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func newTopicSummaryViews(summaries []topicSummary) []topicSummaryView {
+	views := make([]topicSummaryView, 0, len(summaries))
+	for _, summary := range summaries {
+		views = append(views, topicSummaryView{topicView: newTopicView(summary.Topic), UnreadCount: summary.UnreadCount})
+	}
+	return views
+}
+
+// messageView is a message in version 2 responses
+type messageView struct {
+	Did               string     `json:"did"`
+	Rkey              string     `json:"rkey"`
+	TopicDid          string     `json:"topic_did"`
+	TopicRkey         string     `json:"topic_rkey"`
+	ParentMessageRkey string     `json:"parent_message_rkey,omitempty"`
+	Content           string     `json:"content"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy         string     `json:"deleted_by,omitempty"`
+}
+
+func newMessageView(message db.Message) messageView {
+	return messageView{
+		Did:               message.Did,
+		Rkey:              message.Rkey,
+		TopicDid:          message.TopicDid,
+		TopicRkey:         message.TopicRkey,
+		ParentMessageRkey: message.ParentMessageRkey.String,
+		Content:           string(message.Content),
+		CreatedAt:         message.CreatedAt,
+		UpdatedAt:         message.UpdatedAt,
+		DeletedAt:         optionalTime(message.DeletedAt),
+		DeletedBy:         message.DeletedBy.String,
+	}
+}
+
+func newMessageViews(messages []db.Message) []messageView {
+	views := make([]messageView, 0, len(messages))
+	for _, message := range messages {
+		views = append(views, newMessageView(message))
+	}
+	return views
+}
+
+// participationReportView is a participationReport in version 2 responses
+type participationReportView struct {
+	Pending []db.ParticipationRetry `json:"pending"`
+	Missing []topicView             `json:"missing"`
+}
+
+func optionalTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
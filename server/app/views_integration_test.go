@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/testutil"
+)
+
+func TestResponseVersions_Integration(t *testing.T) {
+	dbService := testutil.TestDatabase(t)
+	ctx := context.Background()
+
+	topic, err := dbService.Queries().CreateTopic(ctx, db.CreateTopicParams{
+		Did:            "did:plc:author",
+		Rkey:           "versioned-topic",
+		Subject:        "Versioned Topic",
+		InitialMessage: "Initial message",
+		Category:       sql.NullString{String: "general", Valid: true},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test topic: %v", err)
+	}
+	mux := CreateTestServer(t, dbService, "did:plc:reader")
+	topicPath := "/api/topics/" + formatTopicID(topic.Did, topic.Rkey)
+
+	get := func(path, version string) (*httptest.ResponseRecorder, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var body any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode %s: %v", path, err)
+		}
+		if list, ok := body.([]any); ok {
+			if len(list) != 1 {
+				t.Fatalf("GET %s: expected 1 item, got %d", path, len(list))
+			}
+			body = list[0]
+		}
+		return w, body.(map[string]any)
+	}
+
+	for _, path := range []string{topicPath, "/api/topics"} {
+		t.Run("Version 1 by default "+path, func(t *testing.T) {
+			w, body := get(path, "")
+			if got := w.Header().Get("API-Version"); got != "1" {
+				t.Errorf("Expected API-Version 1, got %q", got)
+			}
+			category, ok := body["category"].(map[string]any)
+			if !ok || category["String"] != "general" || category["Valid"] != true {
+				t.Errorf("Expected legacy category object, got %#v", body["category"])
+			}
+		})
+
+		t.Run("Version 2 on request "+path, func(t *testing.T) {
+			w, body := get(path, "2")
+			if got := w.Header().Get("API-Version"); got != "2" {
+				t.Errorf("Expected API-Version 2, got %q", got)
+			}
+			if body["category"] != "general" {
+				t.Errorf("Expected category \"general\", got %#v", body["category"])
+			}
+			for _, field := range []string{"selected_answer", "deleted_at", "deleted_by"} {
+				if _, ok := body[field]; ok {
+					t.Errorf("Expected unset %s to be omitted, got %#v", field, body[field])
+				}
+			}
+			if body["created_at"] == nil || body["message_count"] == nil {
+				t.Errorf("Expected required fields to be present, got %v", body)
+			}
+		})
+	}
+
+	t.Run("Unknown versions get version 1", func(t *testing.T) {
+		w, _ := get(topicPath, "7")
+		if got := w.Header().Get("API-Version"); got != "1" {
+			t.Errorf("Expected API-Version 1, got %q", got)
+		}
+	})
+}
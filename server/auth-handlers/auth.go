@@ -2,12 +2,25 @@
 package auth
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jrschumacher/dis.quest/components"
 	"github.com/jrschumacher/dis.quest/internal/auth"
 	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/httputil"
 	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+	"github.com/jrschumacher/dis.quest/internal/session"
 	"github.com/jrschumacher/dis.quest/internal/svrlib"
 	"golang.org/x/oauth2"
 )
@@ -15,11 +28,36 @@ import (
 // Router handles authentication-related HTTP routes
 type Router struct {
 	*svrlib.Router
+	sessions *session.Manager
+	identity *auth.IdentityResolver
+	// dpopKeys holds sign-ins' DPoP keys; nil keeps them in a cookie
+	dpopKeys auth.DPoPKeyStore
+	// refreshes lets concurrent requests of one session share a refresh
+	refreshes auth.RefreshGroup
+	// redirects signs the page a sign-in lands on
+	redirects *loginredirect.Signer
+	// provider runs the OAuth flows with the authorization server
+	provider auth.Provider
 }
 
+// loginRedirectCookie carries a sign-in's signed landing page through the
+// authorization server
+const loginRedirectCookie = "login_redirect"
+
 // RegisterRoutes registers all /auth/* routes on the given mux, with the prefix handled by the caller.
-func RegisterRoutes(mux *http.ServeMux, prefix string, cfg *config.Config) {
-	router := &Router{svrlib.NewRouter(mux, prefix, cfg)}
+// A nil provider runs OAuth as an auth.PublicClient.
+func RegisterRoutes(mux *http.ServeMux, prefix string, cfg *config.Config, sessions *session.Manager, dpopKeys auth.DPoPKeyStore, provider auth.Provider) {
+	if provider == nil {
+		provider = auth.PublicClient{}
+	}
+	router := &Router{
+		Router:    svrlib.NewRouter(mux, prefix, cfg),
+		sessions:  sessions,
+		identity:  auth.NewIdentityResolver(),
+		dpopKeys:  dpopKeys,
+		redirects: loginredirect.NewSigner([]byte(cfg.JWKSPrivate), cfg.OAuthFlowTimeout()),
+		provider:  provider,
+	}
 	// Pass config to handlers for env-aware cookie security
 	routerConfig := cfg
 
@@ -28,6 +66,7 @@ func RegisterRoutes(mux *http.ServeMux, prefix string, cfg *config.Config) {
 	mux.HandleFunc(prefix+"/logout", func(w http.ResponseWriter, r *http.Request) { router.LogoutHandlerWithConfig(w, r, routerConfig) })
 	mux.HandleFunc(prefix+"/redirect", router.RedirectHandler)
 	mux.HandleFunc(prefix+"/callback", router.CallbackHandler)
+	mux.HandleFunc(prefix+"/refresh", router.RefreshHandler)
 	mux.HandleFunc(prefix+"/client-metadata.json", router.ClientMetadataHandler)
 }
 
@@ -62,13 +101,25 @@ func (rt *Router) LoginHandlerWithConfig(w http.ResponseWriter, r *http.Request,
 		writeError(w, http.StatusInternalServerError, "Failed to discover PDS", "handle", handle, "error", err)
 		return
 	}
-	session, err := auth.CreateSession(provider, handle, password)
+	created, err := auth.CreateSession(provider, handle, password)
+	if pds.IsUnavailable(err) {
+		writePDSUnavailable(w, err, "handle", handle)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusUnauthorized, "Invalid credentials", "handle", handle, "error", err)
 		return
 	}
-	auth.SetSessionCookieWithEnv(w, session.AccessJwt, []string{session.RefreshJwt}, cfg.AppEnv == "development")
-	http.Redirect(w, r, "/discussion", http.StatusSeeOther)
+	if err := rt.startSession(w, r, created.AccessJwt, nil, cfg.AppEnv == "development"); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to start session", "handle", handle, "error", err)
+		return
+	}
+	auth.SetSessionCookieWithEnv(w, created.AccessJwt, []string{created.RefreshJwt}, cfg.AppEnv == "development")
+	target := loginredirect.Target(r, cfg.PublicDomain)
+	if target == "" {
+		target = defaultLandingPage
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
 }
 
 // LogoutHandler handles /auth/logout requests
@@ -79,6 +130,8 @@ func (rt *Router) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 
 // LogoutHandlerWithConfig handles /auth/logout requests with config for cookie security
 func (rt *Router) LogoutHandlerWithConfig(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	rt.revokeTokens(r)
+	rt.endSession(w, r, cfg.AppEnv == "development")
 	auth.ClearSessionCookieWithEnv(w, cfg.AppEnv == "development")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -90,7 +143,23 @@ func (rt *Router) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "Missing handle", "param", "handle")
 		return
 	}
+	// A loopback client's callback arrives on 127.0.0.1, so the sign-in
+	// cookies must be set there too
+	if loopback := auth.LoopbackHost + ":" + rt.Config.Port; rt.Config.OAuthLoopback && r.Host != loopback {
+		target := *r.URL
+		target.Scheme, target.Host = "http", loopback
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
 	metadata, err := auth.DiscoverAuthorizationServer(handle)
+	if pds.IsUnavailable(err) {
+		writePDSUnavailable(w, err, "handle", handle)
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidMetadata) {
+		writeError(w, http.StatusBadGateway, "Your authorization server does not support atproto OAuth sign-in", "handle", handle, "error", err)
+		return
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to discover authorization server", "handle", handle, "error", err)
 		return
@@ -100,22 +169,38 @@ func (rt *Router) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "Failed to generate PKCE challenge", "handle", handle, "error", err)
 		return
 	}
-	// Generate and store DPoP keypair in secure cookie
+	// Generate the DPoP keypair and keep it until the callback
 	dpopKey, err := auth.GenerateDPoPKeyPair()
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to generate DPoP keypair", "handle", handle, "error", err)
 		return
 	}
-	cfg := rt.Config
-	if err := auth.SetDPoPKeyCookie(w, dpopKey.PrivateKey, cfg.AppEnv == "development"); err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to set DPoP key cookie", "handle", handle, "error", err)
+	cfg := rt.oauthConfig(r)
+	state := auth.GenerateStateToken()
+	// The request is pushed to the server rather than carried in the URL,
+	// as atproto requires
+	authURL, err := rt.provider.PushedAuthRequest(r.Context(), metadata, cfg, state, codeChallenge, dpopKey.PrivateKey)
+	if pds.IsUnavailable(err) {
+		writePDSUnavailable(w, err, "handle", handle)
 		return
 	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Your authorization server refused the sign-in request", "handle", handle, "error", err)
+		return
+	}
+	if err := rt.saveDPoPKey(w, r, state, dpopKey.PrivateKey, cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to store DPoP key", "handle", handle, "error", err)
+		return
+	}
+	// The verifier and state only serve this sign-in; the handle is needed
+	// again to refresh tokens
+	flowMaxAge := int(cfg.OAuthFlowTimeout() / time.Second)
 	http.SetCookie(w, &http.Cookie{
 		Name:     "pkce_verifier",
 		Value:    codeVerifier,
 		Path:     "/",
 		HttpOnly: true,
+		MaxAge:   flowMaxAge,
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:     "oauth_handle",
@@ -124,80 +209,495 @@ func (rt *Router) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		Secure:   true,
 	})
-	state := auth.GenerateStateToken()
 	http.SetCookie(w, &http.Cookie{
 		Name:     "oauth_state",
 		Value:    state,
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   true,
+		MaxAge:   flowMaxAge,
 	})
-	conf := auth.OAuth2Config(metadata, cfg)
-	url := conf.AuthCodeURL(state,
-		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
-		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-	)
-	http.Redirect(w, r, url, http.StatusFound)
+	if target := loginredirect.Target(r, cfg.PublicDomain); target != "" {
+		value, err := rt.redirects.Sign(target)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to remember the page to return to", "handle", handle, "error", err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     loginRedirectCookie,
+			Value:    value,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			MaxAge:   flowMaxAge,
+		})
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
 // CallbackHandler handles /auth/callback requests
 func (rt *Router) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	query := r.URL.Query()
+	// The authorization server redirects back with an error when the user
+	// declines or the request could not be completed
+	if code := query.Get("error"); code != "" {
+		// Anyone can link here with an error_description, so only a response
+		// to this browser's own sign-in gets its words shown on our page
+		stateCookie, err := r.Cookie("oauth_state")
+		if err != nil || stateCookie.Value == "" || query.Get("state") != stateCookie.Value {
+			rt.writeCallbackError(w, r, http.StatusBadRequest, callbackInvalidState, flowMissingMessage, "error", code)
+			return
+		}
+		description := query.Get("error_description")
+		category, message := authorizationError(code, description)
+		rt.writeCallbackError(w, r, http.StatusBadRequest, category, message, "error", code, "description", description)
+		return
+	}
 	handleCookie, err := r.Cookie("oauth_handle")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Missing handle context")
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackFlowMissing, flowMissingMessage)
 		return
 	}
 	handle := handleCookie.Value
 	metadata, err := auth.DiscoverAuthorizationServer(handle)
+	if pds.IsUnavailable(err) {
+		rt.writeCallbackUnavailable(w, r, err, "handle", handle)
+		return
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to rediscover authorization server", "handle", handle, "error", err)
+		rt.writeCallbackError(w, r, http.StatusInternalServerError, callbackServerError, "We could not find your account's sign-in server.", "handle", handle, "error", err)
 		return
 	}
-	code := r.URL.Query().Get("code")
+	code := query.Get("code")
 	if code == "" {
-		writeError(w, http.StatusBadRequest, "Missing code", "handle", handle)
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackInvalidResponse, "Your sign-in server sent back an incomplete response.", "handle", handle)
+		return
+	}
+	if err := auth.VerifyIssuer(metadata, query.Get("iss")); err != nil {
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackInvalidResponse, "The sign-in response did not come from your account's sign-in server.", "handle", handle, "error", err)
 		return
 	}
 	// State validation
-	state := r.URL.Query().Get("state")
+	state := query.Get("state")
 	stateCookie, err := r.Cookie("oauth_state")
-	if err != nil || state != stateCookie.Value {
-		writeError(w, http.StatusBadRequest, "Invalid state", "handle", handle, "expected", stateCookie.Value, "got", state)
+	if err != nil {
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackFlowMissing, flowMissingMessage, "handle", handle)
+		return
+	}
+	if state != stateCookie.Value {
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackInvalidState, flowMissingMessage, "handle", handle, "expected", stateCookie.Value, "got", state)
 		return
 	}
 	verCookie, err := r.Cookie("pkce_verifier")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Missing PKCE verifier", "handle", handle)
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackFlowMissing, flowMissingMessage, "handle", handle, "missing", "pkce_verifier")
+		return
+	}
+	// The DPoP key is only kept apart during sign-in; afterwards the
+	// server-side session keeps it
+	dpopKey, err := rt.takeDPoPKey(r, state)
+	if err != nil {
+		rt.writeCallbackError(w, r, http.StatusBadRequest, callbackFlowMissing, flowMissingMessage, "handle", handle, "missing", "dpop_key")
 		return
 	}
-	// Retrieve DPoP private key from secure cookie
-	dpopKey, err := auth.GetDPoPKeyFromCookie(r)
+	// The tokens must be for the account the user asked to sign in as
+	host, err := auth.DiscoverPDS(handle)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "Missing DPoP key", "handle", handle)
+		rt.writeCallbackError(w, r, http.StatusInternalServerError, callbackServerError, "We could not find your account's PDS.", "handle", handle, "error", err)
+		return
+	}
+	expectedDID, err := auth.ResolveHandle(ctx, host, handle)
+	if pds.IsUnavailable(err) {
+		rt.writeCallbackUnavailable(w, r, err, "handle", handle)
 		return
 	}
-	cfg := rt.Config
-	logger.Info("Starting token exchange with DPoP", "handle", handle, "code", code[:10]+"...", "tokenEndpoint", metadata.TokenEndpoint)
-	token, err := auth.ExchangeCodeForTokenWithDPoP(ctx, metadata, code, verCookie.Value, dpopKey, cfg)
 	if err != nil {
-		logger.Error("Token exchange failed", "handle", handle, "error", err, "tokenEndpoint", metadata.TokenEndpoint)
-		writeError(w, http.StatusUnauthorized, "Token exchange failed", "handle", handle, "error", err)
+		rt.writeCallbackError(w, r, http.StatusBadGateway, callbackServerError, "We could not look up your handle.", "handle", handle, "error", err)
+		return
+	}
+	cfg := rt.oauthConfig(r)
+	logger.Info("Starting token exchange with DPoP", "handle", handle, "code", code[:min(len(code), 10)]+"...", "tokenEndpoint", metadata.TokenEndpoint)
+	grant, err := rt.provider.ExchangeToken(ctx, metadata, code, verCookie.Value, dpopKey, expectedDID, cfg)
+	if pds.IsUnavailable(err) {
+		rt.writeCallbackUnavailable(w, r, err, "handle", handle)
+		return
+	}
+	if err != nil {
+		category, message := tokenExchangeError(err)
+		rt.writeCallbackError(w, r, http.StatusUnauthorized, category, message, "handle", handle, "error", err, "tokenEndpoint", metadata.TokenEndpoint)
+		return
+	}
+	logger.Info("Token exchange successful", "handle", handle, "did", grant.Subject, "scopes", grant.Scopes)
+	// The PDS's word for who the handle belongs to is not enough: the
+	// handle, the DID document and the account's PDS must all agree
+	if err := rt.identity.VerifyIdentity(ctx, handle, grant.Subject, metadata); err != nil {
+		if errors.Is(err, auth.ErrIdentityMismatch) {
+			rt.writeCallbackError(w, r, http.StatusForbidden, callbackIdentityMismatch, "Your handle does not belong to the account you signed in with.", "handle", handle, "did", grant.Subject, "error", err)
+			return
+		}
+		rt.writeCallbackError(w, r, http.StatusBadGateway, callbackServerError, "We could not verify your identity.", "handle", handle, "did", grant.Subject, "error", err)
 		return
 	}
-	logger.Info("Token exchange successful", "handle", handle)
 	refreshToken := ""
-	if token.RefreshToken != "" {
-		refreshToken = token.RefreshToken
+	if grant.RefreshToken != "" {
+		refreshToken = grant.RefreshToken
+	}
+	if err := rt.startSession(w, r, grant.AccessToken, dpopKey, cfg.AppEnv == "development"); err != nil {
+		rt.writeCallbackError(w, r, http.StatusInternalServerError, callbackServerError, "We could not start your session.", "handle", handle, "error", err)
+		return
+	}
+	if rt.dpopKeys == nil {
+		auth.ClearDPoPKeyCookie(w, cfg.AppEnv == "development")
 	}
 	// Use config for secure flag
-	auth.SetSessionCookieWithEnv(w, token.AccessToken, []string{refreshToken}, cfg.AppEnv == "development")
-	http.Redirect(w, r, "/discussion", http.StatusSeeOther)
+	auth.SetSessionCookieWithEnv(w, grant.AccessToken, []string{refreshToken}, cfg.AppEnv == "development")
+	http.Redirect(w, r, rt.landingPage(w, r), http.StatusSeeOther)
+}
+
+// Categories of failed sign-ins, logged with each failure so the common ones
+// can be told apart
+const (
+	callbackAccessDenied     = "access_denied"
+	callbackExpired          = "expired_request"
+	callbackAuthorization    = "authorization_error"
+	callbackFlowMissing      = "flow_missing"
+	callbackInvalidState     = "invalid_state"
+	callbackInvalidResponse  = "invalid_response"
+	callbackTokenExchange    = "token_exchange"
+	callbackIdentityMismatch = "identity_mismatch"
+	callbackUnavailable      = "pds_unavailable"
+	callbackServerError      = "server_error"
+)
+
+// flowMissingMessage explains a callback whose sign-in cookies are gone or
+// belong to another sign-in
+const flowMissingMessage = "This sign-in expired or was started in another browser."
+
+// maxErrorDescription bounds how much of the authorization server's
+// error_description is shown
+const maxErrorDescription = 300
+
+// authorizationError returns the category of an error the authorization
+// server redirected back with, and the message to show for it
+func authorizationError(code, description string) (category, message string) {
+	description = strings.TrimSpace(description)
+	if runes := []rune(description); len(runes) > maxErrorDescription {
+		description = string(runes[:maxErrorDescription]) + "…"
+	}
+	switch {
+	case code == "access_denied":
+		category, message = callbackAccessDenied, "You did not allow dis.quest to access your account."
+	case strings.Contains(strings.ToLower(description), "expired"):
+		// An expired request_uri comes back as invalid_request
+		category, message = callbackExpired, "The sign-in request expired before it was finished."
+	default:
+		category, message = callbackAuthorization, "Your sign-in server could not complete the sign-in."
+	}
+	if description != "" {
+		message += " Your sign-in server said: " + description
+	}
+	return category, message
+}
+
+// tokenExchangeError returns the category of a failed code exchange and the
+// message to show for it
+func tokenExchangeError(err error) (category, message string) {
+	if errors.Is(err, auth.ErrSubjectMismatch) {
+		return callbackIdentityMismatch, "You signed in with a different account than the handle you entered."
+	}
+	// Codes are short-lived and single-use
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+		return callbackExpired, "The sign-in request expired before it was finished."
+	}
+	return callbackTokenExchange, "Your sign-in server did not issue a session."
+}
+
+// writeCallbackError shows the sign-in failure page, which offers to start
+// the sign-in again for the same handle and page to return to, and logs the
+// failure with its category
+func (rt *Router) writeCallbackError(w http.ResponseWriter, r *http.Request, status int, category, message string, logFields ...any) {
+	logFields = append([]any{"category", category, "status", status}, logFields...)
+	if status >= http.StatusInternalServerError {
+		logger.Error("Sign-in failed", logFields...)
+	} else {
+		logger.Warn("Sign-in failed", logFields...)
+	}
+
+	handle := ""
+	if cookie, err := r.Cookie("oauth_handle"); err == nil {
+		handle = cookie.Value
+	}
+	// A remembered page that no longer verifies is dropped, not shown
+	next, _ := rt.rememberedTarget(r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := components.SignInFailed(message, handle, next).Render(r.Context(), w); err != nil {
+		logger.Error("Failed to render page", "error", err, "path", r.URL.Path)
+	}
+}
+
+// writeCallbackUnavailable shows the sign-in failure page for a PDS or
+// authorization server that could not be reached
+func (rt *Router) writeCallbackUnavailable(w http.ResponseWriter, r *http.Request, err error, logFields ...any) {
+	setRetryAfter(w, err)
+	rt.writeCallbackError(w, r, http.StatusServiceUnavailable, callbackUnavailable, httputil.PDSUnavailableMessage, append(logFields, "error", err)...)
+}
+
+// defaultLandingPage is where a sign-in lands when it was not sent from a
+// page to return to
+const defaultLandingPage = "/discussion"
+
+// landingPage returns where a finished OAuth sign-in goes: the page its
+// login_redirect cookie remembers, or defaultLandingPage
+func (rt *Router) landingPage(w http.ResponseWriter, r *http.Request) string {
+	if _, err := r.Cookie(loginRedirectCookie); err != nil {
+		return defaultLandingPage
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginRedirectCookie, Path: "/", HttpOnly: true, Secure: true, MaxAge: -1})
+	target, err := rt.rememberedTarget(r)
+	if err != nil {
+		logger.Warn("Ignoring login redirect", "error", err)
+		return defaultLandingPage
+	}
+	return target
+}
+
+// rememberedTarget returns the page the request's login_redirect cookie
+// remembers, or "" without one
+func (rt *Router) rememberedTarget(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(loginRedirectCookie)
+	if err != nil {
+		return "", nil
+	}
+	return rt.redirects.Verify(cookie.Value)
+}
+
+// saveDPoPKey keeps a sign-in's DPoP key until its callback, or until the
+// sign-in times out: in the key store under the sign-in's state, or in a
+// cookie when there is no store
+func (rt *Router) saveDPoPKey(w http.ResponseWriter, r *http.Request, state string, key *ecdsa.PrivateKey, cfg *config.Config) error {
+	if rt.dpopKeys != nil {
+		return rt.dpopKeys.Save(r.Context(), state, key, time.Now().Add(cfg.OAuthFlowTimeout()))
+	}
+	return auth.SetDPoPKeyCookie(w, key, cfg.OAuthFlowTimeout(), cfg.AppEnv == "development")
+}
+
+// takeDPoPKey returns the DPoP key saved for the sign-in with state. A
+// stored key is removed as it is read, so each can finish one sign-in.
+func (rt *Router) takeDPoPKey(r *http.Request, state string) (*ecdsa.PrivateKey, error) {
+	if rt.dpopKeys == nil {
+		return auth.GetDPoPKeyFromCookie(r)
+	}
+	key, err := rt.dpopKeys.Load(r.Context(), state)
+	if err != nil {
+		return nil, err
+	}
+	if err := rt.dpopKeys.Delete(r.Context(), state); err != nil {
+		logger.Warn("Failed to delete DPoP key", "error", err)
+	}
+	return key, nil
+}
+
+// refreshResponse tells the page when the session's access token expires
+type refreshResponse struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RefreshHandler handles POST /auth/refresh. It rotates the session cookies
+// using the stored refresh token so open pages can renew a session before it
+// expires instead of the user finding out when an action fails. Access tokens
+// not yet due under the configured SessionRefreshPolicy are kept.
+func (rt *Router) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "path", r.URL.Path)
+		return
+	}
+	isDev := rt.Config.AppEnv == "development"
+	refreshToken, err := auth.GetRefreshTokenCookie(r)
+	if err != nil || refreshToken == "" {
+		writeError(w, http.StatusUnauthorized, "No session to refresh")
+		return
+	}
+	// A revoked session must not be able to mint new tokens
+	if !rt.sessionActive(r) {
+		rt.endSession(w, r, isDev)
+		auth.ClearSessionCookieWithEnv(w, isDev)
+		writeError(w, http.StatusUnauthorized, "Session expired")
+		return
+	}
+	// Leave a token that is not due yet alone, e.g. when several open pages
+	// ask at once
+	if policy := rt.Config.SessionRefreshPolicy(); policy.Threshold > 0 {
+		if info, err := auth.TokenInfo(r); err == nil && !info.ExpiresAt.IsZero() && !policy.ShouldRefresh(info.Token) {
+			httputil.WriteSuccess(w, refreshResponse{ExpiresAt: &info.ExpiresAt})
+			return
+		}
+	}
+	tokens, err := rt.refreshes.Do(r.Context(), refreshToken, func(ctx context.Context) (auth.RefreshedTokens, error) {
+		return rt.refreshTokens(r.WithContext(ctx), refreshToken)
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshRejected) {
+			rt.endSession(w, r, isDev)
+			auth.ClearSessionCookieWithEnv(w, isDev)
+			writeError(w, http.StatusUnauthorized, "Session expired", "error", err)
+			return
+		}
+		// Keep the session: the refresh token is still good once the PDS is back
+		if pds.IsUnavailable(err) {
+			httputil.WritePDSUnavailable(w, pds.RetryAfter(err), "error", err)
+			return
+		}
+		writeError(w, http.StatusBadGateway, "Failed to refresh session", "error", err)
+		return
+	}
+	auth.SetSessionCookieWithEnv(w, tokens.AccessToken, []string{tokens.RefreshToken}, isDev)
+
+	var resp refreshResponse
+	if info, err := auth.ParseTokenInfo(tokens.AccessToken); err == nil && !info.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &info.ExpiresAt
+	}
+	httputil.WriteSuccess(w, resp)
+}
+
+// refreshTokens renews an OAuth session with the DPoP key stored in its
+// server-side session, or an app-password session, which has none
+func (rt *Router) refreshTokens(r *http.Request, refreshToken string) (auth.RefreshedTokens, error) {
+	ctx := r.Context()
+	did, err := sessionDID(r)
+	if err != nil {
+		return auth.RefreshedTokens{}, fmt.Errorf("%w: %w", auth.ErrRefreshRejected, err)
+	}
+	dpopKey, err := rt.sessions.DPoPKey(r, did)
+	if errors.Is(err, session.ErrNoDPoPKey) {
+		host, err := auth.DiscoverPDS("")
+		if err != nil {
+			return auth.RefreshedTokens{}, fmt.Errorf("failed to discover PDS: %w", err)
+		}
+		refreshed, err := auth.RefreshSession(ctx, host, refreshToken)
+		if err != nil {
+			return auth.RefreshedTokens{}, err
+		}
+		return auth.RefreshedTokens{AccessToken: refreshed.AccessJwt, RefreshToken: refreshed.RefreshJwt}, nil
+	}
+	if err != nil {
+		return auth.RefreshedTokens{}, err
+	}
+
+	handleCookie, err := r.Cookie("oauth_handle")
+	if err != nil {
+		return auth.RefreshedTokens{}, fmt.Errorf("%w: missing handle context", auth.ErrRefreshRejected)
+	}
+	metadata, err := auth.DiscoverAuthorizationServer(handleCookie.Value)
+	if err != nil {
+		return auth.RefreshedTokens{}, fmt.Errorf("failed to rediscover authorization server: %w", err)
+	}
+	token, err := rt.provider.RefreshToken(ctx, metadata, refreshToken, dpopKey, rt.oauthConfig(r))
+	if err != nil {
+		return auth.RefreshedTokens{}, err
+	}
+	return auth.RefreshedTokens{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken}, nil
+}
+
+// startSession records a server-side session for the user the access token
+// was issued to, keeping dpopKey for OAuth sign-ins, and sets its ID cookie
+func (rt *Router) startSession(w http.ResponseWriter, r *http.Request, accessToken string, dpopKey *ecdsa.PrivateKey, isDev bool) error {
+	info, err := auth.ParseTokenInfo(accessToken)
+	if err != nil {
+		return err
+	}
+	s, err := rt.sessions.StartWithDPoPKey(r.Context(), info.DID, r, dpopKey)
+	if err != nil {
+		return err
+	}
+	session.SetCookie(w, s.ID, isDev)
+	return nil
+}
+
+// sessionActive reports whether the request's server-side session is still live
+func (rt *Router) sessionActive(r *http.Request) bool {
+	did, err := sessionDID(r)
+	if err != nil {
+		return false
+	}
+	return rt.sessions.Check(r, did)
+}
+
+// sessionDID returns the DID the request's access token was issued to,
+// whether or not the token has expired
+func sessionDID(r *http.Request) (string, error) {
+	info, err := auth.TokenInfo(r)
+	if err != nil {
+		return "", err
+	}
+	return info.DID, nil
+}
+
+// revokeTokens asks the authorization server to revoke an OAuth session's
+// refresh token, so signing out ends the grant and not only the cookies.
+// Sign-out goes ahead whatever the outcome; app-password sessions have no
+// DPoP key and nothing to revoke here.
+func (rt *Router) revokeTokens(r *http.Request) {
+	refreshToken, err := auth.GetRefreshTokenCookie(r)
+	if err != nil || refreshToken == "" {
+		return
+	}
+	did, err := sessionDID(r)
+	if err != nil {
+		return
+	}
+	dpopKey, err := rt.sessions.DPoPKey(r, did)
+	if err != nil {
+		return
+	}
+	handleCookie, err := r.Cookie("oauth_handle")
+	if err != nil {
+		return
+	}
+	metadata, err := auth.DiscoverAuthorizationServer(handleCookie.Value)
+	if err != nil {
+		logger.Warn("Failed to revoke tokens at sign-out", "did", did, "error", err)
+		return
+	}
+	err = rt.provider.RevokeToken(r.Context(), metadata, refreshToken, dpopKey, rt.oauthConfig(r))
+	if err != nil && !errors.Is(err, auth.ErrRevocationUnsupported) {
+		logger.Warn("Failed to revoke tokens at sign-out", "did", did, "error", err)
+	}
+}
+
+// endSession revokes the request's server-side session, if any, and clears
+// its cookie
+func (rt *Router) endSession(w http.ResponseWriter, r *http.Request, isDev bool) {
+	defer session.ClearCookie(w, isDev)
+	id, ok := session.IDFromRequest(r)
+	if !ok {
+		return
+	}
+	did, err := sessionDID(r)
+	if err != nil {
+		return
+	}
+	if err := rt.sessions.Revoke(r.Context(), did, id); err != nil && !errors.Is(err, session.ErrNotFound) {
+		logger.Error("Failed to revoke session", "did", did, "error", err)
+	}
 }
 
 // ClientMetadataHandler serves the OAuth client metadata JSON for Bluesky
-func (rt *Router) ClientMetadataHandler(w http.ResponseWriter, _ *http.Request) {
-	cfg := rt.Config
+func (rt *Router) ClientMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	// A loopback client's metadata is implied by its client ID
+	if rt.Config.OAuthLoopback {
+		writeError(w, http.StatusNotFound, "Client metadata is not served in loopback mode")
+		return
+	}
+	cfg := rt.oauthConfig(r)
+	// Every registered callback is listed, whichever host serves this
+	redirectURIs, err := json.Marshal(cfg.RedirectURLs())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to encode redirect URIs", "error", err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	
@@ -209,15 +709,46 @@ func (rt *Router) ClientMetadataHandler(w http.ResponseWriter, _ *http.Request)
 	  "application_type": "web",
 	  "dpop_bound_access_tokens": true,
 	  "grant_types": ["authorization_code", "refresh_token"],
-	  "scope": "atproto transition:generic",
+	  "scope": "%s",
 	  "response_types": ["code"],  
-	  "redirect_uris": ["%s"],
+	  "redirect_uris": %s,
 	  "token_endpoint_auth_method": "none"
-	}`, cfg.OAuthClientID, cfg.AppName, cfg.PublicDomain, cfg.OAuthRedirectURL)
+	}`, cfg.OAuthClientID, cfg.AppName, cfg.PublicDomain, strings.Join(auth.RequestedScopes, " "), redirectURIs)
 	
 	_, _ = w.Write([]byte(metadata))
 }
 
+// oauthConfig returns the config to use for the request's OAuth flow. In
+// loopback mode that is always the loopback client. On a community's
+// verified custom domain the client ID and redirect URL are
+// moved onto that domain, since the browser's cookies belong to it;
+// elsewhere the redirect URL is the registered one on the request's host.
+func (rt *Router) oauthConfig(r *http.Request) *config.Config {
+	if rt.Config.OAuthLoopback {
+		return auth.LoopbackConfig(rt.Config)
+	}
+	if d, ok := domains.FromContext(r.Context()); ok {
+		return rt.Config.ForOrigin(d.Origin())
+	}
+	return rt.Config.ForHost(r.Host)
+}
+
+// writePDSUnavailable tells the browser the PDS or authorization server is
+// down, instead of reporting the outage as bad credentials or a failed login
+func writePDSUnavailable(w http.ResponseWriter, err error, logFields ...any) {
+	setRetryAfter(w, err)
+	http.Error(w, httputil.PDSUnavailableMessage, http.StatusServiceUnavailable)
+	logger.Warn("PDS unavailable", append(logFields, "error", err)...)
+}
+
+// setRetryAfter tells the client when an unavailable PDS suggested trying
+// again, if it did
+func setRetryAfter(w http.ResponseWriter, err error) {
+	if retryAfter := pds.RetryAfter(err); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+	}
+}
+
 // writeError is a helper to write an error response and log it
 func writeError(w http.ResponseWriter, status int, reason string, logFields ...any) {
 	http.Error(w, reason, status)
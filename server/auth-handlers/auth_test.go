@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/auth"
+	"github.com/jrschumacher/dis.quest/internal/loginredirect"
+	"golang.org/x/oauth2"
+)
+
+func TestCallbackHandlerAuthorizationError(t *testing.T) {
+	signer := loginredirect.NewSigner([]byte("secret"), time.Minute)
+	rt := &Router{redirects: signer}
+	next, err := signer.Sign("/discussion/42")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/auth/callback?error=access_denied&error_description=User+said+%3Cno%3E&state=s", nil)
+	req.AddCookie(&http.Cookie{Name: "oauth_handle", Value: "alice.example.com"})
+	req.AddCookie(&http.Cookie{Name: "oauth_state", Value: "s"})
+	req.AddCookie(&http.Cookie{Name: loginRedirectCookie, Value: next})
+	w := httptest.NewRecorder()
+	rt.CallbackHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"You did not allow dis.quest to access your account.",
+		"User said &lt;no&gt;",
+		`name="handle" value="alice.example.com"`,
+		`name="next" value="/discussion/42"`,
+		`action="/auth/redirect"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page lacks %q:\n%s", want, body)
+		}
+	}
+
+	// Without the sign-in's cookies the page asks for the handle again
+	req = httptest.NewRequest("GET", "/auth/callback?error=server_error", nil)
+	w = httptest.NewRecorder()
+	rt.CallbackHandler(w, req)
+	if body := w.Body.String(); !strings.Contains(body, `type="text" id="handle"`) || strings.Contains(body, `name="next"`) {
+		t.Errorf("page without cookies:\n%s", body)
+	}
+}
+
+func TestCallbackHandlerForgedError(t *testing.T) {
+	rt := &Router{redirects: loginredirect.NewSigner([]byte("secret"), time.Minute)}
+	const phishing = "Your+account+is+locked.+Call+555-0100"
+
+	for name, cookie := range map[string]*http.Cookie{
+		"no cookie":     nil,
+		"other sign-in": {Name: "oauth_state", Value: "theirs"},
+		"empty state":   {Name: "oauth_state", Value: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/auth/callback?error=access_denied&state=ours&error_description="+phishing, nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			w := httptest.NewRecorder()
+			rt.CallbackHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			body := w.Body.String()
+			if strings.Contains(body, "locked") || strings.Contains(body, "Your sign-in server said") {
+				t.Errorf("forged error_description shown:\n%s", body)
+			}
+			if !strings.Contains(body, flowMissingMessage) {
+				t.Errorf("page lacks the generic failure message:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestCallbackErrorCategories(t *testing.T) {
+	for _, tc := range []struct {
+		code, description string
+		want              string
+	}{
+		{"access_denied", "", callbackAccessDenied},
+		{"invalid_request", "This request has expired", callbackExpired},
+		{"invalid_request", "Invalid redirect_uri", callbackAuthorization},
+	} {
+		if got, _ := authorizationError(tc.code, tc.description); got != tc.want {
+			t.Errorf("authorizationError(%q, %q) = %q, want %q", tc.code, tc.description, got, tc.want)
+		}
+	}
+	if _, message := authorizationError("server_error", strings.Repeat("a", 2*maxErrorDescription)); len(message) > 2*maxErrorDescription {
+		t.Errorf("error_description not truncated: %d bytes", len(message))
+	}
+
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: got did:plc:bob", auth.ErrSubjectMismatch), callbackIdentityMismatch},
+		{&oauth2.RetrieveError{ErrorCode: "invalid_grant"}, callbackExpired},
+		{&oauth2.RetrieveError{ErrorCode: "invalid_client"}, callbackTokenExchange},
+	} {
+		if got, _ := tokenExchangeError(tc.err); got != tc.want {
+			t.Errorf("tokenExchangeError(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package dotwellknown
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+const instanceMetadataFilename = "quest.dis.json"
+
+// InstanceMetadata describes what a dis.quest instance supports, so other
+// instances and crawlers can discover its capabilities
+type InstanceMetadata struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Lexicons  []LexiconSupport  `json:"lexicons"`
+	Endpoints map[string]string `json:"endpoints"`
+	Firehose  FirehoseSupport   `json:"firehose"`
+	// Labeler is set when the instance publishes its moderation as labels
+	Labeler *LabelerSupport `json:"labeler,omitempty"`
+}
+
+// LexiconSupport is a lexicon the instance reads and writes. Revision is
+// the revision of the document served at URL.
+type LexiconSupport struct {
+	NSID     string `json:"nsid"`
+	Revision int    `json:"revision"`
+	URL      string `json:"url"`
+}
+
+// FirehoseSupport says whether and where record changes can be streamed
+type FirehoseSupport struct {
+	Available bool   `json:"available"`
+	URL       string `json:"url,omitempty"`
+	// Protocol names the framing; the stream follows Jetstream's
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// LabelerSupport identifies the labeler whose labels carry the instance's
+// moderation. URL is the labeler's service endpoint, serving
+// com.atproto.label.queryLabels and subscribeLabels.
+type LabelerSupport struct {
+	DID string `json:"did"`
+	URL string `json:"url"`
+}
+
+// InstanceMetadataHandler serves /.well-known/quest.dis.json. URLs use the
+// community's custom domain when the request arrived on one.
+func (rt *WellKnownRouter) InstanceMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	publicDomain := rt.Config.PublicDomain
+	if d, ok := domains.FromContext(r.Context()); ok {
+		publicDomain = d.Origin()
+	}
+
+	metadata := InstanceMetadata{
+		Name:    rt.Config.Instance().Name,
+		Version: buildVersion(),
+		Endpoints: map[string]string{
+			"api":      publicDomain + "/api",
+			"instance": publicDomain + "/api/v1/instance",
+			"lexicons": publicDomain + "/lexicons/",
+			"oauth":    publicDomain + "/.well-known/" + blueskyClientMetadataFilename,
+		},
+		Firehose: FirehoseSupport{
+			Available: true,
+			URL:       websocketURL(publicDomain) + "/subscribe",
+			Protocol:  "jetstream",
+		},
+	}
+	if rt.Config.LabelerDID != "" {
+		metadata.Labeler = &LabelerSupport{DID: rt.Config.LabelerDID, URL: publicDomain}
+	}
+	for _, nsid := range lexicon.SchemaNSIDs() {
+		metadata.Lexicons = append(metadata.Lexicons, LexiconSupport{
+			NSID:     nsid,
+			Revision: lexiconRevision(nsid),
+			URL:      publicDomain + "/lexicons/" + nsid,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	_ = json.NewEncoder(w).Encode(metadata)
+}
+
+// lexiconRevision reads the revision of a shipped lexicon document, which
+// is 0 when the document does not set one
+func lexiconRevision(nsid string) int {
+	schema, ok := lexicon.Schema(nsid)
+	if !ok {
+		return 0
+	}
+	var doc struct {
+		Revision int `json:"revision"`
+	}
+	_ = json.Unmarshal(schema, &doc)
+	return doc.Revision
+}
+
+// buildVersion identifies the running build: the module version for
+// released builds, otherwise the VCS revision it was built from
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && setting.Value != "" {
+			return setting.Value
+		}
+	}
+	return "devel"
+}
+
+// websocketURL swaps an http(s) origin's scheme for ws(s)
+func websocketURL(origin string) string {
+	if rest, ok := strings.CutPrefix(origin, "https://"); ok {
+		return "wss://" + rest
+	}
+	if rest, ok := strings.CutPrefix(origin, "http://"); ok {
+		return "ws://" + rest
+	}
+	return origin
+}
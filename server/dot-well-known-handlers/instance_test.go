@@ -0,0 +1,48 @@
+package dotwellknown
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/lexicon"
+)
+
+func TestInstanceMetadataHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, "/.well-known", &config.Config{AppName: "Rust Forum", PublicDomain: "https://forum.example.com"})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/quest.dis.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("Expected a public 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var metadata InstanceMetadata
+	if err := json.NewDecoder(w.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+
+	if metadata.Name != "Rust Forum" || metadata.Version == "" {
+		t.Errorf("Unexpected name or version in %+v", metadata)
+	}
+	if !metadata.Firehose.Available || metadata.Firehose.URL != "wss://forum.example.com/subscribe" {
+		t.Errorf("Unexpected firehose %+v", metadata.Firehose)
+	}
+	if metadata.Labeler != nil {
+		t.Errorf("Expected no labeler, got %+v", metadata.Labeler)
+	}
+	if metadata.Endpoints["api"] != "https://forum.example.com/api" {
+		t.Errorf("Unexpected endpoints %v", metadata.Endpoints)
+	}
+	var topic *LexiconSupport
+	for i := range metadata.Lexicons {
+		if metadata.Lexicons[i].NSID == lexicon.TopicNSID {
+			topic = &metadata.Lexicons[i]
+		}
+	}
+	if topic == nil || topic.Revision < 1 || topic.URL != "https://forum.example.com/lexicons/"+lexicon.TopicNSID {
+		t.Errorf("Expected %s with its revision, got %+v", lexicon.TopicNSID, topic)
+	}
+}
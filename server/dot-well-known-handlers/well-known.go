@@ -1,4 +1,5 @@
-// Package dotwellknown handles .well-known endpoints for OAuth2 and JWKS
+// Package dotwellknown handles .well-known endpoints for OAuth2, JWKS and
+// instance discovery
 package dotwellknown
 
 import (
@@ -8,13 +9,13 @@ import (
 
 	"github.com/jrschumacher/dis.quest/internal/auth"
 	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/domains"
 	"github.com/jrschumacher/dis.quest/internal/svrlib"
 	"golang.org/x/oauth2"
 )
 
 const blueskyClientMetadataFilename = "bluesky-client-metadata.json"
 const jwksFilename = "jwks.json"
-const redirectURIPath = "/auth/callback"
 
 // WellKnownRouter handles .well-known HTTP routes
 type WellKnownRouter struct {
@@ -43,6 +44,7 @@ func RegisterRoutes(mux *http.ServeMux, baseRoute string, cfg *config.Config) {
 	mux.HandleFunc(baseRoute, router.WellKnownHandler)
 	mux.HandleFunc(baseRoute+"/"+blueskyClientMetadataFilename, router.BlueskyClientMetadataHandler)
 	mux.HandleFunc(baseRoute+"/"+jwksFilename, router.JWKSHandler)
+	mux.HandleFunc(baseRoute+"/"+instanceMetadataFilename, router.InstanceMetadataHandler)
 }
 
 // WellKnownHandler serves the base .well-known endpoint
@@ -52,16 +54,21 @@ func (rt *WellKnownRouter) WellKnownHandler(w http.ResponseWriter, _ *http.Reque
 	_, _ = w.Write([]byte(`{"status":"ok","message":"Well-known endpoint"}`))
 }
 
-// BlueskyClientMetadataHandler serves the Bluesky OAuth2 client metadata
-func (rt *WellKnownRouter) BlueskyClientMetadataHandler(w http.ResponseWriter, _ *http.Request) {
+// BlueskyClientMetadataHandler serves the Bluesky OAuth2 client metadata. On
+// a community's verified custom domain the client is identified by that
+// domain and redirects back to it.
+func (rt *WellKnownRouter) BlueskyClientMetadataHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	publicDomain := rt.Config.PublicDomain
-	appName := rt.Config.AppName
+	cfg := rt.Config
+	if d, ok := domains.FromContext(r.Context()); ok {
+		cfg = cfg.ForOrigin(d.Origin())
+	}
+	publicDomain := cfg.PublicDomain
 	metadata := BlueskyClientMetadata{
 		ClientID:                publicDomain + "/.well-known/bluesky-client-metadata.json",
-		ClientName:              appName,
+		ClientName:              cfg.AppName,
 		ClientURI:               publicDomain,
-		RedirectURIs:            []string{"http://localhost:3000" + redirectURIPath, publicDomain + redirectURIPath},
+		RedirectURIs:            cfg.RedirectURLs(),
 		GrantTypes:              []string{"authorization_code", "refresh_token"},
 		ResponseTypes:           []string{"code"},
 		Scope:                   "atproto",
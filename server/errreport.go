@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// sentryTimeout bounds sending one error report
+const sentryTimeout = 5 * time.Second
+
+// reportErrors sends handler panics, 5xx responses and failed background
+// jobs to cfg.SentryDSN. It does nothing when SentryDSN is empty; an
+// invalid DSN is logged and leaves reporting off rather than stopping the
+// process.
+func reportErrors(cfg *config.Config) {
+	if cfg.SentryDSN == "" {
+		return
+	}
+	reporter, err := errreport.NewSentry(cfg.SentryDSN, cfg.AppEnv, &http.Client{Timeout: sentryTimeout})
+	if err != nil {
+		logger.Error("Invalid sentry_dsn, error reporting disabled", "error", err)
+		return
+	}
+	errreport.SetReporter(reporter)
+}
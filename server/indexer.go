@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/session"
+	apphandlers "github.com/jrschumacher/dis.quest/server/app"
+)
+
+const (
+	// indexerExternal is the indexer setting for running the jobs in a
+	// separate `disquest indexer` process rather than inside the server
+	indexerExternal = "external"
+
+	// tombstonePurgeInterval is how often expired soft-deleted rows are removed
+	tombstonePurgeInterval = time.Hour
+	// firehosePurgeInterval is how often events past the replay window are removed
+	firehosePurgeInterval = time.Hour
+	// sessionPurgeInterval is how often sessions past their idle timeout are removed
+	sessionPurgeInterval = 6 * time.Hour
+)
+
+// StartIndexer runs the jobs that maintain the shared database without
+// serving HTTP, until interrupted. Servers sharing the database should set
+// indexer to external so the jobs run only here.
+func StartIndexer(cfg *config.Config) {
+	if err := config.Validate(cfg); err != nil {
+		logger.Error("invalid config", "error", err)
+		panic("invalid config")
+	}
+
+	dbService, err := db.NewService(cfg)
+	if err != nil {
+		logger.Error("failed to initialize database service", "error", err)
+		panic("failed to initialize database service")
+	}
+	defer func() {
+		if err := dbService.Close(); err != nil {
+			logger.Error("failed to close database service", "error", err)
+		}
+	}()
+
+	deps, err := apphandlers.NewDeps(cfg, dbService)
+	if err != nil {
+		logger.Error("failed to initialize services", "error", err)
+		panic("failed to initialize services")
+	}
+	serveMetrics(cfg)
+	reportErrors(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchConfig(ctx, cfg)
+
+	logger.Info("Indexer running")
+	runIndexer(ctx, cfg, deps)
+	logger.Info("Indexer stopped")
+}
+
+// runIndexer runs every job that writes to the shared database until ctx is
+// done
+func runIndexer(ctx context.Context, cfg *config.Config, deps *apphandlers.Deps) {
+	errreport.Go("purge_tombstones", func() { purgeTombstones(ctx, deps.DB) })
+	errreport.Go("purge_firehose_events", func() { purgeFirehoseEvents(ctx, deps.DB) })
+	errreport.Go("purge_idle_sessions", func() { purgeIdleSessions(ctx, deps.Sessions) })
+	apphandlers.RunIndexer(ctx, cfg, deps)
+}
+
+// purgeTombstones periodically removes soft-deleted content whose undo grace
+// period has passed
+func purgeTombstones(ctx context.Context, dbService *db.Service) {
+	ticker := time.NewTicker(tombstonePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cutoff := time.Now().Add(-apphandlers.TombstoneGracePeriod)
+		purged, err := dbService.PurgeTombstones(ctx, cutoff)
+		if err != nil {
+			logger.Error("failed to purge tombstones", "error", err)
+			errreport.Job("purge_tombstones", err)
+			continue
+		}
+		if purged > 0 {
+			logger.Info("purged tombstones", "count", purged)
+		}
+	}
+}
+
+// purgeFirehoseEvents periodically removes firehose events older than the
+// replay window
+func purgeFirehoseEvents(ctx context.Context, dbService *db.Service) {
+	ticker := time.NewTicker(firehosePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cutoff := time.Now().Add(-apphandlers.FirehoseRetention).UnixMicro()
+		purged, err := dbService.Queries().PurgeFirehoseEvents(ctx, cutoff)
+		if err != nil {
+			logger.Error("failed to purge firehose events", "error", err)
+			errreport.Job("purge_firehose_events", err)
+			continue
+		}
+		if purged > 0 {
+			logger.Info("purged firehose events", "count", purged)
+		}
+	}
+}
+
+// purgeIdleSessions periodically removes sessions that have gone unused for
+// longer than the idle timeout
+func purgeIdleSessions(ctx context.Context, sessions *session.Manager) {
+	ticker := time.NewTicker(sessionPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		purged, err := sessions.PurgeIdle(ctx)
+		if err != nil {
+			logger.Error("failed to purge idle sessions", "error", err)
+			errreport.Job("purge_idle_sessions", err)
+			continue
+		}
+		if purged > 0 {
+			logger.Info("purged idle sessions", "count", purged)
+		}
+	}
+}
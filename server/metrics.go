@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/metrics"
+	"github.com/jrschumacher/dis.quest/internal/mirror"
+	"github.com/jrschumacher/dis.quest/internal/pds"
+)
+
+// serveMetrics records PDS calls and mirror ingestion and serves them at
+// /metrics on cfg.MetricsAddr, in the background. It does nothing when
+// MetricsAddr is empty.
+func serveMetrics(cfg *config.Config) {
+	if cfg.MetricsAddr == "" {
+		return
+	}
+	sink := metrics.NewPrometheus()
+	pds.SetMetrics(sink)
+	mirror.SetMetrics(sink)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", sink)
+	srv := &http.Server{
+		Addr:         cfg.MetricsAddr,
+		Handler:      mux,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+	}
+	go func() {
+		logger.Info("Serving metrics on " + srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server error", "error", err)
+		}
+	}()
+}
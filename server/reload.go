@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+)
+
+// watchConfig applies reloaded log levels and reloads the configuration
+// whenever the process receives SIGHUP, until ctx is done
+func watchConfig(ctx context.Context, cfg *config.Config) {
+	cfg.OnReload(func(reloaded *config.Config) { logger.SetLevel(reloaded.LogLevel) })
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangups)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				reloadConfig(cfg)
+			}
+		}
+	}()
+}
+
+// reloadConfig rereads the configuration and logs what changed. An invalid
+// configuration is not applied.
+func reloadConfig(cfg *config.Config) {
+	result, err := cfg.Reload(config.Load())
+	if err != nil {
+		logger.Error("Failed to reload config", "error", err)
+		return
+	}
+	logger.Info("Reloaded config", "changed", result.Changed)
+	if len(result.Ignored) > 0 {
+		logger.Warn("Changed settings take effect on restart", "settings", result.Ignored)
+	}
+}
@@ -2,12 +2,17 @@
 package server
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/jrschumacher/dis.quest/internal/config"
 	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/domains"
+	"github.com/jrschumacher/dis.quest/internal/errreport"
 	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
 	apphandlers "github.com/jrschumacher/dis.quest/server/app"
 	authhandlers "github.com/jrschumacher/dis.quest/server/auth-handlers"
 	wellknownhandlers "github.com/jrschumacher/dis.quest/server/dot-well-known-handlers"
@@ -18,13 +23,6 @@ const (
 	readTimeout  = 10 * time.Second
 	writeTimeout = 10 * time.Second
 	idleTimeout  = 60 * time.Second
-
-	// Headers
-	contentTypeOptions    = "nosniff"
-	frameOptions          = "DENY"
-	xssProtection         = "1; mode=block"
-	contentSecurityPolicy = "default-src 'self'"
-	referrerPolicy        = "strict-origin-when-cross-origin"
 )
 
 // Start initializes and starts the HTTP server with the given configuration
@@ -46,15 +44,33 @@ func Start(cfg *config.Config) {
 		}
 	}()
 
+	deps, err := apphandlers.NewDeps(cfg, dbService)
+	if err != nil {
+		logger.Error("failed to initialize services", "error", err)
+		panic("failed to initialize services")
+	}
+	middleware.SetSessionValidator(deps.Sessions.Lookup)
+	middleware.SetTokenAuthenticator(deps.Tokens.Lookup)
+	serveMetrics(cfg)
+	reportErrors(cfg)
+	watchConfig(context.Background(), cfg)
+
+	if cfg.Indexer != indexerExternal {
+		errreport.Go("indexer", func() { runIndexer(context.Background(), cfg, deps) })
+	}
+
 	mux := http.NewServeMux()
 
 	wellknownhandlers.RegisterRoutes(mux, "/.well-known", cfg)
-	authhandlers.RegisterRoutes(mux, "/auth", cfg)
+	authhandlers.RegisterRoutes(mux, "/auth", cfg, deps.Sessions, deps.DPoPKeys, deps.OAuth)
 	healthhandlers.RegisterRoutes(mux, "/health", cfg)
-	apphandlers.RegisterRoutes(mux, "/", cfg, dbService)
+	apphandlers.RegisterRoutes(mux, "/", cfg, deps)
 
-	// Secure headers middleware
-	handler := secureHeaders(mux)
+	// Recover wraps everything so every response carries a request ID and
+	// no panic escapes unreported. Community custom domains are resolved
+	// next so every handler, including the security headers, can see which
+	// domain a request arrived on.
+	handler := middleware.Recover(domains.Middleware(deps.Domains, cfg.PublicDomain)(middleware.SecurityHeaders(cfg)(middleware.CORS(cfg)(middleware.Timeout(routeTimeouts(cfg))(mux)))))
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -64,20 +80,22 @@ func Start(cfg *config.Config) {
 		IdleTimeout:  idleTimeout,
 	}
 
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Error("failed to listen", "addr", srv.Addr, "error", err)
+		panic("failed to listen")
+	}
 	logger.Info("Listening on " + srv.Addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(listener) }()
+
+	// The checks fetch the instance's own client metadata and callback, so
+	// they run once it is serving
+	if !checkStartup(cfg, dbService) {
+		_ = srv.Close()
+		panic("startup checks failed")
+	}
+	if err := <-served; err != nil && err != http.ErrServerClosed {
 		logger.Error("server error", "error", err)
 	}
 }
-
-// secureHeaders adds common security headers to all responses
-func secureHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-Content-Type-Options", contentTypeOptions)
-		w.Header().Set("X-Frame-Options", frameOptions)
-		w.Header().Set("X-XSS-Protection", xssProtection)
-		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
-		w.Header().Set("Referrer-Policy", referrerPolicy)
-		next.ServeHTTP(w, r)
-	})
-}
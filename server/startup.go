@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/db"
+	"github.com/jrschumacher/dis.quest/internal/logger"
+	"github.com/jrschumacher/dis.quest/internal/preflight"
+	"github.com/jrschumacher/dis.quest/migrations"
+)
+
+// Startup checks fetch the instance's own public URLs, so they run once it
+// is listening
+const (
+	startupCheckTimeout = 30 * time.Second
+	startupFetchTimeout = 10 * time.Second
+)
+
+// checkStartup runs the startup checks and logs their report. It returns
+// false when a critical check failed in production, where the instance
+// should not take traffic; elsewhere failures are only reported.
+func checkStartup(cfg *config.Config, dbService *db.Service) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), startupCheckTimeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: startupFetchTimeout}
+	report := preflight.Run(ctx, preflight.Startup(cfg, dbService, migrations.Latest(), client)...)
+	report.Log(logger.Logger().With("env", cfg.AppEnv))
+	return cfg.AppEnv != config.EnvProd || len(report.CriticalFailures()) == 0
+}
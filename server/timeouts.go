@@ -0,0 +1,32 @@
+package server
+
+import (
+	"time"
+
+	"github.com/jrschumacher/dis.quest/internal/config"
+	"github.com/jrschumacher/dis.quest/internal/middleware"
+)
+
+// batchTimeout bounds requests that rewrite or delete every one of a
+// user's records in their PDS
+const batchTimeout = 5 * time.Minute
+
+// routeTimeouts returns how long requests may take by route: the JSON API
+// gets api_timeout_seconds, batch record operations get longer, event
+// streams and WebSockets are exempt, and everything else gets
+// request_timeout_seconds
+func routeTimeouts(cfg *config.Config) middleware.Timeouts {
+	return middleware.Timeouts{
+		Default: cfg.RequestTimeout(),
+		Routes: []middleware.RouteTimeout{
+			{Pattern: "/api/", Timeout: cfg.APITimeout()},
+			{Pattern: "/xrpc/", Timeout: cfg.APITimeout()},
+			{Pattern: "/api/v1/me/records/migrate", Timeout: batchTimeout},
+			{Pattern: "/api/v1/me/deactivate", Timeout: batchTimeout},
+			{Pattern: "/api/events"},
+			{Pattern: "/api/topics/{id}/events"},
+			{Pattern: "/subscribe"},
+			{Pattern: "/xrpc/com.atproto.label.subscribeLabels"},
+		},
+	}
+}